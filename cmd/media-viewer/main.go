@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
 	"runtime"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
@@ -19,11 +24,17 @@ import (
 	"media-viewer/internal/metrics"
 	"media-viewer/internal/middleware"
 	"media-viewer/internal/startup"
+	"media-viewer/internal/streaming"
 	"media-viewer/internal/transcoder"
+	"media-viewer/internal/workers"
 
 	"github.com/gorilla/mux"
 )
 
+// streamDrainTimeout is how long shutdown waits for active streams to end
+// cleanly before carrying on
+const streamDrainTimeout = 10 * time.Second
+
 // dbStatsAdapter adapts database.Database to metrics.StatsProvider
 type dbStatsAdapter struct {
 	db *database.Database
@@ -44,9 +55,17 @@ func (a *dbStatsAdapter) GetStats() metrics.Stats {
 }
 
 func main() {
+	// Load CONFIG_FILE into the environment before anything reads it
+	if err := startup.ApplyConfigFile(); err != nil {
+		startup.LogFatal("Configuration file error: %v", err)
+	}
+
 	// Configure memory limit from environment FIRST, before any significant allocations
 	memResult := memory.ConfigureFromEnv()
 
+	// Likewise GOMAXPROCS, before worker pools are sized from it
+	workers.ConfigureGOMAXPROCS()
+
 	startTime := time.Now()
 
 	// Create a context for background operations that cancels on shutdown
@@ -76,11 +95,12 @@ func main() {
 
 	// Log memory configuration
 	startup.LogMemoryConfig(startup.MemoryConfig{
-		Configured:     memResult.Configured,
-		Source:         memResult.Source,
-		ContainerLimit: memResult.ContainerLimit,
-		GoMemLimit:     memResult.GoMemLimit,
-		Ratio:          memResult.Ratio,
+		Configured:       memResult.Configured,
+		Source:           memResult.Source,
+		ContainerLimit:   memResult.ContainerLimit,
+		GoMemLimit:       memResult.GoMemLimit,
+		Ratio:            memResult.Ratio,
+		TranscodeReserve: memResult.TranscodeReserve,
 	})
 
 	// Set application info metric
@@ -94,9 +114,16 @@ func main() {
 	// Initialize database
 	dbStart := time.Now()
 	dbOpts := &database.Options{
-		MmapDisabled: config.DBMmapDisabled,
+		MmapDisabled:      config.DBMmapDisabled,
+		WALAutoCheckpoint: config.DBWALAutoCheckpoint,
+		RecoverCorrupt:    config.DBRecoverCorrupt,
 	}
 	db, dbInfo, err := database.New(bgCtx, config.DatabasePath, dbOpts)
+	if errors.Is(err, database.ErrCorrupt) {
+		startup.LogFatal("Failed to initialize database: %v. Restore %s from a backup, or set DB_RECOVER_CORRUPT=true "+
+			"to move it aside and start with an empty database (the library is re-indexed, but users, favorites, "+
+			"tags and collections are lost)", err, config.DatabasePath)
+	}
 	if err != nil {
 		startup.LogFatal("Failed to initialize database: %v", err)
 	}
@@ -123,6 +150,31 @@ func main() {
 	// Initialize transcoder
 	startup.LogTranscoderInit(config.TranscodingEnabled)
 	trans := transcoder.New(config.TranscodeDir, config.TranscoderLogDir, config.TranscodingEnabled, config.GPUAccel)
+	trans.SetMaxTranscodeWait(config.TranscodeMaxWait)
+	trans.SetWidthLadder(parseWidthLadder(config.TranscodeWidthLadder))
+	trans.SetHDRToneMapping(config.HDRToneMapping)
+	trans.SetCPUEncoder(parseTranscodePreset(config.TranscodePreset), transcodeCRF(config.TranscodeCRF))
+	trans.SetHLSSegmentDuration(config.HLSSegmentDuration)
+	trans.SetHLSPrefetch(config.HLSPrefetch)
+	trans.SetMemoryMonitor(memMonitor)
+	trans.SetLogRetention(config.TranscoderLogMaxAge, int64(config.TranscoderLogMaxMB)<<20)
+	trans.SetLogErrorsOnly(config.TranscoderLogErrors)
+	trans.ReconcileCache()
+
+	// Prune transcoder logs now and then hourly; a no-op without limits
+	trans.PruneLogs()
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				trans.PruneLogs()
+			case <-bgCtx.Done():
+				return
+			}
+		}
+	}()
 
 	// Initialize thumbnail generator
 	startup.LogThumbnailInit(config.ThumbnailsEnabled)
@@ -134,16 +186,52 @@ func main() {
 		config.ThumbnailInterval,
 		memMonitor,
 	)
+	thumbGen.SetPaletteExtraction(config.PaletteEnabled)
+	thumbGen.SetAnimatedDetection(config.AnimatedDetection)
+	thumbGen.SetVideoSeekStrategy(parseVideoSeekStrategy(config.VideoThumbnailSeek))
+	thumbGen.SetDeduplication(config.ThumbnailDedupe)
+	thumbGen.SetStaleWhileRevalidate(config.ServeStaleThumbnails)
+	thumbGen.SetFolderVideoFrames(config.FolderVideoFrames)
+	thumbGen.SetThumbnailStyle(parseThumbnailStyle(config.ThumbnailStyle))
+	thumbGen.SetOutputFormat(parseThumbnailFormat(config.ThumbnailFormat))
+	thumbGen.SetSensitiveStyle(parseSensitiveStyle(config.ThumbnailSensitive))
+	thumbGen.SetThumbnailSize(thumbnailSize(config.ThumbnailSize))
+	thumbGen.SetVariantSizes(parseThumbnailSizes(config.ThumbnailVariantSizes))
+	thumbGen.SetOtherThumbnailMode(parseOtherThumbnailMode(config.OtherThumbnails))
+	thumbGen.SetChangedFileMode(parseChangedFileMode(config.ChangedFiles))
+	thumbGen.SetLargeFileDeferral(config.LargeFileThreshold, config.LargeFileWorkers)
+	thumbGen.SetCacheLimit(config.ThumbnailCacheMax)
+	thumbGen.SetFreshnessInterval(config.ThumbnailFreshness)
+	thumbGen.SetStopGracePeriod(config.ThumbnailStopGrace)
 
 	// Initialize indexer
 	startup.LogIndexerInit(config.IndexInterval, config.PollInterval)
 	idx := indexer.New(db, config.MediaDir, config.IndexInterval)
 	idx.SetPollInterval(config.PollInterval)
+	idx.SetQuietPeriod(config.IndexQuietPeriod)
+	idx.SetBirthTimeIndexing(config.IndexBirthTime)
+	idx.SetCameraIndexing(config.IndexCamera)
+	idx.SetExifDateIndexing(config.IndexExif)
+	idx.SetSidecarImport(config.IndexXMP)
+	idx.SetStrictPermissions(config.IndexStrictPerms)
+	idx.SetDuplicateHashing(config.IndexDuplicates)
+	idx.SetHashOptions(parseHashOptions(config.IndexHashMode, config.IndexHashAlgorithm))
+	idx.SetProgressInterval(config.IndexProgressInterval)
+	idx.SetOnProgress(func(event indexer.ProgressEvent) {
+		logging.Info("Index progress (%s): %d files, %d folders, elapsed %v, in %q",
+			event.Stage, event.FilesProcessed, event.FoldersProcessed, event.Elapsed.Round(time.Second), event.CurrentFolder)
+	})
 
 	idx.SetOnIndexComplete(func() {
+		// Checkpoint before thumbnail generation starts writing again
+		if config.DBWALIndexCheckpoint {
+			checkpointWAL(bgCtx, db)
+		}
 		thumbGen.NotifyIndexComplete()
 	})
 
+	logWorkerCounts(idx, thumbGen)
+
 	// Start indexer in background
 	go func() {
 		if err := idx.Start(); err != nil {
@@ -162,8 +250,43 @@ func main() {
 	metricsCollector.Start()
 	logging.Info("Metrics collector started")
 
+	// Limit login and setup attempts per client IP
+	trustedProxies := parseTrustedProxies(config.TrustedProxies)
+	authLimiter := middleware.NewRateLimiter(config.AuthRateLimit, trustedProxies)
+	defer authLimiter.Stop()
+
 	// Initialize handlers
 	h := handlers.New(db, idx, trans, thumbGen, config)
+	h.SetConfigReloader(func() (*startup.ReloadResult, error) {
+		result, err := startup.ReloadConfig()
+		if err != nil {
+			return nil, err
+		}
+		applyReloadedConfig(result, idx, thumbGen, memMonitor, authLimiter)
+		return result, nil
+	})
+
+	// Purge expired trash now and then hourly; a no-op without TRASH_TTL
+	go func() {
+		purge := func() {
+			ctx, cancel := context.WithTimeout(bgCtx, 5*time.Minute)
+			defer cancel()
+			if _, err := h.PurgeTrash(ctx); err != nil {
+				logging.Error("Failed to purge trash: %v", err)
+			}
+		}
+		purge()
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				purge()
+			case <-bgCtx.Done():
+				return
+			}
+		}
+	}()
 
 	// Start metrics server if enabled
 	var metricsSrv *http.Server
@@ -198,7 +321,9 @@ func main() {
 	}
 
 	// Setup router
-	router := setupRouter(h)
+	disabled := parseDisabledRoutes(config.DisabledRoutes)
+	disabled[routesMediaWrite] = !config.MediaWriteEnabled
+	router := setupRouter(h, authLimiter, config.RequestTimeout, config.SPAFallback, disabled)
 
 	// Log routes dynamically
 	startup.LogHTTPRoutes(router, config.LogStaticFiles, config.LogHealthChecks)
@@ -214,6 +339,8 @@ func main() {
 	loggingConfig := middleware.DefaultLoggingConfig()
 	loggingConfig.LogStaticFiles = config.LogStaticFiles
 	loggingConfig.LogHealthChecks = config.LogHealthChecks
+	loggingConfig.Format = parseAccessLogFormat(config.AccessLogFormat)
+	loggingConfig.TrustedProxies = trustedProxies
 	loggedHandler := middleware.Logger(loggingConfig)(metricsHandler)
 
 	// Apply compression middleware
@@ -278,100 +405,506 @@ func startMetricsServer(h *handlers.Handlers, port string) *http.Server {
 	return srv
 }
 
-func setupRouter(h *handlers.Handlers) *mux.Router {
+// routeGroup names a set of API routes that DISABLED_ROUTES can turn off
+type routeGroup string
+
+const (
+	routesReindex      routeGroup = "reindex"      // Manual reindexing
+	routesRebuild      routeGroup = "rebuild"      // Rebuilding all thumbnails or the search index
+	routesInvalidate   routeGroup = "invalidate"   // Clearing thumbnails, transcodes and caches
+	routesDelete       routeGroup = "delete"       // Deleting files, tags and collections everywhere
+	routesMove         routeGroup = "move"         // Moving and renaming files and folders
+	routesReload       routeGroup = "reload"       // Reloading the configuration
+	routesImport       routeGroup = "import"       // Importing favorites and tags
+	routesCapabilities routeGroup = "capabilities" // Reporting the supported formats
+
+	// routesMediaWrite covers the routes changing the media directory
+	// itself. It is off unless MEDIA_WRITE_ENABLED is set, and isn't a
+	// DISABLED_ROUTES name.
+	routesMediaWrite routeGroup = "media-write"
+)
+
+// allRouteGroups lists the route groups in the order they're documented
+var allRouteGroups = []routeGroup{routesReindex, routesRebuild, routesInvalidate, routesDelete, routesMove, routesReload, routesImport, routesCapabilities}
+
+// disabledRoutes is the set of route groups turned off by DISABLED_ROUTES
+type disabledRoutes map[routeGroup]bool
+
+// parseDisabledRoutes parses DISABLED_ROUTES, a comma-separated list of
+// route groups. Unknown names are logged and ignored.
+func parseDisabledRoutes(value string) disabledRoutes {
+	disabled := make(disabledRoutes)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		group := routeGroup(strings.ToLower(name))
+		if group == "" {
+			continue
+		}
+		if !slices.Contains(allRouteGroups, group) {
+			logging.Warn("Unknown route group %q in DISABLED_ROUTES, ignoring (valid groups: %v)", name, allRouteGroups)
+			continue
+		}
+		disabled[group] = true
+	}
+	if len(disabled) > 0 {
+		names := make([]string, 0, len(disabled))
+		for _, group := range allRouteGroups {
+			if disabled[group] {
+				names = append(names, string(group))
+			}
+		}
+		logging.Info("Disabled API route groups: %s", strings.Join(names, ", "))
+	}
+	return disabled
+}
+
+// handler returns h, or a handler answering 404 if its group is disabled.
+// Disabled routes stay registered so they don't fall through to another
+// route, such as a GET on the same path or the static files.
+func (d disabledRoutes) handler(group routeGroup, h http.HandlerFunc) http.HandlerFunc {
+	if d[group] {
+		return http.NotFound
+	}
+	return h
+}
+
+// setupRouter registers the HTTP routes. Routes wrapped in h.Public are the
+// ones anonymous visitors may use in PUBLIC_MODE; all others require login.
+func setupRouter(h *handlers.Handlers, authLimiter *middleware.RateLimiter, requestTimeout time.Duration, spaFallback bool, disabled disabledRoutes) *mux.Router {
 	r := mux.NewRouter()
 
 	// Health check and version routes (no auth required)
-	r.HandleFunc("/health", h.HealthCheck).Methods("GET", "HEAD")
-	r.HandleFunc("/healthz", h.HealthCheck).Methods("GET", "HEAD")
-	r.HandleFunc("/livez", h.LivenessCheck).Methods("GET", "HEAD")
-	r.HandleFunc("/readyz", h.ReadinessCheck).Methods("GET", "HEAD")
-	r.HandleFunc("/version", h.GetVersion).Methods("GET")
+	h.Public(r.HandleFunc("/health", h.HealthCheck).Methods("GET", "HEAD"))
+	h.Public(r.HandleFunc("/healthz", h.HealthCheck).Methods("GET", "HEAD"))
+	h.Public(r.HandleFunc("/livez", h.LivenessCheck).Methods("GET", "HEAD"))
+	h.Public(r.HandleFunc("/readyz", h.ReadinessCheck).Methods("GET", "HEAD"))
+	h.Public(r.HandleFunc("/version", h.GetVersion).Methods("GET"))
 
 	// PWA assets (must be accessible without auth for install prompts)
-	r.HandleFunc("/manifest.json", serveStaticFile("./static/manifest.json", "application/manifest+json")).Methods("GET")
-	r.HandleFunc("/favicon.ico", serveStaticFile("./static/icons/favicon.ico", "image/x-icon")).Methods("GET")
-	r.PathPrefix("/icons/").Handler(http.StripPrefix("/icons/", http.FileServer(http.Dir("./static/icons"))))
-	r.HandleFunc("/sw.js", serveStaticFile("./static/sw.js", "application/javascript")).Methods("GET")
+	h.Public(r.HandleFunc("/manifest.json", serveStaticFile("./static/manifest.json", "application/manifest+json")).Methods("GET"))
+	h.Public(r.HandleFunc("/favicon.ico", serveStaticFile("./static/icons/favicon.ico", "image/x-icon")).Methods("GET"))
+	h.Public(r.PathPrefix("/icons/").Handler(http.StripPrefix("/icons/", http.FileServer(http.Dir("./static/icons")))))
+	h.Public(r.HandleFunc("/sw.js", serveStaticFile("./static/sw.js", "application/javascript")).Methods("GET"))
 
 	// Login page (needs to be accessible without auth)
-	r.HandleFunc("/login.html", serveStaticFile("./static/login.html", "text/html; charset=utf-8")).Methods("GET")
+	h.Public(r.HandleFunc("/login.html", serveStaticFile("./static/login.html", "text/html; charset=utf-8")).Methods("GET"))
 
 	// Auth routes
 	auth := r.PathPrefix("/api/auth").Subrouter()
-	auth.HandleFunc("/setup", h.Setup).Methods("POST")
-	auth.HandleFunc("/login", h.Login).Methods("POST")
+	auth.Use(middleware.Timeout(requestTimeout))
+	auth.HandleFunc("/setup", authLimiter.Limit(h.Setup)).Methods("POST")
+	auth.HandleFunc("/login", authLimiter.Limit(h.Login)).Methods("POST")
 	auth.HandleFunc("/logout", h.Logout).Methods("POST")
-	auth.HandleFunc("/check", h.CheckAuth).Methods("GET")
+	h.Public(auth.HandleFunc("/check", h.CheckAuth).Methods("GET"))
 	auth.HandleFunc("/password", h.ChangePassword).Methods("PUT")
 	auth.HandleFunc("/keepalive", h.Keepalive).Methods("POST")
 
 	// WebAuthn/Passkey routes
-	auth.HandleFunc("/webauthn/available", h.WebAuthnAvailable).Methods("GET")
+	h.Public(auth.HandleFunc("/webauthn/available", h.WebAuthnAvailable).Methods("GET"))
 	auth.HandleFunc("/webauthn/register/begin", h.BeginWebAuthnRegistration).Methods("POST")
 	auth.HandleFunc("/webauthn/register/finish", h.FinishWebAuthnRegistration).Methods("POST")
 	auth.HandleFunc("/webauthn/login/begin", h.BeginWebAuthnLogin).Methods("POST")
 	auth.HandleFunc("/webauthn/login/finish", h.FinishWebAuthnLogin).Methods("POST")
 	auth.HandleFunc("/webauthn/passkeys", h.ListPasskeys).Methods("GET")
 	auth.HandleFunc("/webauthn/passkeys", h.DeletePasskey).Methods("DELETE")
+	auth.HandleFunc("/webauthn/credentials", h.ListWebAuthnCredentials).Methods("GET")
+	auth.HandleFunc("/webauthn/credentials/{id}", h.DeleteWebAuthnCredential).Methods("DELETE")
+
+	// Protected file download and streaming routes, including recursive path
+	// listings streamed as they are read, which may legitimately run for as
+	// long as the client keeps reading, so REQUEST_TIMEOUT doesn't apply.
+	// Shutdown waits for them to finish with DrainAll.
+	streams := r.PathPrefix("/api").Subrouter()
+	streams.Use(streaming.Track)
+	h.Public(streams.HandleFunc("/file/{path:.*}", h.GetFile).Methods("GET"))
+	h.Public(streams.HandleFunc("/stream/{path:.*}", h.StreamVideo).Methods("GET", "HEAD"))
+	h.Public(streams.HandleFunc("/hls/{path:.*}/{segment}", h.GetHLS).Methods("GET"))
+	h.Public(streams.HandleFunc("/files/paths", h.ListFilePaths).Methods("GET"))
+
+	// Protected routes moving files within the media directory and in and
+	// out of the trash, which copy them when TRASH_DIR is on another
+	// filesystem. Cutting that short would leave a move half done, so
+	// REQUEST_TIMEOUT doesn't apply.
+	trash := r.PathPrefix("/api").Subrouter()
+	trash.HandleFunc("/move", disabled.handler(routesMove, disabled.handler(routesMediaWrite, h.MoveFile))).Methods("POST")
+	trash.HandleFunc("/file/{path:.*}", disabled.handler(routesDelete, disabled.handler(routesMediaWrite, h.DeleteFile))).Methods("DELETE")
+	trash.HandleFunc("/files/delete", disabled.handler(routesDelete, disabled.handler(routesMediaWrite, h.DeleteFiles))).Methods("POST")
+	trash.HandleFunc("/trash/restore", disabled.handler(routesDelete, disabled.handler(routesMediaWrite, h.RestoreTrash))).Methods("POST")
 
 	// Protected API routes
 	api := r.PathPrefix("/api").Subrouter()
-	api.HandleFunc("/files", h.ListFiles).Methods("GET")
-	api.HandleFunc("/files/paths", h.ListFilePaths).Methods("GET")
-	api.HandleFunc("/file/{path:.*}", h.GetFile).Methods("GET")
-	api.HandleFunc("/media", h.GetMediaFiles).Methods("GET")
-	api.HandleFunc("/thumbnail/{path:.*}", h.GetThumbnail).Methods("GET")
-	api.HandleFunc("/playlists", h.ListPlaylists).Methods("GET")
-	api.HandleFunc("/playlist/{name}", h.GetPlaylist).Methods("GET")
-	api.HandleFunc("/stream/{path:.*}", h.StreamVideo).Methods("GET", "HEAD")
-	api.HandleFunc("/stream-info/{path:.*}", h.GetStreamInfo).Methods("GET")
-	api.HandleFunc("/search", h.Search).Methods("GET")
-	api.HandleFunc("/search/suggestions", h.SearchSuggestions).Methods("GET")
-	api.HandleFunc("/stats", h.GetStats).Methods("GET")
-	api.HandleFunc("/reindex", h.TriggerReindex).Methods("POST")
+	api.Use(middleware.Timeout(requestTimeout))
+	h.Public(api.HandleFunc("/files", h.ListFiles).Methods("GET"))
+	h.Public(api.HandleFunc("/files/check", h.CheckFiles).Methods("POST"))
+	h.Public(api.HandleFunc("/media", h.GetMediaFiles).Methods("GET"))
+	h.Public(api.HandleFunc("/folder/order", h.GetFolderOrder).Methods("GET"))
+	api.HandleFunc("/folder/order", h.SetFolderOrder).Methods("PUT")
+	api.HandleFunc("/file/note", h.SetFileNote).Methods("PUT")
+	api.HandleFunc("/file/sensitive", h.SetFileSensitive).Methods("PUT")
+	api.HandleFunc("/poster/{path:.*}", h.SetVideoPoster).Methods("PUT")
+	api.HandleFunc("/poster/{path:.*}", h.ClearVideoPoster).Methods("DELETE")
+	h.Public(api.HandleFunc("/thumbnail/{path:.*}", h.GetThumbnail).Methods("GET"))
+	h.Public(api.HandleFunc("/thumbnail-sizes/{path:.*}", h.GetThumbnailSizes).Methods("GET"))
+	h.Public(api.HandleFunc("/scrub/{path:.*}", h.GetScrubVTT).Methods("GET"))
+	h.Public(api.HandleFunc("/scrub-sprite/{path:.*}", h.GetScrubSprite).Methods("GET"))
+	h.Public(api.HandleFunc("/playlists", h.ListPlaylists).Methods("GET"))
+	h.Public(api.HandleFunc("/playlist/{name}", h.GetPlaylist).Methods("GET"))
+	h.Public(api.HandleFunc("/stream-info/{path:.*}", h.GetStreamInfo).Methods("GET"))
+	h.Public(api.HandleFunc("/search", h.Search).Methods("GET"))
+	h.Public(api.HandleFunc("/search/suggestions", h.SearchSuggestions).Methods("GET"))
+	h.Public(api.HandleFunc("/search/color", h.SearchByColor).Methods("GET"))
+	h.Public(api.HandleFunc("/facets/cameras", h.GetCameraFacets).Methods("GET"))
+	h.Public(api.HandleFunc("/facets/cameras/{name:.*}", h.GetFilesByCamera).Methods("GET"))
+	h.Public(api.HandleFunc("/facets/lenses", h.GetLensFacets).Methods("GET"))
+	h.Public(api.HandleFunc("/facets/lenses/{name:.*}", h.GetFilesByLens).Methods("GET"))
+	api.HandleFunc("/duplicates", h.GetDuplicates).Methods("GET")
+	h.Public(api.HandleFunc("/stats", h.GetStats).Methods("GET"))
+	h.Public(api.HandleFunc("/capabilities", disabled.handler(routesCapabilities, h.GetCapabilities)).Methods("GET"))
+	api.HandleFunc("/reindex", disabled.handler(routesReindex, h.TriggerReindex)).Methods("POST")
+	api.HandleFunc("/index/errors", h.GetIndexErrors).Methods("GET")
+	api.HandleFunc("/trash", h.ListTrash).Methods("GET")
 
 	// Favorites
-	api.HandleFunc("/favorites", h.GetFavorites).Methods("GET")
+	h.Public(api.HandleFunc("/favorites", h.GetFavorites).Methods("GET"))
 	api.HandleFunc("/favorites", h.AddFavorite).Methods("POST")
 	api.HandleFunc("/favorites", h.RemoveFavorite).Methods("DELETE")
 	api.HandleFunc("/favorites/bulk", h.BulkAddFavorites).Methods("POST")
 	api.HandleFunc("/favorites/bulk", h.BulkRemoveFavorites).Methods("DELETE")
-	api.HandleFunc("/favorites/check", h.CheckFavorite).Methods("GET")
+	h.Public(api.HandleFunc("/favorites/check", h.CheckFavorite).Methods("GET"))
 
 	// Tags
-	api.HandleFunc("/tags", h.GetAllTags).Methods("GET")
-	api.HandleFunc("/tags/stats", h.GetAllTagsWithCounts).Methods("GET")
+	h.Public(api.HandleFunc("/tags", h.GetAllTags).Methods("GET"))
+	h.Public(api.HandleFunc("/tags/stats", h.GetAllTagsWithCounts).Methods("GET"))
 	api.HandleFunc("/tags/unused", h.GetUnusedTags).Methods("GET")
-	api.HandleFunc("/tags/file", h.GetFileTags).Methods("GET")
+	h.Public(api.HandleFunc("/tags/file", h.GetFileTags).Methods("GET"))
 	api.HandleFunc("/tags/file", h.AddTagToFile).Methods("POST")
 	api.HandleFunc("/tags/file", h.RemoveTagFromFile).Methods("DELETE")
 	api.HandleFunc("/tags/file/set", h.SetFileTags).Methods("POST")
-	api.HandleFunc("/tags/batch", h.GetBatchFileTags).Methods("POST")
+	h.Public(api.HandleFunc("/tags/batch", h.GetBatchFileTags).Methods("POST"))
 	api.HandleFunc("/tags/bulk", h.BulkAddTag).Methods("POST")
 	api.HandleFunc("/tags/bulk", h.BulkRemoveTag).Methods("DELETE")
-	api.HandleFunc("/tags/{tag}", h.GetFilesByTag).Methods("GET")
-	api.HandleFunc("/tags/{tag}", h.DeleteTag).Methods("DELETE")
+	h.Public(api.HandleFunc("/tags/{tag}", h.GetFilesByTag).Methods("GET"))
+	api.HandleFunc("/tags/{tag}", disabled.handler(routesDelete, h.DeleteTag)).Methods("DELETE")
 	api.HandleFunc("/tags/{tag}", h.RenameTag).Methods("PUT")
 	api.HandleFunc("/tags/{tag}/rename", h.RenameTagEverywhere).Methods("POST")
-	api.HandleFunc("/tags/{tag}/delete", h.DeleteTagEverywhere).Methods("DELETE")
+	api.HandleFunc("/tags/{tag}/delete", disabled.handler(routesDelete, h.DeleteTagEverywhere)).Methods("DELETE")
+
+	// Collections
+	h.Public(api.HandleFunc("/collections", h.GetCollections).Methods("GET"))
+	api.HandleFunc("/collections", h.CreateCollection).Methods("POST")
+	h.Public(api.HandleFunc("/collections/{id}", h.GetCollection).Methods("GET"))
+	api.HandleFunc("/collections/{id}", h.RenameCollection).Methods("PUT")
+	api.HandleFunc("/collections/{id}", disabled.handler(routesDelete, h.DeleteCollection)).Methods("DELETE")
+	api.HandleFunc("/collections/{id}/items", h.AddToCollection).Methods("POST")
+	api.HandleFunc("/collections/{id}/items", h.RemoveFromCollection).Methods("DELETE")
+	api.HandleFunc("/collections/{id}/order", h.SetCollectionOrder).Methods("PUT")
+
+	// Favorites and tags export and import
+	api.HandleFunc("/export/curation", h.ExportCuration).Methods("GET")
+	api.HandleFunc("/import/curation", disabled.handler(routesImport, h.ImportCuration)).Methods("POST")
 
 	// Thumbnails
-	api.HandleFunc("/thumbnail/{path:.*}", h.GetThumbnail).Methods("GET")
-	api.HandleFunc("/thumbnail/{path:.*}", h.InvalidateThumbnail).Methods("DELETE")
-	api.HandleFunc("/thumbnails/invalidate", h.InvalidateAllThumbnails).Methods("POST")
-	api.HandleFunc("/thumbnails/rebuild", h.RebuildAllThumbnails).Methods("POST")
+	h.Public(api.HandleFunc("/thumbnail/{path:.*}", h.GetThumbnail).Methods("GET"))
+	api.HandleFunc("/thumbnail/{path:.*}", disabled.handler(routesInvalidate, h.InvalidateThumbnail)).Methods("DELETE")
+	api.HandleFunc("/thumbnails/invalidate", disabled.handler(routesInvalidate, h.InvalidateAllThumbnails)).Methods("POST")
+	api.HandleFunc("/thumbnails/rebuild", disabled.handler(routesRebuild, h.RebuildAllThumbnails)).Methods("POST")
 	api.HandleFunc("/thumbnails/status", h.GetThumbnailStatus).Methods("GET")
 
 	// Cache management
-	api.HandleFunc("/transcode/clear", h.ClearTranscodeCache).Methods("POST")
+	api.HandleFunc("/transcode/clear", disabled.handler(routesInvalidate, h.ClearTranscodeCache)).Methods("POST")
+
+	// Administration
+	api.HandleFunc("/admin/reload", disabled.handler(routesReload, h.ReloadConfig)).Methods("POST")
+	api.HandleFunc("/admin/cache/stats", h.GetCacheStats).Methods("GET")
+	api.HandleFunc("/admin/cache/flush", disabled.handler(routesInvalidate, h.FlushCaches)).Methods("POST")
+	api.HandleFunc("/admin/workers", h.GetWorkers).Methods("GET")
+	api.HandleFunc("/admin/fts/rebuild", disabled.handler(routesRebuild, h.RebuildFTS)).Methods("POST")
+	api.HandleFunc("/admin/fts/status", h.GetFTSRebuildStatus).Methods("GET")
+	api.HandleFunc("/admin/orientation/{path:.*}", h.GetImageOrientation).Methods("GET")
 
 	// Static files
-	r.PathPrefix("/").Handler(http.FileServer(http.Dir("./static")))
+	h.Public(r.PathPrefix("/").Handler(staticHandler("./static", spaFallback)))
 
 	return r
 }
 
+// applyReloadedConfig pushes reloaded settings to the running components.
+// THUMBNAIL_WORKERS and THUMBNAIL_INITIAL_WORKERS need no action: the
+// generator reads them for every batch.
+func applyReloadedConfig(result *startup.ReloadResult, idx *indexer.Indexer, thumbGen *media.ThumbnailGenerator, memMonitor *memory.Monitor, authLimiter *middleware.RateLimiter) {
+	if result.HasChanged("LOG_LEVEL") || result.HasChanged("DEBUG") {
+		logging.Info("Log level changed to %s", logging.ReloadLevel())
+	}
+	if result.HasChanged("LOG_SAMPLE_INTERVAL") {
+		logging.Info("Log sample interval changed to %v", logging.ReloadSampleInterval())
+	}
+
+	if result.HasChanged("INDEX_INTERVAL") {
+		idx.SetIndexInterval(result.IndexInterval)
+	}
+	if result.HasChanged("POLL_INTERVAL") {
+		idx.SetPollInterval(result.PollInterval)
+	}
+	if result.HasChanged("INDEX_QUIET_PERIOD") {
+		idx.SetQuietPeriod(result.IndexQuietPeriod)
+	}
+	if result.HasChanged("THUMBNAIL_INTERVAL") {
+		thumbGen.SetGenerationInterval(result.ThumbnailInterval)
+	}
+
+	if result.HasChanged("THUMBNAIL_VIDEO_SEEK") {
+		thumbGen.SetVideoSeekStrategy(parseVideoSeekStrategy(result.VideoThumbnailSeek))
+	}
+	if result.HasChanged("THUMBNAIL_SERVE_STALE") {
+		thumbGen.SetStaleWhileRevalidate(result.ServeStaleThumbnails)
+	}
+	if result.HasChanged("THUMBNAIL_FOLDER_FRAMES") {
+		thumbGen.SetFolderVideoFrames(result.FolderVideoFrames)
+	}
+	if result.HasChanged("THUMBNAIL_STYLE") {
+		thumbGen.SetThumbnailStyle(parseThumbnailStyle(result.ThumbnailStyle))
+	}
+	if result.HasChanged("THUMBNAIL_FORMAT") {
+		thumbGen.SetOutputFormat(parseThumbnailFormat(result.ThumbnailFormat))
+	}
+	if result.HasChanged("THUMBNAIL_SENSITIVE") {
+		thumbGen.SetSensitiveStyle(parseSensitiveStyle(result.ThumbnailSensitive))
+	}
+	if result.HasChanged("THUMBNAIL_OTHER_FILES") {
+		thumbGen.SetOtherThumbnailMode(parseOtherThumbnailMode(result.OtherThumbnails))
+	}
+	if result.HasChanged("THUMBNAIL_CHANGED_FILES") {
+		thumbGen.SetChangedFileMode(parseChangedFileMode(result.ChangedFiles))
+	}
+	if result.HasChanged("THUMBNAIL_LARGE_FILE_MB") || result.HasChanged("THUMBNAIL_LARGE_WORKERS") {
+		thumbGen.SetLargeFileDeferral(result.LargeFileThreshold, result.LargeFileWorkers)
+	}
+	if result.HasChanged("THUMBNAIL_CACHE_MAX_BYTES") {
+		thumbGen.SetCacheLimit(result.ThumbnailCacheMax)
+	}
+	if result.HasChanged("THUMBNAIL_FRESHNESS_INTERVAL") {
+		thumbGen.SetFreshnessInterval(result.ThumbnailFreshness)
+	}
+	if result.HasChanged("AUTH_RATE_LIMIT") {
+		authLimiter.SetLimit(result.AuthRateLimit)
+	}
+
+	if result.HasChanged("INDEX_WORKERS") {
+		idx.SetParallelConfig(indexer.DefaultParallelWalkerConfig())
+	}
+	if result.HasChanged("INDEX_BIRTHTIME") {
+		idx.SetBirthTimeIndexing(result.IndexBirthTime)
+	}
+	if result.HasChanged("INDEX_CAMERA") {
+		idx.SetCameraIndexing(result.IndexCamera)
+	}
+	if result.HasChanged("INDEX_EXIF") {
+		idx.SetExifDateIndexing(result.IndexExif)
+	}
+	if result.HasChanged("INDEX_XMP") {
+		idx.SetSidecarImport(result.IndexXMP)
+	}
+	if result.HasChanged("INDEX_STRICT_PERMISSIONS") {
+		idx.SetStrictPermissions(result.IndexStrictPerm)
+	}
+	if result.HasChanged("INDEX_DUPLICATES") {
+		idx.SetDuplicateHashing(result.IndexDuplicates)
+	}
+	if result.HasChanged("INDEX_HASH_MODE") || result.HasChanged("INDEX_HASH_ALGORITHM") {
+		idx.SetHashOptions(parseHashOptions(result.IndexHashMode, result.IndexHashAlgorithm))
+	}
+	if result.HasChanged("INDEX_PROGRESS_INTERVAL") {
+		idx.SetProgressInterval(result.IndexProgressInterval)
+	}
+	if result.HasChanged("INDEX_WORKERS") || result.HasChanged("THUMBNAIL_WORKERS") ||
+		result.HasChanged("THUMBNAIL_INITIAL_WORKERS") || result.HasChanged("THUMBNAIL_LARGE_WORKERS") {
+		logWorkerCounts(idx, thumbGen)
+	}
+
+	if result.HasChanged("MEMORY_LIMIT") || result.HasChanged("MEMORY_RATIO") ||
+		result.HasChanged("MEMORY_RESERVE_TRANSCODES") || result.HasChanged("MEMORY_TRANSCODE_BYTES") {
+		memResult := memory.ReconfigureFromEnv()
+		memMonitor.SetLimit(memResult.GoMemLimit)
+	}
+}
+
+// logWorkerCounts logs the worker counts the indexer and thumbnail generator
+// use, which THUMBNAIL_WORKERS and the container's CPU limit decide
+func logWorkerCounts(idx *indexer.Indexer, thumbGen *media.ThumbnailGenerator) {
+	thumbs := thumbGen.WorkerCounts()
+	source := fmt.Sprintf("%.1f per CPU, max %d", thumbs.Multiplier, thumbs.Limit)
+	if thumbs.Override {
+		source = "THUMBNAIL_WORKERS"
+	}
+	logging.Info("Worker counts: GOMAXPROCS=%d (%d CPUs on host), index=%d, thumbnails=%d (%s), initial thumbnails=%d, large files=%d",
+		runtime.GOMAXPROCS(0), runtime.NumCPU(), idx.Workers(), thumbs.Count, source, thumbs.Initial, thumbs.LargeFiles)
+}
+
+// parseVideoSeekStrategy parses THUMBNAIL_VIDEO_SEEK, falling back to the
+// default strategy if the value is invalid
+func parseVideoSeekStrategy(value string) media.VideoSeekStrategy {
+	strategy, err := media.ParseVideoSeekStrategy(value)
+	if err != nil {
+		strategy = media.DefaultVideoSeekStrategy()
+		logging.Warn("Invalid THUMBNAIL_VIDEO_SEEK: %v, using default: %s", err, strategy)
+	}
+	return strategy
+}
+
+// thumbnailSize clamps THUMBNAIL_SIZE to the supported range, logging a
+// warning if it was outside it
+func thumbnailSize(size int) int {
+	clamped := media.ClampThumbnailSize(size)
+	if clamped != size {
+		logging.Warn("THUMBNAIL_SIZE %d is outside %d-%d, using %d", size, media.MinThumbnailSize, media.MaxThumbnailSize, clamped)
+	}
+	return clamped
+}
+
+// parseThumbnailSizes parses THUMBNAIL_VARIANT_SIZES, serving only the
+// regular size if the value is invalid
+func parseThumbnailSizes(value string) []int {
+	sizes, err := media.ParseThumbnailSizes(value)
+	if err != nil {
+		logging.Warn("Invalid THUMBNAIL_VARIANT_SIZES: %v, thumbnail size variants disabled", err)
+		return nil
+	}
+	return sizes
+}
+
+// parseThumbnailStyle parses THUMBNAIL_STYLE, leaving thumbnails unstyled
+// if the value is invalid
+func parseThumbnailStyle(value string) media.ThumbnailStyle {
+	style, err := media.ParseThumbnailStyle(value)
+	if err != nil {
+		logging.Warn("Invalid THUMBNAIL_STYLE: %v, thumbnails will not be styled", err)
+	}
+	return style
+}
+
+// parseThumbnailFormat parses THUMBNAIL_FORMAT, encoding no output format if
+// the value is invalid
+func parseThumbnailFormat(value string) media.ThumbnailFormat {
+	format, err := media.ParseOutputFormat(value)
+	if err != nil {
+		logging.Warn("Invalid THUMBNAIL_FORMAT: %v, using jpeg", err)
+	}
+	return format
+}
+
+// parseSensitiveStyle parses THUMBNAIL_SENSITIVE, blurring if the value is
+// invalid
+func parseSensitiveStyle(value string) media.SensitiveStyle {
+	style, err := media.ParseSensitiveStyle(value)
+	if err != nil {
+		logging.Warn("Invalid THUMBNAIL_SENSITIVE: %v, using blur", err)
+		return media.SensitiveBlur
+	}
+	return style
+}
+
+// parseOtherThumbnailMode parses THUMBNAIL_OTHER_FILES, drawing no
+// thumbnails for other files if the value is invalid
+func parseOtherThumbnailMode(value string) media.OtherThumbnailMode {
+	mode, err := media.ParseOtherThumbnailMode(value)
+	if err != nil {
+		mode = media.OtherThumbnailsOff
+		logging.Warn("Invalid THUMBNAIL_OTHER_FILES: %v, using %s", err, mode)
+	}
+	return mode
+}
+
+// parseChangedFileMode parses THUMBNAIL_CHANGED_FILES, retrying files that
+// change during generation if the value is invalid
+func parseChangedFileMode(value string) media.ChangedFileMode {
+	mode, err := media.ParseChangedFileMode(value)
+	if err != nil {
+		mode = media.ChangedFilesRetry
+		logging.Warn("Invalid THUMBNAIL_CHANGED_FILES: %v, using %s", err, mode)
+	}
+	return mode
+}
+
+// parseHashOptions parses INDEX_HASH_MODE and INDEX_HASH_ALGORITHM, using
+// the default for either if it's invalid
+func parseHashOptions(mode, algorithm string) filesystem.HashOptions {
+	var opts filesystem.HashOptions
+	var err error
+	if opts.Mode, err = filesystem.ParseHashMode(mode); err != nil {
+		logging.Warn("Invalid INDEX_HASH_MODE: %v, using %s", err, opts.Mode)
+	}
+	if opts.Algorithm, err = filesystem.ParseHashAlgorithm(algorithm); err != nil {
+		logging.Warn("Invalid INDEX_HASH_ALGORITHM: %v, using %s", err, opts.Algorithm)
+	}
+	return opts
+}
+
+// parseAccessLogFormat parses ACCESS_LOG_FORMAT, using the W3C format if the
+// value is invalid
+func parseAccessLogFormat(value string) middleware.LogFormat {
+	format, err := middleware.ParseLogFormat(value)
+	if err != nil {
+		logging.Warn("Invalid ACCESS_LOG_FORMAT: %v, using %s", err, format)
+	}
+	return format
+}
+
+// parseTrustedProxies parses TRUSTED_PROXIES, leaving out invalid entries
+func parseTrustedProxies(value string) middleware.TrustedProxies {
+	proxies, err := middleware.ParseTrustedProxies(value)
+	if err != nil {
+		logging.Warn("Invalid TRUSTED_PROXIES: %v, ignoring them", err)
+	}
+	return proxies
+}
+
+// parseTranscodePreset parses TRANSCODE_PRESET, using the default preset if
+// the value is invalid
+func parseTranscodePreset(value string) string {
+	preset, err := transcoder.ParseX264Preset(value)
+	if err != nil {
+		logging.Warn("Invalid TRANSCODE_PRESET: %v, using %s", err, transcoder.DefaultX264Preset)
+		return transcoder.DefaultX264Preset
+	}
+	return preset
+}
+
+// transcodeCRF clamps TRANSCODE_CRF to the range libx264 accepts, logging a
+// warning if it was outside it
+func transcodeCRF(crf int) int {
+	clamped := transcoder.ClampX264CRF(crf)
+	if clamped != crf {
+		logging.Warn("TRANSCODE_CRF %d is outside %d-%d, using %d", crf, transcoder.MinX264CRF, transcoder.MaxX264CRF, clamped)
+	}
+	return clamped
+}
+
+// parseWidthLadder parses TRANSCODE_WIDTH_LADDER, disabling width snapping
+// if the value is invalid
+func parseWidthLadder(value string) []int {
+	ladder, err := transcoder.ParseWidthLadder(value)
+	if err != nil {
+		logging.Warn("Invalid TRANSCODE_WIDTH_LADDER: %v, width snapping disabled", err)
+		return nil
+	}
+	return ladder
+}
+
+// checkpointWAL truncates the WAL once indexing has finished writing, so it
+// doesn't grow until an automatic checkpoint lands mid-request
+func checkpointWAL(ctx context.Context, db *database.Database) {
+	start := time.Now()
+	result, err := db.CheckpointWAL(ctx)
+	switch {
+	case err != nil:
+		logging.Warn("WAL checkpoint failed: %v", err)
+	case result.Busy:
+		logging.Debug("WAL checkpoint incomplete (database busy): %d of %d pages written", result.Checkpointed, result.LogPages)
+	default:
+		logging.Debug("WAL checkpoint complete: %d pages in %v", result.Checkpointed, time.Since(start))
+	}
+}
+
 func handleShutdown(srv, metricsSrv *http.Server, db *database.Database, idx *indexer.Indexer, trans *transcoder.Transcoder, thumbGen *media.ThumbnailGenerator, metricsCollector *metrics.Collector, memMonitor *memory.Monitor, webAuthnEnabled bool, done chan struct{}) {
 	defer close(done)
 
@@ -384,12 +917,22 @@ func handleShutdown(srv, metricsSrv *http.Server, db *database.Database, idx *in
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Before the transcoder is cleaned up, which would cut its streams off
+	startup.LogShutdownStep("Draining active streams")
+	drainCtx, drainCancel := context.WithTimeout(ctx, streamDrainTimeout)
+	if remaining := streaming.DrainAll(drainCtx); remaining > 0 {
+		logging.Warn("%d streams still open after %v", remaining, streamDrainTimeout)
+	} else {
+		startup.LogShutdownStepComplete("Streams drained")
+	}
+	drainCancel()
+
 	startup.LogShutdownStep("Stopping metrics collector")
 	metricsCollector.Stop()
 	startup.LogShutdownStepComplete("Metrics collector stopped")
 
 	startup.LogShutdownStep("Stopping thumbnail generator")
-	thumbGen.Stop()
+	thumbGen.Close()
 	startup.LogShutdownStepComplete("Thumbnail generator stopped")
 
 	startup.LogShutdownStep("Stopping indexer")
@@ -436,9 +979,54 @@ func handleShutdown(srv, metricsSrv *http.Server, db *database.Database, idx *in
 		startup.LogShutdownStepComplete("Database closed")
 	}
 
+	// Report any warnings still held back by log sampling
+	logging.FlushSampled()
+
 	startup.LogShutdownComplete()
 }
 
+// staticHandler serves the files in dir. With spaFallback, a GET for a page
+// of the app that isn't a file gets index.html instead of a 404, so deep
+// links work on reload; missing API endpoints and assets still 404.
+func staticHandler(dir string, spaFallback bool) http.Handler {
+	files := http.FileServer(http.Dir(dir))
+	if !spaFallback {
+		return files
+	}
+
+	index := path.Join(dir, "index.html")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAppRoute(r) && !staticFileExists(dir, r.URL.Path) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			http.ServeFile(w, r, index)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// isAppRoute reports whether a request may be for one of the app's own
+// routes: a GET outside the API for a path without a file extension
+func isAppRoute(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/api/") {
+		return false
+	}
+	return path.Ext(r.URL.Path) == ""
+}
+
+// staticFileExists reports whether a URL path names a file or directory in dir
+func staticFileExists(dir, urlPath string) bool {
+	f, err := http.Dir(dir).Open(path.Clean("/" + urlPath))
+	if err != nil {
+		return false
+	}
+	_ = f.Close()
+	return true
+}
+
 // serveStaticFile returns a handler that serves a specific static file with the given content type
 func serveStaticFile(filepath, contentType string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
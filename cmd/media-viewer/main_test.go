@@ -1,13 +1,23 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"media-viewer/internal/database"
+	"media-viewer/internal/handlers"
 	"media-viewer/internal/metrics"
+	"media-viewer/internal/middleware"
+	"media-viewer/internal/startup"
+
+	"github.com/gorilla/mux"
 )
 
 // mockStatsDatabase implements the GetStats method needed by dbStatsAdapter
@@ -155,6 +165,49 @@ func TestServeStaticFile(t *testing.T) {
 	}
 }
 
+func TestStaticHandler(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "js"), 0o755); err != nil {
+		t.Fatalf("Failed to create js dir: %v", err)
+	}
+	for name, content := range map[string]string{"index.html": "<html>app</html>", "js/app.js": "app()"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		fallback   bool
+		wantStatus int
+		wantBody   string
+	}{
+		{"existing asset", "GET", "/js/app.js", true, http.StatusOK, "app()"},
+		{"app route", "GET", "/folder/holiday", true, http.StatusOK, "<html>app</html>"},
+		{"app route without fallback", "GET", "/folder/holiday", false, http.StatusNotFound, ""},
+		{"missing asset", "GET", "/js/missing.js", true, http.StatusNotFound, ""},
+		{"missing API route", "GET", "/api/missing", true, http.StatusNotFound, ""},
+		{"non-GET request", "POST", "/folder/holiday", true, http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, http.NoBody)
+			w := httptest.NewRecorder()
+			staticHandler(dir, tt.fallback).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestSetupRouter(t *testing.T) {
 	// Note: This requires a handlers.Handlers instance which needs database, etc.
 	// For now, we test that the function signature is correct and can be called
@@ -168,6 +221,190 @@ func TestSetupRouter(t *testing.T) {
 	})
 }
 
+func TestParseDisabledRoutes(t *testing.T) {
+	disabled := parseDisabledRoutes(" Reindex,rebuild,,bogus ")
+
+	want := disabledRoutes{routesReindex: true, routesRebuild: true}
+	if len(disabled) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, disabled)
+	}
+	for group := range want {
+		if !disabled[group] {
+			t.Errorf("Expected %s to be disabled", group)
+		}
+	}
+
+	if len(parseDisabledRoutes("")) != 0 {
+		t.Error("Expected no disabled groups for an empty value")
+	}
+}
+
+func TestSetupRouterDisabledRoutes(t *testing.T) {
+	// Disabled routes never reach the handlers, so they can be left unset
+	router := setupRouter(&handlers.Handlers{}, middleware.NewRateLimiter(0, nil), time.Second, false, parseDisabledRoutes("reindex,delete,move,capabilities"))
+
+	for _, tt := range []struct{ method, path string }{
+		{http.MethodPost, "/api/reindex"},
+		{http.MethodDelete, "/api/tags/holiday/delete"},
+		{http.MethodDelete, "/api/collections/3"},
+		{http.MethodGet, "/api/capabilities"},
+		{http.MethodPost, "/api/move"},
+		{http.MethodDelete, "/api/file/photos/beach.jpg"},
+		{http.MethodPost, "/api/files/delete"},
+		{http.MethodPost, "/api/trash/restore"},
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("%s %s: expected 404, got %d", tt.method, tt.path, rr.Code)
+		}
+	}
+
+	// Routes in other groups are still served
+	var match mux.RouteMatch
+	req := httptest.NewRequest(http.MethodPost, "/api/thumbnails/rebuild", nil)
+	if !router.Match(req, &match) {
+		t.Fatal("Expected POST /api/thumbnails/rebuild to be routed")
+	}
+	if template, _ := match.Route.GetPathTemplate(); template != "/api/thumbnails/rebuild" {
+		t.Errorf("Expected POST /api/thumbnails/rebuild to reach its own route, got %q", template)
+	}
+}
+
+func TestSetupRouterMediaWriteDisabled(t *testing.T) {
+	disabled := parseDisabledRoutes("")
+	disabled[routesMediaWrite] = true
+	router := setupRouter(&handlers.Handlers{}, middleware.NewRateLimiter(0, nil), time.Second, false, disabled)
+
+	for _, tt := range []struct{ method, path string }{
+		{http.MethodPost, "/api/move"},
+		{http.MethodDelete, "/api/file/photos/beach.jpg"},
+		{http.MethodPost, "/api/files/delete"},
+		{http.MethodPost, "/api/trash/restore"},
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("%s %s: expected 404, got %d", tt.method, tt.path, rr.Code)
+		}
+	}
+
+	if len(parseDisabledRoutes(string(routesMediaWrite))) != 0 {
+		t.Error("Expected DISABLED_ROUTES not to accept the media write group")
+	}
+}
+
+// loginOnlyReadRoutes are the routes serving GET that anonymous visitors
+// can't use in public mode. Every other route serving GET must be marked
+// public in setupRouter.
+var loginOnlyReadRoutes = map[string]bool{
+	"/api/auth/webauthn/passkeys":      true,
+	"/api/auth/webauthn/credentials":   true,
+	"/api/duplicates":                  true,
+	"/api/index/errors":                true,
+	"/api/trash":                       true,
+	"/api/tags/unused":                 true,
+	"/api/export/curation":             true,
+	"/api/thumbnails/status":           true,
+	"/api/admin/cache/stats":           true,
+	"/api/admin/workers":               true,
+	"/api/admin/fts/status":            true,
+	"/api/admin/orientation/{path:.*}": true,
+}
+
+func TestSetupRouterClassifiesReadRoutes(t *testing.T) {
+	h := &handlers.Handlers{}
+	router := setupRouter(h, middleware.NewRateLimiter(0, nil), time.Second, false, parseDisabledRoutes(""))
+
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil // A subrouter's prefix
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+
+		// Routes without a method restriction serve GET too
+		methods, err := route.GetMethods()
+		if err == nil && !slices.Contains(methods, http.MethodGet) {
+			if h.IsPublic(route) && !slices.Contains(methods, http.MethodPost) {
+				t.Errorf("%s %v: only GET and read-only POST routes can be public", template, methods)
+			}
+			return nil
+		}
+
+		switch public := h.IsPublic(route); {
+		case public && loginOnlyReadRoutes[template]:
+			t.Errorf("GET %s is public but listed as login-only", template)
+		case !public && !loginOnlyReadRoutes[template]:
+			t.Errorf("GET %s is neither public nor listed as login-only; mark it with h.Public or add it to loginOnlyReadRoutes", template)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+}
+
+// flushRecorder records the body sent by each Flush
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed []string
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushed = append(f.flushed, f.Body.String())
+	f.ResponseRecorder.Flush()
+}
+
+func TestSetupRouterStreamsFilePaths(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	db, _, err := database.New(ctx, filepath.Join(t.TempDir(), "test.db"), &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	tx, err := db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("Failed to begin batch: %v", err)
+	}
+	for _, name := range []string{"a.jpg", "b.jpg"} {
+		file := &database.MediaFile{Name: name, Path: "trip/" + name, ParentPath: "trip", Type: database.FileTypeImage, ModTime: time.Now()}
+		if err := db.UpsertFile(ctx, tx, file); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+	}
+	if err := db.EndBatch(tx, nil); err != nil {
+		t.Fatalf("Failed to end batch: %v", err)
+	}
+
+	h := handlers.New(db, nil, nil, nil, &startup.Config{MediaDir: t.TempDir()})
+	router := setupRouter(h, middleware.NewRateLimiter(0, nil), time.Minute, false, parseDisabledRoutes(""))
+
+	// REQUEST_TIMEOUT would buffer the whole response, so nothing would be
+	// flushed before the handler returned
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/files/paths?path=trip&recursive=true", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(w.flushed) == 0 {
+		t.Fatal("Expected the listing to be flushed as it streams")
+	}
+	first := w.flushed[0]
+	if !strings.Contains(first, `"trip/a.jpg"`) || strings.Contains(first, "totalItems") {
+		t.Errorf("Expected the first flush to hold the first item only, got %q", first)
+	}
+	if !strings.HasSuffix(w.Body.String(), `"totalItems":2}`) {
+		t.Errorf("Expected the complete listing, got %q", w.Body.String())
+	}
+}
+
 func TestServerTimeouts(t *testing.T) {
 	// Test that server timeouts are configured reasonably
 	// This is a documentation test for the expected values
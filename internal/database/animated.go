@@ -0,0 +1,22 @@
+package database
+
+import (
+	"context"
+)
+
+// SetFileAnimated records whether an indexed image is animated. The flag is
+// cleared by UpsertFile whenever the file's size or modification time
+// changes, until it is recorded again.
+func (d *Database) SetFileAnimated(ctx context.Context, filePath string, animated bool) error {
+	done := observeQuery("set_file_animated")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, "UPDATE files SET animated = ? WHERE path = ?", animated, filePath)
+	done(err)
+	return err
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetFileAnimatedIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	upsert := func(files ...MediaFile) {
+		t.Helper()
+		tx, err := db.BeginBatch(ctx)
+		if err != nil {
+			t.Fatalf("BeginBatch failed: %v", err)
+		}
+		for i := range files {
+			if err := db.UpsertFile(ctx, tx, &files[i]); err != nil {
+				t.Fatalf("UpsertFile failed: %v", err)
+			}
+		}
+		if err := db.EndBatch(tx, nil); err != nil {
+			t.Fatalf("EndBatch failed: %v", err)
+		}
+	}
+	animated := func() map[string]bool {
+		t.Helper()
+		listing, err := db.ListDirectory(ctx, ListOptions{Path: "gifs", Page: 1, PageSize: 10})
+		if err != nil {
+			t.Fatalf("ListDirectory failed: %v", err)
+		}
+		media, err := db.GetMediaInDirectory(ctx, "gifs", SortByName, SortAsc)
+		if err != nil {
+			t.Fatalf("GetMediaInDirectory failed: %v", err)
+		}
+		flags := make(map[string]bool)
+		for i, item := range listing.Items {
+			if media[i].Animated != item.Animated {
+				t.Errorf("%s: GetMediaInDirectory animated = %v, ListDirectory %v", item.Path, media[i].Animated, item.Animated)
+			}
+			flags[item.Path] = item.Animated
+		}
+		return flags
+	}
+
+	dance := MediaFile{Name: "dance.gif", Path: "gifs/dance.gif", ParentPath: "gifs", Type: FileTypeImage, Size: 100, ModTime: modTime}
+	still := MediaFile{Name: "still.gif", Path: "gifs/still.gif", ParentPath: "gifs", Type: FileTypeImage, Size: 100, ModTime: modTime}
+	upsert(dance, still)
+
+	if err := db.SetFileAnimated(ctx, "gifs/dance.gif", true); err != nil {
+		t.Fatalf("SetFileAnimated failed: %v", err)
+	}
+	if got := animated(); !got["gifs/dance.gif"] || got["gifs/still.gif"] {
+		t.Errorf("Expected only dance.gif to be animated, got %v", got)
+	}
+
+	// Re-indexing an unchanged file keeps the flag
+	upsert(dance)
+	if got := animated(); !got["gifs/dance.gif"] {
+		t.Errorf("Expected the flag to survive re-indexing, got %v", got)
+	}
+
+	// A changed file is no longer known to be animated
+	dance.ModTime = modTime.Add(time.Minute)
+	upsert(dance)
+	if got := animated(); got["gifs/dance.gif"] {
+		t.Errorf("Expected the flag to be cleared when the file changed, got %v", got)
+	}
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSortByCreatedIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	base := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+
+	// b.jpg was edited recently but created first; c.jpg has no birth time
+	// and falls back to its modification time
+	files := []MediaFile{
+		{Name: "a.jpg", Path: "album/a.jpg", ParentPath: "album", Type: FileTypeImage, ModTime: base.Add(3 * time.Hour), BirthTime: base.Add(2 * time.Hour)},
+		{Name: "b.jpg", Path: "album/b.jpg", ParentPath: "album", Type: FileTypeImage, ModTime: base.Add(5 * time.Hour), BirthTime: base},
+		{Name: "c.mp4", Path: "album/c.mp4", ParentPath: "album", Type: FileTypeVideo, ModTime: base.Add(time.Hour)},
+	}
+	tx, err := db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	for i := range files {
+		if err := db.UpsertFile(ctx, tx, &files[i]); err != nil {
+			t.Fatalf("UpsertFile failed: %v", err)
+		}
+	}
+	if err := db.EndBatch(tx, nil); err != nil {
+		t.Fatalf("EndBatch failed: %v", err)
+	}
+
+	expected := []string{"album/b.jpg", "album/c.mp4", "album/a.jpg"}
+
+	listing, err := db.ListDirectory(ctx, ListOptions{Path: "album", SortField: SortByCreated, SortOrder: SortAsc, Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("ListDirectory failed: %v", err)
+	}
+	if got := listingPaths(listing.Items); !slices.Equal(got, expected) {
+		t.Errorf("ListDirectory by created = %v, want %v", got, expected)
+	}
+
+	media, err := db.GetMediaInDirectory(ctx, "album", SortByCreated, SortDesc)
+	if err != nil {
+		t.Fatalf("GetMediaInDirectory failed: %v", err)
+	}
+	slices.Reverse(expected)
+	if got := listingPaths(media); !slices.Equal(got, expected) {
+		t.Errorf("GetMediaInDirectory by created desc = %v, want %v", got, expected)
+	}
+
+	// Sorting by date still uses the modification time
+	listing, err = db.ListDirectory(ctx, ListOptions{Path: "album", SortField: SortByDate, SortOrder: SortAsc, Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("ListDirectory failed: %v", err)
+	}
+	if got := listingPaths(listing.Items); !slices.Equal(got, []string{"album/c.mp4", "album/a.jpg", "album/b.jpg"}) {
+		t.Errorf("ListDirectory by date = %v", got)
+	}
+}
+
+func TestBirthTimeMigrationIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+
+	db, _, err := New(ctx, dbPath, nil)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	// Simulate a database created before the column existed
+	if _, err := db.db.ExecContext(ctx, "ALTER TABLE files DROP COLUMN birth_time"); err != nil {
+		t.Fatalf("Failed to drop birth_time column: %v", err)
+	}
+	db.Close()
+
+	db, _, err = New(ctx, dbPath, nil)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	var exists bool
+	if err := db.db.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM pragma_table_info('files') WHERE name='birth_time'").Scan(&exists); err != nil {
+		t.Fatalf("Failed to check column: %v", err)
+	}
+	if !exists {
+		t.Error("Expected the migration to add the birth_time column")
+	}
+}
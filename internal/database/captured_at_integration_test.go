@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSortByCapturedIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	base := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+
+	// a.jpg was copied in last, after the photos it was taken before; c.mp4
+	// has no capture date and falls back to its modification time
+	files := []MediaFile{
+		{Name: "a.jpg", Path: "trip/a.jpg", ParentPath: "trip", Type: FileTypeImage, ModTime: base.Add(5 * time.Hour), CapturedAt: base},
+		{Name: "b.jpg", Path: "trip/b.jpg", ParentPath: "trip", Type: FileTypeImage, ModTime: base.Add(4 * time.Hour), CapturedAt: base.Add(3 * time.Hour)},
+		{Name: "c.mp4", Path: "trip/c.mp4", ParentPath: "trip", Type: FileTypeVideo, ModTime: base.Add(time.Hour)},
+	}
+	tx, err := db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	for i := range files {
+		if err := db.UpsertFile(ctx, tx, &files[i]); err != nil {
+			t.Fatalf("UpsertFile failed: %v", err)
+		}
+	}
+	if err := db.EndBatch(tx, nil); err != nil {
+		t.Fatalf("EndBatch failed: %v", err)
+	}
+
+	expected := []string{"trip/a.jpg", "trip/c.mp4", "trip/b.jpg"}
+
+	listing, err := db.ListDirectory(ctx, ListOptions{Path: "trip", SortField: SortByCaptured, SortOrder: SortAsc, Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("ListDirectory failed: %v", err)
+	}
+	if got := listingPaths(listing.Items); !slices.Equal(got, expected) {
+		t.Errorf("ListDirectory by captured = %v, want %v", got, expected)
+	}
+
+	media, err := db.GetMediaInDirectory(ctx, "trip", SortByCaptured, SortDesc)
+	if err != nil {
+		t.Fatalf("GetMediaInDirectory failed: %v", err)
+	}
+	slices.Reverse(expected)
+	if got := listingPaths(media); !slices.Equal(got, expected) {
+		t.Errorf("GetMediaInDirectory by captured desc = %v, want %v", got, expected)
+	}
+}
+
+func TestCapturedAtMigrationIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+
+	db, _, err := New(ctx, dbPath, nil)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	// Simulate a database created before the column existed
+	if _, err := db.db.ExecContext(ctx, "ALTER TABLE files DROP COLUMN captured_at"); err != nil {
+		t.Fatalf("Failed to drop captured_at column: %v", err)
+	}
+	db.Close()
+
+	db, _, err = New(ctx, dbPath, nil)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	var exists bool
+	if err := db.db.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM pragma_table_info('files') WHERE name='captured_at'").Scan(&exists); err != nil {
+		t.Fatalf("Failed to check column: %v", err)
+	}
+	if !exists {
+		t.Error("Expected the migration to add the captured_at column")
+	}
+}
@@ -0,0 +1,634 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"media-viewer/internal/logging"
+)
+
+var (
+	// ErrCollectionNotFound is returned for a collection ID that doesn't exist.
+	ErrCollectionNotFound = errors.New("collection not found")
+
+	// ErrCollectionExists is returned when creating or renaming a collection
+	// to the name of another one. Names are compared case-insensitively.
+	ErrCollectionExists = errors.New("a collection with that name already exists")
+
+	// ErrInvalidCollectionName is returned for an empty collection name.
+	ErrInvalidCollectionName = errors.New("collection name cannot be empty")
+
+	// ErrInvalidCollectionOrder is returned by SetCollectionOrder for paths
+	// that aren't in the collection or are listed more than once.
+	ErrInvalidCollectionOrder = errors.New("invalid collection order")
+)
+
+// collectionColumns selects a collection with the number of its items that
+// are currently indexed, for scanCollection. The query must join collection
+// items as ci and files as f, and group by c.id.
+const collectionColumns = `c.id, c.name, c.created_at, c.updated_at, COUNT(f.id)`
+
+// CreateCollection creates an empty collection.
+func (d *Database) CreateCollection(ctx context.Context, name string) (*Collection, error) {
+	done := observeQuery("create_collection")
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		done(ErrInvalidCollectionName)
+		return nil, ErrInvalidCollectionName
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if err := d.checkCollectionNameUnlocked(ctx, name, 0); err != nil {
+		done(err)
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	result, err := d.db.ExecContext(ctx,
+		"INSERT INTO collections (name, created_at, updated_at) VALUES (?, ?, ?)",
+		name, now, now,
+	)
+	if err != nil {
+		err = fmt.Errorf("failed to create collection: %w", err)
+		done(err)
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+
+	done(nil)
+	return &Collection{
+		ID:        id,
+		Name:      name,
+		CreatedAt: time.Unix(now, 0),
+		UpdatedAt: time.Unix(now, 0),
+	}, nil
+}
+
+// RenameCollection renames a collection. Changing only the case of its name
+// is allowed.
+func (d *Database) RenameCollection(ctx context.Context, id int64, name string) error {
+	done := observeQuery("rename_collection")
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		done(ErrInvalidCollectionName)
+		return ErrInvalidCollectionName
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if err := d.checkCollectionNameUnlocked(ctx, name, id); err != nil {
+		done(err)
+		return err
+	}
+
+	result, err := d.db.ExecContext(ctx,
+		"UPDATE collections SET name = ?, updated_at = ? WHERE id = ?",
+		name, time.Now().Unix(), id,
+	)
+	if err != nil {
+		err = fmt.Errorf("failed to rename collection: %w", err)
+		done(err)
+		return err
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		done(ErrCollectionNotFound)
+		return ErrCollectionNotFound
+	}
+
+	done(nil)
+	return nil
+}
+
+// checkCollectionNameUnlocked returns ErrCollectionExists if a collection
+// other than exceptID is named name.
+// Caller must hold the write lock.
+func (d *Database) checkCollectionNameUnlocked(ctx context.Context, name string, exceptID int64) error {
+	var existingID int64
+	err := d.db.QueryRowContext(ctx,
+		"SELECT id FROM collections WHERE name = ? COLLATE NOCASE",
+		name,
+	).Scan(&existingID)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to check collection name: %w", err)
+	case existingID != exceptID:
+		return ErrCollectionExists
+	default:
+		return nil
+	}
+}
+
+// DeleteCollection removes a collection and its items. The files themselves
+// are untouched.
+func (d *Database) DeleteCollection(ctx context.Context, id int64) error {
+	done := observeQuery("delete_collection")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		done(err)
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// Foreign keys aren't enforced on every connection, so don't rely on the cascade
+	if _, err := tx.ExecContext(ctx, "DELETE FROM collection_items WHERE collection_id = ?", id); err != nil {
+		err = fmt.Errorf("failed to delete collection items: %w", err)
+		done(err)
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM collections WHERE id = ?", id)
+	if err != nil {
+		err = fmt.Errorf("failed to delete collection: %w", err)
+		done(err)
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		done(ErrCollectionNotFound)
+		return ErrCollectionNotFound
+	}
+
+	err = tx.Commit()
+	done(err)
+	return err
+}
+
+// GetCollections returns all collections, sorted by name.
+func (d *Database) GetCollections(ctx context.Context) ([]Collection, error) {
+	done := observeQuery("get_collections")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		SELECT ` + collectionColumns + `
+		FROM collections c
+		LEFT JOIN collection_items ci ON ci.collection_id = c.id
+		LEFT JOIN files f ON f.path = ci.file_path
+		GROUP BY c.id
+		ORDER BY c.name COLLATE NOCASE
+	`
+
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		err = fmt.Errorf("failed to get collections: %w", err)
+		done(err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.Error("error closing rows in GetCollections: %v", err)
+		}
+	}()
+
+	collections := []Collection{}
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			done(err)
+			return nil, err
+		}
+		collections = append(collections, *collection)
+	}
+
+	err = rows.Err()
+	done(err)
+	return collections, err
+}
+
+// GetCollection returns a collection by ID, or ErrCollectionNotFound.
+func (d *Database) GetCollection(ctx context.Context, id int64) (*Collection, error) {
+	done := observeQuery("get_collection")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	collection, err := d.getCollectionUnlocked(ctx, id)
+	done(err)
+	return collection, err
+}
+
+// getCollectionUnlocked returns a collection by ID, or ErrCollectionNotFound.
+// Caller must hold at least a read lock.
+func (d *Database) getCollectionUnlocked(ctx context.Context, id int64) (*Collection, error) {
+	query := `
+		SELECT ` + collectionColumns + `
+		FROM collections c
+		LEFT JOIN collection_items ci ON ci.collection_id = c.id
+		LEFT JOIN files f ON f.path = ci.file_path
+		WHERE c.id = ?
+		GROUP BY c.id
+	`
+
+	collection, err := scanCollection(d.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCollectionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	return collection, nil
+}
+
+// scanCollection scans a row selected with collectionColumns
+func scanCollection(row interface{ Scan(...any) error }) (*Collection, error) {
+	var collection Collection
+	var createdAt, updatedAt int64
+	if err := row.Scan(&collection.ID, &collection.Name, &createdAt, &updatedAt, &collection.ItemCount); err != nil {
+		return nil, err
+	}
+	collection.CreatedAt = time.Unix(createdAt, 0)
+	collection.UpdatedAt = time.Unix(updatedAt, 0)
+	return &collection, nil
+}
+
+// AddToCollection appends paths to the end of a collection, in the given
+// order, and returns how many were added. Paths already in the collection
+// keep their position.
+//
+// Paths aren't required to be indexed. Items whose files are missing are left
+// out of listings and counts, and reappear if the files come back.
+func (d *Database) AddToCollection(ctx context.Context, id int64, paths []string) (int, error) {
+	done := observeQuery("add_to_collection")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		done(err)
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	now := time.Now().Unix()
+	result, err := tx.ExecContext(ctx, "UPDATE collections SET updated_at = ? WHERE id = ?", now, id)
+	if err != nil {
+		err = fmt.Errorf("failed to update collection: %w", err)
+		done(err)
+		return 0, err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		done(ErrCollectionNotFound)
+		return 0, ErrCollectionNotFound
+	}
+
+	var position int64
+	if err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(position), -1) + 1 FROM collection_items WHERE collection_id = ?",
+		id,
+	).Scan(&position); err != nil {
+		err = fmt.Errorf("failed to find end of collection: %w", err)
+		done(err)
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO collection_items (collection_id, file_path, position, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(collection_id, file_path) DO NOTHING
+	`)
+	if err != nil {
+		done(err)
+		return 0, err
+	}
+	defer stmt.Close()
+
+	added := 0
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		result, err := stmt.ExecContext(ctx, id, path, position, now)
+		if err != nil {
+			err = fmt.Errorf("failed to add %s to collection: %w", path, err)
+			done(err)
+			return 0, err
+		}
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected > 0 {
+			added++
+			position++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		done(err)
+		return 0, err
+	}
+
+	done(nil)
+	return added, nil
+}
+
+// RemoveFromCollection removes paths from a collection and returns how many
+// were removed. The remaining items keep their order.
+func (d *Database) RemoveFromCollection(ctx context.Context, id int64, paths []string) (int, error) {
+	done := observeQuery("remove_from_collection")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		done(err)
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	result, err := tx.ExecContext(ctx, "UPDATE collections SET updated_at = ? WHERE id = ?", time.Now().Unix(), id)
+	if err != nil {
+		err = fmt.Errorf("failed to update collection: %w", err)
+		done(err)
+		return 0, err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		done(ErrCollectionNotFound)
+		return 0, ErrCollectionNotFound
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "DELETE FROM collection_items WHERE collection_id = ? AND file_path = ?")
+	if err != nil {
+		done(err)
+		return 0, err
+	}
+	defer stmt.Close()
+
+	removed := 0
+	for _, path := range paths {
+		result, err := stmt.ExecContext(ctx, id, path)
+		if err != nil {
+			err = fmt.Errorf("failed to remove %s from collection: %w", path, err)
+			done(err)
+			return 0, err
+		}
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected > 0 {
+			removed++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		done(err)
+		return 0, err
+	}
+
+	done(nil)
+	return removed, nil
+}
+
+// SetCollectionOrder moves the given paths to the start of a collection, in
+// the given order. Items not listed follow in their current order, so listing
+// every item sets the whole order. Every path must be in the collection and
+// appear only once.
+func (d *Database) SetCollectionOrder(ctx context.Context, id int64, paths []string) error {
+	done := observeQuery("set_collection_order")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		done(err)
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	result, err := tx.ExecContext(ctx, "UPDATE collections SET updated_at = ? WHERE id = ?", time.Now().Unix(), id)
+	if err != nil {
+		err = fmt.Errorf("failed to update collection: %w", err)
+		done(err)
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		done(ErrCollectionNotFound)
+		return ErrCollectionNotFound
+	}
+
+	current, err := collectionPathsTx(ctx, tx, id)
+	if err != nil {
+		done(err)
+		return err
+	}
+
+	members := make(map[string]bool, len(current))
+	for _, path := range current {
+		members[path] = true
+	}
+
+	order := make([]string, 0, len(current))
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if !members[path] {
+			err := fmt.Errorf("%w: %q is not in the collection", ErrInvalidCollectionOrder, path)
+			done(err)
+			return err
+		}
+		if seen[path] {
+			err := fmt.Errorf("%w: %q is listed more than once", ErrInvalidCollectionOrder, path)
+			done(err)
+			return err
+		}
+		seen[path] = true
+		order = append(order, path)
+	}
+	for _, path := range current {
+		if !seen[path] {
+			order = append(order, path)
+		}
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "UPDATE collection_items SET position = ? WHERE collection_id = ? AND file_path = ?")
+	if err != nil {
+		done(err)
+		return err
+	}
+	defer stmt.Close()
+
+	for i, path := range order {
+		if _, err := stmt.ExecContext(ctx, i, id, path); err != nil {
+			err = fmt.Errorf("failed to store position of %s: %w", path, err)
+			done(err)
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	done(err)
+	return err
+}
+
+// collectionPathsTx returns every path in a collection in order, including
+// those whose files aren't indexed
+func collectionPathsTx(ctx context.Context, tx *sql.Tx, id int64) ([]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT file_path FROM collection_items WHERE collection_id = ? ORDER BY position, id",
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection items: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
+
+// ListCollection returns a page of a collection's indexed items with the same
+// file metadata, tags, sorting and type filtering as ListDirectory. opts.Path
+// is ignored. Without a sort field, or with SortByManual, items are in the
+// collection's own order, reversed by SortDesc.
+func (d *Database) ListCollection(ctx context.Context, id int64, opts ListOptions) (*CollectionListing, error) {
+	done := observeQuery("list_collection")
+
+	opts = normalizeListOptions(opts)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	collection, err := d.getCollectionUnlocked(ctx, id)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+
+	filter := ` WHERE ci.collection_id = ?`
+	filterArgs := []interface{}{id}
+	if opts.FilterType != "" {
+		filter += ` AND (f.type = 'folder' OR f.type = ?)`
+		filterArgs = append(filterArgs, opts.FilterType)
+	}
+
+	var totalItems int
+	countQuery := `SELECT COUNT(*) FROM collection_items ci INNER JOIN files f ON ci.file_path = f.path` + filter
+	if err := d.db.QueryRowContext(ctx, countQuery, filterArgs...).Scan(&totalItems); err != nil {
+		err = fmt.Errorf("failed to count collection items: %w", err)
+		done(err)
+		return nil, err
+	}
+
+	items, err := d.fetchCollectionPageUnlocked(ctx, opts, filter, filterArgs)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+
+	totalPages := int(math.Ceil(float64(totalItems) / float64(opts.PageSize)))
+	if totalPages < 1 {
+		totalPages = 1
+	}
+
+	done(nil)
+	return &CollectionListing{
+		Collection: *collection,
+		Items:      items,
+		TotalItems: totalItems,
+		Page:       opts.Page,
+		PageSize:   opts.PageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// fetchCollectionPageUnlocked retrieves one page of a collection's items.
+// Caller must hold at least a read lock.
+func (d *Database) fetchCollectionPageUnlocked(ctx context.Context, opts ListOptions, filter string, filterArgs []interface{}) ([]MediaFile, error) {
+	query := `
+		SELECT
+			f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type,
+			CASE WHEN fav.path IS NOT NULL THEN 1 ELSE 0 END as is_favorite,
+			GROUP_CONCAT(t.name, ',') as tags,
+			(SELECT COUNT(*) FROM files WHERE parent_path = f.path) as folder_count,
+			(SELECT description FROM file_notes WHERE file_path = f.path) as description,
+			EXISTS (SELECT 1 FROM sensitive_files WHERE file_path = f.path) as sensitive,
+			f.animated
+		FROM collection_items ci
+		INNER JOIN files f ON ci.file_path = f.path
+		LEFT JOIN favorites fav ON f.path = fav.path
+		LEFT JOIN file_tags ft ON f.path = ft.file_path
+		LEFT JOIN tags t ON ft.tag_id = t.id
+	` + filter + `
+		GROUP BY f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type, fav.path, ci.id, ci.position
+	`
+
+	if opts.SortField == "" || opts.SortField == SortByManual {
+		// The curated order puts folders wherever they were placed
+		if opts.SortOrder == SortDesc {
+			query += ` ORDER BY ci.position DESC, ci.id DESC`
+		} else {
+			query += ` ORDER BY ci.position, ci.id`
+		}
+	} else {
+		orderColumn, sortDir := resolveListOrder(opts.SortField, opts.SortOrder, opts.Locale)
+		query += fmt.Sprintf(` ORDER BY (CASE WHEN f.type = 'folder' THEN 0 ELSE 1 END), %s %s, f.id`, orderColumn, sortDir) //nolint:gosec // G202 - orderColumn and sortDir are validated against static allowlists; SQL column names cannot be parameterized
+	}
+	query += ` LIMIT ? OFFSET ?`
+	args := make([]interface{}, 0, len(filterArgs)+2)
+	args = append(args, filterArgs...)
+	args = append(args, opts.PageSize, (opts.Page-1)*opts.PageSize)
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collection: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.Error("error closing rows in fetchCollectionPageUnlocked: %v", err)
+		}
+	}()
+
+	return d.scanDirectoryItemsUnlocked(rows)
+}
@@ -0,0 +1,186 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCollectionsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	best, err := db.CreateCollection(ctx, "  Best of 2023 ")
+	if err != nil {
+		t.Fatalf("CreateCollection failed: %v", err)
+	}
+	if best.ID == 0 || best.Name != "Best of 2023" {
+		t.Errorf("Expected a trimmed, stored collection, got %+v", best)
+	}
+
+	if _, err := db.CreateCollection(ctx, "best OF 2023"); !errors.Is(err, ErrCollectionExists) {
+		t.Errorf("Expected ErrCollectionExists for a duplicate name, got %v", err)
+	}
+	if _, err := db.CreateCollection(ctx, " "); !errors.Is(err, ErrInvalidCollectionName) {
+		t.Errorf("Expected ErrInvalidCollectionName for a blank name, got %v", err)
+	}
+
+	other, err := db.CreateCollection(ctx, "Animals")
+	if err != nil {
+		t.Fatalf("CreateCollection failed: %v", err)
+	}
+
+	// Renaming to another collection's name fails; changing case doesn't
+	if err := db.RenameCollection(ctx, other.ID, "BEST of 2023"); !errors.Is(err, ErrCollectionExists) {
+		t.Errorf("Expected ErrCollectionExists renaming onto another name, got %v", err)
+	}
+	if err := db.RenameCollection(ctx, best.ID, "Best Of 2023"); err != nil {
+		t.Errorf("Expected a case-only rename to succeed, got %v", err)
+	}
+	if err := db.RenameCollection(ctx, 9999, "Missing"); !errors.Is(err, ErrCollectionNotFound) {
+		t.Errorf("Expected ErrCollectionNotFound renaming a missing collection, got %v", err)
+	}
+
+	collections, err := db.GetCollections(ctx)
+	if err != nil {
+		t.Fatalf("GetCollections failed: %v", err)
+	}
+	if len(collections) != 2 || collections[0].Name != "Animals" || collections[1].Name != "Best Of 2023" {
+		t.Errorf("Expected collections sorted by name, got %+v", collections)
+	}
+
+	if err := db.DeleteCollection(ctx, other.ID); err != nil {
+		t.Fatalf("DeleteCollection failed: %v", err)
+	}
+	if _, err := db.GetCollection(ctx, other.ID); !errors.Is(err, ErrCollectionNotFound) {
+		t.Errorf("Expected a deleted collection to be gone, got %v", err)
+	}
+	if err := db.DeleteCollection(ctx, other.ID); !errors.Is(err, ErrCollectionNotFound) {
+		t.Errorf("Expected ErrCollectionNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestCollectionItemsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "2023", Path: "2023", ParentPath: "", Type: FileTypeFolder},
+		{Name: "beach.jpg", Path: "2023/summer/beach.jpg", ParentPath: "2023/summer", Type: FileTypeImage},
+		{Name: "snow.mp4", Path: "2023/winter/snow.mp4", ParentPath: "2023/winter", Type: FileTypeVideo},
+		{Name: "cat.jpg", Path: "pets/cat.jpg", ParentPath: "pets", Type: FileTypeImage},
+	})
+
+	collection, err := db.CreateCollection(ctx, "Best of 2023")
+	if err != nil {
+		t.Fatalf("CreateCollection failed: %v", err)
+	}
+
+	added, err := db.AddToCollection(ctx, collection.ID, []string{"2023/winter/snow.mp4", "pets/cat.jpg", "2023"})
+	if err != nil || added != 3 {
+		t.Fatalf("AddToCollection = %d, %v; want 3 added", added, err)
+	}
+
+	// Existing items keep their place, new ones go to the end
+	added, err = db.AddToCollection(ctx, collection.ID, []string{"pets/cat.jpg", "2023/summer/beach.jpg", "gone/missing.jpg"})
+	if err != nil || added != 2 {
+		t.Fatalf("AddToCollection = %d, %v; want 2 added", added, err)
+	}
+
+	if _, err := db.AddToCollection(ctx, 9999, []string{"pets/cat.jpg"}); !errors.Is(err, ErrCollectionNotFound) {
+		t.Errorf("Expected ErrCollectionNotFound adding to a missing collection, got %v", err)
+	}
+
+	list := func(opts ListOptions) *CollectionListing {
+		t.Helper()
+		listing, err := db.ListCollection(ctx, collection.ID, opts)
+		if err != nil {
+			t.Fatalf("ListCollection failed: %v", err)
+		}
+		return listing
+	}
+
+	// Unindexed items are left out of the listing and the count
+	listing := list(ListOptions{})
+	want := []string{"2023/winter/snow.mp4", "pets/cat.jpg", "2023", "2023/summer/beach.jpg"}
+	if got := listingPaths(listing.Items); !slices.Equal(got, want) {
+		t.Errorf("Expected collection order %v, got %v", want, got)
+	}
+	if listing.TotalItems != 4 || listing.Collection.ItemCount != 4 || listing.Collection.Name != "Best of 2023" {
+		t.Errorf("Unexpected listing totals: %+v", listing)
+	}
+
+	tests := []struct {
+		name       string
+		opts       ListOptions
+		expected   []string
+		totalPages int
+	}{
+		{"reversed", ListOptions{SortOrder: SortDesc}, []string{"2023/summer/beach.jpg", "2023", "pets/cat.jpg", "2023/winter/snow.mp4"}, 1},
+		{"by name", ListOptions{SortField: SortByName, SortOrder: SortAsc}, []string{"2023", "2023/summer/beach.jpg", "pets/cat.jpg", "2023/winter/snow.mp4"}, 1},
+		{"second page", ListOptions{Page: 2, PageSize: 3}, []string{"2023/summer/beach.jpg"}, 2},
+		{"filtered", ListOptions{FilterType: string(FileTypeImage)}, []string{"pets/cat.jpg", "2023", "2023/summer/beach.jpg"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listing := list(tt.opts)
+			if got := listingPaths(listing.Items); !slices.Equal(got, tt.expected) {
+				t.Errorf("Items = %v, want %v", got, tt.expected)
+			}
+			if listing.TotalPages != tt.totalPages {
+				t.Errorf("TotalPages = %d, want %d", listing.TotalPages, tt.totalPages)
+			}
+		})
+	}
+
+	// Reordering moves the listed items first; the missing file keeps its
+	// place among the rest
+	if err := db.SetCollectionOrder(ctx, collection.ID, []string{"2023/summer/beach.jpg", "2023"}); err != nil {
+		t.Fatalf("SetCollectionOrder failed: %v", err)
+	}
+	want = []string{"2023/summer/beach.jpg", "2023", "2023/winter/snow.mp4", "pets/cat.jpg"}
+	if got := listingPaths(list(ListOptions{}).Items); !slices.Equal(got, want) {
+		t.Errorf("Expected order %v after reordering, got %v", want, got)
+	}
+
+	for _, paths := range [][]string{{"pets/dog.jpg"}, {"2023", "2023"}} {
+		if err := db.SetCollectionOrder(ctx, collection.ID, paths); !errors.Is(err, ErrInvalidCollectionOrder) {
+			t.Errorf("Expected ErrInvalidCollectionOrder for %v, got %v", paths, err)
+		}
+	}
+
+	removed, err := db.RemoveFromCollection(ctx, collection.ID, []string{"2023", "pets/dog.jpg"})
+	if err != nil || removed != 1 {
+		t.Fatalf("RemoveFromCollection = %d, %v; want 1 removed", removed, err)
+	}
+	want = []string{"2023/summer/beach.jpg", "2023/winter/snow.mp4", "pets/cat.jpg"}
+	if got := listingPaths(list(ListOptions{}).Items); !slices.Equal(got, want) {
+		t.Errorf("Expected %v after removing, got %v", want, got)
+	}
+
+	// Deleting the collection deletes its items but not the files
+	if err := db.DeleteCollection(ctx, collection.ID); err != nil {
+		t.Fatalf("DeleteCollection failed: %v", err)
+	}
+	if _, err := db.ListCollection(ctx, collection.ID, ListOptions{}); !errors.Is(err, ErrCollectionNotFound) {
+		t.Errorf("Expected ErrCollectionNotFound listing a deleted collection, got %v", err)
+	}
+	var orphans int
+	if err := db.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM collection_items").Scan(&orphans); err != nil || orphans != 0 {
+		t.Errorf("Expected no items left after deleting the collection, got %d (%v)", orphans, err)
+	}
+	if file, err := db.GetFileByPath(ctx, "pets/cat.jpg"); err != nil || file == nil {
+		t.Errorf("Expected files to survive deleting the collection, got %v", err)
+	}
+}
@@ -0,0 +1,342 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"media-viewer/internal/logging"
+)
+
+// CurationExportVersion is the format version of exported curation data
+const CurationExportVersion = 1
+
+// maxReportedMissing limits the missing paths listed by ImportCuration
+const maxReportedMissing = 1000
+
+// CurationImportMode decides what ImportCuration does with files that
+// already have favorites or tags.
+type CurationImportMode string
+
+const (
+	// CurationMerge adds imported favorites and tags to existing ones
+	CurationMerge CurationImportMode = "merge"
+	// CurationOverwrite replaces a file's favorite and tags with the imported ones
+	CurationOverwrite CurationImportMode = "overwrite"
+	// CurationSkip leaves files that already have favorites or tags unchanged
+	CurationSkip CurationImportMode = "skip"
+)
+
+var (
+	// ErrInvalidCurationMode is returned for an unknown import mode.
+	ErrInvalidCurationMode = errors.New("invalid import mode: must be skip, overwrite or merge")
+
+	// ErrUnsupportedCurationVersion is returned for curation data in a format
+	// this version can't read.
+	ErrUnsupportedCurationVersion = errors.New("unsupported curation export version")
+)
+
+// ParseCurationImportMode parses an import mode, defaulting to CurationMerge.
+func ParseCurationImportMode(value string) (CurationImportMode, error) {
+	switch mode := CurationImportMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return CurationMerge, nil
+	case CurationMerge, CurationOverwrite, CurationSkip:
+		return mode, nil
+	default:
+		return "", ErrInvalidCurationMode
+	}
+}
+
+// CurationTag is a tag definition in exported curation data.
+type CurationTag struct {
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+// CurationFile is the favorite flag and tags of one file in exported
+// curation data.
+type CurationFile struct {
+	Path     string   `json:"path"`
+	Favorite bool     `json:"favorite,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// CurationExport is a portable copy of all favorites and tags, matched to
+// files by path.
+type CurationExport struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exportedAt"`
+	Tags       []CurationTag  `json:"tags"`
+	Files      []CurationFile `json:"files"`
+}
+
+// CurationImportResult reports what ImportCuration changed.
+type CurationImportResult struct {
+	Mode         CurationImportMode `json:"mode"`
+	Imported     int                `json:"imported"`     // Files whose favorite or tags were applied
+	Skipped      int                `json:"skipped"`      // Files left unchanged in skip mode
+	TagsCreated  int                `json:"tagsCreated"`  // Tags that didn't exist before
+	MissingCount int                `json:"missingCount"` // Files not indexed here
+	Missing      []string           `json:"missing"`      // The first maxReportedMissing of them
+}
+
+// ExportCuration returns every tag, with its color, and every file that is a
+// favorite or has tags. Favorites and tags of files that are no longer
+// indexed are included.
+func (d *Database) ExportCuration(ctx context.Context) (*CurationExport, error) {
+	done := observeQuery("export_curation")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	export := &CurationExport{
+		Version:    CurationExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Tags:       []CurationTag{},
+		Files:      []CurationFile{},
+	}
+
+	tagRows, err := d.db.QueryContext(ctx, "SELECT name, COALESCE(color, '') FROM tags ORDER BY name COLLATE NOCASE")
+	if err != nil {
+		done(err)
+		return nil, fmt.Errorf("failed to export tags: %w", err)
+	}
+	defer func() {
+		if err := tagRows.Close(); err != nil {
+			logging.Error("error closing rows: %v", err)
+		}
+	}()
+	for tagRows.Next() {
+		var tag CurationTag
+		if err := tagRows.Scan(&tag.Name, &tag.Color); err != nil {
+			done(err)
+			return nil, err
+		}
+		export.Tags = append(export.Tags, tag)
+	}
+	if err := tagRows.Err(); err != nil {
+		done(err)
+		return nil, err
+	}
+
+	fileRows, err := d.db.QueryContext(ctx, `
+		SELECT p.path,
+			EXISTS (SELECT 1 FROM favorites WHERE path = p.path),
+			COALESCE((
+				SELECT GROUP_CONCAT(name, char(31)) FROM (
+					SELECT t.name FROM file_tags ft
+					INNER JOIN tags t ON t.id = ft.tag_id
+					WHERE ft.file_path = p.path
+					ORDER BY t.name COLLATE NOCASE
+				)
+			), '')
+		FROM (
+			SELECT path FROM favorites
+			UNION
+			SELECT file_path FROM file_tags
+		) p
+		ORDER BY p.path
+	`)
+	if err != nil {
+		done(err)
+		return nil, fmt.Errorf("failed to export files: %w", err)
+	}
+	defer func() {
+		if err := fileRows.Close(); err != nil {
+			logging.Error("error closing rows: %v", err)
+		}
+	}()
+	for fileRows.Next() {
+		var file CurationFile
+		var tags string
+		if err := fileRows.Scan(&file.Path, &file.Favorite, &tags); err != nil {
+			done(err)
+			return nil, err
+		}
+		if tags != "" {
+			// Tag names may contain commas, so they are joined by a unit separator
+			file.Tags = strings.Split(tags, "\x1f")
+		}
+		export.Files = append(export.Files, file)
+	}
+
+	err = fileRows.Err()
+	done(err)
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// ImportCuration applies exported curation data, matching files by path. Files
+// that aren't indexed are reported and skipped. mode decides what happens to
+// files that already have favorites or tags; tag colors are replaced only in
+// CurationOverwrite mode, and otherwise set only for tags without one. The
+// import is applied in a single transaction.
+func (d *Database) ImportCuration(ctx context.Context, data *CurationExport, mode CurationImportMode) (*CurationImportResult, error) {
+	done := observeQuery("import_curation")
+
+	if data.Version != CurationExportVersion {
+		done(ErrUnsupportedCurationVersion)
+		return nil, ErrUnsupportedCurationVersion
+	}
+	switch mode {
+	case CurationMerge, CurationOverwrite, CurationSkip:
+	default:
+		done(ErrInvalidCurationMode)
+		return nil, ErrInvalidCurationMode
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	result := &CurationImportResult{Mode: mode, Missing: []string{}}
+	tagIDs := make(map[string]int64)
+
+	for _, tag := range data.Tags {
+		if _, err := importTag(ctx, tx, tag, mode, tagIDs, result); err != nil {
+			done(err)
+			return nil, err
+		}
+	}
+
+	for _, file := range data.Files {
+		if err := importCurationFile(ctx, tx, file, mode, tagIDs, result); err != nil {
+			done(err)
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		done(err)
+		return nil, err
+	}
+
+	logging.Info("Imported curation for %d files (%s): %d skipped, %d not indexed, %d new tags",
+		result.Imported, mode, result.Skipped, result.MissingCount, result.TagsCreated)
+	done(nil)
+	return result, nil
+}
+
+// importTag finds or creates a tag, applying its color as the mode allows,
+// and returns its ID. IDs are cached in tagIDs by lowercased name.
+func importTag(ctx context.Context, tx *sql.Tx, tag CurationTag, mode CurationImportMode, tagIDs map[string]int64, result *CurationImportResult) (int64, error) {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Name == "" {
+		return 0, nil
+	}
+	if err := validateTagColor(tag.Color); err != nil {
+		return 0, fmt.Errorf("tag %q: %w", tag.Name, err)
+	}
+	key := strings.ToLower(tag.Name)
+	if id, ok := tagIDs[key]; ok && tag.Color == "" {
+		return id, nil
+	}
+
+	id, created, err := ensureTag(ctx, tx, tag.Name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to import tag %q: %w", tag.Name, err)
+	}
+	if created {
+		result.TagsCreated++
+	}
+
+	if tag.Color != "" {
+		// Unless overwriting, only tags without a color take it
+		query := "UPDATE tags SET color = ? WHERE id = ? AND (color IS NULL OR color = '')"
+		if mode == CurationOverwrite {
+			query = "UPDATE tags SET color = ? WHERE id = ?"
+		}
+		if _, err := tx.ExecContext(ctx, query, tag.Color, id); err != nil {
+			return 0, fmt.Errorf("failed to set color of tag %q: %w", tag.Name, err)
+		}
+	}
+
+	tagIDs[key] = id
+	return id, nil
+}
+
+// importCurationFile applies the favorite flag and tags of one file.
+func importCurationFile(ctx context.Context, tx *sql.Tx, file CurationFile, mode CurationImportMode, tagIDs map[string]int64, result *CurationImportResult) error {
+	var name string
+	var fileType FileType
+	err := tx.QueryRowContext(ctx, "SELECT name, type FROM files WHERE path = ?", file.Path).Scan(&name, &fileType)
+	if errors.Is(err, sql.ErrNoRows) {
+		result.MissingCount++
+		if len(result.Missing) < maxReportedMissing {
+			result.Missing = append(result.Missing, file.Path)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if mode == CurationSkip {
+		var curated bool
+		if err := tx.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM favorites WHERE path = ?)
+				OR EXISTS (SELECT 1 FROM file_tags WHERE file_path = ?)
+		`, file.Path, file.Path).Scan(&curated); err != nil {
+			return err
+		}
+		if curated {
+			result.Skipped++
+			return nil
+		}
+	}
+
+	if mode == CurationOverwrite {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM file_tags WHERE file_path = ?", file.Path); err != nil {
+			return err
+		}
+		if !file.Favorite {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM favorites WHERE path = ?", file.Path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if file.Favorite {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO favorites (path, name, type, created_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(path) DO NOTHING
+		`, file.Path, name, fileType, time.Now().Unix()); err != nil {
+			return err
+		}
+	}
+
+	for _, tagName := range file.Tags {
+		tagID, err := importTag(ctx, tx, CurationTag{Name: tagName}, mode, tagIDs, result)
+		if err != nil {
+			return err
+		}
+		if tagID == 0 {
+			continue
+		}
+		if err := tagFile(ctx, tx, file.Path, tagID); err != nil {
+			return err
+		}
+	}
+
+	result.Imported++
+	return nil
+}
@@ -0,0 +1,217 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseCurationImportMode(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected CurationImportMode
+		wantErr  bool
+	}{
+		{"", CurationMerge, false},
+		{"merge", CurationMerge, false},
+		{" Overwrite ", CurationOverwrite, false},
+		{"skip", CurationSkip, false},
+		{"replace", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCurationImportMode(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.expected {
+			t.Errorf("ParseCurationImportMode(%q) = %q, %v; want %q (error: %v)", tt.value, got, err, tt.expected, tt.wantErr)
+		}
+	}
+}
+
+func TestExportCurationIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "beach.jpg", Path: "2023/beach.jpg", ParentPath: "2023", Type: FileTypeImage},
+		{Name: "dunes.jpg", Path: "2023/dunes.jpg", ParentPath: "2023", Type: FileTypeImage},
+	})
+	if err := db.AddFavorite(ctx, "2023/beach.jpg", "beach.jpg", FileTypeImage); err != nil {
+		t.Fatalf("AddFavorite failed: %v", err)
+	}
+	for _, tag := range []string{"summer", "Family, friends"} {
+		if err := db.AddTagToFile(ctx, "2023/beach.jpg", tag); err != nil {
+			t.Fatalf("AddTagToFile failed: %v", err)
+		}
+	}
+	if err := db.AddTagToFile(ctx, "gone.jpg", "summer"); err != nil {
+		t.Fatalf("AddTagToFile failed: %v", err)
+	}
+	if err := db.SetTagColor(ctx, "summer", "#ffaa00"); err != nil {
+		t.Fatalf("SetTagColor failed: %v", err)
+	}
+	if _, err := db.GetOrCreateTag(ctx, "unused"); err != nil {
+		t.Fatalf("GetOrCreateTag failed: %v", err)
+	}
+
+	export, err := db.ExportCuration(ctx)
+	if err != nil {
+		t.Fatalf("ExportCuration failed: %v", err)
+	}
+	if export.Version != CurationExportVersion || export.ExportedAt.IsZero() {
+		t.Errorf("Expected version %d with an export time, got %+v", CurationExportVersion, export)
+	}
+
+	wantTags := []CurationTag{{Name: "Family, friends"}, {Name: "summer", Color: "#ffaa00"}, {Name: "unused"}}
+	if !reflect.DeepEqual(export.Tags, wantTags) {
+		t.Errorf("Tags = %+v, want %+v", export.Tags, wantTags)
+	}
+	wantFiles := []CurationFile{
+		{Path: "2023/beach.jpg", Favorite: true, Tags: []string{"Family, friends", "summer"}},
+		{Path: "gone.jpg", Tags: []string{"summer"}},
+	}
+	if !reflect.DeepEqual(export.Files, wantFiles) {
+		t.Errorf("Files = %+v, want %+v", export.Files, wantFiles)
+	}
+}
+
+func TestImportCurationIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	data := &CurationExport{
+		Version: CurationExportVersion,
+		Tags:    []CurationTag{{Name: "summer", Color: "#ffaa00"}, {Name: "archive"}},
+		Files: []CurationFile{
+			{Path: "2023/beach.jpg", Favorite: true, Tags: []string{"summer"}},
+			{Path: "2023/dunes.jpg", Tags: []string{"Summer", "sand"}},
+			{Path: "elsewhere/pier.jpg", Favorite: true},
+		},
+	}
+
+	// setup returns a database where beach.jpg is tagged "old" and dunes.jpg
+	// is a favorite, and summer is red
+	setup := func(t *testing.T) *Database {
+		t.Helper()
+		db, _ := setupTestDB(t)
+		t.Cleanup(func() { db.Close() })
+
+		ctx := context.Background()
+		insertOrderTestFiles(t, db, []MediaFile{
+			{Name: "beach.jpg", Path: "2023/beach.jpg", ParentPath: "2023", Type: FileTypeImage},
+			{Name: "dunes.jpg", Path: "2023/dunes.jpg", ParentPath: "2023", Type: FileTypeImage},
+		})
+		if err := db.AddTagToFile(ctx, "2023/beach.jpg", "old"); err != nil {
+			t.Fatalf("AddTagToFile failed: %v", err)
+		}
+		if err := db.AddFavorite(ctx, "2023/dunes.jpg", "dunes.jpg", FileTypeImage); err != nil {
+			t.Fatalf("AddFavorite failed: %v", err)
+		}
+		if err := db.AddTagToFile(ctx, "2023/dunes.jpg", "summer"); err != nil {
+			t.Fatalf("AddTagToFile failed: %v", err)
+		}
+		if err := db.SetTagColor(ctx, "summer", "#ff0000"); err != nil {
+			t.Fatalf("SetTagColor failed: %v", err)
+		}
+		return db
+	}
+
+	tagColor := func(t *testing.T, db *Database, name string) string {
+		t.Helper()
+		tags, err := db.GetAllTags(context.Background())
+		if err != nil {
+			t.Fatalf("GetAllTags failed: %v", err)
+		}
+		for _, tag := range tags {
+			if tag.Name == name {
+				return tag.Color
+			}
+		}
+		t.Fatalf("tag %q not found", name)
+		return ""
+	}
+
+	tests := []struct {
+		mode          CurationImportMode
+		imported      int
+		skipped       int
+		beachTags     []string
+		beachFavorite bool
+		dunesTags     []string
+		dunesFavorite bool
+		summerColor   string
+	}{
+		{CurationMerge, 2, 0, []string{"old", "summer"}, true, []string{"sand", "summer"}, true, "#ff0000"},
+		{CurationOverwrite, 2, 0, []string{"summer"}, true, []string{"sand", "summer"}, false, "#ffaa00"},
+		{CurationSkip, 0, 2, []string{"old"}, false, []string{"summer"}, true, "#ff0000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			db := setup(t)
+			ctx := context.Background()
+
+			result, err := db.ImportCuration(ctx, data, tt.mode)
+			if err != nil {
+				t.Fatalf("ImportCuration failed: %v", err)
+			}
+			if result.Mode != tt.mode || result.Imported != tt.imported || result.Skipped != tt.skipped {
+				t.Errorf("Expected %d imported and %d skipped, got %+v", tt.imported, tt.skipped, result)
+			}
+			if result.MissingCount != 1 || !reflect.DeepEqual(result.Missing, []string{"elsewhere/pier.jpg"}) {
+				t.Errorf("Expected pier.jpg to be reported missing, got %+v", result)
+			}
+			if db.IsFavorite(ctx, "elsewhere/pier.jpg") {
+				t.Error("Expected the missing file not to be made a favorite")
+			}
+
+			if tags, _ := db.GetFileTags(ctx, "2023/beach.jpg"); !reflect.DeepEqual(tags, tt.beachTags) {
+				t.Errorf("beach.jpg tags = %v, want %v", tags, tt.beachTags)
+			}
+			if tags, _ := db.GetFileTags(ctx, "2023/dunes.jpg"); !reflect.DeepEqual(tags, tt.dunesTags) {
+				t.Errorf("dunes.jpg tags = %v, want %v", tags, tt.dunesTags)
+			}
+			if got := db.IsFavorite(ctx, "2023/beach.jpg"); got != tt.beachFavorite {
+				t.Errorf("beach.jpg favorite = %v, want %v", got, tt.beachFavorite)
+			}
+			if got := db.IsFavorite(ctx, "2023/dunes.jpg"); got != tt.dunesFavorite {
+				t.Errorf("dunes.jpg favorite = %v, want %v", got, tt.dunesFavorite)
+			}
+			if color := tagColor(t, db, "summer"); color != tt.summerColor {
+				t.Errorf("summer color = %q, want %q", color, tt.summerColor)
+			}
+			// Tags without files are created in every mode
+			tagColor(t, db, "archive")
+		})
+	}
+}
+
+func TestImportCurationInvalidIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := db.ImportCuration(ctx, &CurationExport{Version: 2}, CurationMerge); !errors.Is(err, ErrUnsupportedCurationVersion) {
+		t.Errorf("Expected ErrUnsupportedCurationVersion, got %v", err)
+	}
+	if _, err := db.ImportCuration(ctx, &CurationExport{Version: CurationExportVersion}, "replace"); !errors.Is(err, ErrInvalidCurationMode) {
+		t.Errorf("Expected ErrInvalidCurationMode, got %v", err)
+	}
+
+	// Colors end up in a style attribute, so only plain color values are taken
+	bad := &CurationExport{Version: CurationExportVersion, Tags: []CurationTag{{Name: "summer", Color: "red; background: url(x)"}}}
+	if _, err := db.ImportCuration(ctx, bad, CurationMerge); !errors.Is(err, ErrInvalidTagColor) {
+		t.Errorf("Expected ErrInvalidTagColor, got %v", err)
+	}
+	if tags, _ := db.GetAllTags(ctx); len(tags) != 0 {
+		t.Errorf("Expected the failed import to create no tags, got %v", tags)
+	}
+}
@@ -3,16 +3,19 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	sqlite3 "github.com/mattn/go-sqlite3"
 
+	"media-viewer/internal/i18n"
 	"media-viewer/internal/logging"
 	"media-viewer/internal/metrics"
 )
@@ -69,6 +72,9 @@ type Database struct {
 	statsMu      sync.RWMutex
 	txStart      time.Time
 	mmapDisabled bool
+
+	// ftsRebuild is guarded by statsMu
+	ftsRebuild FTSRebuildStatus
 }
 
 // Options holds configuration options for database initialization.
@@ -78,6 +84,18 @@ type Options struct {
 	// (e.g., Longhorn, NFS, network-attached volumes).
 	// Default: false (mmap enabled — standard SQLite behavior).
 	MmapDisabled bool
+
+	// WALAutoCheckpoint sets PRAGMA wal_autocheckpoint on every connection:
+	// the WAL size, in pages, after which a commit checkpoints it.
+	// Zero keeps SQLite's default of 1000 pages; negative disables automatic
+	// checkpoints, leaving them to CheckpointWAL.
+	WALAutoCheckpoint int
+
+	// RecoverCorrupt runs an integrity check at startup and moves a database
+	// that fails it aside, creating an empty one in its place, instead of
+	// failing with ErrCorrupt. The index is rebuilt from the media directory,
+	// but users, favorites, tags and collections are lost.
+	RecoverCorrupt bool
 }
 
 // Info holds diagnostic info about the database initialization
@@ -87,6 +105,7 @@ type Info struct {
 	SQLiteVersion     string
 	MmapStatus        string
 	MmapWarning       string
+	RecoveredFrom     string // Where a corrupt database was moved before recreating it
 }
 
 // ---------------------------------------------------------------------------
@@ -129,6 +148,8 @@ func activeDriverName(opts *Options) string {
 }
 
 // New creates a new Database instance and returns diagnostic info for logging.
+// A database SQLite finds damaged returns an error wrapping ErrCorrupt,
+// unless opts.RecoverCorrupt is set.
 func New(ctx context.Context, dbPath string, opts *Options) (*Database, *Info, error) {
 	info := &Info{Path: dbPath}
 
@@ -136,20 +157,47 @@ func New(ctx context.Context, dbPath string, opts *Options) (*Database, *Info, e
 		info.PermissionWarning = err.Error()
 	}
 
-	// Determine which driver to use based on mmap configuration
-	driver := activeDriverName(opts)
-	isMmapDisabled := opts != nil && opts.MmapDisabled
-	if isMmapDisabled {
+	if opts != nil && opts.MmapDisabled {
 		logging.Info("SQLite mmap disabled (SIGBUS protection active for unreliable storage)")
 	} else {
 		logging.Debug("SQLite mmap enabled (default — standard performance mode)")
 	}
 
+	d, err := open(ctx, dbPath, opts)
+	if errors.Is(err, ErrCorrupt) && opts != nil && opts.RecoverCorrupt {
+		logging.Error("Database %s is corrupt: %v", dbPath, err)
+		movedTo, moveErr := moveAside(dbPath)
+		if moveErr != nil {
+			return nil, info, fmt.Errorf("failed to move corrupt database aside: %w", moveErr)
+		}
+		logging.Warn("Moved corrupt database to %s; creating an empty database", movedTo)
+		info.RecoveredFrom = movedTo
+		d, err = open(ctx, dbPath, opts)
+	}
+	if err != nil {
+		return nil, info, err
+	}
+
+	version, mmapStatus, mmapWarning := d.getSQLiteDiagnostics(ctx)
+	info.SQLiteVersion = version
+	info.MmapStatus = mmapStatus
+	info.MmapWarning = mmapWarning
+
+	return d, info, nil
+}
+
+// open connects to the database at dbPath, checks its integrity if
+// opts.RecoverCorrupt is set and creates any missing schema
+func open(ctx context.Context, dbPath string, opts *Options) (*Database, error) {
+	// Determine which driver to use based on mmap configuration
+	driver := activeDriverName(opts)
+	isMmapDisabled := opts != nil && opts.MmapDisabled
+
 	connStr := fmt.Sprintf("%s?_journal_mode=WAL&_synchronous=NORMAL&_cache_size=10000&_temp_store=MEMORY&_busy_timeout=5000", dbPath)
 
-	db, err := sql.Open(driver, connStr)
+	db, err := openDB(driver, connStr, connectionPragmas(opts))
 	if err != nil {
-		return nil, info, fmt.Errorf("failed to open database: %w", err)
+		return nil, corruptionError(fmt.Errorf("failed to open database: %w", err))
 	}
 
 	pingCtx, cancel := context.WithTimeout(ctx, defaultTimeout)
@@ -159,7 +207,19 @@ func New(ctx context.Context, dbPath string, opts *Options) (*Database, *Info, e
 		if cerr := db.Close(); cerr != nil {
 			logging.Warn("failed to close db after ping failure: %v", cerr)
 		}
-		return nil, info, fmt.Errorf("failed to connect to database: %w", err)
+		return nil, corruptionError(fmt.Errorf("failed to connect to database: %w", err))
+	}
+
+	// The full check reads the whole file, so it only runs when a corrupt
+	// database would be recovered; otherwise damage is caught by corruptionError
+	// when SQLite runs into it
+	if opts != nil && opts.RecoverCorrupt {
+		if err := checkIntegrity(ctx, db); err != nil {
+			if cerr := db.Close(); cerr != nil {
+				logging.Warn("failed to close db after integrity check failure: %v", cerr)
+			}
+			return nil, err
+		}
 	}
 
 	db.SetMaxOpenConns(25)
@@ -176,15 +236,69 @@ func New(ctx context.Context, dbPath string, opts *Options) (*Database, *Info, e
 		if cerr := db.Close(); cerr != nil {
 			logging.Warn("failed to close db after initialize failure: %v", cerr)
 		}
-		return nil, info, fmt.Errorf("failed to initialize database schema: %w", err)
+		return nil, corruptionError(fmt.Errorf("failed to initialize database schema: %w", err))
 	}
 
-	version, mmapStatus, mmapWarning := d.getSQLiteDiagnostics(ctx)
-	info.SQLiteVersion = version
-	info.MmapStatus = mmapStatus
-	info.MmapWarning = mmapWarning
+	return d, nil
+}
 
-	return d, info, nil
+// openDB opens the database with the named driver, running the pragmas on
+// and registering the collations with each pooled connection.
+func openDB(driverName, dsn string, pragmas []string) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// sql.Open only resolves the driver; no connection has been made yet
+	connector := &pragmaConnector{driver: db.Driver(), dsn: dsn, pragmas: pragmas}
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
+
+// pragmaConnector opens connections through a registered driver and runs
+// per-connection pragmas on each, which the go-sqlite3 DSN can't express. It
+// also registers the locale collations names are sorted with.
+type pragmaConnector struct {
+	driver  driver.Driver
+	dsn     string
+	pragmas []string
+}
+
+// Connect opens a connection, applies the pragmas to it and registers the
+// collations
+func (c *pragmaConnector) Connect(_ context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	sqliteConn, ok := conn.(*sqlite3.SQLiteConn)
+	if !ok {
+		return conn, nil
+	}
+	if err := i18n.RegisterCollations(sqliteConn.RegisterCollation); err != nil {
+		if cerr := conn.Close(); cerr != nil {
+			logging.Warn("failed to close connection after collation failure: %v", cerr)
+		}
+		return nil, fmt.Errorf("failed to register collations: %w", err)
+	}
+	for _, pragma := range c.pragmas {
+		if _, err := sqliteConn.Exec(pragma, nil); err != nil {
+			if cerr := conn.Close(); cerr != nil {
+				logging.Warn("failed to close connection after pragma failure: %v", cerr)
+			}
+			return nil, fmt.Errorf("failed to apply %q: %w", pragma, err)
+		}
+	}
+	return conn, nil
+}
+
+// Driver returns the underlying driver
+func (c *pragmaConnector) Driver() driver.Driver {
+	return c.driver
 }
 
 // getSQLiteDiagnostics returns SQLite version, mmap status, and any mmap warnings.
@@ -310,6 +424,13 @@ func (d *Database) initialize(ctx context.Context) error {
 		mod_time INTEGER NOT NULL,
 		mime_type TEXT,
 		file_hash TEXT,
+		birth_time INTEGER,
+		camera TEXT,
+		lens TEXT,
+		captured_at INTEGER,
+		content_hash TEXT,
+		full_hash TEXT,
+		animated INTEGER NOT NULL DEFAULT 0,
 		created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
 		updated_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
 		content_updated_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
@@ -363,6 +484,29 @@ func (d *Database) initialize(ctx context.Context) error {
 		INSERT INTO files_fts(rowid, name, path) VALUES (new.id, new.name, new.path);
 	END;
 
+	CREATE TABLE IF NOT EXISTS file_palettes (
+		file_path TEXT PRIMARY KEY,
+		colors TEXT NOT NULL,
+		updated_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+	);
+
+	CREATE TRIGGER IF NOT EXISTS files_palette_ad AFTER DELETE ON files BEGIN
+		DELETE FROM file_palettes WHERE file_path = old.path;
+	END;
+
+	-- Manual sort order of files within a folder
+	CREATE TABLE IF NOT EXISTS folder_order (
+		file_path TEXT PRIMARY KEY,
+		folder_path TEXT NOT NULL,
+		sort_order INTEGER NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_folder_order_folder ON folder_order(folder_path);
+
+	CREATE TRIGGER IF NOT EXISTS files_order_ad AFTER DELETE ON files BEGIN
+		DELETE FROM folder_order WHERE file_path = old.path;
+	END;
+
 	CREATE TABLE IF NOT EXISTS favorites (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		path TEXT NOT NULL UNIQUE,
@@ -394,6 +538,90 @@ func (d *Database) initialize(ctx context.Context) error {
 	CREATE INDEX IF NOT EXISTS idx_file_tags_path ON file_tags(file_path);
 	CREATE INDEX IF NOT EXISTS idx_file_tags_tag ON file_tags(tag_id);
 
+	-- Tags imported from XMP sidecars, so a reindex can tell them from tags
+	-- added by hand
+	CREATE TABLE IF NOT EXISTS sidecar_tags (
+		file_path TEXT NOT NULL,
+		tag_id INTEGER NOT NULL,
+		PRIMARY KEY (file_path, tag_id),
+		FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+	);
+
+	-- Free-text notes on files, searched alongside file names
+	CREATE TABLE IF NOT EXISTS file_notes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		file_path TEXT NOT NULL UNIQUE,
+		description TEXT NOT NULL,
+		updated_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+	);
+
+	CREATE VIRTUAL TABLE IF NOT EXISTS file_notes_fts USING fts5(
+		description,
+		content='file_notes',
+		content_rowid='id',
+		tokenize='trigram'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS file_notes_ai AFTER INSERT ON file_notes BEGIN
+		INSERT INTO file_notes_fts(rowid, description) VALUES (new.id, new.description);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS file_notes_ad AFTER DELETE ON file_notes BEGIN
+		INSERT INTO file_notes_fts(file_notes_fts, rowid, description) VALUES('delete', old.id, old.description);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS file_notes_au AFTER UPDATE ON file_notes BEGIN
+		INSERT INTO file_notes_fts(file_notes_fts, rowid, description) VALUES('delete', old.id, old.description);
+		INSERT INTO file_notes_fts(rowid, description) VALUES (new.id, new.description);
+	END;
+
+	-- Files whose thumbnails are served obscured until revealed
+	CREATE TABLE IF NOT EXISTS sensitive_files (
+		file_path TEXT PRIMARY KEY,
+		created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+	);
+
+	-- Frames chosen for video thumbnails, overriding the seek strategy
+	CREATE TABLE IF NOT EXISTS video_posters (
+		file_path TEXT PRIMARY KEY,
+		offset_ms INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+	);
+
+	-- Named, ordered sets of files curated across folders
+	CREATE TABLE IF NOT EXISTS collections (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+		created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+		updated_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+	);
+
+	CREATE TABLE IF NOT EXISTS collection_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		collection_id INTEGER NOT NULL,
+		file_path TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+		FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+		UNIQUE(collection_id, file_path)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_collection_items_position ON collection_items(collection_id, position);
+	CREATE INDEX IF NOT EXISTS idx_collection_items_path ON collection_items(file_path);
+
+	-- Files and folders deleted through the API, held in the trash directory
+	-- until they are restored or purged
+	CREATE TABLE IF NOT EXISTS trash (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		original_path TEXT NOT NULL,
+		trash_name TEXT NOT NULL UNIQUE,
+		type TEXT NOT NULL,
+		size INTEGER NOT NULL DEFAULT 0,
+		deleted_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_trash_deleted_at ON trash(deleted_at);
+
 	CREATE TABLE IF NOT EXISTS users (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		password_hash TEXT NOT NULL,
@@ -503,7 +731,191 @@ func (d *Database) runMigrations(ctx context.Context) error {
 		logging.Info("Migration complete: setup_complete column added and initialized")
 	}
 
-	return err
+	// Migration 3: Add birth_time column to files table if it doesn't exist
+	var birthTimeExists bool
+	err = d.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('files')
+		WHERE name='birth_time'
+	`).Scan(&birthTimeExists)
+
+	if err != nil {
+		return fmt.Errorf("failed to check for birth_time column: %w", err)
+	}
+
+	if !birthTimeExists {
+		logging.Info("Migrating database: adding birth_time column to files table")
+
+		done := observeQuery("migrate_add_birth_time")
+		_, err = d.db.ExecContext(ctx, `
+			ALTER TABLE files ADD COLUMN birth_time INTEGER
+		`)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to add birth_time column: %w", err)
+		}
+
+		logging.Info("Migration complete: birth_time column added (filled in by the next index run when INDEX_BIRTHTIME is enabled)")
+	}
+
+	// Migration 4: Add camera and lens columns to files table if they don't exist
+	var cameraExists bool
+	err = d.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('files')
+		WHERE name='camera'
+	`).Scan(&cameraExists)
+
+	if err != nil {
+		return fmt.Errorf("failed to check for camera column: %w", err)
+	}
+
+	if !cameraExists {
+		logging.Info("Migrating database: adding camera and lens columns to files table")
+
+		done := observeQuery("migrate_add_camera")
+		_, err = d.db.ExecContext(ctx, `
+			ALTER TABLE files ADD COLUMN camera TEXT;
+			ALTER TABLE files ADD COLUMN lens TEXT;
+		`)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to add camera and lens columns: %w", err)
+		}
+
+		logging.Info("Migration complete: camera and lens columns added (filled in by the next index run when INDEX_CAMERA is enabled)")
+	}
+
+	// Migration 5: Add captured_at column to files table if it doesn't exist
+	var capturedAtExists bool
+	err = d.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('files')
+		WHERE name='captured_at'
+	`).Scan(&capturedAtExists)
+
+	if err != nil {
+		return fmt.Errorf("failed to check for captured_at column: %w", err)
+	}
+
+	if !capturedAtExists {
+		logging.Info("Migrating database: adding captured_at column to files table")
+
+		done := observeQuery("migrate_add_captured_at")
+		_, err = d.db.ExecContext(ctx, `
+			ALTER TABLE files ADD COLUMN captured_at INTEGER
+		`)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to add captured_at column: %w", err)
+		}
+
+		logging.Info("Migration complete: captured_at column added (filled in by the next index run when INDEX_EXIF is enabled)")
+	}
+
+	// Migration 6: Add content_hash column to files table if it doesn't exist
+	var contentHashExists bool
+	err = d.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('files')
+		WHERE name='content_hash'
+	`).Scan(&contentHashExists)
+
+	if err != nil {
+		return fmt.Errorf("failed to check for content_hash column: %w", err)
+	}
+
+	if !contentHashExists {
+		logging.Info("Migrating database: adding content_hash column to files table")
+
+		done := observeQuery("migrate_add_content_hash")
+		_, err = d.db.ExecContext(ctx, `
+			ALTER TABLE files ADD COLUMN content_hash TEXT
+		`)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to add content_hash column: %w", err)
+		}
+
+		logging.Info("Migration complete: content_hash column added (filled in by the next index run when INDEX_DUPLICATES is enabled)")
+	}
+
+	// Migration 7: Add animated column to files table if it doesn't exist
+	var animatedExists bool
+	err = d.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('files')
+		WHERE name='animated'
+	`).Scan(&animatedExists)
+
+	if err != nil {
+		return fmt.Errorf("failed to check for animated column: %w", err)
+	}
+
+	if !animatedExists {
+		logging.Info("Migrating database: adding animated column to files table")
+
+		done := observeQuery("migrate_add_animated")
+		_, err = d.db.ExecContext(ctx, `
+			ALTER TABLE files ADD COLUMN animated INTEGER NOT NULL DEFAULT 0
+		`)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to add animated column: %w", err)
+		}
+
+		logging.Info("Migration complete: animated column added (filled in as thumbnails are generated when ANIMATED_DETECTION is enabled)")
+	}
+
+	// Migration 8: Add full_hash column to files table if it doesn't exist
+	var fullHashExists bool
+	err = d.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('files')
+		WHERE name='full_hash'
+	`).Scan(&fullHashExists)
+
+	if err != nil {
+		return fmt.Errorf("failed to check for full_hash column: %w", err)
+	}
+
+	if !fullHashExists {
+		logging.Info("Migrating database: adding full_hash column to files table")
+
+		done := observeQuery("migrate_add_full_hash")
+		_, err = d.db.ExecContext(ctx, `
+			ALTER TABLE files ADD COLUMN full_hash TEXT
+		`)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to add full_hash column: %w", err)
+		}
+
+		logging.Info("Migration complete: full_hash column added (filled in by the next index run when INDEX_DUPLICATES is enabled)")
+	}
+
+	// Created after the migration, as older databases only now have the columns
+	done := observeQuery("create_camera_indexes")
+	_, err = d.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_files_camera ON files(camera COLLATE NOCASE);
+		CREATE INDEX IF NOT EXISTS idx_files_lens ON files(lens COLLATE NOCASE);
+	`)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to create camera indexes: %w", err)
+	}
+
+	done = observeQuery("create_content_hash_index")
+	_, err = d.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_files_size_content_hash ON files(size, content_hash);
+		CREATE INDEX IF NOT EXISTS idx_files_size_full_hash ON files(size, full_hash);
+	`)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to create content hash index: %w", err)
+	}
+
+	return nil
 }
 
 // Close closes the database connection.
@@ -562,8 +974,8 @@ func (d *Database) UpsertFile(ctx context.Context, tx *sql.Tx, file *MediaFile)
 	done := observeQuery("upsert_file")
 
 	query := `
-	INSERT INTO files (name, path, parent_path, type, size, mod_time, mime_type, file_hash, updated_at, content_updated_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now'))
+	INSERT INTO files (name, path, parent_path, type, size, mod_time, mime_type, file_hash, birth_time, camera, lens, captured_at, updated_at, content_updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now'))
 	ON CONFLICT(path) DO UPDATE SET
 		name = excluded.name,
 		type = excluded.type,
@@ -571,6 +983,23 @@ func (d *Database) UpsertFile(ctx context.Context, tx *sql.Tx, file *MediaFile)
 		mod_time = excluded.mod_time,
 		mime_type = excluded.mime_type,
 		file_hash = excluded.file_hash,
+		birth_time = excluded.birth_time,
+		camera = excluded.camera,
+		lens = excluded.lens,
+		captured_at = excluded.captured_at,
+		content_hash = CASE
+			WHEN files.size = excluded.size AND files.mod_time = excluded.mod_time
+			THEN files.content_hash
+		END,
+		full_hash = CASE
+			WHEN files.size = excluded.size AND files.mod_time = excluded.mod_time
+			THEN files.full_hash
+		END,
+		animated = CASE
+			WHEN files.size = excluded.size AND files.mod_time = excluded.mod_time
+			THEN files.animated
+			ELSE 0
+		END,
 		updated_at = strftime('%s', 'now'),
 		content_updated_at = CASE
 			WHEN files.size != excluded.size
@@ -591,6 +1020,10 @@ func (d *Database) UpsertFile(ctx context.Context, tx *sql.Tx, file *MediaFile)
 		file.ModTime.Unix(),
 		file.MimeType,
 		file.FileHash,
+		nullableUnix(file.BirthTime),
+		nullableString(file.Camera),
+		nullableString(file.Lens),
+		nullableUnix(file.CapturedAt),
 	)
 	done(err)
 
@@ -602,6 +1035,22 @@ func (d *Database) UpsertFile(ctx context.Context, tx *sql.Tx, file *MediaFile)
 	return err
 }
 
+// nullableUnix returns t as a Unix timestamp, or nil (NULL) for the zero time.
+func nullableUnix(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Unix()
+}
+
+// nullableString returns s, or nil (NULL) for the empty string.
+func nullableString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
 // DeleteMissingFiles removes files that weren't seen during indexing.
 func (d *Database) DeleteMissingFiles(ctx context.Context, tx *sql.Tx, cutoffTime time.Time) (int64, error) {
 	done := observeQuery("delete_missing_files")
@@ -623,6 +1072,46 @@ func (d *Database) DeleteMissingFiles(ctx context.Context, tx *sql.Tx, cutoffTim
 	return rowsAffected, err
 }
 
+// DeleteFilesByPrefix removes a folder and everything under it, along with
+// the tags of the files in it and the record of which came from sidecars,
+// and returns how many files rows were deleted. Search entries, palettes
+// and manual sort order go with the rows through their triggers. Unlike
+// DeleteMissingFiles, it doesn't wait for a full index to find each file
+// gone, so it's used when a whole folder disappears.
+func (d *Database) DeleteFilesByPrefix(ctx context.Context, tx *sql.Tx, pathPrefix string) (int64, error) {
+	pathPrefix = strings.Trim(pathPrefix, "/")
+	if pathPrefix == "" {
+		return 0, errors.New("path prefix must not be empty")
+	}
+
+	done := observeQuery("delete_files_by_prefix")
+
+	// A range on the path index instead of LIKE, which would need the
+	// folder name escaped. '0' is the character after '/'.
+	const match = "(%[1]s = ? OR (%[1]s >= ? AND %[1]s < ?))"
+	args := []any{pathPrefix, pathPrefix + "/", pathPrefix + "0"}
+
+	for _, table := range []string{"file_tags", "sidecar_tags"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE "+fmt.Sprintf(match, "file_path"), args...); err != nil {
+			done(err)
+			return 0, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM files WHERE "+fmt.Sprintf(match, "path"), args...)
+	done(err)
+
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err == nil && rowsAffected > 0 {
+		metrics.DBRowsAffected.WithLabelValues("delete_files_by_prefix").Observe(float64(rowsAffected))
+	}
+	return rowsAffected, err
+}
+
 // GetFileByPath retrieves a single file by path.
 func (d *Database) GetFileByPath(ctx context.Context, path string) (*MediaFile, error) {
 	d.mu.RLock()
@@ -669,7 +1158,7 @@ func (d *Database) GetStats() IndexStats {
 	return d.stats
 }
 
-// RebuildFTS rebuilds the full-text search index.
+// RebuildFTS rebuilds the full-text search indexes of file names and notes.
 func (d *Database) RebuildFTS() error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -679,6 +1168,9 @@ func (d *Database) RebuildFTS() error {
 
 	done := observeQuery("rebuild_fts")
 	_, err := d.db.ExecContext(ctx, "INSERT INTO files_fts(files_fts) VALUES('rebuild')")
+	if err == nil {
+		_, err = d.db.ExecContext(ctx, "INSERT INTO file_notes_fts(file_notes_fts) VALUES('rebuild')")
+	}
 	done(err)
 
 	return err
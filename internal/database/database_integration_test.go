@@ -9,6 +9,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"media-viewer/internal/i18n"
 )
 
 // Integration tests for database operations with real SQLite database
@@ -561,6 +563,104 @@ func TestSearchIntegration(t *testing.T) {
 	}
 }
 
+func TestDeleteFilesByPrefixIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	// "trip 2", "trip-notes.jpg" and "trip0.jpg" sort next to "trip/" and
+	// must survive
+	files := []MediaFile{
+		{Name: "trip", Path: "trip", Type: FileTypeFolder, ModTime: time.Now()},
+		{Name: "beach.jpg", Path: "trip/beach.jpg", ParentPath: "trip", Type: FileTypeImage, ModTime: time.Now()},
+		{Name: "day 1", Path: "trip/day 1", ParentPath: "trip", Type: FileTypeFolder, ModTime: time.Now()},
+		{Name: "dunes.jpg", Path: "trip/day 1/dunes.jpg", ParentPath: "trip/day 1", Type: FileTypeImage, ModTime: time.Now()},
+		{Name: "trip 2", Path: "trip 2", Type: FileTypeFolder, ModTime: time.Now()},
+		{Name: "harbor.jpg", Path: "trip 2/harbor.jpg", ParentPath: "trip 2", Type: FileTypeImage, ModTime: time.Now()},
+		{Name: "trip-notes.jpg", Path: "trip-notes.jpg", Type: FileTypeImage, ModTime: time.Now()},
+		{Name: "trip0.jpg", Path: "trip0.jpg", Type: FileTypeImage, ModTime: time.Now()},
+	}
+
+	tx, err := db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	for i := range files {
+		if err := db.UpsertFile(ctx, tx, &files[i]); err != nil {
+			t.Fatalf("Failed to insert file: %v", err)
+		}
+	}
+	if err := db.EndBatch(tx, nil); err != nil {
+		t.Fatalf("EndBatch failed: %v", err)
+	}
+
+	for _, path := range []string{"trip/beach.jpg", "trip 2/harbor.jpg"} {
+		if err := db.AddTagToFile(ctx, path, "holiday"); err != nil {
+			t.Fatalf("AddTagToFile(%s) failed: %v", path, err)
+		}
+	}
+
+	tx, err = db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	deleted, err := db.DeleteFilesByPrefix(ctx, tx, "trip/")
+	if endErr := db.EndBatch(tx, err); endErr != nil {
+		t.Fatalf("DeleteFilesByPrefix failed: %v", endErr)
+	}
+	if deleted != 4 {
+		t.Errorf("DeleteFilesByPrefix deleted %d rows, want 4", deleted)
+	}
+
+	for _, f := range files {
+		_, err := db.GetFileByPath(ctx, f.Path)
+		gone := strings.HasPrefix(f.Path+"/", "trip/")
+		if gone && err == nil {
+			t.Errorf("Expected %s to be deleted", f.Path)
+		} else if !gone && err != nil {
+			t.Errorf("Expected %s to be kept, got %v", f.Path, err)
+		}
+	}
+
+	// The search index follows the rows
+	results, err := db.Search(ctx, SearchOptions{Query: "jpg", Page: 1, PageSize: 100})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	for _, item := range results.Items {
+		if strings.HasPrefix(item.Path, "trip/") {
+			t.Errorf("Search returned deleted file %s", item.Path)
+		}
+	}
+	if results.TotalItems != 3 {
+		t.Errorf("Search found %d files, want 3", results.TotalItems)
+	}
+
+	tags, err := db.GetFileTags(ctx, "trip/beach.jpg")
+	if err != nil {
+		t.Fatalf("GetFileTags failed: %v", err)
+	}
+	if len(tags) != 0 {
+		t.Errorf("Expected tags of deleted file to be removed, got %v", tags)
+	}
+	if tags, err := db.GetFileTags(ctx, "trip 2/harbor.jpg"); err != nil || len(tags) != 1 {
+		t.Errorf("Expected tags of kept file to remain, got %v, %v", tags, err)
+	}
+
+	tx, err = db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	_, err = db.DeleteFilesByPrefix(ctx, tx, "/")
+	if endErr := db.EndBatch(tx, err); endErr == nil {
+		t.Error("Expected an error for an empty prefix")
+	}
+}
+
 func TestGetFileByPathIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
@@ -1040,6 +1140,49 @@ func TestListDirectorySorting(t *testing.T) {
 	}
 }
 
+func TestListDirectoryLocaleSorting(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	tx, _ := db.BeginBatch(ctx)
+	for _, name := range []string{"IMG_10.jpg", "Zebra.jpg", "Äpfel.jpg", "IMG_2.jpg"} {
+		_ = db.UpsertFile(ctx, tx, &MediaFile{Name: name, Path: name, ParentPath: "", Type: FileTypeImage, ModTime: time.Now()})
+	}
+	_ = db.EndBatch(tx, nil)
+
+	tests := []struct {
+		locale i18n.Locale
+		want   []string
+	}{
+		{i18n.English, []string{"IMG_10.jpg", "IMG_2.jpg", "Zebra.jpg", "Äpfel.jpg"}},
+		{i18n.German, []string{"Äpfel.jpg", "IMG_2.jpg", "IMG_10.jpg", "Zebra.jpg"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.locale), func(t *testing.T) {
+			listing, err := db.ListDirectory(ctx, ListOptions{SortField: SortByName, SortOrder: SortAsc, Locale: tt.locale})
+			if err != nil {
+				t.Fatalf("ListDirectory failed: %v", err)
+			}
+			media, err := db.GetMediaInDirectoryForLocale(ctx, "", SortByName, SortAsc, tt.locale)
+			if err != nil {
+				t.Fatalf("GetMediaInDirectoryForLocale failed: %v", err)
+			}
+
+			for i, want := range tt.want {
+				if listing.Items[i].Name != want {
+					t.Errorf("ListDirectory item %d = %s, want %s", i, listing.Items[i].Name, want)
+				}
+				if media[i].Name != want {
+					t.Errorf("GetMediaInDirectoryForLocale item %d = %s, want %s", i, media[i].Name, want)
+				}
+			}
+		})
+	}
+}
+
 func TestListDirectoryPagination(t *testing.T) {
 	db, _ := setupTestDB(t)
 	defer db.Close()
@@ -12,6 +12,8 @@
 //
 // The database uses SQLite with the following optimizations:
 //   - WAL (Write-Ahead Logging) mode for improved concurrent read performance
+//     (the automatic checkpoint threshold is set with Options.WALAutoCheckpoint;
+//     CheckpointWAL truncates the log on demand)
 //   - Synchronous mode set to NORMAL for balanced durability and performance
 //   - In-memory temp store for faster temporary table operations
 //   - 10MB cache size for improved query performance
@@ -24,6 +26,8 @@
 //   - favorites: User-favorited files and folders
 //   - tags: Labels that can be applied to media files
 //   - file_tags: Many-to-many relationship between files and tags
+//   - sidecar_tags: Which file tags were imported from XMP sidecars
+//   - folder_order: Manual sort positions of files within a folder
 //   - users: Single-user authentication (password only)
 //   - sessions: Authentication session tokens with expiration
 //   - metadata: Key-value store for application state
@@ -33,6 +37,8 @@
 // The Database type is safe for concurrent use. It uses a read-write mutex
 // to allow multiple concurrent readers while ensuring exclusive write access.
 // Batch operations use explicit transactions for atomicity and performance.
+// StreamFiles is the exception: it reads from a SQLite snapshot without the
+// mutex, so large results can be consumed at the client's pace.
 //
 // # Full-Text Search
 //
@@ -0,0 +1,313 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"media-viewer/internal/logging"
+)
+
+// DuplicateOptions selects a page of duplicate groups.
+type DuplicateOptions struct {
+	MinSize  int64 // Ignore files smaller than this many bytes
+	Page     int
+	PageSize int
+}
+
+// DuplicateFile is one of the identical files of a DuplicateGroup.
+type DuplicateFile struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Type    FileType  `json:"type"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// DuplicateGroup is a set of files with identical content.
+type DuplicateGroup struct {
+	Hash  string          `json:"hash"`
+	Size  int64           `json:"size"` // Of each file
+	Files []DuplicateFile `json:"files"`
+}
+
+// DuplicateResult is a page of duplicate groups, largest files first.
+type DuplicateResult struct {
+	Groups      []DuplicateGroup `json:"groups"`
+	TotalGroups int              `json:"totalGroups"`
+	WastedBytes int64            `json:"wastedBytes"` // Freed by keeping one file of every group
+	Page        int              `json:"page"`
+	PageSize    int              `json:"pageSize"`
+	TotalPages  int              `json:"totalPages"`
+}
+
+// HashCandidate is a file the indexer should hash for duplicate detection.
+type HashCandidate struct {
+	ID          int64
+	Path        string
+	Size        int64
+	ModTime     time.Time
+	ContentHash string // Recorded content hash, of GetConfirmCandidates
+	Hash        string // Set by the caller for SetContentHashes or SetFullHashes
+}
+
+// GetDuplicates returns a page of the groups of files whose full hashes
+// match, biggest files first. Only files whose size matches another file's
+// are hashed, by the indexer when INDEX_DUPLICATES is enabled, and only those
+// whose content hash then matches another file's get a full hash; files it
+// hasn't reached yet are left out.
+func (d *Database) GetDuplicates(ctx context.Context, opts DuplicateOptions) (*DuplicateResult, error) {
+	done := observeQuery("get_duplicates")
+
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+	if opts.PageSize < 1 {
+		opts.PageSize = 50
+	}
+	if opts.PageSize > 200 {
+		opts.PageSize = 200
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	const groups = `
+		SELECT size, full_hash, COUNT(*) AS copies
+		FROM files
+		WHERE full_hash IS NOT NULL AND type != 'folder' AND size >= ?
+		GROUP BY size, full_hash
+		HAVING COUNT(*) > 1
+	`
+
+	result := &DuplicateResult{
+		Groups:   []DuplicateGroup{},
+		Page:     opts.Page,
+		PageSize: opts.PageSize,
+	}
+	err := d.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(size * (copies - 1)), 0) FROM (`+groups+`)
+	`, opts.MinSize).Scan(&result.TotalGroups, &result.WastedBytes)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+
+	result.TotalPages = max((result.TotalGroups+opts.PageSize-1)/opts.PageSize, 1)
+	offset := (opts.Page - 1) * opts.PageSize
+
+	rows, err := d.db.QueryContext(ctx, `
+		WITH page AS (`+groups+`
+			ORDER BY size DESC, full_hash
+			LIMIT ? OFFSET ?
+		)
+		SELECT f.name, f.path, f.type, f.size, f.mod_time, f.full_hash
+		FROM page
+		INNER JOIN files f ON f.size = page.size AND f.full_hash = page.full_hash
+		WHERE f.type != 'folder'
+		ORDER BY page.size DESC, page.full_hash, f.path
+	`, opts.MinSize, opts.PageSize, offset)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.Error("error closing rows: %v", err)
+		}
+	}()
+
+	for rows.Next() {
+		var file DuplicateFile
+		var modTime int64
+		var hash string
+		if err := rows.Scan(&file.Name, &file.Path, &file.Type, &file.Size, &modTime, &hash); err != nil {
+			done(err)
+			return nil, err
+		}
+		file.ModTime = time.Unix(modTime, 0)
+
+		if n := len(result.Groups); n == 0 || result.Groups[n-1].Hash != hash || result.Groups[n-1].Size != file.Size {
+			result.Groups = append(result.Groups, DuplicateGroup{Hash: hash, Size: file.Size})
+		}
+		group := &result.Groups[len(result.Groups)-1]
+		group.Files = append(group.Files, file)
+	}
+
+	err = rows.Err()
+	done(err)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetHashCandidates returns up to limit files after afterID, in order of ID,
+// that have no content hash and share their size with another file. Files of
+// a unique size can't have a duplicate, so they are never hashed.
+func (d *Database) GetHashCandidates(ctx context.Context, afterID int64, limit int) ([]HashCandidate, error) {
+	done := observeQuery("get_hash_candidates")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT f.id, f.path, f.size, f.mod_time
+		FROM files f
+		WHERE f.id > ? AND f.content_hash IS NULL AND f.type != 'folder' AND f.size > 0
+		  AND EXISTS (
+			SELECT 1 FROM files other
+			WHERE other.size = f.size AND other.id != f.id AND other.type != 'folder'
+		  )
+		ORDER BY f.id
+		LIMIT ?
+	`, afterID, limit)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.Error("error closing rows: %v", err)
+		}
+	}()
+
+	var candidates []HashCandidate
+	for rows.Next() {
+		var c HashCandidate
+		var modTime int64
+		if err := rows.Scan(&c.ID, &c.Path, &c.Size, &modTime); err != nil {
+			done(err)
+			return nil, err
+		}
+		c.ModTime = time.Unix(modTime, 0)
+		candidates = append(candidates, c)
+	}
+
+	err = rows.Err()
+	done(err)
+	return candidates, err
+}
+
+// GetConfirmCandidates returns up to limit files after afterID, in order of
+// ID, that have a content hash matching another file's of the same size but
+// no full hash yet. Content hashes may be made from samples of a file, so
+// only a full hash shows such files are identical.
+func (d *Database) GetConfirmCandidates(ctx context.Context, afterID int64, limit int) ([]HashCandidate, error) {
+	done := observeQuery("get_confirm_candidates")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT f.id, f.path, f.size, f.mod_time, f.content_hash
+		FROM files f
+		WHERE f.id > ? AND f.content_hash IS NOT NULL AND f.full_hash IS NULL AND f.type != 'folder'
+		  AND EXISTS (
+			SELECT 1 FROM files other
+			WHERE other.size = f.size AND other.content_hash = f.content_hash
+			  AND other.id != f.id AND other.type != 'folder'
+		  )
+		ORDER BY f.id
+		LIMIT ?
+	`, afterID, limit)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.Error("error closing rows: %v", err)
+		}
+	}()
+
+	var candidates []HashCandidate
+	for rows.Next() {
+		var c HashCandidate
+		var modTime int64
+		if err := rows.Scan(&c.ID, &c.Path, &c.Size, &modTime, &c.ContentHash); err != nil {
+			done(err)
+			return nil, err
+		}
+		c.ModTime = time.Unix(modTime, 0)
+		candidates = append(candidates, c)
+	}
+
+	err = rows.Err()
+	done(err)
+	return candidates, err
+}
+
+// SetContentHashes records the content hashes of files returned by
+// GetHashCandidates. Candidates without a hash, and files that changed since
+// they were read, are skipped.
+func (d *Database) SetContentHashes(ctx context.Context, candidates []HashCandidate) error {
+	return d.setHashes(ctx, "set_content_hashes", `
+		UPDATE files SET content_hash = ?
+		WHERE path = ? AND size = ? AND mod_time = ?
+	`, candidates)
+}
+
+// SetFullHashes records the full hashes of files returned by
+// GetConfirmCandidates, skipping candidates as SetContentHashes does.
+func (d *Database) SetFullHashes(ctx context.Context, candidates []HashCandidate) error {
+	return d.setHashes(ctx, "set_full_hashes", `
+		UPDATE files SET full_hash = ?
+		WHERE path = ? AND size = ? AND mod_time = ?
+	`, candidates)
+}
+
+// setHashes records the hashes of candidates with an update taking the hash,
+// path, size and modification time, in one transaction
+func (d *Database) setHashes(ctx context.Context, name, update string, candidates []HashCandidate) error {
+	tx, err := d.BeginBatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	done := observeQuery(name)
+	for _, c := range candidates {
+		if c.Hash == "" {
+			continue
+		}
+		if _, err = tx.ExecContext(ctx, update, c.Hash, c.Path, c.Size, c.ModTime.Unix()); err != nil {
+			break
+		}
+	}
+	done(err)
+
+	return d.EndBatch(tx, err)
+}
+
+// ClearContentHashes forgets every recorded content and full hash, so the
+// indexer hashes all candidates again. It returns the number of files
+// cleared.
+func (d *Database) ClearContentHashes(ctx context.Context) (int64, error) {
+	done := observeQuery("clear_content_hashes")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	result, err := d.db.ExecContext(ctx, `
+		UPDATE files SET content_hash = NULL, full_hash = NULL
+		WHERE content_hash IS NOT NULL OR full_hash IS NOT NULL
+	`)
+	if err != nil {
+		done(err)
+		return 0, err
+	}
+	cleared, err := result.RowsAffected()
+	done(err)
+	return cleared, err
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetDuplicatesIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	modTime := time.Unix(1700000000, 0)
+	files := []MediaFile{
+		{Name: "a.jpg", Path: "a.jpg", Type: FileTypeImage, Size: 100},
+		{Name: "a.jpg", Path: "copy/a.jpg", Type: FileTypeImage, Size: 100},
+		{Name: "a.jpg", Path: "copy/again/a.jpg", Type: FileTypeImage, Size: 100},
+		{Name: "b.jpg", Path: "b.jpg", Type: FileTypeImage, Size: 100},
+		{Name: "clip.mp4", Path: "clip.mp4", Type: FileTypeVideo, Size: 5000},
+		{Name: "clip.mp4", Path: "copy/clip.mp4", Type: FileTypeVideo, Size: 5000},
+		{Name: "solo.jpg", Path: "solo.jpg", Type: FileTypeImage, Size: 42},
+	}
+	tx, err := db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	for i := range files {
+		files[i].ModTime = modTime
+		if err := db.UpsertFile(ctx, tx, &files[i]); err != nil {
+			t.Fatalf("UpsertFile failed: %v", err)
+		}
+	}
+	if err := db.EndBatch(tx, nil); err != nil {
+		t.Fatalf("EndBatch failed: %v", err)
+	}
+
+	// Files of a unique size are never candidates
+	candidates, err := db.GetHashCandidates(ctx, 0, 100)
+	if err != nil {
+		t.Fatalf("GetHashCandidates failed: %v", err)
+	}
+	if len(candidates) != 6 {
+		t.Fatalf("Expected 6 candidates, got %+v", candidates)
+	}
+	next, _ := db.GetHashCandidates(ctx, candidates[3].ID, 100)
+	if len(next) != 2 || next[0].Path != "clip.mp4" {
+		t.Errorf("Expected the candidates after the fourth, got %+v", next)
+	}
+
+	hashes := map[string]string{
+		"a.jpg": "h1", "copy/a.jpg": "h1", "copy/again/a.jpg": "h1", "b.jpg": "h2",
+		"clip.mp4": "h3", "copy/clip.mp4": "h3",
+	}
+	for i := range candidates {
+		candidates[i].Hash = hashes[candidates[i].Path]
+	}
+	// A file changed since it was read keeps no hash
+	candidates[3].ModTime = modTime.Add(time.Second)
+	if err := db.SetContentHashes(ctx, candidates); err != nil {
+		t.Fatalf("SetContentHashes failed: %v", err)
+	}
+
+	// Matching content hashes only make a group once full hashes confirm them
+	if result, _ := db.GetDuplicates(ctx, DuplicateOptions{}); result.TotalGroups != 0 {
+		t.Errorf("Expected no groups before full hashes, got %+v", result.Groups)
+	}
+	confirm, err := db.GetConfirmCandidates(ctx, 0, 100)
+	if err != nil {
+		t.Fatalf("GetConfirmCandidates failed: %v", err)
+	}
+	if len(confirm) != 5 || confirm[0].ContentHash != "h1" {
+		t.Fatalf("Expected the 5 files sharing a content hash, got %+v", confirm)
+	}
+	for i := range confirm {
+		confirm[i].Hash = confirm[i].ContentHash
+	}
+	if err := db.SetFullHashes(ctx, confirm); err != nil {
+		t.Fatalf("SetFullHashes failed: %v", err)
+	}
+	if left, _ := db.GetConfirmCandidates(ctx, 0, 100); len(left) != 0 {
+		t.Errorf("Expected every candidate to be confirmed, got %+v", left)
+	}
+
+	result, err := db.GetDuplicates(ctx, DuplicateOptions{})
+	if err != nil {
+		t.Fatalf("GetDuplicates failed: %v", err)
+	}
+	if result.TotalGroups != 2 || result.WastedBytes != 5000+2*100 || len(result.Groups) != 2 {
+		t.Fatalf("Expected 2 groups wasting 5200 bytes, got %+v", result)
+	}
+	if g := result.Groups[0]; g.Hash != "h3" || g.Size != 5000 || len(g.Files) != 2 {
+		t.Errorf("Expected the videos first, got %+v", g)
+	}
+	if g := result.Groups[1]; g.Hash != "h1" || len(g.Files) != 3 || g.Files[0].Path != "a.jpg" || !g.Files[0].ModTime.Equal(modTime) {
+		t.Errorf("Expected the three images by path, got %+v", g)
+	}
+	if left, _ := db.GetHashCandidates(ctx, 0, 100); len(left) != 1 || left[0].Path != "b.jpg" {
+		t.Errorf("Expected only the skipped file to be left to hash, got %+v", left)
+	}
+
+	result, _ = db.GetDuplicates(ctx, DuplicateOptions{MinSize: 1000})
+	if result.TotalGroups != 1 || result.Groups[0].Hash != "h3" {
+		t.Errorf("Expected only the videos at or above 1000 bytes, got %+v", result.Groups)
+	}
+
+	result, _ = db.GetDuplicates(ctx, DuplicateOptions{Page: 2, PageSize: 1})
+	if result.TotalPages != 2 || len(result.Groups) != 1 || result.Groups[0].Hash != "h1" {
+		t.Errorf("Expected the images on page 2 of 2, got %+v", result)
+	}
+
+	// Reindexing an unchanged file keeps its hash; a changed one loses it
+	tx, _ = db.BeginBatch(ctx)
+	if err := db.UpsertFile(ctx, tx, &files[0]); err != nil {
+		t.Fatalf("UpsertFile failed: %v", err)
+	}
+	files[1].ModTime = modTime.Add(time.Minute)
+	if err := db.UpsertFile(ctx, tx, &files[1]); err != nil {
+		t.Fatalf("UpsertFile failed: %v", err)
+	}
+	if err := db.EndBatch(tx, nil); err != nil {
+		t.Fatalf("EndBatch failed: %v", err)
+	}
+	result, _ = db.GetDuplicates(ctx, DuplicateOptions{MinSize: 1, PageSize: 10})
+	if len(result.Groups) != 2 || len(result.Groups[1].Files) != 2 || result.Groups[1].Files[1].Path != "copy/again/a.jpg" {
+		t.Errorf("Expected the changed copy to drop out of its group, got %+v", result.Groups)
+	}
+}
@@ -0,0 +1,190 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"media-viewer/internal/logging"
+)
+
+// Columns of the files table that files can be browsed by. Queries are
+// formatted with these constants only, never with user input.
+const (
+	facetCamera = "camera"
+	facetLens   = "lens"
+)
+
+// FacetValue is a value files are grouped by, such as a camera, with the
+// number of files that have it.
+type FacetValue struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// GetCameraFacets returns every camera recorded for indexed files, with
+// their file counts, most used first.
+func (d *Database) GetCameraFacets(ctx context.Context) ([]FacetValue, error) {
+	return d.getFacetValues(ctx, facetCamera)
+}
+
+// GetLensFacets returns every lens recorded for indexed files, with their
+// file counts, most used first.
+func (d *Database) GetLensFacets(ctx context.Context) ([]FacetValue, error) {
+	return d.getFacetValues(ctx, facetLens)
+}
+
+// GetFilesByCamera returns a page of the files taken with a camera, as named
+// by GetCameraFacets. Names match case-insensitively.
+func (d *Database) GetFilesByCamera(ctx context.Context, camera string, page, pageSize int) (*SearchResult, error) {
+	return d.getFilesByFacet(ctx, facetCamera, camera, page, pageSize)
+}
+
+// GetFilesByLens returns a page of the files taken with a lens, as named by
+// GetLensFacets. Names match case-insensitively.
+func (d *Database) GetFilesByLens(ctx context.Context, lens string, page, pageSize int) (*SearchResult, error) {
+	return d.getFilesByFacet(ctx, facetLens, lens, page, pageSize)
+}
+
+// getFacetValues returns the distinct values of a facet column with their
+// file counts
+func (d *Database) getFacetValues(ctx context.Context, column string) ([]FacetValue, error) {
+	done := observeQuery("get_" + column + "_facets")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+		SELECT %[1]s, COUNT(*)
+		FROM files
+		WHERE %[1]s IS NOT NULL
+		GROUP BY %[1]s COLLATE NOCASE
+		ORDER BY COUNT(*) DESC, %[1]s COLLATE NOCASE
+	`, column) //nolint:gosec // G201 - column is one of the facet constants; SQL column names cannot be parameterized
+
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.Error("error closing rows: %v", err)
+		}
+	}()
+
+	values := []FacetValue{}
+	for rows.Next() {
+		var value FacetValue
+		if err := rows.Scan(&value.Name, &value.Count); err != nil {
+			done(err)
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	err = rows.Err()
+	done(err)
+	return values, err
+}
+
+// getFilesByFacet returns a page of the files with a value of a facet column
+func (d *Database) getFilesByFacet(ctx context.Context, column, value string, page, pageSize int) (*SearchResult, error) {
+	done := observeQuery("get_files_by_" + column)
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 50
+	}
+	if pageSize > 200 {
+		pageSize = 200
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	where := fmt.Sprintf("%s = ? COLLATE NOCASE", column) //nolint:gosec // G201 - column is one of the facet constants; SQL column names cannot be parameterized
+
+	var totalItems int
+	err := d.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM files WHERE "+where, value).Scan(&totalItems)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+
+	totalPages := (totalItems + pageSize - 1) / pageSize
+	if totalPages < 1 {
+		totalPages = 1
+	}
+	offset := (page - 1) * pageSize
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT id, name, path, parent_path, type, size, mod_time, mime_type
+		FROM files
+		WHERE `+where+`
+		ORDER BY name COLLATE NOCASE, path
+		LIMIT ? OFFSET ?
+	`, value, pageSize, offset)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.Error("error closing rows: %v", err)
+		}
+	}()
+
+	items := []MediaFile{}
+	for rows.Next() {
+		var file MediaFile
+		var modTime int64
+		var mimeType sql.NullString
+
+		if err := rows.Scan(
+			&file.ID, &file.Name, &file.Path, &file.ParentPath,
+			&file.Type, &file.Size, &modTime, &mimeType,
+		); err != nil {
+			done(err)
+			return nil, err
+		}
+
+		file.ModTime = time.Unix(modTime, 0)
+		if mimeType.Valid {
+			file.MimeType = mimeType.String
+		}
+		if file.Type == FileTypeImage || file.Type == FileTypeVideo {
+			file.ThumbnailURL = "/api/thumbnail/" + file.Path
+		}
+
+		// Use unlocked versions since we already hold the lock
+		tags, _ := d.getFileTagsUnlocked(ctx, file.Path)
+		file.Tags = tags
+		file.IsFavorite = d.isFavoriteUnlocked(ctx, file.Path)
+
+		items = append(items, file)
+	}
+	if err := rows.Err(); err != nil {
+		done(err)
+		return nil, err
+	}
+
+	done(nil)
+	return &SearchResult{
+		Items:      items,
+		Query:      column + ":" + value,
+		TotalItems: totalItems,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
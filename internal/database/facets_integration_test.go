@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCameraFacetsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	modTime := time.Now().Truncate(time.Second)
+
+	files := []MediaFile{
+		{Name: "a.jpg", Path: "trip/a.jpg", ParentPath: "trip", Type: FileTypeImage, ModTime: modTime, Camera: "Canon EOS R5", Lens: "RF24-105mm F4 L IS USM"},
+		{Name: "b.jpg", Path: "trip/b.jpg", ParentPath: "trip", Type: FileTypeImage, ModTime: modTime, Camera: "Canon EOS R5", Lens: "RF50mm F1.8 STM"},
+		{Name: "c.jpg", Path: "home/c.jpg", ParentPath: "home", Type: FileTypeImage, ModTime: modTime, Camera: "Apple iPhone 12"},
+		{Name: "d.png", Path: "home/d.png", ParentPath: "home", Type: FileTypeImage, ModTime: modTime},
+	}
+	tx, err := db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	for i := range files {
+		if err := db.UpsertFile(ctx, tx, &files[i]); err != nil {
+			t.Fatalf("UpsertFile failed: %v", err)
+		}
+	}
+	if err := db.EndBatch(tx, nil); err != nil {
+		t.Fatalf("EndBatch failed: %v", err)
+	}
+
+	cameras, err := db.GetCameraFacets(ctx)
+	if err != nil {
+		t.Fatalf("GetCameraFacets failed: %v", err)
+	}
+	wantCameras := []FacetValue{{Name: "Canon EOS R5", Count: 2}, {Name: "Apple iPhone 12", Count: 1}}
+	if !slices.Equal(cameras, wantCameras) {
+		t.Errorf("GetCameraFacets = %v, want %v", cameras, wantCameras)
+	}
+
+	lenses, err := db.GetLensFacets(ctx)
+	if err != nil {
+		t.Fatalf("GetLensFacets failed: %v", err)
+	}
+	wantLenses := []FacetValue{{Name: "RF24-105mm F4 L IS USM", Count: 1}, {Name: "RF50mm F1.8 STM", Count: 1}}
+	if !slices.Equal(lenses, wantLenses) {
+		t.Errorf("GetLensFacets = %v, want %v", lenses, wantLenses)
+	}
+
+	result, err := db.GetFilesByCamera(ctx, "canon eos r5", 1, 1)
+	if err != nil {
+		t.Fatalf("GetFilesByCamera failed: %v", err)
+	}
+	if result.TotalItems != 2 || result.TotalPages != 2 || len(result.Items) != 1 || result.Items[0].Path != "trip/a.jpg" {
+		t.Errorf("GetFilesByCamera page 1 = %d items of %d (%d pages), want trip/a.jpg of 2", len(result.Items), result.TotalItems, result.TotalPages)
+	}
+	if result.Items[0].ThumbnailURL != "/api/thumbnail/trip/a.jpg" {
+		t.Errorf("ThumbnailURL = %q", result.Items[0].ThumbnailURL)
+	}
+
+	result, err = db.GetFilesByLens(ctx, "RF50mm F1.8 STM", 1, 10)
+	if err != nil {
+		t.Fatalf("GetFilesByLens failed: %v", err)
+	}
+	if got := listingPaths(result.Items); !slices.Equal(got, []string{"trip/b.jpg"}) {
+		t.Errorf("GetFilesByLens = %v, want [trip/b.jpg]", got)
+	}
+
+	result, err = db.GetFilesByCamera(ctx, "Nikon D750", 1, 10)
+	if err != nil {
+		t.Fatalf("GetFilesByCamera failed: %v", err)
+	}
+	if result.TotalItems != 0 || result.Items == nil {
+		t.Errorf("GetFilesByCamera of an unknown camera = %v, want an empty page", result.Items)
+	}
+
+	// Reindexing without camera indexing clears what was recorded
+	files[2].Camera = ""
+	tx, err = db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	if err := db.UpsertFile(ctx, tx, &files[2]); err != nil {
+		t.Fatalf("UpsertFile failed: %v", err)
+	}
+	if err := db.EndBatch(tx, nil); err != nil {
+		t.Fatalf("EndBatch failed: %v", err)
+	}
+	cameras, err = db.GetCameraFacets(ctx)
+	if err != nil {
+		t.Fatalf("GetCameraFacets failed: %v", err)
+	}
+	if !slices.Equal(cameras, wantCameras[:1]) {
+		t.Errorf("GetCameraFacets after clearing = %v, want %v", cameras, wantCameras[:1])
+	}
+}
+
+func TestCameraMigrationIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+
+	db, _, err := New(ctx, dbPath, nil)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	// Simulate a database created before the columns existed
+	if _, err := db.db.ExecContext(ctx, `
+		DROP INDEX idx_files_camera;
+		DROP INDEX idx_files_lens;
+		ALTER TABLE files DROP COLUMN camera;
+		ALTER TABLE files DROP COLUMN lens;
+	`); err != nil {
+		t.Fatalf("Failed to drop camera columns: %v", err)
+	}
+	db.Close()
+
+	db, _, err = New(ctx, dbPath, nil)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	var count int
+	if err := db.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pragma_table_info('files') WHERE name IN ('camera', 'lens')").Scan(&count); err != nil {
+		t.Fatalf("Failed to check columns: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected the migration to add the camera and lens columns, found %d", count)
+	}
+
+	if _, err := db.GetCameraFacets(ctx); err != nil {
+		t.Errorf("GetCameraFacets after migration failed: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 
 	"media-viewer/internal/logging"
@@ -158,3 +159,101 @@ func (d *Database) GetFavoriteCount(ctx context.Context) int {
 	}
 	return count
 }
+
+// ListFavorites returns a page of favorites with the same file metadata, tags,
+// sorting and type filtering as ListDirectory. opts.Path is ignored. Without a
+// sort field, favorites are ordered by when they were added, newest first.
+func (d *Database) ListFavorites(ctx context.Context, opts ListOptions) (*FavoritesListing, error) {
+	done := observeQuery("list_favorites")
+
+	opts = normalizeListOptions(opts)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	filter := ""
+	var filterArgs []interface{}
+	if opts.FilterType != "" {
+		filter = ` WHERE (f.type = 'folder' OR f.type = ?)`
+		filterArgs = append(filterArgs, opts.FilterType)
+	}
+
+	var totalItems int
+	countQuery := `SELECT COUNT(*) FROM favorites fav INNER JOIN files f ON fav.path = f.path` + filter
+	if err := d.db.QueryRowContext(ctx, countQuery, filterArgs...).Scan(&totalItems); err != nil {
+		done(err)
+		return nil, fmt.Errorf("failed to count favorites: %w", err)
+	}
+
+	items, err := d.fetchFavoritesPageUnlocked(ctx, opts, filter, filterArgs)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+
+	totalPages := int(math.Ceil(float64(totalItems) / float64(opts.PageSize)))
+	if totalPages < 1 {
+		totalPages = 1
+	}
+
+	done(nil)
+	return &FavoritesListing{
+		Items:      items,
+		TotalItems: totalItems,
+		Page:       opts.Page,
+		PageSize:   opts.PageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// fetchFavoritesPageUnlocked retrieves one page of favorites.
+// Caller must hold at least a read lock.
+func (d *Database) fetchFavoritesPageUnlocked(ctx context.Context, opts ListOptions, filter string, filterArgs []interface{}) ([]MediaFile, error) {
+	query := `
+		SELECT
+			f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type,
+			1 as is_favorite,
+			GROUP_CONCAT(t.name, ',') as tags,
+			(SELECT COUNT(*) FROM files WHERE parent_path = f.path) as folder_count,
+			(SELECT description FROM file_notes WHERE file_path = f.path) as description,
+			EXISTS (SELECT 1 FROM sensitive_files WHERE file_path = f.path) as sensitive,
+			f.animated
+		FROM favorites fav
+		INNER JOIN files f ON fav.path = f.path
+		LEFT JOIN file_tags ft ON f.path = ft.file_path
+		LEFT JOIN tags t ON ft.tag_id = t.id
+	` + filter + `
+		GROUP BY f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type, fav.id, fav.created_at
+	`
+
+	if opts.SortField == "" {
+		query += ` ORDER BY fav.created_at DESC, fav.id DESC`
+	} else {
+		// Favorites have no manual order; resolveListOrder would otherwise join folder_order
+		field := opts.SortField
+		if field == SortByManual {
+			field = SortByName
+		}
+		orderColumn, sortDir := resolveListOrder(field, opts.SortOrder, opts.Locale)
+		query += fmt.Sprintf(` ORDER BY (CASE WHEN f.type = 'folder' THEN 0 ELSE 1 END), %s %s, f.id`, orderColumn, sortDir) //nolint:gosec // G202 - orderColumn and sortDir are validated against static allowlists; SQL column names cannot be parameterized
+	}
+	query += ` LIMIT ? OFFSET ?`
+	args := make([]interface{}, 0, len(filterArgs)+2)
+	args = append(args, filterArgs...)
+	args = append(args, opts.PageSize, (opts.Page-1)*opts.PageSize)
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list favorites: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.Error("error closing rows in fetchFavoritesPageUnlocked: %v", err)
+		}
+	}()
+
+	return d.scanDirectoryItemsUnlocked(rows)
+}
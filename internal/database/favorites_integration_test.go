@@ -2,6 +2,8 @@ package database
 
 import (
 	"context"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -176,6 +178,100 @@ func TestGetFavoritesEmptyIntegration(t *testing.T) {
 	}
 }
 
+func TestListFavoritesIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	files := []MediaFile{
+		{Name: "b.jpg", Path: "photos/b.jpg", ParentPath: "photos", Type: FileTypeImage, Size: 300},
+		{Name: "a.mp4", Path: "videos/a.mp4", ParentPath: "videos", Type: FileTypeVideo, Size: 100},
+		{Name: "c.png", Path: "photos/c.png", ParentPath: "photos", Type: FileTypeImage, Size: 200},
+		{Name: "photos", Path: "photos", ParentPath: "", Type: FileTypeFolder},
+		{Name: "unfavorited.jpg", Path: "photos/unfavorited.jpg", ParentPath: "photos", Type: FileTypeImage, Size: 50},
+	}
+	tx, _ := db.BeginBatch(ctx)
+	for i := range files {
+		files[i].ModTime = time.Now()
+		if err := db.UpsertFile(ctx, tx, &files[i]); err != nil {
+			t.Fatalf("UpsertFile failed: %v", err)
+		}
+	}
+	_ = db.EndBatch(tx, nil)
+
+	for _, f := range files[:4] {
+		if err := db.AddFavorite(ctx, f.Path, f.Name, f.Type); err != nil {
+			t.Fatalf("AddFavorite failed for %s: %v", f.Path, err)
+		}
+	}
+	if err := db.AddTagToFile(ctx, "photos/b.jpg", "sunset"); err != nil {
+		t.Fatalf("AddTagToFile failed: %v", err)
+	}
+
+	names := func(listing *FavoritesListing) []string {
+		var result []string
+		for _, item := range listing.Items {
+			result = append(result, item.Name)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name       string
+		opts       ListOptions
+		expected   []string
+		totalItems int
+		totalPages int
+	}{
+		{"most recently added first", ListOptions{}, []string{"photos", "c.png", "a.mp4", "b.jpg"}, 4, 1},
+		{"by name", ListOptions{SortField: SortByName, SortOrder: SortAsc}, []string{"photos", "a.mp4", "b.jpg", "c.png"}, 4, 1},
+		{"by size descending", ListOptions{SortField: SortBySize, SortOrder: SortDesc}, []string{"photos", "b.jpg", "c.png", "a.mp4"}, 4, 1},
+		{"second page", ListOptions{SortField: SortByName, Page: 2, PageSize: 3}, []string{"c.png"}, 4, 2},
+		{"filtered", ListOptions{SortField: SortByName, FilterType: string(FileTypeVideo)}, []string{"photos", "a.mp4"}, 2, 1},
+		{"manual falls back to name", ListOptions{SortField: SortByManual}, []string{"photos", "a.mp4", "b.jpg", "c.png"}, 4, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listing, err := db.ListFavorites(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("ListFavorites failed: %v", err)
+			}
+			if got := names(listing); strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Items = %v, want %v", got, tt.expected)
+			}
+			if listing.TotalItems != tt.totalItems || listing.TotalPages != tt.totalPages {
+				t.Errorf("TotalItems = %d, TotalPages = %d, want %d, %d", listing.TotalItems, listing.TotalPages, tt.totalItems, tt.totalPages)
+			}
+		})
+	}
+
+	// Items carry the same metadata as directory listings
+	listing, err := db.ListFavorites(ctx, ListOptions{SortField: SortByName})
+	if err != nil {
+		t.Fatalf("ListFavorites failed: %v", err)
+	}
+	for _, item := range listing.Items {
+		if !item.IsFavorite || item.ThumbnailURL == "" {
+			t.Errorf("Expected %s marked as favorite with a thumbnail URL, got %+v", item.Name, item)
+		}
+		switch item.Name {
+		case "b.jpg":
+			if item.Size != 300 || len(item.Tags) != 1 || item.Tags[0] != "sunset" {
+				t.Errorf("Expected size and tags for b.jpg, got %+v", item)
+			}
+		case "photos":
+			if item.ItemCount != 3 {
+				t.Errorf("Expected 3 items in the photos folder, got %d", item.ItemCount)
+			}
+		}
+	}
+}
+
 func TestGetFavoriteCountIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
@@ -251,3 +347,42 @@ func TestFavoritesConcurrencyIntegration(t *testing.T) {
 		t.Errorf("Expected 0 favorites after concurrent operations, got %d", count)
 	}
 }
+
+func TestFavoritesConcurrentSamePathIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	run := func(op func() error) {
+		t.Helper()
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- op()
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Errorf("Concurrent favorite write failed: %v", err)
+			}
+		}
+	}
+
+	run(func() error { return db.AddFavorite(ctx, "/test/same.jpg", "same.jpg", FileTypeImage) })
+	if count := db.GetFavoriteCount(ctx); count != 1 {
+		t.Errorf("Expected one favorite after concurrent adds, got %d", count)
+	}
+
+	run(func() error { return db.RemoveFavorite(ctx, "/test/same.jpg") })
+	if count := db.GetFavoriteCount(ctx); count != 0 {
+		t.Errorf("Expected no favorites after concurrent removes, got %d", count)
+	}
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// checkFilesChunkSize bounds the number of paths bound to a single query
+const checkFilesChunkSize = 500
+
+// FileState is the indexed state of a file, used by clients to find entries
+// of a cached listing that changed. Hash changes whenever the file's size or
+// modification time does.
+type FileState struct {
+	Exists  bool       `json:"exists"`
+	ModTime *time.Time `json:"modTime,omitempty"`
+	Size    int64      `json:"size,omitempty"`
+	Hash    string     `json:"hash,omitempty"`
+}
+
+// CheckFiles returns the state of each path. Paths that aren't indexed are
+// reported with Exists false.
+func (d *Database) CheckFiles(ctx context.Context, paths []string) (map[string]FileState, error) {
+	done := observeQuery("check_files")
+
+	states := make(map[string]FileState, len(paths))
+	for _, path := range paths {
+		states[path] = FileState{}
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	for start := 0; start < len(paths); start += checkFilesChunkSize {
+		chunk := paths[start:min(start+checkFilesChunkSize, len(paths))]
+
+		args := make([]any, len(chunk))
+		for i, path := range chunk {
+			args[i] = path
+		}
+
+		placeholders := strings.Repeat(", ?", len(chunk)-1)
+		query := "SELECT path, size, mod_time, COALESCE(file_hash, '') FROM files WHERE path IN (?" + placeholders + ")" //nolint:gosec // G202 false positive - only parameter placeholders are concatenated
+
+		rows, err := d.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			done(err)
+			return nil, err
+		}
+
+		for rows.Next() {
+			var path string
+			var state FileState
+			var modTime int64
+			if err := rows.Scan(&path, &state.Size, &modTime, &state.Hash); err != nil {
+				rows.Close()
+				done(err)
+				return nil, err
+			}
+			mt := time.Unix(modTime, 0)
+			state.Exists = true
+			state.ModTime = &mt
+			states[path] = state
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			done(err)
+			return nil, err
+		}
+	}
+
+	done(nil)
+	return states, nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestCheckFilesIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "a.jpg", Path: "album/a.jpg", ParentPath: "album", Type: FileTypeImage, FileHash: "hash-a"},
+		{Name: "b.mp4", Path: "album/b.mp4", ParentPath: "album", Type: FileTypeVideo},
+	})
+
+	states, err := db.CheckFiles(context.Background(), []string{"album/a.jpg", "album/b.mp4", "album/gone.jpg"})
+	if err != nil {
+		t.Fatalf("CheckFiles failed: %v", err)
+	}
+	if len(states) != 3 {
+		t.Fatalf("Expected a state for each of 3 paths, got %d", len(states))
+	}
+
+	a := states["album/a.jpg"]
+	if !a.Exists || a.Hash != "hash-a" || a.Size != 1024 || a.ModTime == nil {
+		t.Errorf("Unexpected state for indexed file: %+v", a)
+	}
+	if b := states["album/b.mp4"]; !b.Exists || b.Hash != "" {
+		t.Errorf("Expected indexed file without hash, got %+v", b)
+	}
+	if gone := states["album/gone.jpg"]; gone.Exists || gone.ModTime != nil {
+		t.Errorf("Expected missing file to be reported as not existing, got %+v", gone)
+	}
+}
+
+func TestCheckFilesIntegration_ManyPaths(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	// Spans several query chunks
+	files := make([]MediaFile, checkFilesChunkSize+10)
+	paths := make([]string, 0, len(files)+1)
+	for i := range files {
+		name := fmt.Sprintf("%04d.jpg", i)
+		files[i] = MediaFile{Name: name, Path: "many/" + name, ParentPath: "many", Type: FileTypeImage}
+		paths = append(paths, files[i].Path)
+	}
+	insertOrderTestFiles(t, db, files)
+	paths = append(paths, "many/missing.jpg")
+
+	states, err := db.CheckFiles(context.Background(), paths)
+	if err != nil {
+		t.Fatalf("CheckFiles failed: %v", err)
+	}
+
+	existing := 0
+	for _, state := range states {
+		if state.Exists {
+			existing++
+		}
+	}
+	if existing != len(files) || len(states) != len(paths) {
+		t.Errorf("Expected %d of %d paths to exist, got %d of %d", len(files), len(paths), existing, len(states))
+	}
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// ErrInvalidFolderOrder is returned by SetFolderOrder for paths outside the
+// folder or listed more than once.
+var ErrInvalidFolderOrder = errors.New("invalid folder order")
+
+// SetFolderOrder replaces the manual sort order of a folder. Paths are
+// positioned in the given order; items not listed are unpositioned and sort
+// after the positioned ones by name. An empty list clears the order.
+// Every path must be a direct child of folderPath and appear only once.
+func (d *Database) SetFolderOrder(ctx context.Context, folderPath string, paths []string) error {
+	done := observeQuery("set_folder_order")
+
+	if folderPath == "." {
+		folderPath = ""
+	}
+
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if parentOf(path) != folderPath {
+			err := fmt.Errorf("%w: %q is not in folder %q", ErrInvalidFolderOrder, path, folderPath)
+			done(err)
+			return err
+		}
+		if seen[path] {
+			err := fmt.Errorf("%w: %q is listed more than once", ErrInvalidFolderOrder, path)
+			done(err)
+			return err
+		}
+		seen[path] = true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		done(err)
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM folder_order WHERE folder_path = ?", folderPath); err != nil {
+		err = fmt.Errorf("failed to clear folder order: %w", err)
+		done(err)
+		return err
+	}
+
+	if len(paths) > 0 {
+		stmt, err := tx.PrepareContext(ctx, "INSERT INTO folder_order (file_path, folder_path, sort_order) VALUES (?, ?, ?)")
+		if err != nil {
+			done(err)
+			return err
+		}
+		defer stmt.Close()
+
+		for i, path := range paths {
+			if _, err := stmt.ExecContext(ctx, path, folderPath, i); err != nil {
+				err = fmt.Errorf("failed to store position of %s: %w", path, err)
+				done(err)
+				return err
+			}
+		}
+	}
+
+	err = tx.Commit()
+	done(err)
+	return err
+}
+
+// GetFolderOrder returns the manually ordered paths of a folder, in order.
+// Returns an empty slice if the folder has no manual order.
+func (d *Database) GetFolderOrder(ctx context.Context, folderPath string) ([]string, error) {
+	done := observeQuery("get_folder_order")
+
+	if folderPath == "." {
+		folderPath = ""
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	rows, err := d.db.QueryContext(ctx,
+		"SELECT file_path FROM folder_order WHERE folder_path = ? ORDER BY sort_order",
+		folderPath,
+	)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := []string{}
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			done(err)
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	err = rows.Err()
+	done(err)
+	return paths, err
+}
+
+// parentOf returns the parent_path value stored for a relative path
+func parentOf(path string) string {
+	parent := filepath.Dir(path)
+	if parent == "." {
+		return ""
+	}
+	return parent
+}
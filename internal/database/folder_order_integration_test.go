@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func insertOrderTestFiles(t *testing.T, db *Database, files []MediaFile) {
+	t.Helper()
+
+	ctx := context.Background()
+	tx, err := db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	for i := range files {
+		files[i].Size = 1024
+		files[i].ModTime = time.Now()
+		if err := db.UpsertFile(ctx, tx, &files[i]); err != nil {
+			t.Fatalf("UpsertFile failed: %v", err)
+		}
+	}
+	if err := db.EndBatch(tx, nil); err != nil {
+		t.Fatalf("EndBatch failed: %v", err)
+	}
+}
+
+func listingPaths(items []MediaFile) []string {
+	paths := make([]string, len(items))
+	for i := range items {
+		paths[i] = items[i].Path
+	}
+	return paths
+}
+
+func TestFolderOrderIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "album", Path: "album", ParentPath: "", Type: FileTypeFolder},
+		{Name: "a.jpg", Path: "album/a.jpg", ParentPath: "album", Type: FileTypeImage},
+		{Name: "b.jpg", Path: "album/b.jpg", ParentPath: "album", Type: FileTypeImage},
+		{Name: "c.mp4", Path: "album/c.mp4", ParentPath: "album", Type: FileTypeVideo},
+		{Name: "d.jpg", Path: "album/d.jpg", ParentPath: "album", Type: FileTypeImage},
+		{Name: "sub", Path: "album/sub", ParentPath: "album", Type: FileTypeFolder},
+	})
+
+	if err := db.SetFolderOrder(ctx, "album", []string{"album/c.mp4", "album/a.jpg"}); err != nil {
+		t.Fatalf("SetFolderOrder failed: %v", err)
+	}
+
+	order, err := db.GetFolderOrder(ctx, "album")
+	if err != nil {
+		t.Fatalf("GetFolderOrder failed: %v", err)
+	}
+	if !slices.Equal(order, []string{"album/c.mp4", "album/a.jpg"}) {
+		t.Errorf("Unexpected stored order: %v", order)
+	}
+
+	// Folders first, then the ordered items, then the rest by name
+	listing, err := db.ListDirectory(ctx, ListOptions{Path: "album", SortField: SortByManual, SortOrder: SortAsc})
+	if err != nil {
+		t.Fatalf("ListDirectory failed: %v", err)
+	}
+	want := []string{"album/sub", "album/c.mp4", "album/a.jpg", "album/b.jpg", "album/d.jpg"}
+	if got := listingPaths(listing.Items); !slices.Equal(got, want) {
+		t.Errorf("ListDirectory manual order = %v, want %v", got, want)
+	}
+
+	// Descending reverses the ordered items; unpositioned items stay last
+	listing, err = db.ListDirectory(ctx, ListOptions{Path: "album", SortField: SortByManual, SortOrder: SortDesc})
+	if err != nil {
+		t.Fatalf("ListDirectory failed: %v", err)
+	}
+	want = []string{"album/sub", "album/a.jpg", "album/c.mp4", "album/b.jpg", "album/d.jpg"}
+	if got := listingPaths(listing.Items); !slices.Equal(got, want) {
+		t.Errorf("ListDirectory manual order desc = %v, want %v", got, want)
+	}
+
+	media, err := db.GetMediaInDirectory(ctx, "album", SortByManual, SortAsc)
+	if err != nil {
+		t.Fatalf("GetMediaInDirectory failed: %v", err)
+	}
+	want = []string{"album/c.mp4", "album/a.jpg", "album/b.jpg", "album/d.jpg"}
+	if got := listingPaths(media); !slices.Equal(got, want) {
+		t.Errorf("GetMediaInDirectory manual order = %v, want %v", got, want)
+	}
+
+	// Setting a new order replaces the old one
+	if err := db.SetFolderOrder(ctx, "album", []string{"album/d.jpg"}); err != nil {
+		t.Fatalf("SetFolderOrder failed: %v", err)
+	}
+	order, _ = db.GetFolderOrder(ctx, "album")
+	if !slices.Equal(order, []string{"album/d.jpg"}) {
+		t.Errorf("Expected order to be replaced, got %v", order)
+	}
+
+	// An empty list clears it
+	if err := db.SetFolderOrder(ctx, "album", nil); err != nil {
+		t.Fatalf("SetFolderOrder failed: %v", err)
+	}
+	order, _ = db.GetFolderOrder(ctx, "album")
+	if len(order) != 0 {
+		t.Errorf("Expected order to be cleared, got %v", order)
+	}
+}
+
+func TestSetFolderOrderInvalid(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		folder string
+		paths  []string
+	}{
+		{"other folder", "album", []string{"album/a.jpg", "other/b.jpg"}},
+		{"nested path", "album", []string{"album/sub/a.jpg"}},
+		{"root file in subfolder order", "album", []string{"a.jpg"}},
+		{"duplicate", "album", []string{"album/a.jpg", "album/a.jpg"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := db.SetFolderOrder(ctx, tt.folder, tt.paths)
+			if !errors.Is(err, ErrInvalidFolderOrder) {
+				t.Errorf("Expected ErrInvalidFolderOrder, got %v", err)
+			}
+		})
+	}
+
+	// Root folder accepts top-level paths
+	if err := db.SetFolderOrder(ctx, "", []string{"b.jpg", "a.jpg"}); err != nil {
+		t.Errorf("Expected root folder order to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"media-viewer/internal/logging"
+)
+
+// DefaultFTSRebuildBatchSize is the number of rows RebuildFTSInBatches
+// indexes at a time when no batch size is given
+const DefaultFTSRebuildBatchSize = 5000
+
+// MaxFTSRebuildBatchSize bounds the batch size of RebuildFTSInBatches
+const MaxFTSRebuildBatchSize = 100000
+
+// ErrFTSRebuildRunning is returned when a full-text index rebuild is started
+// while another is running.
+var ErrFTSRebuildRunning = errors.New("full-text index rebuild already running")
+
+// FTSRebuildStatus reports the progress of the running or most recent
+// rebuild of the full-text search indexes.
+type FTSRebuildStatus struct {
+	Running    bool       `json:"running"`
+	Processed  int        `json:"processed"` // Files and notes indexed so far
+	Total      int        `json:"total"`
+	BatchSize  int        `json:"batchSize,omitempty"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// ftsIndex is a full-text index and the table its content is read from
+type ftsIndex struct {
+	name    string // FTS5 table
+	content string // Content table
+	columns string // Indexed columns, in the order of both tables
+}
+
+// ftsIndexes are the full-text indexes RebuildFTSInBatches rebuilds
+var ftsIndexes = []ftsIndex{
+	{name: "files_fts", content: "files", columns: "name, path"},
+	{name: "file_notes_fts", content: "file_notes", columns: "description"},
+}
+
+// FTSRebuildStatus returns the progress of the running or most recent
+// RebuildFTSInBatches.
+func (d *Database) FTSRebuildStatus() FTSRebuildStatus {
+	d.statsMu.RLock()
+	defer d.statsMu.RUnlock()
+	return d.ftsRebuild
+}
+
+// RebuildFTSInBatches repopulates the full-text search indexes of file names
+// and notes from their tables, batchSize rows at a time, reporting progress
+// through FTSRebuildStatus. It runs in a single transaction under the lock
+// the indexer takes for its batches, so indexing and other database access
+// wait until it commits, and a failed rebuild leaves the old indexes in
+// place. A batch size of 0 or less uses DefaultFTSRebuildBatchSize. Returns
+// ErrFTSRebuildRunning if a rebuild is already running.
+func (d *Database) RebuildFTSInBatches(ctx context.Context, batchSize int) (err error) {
+	if batchSize <= 0 {
+		batchSize = DefaultFTSRebuildBatchSize
+	}
+	batchSize = min(batchSize, MaxFTSRebuildBatchSize)
+
+	started := time.Now()
+	d.statsMu.Lock()
+	if d.ftsRebuild.Running {
+		d.statsMu.Unlock()
+		return ErrFTSRebuildRunning
+	}
+	d.ftsRebuild = FTSRebuildStatus{Running: true, BatchSize: batchSize, StartedAt: &started}
+	d.statsMu.Unlock()
+
+	defer func() {
+		finished := time.Now()
+		d.statsMu.Lock()
+		d.ftsRebuild.Running = false
+		d.ftsRebuild.FinishedAt = &finished
+		if err != nil {
+			d.ftsRebuild.Error = err.Error()
+		}
+		d.statsMu.Unlock()
+	}()
+
+	tx, err := d.BeginBatch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin full-text index rebuild: %w", err)
+	}
+	defer func() {
+		err = d.EndBatch(tx, err)
+	}()
+
+	total := 0
+	for _, index := range ftsIndexes {
+		var count int
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s", index.content) //nolint:gosec // G201 - table and column names come from ftsIndexes; SQL identifiers cannot be parameterized
+		if err := tx.QueryRowContext(ctx, query).Scan(&count); err != nil {
+			return fmt.Errorf("failed to count %s: %w", index.content, err)
+		}
+		total += count
+	}
+	d.statsMu.Lock()
+	d.ftsRebuild.Total = total
+	d.statsMu.Unlock()
+
+	logging.Info("Rebuilding full-text search indexes: %d rows in batches of %d", total, batchSize)
+
+	for _, index := range ftsIndexes {
+		if err := d.rebuildFTSIndex(ctx, tx, index, batchSize); err != nil {
+			return err
+		}
+	}
+
+	logging.Info("Rebuilt full-text search indexes in %v", time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// rebuildFTSIndex empties a full-text index and indexes its content table in
+// order of rowid, a batch at a time
+func (d *Database) rebuildFTSIndex(ctx context.Context, tx *sql.Tx, index ftsIndex, batchSize int) error {
+	deleteAll := fmt.Sprintf("INSERT INTO %s(%s) VALUES('delete-all')", index.name, index.name) //nolint:gosec // G201 - table and column names come from ftsIndexes; SQL identifiers cannot be parameterized
+	if _, err := tx.ExecContext(ctx, deleteAll); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", index.name, err)
+	}
+
+	nextBatch := fmt.Sprintf("SELECT COALESCE(MAX(id), 0) FROM (SELECT id FROM %s WHERE id > ? ORDER BY id LIMIT ?)", index.content) //nolint:gosec // G201 - table and column names come from ftsIndexes; SQL identifiers cannot be parameterized
+
+	insert := fmt.Sprintf("INSERT INTO %s(rowid, %s) SELECT id, %s FROM %s WHERE id > ? AND id <= ?", index.name, index.columns, index.columns, index.content) //nolint:gosec // G201 - table and column names come from ftsIndexes; SQL identifiers cannot be parameterized
+
+	for last := int64(0); ; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var through int64
+		if err := tx.QueryRowContext(ctx, nextBatch, last, batchSize).Scan(&through); err != nil {
+			return fmt.Errorf("failed to read %s: %w", index.content, err)
+		}
+		if through == 0 {
+			return nil
+		}
+
+		done := observeQuery("rebuild_fts")
+		result, err := tx.ExecContext(ctx, insert, last, through)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to rebuild %s: %w", index.name, err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to rebuild %s: %w", index.name, err)
+		}
+
+		d.statsMu.Lock()
+		d.ftsRebuild.Processed += int(rows)
+		d.statsMu.Unlock()
+		last = through
+	}
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRebuildFTSInBatchesIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "lighthouse.jpg", Path: "lighthouse.jpg", Type: FileTypeImage},
+		{Name: "harbor.jpg", Path: "harbor.jpg", Type: FileTypeImage},
+		{Name: "dunes.jpg", Path: "dunes.jpg", Type: FileTypeImage},
+		{Name: "pier.jpg", Path: "pier.jpg", Type: FileTypeImage},
+		{Name: "IMG_0001.jpg", Path: "IMG_0001.jpg", Type: FileTypeImage},
+	})
+	if err := db.SetFileDescription(ctx, "IMG_0001.jpg", "Boardwalk at dusk"); err != nil {
+		t.Fatalf("SetFileDescription failed: %v", err)
+	}
+
+	// Empty both indexes, as if they had fallen out of sync
+	for _, index := range ftsIndexes {
+		if _, err := db.db.ExecContext(ctx, "INSERT INTO "+index.name+"("+index.name+") VALUES('delete-all')"); err != nil {
+			t.Fatalf("failed to clear %s: %v", index.name, err)
+		}
+	}
+	if result, _ := db.Search(ctx, SearchOptions{Query: "harbor"}); result.TotalItems != 0 {
+		t.Fatalf("Expected no matches from the emptied index, got %v", listingPaths(result.Items))
+	}
+
+	if err := db.RebuildFTSInBatches(ctx, 2); err != nil {
+		t.Fatalf("RebuildFTSInBatches failed: %v", err)
+	}
+
+	status := db.FTSRebuildStatus()
+	if status.Running || status.Processed != 6 || status.Total != 6 || status.BatchSize != 2 || status.Error != "" {
+		t.Errorf("Expected a finished rebuild of 6 rows, got %+v", status)
+	}
+	if status.StartedAt == nil || status.FinishedAt == nil || status.FinishedAt.Before(*status.StartedAt) {
+		t.Errorf("Expected start and finish times, got %v and %v", status.StartedAt, status.FinishedAt)
+	}
+
+	for _, query := range []string{"harbor", "pier", "boardwalk"} {
+		if result, err := db.Search(ctx, SearchOptions{Query: query}); err != nil || result.TotalItems != 1 {
+			t.Errorf("Expected one match for %q after the rebuild, got %d (error: %v)", query, result.TotalItems, err)
+		}
+	}
+
+	// Rebuilding an index that is in sync changes nothing, and triggers
+	// keep it in sync afterwards
+	if err := db.RebuildFTSInBatches(ctx, 0); err != nil {
+		t.Fatalf("RebuildFTSInBatches failed: %v", err)
+	}
+	if status := db.FTSRebuildStatus(); status.BatchSize != DefaultFTSRebuildBatchSize || status.Processed != 6 {
+		t.Errorf("Expected the default batch size, got %+v", status)
+	}
+	if err := db.SetFileDescription(ctx, "dunes.jpg", "Windswept"); err != nil {
+		t.Fatalf("SetFileDescription failed: %v", err)
+	}
+	if result, _ := db.Search(ctx, SearchOptions{Query: "windswept"}); result.TotalItems != 1 {
+		t.Errorf("Expected the new note to match, got %v", listingPaths(result.Items))
+	}
+	if result, _ := db.Search(ctx, SearchOptions{Query: "lighthouse"}); result.TotalItems != 1 {
+		t.Errorf("Expected one match for lighthouse, got %v", listingPaths(result.Items))
+	}
+}
+
+func TestRebuildFTSInBatchesRunningIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	db.statsMu.Lock()
+	db.ftsRebuild.Running = true
+	db.statsMu.Unlock()
+
+	if err := db.RebuildFTSInBatches(context.Background(), 10); !errors.Is(err, ErrFTSRebuildRunning) {
+		t.Errorf("Expected ErrFTSRebuildRunning, got %v", err)
+	}
+	if !db.FTSRebuildStatus().Running {
+		t.Error("Expected the running rebuild's status to be kept")
+	}
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"media-viewer/internal/logging"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// ErrCorrupt reports a database file that is damaged or not a SQLite
+// database, as left behind by a power loss or failing storage.
+var ErrCorrupt = errors.New("database is corrupt")
+
+// integrityCheckTimeout bounds the startup integrity check, which reads the
+// whole database file. A var so tests can shorten it.
+var integrityCheckTimeout = 2 * time.Minute
+
+// checkIntegrity runs SQLite's quick_check, which verifies the structure of
+// every table and index but, unlike integrity_check, not that indexes match
+// their tables. Returns an error wrapping ErrCorrupt if it finds damage. A
+// check that times out or is canceled leaves the database unverified: it is
+// logged and nil is returned, so a slow disk doesn't stop startup.
+func checkIntegrity(ctx context.Context, db *sql.DB) error {
+	done := observeQuery("integrity_check")
+
+	ctx, cancel := context.WithTimeout(ctx, integrityCheckTimeout)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, "PRAGMA quick_check")
+	if err != nil {
+		done(err)
+		return integrityCheckError(ctx, err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			done(err)
+			return integrityCheckError(ctx, err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		done(err)
+		return integrityCheckError(ctx, err)
+	}
+	done(nil)
+
+	if len(problems) > 0 {
+		const maxReported = 3
+		if len(problems) > maxReported {
+			problems = append(problems[:maxReported], fmt.Sprintf("and %d more", len(problems)-maxReported))
+		}
+		return fmt.Errorf("%w: %s", ErrCorrupt, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// integrityCheckError returns the error for a quick_check that failed with
+// err, or nil after logging a warning if ctx ended before it finished
+func integrityCheckError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		logging.Warn("Database integrity check did not finish (%v); continuing without it", ctx.Err())
+		return nil
+	}
+	return corruptionError(fmt.Errorf("integrity check failed: %w", err))
+}
+
+// corruptionError wraps err with ErrCorrupt if SQLite reported a malformed
+// database or a file that is not a database, and returns it unchanged otherwise
+func corruptionError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrCorrupt || sqliteErr.Code == sqlite3.ErrNotADB) {
+		return fmt.Errorf("%w: %w", ErrCorrupt, err)
+	}
+	return err
+}
+
+// moveAside renames a corrupt database, with its WAL and shared-memory files,
+// to a timestamped name next to it, so it can be inspected or recovered with
+// the sqlite3 CLI later. Returns the new path of the database file.
+func moveAside(dbPath string) (string, error) {
+	movedTo := dbPath + ".corrupt-" + time.Now().Format("20060102-150405")
+	if err := os.Rename(dbPath, movedTo); err != nil {
+		return "", err
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Rename(dbPath+suffix, movedTo+suffix); err != nil && !os.IsNotExist(err) {
+			return movedTo, fmt.Errorf("failed to move %s: %w", dbPath+suffix, err)
+		}
+	}
+	return movedTo, nil
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCorruptDB writes a file that SQLite can't read as a database
+func writeCorruptDB(t *testing.T) string {
+	t.Helper()
+
+	dbPath := filepath.Join(t.TempDir(), "media.db")
+	garbage := make([]byte, 8192)
+	for i := range garbage {
+		garbage[i] = byte(i * 31)
+	}
+	if err := os.WriteFile(dbPath, garbage, 0o644); err != nil {
+		t.Fatalf("Failed to write corrupt database: %v", err)
+	}
+	return dbPath
+}
+
+func TestNewRejectsCorruptDatabaseIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	dbPath := writeCorruptDB(t)
+
+	db, _, err := New(context.Background(), dbPath, nil)
+	if err == nil {
+		db.Close()
+		t.Fatal("Expected an error opening a corrupt database")
+	}
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Expected ErrCorrupt, got %v", err)
+	}
+
+	// Without RecoverCorrupt the file is left alone
+	if _, err := os.Stat(dbPath); err != nil {
+		t.Errorf("Expected the corrupt database to stay in place: %v", err)
+	}
+}
+
+func TestNewRecoversCorruptDatabaseIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	dbPath := writeCorruptDB(t)
+
+	db, info, err := New(context.Background(), dbPath, &Options{RecoverCorrupt: true})
+	if err != nil {
+		t.Fatalf("Expected recovery to succeed, got %v", err)
+	}
+	defer db.Close()
+
+	if info.RecoveredFrom == "" {
+		t.Fatal("Expected RecoveredFrom to be set")
+	}
+	if filepath.Dir(info.RecoveredFrom) != filepath.Dir(dbPath) {
+		t.Errorf("Expected the corrupt database next to the original, got %s", info.RecoveredFrom)
+	}
+	if _, err := os.Stat(info.RecoveredFrom); err != nil {
+		t.Errorf("Expected the corrupt database at %s: %v", info.RecoveredFrom, err)
+	}
+
+	// The new database is empty and usable
+	if db.HasUsers(context.Background()) {
+		t.Error("Expected the recreated database to have no users")
+	}
+	if _, err := db.GetCollections(context.Background()); err != nil {
+		t.Errorf("GetCollections on the recreated database failed: %v", err)
+	}
+}
+
+func TestNewHealthyDatabaseNotRecoveredIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	db, dbPath := setupTestDB(t)
+	db.Close()
+
+	db, info, err := New(context.Background(), dbPath, &Options{RecoverCorrupt: true})
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	if info.RecoveredFrom != "" {
+		t.Errorf("Expected a healthy database to be kept, got RecoveredFrom %s", info.RecoveredFrom)
+	}
+	if err := checkIntegrity(context.Background(), db.db); err != nil {
+		t.Errorf("checkIntegrity on a healthy database: %v", err)
+	}
+}
+
+func TestMoveAside(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "media.db")
+	for _, name := range []string{dbPath, dbPath + "-wal"} {
+		if err := os.WriteFile(name, []byte(filepath.Base(name)), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	movedTo, err := moveAside(dbPath)
+	if err != nil {
+		t.Fatalf("moveAside failed: %v", err)
+	}
+
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be gone, got %v", dbPath, err)
+	}
+	if data, err := os.ReadFile(movedTo + "-wal"); err != nil || string(data) != "media.db-wal" {
+		t.Errorf("Expected the WAL to move with the database, got %q, %v", data, err)
+	}
+	// There was no shared-memory file to move
+	if _, err := os.Stat(movedTo + "-shm"); !os.IsNotExist(err) {
+		t.Errorf("Expected no -shm file, got %v", err)
+	}
+}
+
+func TestNewIntegrityCheckTimeoutIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	db, dbPath := setupTestDB(t)
+	db.Close()
+
+	// The check's context has expired before quick_check starts
+	saved := integrityCheckTimeout
+	integrityCheckTimeout = time.Nanosecond
+	t.Cleanup(func() { integrityCheckTimeout = saved })
+
+	db, info, err := New(context.Background(), dbPath, &Options{RecoverCorrupt: true})
+	if err != nil {
+		t.Fatalf("Expected an unverified database to open, got %v", err)
+	}
+	defer db.Close()
+
+	if info.RecoveredFrom != "" {
+		t.Errorf("Expected an unverified database to be kept, got RecoveredFrom %s", info.RecoveredFrom)
+	}
+}
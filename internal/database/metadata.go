@@ -3,7 +3,9 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -72,3 +74,58 @@ func (d *Database) SetLastThumbnailRun(ctx context.Context, t time.Time) error {
 	}
 	return d.SetMetadata(ctx, "last_thumbnail_run", t.Format(time.RFC3339))
 }
+
+// GetContentHashOptions returns the options the recorded content hashes
+// were made with, or "" if none were recorded.
+func (d *Database) GetContentHashOptions(ctx context.Context) (string, error) {
+	value, err := d.GetMetadata(ctx, "content_hash_options")
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+// SetContentHashOptions stores the options content hashes are made with.
+func (d *Database) SetContentHashOptions(ctx context.Context, options string) error {
+	return d.SetMetadata(ctx, "content_hash_options", options)
+}
+
+// ThumbnailCheckpoint records how far a thumbnail generation run got, so a run
+// interrupted by a restart can resume instead of starting over.
+type ThumbnailCheckpoint struct {
+	StartedAt   time.Time `json:"startedAt"`   // Start of the interrupted run
+	Incremental bool      `json:"incremental"` // Whether it was an incremental run
+	Phase       string    `json:"phase"`       // Pass of the run in progress
+	LastPath    string    `json:"lastPath"`    // Last path completed in Phase, "" if none
+}
+
+// GetThumbnailCheckpoint returns the checkpoint of an interrupted thumbnail
+// generation run, or nil if there is none.
+func (d *Database) GetThumbnailCheckpoint(ctx context.Context) (*ThumbnailCheckpoint, error) {
+	value, err := d.GetMetadata(ctx, "thumbnail_checkpoint")
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && value == "") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint ThumbnailCheckpoint
+	if err := json.Unmarshal([]byte(value), &checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid thumbnail checkpoint: %w", err)
+	}
+	return &checkpoint, nil
+}
+
+// SetThumbnailCheckpoint stores the progress of the running thumbnail
+// generation. A nil checkpoint clears it.
+func (d *Database) SetThumbnailCheckpoint(ctx context.Context, checkpoint *ThumbnailCheckpoint) error {
+	if checkpoint == nil {
+		return d.SetMetadata(ctx, "thumbnail_checkpoint", "")
+	}
+	value, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	return d.SetMetadata(ctx, "thumbnail_checkpoint", string(value))
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"testing"
+	"time"
 )
 
 func TestGetMetadataIntegration(t *testing.T) {
@@ -229,3 +230,50 @@ func TestMetadataConcurrencyIntegration(t *testing.T) {
 		}
 	}
 }
+
+func TestThumbnailCheckpointIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	checkpoint, err := db.GetThumbnailCheckpoint(ctx)
+	if err != nil || checkpoint != nil {
+		t.Fatalf("Expected no checkpoint initially, got %+v, %v", checkpoint, err)
+	}
+
+	want := ThumbnailCheckpoint{
+		StartedAt:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Incremental: true,
+		Phase:       "files",
+		LastPath:    "photos/2024/img_0042.jpg",
+	}
+	if err := db.SetThumbnailCheckpoint(ctx, &want); err != nil {
+		t.Fatalf("SetThumbnailCheckpoint failed: %v", err)
+	}
+	checkpoint, err = db.GetThumbnailCheckpoint(ctx)
+	if err != nil || checkpoint == nil {
+		t.Fatalf("GetThumbnailCheckpoint = %+v, %v", checkpoint, err)
+	}
+	if !checkpoint.StartedAt.Equal(want.StartedAt) || checkpoint.Incremental != want.Incremental ||
+		checkpoint.Phase != want.Phase || checkpoint.LastPath != want.LastPath {
+		t.Errorf("Expected %+v, got %+v", want, *checkpoint)
+	}
+
+	if err := db.SetThumbnailCheckpoint(ctx, nil); err != nil {
+		t.Fatalf("Clearing the checkpoint failed: %v", err)
+	}
+	if checkpoint, err = db.GetThumbnailCheckpoint(ctx); err != nil || checkpoint != nil {
+		t.Errorf("Expected no checkpoint after clearing, got %+v, %v", checkpoint, err)
+	}
+
+	if err := db.SetMetadata(ctx, "thumbnail_checkpoint", "{"); err != nil {
+		t.Fatalf("SetMetadata failed: %v", err)
+	}
+	if _, err := db.GetThumbnailCheckpoint(ctx); err == nil {
+		t.Error("Expected an error for a corrupt checkpoint")
+	}
+}
@@ -38,8 +38,16 @@ type MediaFile struct {
 	ThumbnailURL string    `json:"thumbnailUrl,omitempty"`
 	ItemCount    int       `json:"itemCount,omitempty"`
 	FileHash     string    `json:"-"`
+	BirthTime    time.Time `json:"-"` // Zero when unknown; written by the indexer, not read back
+	Camera       string    `json:"-"` // From EXIF; empty when unknown, written by the indexer, not read back
+	Lens         string    `json:"-"` // From EXIF, like Camera
+	CapturedAt   time.Time `json:"-"` // EXIF capture date; zero when unknown, written by the indexer, not read back
 	IsFavorite   bool      `json:"isFavorite,omitempty"`
 	Tags         []string  `json:"tags,omitempty"`
+	Description  string    `json:"description,omitempty"` // Note attached with SetFileDescription
+	Sensitive    bool      `json:"sensitive,omitempty"`   // Flagged with SetFileSensitive; thumbnails are obscured
+	Animated     bool      `json:"animated,omitempty"`    // Multi-frame GIF, APNG or WebP; recorded with SetFileAnimated
+	Score        float64   `json:"score,omitempty"`       // Search relevance, higher is better; only set in text search results
 }
 
 // Tag represents a label that can be applied to media files.
@@ -88,6 +96,63 @@ type SearchResult struct {
 	Page       int         `json:"page"`
 	PageSize   int         `json:"pageSize"`
 	TotalPages int         `json:"totalPages"`
+
+	// "Did you mean" suggestions, returned when nothing matched
+	Suggestions []SearchSuggestion `json:"suggestions,omitempty"`
+}
+
+// FavoritesListing is a page of favorites.
+type FavoritesListing struct {
+	Items      []MediaFile `json:"items"`
+	TotalItems int         `json:"totalItems"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"pageSize"`
+	TotalPages int         `json:"totalPages"`
+}
+
+// Collection is a named, ordered set of files that can span folders.
+// Collections are global to the library, not scoped per user.
+type Collection struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	ItemCount int       `json:"itemCount"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CollectionListing is a page of a collection's items.
+type CollectionListing struct {
+	Collection Collection  `json:"collection"`
+	Items      []MediaFile `json:"items"`
+	TotalItems int         `json:"totalItems"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"pageSize"`
+	TotalPages int         `json:"totalPages"`
+}
+
+// FilePalette pairs an indexed media file with its stored dominant colors.
+type FilePalette struct {
+	File   MediaFile
+	Colors []string
+}
+
+// ColorMatch is a media file returned by color search, with its palette and
+// distance to the requested color (lower is closer).
+type ColorMatch struct {
+	MediaFile
+	Palette  []string `json:"palette"`
+	Distance float64  `json:"distance"`
+}
+
+// ColorSearchResult represents paginated results of a dominant-color search.
+type ColorSearchResult struct {
+	Items      []ColorMatch `json:"items"`
+	Color      string       `json:"color"`
+	Threshold  float64      `json:"threshold"`
+	TotalItems int          `json:"totalItems"`
+	Page       int          `json:"page"`
+	PageSize   int          `json:"pageSize"`
+	TotalPages int          `json:"totalPages"`
 }
 
 // SearchSuggestion represents an autocomplete suggestion for search.
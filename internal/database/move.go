@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// pathColumns lists the tables, other than files, that refer to files by
+// path, and the column holding it. Moving a file carries these along.
+var pathColumns = []struct{ table, column string }{
+	{"favorites", "path"},
+	{"file_tags", "file_path"},
+	{"sidecar_tags", "file_path"},
+	{"file_notes", "file_path"},
+	{"file_palettes", "file_path"},
+	{"folder_order", "file_path"},
+	{"sensitive_files", "file_path"},
+	{"video_posters", "file_path"},
+	{"collection_items", "file_path"},
+}
+
+// MoveFiles moves the index entry of a file or folder from one path to
+// another, along with everything in the folder. Favorites, tags, notes and
+// the other data kept by path move with the entries. Any entries still
+// recorded at the destination, left behind by files that are gone, are
+// replaced. Returns the moved entries with the paths they had before.
+func (d *Database) MoveFiles(ctx context.Context, from, to string) ([]MediaFile, error) {
+	from = strings.Trim(from, "/")
+	to = strings.Trim(to, "/")
+	if from == "" || to == "" {
+		return nil, errors.New("paths must not be empty")
+	}
+	if to == from || strings.HasPrefix(to, from+"/") {
+		return nil, fmt.Errorf("cannot move %s into itself", from)
+	}
+
+	done := observeQuery("move_files")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// A range on the path index instead of LIKE, as in DeleteFilesByPrefix
+	const match = "(%[1]s = ? OR (%[1]s >= ? AND %[1]s < ?))"
+	fromArgs := []any{from, from + "/", from + "0"}
+	toArgs := []any{to, to + "/", to + "0"}
+
+	moved, err := scanMovedFiles(tx.QueryContext(ctx, "SELECT id, name, path, parent_path, type FROM files WHERE "+fmt.Sprintf(match, "path")+" ORDER BY path", fromArgs...))
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+
+	// SQLite counts characters, not bytes, in substr
+	rest := utf8.RuneCountInString(from) + 1
+	newParent, newName := path.Split(to)
+	newParent = strings.TrimSuffix(newParent, "/")
+	exec := func(query string, args ...any) error {
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	}
+
+	// Stale entries at the destination go first: deleting them fires the
+	// triggers clearing their palettes and manual order, which would
+	// otherwise take the data just moved in with them
+	if err := exec("DELETE FROM files WHERE "+fmt.Sprintf(match, "path"), toArgs...); err != nil {
+		done(err)
+		return nil, err
+	}
+
+	for _, pc := range pathColumns {
+		where := fmt.Sprintf(match, pc.column)
+		if err := exec("DELETE FROM "+pc.table+" WHERE "+where, toArgs...); err != nil {
+			done(err)
+			return nil, err
+		}
+		if err := exec("UPDATE "+pc.table+" SET "+pc.column+" = ? || substr("+pc.column+", ?) WHERE "+where,
+			append([]any{to, rest}, fromArgs...)...); err != nil {
+			done(err)
+			return nil, err
+		}
+	}
+
+	if err := exec("UPDATE favorites SET name = ? WHERE path = ?", newName, to); err != nil {
+		done(err)
+		return nil, err
+	}
+
+	// The folder's own manual order moves with it; its place in the old
+	// folder's order is dropped unless it was only renamed
+	if err := exec("UPDATE folder_order SET folder_path = ? || substr(folder_path, ?) WHERE "+fmt.Sprintf(match, "folder_path"),
+		append([]any{to, rest}, fromArgs...)...); err != nil {
+		done(err)
+		return nil, err
+	}
+	if err := exec("DELETE FROM folder_order WHERE file_path = ? AND folder_path != ?", to, newParent); err != nil {
+		done(err)
+		return nil, err
+	}
+
+	if err := exec(`UPDATE files SET path = ? || substr(path, ?), parent_path = ? || substr(parent_path, ?), updated_at = strftime('%s', 'now')
+		WHERE path >= ? AND path < ?`, to, rest, to, rest, from+"/", from+"0"); err != nil {
+		done(err)
+		return nil, err
+	}
+	if err := exec("UPDATE files SET path = ?, parent_path = ?, name = ?, updated_at = strftime('%s', 'now') WHERE path = ?",
+		to, newParent, newName, from); err != nil {
+		done(err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	done(err)
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// scanMovedFiles reads the entries selected by MoveFiles
+func scanMovedFiles(rows *sql.Rows, err error) ([]MediaFile, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []MediaFile
+	for rows.Next() {
+		var file MediaFile
+		if err := rows.Scan(&file.ID, &file.Name, &file.Path, &file.ParentPath, &file.Type); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
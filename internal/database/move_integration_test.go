@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestMoveFilesIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "trips", Path: "trips", ParentPath: "", Type: FileTypeFolder},
+		{Name: "rome", Path: "trips/rome", ParentPath: "trips", Type: FileTypeFolder},
+		{Name: "a.jpg", Path: "trips/rome/a.jpg", ParentPath: "trips/rome", Type: FileTypeImage},
+		{Name: "b.jpg", Path: "trips/rome/b.jpg", ParentPath: "trips/rome", Type: FileTypeImage},
+		{Name: "rome2", Path: "trips/rome2", ParentPath: "trips", Type: FileTypeFolder},
+		{Name: "c.jpg", Path: "trips/rome2/c.jpg", ParentPath: "trips/rome2", Type: FileTypeImage},
+		{Name: "archive", Path: "archive", ParentPath: "", Type: FileTypeFolder},
+	})
+
+	if err := db.AddFavorite(ctx, "trips/rome", "rome", FileTypeFolder); err != nil {
+		t.Fatalf("AddFavorite failed: %v", err)
+	}
+	if err := db.AddTagToFile(ctx, "trips/rome/a.jpg", "sunset"); err != nil {
+		t.Fatalf("AddTagToFile failed: %v", err)
+	}
+	if err := db.SetFolderOrder(ctx, "trips/rome", []string{"trips/rome/b.jpg", "trips/rome/a.jpg"}); err != nil {
+		t.Fatalf("SetFolderOrder failed: %v", err)
+	}
+
+	moved, err := db.MoveFiles(ctx, "trips/rome", "archive/italy")
+	if err != nil {
+		t.Fatalf("MoveFiles failed: %v", err)
+	}
+	if got := listingPaths(moved); !slices.Equal(got, []string{"trips/rome", "trips/rome/a.jpg", "trips/rome/b.jpg"}) {
+		t.Errorf("moved = %v, want the folder and its files by their old paths", got)
+	}
+
+	folder, err := db.GetFileByPath(ctx, "archive/italy")
+	if err != nil {
+		t.Fatalf("Expected the folder at its new path: %v", err)
+	}
+	if folder.Name != "italy" || folder.ParentPath != "archive" {
+		t.Errorf("folder = %q in %q, want italy in archive", folder.Name, folder.ParentPath)
+	}
+	file, err := db.GetFileByPath(ctx, "archive/italy/a.jpg")
+	if err != nil {
+		t.Fatalf("Expected the file at its new path: %v", err)
+	}
+	if file.ParentPath != "archive/italy" {
+		t.Errorf("ParentPath = %q, want archive/italy", file.ParentPath)
+	}
+
+	// A sibling sharing the name as a prefix stays put
+	if _, err := db.GetFileByPath(ctx, "trips/rome2/c.jpg"); err != nil {
+		t.Errorf("Expected trips/rome2/c.jpg to be left alone: %v", err)
+	}
+	if _, err := db.GetFileByPath(ctx, "trips/rome/a.jpg"); err == nil {
+		t.Error("Expected nothing left at the old path")
+	}
+
+	if !db.IsFavorite(ctx, "archive/italy") || db.IsFavorite(ctx, "trips/rome") {
+		t.Error("Expected the favorite to move with the folder")
+	}
+	if tags, _ := db.GetFileTags(ctx, "archive/italy/a.jpg"); !slices.Equal(tags, []string{"sunset"}) {
+		t.Errorf("tags = %v, want [sunset]", tags)
+	}
+	if order, _ := db.GetFolderOrder(ctx, "archive/italy"); !slices.Equal(order, []string{"archive/italy/b.jpg", "archive/italy/a.jpg"}) {
+		t.Errorf("order = %v, want the manual order kept", order)
+	}
+
+	if _, err := db.MoveFiles(ctx, "archive", "archive/inner"); err == nil {
+		t.Error("Expected moving a folder into itself to fail")
+	}
+}
+
+func TestMoveFilesOntoStaleEntriesIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "new", Path: "new", ParentPath: "", Type: FileTypeFolder},
+		{Name: "a.jpg", Path: "new/a.jpg", ParentPath: "new", Type: FileTypeImage},
+		{Name: "b.jpg", Path: "new/b.jpg", ParentPath: "new", Type: FileTypeImage},
+		// Left in the index by a folder that is gone from disk
+		{Name: "old", Path: "old", ParentPath: "", Type: FileTypeFolder},
+		{Name: "a.jpg", Path: "old/a.jpg", ParentPath: "old", Type: FileTypeImage},
+		{Name: "b.jpg", Path: "old/b.jpg", ParentPath: "old", Type: FileTypeImage},
+	})
+
+	if err := db.SetFilePalette(ctx, "new/a.jpg", []string{"#112233"}); err != nil {
+		t.Fatalf("SetFilePalette failed: %v", err)
+	}
+	if err := db.SetFolderOrder(ctx, "new", []string{"new/b.jpg", "new/a.jpg"}); err != nil {
+		t.Fatalf("SetFolderOrder failed: %v", err)
+	}
+
+	if _, err := db.MoveFiles(ctx, "new", "old"); err != nil {
+		t.Fatalf("MoveFiles failed: %v", err)
+	}
+
+	if palette, _ := db.GetFilePalette(ctx, "old/a.jpg"); !slices.Equal(palette, []string{"#112233"}) {
+		t.Errorf("palette = %v, want it moved over the stale entry", palette)
+	}
+	if order, _ := db.GetFolderOrder(ctx, "old"); !slices.Equal(order, []string{"old/b.jpg", "old/a.jpg"}) {
+		t.Errorf("order = %v, want both entries of the manual order kept", order)
+	}
+	if _, err := db.GetFileByPath(ctx, "old/a.jpg"); err != nil {
+		t.Errorf("Expected the moved file at the destination: %v", err)
+	}
+}
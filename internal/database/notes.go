@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxDescriptionLength is the longest description, in characters, that can be
+// attached to a file.
+const MaxDescriptionLength = 4000
+
+// ErrDescriptionTooLong is returned by SetFileDescription for descriptions
+// longer than MaxDescriptionLength.
+var ErrDescriptionTooLong = fmt.Errorf("description is longer than %d characters", MaxDescriptionLength)
+
+// SetFileDescription attaches a free-text note to a file, replacing any
+// previous one. An empty or blank description removes the note. Like tags,
+// notes are kept by path, survive the file briefly disappearing from the
+// index, and are global to the library rather than scoped per user.
+func (d *Database) SetFileDescription(ctx context.Context, filePath, description string) error {
+	done := observeQuery("set_file_description")
+
+	description = strings.TrimSpace(description)
+	if utf8.RuneCountInString(description) > MaxDescriptionLength {
+		done(ErrDescriptionTooLong)
+		return ErrDescriptionTooLong
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var err error
+	if description == "" {
+		_, err = d.db.ExecContext(ctx, "DELETE FROM file_notes WHERE file_path = ?", filePath)
+	} else {
+		_, err = d.db.ExecContext(ctx, `
+			INSERT INTO file_notes (file_path, description, updated_at)
+			VALUES (?, ?, ?)
+			ON CONFLICT(file_path) DO UPDATE SET
+				description = excluded.description,
+				updated_at = excluded.updated_at
+		`, filePath, description, time.Now().Unix())
+	}
+	done(err)
+	return err
+}
+
+// GetFileDescription returns the note attached to a file, or "" if it has none.
+func (d *Database) GetFileDescription(ctx context.Context, filePath string) (string, error) {
+	done := observeQuery("get_file_description")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var description string
+	err := d.db.QueryRowContext(ctx, "SELECT description FROM file_notes WHERE file_path = ?", filePath).Scan(&description)
+	if errors.Is(err, sql.ErrNoRows) {
+		done(nil)
+		return "", nil
+	}
+	done(err)
+	return description, err
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFileDescriptionIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	const path = "trips/beach.jpg"
+
+	if got, err := db.GetFileDescription(ctx, path); err != nil || got != "" {
+		t.Fatalf("Expected no description, got %q, %v", got, err)
+	}
+
+	if err := db.SetFileDescription(ctx, path, "  Sunset at the pier  "); err != nil {
+		t.Fatalf("SetFileDescription failed: %v", err)
+	}
+	if got, _ := db.GetFileDescription(ctx, path); got != "Sunset at the pier" {
+		t.Errorf("Expected the trimmed description, got %q", got)
+	}
+
+	if err := db.SetFileDescription(ctx, path, "Sunrise instead"); err != nil {
+		t.Fatalf("SetFileDescription failed to replace: %v", err)
+	}
+	if got, _ := db.GetFileDescription(ctx, path); got != "Sunrise instead" {
+		t.Errorf("Expected the replaced description, got %q", got)
+	}
+
+	if err := db.SetFileDescription(ctx, path, " "); err != nil {
+		t.Fatalf("SetFileDescription failed to clear: %v", err)
+	}
+	if got, _ := db.GetFileDescription(ctx, path); got != "" {
+		t.Errorf("Expected a blank description to clear the note, got %q", got)
+	}
+
+	err := db.SetFileDescription(ctx, path, strings.Repeat("é", MaxDescriptionLength+1))
+	if !errors.Is(err, ErrDescriptionTooLong) {
+		t.Errorf("Expected ErrDescriptionTooLong, got %v", err)
+	}
+	if err := db.SetFileDescription(ctx, path, strings.Repeat("é", MaxDescriptionLength)); err != nil {
+		t.Errorf("Expected a description of exactly %d characters to be accepted, got %v", MaxDescriptionLength, err)
+	}
+}
+
+func TestSearchMatchesDescriptionsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "IMG_0001.jpg", Path: "IMG_0001.jpg", Type: FileTypeImage},
+		{Name: "IMG_0002.jpg", Path: "IMG_0002.jpg", Type: FileTypeImage},
+		{Name: "lighthouse.jpg", Path: "lighthouse.jpg", Type: FileTypeImage},
+	})
+
+	if err := db.SetFileDescription(ctx, "IMG_0001.jpg", "Grandma's lighthouse visit"); err != nil {
+		t.Fatalf("SetFileDescription failed: %v", err)
+	}
+
+	result, err := db.Search(ctx, SearchOptions{Query: "lighthouse", SortField: SortByName})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.TotalItems != 2 || len(result.Items) != 2 {
+		t.Fatalf("Expected the file name and the note to match, got %d items: %v", result.TotalItems, listingPaths(result.Items))
+	}
+	if result.Items[0].Path != "IMG_0001.jpg" || result.Items[0].Description != "Grandma's lighthouse visit" {
+		t.Errorf("Expected the noted file with its description first, got %+v", result.Items[0])
+	}
+
+	// Replacing the note updates the index
+	if err := db.SetFileDescription(ctx, "IMG_0001.jpg", "Harbor walk"); err != nil {
+		t.Fatalf("SetFileDescription failed: %v", err)
+	}
+	result, _ = db.Search(ctx, SearchOptions{Query: "lighthouse"})
+	if result.TotalItems != 1 {
+		t.Errorf("Expected the old note to no longer match, got %v", listingPaths(result.Items))
+	}
+
+	// Descriptions are returned in listings too
+	listing, err := db.ListDirectory(ctx, ListOptions{Path: ""})
+	if err != nil {
+		t.Fatalf("ListDirectory failed: %v", err)
+	}
+	for _, item := range listing.Items {
+		want := ""
+		if item.Path == "IMG_0001.jpg" {
+			want = "Harbor walk"
+		}
+		if item.Description != want {
+			t.Errorf("Expected description %q for %s, got %q", want, item.Path, item.Description)
+		}
+	}
+
+	if err := db.RebuildFTS(); err != nil {
+		t.Fatalf("RebuildFTS failed: %v", err)
+	}
+	result, _ = db.Search(ctx, SearchOptions{Query: "harbor"})
+	if result.TotalItems != 1 {
+		t.Errorf("Expected the note to match after a rebuild, got %v", listingPaths(result.Items))
+	}
+}
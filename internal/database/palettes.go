@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SetFilePalette stores the dominant colors for a file, replacing any previous palette.
+func (d *Database) SetFilePalette(ctx context.Context, path string, colors []string) error {
+	done := observeQuery("set_file_palette")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO file_palettes (file_path, colors, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(file_path) DO UPDATE SET
+			colors = excluded.colors,
+			updated_at = excluded.updated_at
+	`, path, strings.Join(colors, ","), time.Now().Unix())
+	done(err)
+	return err
+}
+
+// GetFilePalette returns the stored dominant colors for a file.
+// Returns an empty slice if the file has no palette.
+func (d *Database) GetFilePalette(ctx context.Context, path string) ([]string, error) {
+	done := observeQuery("get_file_palette")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var value string
+	err := d.db.QueryRowContext(ctx, "SELECT colors FROM file_palettes WHERE file_path = ?", path).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		done(nil)
+		return []string{}, nil
+	}
+	done(err)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitPalette(value), nil
+}
+
+// GetAllFilePalettes returns every indexed file that has a stored palette.
+// Palettes for files no longer in the index are excluded.
+func (d *Database) GetAllFilePalettes(ctx context.Context) ([]FilePalette, error) {
+	done := observeQuery("get_all_file_palettes")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type, p.colors
+		FROM file_palettes p
+		INNER JOIN files f ON f.path = p.file_path
+	`)
+	if err != nil {
+		done(err)
+		return nil, fmt.Errorf("failed to query palettes: %w", err)
+	}
+	defer rows.Close()
+
+	var palettes []FilePalette
+	for rows.Next() {
+		var entry FilePalette
+		var modTime int64
+		var mimeType sql.NullString
+		var colors string
+
+		if err := rows.Scan(
+			&entry.File.ID, &entry.File.Name, &entry.File.Path, &entry.File.ParentPath,
+			&entry.File.Type, &entry.File.Size, &modTime, &mimeType, &colors,
+		); err != nil {
+			done(err)
+			return nil, fmt.Errorf("failed to scan palette row: %w", err)
+		}
+
+		entry.File.ModTime = time.Unix(modTime, 0)
+		if mimeType.Valid {
+			entry.File.MimeType = mimeType.String
+		}
+		entry.Colors = splitPalette(colors)
+		palettes = append(palettes, entry)
+	}
+
+	err = rows.Err()
+	done(err)
+	return palettes, err
+}
+
+// GetFilesWithoutPalette returns the indexed images and videos that have no
+// stored palette, with their path, type and modification time set, so
+// palettes can be filled in from thumbnails cached before extraction was
+// enabled.
+func (d *Database) GetFilesWithoutPalette(ctx context.Context) ([]MediaFile, error) {
+	done := observeQuery("get_files_without_palette")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT f.path, f.type, f.mod_time
+		FROM files f
+		LEFT JOIN file_palettes p ON p.file_path = f.path
+		WHERE f.type IN (?, ?) AND p.file_path IS NULL
+		ORDER BY f.path
+	`, FileTypeImage, FileTypeVideo)
+	if err != nil {
+		done(err)
+		return nil, fmt.Errorf("failed to query files without palette: %w", err)
+	}
+	defer rows.Close()
+
+	var files []MediaFile
+	for rows.Next() {
+		var f MediaFile
+		var modTime int64
+		if err := rows.Scan(&f.Path, &f.Type, &modTime); err != nil {
+			done(err)
+			return nil, fmt.Errorf("failed to scan file row: %w", err)
+		}
+		f.ModTime = time.Unix(modTime, 0)
+		files = append(files, f)
+	}
+
+	err = rows.Err()
+	done(err)
+	return files, err
+}
+
+// splitPalette parses the comma-separated color list stored in file_palettes.
+func splitPalette(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
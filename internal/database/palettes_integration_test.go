@@ -0,0 +1,169 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func insertPaletteTestFile(t *testing.T, db *Database, path string) {
+	t.Helper()
+
+	ctx := context.Background()
+	tx, err := db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+
+	file := &MediaFile{
+		Name:    path,
+		Path:    path,
+		Type:    FileTypeImage,
+		Size:    1024,
+		ModTime: time.Now(),
+	}
+	if err := db.UpsertFile(ctx, tx, file); err != nil {
+		t.Fatalf("UpsertFile failed: %v", err)
+	}
+	if err := db.EndBatch(tx, nil); err != nil {
+		t.Fatalf("EndBatch failed: %v", err)
+	}
+}
+
+func TestFilePaletteIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	// Missing palette returns an empty slice, not an error
+	colors, err := db.GetFilePalette(ctx, "missing.jpg")
+	if err != nil {
+		t.Fatalf("GetFilePalette failed: %v", err)
+	}
+	if len(colors) != 0 {
+		t.Errorf("Expected empty palette, got %v", colors)
+	}
+
+	if err := db.SetFilePalette(ctx, "sunset.jpg", []string{"#ff8000", "#202040"}); err != nil {
+		t.Fatalf("SetFilePalette failed: %v", err)
+	}
+
+	colors, err = db.GetFilePalette(ctx, "sunset.jpg")
+	if err != nil {
+		t.Fatalf("GetFilePalette failed: %v", err)
+	}
+	if len(colors) != 2 || colors[0] != "#ff8000" || colors[1] != "#202040" {
+		t.Errorf("Unexpected palette: %v", colors)
+	}
+
+	// Overwrite replaces the previous palette
+	if err := db.SetFilePalette(ctx, "sunset.jpg", []string{"#112233"}); err != nil {
+		t.Fatalf("SetFilePalette update failed: %v", err)
+	}
+	colors, err = db.GetFilePalette(ctx, "sunset.jpg")
+	if err != nil {
+		t.Fatalf("GetFilePalette failed: %v", err)
+	}
+	if len(colors) != 1 || colors[0] != "#112233" {
+		t.Errorf("Expected updated palette, got %v", colors)
+	}
+}
+
+func TestGetAllFilePalettesIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	insertPaletteTestFile(t, db, "beach.jpg")
+
+	if err := db.SetFilePalette(ctx, "beach.jpg", []string{"#3080ff"}); err != nil {
+		t.Fatalf("SetFilePalette failed: %v", err)
+	}
+	// Palette for a path that isn't indexed should be excluded
+	if err := db.SetFilePalette(ctx, "orphan.jpg", []string{"#000000"}); err != nil {
+		t.Fatalf("SetFilePalette failed: %v", err)
+	}
+
+	palettes, err := db.GetAllFilePalettes(ctx)
+	if err != nil {
+		t.Fatalf("GetAllFilePalettes failed: %v", err)
+	}
+	if len(palettes) != 1 {
+		t.Fatalf("Expected 1 palette, got %d", len(palettes))
+	}
+	if palettes[0].File.Path != "beach.jpg" {
+		t.Errorf("Expected beach.jpg, got %s", palettes[0].File.Path)
+	}
+	if palettes[0].File.Type != FileTypeImage {
+		t.Errorf("Expected image type, got %s", palettes[0].File.Type)
+	}
+	if len(palettes[0].Colors) != 1 || palettes[0].Colors[0] != "#3080ff" {
+		t.Errorf("Unexpected colors: %v", palettes[0].Colors)
+	}
+}
+
+func TestGetFilesWithoutPaletteIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	insertPaletteTestFile(t, db, "beach.jpg")
+	insertPaletteTestFile(t, db, "forest.jpg")
+	if err := db.SetFilePalette(ctx, "beach.jpg", []string{"#3080ff"}); err != nil {
+		t.Fatalf("SetFilePalette failed: %v", err)
+	}
+
+	files, err := db.GetFilesWithoutPalette(ctx)
+	if err != nil {
+		t.Fatalf("GetFilesWithoutPalette failed: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "forest.jpg" || files[0].Type != FileTypeImage {
+		t.Errorf("Expected only forest.jpg, got %+v", files)
+	}
+}
+
+func TestFilePaletteRemovedWithFileIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	insertPaletteTestFile(t, db, "gone.jpg")
+	if err := db.SetFilePalette(ctx, "gone.jpg", []string{"#abcdef"}); err != nil {
+		t.Fatalf("SetFilePalette failed: %v", err)
+	}
+
+	tx, err := db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	if _, err := db.DeleteMissingFiles(ctx, tx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("DeleteMissingFiles failed: %v", err)
+	}
+	if err := db.EndBatch(tx, nil); err != nil {
+		t.Fatalf("EndBatch failed: %v", err)
+	}
+
+	colors, err := db.GetFilePalette(ctx, "gone.jpg")
+	if err != nil {
+		t.Fatalf("GetFilePalette failed: %v", err)
+	}
+	if len(colors) != 0 {
+		t.Errorf("Expected palette to be removed with the file, got %v", colors)
+	}
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SetVideoPoster sets the offset a video's thumbnail is taken from, in place
+// of the configured seek strategy. Like tags, posters are kept by path and
+// survive the file briefly disappearing from the index.
+func (d *Database) SetVideoPoster(ctx context.Context, filePath string, offset time.Duration) error {
+	done := observeQuery("set_video_poster")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO video_posters (file_path, offset_ms, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(file_path) DO UPDATE SET offset_ms = excluded.offset_ms, updated_at = excluded.updated_at`,
+		filePath, offset.Milliseconds(), time.Now().Unix())
+	done(err)
+	return err
+}
+
+// ClearVideoPoster removes a video's poster offset, returning its thumbnail
+// to the configured seek strategy.
+func (d *Database) ClearVideoPoster(ctx context.Context, filePath string) error {
+	done := observeQuery("clear_video_poster")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, "DELETE FROM video_posters WHERE file_path = ?", filePath)
+	done(err)
+	return err
+}
+
+// GetVideoPoster returns a video's poster offset, and whether one is set.
+func (d *Database) GetVideoPoster(ctx context.Context, filePath string) (time.Duration, bool, error) {
+	done := observeQuery("get_video_poster")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var offsetMs int64
+	err := d.db.QueryRowContext(ctx, "SELECT offset_ms FROM video_posters WHERE file_path = ?", filePath).Scan(&offsetMs)
+	if errors.Is(err, sql.ErrNoRows) {
+		done(nil)
+		return 0, false, nil
+	}
+	done(err)
+	if err != nil {
+		return 0, false, err
+	}
+	return time.Duration(offsetMs) * time.Millisecond, true, nil
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestVideoPosterIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, ok, err := db.GetVideoPoster(ctx, "clip.mp4"); err != nil || ok {
+		t.Fatalf("Expected no poster, got %v, %v", ok, err)
+	}
+
+	// Setting again replaces the offset
+	for _, offset := range []time.Duration{5 * time.Second, 90*time.Second + 250*time.Millisecond} {
+		if err := db.SetVideoPoster(ctx, "clip.mp4", offset); err != nil {
+			t.Fatalf("SetVideoPoster(%v) failed: %v", offset, err)
+		}
+	}
+	offset, ok, err := db.GetVideoPoster(ctx, "clip.mp4")
+	if err != nil || !ok || offset != 90*time.Second+250*time.Millisecond {
+		t.Errorf("GetVideoPoster = %v, %v, %v; want 1m30.25s", offset, ok, err)
+	}
+	if _, ok, _ := db.GetVideoPoster(ctx, "other.mp4"); ok {
+		t.Error("Expected other.mp4 to have no poster")
+	}
+
+	if err := db.ClearVideoPoster(ctx, "clip.mp4"); err != nil {
+		t.Fatalf("ClearVideoPoster failed: %v", err)
+	}
+	if _, ok, _ := db.GetVideoPoster(ctx, "clip.mp4"); ok {
+		t.Error("Expected the poster to be cleared")
+	}
+}
@@ -0,0 +1,61 @@
+package database
+
+import "strconv"
+
+// PresenceFilter narrows listings and searches by whether files have any
+// tags or are favorited. A nil field doesn't filter on that property.
+type PresenceFilter struct {
+	// Tagged keeps only files with at least one tag when true, and only
+	// untagged files when false.
+	Tagged *bool
+
+	// Favorited keeps only favorites when true, and only files that aren't
+	// favorites when false.
+	Favorited *bool
+}
+
+// IsSet reports whether the filter narrows anything.
+func (p PresenceFilter) IsSet() bool {
+	return p.Tagged != nil || p.Favorited != nil
+}
+
+// String describes the filter for logs and cache keys, such as
+// "tagged=false favorited=any".
+func (p PresenceFilter) String() string {
+	return "tagged=" + optionalBoolString(p.Tagged) + " favorited=" + optionalBoolString(p.Favorited)
+}
+
+// optionalBoolString formats an optional bool, with "any" for nil
+func optionalBoolString(b *bool) string {
+	if b == nil {
+		return "any"
+	}
+	return strconv.FormatBool(*b)
+}
+
+const (
+	taggedPredicate    = "EXISTS (SELECT 1 FROM file_tags WHERE file_path = f.path)"
+	favoritedPredicate = "EXISTS (SELECT 1 FROM favorites WHERE path = f.path)"
+)
+
+// conditions returns the filter's SQL conditions on the files table as f.
+// They're correlated EXISTS subqueries, so the absent cases are anti-joins
+// answered from the file_tags and favorites indexes.
+func (p PresenceFilter) conditions() []string {
+	var conditions []string
+	if p.Tagged != nil {
+		conditions = append(conditions, presencePredicate(taggedPredicate, *p.Tagged))
+	}
+	if p.Favorited != nil {
+		conditions = append(conditions, presencePredicate(favoritedPredicate, *p.Favorited))
+	}
+	return conditions
+}
+
+// presencePredicate returns predicate, negated unless present
+func presencePredicate(predicate string, present bool) string {
+	if present {
+		return predicate
+	}
+	return "NOT " + predicate
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// setupPresenceTestDB indexes a folder with four photos covering every mix of
+// tagged and favorited, plus a subfolder that is neither
+func setupPresenceTestDB(t *testing.T) *Database {
+	t.Helper()
+	db, _ := setupTestDB(t)
+
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "album", Path: "album", ParentPath: "", Type: FileTypeFolder},
+		{Name: "sub", Path: "album/sub", ParentPath: "album", Type: FileTypeFolder},
+		{Name: "both.jpg", Path: "album/both.jpg", ParentPath: "album", Type: FileTypeImage},
+		{Name: "fav.jpg", Path: "album/fav.jpg", ParentPath: "album", Type: FileTypeImage},
+		{Name: "plain.jpg", Path: "album/plain.jpg", ParentPath: "album", Type: FileTypeImage},
+		{Name: "tagged.jpg", Path: "album/tagged.jpg", ParentPath: "album", Type: FileTypeImage},
+	})
+
+	ctx := context.Background()
+	for _, path := range []string{"album/both.jpg", "album/tagged.jpg"} {
+		if err := db.AddTagToFile(ctx, path, "beach"); err != nil {
+			t.Fatalf("AddTagToFile(%s) failed: %v", path, err)
+		}
+	}
+	for _, name := range []string{"both.jpg", "fav.jpg"} {
+		if err := db.AddFavorite(ctx, "album/"+name, name, FileTypeImage); err != nil {
+			t.Fatalf("AddFavorite(%s) failed: %v", name, err)
+		}
+	}
+	return db
+}
+
+func presencePaths(items []MediaFile) []string {
+	paths := make([]string, 0, len(items))
+	for _, item := range items {
+		paths = append(paths, item.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestListDirectoryPresenceIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db := setupPresenceTestDB(t)
+	defer db.Close()
+
+	yes, no := true, false
+	tests := []struct {
+		name     string
+		presence PresenceFilter
+		want     []string
+	}{
+		{"unset", PresenceFilter{}, []string{"album/both.jpg", "album/fav.jpg", "album/plain.jpg", "album/sub", "album/tagged.jpg"}},
+		{"untagged", PresenceFilter{Tagged: &no}, []string{"album/fav.jpg", "album/plain.jpg", "album/sub"}},
+		{"tagged", PresenceFilter{Tagged: &yes}, []string{"album/both.jpg", "album/sub", "album/tagged.jpg"}},
+		{"not favorited", PresenceFilter{Favorited: &no}, []string{"album/plain.jpg", "album/sub", "album/tagged.jpg"}},
+		{"untagged favorites", PresenceFilter{Tagged: &no, Favorited: &yes}, []string{"album/fav.jpg", "album/sub"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listing, err := db.ListDirectory(context.Background(), ListOptions{Path: "album", Presence: tt.presence})
+			if err != nil {
+				t.Fatalf("ListDirectory failed: %v", err)
+			}
+			if got := presencePaths(listing.Items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+			if listing.TotalItems != len(tt.want) {
+				t.Errorf("Expected a total of %d, got %d", len(tt.want), listing.TotalItems)
+			}
+		})
+	}
+}
+
+func TestSearchPresenceIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db := setupPresenceTestDB(t)
+	defer db.Close()
+
+	yes, no := true, false
+	tests := []struct {
+		name string
+		opts SearchOptions
+		want []string
+	}{
+		{"untagged without a query", SearchOptions{Presence: PresenceFilter{Tagged: &no}}, []string{"album", "album/fav.jpg", "album/plain.jpg", "album/sub"}},
+		{"untagged images", SearchOptions{FilterType: "image", Presence: PresenceFilter{Tagged: &no}}, []string{"album/fav.jpg", "album/plain.jpg"}},
+		{"text and not favorited", SearchOptions{Query: "jpg", Presence: PresenceFilter{Favorited: &no}}, []string{"album/plain.jpg", "album/tagged.jpg"}},
+		{"tag and favorited", SearchOptions{Query: "tag:beach", Presence: PresenceFilter{Favorited: &yes}}, []string{"album/both.jpg"}},
+		{"text and tagged", SearchOptions{Query: "jpg", Presence: PresenceFilter{Tagged: &yes}}, []string{"album/both.jpg", "album/tagged.jpg"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Page, tt.opts.PageSize = 1, 50
+			result, err := db.Search(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if got := presencePaths(result.Items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+			if result.TotalItems != len(tt.want) {
+				t.Errorf("Expected a total of %d, got %d", len(tt.want), result.TotalItems)
+			}
+		})
+	}
+
+	// An empty query with no presence filter still finds nothing
+	result, err := db.Search(context.Background(), SearchOptions{Page: 1, PageSize: 50})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(result.Items) != 0 {
+		t.Errorf("Expected no results for an empty search, got %v", presencePaths(result.Items))
+	}
+}
+
+func TestStreamFilesPresenceIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db := setupPresenceTestDB(t)
+	defer db.Close()
+
+	no := false
+	var got []string
+	err := db.StreamFiles(context.Background(), StreamOptions{Path: "album", Recursive: true, Presence: PresenceFilter{Tagged: &no, Favorited: &no}}, func(f MediaFile) error {
+		got = append(got, f.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamFiles failed: %v", err)
+	}
+	if want := []string{"album/plain.jpg", "album/sub"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
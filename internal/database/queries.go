@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"media-viewer/internal/i18n"
 	"media-viewer/internal/logging"
 )
 
@@ -27,6 +28,18 @@ const (
 	SortBySize SortField = "size"
 	// SortByType sorts results by file type.
 	SortByType SortField = "type"
+	// SortByManual sorts results by the folder's manual order; unpositioned items follow by name.
+	SortByManual SortField = "manual"
+	// SortByCreated sorts results by creation (birth) time, falling back to
+	// modification time for files indexed without one.
+	SortByCreated SortField = "created"
+	// SortByCaptured sorts results by the EXIF capture date, falling back to
+	// modification time for files indexed without one.
+	SortByCaptured SortField = "captured"
+	// SortByRelevance sorts search results by how well they match, best
+	// first. It is the default for searches with text; other sorts, and
+	// searches by tag alone, are by name.
+	SortByRelevance SortField = "relevance"
 	// SortAsc sorts in ascending order.
 	SortAsc SortOrder = "asc"
 	// SortDesc sorts in descending order.
@@ -51,14 +64,31 @@ type ListOptions struct {
 	FilterType string
 	Page       int
 	PageSize   int
+
+	// Presence narrows the files listed by whether they're tagged or
+	// favorited. Like FilterType, it leaves folders in place.
+	Presence PresenceFilter
+
+	// Locale sets the collation names are sorted with; the zero value sorts
+	// them case-insensitively by code point
+	Locale i18n.Locale
 }
 
 // SearchOptions specifies options for searching the media library.
 type SearchOptions struct {
 	Query      string
 	FilterType string
+	Mode       SearchMode
+	SortField  SortField
 	Page       int
 	PageSize   int
+
+	// Presence narrows results by whether they're tagged or favorited. With
+	// it set, an empty query lists every file it matches.
+	Presence PresenceFilter
+
+	// Locale sets the collation results are sorted by name with
+	Locale i18n.Locale
 }
 
 // TagFilter represents an included or excluded tag in a search query
@@ -129,19 +159,29 @@ const (
 	SortAscStr       = "ASC"
 	SortDescStr      = "DESC"
 	NameCollationStr = "f.name COLLATE NOCASE"
+
+	// manualOrderColumn is the position column joined in for SortByManual
+	manualOrderColumn = "fo.sort_order"
+
+	// createdOrderColumn is the SortByCreated expression
+	createdOrderColumn = "COALESCE(f.birth_time, f.mod_time)"
+
+	// capturedOrderColumn is the SortByCaptured expression
+	capturedOrderColumn = "COALESCE(f.captured_at, f.mod_time)"
 )
 
 // Caller must hold at least a read lock.
 func (d *Database) countDirectoryItemsUnlocked(ctx context.Context, opts ListOptions) (int, error) {
 	logging.Debug("ListDirectory: getting count...")
 
-	countQuery := `SELECT COUNT(*) FROM files WHERE parent_path = ?`
+	countQuery := `SELECT COUNT(*) FROM files f WHERE f.parent_path = ?`
 	countArgs := []interface{}{opts.Path}
 
 	if opts.FilterType != "" {
-		countQuery += ` AND (type = 'folder' OR type = ?)`
+		countQuery += ` AND (f.type = 'folder' OR f.type = ?)`
 		countArgs = append(countArgs, opts.FilterType)
 	}
+	countQuery += listPresenceClause(opts.Presence)
 
 	var totalItems int
 	err := d.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&totalItems)
@@ -158,12 +198,6 @@ func (d *Database) countDirectoryItemsUnlocked(ctx context.Context, opts ListOpt
 func (d *Database) fetchDirectoryItemsUnlocked(ctx context.Context, opts ListOptions) ([]MediaFile, error) {
 	logging.Debug("ListDirectory: executing select query...")
 
-	sortColumn := getSortColumn(opts.SortField)
-	sortDir := SortAscStr
-	if opts.SortOrder == SortDesc {
-		sortDir = "DESC"
-	}
-
 	offset := (opts.Page - 1) * opts.PageSize
 
 	selectQuery := `
@@ -171,34 +205,96 @@ func (d *Database) fetchDirectoryItemsUnlocked(ctx context.Context, opts ListOpt
 			f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type,
 			CASE WHEN fav.path IS NOT NULL THEN 1 ELSE 0 END as is_favorite,
 			GROUP_CONCAT(t.name, ',') as tags,
-			(SELECT COUNT(*) FROM files WHERE parent_path = f.path) as folder_count
+			(SELECT COUNT(*) FROM files WHERE parent_path = f.path) as folder_count,
+			(SELECT description FROM file_notes WHERE file_path = f.path) as description,
+			EXISTS (SELECT 1 FROM sensitive_files WHERE file_path = f.path) as sensitive,
+			f.animated
 		FROM files f
 		LEFT JOIN favorites fav ON f.path = fav.path
 		LEFT JOIN file_tags ft ON f.path = ft.file_path
 		LEFT JOIN tags t ON ft.tag_id = t.id
-		WHERE f.parent_path = ?
 	`
+	if opts.SortField == SortByManual {
+		selectQuery += ` LEFT JOIN folder_order fo ON f.path = fo.file_path`
+	}
+	selectQuery += ` WHERE f.parent_path = ?`
 	selectArgs := []interface{}{opts.Path}
 
 	if opts.FilterType != "" {
 		selectQuery += ` AND (f.type = 'folder' OR f.type = ?)`
 		selectArgs = append(selectArgs, opts.FilterType)
 	}
+	selectQuery += listPresenceClause(opts.Presence)
 
 	selectQuery += ` GROUP BY f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type, fav.path`
 
-	var orderColumn string
-	if sortColumn == NameCollation {
-		orderColumn = NameCollationStr
+	orderColumn, sortDir := resolveListOrder(opts.SortField, opts.SortOrder, opts.Locale)
+	if orderColumn == manualOrderColumn {
+		// Unpositioned items follow the positioned ones in either direction
+		selectQuery += fmt.Sprintf(` ORDER BY (CASE WHEN f.type = 'folder' THEN 0 ELSE 1 END), (fo.sort_order IS NULL), %s %s, %s`, orderColumn, sortDir, nameOrderColumn(opts.Locale)) //nolint:gosec // G202 - orderColumn and sortDir are validated against static allowlists; SQL column names cannot be parameterized
 	} else {
+		selectQuery += fmt.Sprintf(` ORDER BY (CASE WHEN f.type = 'folder' THEN 0 ELSE 1 END), %s %s`, orderColumn, sortDir) //nolint:gosec // G202 - orderColumn and sortDir are validated against static allowlists; SQL column names cannot be parameterized
+	}
+	selectQuery += ` LIMIT ? OFFSET ?`
+	selectArgs = append(selectArgs, opts.PageSize, offset)
+
+	rows, err := d.db.QueryContext(ctx, selectQuery, selectArgs...)
+	if err != nil {
+		logging.Error("ListDirectory select query failed: %v", err)
+		return nil, fmt.Errorf("select query failed: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.Error("error closing rows: %v", err)
+		}
+	}()
+
+	return d.scanDirectoryItemsUnlocked(rows)
+}
+
+// listPresenceClause returns the AND clause applying a presence filter to a
+// directory listing, or "" if it's unset. Folders always pass so the
+// listing can still be navigated.
+func listPresenceClause(p PresenceFilter) string {
+	conditions := p.conditions()
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " AND (f.type = 'folder' OR (" + strings.Join(conditions, " AND ") + "))"
+}
+
+// resolveListOrder returns the ORDER BY column and direction for a listing
+// sort, validated against static allowlists. Column names refer to the files
+// table as f, and names sort with locale's collation.
+func resolveListOrder(field SortField, order SortOrder, locale i18n.Locale) (orderColumn, sortDir string) {
+	sortColumn := getSortColumn(field)
+	sortDir = SortAscStr
+	if order == SortDesc {
+		sortDir = "DESC"
+	}
+
+	switch {
+	case field == SortByManual:
+		orderColumn = manualOrderColumn
+	case field == SortByCreated:
+		orderColumn = createdOrderColumn
+	case field == SortByCaptured:
+		orderColumn = capturedOrderColumn
+	case sortColumn == NameCollation:
+		orderColumn = nameOrderColumn(locale)
+	default:
 		orderColumn = "f." + sortColumn
 	}
 
 	allowedColumns := map[string]bool{
 		"f.name COLLATE NOCASE": true,
+		nameOrderColumn(locale): true,
 		"f.mod_time":            true,
 		"f.size":                true,
 		"f.type":                true,
+		manualOrderColumn:       true,
+		createdOrderColumn:      true,
+		capturedOrderColumn:     true,
 	}
 	allowedSortDirs := map[string]bool{
 		SortAscStr:  true,
@@ -210,22 +306,13 @@ func (d *Database) fetchDirectoryItemsUnlocked(ctx context.Context, opts ListOpt
 	if !allowedSortDirs[sortDir] {
 		sortDir = SortAscStr
 	}
-	selectQuery += fmt.Sprintf(` ORDER BY (CASE WHEN f.type = 'folder' THEN 0 ELSE 1 END), %s %s`, orderColumn, sortDir) //nolint:gosec // G202 - orderColumn and sortDir are validated against static allowlists; SQL column names cannot be parameterized
-	selectQuery += ` LIMIT ? OFFSET ?`
-	selectArgs = append(selectArgs, opts.PageSize, offset)
-
-	rows, err := d.db.QueryContext(ctx, selectQuery, selectArgs...)
-	if err != nil {
-		logging.Error("ListDirectory select query failed: %v", err)
-		return nil, fmt.Errorf("select query failed: %w", err)
-	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			logging.Error("error closing rows: %v", err)
-		}
-	}()
+	return orderColumn, sortDir
+}
 
-	return d.scanDirectoryItemsUnlocked(rows)
+// nameOrderColumn returns the ORDER BY expression sorting files by name in
+// locale. Collation only returns the names of registered collations.
+func nameOrderColumn(locale i18n.Locale) string {
+	return "f.name COLLATE " + locale.Collation()
 }
 
 // getSortColumn returns the SQL column for sorting.
@@ -257,11 +344,12 @@ func (d *Database) scanDirectoryItemsUnlocked(rows *sql.Rows) ([]MediaFile, erro
 		var isFavorite int
 		var tagsString sql.NullString
 		var folderCount int
+		var description sql.NullString
 
 		if err := rows.Scan(
 			&file.ID, &file.Name, &file.Path, &file.ParentPath,
 			&file.Type, &file.Size, &modTime, &mimeType,
-			&isFavorite, &tagsString, &folderCount,
+			&isFavorite, &tagsString, &folderCount, &description, &file.Sensitive, &file.Animated,
 		); err != nil {
 			return nil, err
 		}
@@ -284,6 +372,7 @@ func (d *Database) scanDirectoryItemsUnlocked(rows *sql.Rows) ([]MediaFile, erro
 		if file.Type == FileTypeFolder {
 			file.ItemCount = folderCount
 		}
+		file.Description = description.String
 
 		items = append(items, file)
 	}
@@ -378,7 +467,7 @@ func buildBreadcrumb(path string) []PathPart {
 func (d *Database) Search(ctx context.Context, opts SearchOptions) (*SearchResult, error) {
 	done := observeQuery("search")
 
-	if opts.Query == "" {
+	if opts.Query == "" && !opts.Presence.IsSet() {
 		done(nil)
 		return &SearchResult{
 			Items:      []MediaFile{},
@@ -421,9 +510,9 @@ func (d *Database) Search(ctx context.Context, opts SearchOptions) (*SearchResul
 	var err error
 
 	switch {
-	case textQuery == "" && len(tagFilters) > 0:
+	case textQuery == "" && (len(tagFilters) > 0 || opts.Presence.IsSet()):
 		result, err = d.searchByTagFiltersUnlocked(ctx, opts, includedTags, excludedTags)
-	case textQuery == "" && len(tagFilters) == 0:
+	case textQuery == "":
 		result = &SearchResult{Items: []MediaFile{}, Query: opts.Query}
 	default:
 		result, err = d.searchWithTagFiltersUnlocked(ctx, opts, textQuery, includedTags, excludedTags)
@@ -433,7 +522,8 @@ func (d *Database) Search(ctx context.Context, opts SearchOptions) (*SearchResul
 	return result, err
 }
 
-// searchByTagFiltersUnlocked handles searches with only tag filters (no text)
+// searchByTagFiltersUnlocked handles searches with only tag or presence
+// filters (no text)
 func (d *Database) searchByTagFiltersUnlocked(ctx context.Context, opts SearchOptions, includedTags, excludedTags []string) (*SearchResult, error) {
 	var conditions []string
 	var args []interface{}
@@ -441,7 +531,10 @@ func (d *Database) searchByTagFiltersUnlocked(ctx context.Context, opts SearchOp
 	baseQuery := `
 		SELECT f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type,
 		       COALESCE(fav.path IS NOT NULL, 0) AS is_favorite,
-		       GROUP_CONCAT(t_all.name, ',') AS tags
+		       GROUP_CONCAT(t_all.name, ',') AS tags,
+		       (SELECT description FROM file_notes WHERE file_path = f.path) AS description,
+		       EXISTS (SELECT 1 FROM sensitive_files WHERE file_path = f.path) AS sensitive,
+		       f.animated
 		FROM files f
 		LEFT JOIN favorites fav ON f.path = fav.path
 		LEFT JOIN file_tags ft_all ON f.path = ft_all.file_path
@@ -473,6 +566,7 @@ func (d *Database) searchByTagFiltersUnlocked(ctx context.Context, opts SearchOp
 		conditions = append(conditions, "f.type = ?")
 		args = append(args, opts.FilterType)
 	}
+	conditions = append(conditions, opts.Presence.conditions()...)
 
 	whereClause := ""
 	if len(conditions) > 0 {
@@ -506,13 +600,13 @@ func (d *Database) searchByTagFiltersUnlocked(ctx context.Context, opts SearchOp
 	}
 	offset := (opts.Page - 1) * opts.PageSize
 
-	groupBy := " GROUP BY f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type, fav.path"
+	groupBy := " GROUP BY f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type, f.animated, fav.path"
 
 	selectQuery := baseQuery
 	if whereClause != "" {
 		selectQuery += " " + whereClause
 	}
-	selectQuery += groupBy + " ORDER BY f.name COLLATE NOCASE LIMIT ? OFFSET ?"
+	selectQuery += groupBy + " ORDER BY " + nameOrderColumn(opts.Locale) + " LIMIT ? OFFSET ?"
 	selectArgs := make([]interface{}, len(args), len(args)+2)
 	copy(selectArgs, args)
 	selectArgs = append(selectArgs, opts.PageSize, offset)
@@ -534,11 +628,12 @@ func (d *Database) searchByTagFiltersUnlocked(ctx context.Context, opts SearchOp
 		var mimeType sql.NullString
 		var isFavorite int
 		var tagsString sql.NullString
+		var description sql.NullString
 
 		if err := rows.Scan(
 			&file.ID, &file.Name, &file.Path, &file.ParentPath,
 			&file.Type, &file.Size, &modTime, &mimeType,
-			&isFavorite, &tagsString,
+			&isFavorite, &tagsString, &description, &file.Sensitive, &file.Animated,
 		); err != nil {
 			continue
 		}
@@ -557,6 +652,7 @@ func (d *Database) searchByTagFiltersUnlocked(ctx context.Context, opts SearchOp
 		if tagsString.Valid && tagsString.String != "" {
 			file.Tags = strings.Split(tagsString.String, ",")
 		}
+		file.Description = description.String
 
 		items = append(items, file)
 	}
@@ -573,9 +669,6 @@ func (d *Database) searchByTagFiltersUnlocked(ctx context.Context, opts SearchOp
 
 // searchWithTagFiltersUnlocked handles combined text + tag filter searches
 func (d *Database) searchWithTagFiltersUnlocked(ctx context.Context, opts SearchOptions, textQuery string, includedTags, excludedTags []string) (*SearchResult, error) {
-	searchTerm := prepareSearchTerm(textQuery)
-	tagPattern := "%" + textQuery + "%"
-
 	exclusionConditions := make([]string, 0, len(excludedTags))
 	exclusionArgs := make([]interface{}, 0, len(excludedTags))
 
@@ -613,101 +706,76 @@ func (d *Database) searchWithTagFiltersUnlocked(ctx context.Context, opts Search
 		filterClause = FilterTypeClause
 		filterArgs = append(filterArgs, opts.FilterType)
 	}
+	for _, condition := range opts.Presence.conditions() {
+		filterClause += " AND " + condition
+	}
+
+	// Each way of matching the text selects its files separately; the
+	// results are combined and grouped, so a file matching several is
+	// listed once with its best score
+	matches := searchMatchesFor(opts.Mode, textQuery)
+	selectQueries := make([]string, 0, len(matches))
+	countQueries := make([]string, 0, len(matches))
+	var matchArgs []interface{}
+
+	for _, m := range matches {
+		selectQueries = append(selectQueries, fmt.Sprintf(`
+			SELECT f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type,
+			       CASE WHEN fav.path IS NOT NULL THEN 1 ELSE 0 END as is_favorite,
+			       GROUP_CONCAT(t_all.name, ',') as tags,
+			       (SELECT description FROM file_notes WHERE file_path = f.path) as description,
+			       EXISTS (SELECT 1 FROM sensitive_files WHERE file_path = f.path) as sensitive,
+			       f.animated,
+			       MAX(%s) as score
+			FROM files f
+			%s
+			%s
+			LEFT JOIN favorites fav ON f.path = fav.path
+			LEFT JOIN file_tags ft_all ON f.path = ft_all.file_path
+			LEFT JOIN tags t_all ON ft_all.tag_id = t_all.id
+			WHERE %s
+			%s
+			%s
+			GROUP BY f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type, f.animated, fav.path
+		`, m.score, m.joins, inclusionJoins, m.condition, filterClause, exclusionClause))
 
-	ftsQuery := fmt.Sprintf(`
-		SELECT f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type,
-		       CASE WHEN fav.path IS NOT NULL THEN 1 ELSE 0 END as is_favorite,
-		       GROUP_CONCAT(t_all.name, ',') as tags
-		FROM files f
-		INNER JOIN files_fts fts ON f.id = fts.rowid
-		%s
-		LEFT JOIN favorites fav ON f.path = fav.path
-		LEFT JOIN file_tags ft_all ON f.path = ft_all.file_path
-		LEFT JOIN tags t_all ON ft_all.tag_id = t_all.id
-		WHERE files_fts MATCH ?
-		%s
-		%s
-		GROUP BY f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type, fav.path
-	`, inclusionJoins, filterClause, exclusionClause)
-
-	tagQuery := fmt.Sprintf(`
-		SELECT f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type,
-		       CASE WHEN fav.path IS NOT NULL THEN 1 ELSE 0 END as is_favorite,
-		       GROUP_CONCAT(t_all.name, ',') as tags
-		FROM files f
-		INNER JOIN file_tags ft ON f.path = ft.file_path
-		INNER JOIN tags t ON ft.tag_id = t.id
-		%s
-		LEFT JOIN favorites fav ON f.path = fav.path
-		LEFT JOIN file_tags ft_all ON f.path = ft_all.file_path
-		LEFT JOIN tags t_all ON ft_all.tag_id = t_all.id
-		WHERE t.name LIKE ?
-		%s
-		%s
-		GROUP BY f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type, fav.path
-	`, inclusionJoins, filterClause, exclusionClause)
-
-	ftsArgs := make([]interface{}, 0, len(inclusionArgs)+1+len(filterArgs)+len(exclusionArgs))
-	ftsArgs = append(ftsArgs, inclusionArgs...)
-	ftsArgs = append(ftsArgs, searchTerm)
-	ftsArgs = append(ftsArgs, filterArgs...)
-	ftsArgs = append(ftsArgs, exclusionArgs...)
-
-	tagArgs := make([]interface{}, 0, len(inclusionArgs)+1+len(filterArgs)+len(exclusionArgs))
-	tagArgs = append(tagArgs, inclusionArgs...)
-	tagArgs = append(tagArgs, tagPattern)
-	tagArgs = append(tagArgs, filterArgs...)
-	tagArgs = append(tagArgs, exclusionArgs...)
-
-	combinedQuery := fmt.Sprintf(`
-		SELECT id, name, path, parent_path, type, size, mod_time, mime_type, is_favorite, tags
-		FROM (
+		countQueries = append(countQueries, fmt.Sprintf(`
+			SELECT DISTINCT f.path
+			FROM files f
 			%s
-			UNION
 			%s
-		) combined
-		ORDER BY name COLLATE NOCASE
-	`, ftsQuery, tagQuery)
+			WHERE %s
+			%s
+			%s
+		`, m.joins, inclusionJoins, m.condition, filterClause, exclusionClause))
 
-	ftsCountQuery := fmt.Sprintf(`
-		SELECT DISTINCT f.path
-		FROM files f
-		INNER JOIN files_fts fts ON f.id = fts.rowid
-		%s
-		WHERE files_fts MATCH ?
-		%s
-		%s
-	`, inclusionJoins, filterClause, exclusionClause)
+		matchArgs = append(matchArgs, inclusionArgs...)
+		matchArgs = append(matchArgs, m.args...)
+		matchArgs = append(matchArgs, filterArgs...)
+		matchArgs = append(matchArgs, exclusionArgs...)
+	}
 
-	tagCountQuery := fmt.Sprintf(`
-		SELECT DISTINCT f.path
-		FROM files f
-		INNER JOIN file_tags ft ON f.path = ft.file_path
-		INNER JOIN tags t ON ft.tag_id = t.id
-		%s
-		WHERE t.name LIKE ?
-		%s
-		%s
-	`, inclusionJoins, filterClause, exclusionClause)
+	nameOrder := "name COLLATE " + opts.Locale.Collation()
+	orderBy := "score DESC, " + nameOrder
+	if opts.SortField != "" && opts.SortField != SortByRelevance {
+		orderBy = nameOrder
+	}
+
+	combinedQuery := fmt.Sprintf(`
+		SELECT id, name, path, parent_path, type, size, mod_time, mime_type, is_favorite, tags, description, sensitive, animated, MAX(score)
+		FROM (%s) combined
+		GROUP BY id
+		ORDER BY %s
+	`, strings.Join(selectQueries, " UNION ALL "), orderBy)
 
 	countQuery := fmt.Sprintf(`
 		SELECT COUNT(*) FROM (
-			SELECT path FROM (%s UNION %s)
+			SELECT path FROM (%s)
 		)
-	`, ftsCountQuery, tagCountQuery)
-
-	countArgs := make([]interface{}, 0, len(inclusionArgs)+1+len(filterArgs)+len(exclusionArgs)+len(inclusionArgs)+1+len(filterArgs)+len(exclusionArgs))
-	countArgs = append(countArgs, inclusionArgs...)
-	countArgs = append(countArgs, searchTerm)
-	countArgs = append(countArgs, filterArgs...)
-	countArgs = append(countArgs, exclusionArgs...)
-	countArgs = append(countArgs, inclusionArgs...)
-	countArgs = append(countArgs, tagPattern)
-	countArgs = append(countArgs, filterArgs...)
-	countArgs = append(countArgs, exclusionArgs...)
+	`, strings.Join(countQueries, " UNION "))
 
 	var totalItems int
-	err := d.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&totalItems)
+	err := d.db.QueryRowContext(ctx, countQuery, matchArgs...).Scan(&totalItems)
 	if err != nil {
 		logging.Warn("Combined search count failed, trying tag-only: %v", err)
 		return d.searchByTagFiltersUnlocked(ctx, opts, includedTags, excludedTags)
@@ -721,9 +789,8 @@ func (d *Database) searchWithTagFiltersUnlocked(ctx context.Context, opts Search
 
 	paginatedQuery := combinedQuery + " LIMIT ? OFFSET ?" //nolint:gosec // G202 false positive - LIMIT and OFFSET use parameterized placeholders (?), values are bound via selectArgs
 
-	selectArgs := make([]interface{}, 0, len(ftsArgs)+len(tagArgs)+2)
-	selectArgs = append(selectArgs, ftsArgs...)
-	selectArgs = append(selectArgs, tagArgs...)
+	selectArgs := make([]interface{}, 0, len(matchArgs)+2)
+	selectArgs = append(selectArgs, matchArgs...)
 	selectArgs = append(selectArgs, opts.PageSize, offset)
 
 	rows, err := d.db.QueryContext(ctx, paginatedQuery, selectArgs...)
@@ -744,11 +811,12 @@ func (d *Database) searchWithTagFiltersUnlocked(ctx context.Context, opts Search
 		var mimeType sql.NullString
 		var isFavorite int
 		var tagsString sql.NullString
+		var description sql.NullString
 
 		if err := rows.Scan(
 			&file.ID, &file.Name, &file.Path, &file.ParentPath,
 			&file.Type, &file.Size, &modTime, &mimeType,
-			&isFavorite, &tagsString,
+			&isFavorite, &tagsString, &description, &file.Sensitive, &file.Animated, &file.Score,
 		); err != nil {
 			continue
 		}
@@ -767,6 +835,7 @@ func (d *Database) searchWithTagFiltersUnlocked(ctx context.Context, opts Search
 		if tagsString.Valid && tagsString.String != "" {
 			file.Tags = strings.Split(tagsString.String, ",")
 		}
+		file.Description = description.String
 
 		items = append(items, file)
 	}
@@ -1143,6 +1212,12 @@ func (d *Database) GetAllPlaylists(ctx context.Context) ([]MediaFile, error) {
 // GetMediaInDirectory returns all media files in a directory (for lightbox).
 // Optimized to fetch favorites and tags in a single query using JOINs to eliminate N+1 queries.
 func (d *Database) GetMediaInDirectory(ctx context.Context, parentPath string, sortField SortField, sortOrder SortOrder) ([]MediaFile, error) {
+	return d.GetMediaInDirectoryForLocale(ctx, parentPath, sortField, sortOrder, i18n.English)
+}
+
+// GetMediaInDirectoryForLocale is GetMediaInDirectory with names sorted by
+// locale's collation, matching a ListDirectory with the same Locale.
+func (d *Database) GetMediaInDirectoryForLocale(ctx context.Context, parentPath string, sortField SortField, sortOrder SortOrder, locale i18n.Locale) ([]MediaFile, error) {
 	done := observeQuery("get_media_in_directory")
 
 	d.mu.RLock()
@@ -1164,30 +1239,45 @@ func (d *Database) GetMediaInDirectory(ctx context.Context, parentPath string, s
 		sortDir = "DESC"
 	}
 
-	if sortColumn == NameCollation {
-		sortColumn = "f.name COLLATE NOCASE"
-	} else {
+	orderJoin := ""
+	orderPrefix := ""
+	switch {
+	case sortField == SortByManual:
+		sortColumn = manualOrderColumn
+		orderJoin = "LEFT JOIN folder_order fo ON f.path = fo.file_path"
+		orderPrefix = "(fo.sort_order IS NULL), "
+	case sortField == SortByCreated:
+		sortColumn = createdOrderColumn
+	case sortField == SortByCaptured:
+		sortColumn = capturedOrderColumn
+	case sortColumn == NameCollation:
+		sortColumn = nameOrderColumn(locale)
+	default:
 		sortColumn = "f." + sortColumn
 	}
 
 	secondarySort := ""
 	if sortField != SortByName && sortField != "" {
-		secondarySort = ", f.name COLLATE NOCASE ASC"
+		secondarySort = ", " + nameOrderColumn(locale) + " ASC"
 	}
 
 	query := fmt.Sprintf(`
 		SELECT
 			f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type,
 			CASE WHEN fav.path IS NOT NULL THEN 1 ELSE 0 END as is_favorite,
-			GROUP_CONCAT(t.name, ',') as tags
+			GROUP_CONCAT(t.name, ',') as tags,
+			(SELECT description FROM file_notes WHERE file_path = f.path) as description,
+			EXISTS (SELECT 1 FROM sensitive_files WHERE file_path = f.path) as sensitive,
+			f.animated
 		FROM files f
 		LEFT JOIN favorites fav ON f.path = fav.path
 		LEFT JOIN file_tags ft ON f.path = ft.file_path
 		LEFT JOIN tags t ON ft.tag_id = t.id
+		%s
 		WHERE f.parent_path = ? AND f.type IN ('image', 'video')
 		GROUP BY f.id, f.name, f.path, f.parent_path, f.type, f.size, f.mod_time, f.mime_type, fav.path
-		ORDER BY %s %s%s
-	`, sortColumn, sortDir, secondarySort)
+		ORDER BY %s%s %s%s
+	`, orderJoin, orderPrefix, sortColumn, sortDir, secondarySort)
 
 	rows, err := d.db.QueryContext(ctx, query, parentPath)
 	if err != nil {
@@ -1208,11 +1298,12 @@ func (d *Database) GetMediaInDirectory(ctx context.Context, parentPath string, s
 		var mimeType sql.NullString
 		var isFavorite int
 		var tagsString sql.NullString
+		var description sql.NullString
 
 		if err := rows.Scan(
 			&file.ID, &file.Name, &file.Path, &file.ParentPath,
 			&file.Type, &file.Size, &modTime, &mimeType,
-			&isFavorite, &tagsString,
+			&isFavorite, &tagsString, &description, &file.Sensitive, &file.Animated,
 		); err != nil {
 			done(err)
 			return nil, err
@@ -1228,6 +1319,7 @@ func (d *Database) GetMediaInDirectory(ctx context.Context, parentPath string, s
 		if tagsString.Valid && tagsString.String != "" {
 			file.Tags = strings.Split(tagsString.String, ",")
 		}
+		file.Description = description.String
 
 		files = append(files, file)
 	}
@@ -1237,6 +1329,7 @@ func (d *Database) GetMediaInDirectory(ctx context.Context, parentPath string, s
 }
 
 // GetMediaFilesInFolder returns media files directly within a folder (for folder thumbnails).
+// Files flagged as sensitive are left out, so folder thumbnails don't show them.
 func (d *Database) GetMediaFilesInFolder(ctx context.Context, folderPath string, limit int) ([]MediaFile, error) {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
@@ -1252,6 +1345,7 @@ func (d *Database) GetMediaFilesInFolder(ctx context.Context, folderPath string,
 		SELECT id, name, path, parent_path, type, size, mod_time, mime_type
 		FROM files
 		WHERE parent_path = ? AND type IN (?, ?)
+		  AND path NOT IN (SELECT file_path FROM sensitive_files)
 		ORDER BY name COLLATE NOCASE
 		LIMIT ?
 	`
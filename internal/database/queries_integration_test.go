@@ -438,3 +438,52 @@ func TestPerformRegularSearchDistribution(t *testing.T) {
 
 	t.Logf("Regular search returned %d tags and %d files (total %d)", tagCount, fileCount, len(suggestions))
 }
+
+// TestSearchRelevanceIntegration tests that text searches are ranked by
+// relevance unless another sort is asked for.
+func TestSearchRelevanceIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	deepPath := "archive/2019/trips/harbor/day two/photos/beacon.jpg"
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "beacon.jpg", Path: deepPath, ParentPath: "archive/2019/trips/harbor/day two/photos", Type: FileTypeImage},
+		{Name: "harbor.jpg", Path: "harbor.jpg", ParentPath: "", Type: FileTypeImage},
+	})
+
+	result, err := db.Search(ctx, SearchOptions{Query: "harbor", Page: 1, PageSize: 50})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(result.Items) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(result.Items))
+	}
+	if result.Items[0].Path != "harbor.jpg" || result.Items[1].Path != deepPath {
+		t.Errorf("Expected the name match to rank first, got %s then %s", result.Items[0].Path, result.Items[1].Path)
+	}
+	if result.Items[0].Score <= result.Items[1].Score || result.Items[1].Score <= 0 {
+		t.Errorf("Expected positive scores, best first, got %v and %v", result.Items[0].Score, result.Items[1].Score)
+	}
+
+	// Explicitly by relevance, the same order
+	result, err = db.Search(ctx, SearchOptions{Query: "harbor", SortField: SortByRelevance, Page: 1, PageSize: 50})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(result.Items) != 2 || result.Items[0].Path != "harbor.jpg" {
+		t.Errorf("Expected harbor.jpg first when sorting by relevance, got %v", result.Items)
+	}
+
+	// By name, the deep match comes first
+	result, err = db.Search(ctx, SearchOptions{Query: "harbor", SortField: SortByName, Page: 1, PageSize: 50})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(result.Items) != 2 || result.Items[0].Path != deepPath || result.Items[1].Path != "harbor.jpg" {
+		t.Errorf("Expected results by name, got %v", result.Items)
+	}
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SearchMode specifies how a search's text is matched.
+type SearchMode string
+
+const (
+	// SearchSubstring matches the text anywhere in a file's name or path,
+	// its tags or its note. This is the default.
+	SearchSubstring SearchMode = "substring"
+	// SearchExact matches the text as whole words in a file's name, path or
+	// note, or as a whole tag name, so "cat" doesn't find "vacation".
+	SearchExact SearchMode = "exact"
+	// SearchFilenameOnly matches the text anywhere in a file's name, ignoring
+	// its folder, tags and note.
+	SearchFilenameOnly SearchMode = "filename"
+)
+
+// ErrInvalidSearchMode is returned by ParseSearchMode for an unknown mode.
+var ErrInvalidSearchMode = errors.New("invalid search mode: must be substring, exact or filename")
+
+// ParseSearchMode parses a search mode, defaulting to SearchSubstring.
+func ParseSearchMode(value string) (SearchMode, error) {
+	switch mode := SearchMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return SearchSubstring, nil
+	case SearchSubstring, SearchExact, SearchFilenameOnly:
+		return mode, nil
+	default:
+		return "", ErrInvalidSearchMode
+	}
+}
+
+// ErrInvalidSearchSort is returned by ParseSearchSort for an unknown order.
+var ErrInvalidSearchSort = errors.New("invalid search sort: must be relevance or name")
+
+// ParseSearchSort parses the order of search results, defaulting to
+// SortByRelevance.
+func ParseSearchSort(value string) (SortField, error) {
+	switch field := SortField(strings.ToLower(strings.TrimSpace(value))); field {
+	case "":
+		return SortByRelevance, nil
+	case SortByRelevance, SortByName:
+		return field, nil
+	default:
+		return "", ErrInvalidSearchSort
+	}
+}
+
+// searchMatch is one way a search's text can match a file: the joins that
+// reach the text it's matched against, the condition and args that match
+// it, and an expression scoring how well it matched
+type searchMatch struct {
+	joins     string
+	condition string
+	args      []interface{}
+	score     string
+}
+
+// Relevance scores are FTS5 ranks negated, so a higher score is a better
+// match. The ranks are read from the rank column rather than by calling
+// bm25(), which SQLite won't evaluate inside the grouped queries. Name and
+// path matches are ranked with the name weighted above the rest of the
+// path, so a file named after the search ranks above one that only sits
+// deep in a matching folder. A tag match has no rank and scores 0.
+const (
+	filesRankWeights = "fts.rank MATCH 'bm25(10.0, 1.0)'"
+	filesScore       = "-fts.rank"
+	notesScore       = "-nfts.rank"
+	tagsScore        = "0.0"
+)
+
+// searchMatchesFor returns the ways text is matched in a search mode. Names
+// and paths, and notes, go through their trigram indexes, where a quoted
+// phrase matches as a substring; exact matching narrows those matches to
+// whole words.
+func searchMatchesFor(mode SearchMode, text string) []searchMatch {
+	phrase := prepareSearchTerm(text)
+
+	if mode == SearchFilenameOnly {
+		return []searchMatch{{
+			joins:     "INNER JOIN files_fts fts ON f.id = fts.rowid",
+			condition: "files_fts MATCH ? AND " + filesRankWeights,
+			args:      []interface{}{"name : " + phrase},
+			score:     filesScore,
+		}}
+	}
+
+	files := searchMatch{
+		joins:     "INNER JOIN files_fts fts ON f.id = fts.rowid",
+		condition: "files_fts MATCH ? AND " + filesRankWeights,
+		args:      []interface{}{phrase},
+		score:     filesScore,
+	}
+	tags := searchMatch{
+		joins: `INNER JOIN file_tags ft ON f.path = ft.file_path
+			INNER JOIN tags t ON ft.tag_id = t.id`,
+		condition: "t.name LIKE ?",
+		args:      []interface{}{"%" + text + "%"},
+		score:     tagsScore,
+	}
+	notes := searchMatch{
+		joins: `INNER JOIN file_notes fn ON f.path = fn.file_path
+			INNER JOIN file_notes_fts nfts ON fn.id = nfts.rowid`,
+		condition: "file_notes_fts MATCH ?",
+		args:      []interface{}{phrase},
+		score:     notesScore,
+	}
+
+	if mode == SearchExact {
+		word := strings.TrimSpace(text)
+		files.condition += fmt.Sprintf(" AND (%s OR %s)", wordMatchCondition("f.name"), wordMatchCondition("f.path"))
+		files.args = append(files.args, word, word)
+		tags.condition = "t.name = ? COLLATE NOCASE"
+		tags.args = []interface{}{word}
+		notes.condition += " AND " + wordMatchCondition("fn.description")
+		notes.args = append(notes.args, word)
+	}
+
+	return []searchMatch{files, tags, notes}
+}
+
+// wordSeparators are the characters, besides spaces, that separate words in
+// file names and paths for exact matching
+const wordSeparators = "/_-.,()[]"
+
+// wordMatchCondition returns a condition that a column contains a bound
+// parameter as whole words. Both have their separators turned into spaces
+// and are padded with one, so the parameter must be found with a space on
+// each side.
+func wordMatchCondition(column string) string {
+	return fmt.Sprintf("instr(%s, %s) > 0", wordPadded(column), wordPadded("?"))
+}
+
+// wordPadded returns a lowercased SQL expression with its word separators
+// replaced by spaces and a space added at each end
+func wordPadded(expr string) string {
+	expr = "lower(" + expr + ")"
+	for _, sep := range wordSeparators {
+		expr = fmt.Sprintf("replace(%s, '%c', ' ')", expr, sep)
+	}
+	return "(' ' || " + expr + " || ' ')"
+}
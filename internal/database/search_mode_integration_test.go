@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestParseSearchMode(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected SearchMode
+		wantErr  bool
+	}{
+		{"", SearchSubstring, false},
+		{"substring", SearchSubstring, false},
+		{" Exact ", SearchExact, false},
+		{"filename", SearchFilenameOnly, false},
+		{"fuzzy", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSearchMode(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.expected {
+			t.Errorf("ParseSearchMode(%q) = %q, %v; want %q (error: %v)", tt.value, got, err, tt.expected, tt.wantErr)
+		}
+	}
+}
+
+func TestParseSearchSort(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected SortField
+		wantErr  bool
+	}{
+		{"", SortByRelevance, false},
+		{"relevance", SortByRelevance, false},
+		{" Name ", SortByName, false},
+		{"date", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSearchSort(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.expected {
+			t.Errorf("ParseSearchSort(%q) = %q, %v; want %q (error: %v)", tt.value, got, err, tt.expected, tt.wantErr)
+		}
+	}
+}
+
+func TestSearchModesIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "beach.jpg", Path: "vacation/beach.jpg", ParentPath: "vacation", Type: FileTypeImage},
+		{Name: "cat.jpg", Path: "pets/cat.jpg", ParentPath: "pets", Type: FileTypeImage},
+		{Name: "concatenate.png", Path: "pets/concatenate.png", ParentPath: "pets", Type: FileTypeImage},
+		{Name: "ball.jpg", Path: "my cat/ball.jpg", ParentPath: "my cat", Type: FileTypeImage},
+		{Name: "sunset.jpg", Path: "evenings/sunset.jpg", ParentPath: "evenings", Type: FileTypeImage},
+		{Name: "dog.jpg", Path: "garden/dog.jpg", ParentPath: "garden", Type: FileTypeImage},
+		{Name: "bird.jpg", Path: "garden/bird.jpg", ParentPath: "garden", Type: FileTypeImage},
+	})
+	if err := db.SetFileDescription(ctx, "evenings/sunset.jpg", "Our cat at dusk"); err != nil {
+		t.Fatalf("SetFileDescription failed: %v", err)
+	}
+	if err := db.AddTagToFile(ctx, "garden/dog.jpg", "cats"); err != nil {
+		t.Fatalf("AddTagToFile failed: %v", err)
+	}
+	if err := db.AddTagToFile(ctx, "garden/bird.jpg", "Cat"); err != nil {
+		t.Fatalf("AddTagToFile failed: %v", err)
+	}
+
+	tests := []struct {
+		mode     SearchMode
+		expected []string
+	}{
+		{"", []string{
+			"evenings/sunset.jpg", "garden/bird.jpg", "garden/dog.jpg", "my cat/ball.jpg",
+			"pets/cat.jpg", "pets/concatenate.png", "vacation/beach.jpg",
+		}},
+		{SearchSubstring, []string{
+			"evenings/sunset.jpg", "garden/bird.jpg", "garden/dog.jpg", "my cat/ball.jpg",
+			"pets/cat.jpg", "pets/concatenate.png", "vacation/beach.jpg",
+		}},
+		{SearchExact, []string{"evenings/sunset.jpg", "garden/bird.jpg", "my cat/ball.jpg", "pets/cat.jpg"}},
+		{SearchFilenameOnly, []string{"pets/cat.jpg", "pets/concatenate.png"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			result, err := db.Search(ctx, SearchOptions{Query: "cat", Mode: tt.mode, Page: 1, PageSize: 50})
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+
+			var paths []string
+			for _, item := range result.Items {
+				paths = append(paths, item.Path)
+			}
+			slices.Sort(paths)
+			if !slices.Equal(paths, tt.expected) {
+				t.Errorf("Search(mode %q) = %v, want %v", tt.mode, paths, tt.expected)
+			}
+			if result.TotalItems != len(tt.expected) {
+				t.Errorf("TotalItems = %d, want %d", result.TotalItems, len(tt.expected))
+			}
+		})
+	}
+
+	// Tag filters still apply in every mode
+	result, err := db.Search(ctx, SearchOptions{Query: "cat -tag:cat", Mode: SearchExact, Page: 1, PageSize: 50})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	for _, item := range result.Items {
+		if item.Path == "garden/bird.jpg" {
+			t.Errorf("Expected the excluded tag's file to be filtered out, got %v", result.Items)
+		}
+	}
+}
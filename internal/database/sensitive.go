@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SetFileSensitive flags a file as sensitive, so its thumbnail is served
+// obscured until explicitly revealed, or clears the flag. Like tags, flags
+// are kept by path and survive the file briefly disappearing from the index.
+func (d *Database) SetFileSensitive(ctx context.Context, filePath string, sensitive bool) error {
+	done := observeQuery("set_file_sensitive")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var err error
+	if sensitive {
+		_, err = d.db.ExecContext(ctx, `
+			INSERT INTO sensitive_files (file_path, created_at) VALUES (?, ?)
+			ON CONFLICT(file_path) DO UPDATE SET created_at = excluded.created_at`,
+			filePath, time.Now().Unix())
+	} else {
+		_, err = d.db.ExecContext(ctx, "DELETE FROM sensitive_files WHERE file_path = ?", filePath)
+	}
+	done(err)
+	return err
+}
+
+// IsFileSensitive reports whether a file is flagged as sensitive.
+func (d *Database) IsFileSensitive(ctx context.Context, filePath string) (bool, error) {
+	done := observeQuery("is_file_sensitive")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var exists int
+	err := d.db.QueryRowContext(ctx, "SELECT 1 FROM sensitive_files WHERE file_path = ?", filePath).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		done(nil)
+		return false, nil
+	}
+	done(err)
+	return err == nil, err
+}
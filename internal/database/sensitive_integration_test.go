@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFileSensitiveIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "a.jpg", Path: "a.jpg", Type: FileTypeImage},
+		{Name: "b.jpg", Path: "b.jpg", Type: FileTypeImage},
+	})
+
+	if sensitive, err := db.IsFileSensitive(ctx, "a.jpg"); err != nil || sensitive {
+		t.Fatalf("Expected a.jpg not to be sensitive, got %v, %v", sensitive, err)
+	}
+
+	// Flagging twice is the same as once
+	for range 2 {
+		if err := db.SetFileSensitive(ctx, "a.jpg", true); err != nil {
+			t.Fatalf("SetFileSensitive failed: %v", err)
+		}
+	}
+	if sensitive, _ := db.IsFileSensitive(ctx, "a.jpg"); !sensitive {
+		t.Error("Expected a.jpg to be sensitive")
+	}
+
+	listing, err := db.ListDirectory(ctx, ListOptions{Path: "", SortField: SortByName, SortOrder: SortAsc, Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("ListDirectory failed: %v", err)
+	}
+	if len(listing.Items) != 2 || !listing.Items[0].Sensitive || listing.Items[1].Sensitive {
+		t.Errorf("Expected only a.jpg to be listed as sensitive, got %+v", listing.Items)
+	}
+
+	media, err := db.GetMediaInDirectory(ctx, "", SortByName, SortAsc)
+	if err != nil {
+		t.Fatalf("GetMediaInDirectory failed: %v", err)
+	}
+	if len(media) != 2 || !media[0].Sensitive || media[1].Sensitive {
+		t.Errorf("Expected only a.jpg to be sensitive in media, got %+v", media)
+	}
+
+	// Search results carry the flags too, so the lightbox opened from them
+	// obscures the file
+	if err := db.SetFileAnimated(ctx, "a.jpg", true); err != nil {
+		t.Fatalf("SetFileAnimated failed: %v", err)
+	}
+	if err := db.AddTagToFile(ctx, "a.jpg", "beach"); err != nil {
+		t.Fatalf("AddTagToFile failed: %v", err)
+	}
+	for _, query := range []string{"a.jpg", "tag:beach"} {
+		result, err := db.Search(ctx, SearchOptions{Query: query, Page: 1, PageSize: 10})
+		if err != nil {
+			t.Fatalf("Search(%q) failed: %v", query, err)
+		}
+		if len(result.Items) == 0 || result.Items[0].Path != "a.jpg" || !result.Items[0].Sensitive || !result.Items[0].Animated {
+			t.Errorf("Search(%q) = %+v, want a.jpg first, sensitive and animated", query, result.Items)
+		}
+	}
+
+	if err := db.SetFileSensitive(ctx, "a.jpg", false); err != nil {
+		t.Fatalf("SetFileSensitive failed to clear: %v", err)
+	}
+	if sensitive, _ := db.IsFileSensitive(ctx, "a.jpg"); sensitive {
+		t.Error("Expected the flag to be cleared")
+	}
+}
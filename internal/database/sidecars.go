@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SyncSidecarTags brings the tags imported from XMP sidecars up to date. tags
+// maps file paths to the tags their sidecars now call for; a path with an
+// empty list has no sidecar, or one with nothing to import.
+//
+// Only what changed since the last sync is applied: tags a sidecar no longer
+// calls for are removed from the file, and new ones are added. Other tags of
+// the file are left alone, and a tag that was imported and then removed by
+// hand stays removed until the sidecar changes it.
+func (d *Database) SyncSidecarTags(ctx context.Context, tags map[string][]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	done := observeQuery("sync_sidecar_tags")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		done(err)
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for filePath, names := range tags {
+		if err := syncFileSidecarTags(ctx, tx, filePath, names); err != nil {
+			err = fmt.Errorf("failed to sync sidecar tags of %s: %w", filePath, err)
+			done(err)
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	done(err)
+	return err
+}
+
+// syncFileSidecarTags applies the changes in one file's sidecar tags
+func syncFileSidecarTags(ctx context.Context, tx *sql.Tx, filePath string, names []string) error {
+	imported, err := importedSidecarTags(ctx, tx, filePath)
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || wanted[key] {
+			continue
+		}
+		wanted[key] = true
+
+		if _, ok := imported[key]; ok {
+			continue
+		}
+		tagID, err := ensureTagID(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		if err := tagFile(ctx, tx, filePath, tagID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO sidecar_tags (file_path, tag_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			filePath, tagID,
+		); err != nil {
+			return err
+		}
+	}
+
+	for key, tagID := range imported {
+		if wanted[key] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM file_tags WHERE file_path = ? AND tag_id = ?", filePath, tagID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM sidecar_tags WHERE file_path = ? AND tag_id = ?", filePath, tagID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importedSidecarTags returns the IDs of the tags last imported for a file
+// from its sidecar, keyed by lowercased name
+func importedSidecarTags(ctx context.Context, tx *sql.Tx, filePath string) (map[string]int64, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT t.id, t.name
+		FROM sidecar_tags st
+		INNER JOIN tags t ON st.tag_id = t.id
+		WHERE st.file_path = ?
+	`, filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	imported := make(map[string]int64)
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		imported[strings.ToLower(name)] = id
+	}
+	return imported, rows.Err()
+}
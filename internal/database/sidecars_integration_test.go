@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSyncSidecarTagsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	const photo = "shoot/IMG_0001.CR2"
+
+	expectTags := func(step string, want ...string) {
+		t.Helper()
+		got, err := db.GetFileTags(ctx, photo)
+		if err != nil {
+			t.Fatalf("%s: GetFileTags failed: %v", step, err)
+		}
+		if len(got) == 0 && len(want) == 0 {
+			return
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected tags %v, got %v", step, want, got)
+		}
+	}
+	syncTags := func(step string, tags ...string) {
+		t.Helper()
+		if err := db.SyncSidecarTags(ctx, map[string][]string{photo: tags}); err != nil {
+			t.Fatalf("%s: SyncSidecarTags failed: %v", step, err)
+		}
+	}
+
+	if err := db.AddTagToFile(ctx, photo, "portfolio"); err != nil {
+		t.Fatalf("AddTagToFile failed: %v", err)
+	}
+
+	syncTags("import", "rating:3", "label:Red")
+	expectTags("import", "label:Red", "portfolio", "rating:3")
+
+	// Syncing the same sidecar again changes nothing
+	syncTags("resync", "rating:3", "label:Red")
+	expectTags("resync", "label:Red", "portfolio", "rating:3")
+
+	// A label removed by hand stays removed while the sidecar still has it
+	if err := db.RemoveTagFromFile(ctx, photo, "label:Red"); err != nil {
+		t.Fatalf("RemoveTagFromFile failed: %v", err)
+	}
+	syncTags("after manual removal", "rating:3", "label:Red")
+	expectTags("after manual removal", "portfolio", "rating:3")
+
+	// A new rating replaces the old one; the hand-added tag is kept
+	syncTags("rerated", "rating:5", "label:red")
+	expectTags("rerated", "portfolio", "rating:5")
+
+	// The sidecar was deleted
+	syncTags("cleared")
+	expectTags("cleared", "portfolio")
+}
+
+func TestDeleteFilesByPrefixClearsSidecarTagsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	if err := db.SyncSidecarTags(ctx, map[string][]string{"shoot/a.jpg": {"rejected"}}); err != nil {
+		t.Fatalf("SyncSidecarTags failed: %v", err)
+	}
+
+	tx, err := db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	if _, err := db.DeleteFilesByPrefix(ctx, tx, "shoot"); err != nil {
+		t.Fatalf("DeleteFilesByPrefix failed: %v", err)
+	}
+	if err := db.EndBatch(tx, nil); err != nil {
+		t.Fatalf("EndBatch failed: %v", err)
+	}
+
+	var count int
+	if err := db.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sidecar_tags").Scan(&count); err != nil {
+		t.Fatalf("Failed to count sidecar tags: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected sidecar tags to be cleared, found %d", count)
+	}
+}
@@ -0,0 +1,239 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"media-viewer/internal/logging"
+)
+
+const (
+	// minTrigramCoverage is the share of a query's trigrams a name must
+	// contain to be suggested for it
+	minTrigramCoverage = 0.5
+
+	// similarFileCandidates bounds the file names read from the trigram
+	// index and scored for one query
+	similarFileCandidates = 200
+)
+
+// scoredSuggestion is a "did you mean" suggestion with its similarity to the query
+type scoredSuggestion struct {
+	SearchSuggestion
+	coverage float64 // Share of the query's trigrams found in the name
+	jaccard  float64 // Shared trigrams over all trigrams of both; favors names close in length
+}
+
+// SimilarSuggestions returns "did you mean" suggestions for a search that
+// found nothing: tags and file names sharing most of the query's trigrams,
+// most similar first. Tag filters in the query are matched against tag names
+// only. Words shorter than three characters have no trigrams and get no
+// suggestions.
+func (d *Database) SimilarSuggestions(ctx context.Context, query string, limit int) ([]SearchSuggestion, error) {
+	done := observeQuery("similar_suggestions")
+
+	limit = normalizeLimit(limit)
+	textQuery, tagFilters := parseTagFilters(query)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tags, err := d.tagCountsUnlocked(ctx)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+
+	var scored []scoredSuggestion
+	for _, filter := range tagFilters {
+		scored = append(scored, similarTags(tags, filter.Name, filter.Excluded)...)
+	}
+	if textQuery != "" {
+		scored = append(scored, similarTags(tags, textQuery, false)...)
+
+		files, err := d.similarFilesUnlocked(ctx, textQuery)
+		if err != nil {
+			done(err)
+			return nil, err
+		}
+		scored = append(scored, files...)
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].coverage != scored[j].coverage {
+			return scored[i].coverage > scored[j].coverage
+		}
+		if scored[i].jaccard != scored[j].jaccard {
+			return scored[i].jaccard > scored[j].jaccard
+		}
+		return scored[i].Path < scored[j].Path
+	})
+
+	suggestions := []SearchSuggestion{}
+	seen := make(map[string]bool)
+	for _, s := range scored {
+		if len(suggestions) == limit {
+			break
+		}
+		if seen[s.Path] {
+			continue
+		}
+		seen[s.Path] = true
+		suggestions = append(suggestions, s.SearchSuggestion)
+	}
+
+	done(nil)
+	return suggestions, nil
+}
+
+// tagCount is a tag name and the number of files carrying it
+type tagCount struct {
+	name  string
+	count int
+}
+
+// tagCountsUnlocked returns every tag with its file count. Tags are few
+// enough to score in full.
+func (d *Database) tagCountsUnlocked(ctx context.Context) ([]tagCount, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT t.name, COUNT(ft.id)
+		FROM tags t
+		LEFT JOIN file_tags ft ON t.id = ft.tag_id
+		GROUP BY t.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.Error("error closing rows: %v", err)
+		}
+	}()
+
+	var tags []tagCount
+	for rows.Next() {
+		var tag tagCount
+		if err := rows.Scan(&tag.name, &tag.count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// similarTags returns the tags similar to query as tag filter suggestions
+func similarTags(tags []tagCount, query string, excluded bool) []scoredSuggestion {
+	queryTrigrams := trigrams(query)
+	if len(queryTrigrams) == 0 {
+		return nil
+	}
+
+	prefix, suggestionType := TagPrefix, TagSuggestionType
+	if excluded {
+		prefix, suggestionType = "-"+TagPrefix, TagExcludeSuggestionType
+	}
+
+	var scored []scoredSuggestion
+	for _, tag := range tags {
+		coverage, jaccard := trigramSimilarity(queryTrigrams, trigrams(tag.name))
+		if coverage < minTrigramCoverage {
+			continue
+		}
+		scored = append(scored, scoredSuggestion{
+			SearchSuggestion: SearchSuggestion{
+				Path:      prefix + tag.name,
+				Name:      tag.name,
+				Type:      suggestionType,
+				Highlight: tag.name,
+				ItemCount: tag.count,
+			},
+			coverage: coverage,
+			jaccard:  jaccard,
+		})
+	}
+	return scored
+}
+
+// similarFilesUnlocked returns the files whose names are similar to query.
+// Candidates are the names sharing any trigram with it in files_fts, those
+// sharing the most first.
+func (d *Database) similarFilesUnlocked(ctx context.Context, query string) ([]scoredSuggestion, error) {
+	queryTrigrams := trigrams(query)
+	if len(queryTrigrams) == 0 {
+		return nil, nil
+	}
+
+	terms := make([]string, 0, len(queryTrigrams))
+	for trigram := range queryTrigrams {
+		terms = append(terms, prepareSearchTerm(trigram))
+	}
+	sort.Strings(terms)
+	match := "name : (" + strings.Join(terms, " OR ") + ")"
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT f.name, f.path, f.type
+		FROM files f
+		INNER JOIN files_fts fts ON f.id = fts.rowid
+		WHERE files_fts MATCH ?
+		ORDER BY bm25(files_fts)
+		LIMIT ?
+	`, match, similarFileCandidates)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.Error("error closing rows: %v", err)
+		}
+	}()
+
+	var scored []scoredSuggestion
+	for rows.Next() {
+		var s SearchSuggestion
+		if err := rows.Scan(&s.Name, &s.Path, &s.Type); err != nil {
+			return nil, err
+		}
+
+		// The extension is no part of what people misspell
+		stem := strings.TrimSuffix(s.Name, filepath.Ext(s.Name))
+		coverage, jaccard := trigramSimilarity(queryTrigrams, trigrams(stem))
+		if coverage < minTrigramCoverage {
+			continue
+		}
+		s.Highlight = s.Name
+		scored = append(scored, scoredSuggestion{SearchSuggestion: s, coverage: coverage, jaccard: jaccard})
+	}
+	return scored, rows.Err()
+}
+
+// trigrams returns the distinct three-character sequences of s, lowercased
+// as the trigram tokenizer does
+func trigrams(s string) map[string]bool {
+	runes := []rune(strings.ToLower(strings.TrimSpace(s)))
+	set := make(map[string]bool)
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = true
+	}
+	return set
+}
+
+// trigramSimilarity returns the share of the query's trigrams found in the
+// name, and the Jaccard index of both sets
+func trigramSimilarity(query, name map[string]bool) (coverage, jaccard float64) {
+	if len(query) == 0 {
+		return 0, 0
+	}
+	shared := 0
+	for trigram := range query {
+		if name[trigram] {
+			shared++
+		}
+	}
+	union := len(query) + len(name) - shared
+	return float64(shared) / float64(len(query)), float64(shared) / float64(union)
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSimilarSuggestionsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "lighthouse.jpg", Path: "coast/lighthouse.jpg", Type: FileTypeImage},
+		{Name: "lighthouse_night.jpg", Path: "coast/lighthouse_night.jpg", Type: FileTypeImage},
+		{Name: "harbor.mp4", Path: "coast/harbor.mp4", Type: FileTypeVideo},
+	})
+	if err := db.AddTagToFile(ctx, "coast/harbor.mp4", "Vacation"); err != nil {
+		t.Fatalf("AddTagToFile failed: %v", err)
+	}
+
+	suggestions, err := db.SimilarSuggestions(ctx, "lighthuose", 5)
+	if err != nil {
+		t.Fatalf("SimilarSuggestions failed: %v", err)
+	}
+	if len(suggestions) != 2 {
+		t.Fatalf("Expected both lighthouse files, got %+v", suggestions)
+	}
+	if suggestions[0].Path != "coast/lighthouse.jpg" || suggestions[0].Type != string(FileTypeImage) {
+		t.Errorf("Expected the closest name first, got %+v", suggestions[0])
+	}
+
+	// Misspelled tag filters suggest tags, keeping the exclusion
+	suggestions, err = db.SimilarSuggestions(ctx, "-tag:vacaton", 5)
+	if err != nil {
+		t.Fatalf("SimilarSuggestions failed: %v", err)
+	}
+	if len(suggestions) != 1 || suggestions[0].Path != "-tag:Vacation" ||
+		suggestions[0].Type != TagExcludeSuggestionType || suggestions[0].ItemCount != 1 {
+		t.Errorf("Expected an exclusion suggestion for the Vacation tag, got %+v", suggestions)
+	}
+
+	for _, query := range []string{"zebra", "ab", ""} {
+		suggestions, err = db.SimilarSuggestions(ctx, query, 5)
+		if err != nil || len(suggestions) != 0 {
+			t.Errorf("Expected no suggestions for %q, got %+v, %v", query, suggestions, err)
+		}
+	}
+
+	suggestions, _ = db.SimilarSuggestions(ctx, "lighthuose", 1)
+	if len(suggestions) != 1 {
+		t.Errorf("Expected the limit to apply, got %d suggestions", len(suggestions))
+	}
+}
@@ -0,0 +1,30 @@
+package database
+
+import "testing"
+
+func TestTrigramSimilarity(t *testing.T) {
+	if got := trigrams("Ab"); len(got) != 0 {
+		t.Errorf("Expected no trigrams for two characters, got %v", got)
+	}
+	if got := trigrams("Über"); !got["übe"] || !got["ber"] || len(got) != 2 {
+		t.Errorf("Expected lowercased rune trigrams, got %v", got)
+	}
+
+	tests := []struct {
+		query, name  string
+		wantCoverage float64
+		wantJaccard  float64
+	}{
+		{"sunset", "sunset", 1, 1},
+		{"sunste", "sunset", 0.5, 2.0 / 6},
+		{"beach", "sunset", 0, 0},
+		{"sun", "sunset_beach", 1, 0.1},
+	}
+	for _, tt := range tests {
+		coverage, jaccard := trigramSimilarity(trigrams(tt.query), trigrams(tt.name))
+		if coverage != tt.wantCoverage || jaccard != tt.wantJaccard {
+			t.Errorf("trigramSimilarity(%q, %q) = %v, %v; want %v, %v",
+				tt.query, tt.name, coverage, jaccard, tt.wantCoverage, tt.wantJaccard)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"media-viewer/internal/logging"
+)
+
+// StreamOptions selects the files passed to a StreamFiles callback.
+type StreamOptions struct {
+	// Path limits the stream to the contents of this folder. Empty means the
+	// whole library.
+	Path string
+
+	// Recursive includes everything below Path rather than only its direct
+	// children.
+	Recursive bool
+
+	// FilterType limits the stream to one file type, such as "image".
+	// Unlike ListOptions, folders are not included unless FilterType is
+	// "folder".
+	FilterType string
+
+	// Presence limits the stream by whether files are tagged or favorited.
+	Presence PresenceFilter
+}
+
+// StreamFiles calls fn for each file matching opts, in path order, reading
+// rows as they are consumed so memory use stays flat however many files
+// match. It stops at the first error from fn and returns it unchanged.
+//
+// The database lock isn't held while streaming: the rows come from a single
+// SQLite read snapshot, so a slow consumer doesn't hold up the indexer, and fn
+// may call other Database methods.
+func (d *Database) StreamFiles(ctx context.Context, opts StreamOptions, fn func(MediaFile) error) error {
+	done := observeQuery("stream_files")
+
+	query := `
+		SELECT id, name, path, parent_path, type, size, mod_time, mime_type
+		FROM files f
+	`
+	var conditions []string
+	var args []interface{}
+
+	folder := strings.Trim(opts.Path, "/")
+	switch {
+	case !opts.Recursive:
+		conditions = append(conditions, "parent_path = ?")
+		args = append(args, folder)
+	case folder != "":
+		// Everything under folder/ sorts between "folder/" and "folder0",
+		// which keeps the range on the path index
+		conditions = append(conditions, "path >= ? AND path < ?")
+		args = append(args, folder+"/", folder+"0")
+	}
+	if opts.FilterType != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, opts.FilterType)
+	}
+	conditions = append(conditions, opts.Presence.conditions()...)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY path"
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		done(err)
+		return fmt.Errorf("failed to query files: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.Error("error closing rows: %v", err)
+		}
+	}()
+
+	for rows.Next() {
+		var f MediaFile
+		var modTime int64
+		var mimeType sql.NullString
+
+		if err := rows.Scan(&f.ID, &f.Name, &f.Path, &f.ParentPath, &f.Type, &f.Size, &modTime, &mimeType); err != nil {
+			done(err)
+			return fmt.Errorf("error scanning file row: %w", err)
+		}
+
+		f.ModTime = time.Unix(modTime, 0)
+		if mimeType.Valid {
+			f.MimeType = mimeType.String
+		}
+
+		if err := fn(f); err != nil {
+			done(nil)
+			return err
+		}
+	}
+
+	err = rows.Err()
+	done(err)
+	if err != nil {
+		return fmt.Errorf("error iterating file rows: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestStreamFilesIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "trips", Path: "trips", ParentPath: "", Type: FileTypeFolder},
+		{Name: "beach.jpg", Path: "trips/beach.jpg", ParentPath: "trips", Type: FileTypeImage},
+		{Name: "2023", Path: "trips/2023", ParentPath: "trips", Type: FileTypeFolder},
+		{Name: "dunes.jpg", Path: "trips/2023/dunes.jpg", ParentPath: "trips/2023", Type: FileTypeImage},
+		{Name: "waves.mp4", Path: "trips/2023/waves.mp4", ParentPath: "trips/2023", Type: FileTypeVideo},
+		{Name: "pier.jpg", Path: "trips-old/pier.jpg", ParentPath: "trips-old", Type: FileTypeImage},
+		{Name: "cover.jpg", Path: "cover.jpg", ParentPath: "", Type: FileTypeImage},
+	})
+
+	tests := []struct {
+		name     string
+		opts     StreamOptions
+		expected []string
+	}{
+		{"root children", StreamOptions{}, []string{"cover.jpg", "trips"}},
+		{"folder children", StreamOptions{Path: "trips"}, []string{"trips/2023", "trips/beach.jpg"}},
+		{"recursive", StreamOptions{Path: "trips", Recursive: true}, []string{
+			"trips/2023", "trips/2023/dunes.jpg", "trips/2023/waves.mp4", "trips/beach.jpg",
+		}},
+		{"recursive with type", StreamOptions{Path: "/trips/", Recursive: true, FilterType: "image"}, []string{
+			"trips/2023/dunes.jpg", "trips/beach.jpg",
+		}},
+		{"whole library", StreamOptions{Recursive: true, FilterType: "image"}, []string{
+			"cover.jpg", "trips-old/pier.jpg", "trips/2023/dunes.jpg", "trips/beach.jpg",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := []string{}
+			err := db.StreamFiles(context.Background(), tt.opts, func(f MediaFile) error {
+				paths = append(paths, f.Path)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("StreamFiles failed: %v", err)
+			}
+			if !reflect.DeepEqual(paths, tt.expected) {
+				t.Errorf("StreamFiles(%+v) = %v, want %v", tt.opts, paths, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStreamFilesCallbackErrorIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "a.jpg", Path: "a.jpg", Type: FileTypeImage},
+		{Name: "b.jpg", Path: "b.jpg", Type: FileTypeImage},
+		{Name: "c.jpg", Path: "c.jpg", Type: FileTypeImage},
+	})
+
+	ctx := context.Background()
+	errStop := errors.New("stop")
+	calls := 0
+	err := db.StreamFiles(ctx, StreamOptions{}, func(f MediaFile) error {
+		calls++
+		// Other methods can be called from the callback without deadlocking
+		if _, err := db.GetFileByPath(ctx, f.Path); err != nil {
+			t.Errorf("GetFileByPath failed: %v", err)
+		}
+		if calls == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("Expected the callback error, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected streaming to stop after 2 files, got %d calls", calls)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
@@ -25,38 +26,71 @@ func (d *Database) GetOrCreateTag(ctx context.Context, name string) (*Tag, error
 	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
-	// Try to get existing tag
+	id, err := ensureTagID(ctx, d.db, name)
+	if err != nil {
+		return nil, err
+	}
+
 	var tag Tag
 	var createdAt int64
 	var color sql.NullString
-
-	err := d.db.QueryRowContext(ctx,
-		"SELECT id, name, color, created_at FROM tags WHERE name = ? COLLATE NOCASE",
-		name,
+	err = d.db.QueryRowContext(ctx,
+		"SELECT id, name, color, created_at FROM tags WHERE id = ?",
+		id,
 	).Scan(&tag.ID, &tag.Name, &color, &createdAt)
+	if err != nil {
+		return nil, err
+	}
 
-	if err == nil {
-		tag.CreatedAt = time.Unix(createdAt, 0)
-		if color.Valid {
-			tag.Color = color.String
-		}
-		return &tag, nil
+	tag.CreatedAt = time.Unix(createdAt, 0)
+	if color.Valid {
+		tag.Color = color.String
+	}
+	return &tag, nil
+}
+
+// tagWriter is the part of *sql.DB and *sql.Tx the tag helpers need
+type tagWriter interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// ensureTagID returns the ID of the tag with the given name, in any case,
+// creating it if it doesn't exist. A tag created by another writer between
+// the lookup and the insert is picked up rather than failing on the unique
+// name, so concurrent adds of a new tag all succeed with the same ID.
+func ensureTagID(ctx context.Context, q tagWriter, name string) (int64, error) {
+	id, _, err := ensureTag(ctx, q, name)
+	return id, err
+}
+
+// ensureTag is ensureTagID, also reporting whether this call created the tag
+func ensureTag(ctx context.Context, q tagWriter, name string) (id int64, created bool, err error) {
+	const lookup = "SELECT id FROM tags WHERE name = ? COLLATE NOCASE"
+
+	err = q.QueryRowContext(ctx, lookup, name).Scan(&id)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return id, false, err
 	}
 
-	// Create new tag
-	result, err := d.db.ExecContext(ctx,
-		"INSERT INTO tags (name) VALUES (?)",
-		name,
-	)
+	res, err := q.ExecContext(ctx, "INSERT INTO tags (name) VALUES (?) ON CONFLICT(name) DO NOTHING", name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create tag: %w", err)
+		return 0, false, fmt.Errorf("failed to create tag: %w", err)
 	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		created = true
+	}
+	err = q.QueryRowContext(ctx, lookup, name).Scan(&id)
+	return id, created, err
+}
 
-	tag.ID, _ = result.LastInsertId()
-	tag.Name = name
-	tag.CreatedAt = time.Now()
-
-	return &tag, nil
+// tagFile links a file to a tag. Linking it again is a no-op.
+func tagFile(ctx context.Context, q tagWriter, filePath string, tagID int64) error {
+	_, err := q.ExecContext(ctx,
+		"INSERT INTO file_tags (file_path, tag_id) VALUES (?, ?) ON CONFLICT(file_path, tag_id) DO NOTHING",
+		filePath, tagID,
+	)
+	return err
 }
 
 // AddTagToFile adds a tag to a file.
@@ -78,32 +112,19 @@ func (d *Database) AddTagToFile(ctx context.Context, filePath, tagName string) e
 	defer cancel()
 
 	// Get or create tag within the same lock
-	var tagID int64
-	err := d.db.QueryRowContext(ctx,
-		"SELECT id FROM tags WHERE name = ? COLLATE NOCASE",
-		tagName,
-	).Scan(&tagID)
-
+	tagID, err := ensureTagID(ctx, d.db, tagName)
 	if err != nil {
-		// Create new tag
-		result, createErr := d.db.ExecContext(ctx, "INSERT INTO tags (name) VALUES (?)", tagName)
-		if createErr != nil {
-			err = fmt.Errorf("failed to create tag: %w", createErr)
-			done(err)
-			return err
-		}
-		tagID, _ = result.LastInsertId()
+		done(err)
+		return err
 	}
 
-	_, err = d.db.ExecContext(ctx,
-		"INSERT OR IGNORE INTO file_tags (file_path, tag_id) VALUES (?, ?)",
-		filePath, tagID,
-	)
+	err = tagFile(ctx, d.db, filePath, tagID)
 	done(err)
 	return err
 }
 
-// RemoveTagFromFile removes a tag from a file.
+// RemoveTagFromFile removes a tag from a file. Removing a tag the file
+// doesn't have, or one that doesn't exist, is a no-op.
 func (d *Database) RemoveTagFromFile(ctx context.Context, filePath, tagName string) error {
 	done := observeQuery("remove_tag_from_file")
 
@@ -206,25 +227,14 @@ func (d *Database) SetFileTags(ctx context.Context, filePath string, tagNames []
 		}
 
 		// Get or create tag
-		var tagID int64
-		err = tx.QueryRowContext(ctx, "SELECT id FROM tags WHERE name = ? COLLATE NOCASE", tagName).Scan(&tagID)
+		tagID, err := ensureTagID(ctx, tx, tagName)
 		if err != nil {
-			// Create tag
-			result, createErr := tx.ExecContext(ctx, "INSERT INTO tags (name) VALUES (?)", tagName)
-			if createErr != nil {
-				err = createErr
-				done(err)
-				return err
-			}
-			tagID, _ = result.LastInsertId()
+			done(err)
+			return err
 		}
 
 		// Add relationship
-		_, err = tx.ExecContext(ctx,
-			"INSERT OR IGNORE INTO file_tags (file_path, tag_id) VALUES (?, ?)",
-			filePath, tagID,
-		)
-		if err != nil {
+		if err := tagFile(ctx, tx, filePath, tagID); err != nil {
 			done(err)
 			return err
 		}
@@ -433,8 +443,30 @@ func (d *Database) RenameTag(ctx context.Context, oldName, newName string) error
 	return err
 }
 
+// ErrInvalidTagColor is returned for a tag color that isn't a hex color,
+// a color name or an rgb() or hsl() color.
+var ErrInvalidTagColor = errors.New("invalid tag color")
+
+// tagColorPattern matches the tag colors the web UI can put in a style
+// attribute: "#rgb", "#rgba", "#rrggbb" and "#rrggbbaa", a color name, and
+// rgb(), rgba(), hsl() and hsla() with numeric arguments.
+var tagColorPattern = regexp.MustCompile(`^(#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})|[a-zA-Z]{1,32}|(rgb|rgba|hsl|hsla)\([0-9.,%/ ]+\))$`)
+
+// validateTagColor returns ErrInvalidTagColor unless color is empty, which
+// clears it, or matches tagColorPattern.
+func validateTagColor(color string) error {
+	if color != "" && !tagColorPattern.MatchString(color) {
+		return fmt.Errorf("%w %q", ErrInvalidTagColor, color)
+	}
+	return nil
+}
+
 // SetTagColor sets the color for a tag.
 func (d *Database) SetTagColor(ctx context.Context, tagName, color string) error {
+	if err := validateTagColor(color); err != nil {
+		return err
+	}
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
@@ -629,10 +661,11 @@ func (d *Database) RenameTagEverywhere(ctx context.Context, oldName, newName str
 			// Different tags, we need to merge
 			// Move all file_tags from old tag to new tag (skip duplicates)
 			_, err = tx.ExecContext(ctx, `
-				INSERT OR IGNORE INTO file_tags (file_path, tag_id, created_at)
+				INSERT INTO file_tags (file_path, tag_id, created_at)
 				SELECT file_path, ?, created_at
 				FROM file_tags
 				WHERE tag_id = ?
+				ON CONFLICT(file_path, tag_id) DO NOTHING
 			`, existingID, oldID)
 			if err != nil {
 				err = fmt.Errorf("failed to merge file tags: %w", err)
@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 )
@@ -835,3 +836,62 @@ func TestDeleteTagEverywhereIntegration(t *testing.T) {
 		}
 	})
 }
+
+func TestTagsConcurrentSameTagIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, dbPath := setupTestDB(t)
+	defer db.Close()
+
+	// A second connection pool isn't serialized by db's lock, so both can
+	// try to create the same new tag at once
+	other, _, err := New(context.Background(), dbPath, nil)
+	if err != nil {
+		t.Fatalf("Failed to open second connection: %v", err)
+	}
+	defer other.Close()
+
+	ctx := context.Background()
+	names := []string{"Holiday", "holiday", "HOLIDAY"}
+	run := func(op func(d *Database, i int) error) {
+		t.Helper()
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for i := range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d := db
+				if i%2 == 1 {
+					d = other
+				}
+				errs <- op(d, i)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Errorf("Concurrent tag write failed: %v", err)
+			}
+		}
+	}
+
+	run(func(d *Database, i int) error {
+		return d.AddTagToFile(ctx, "a.jpg", names[i%len(names)])
+	})
+	if tags, _ := db.GetFileTags(ctx, "a.jpg"); len(tags) != 1 {
+		t.Errorf("Expected one tag on a.jpg, got %v", tags)
+	}
+	if count := db.GetTagCount(ctx); count != 1 {
+		t.Errorf("Expected one tag to be created, got %d", count)
+	}
+
+	run(func(d *Database, i int) error {
+		return d.RemoveTagFromFile(ctx, "a.jpg", names[i%len(names)])
+	})
+	if tags, _ := db.GetFileTags(ctx, "a.jpg"); len(tags) != 0 {
+		t.Errorf("Expected no tags on a.jpg after removal, got %v", tags)
+	}
+}
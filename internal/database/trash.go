@@ -0,0 +1,310 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrTrashItemNotFound is returned for a trash ID that doesn't exist.
+var ErrTrashItemNotFound = errors.New("trash item not found")
+
+// TrashItem is a file or folder deleted through the API and held in the
+// trash directory until it is restored or purged.
+type TrashItem struct {
+	ID           int64     `json:"id"`
+	OriginalPath string    `json:"originalPath"`
+	Name         string    `json:"name"`
+	Type         FileType  `json:"type"`
+	Size         int64     `json:"size"` // Total of the indexed files in a folder
+	DeletedAt    time.Time `json:"deletedAt"`
+	TrashName    string    `json:"-"` // Name in the trash directory
+}
+
+const (
+	trashColumns = "id, original_path, trash_name, type, size, deleted_at"
+
+	// trashMatch selects a trashed path and everything under it
+	trashMatch = "(%[1]s = ? OR (%[1]s >= ? AND %[1]s < ?))"
+)
+
+// triggerClearedColumns lists the tables the delete triggers on files clear,
+// with their columns, so removing trashed files from the index can put their
+// rows back for a restore.
+var triggerClearedColumns = []struct{ table, columns string }{
+	{"file_palettes", "file_path, colors, updated_at"},
+	{"folder_order", "file_path, folder_path, sort_order"},
+}
+
+// AddTrashItem records a file or folder about to be moved to the trash, so
+// it can be found and restored even if the move is cut short. Its size is
+// the total of the indexed files under it. UnindexTrashItems removes it from
+// the index once it is moved. item.OriginalPath and item.TrashName must be
+// set; ID, Name, Size and DeletedAt are filled in.
+func (d *Database) AddTrashItem(ctx context.Context, item *TrashItem) error {
+	item.OriginalPath = strings.Trim(item.OriginalPath, "/")
+	if item.OriginalPath == "" || item.TrashName == "" {
+		return errors.New("trash item needs a path and a trash name")
+	}
+
+	done := observeQuery("add_trash_item")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	// A range on the path index instead of LIKE, as in DeleteFilesByPrefix
+	var size int64
+	if err := d.db.QueryRowContext(ctx, "SELECT COALESCE(SUM(size), 0) FROM files WHERE type != ? AND "+fmt.Sprintf(trashMatch, "path"),
+		append([]any{FileTypeFolder}, trashMatchArgs(item.OriginalPath)...)...).Scan(&size); err != nil {
+		done(err)
+		return err
+	}
+
+	deletedAt := time.Now().Truncate(time.Second)
+	result, err := d.db.ExecContext(ctx, `
+		INSERT INTO trash (original_path, trash_name, type, size, deleted_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		item.OriginalPath, item.TrashName, item.Type, size, deletedAt.Unix())
+	if err != nil {
+		done(err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	done(err)
+	if err != nil {
+		return err
+	}
+
+	item.ID = id
+	item.Name = item.OriginalPath[strings.LastIndex(item.OriginalPath, "/")+1:]
+	item.Size = size
+	item.DeletedAt = deletedAt
+	return nil
+}
+
+// UnindexTrashItems removes files and folders moved to the trash, and
+// everything in the folders, from the index in a single transaction: if it
+// fails, the index is left as it was. Favorites, tags, palettes, manual
+// order and the other data kept by path stay, so a restore brings them
+// back; PurgeTrashItem removes them.
+func (d *Database) UnindexTrashItems(ctx context.Context, items []*TrashItem) error {
+	done := observeQuery("unindex_trash_items")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		done(err)
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, item := range items {
+		if err := unindexTrashItemTx(ctx, tx, item.OriginalPath); err != nil {
+			done(err)
+			return fmt.Errorf("%s: %w", item.OriginalPath, err)
+		}
+	}
+
+	err = tx.Commit()
+	done(err)
+	return err
+}
+
+// unindexTrashItemTx removes one path of UnindexTrashItems from the index,
+// putting back the rows the delete triggers clear
+func unindexTrashItemTx(ctx context.Context, tx *sql.Tx, originalPath string) error {
+	args := trashMatchArgs(originalPath)
+
+	kept := make([][][]any, len(triggerClearedColumns))
+	for i, tc := range triggerClearedColumns {
+		rows, err := tx.QueryContext(ctx, "SELECT "+tc.columns+" FROM "+tc.table+" WHERE "+fmt.Sprintf(trashMatch, "file_path"), args...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			row := make([]any, strings.Count(tc.columns, ",")+1)
+			dest := make([]any, len(row))
+			for j := range row {
+				dest[j] = &row[j]
+			}
+			if err := rows.Scan(dest...); err != nil {
+				_ = rows.Close()
+				return err
+			}
+			kept[i] = append(kept[i], row)
+		}
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM files WHERE "+fmt.Sprintf(trashMatch, "path"), args...); err != nil {
+		return err
+	}
+
+	for i, tc := range triggerClearedColumns {
+		for _, row := range kept[i] {
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(row)), ", ")
+			if _, err := tx.ExecContext(ctx, "INSERT OR REPLACE INTO "+tc.table+" ("+tc.columns+") VALUES ("+placeholders+")", row...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// trashMatchArgs returns the arguments of trashMatch for a path
+func trashMatchArgs(p string) []any {
+	return []any{p, p + "/", p + "0"}
+}
+
+// ListTrash returns the items in the trash, most recently deleted first.
+func (d *Database) ListTrash(ctx context.Context) ([]TrashItem, error) {
+	done := observeQuery("list_trash")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	items, err := scanTrashItems(d.db.QueryContext(ctx, "SELECT "+trashColumns+" FROM trash ORDER BY deleted_at DESC, id DESC"))
+	done(err)
+	return items, err
+}
+
+// ListExpiredTrash returns the items deleted before cutoff, oldest first.
+func (d *Database) ListExpiredTrash(ctx context.Context, cutoff time.Time) ([]TrashItem, error) {
+	done := observeQuery("list_expired_trash")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	items, err := scanTrashItems(d.db.QueryContext(ctx,
+		"SELECT "+trashColumns+" FROM trash WHERE deleted_at < ? ORDER BY deleted_at, id", cutoff.Unix()))
+	done(err)
+	return items, err
+}
+
+// GetTrashItem returns an item in the trash by ID, or ErrTrashItemNotFound.
+func (d *Database) GetTrashItem(ctx context.Context, id int64) (*TrashItem, error) {
+	done := observeQuery("get_trash_item")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	items, err := scanTrashItems(d.db.QueryContext(ctx, "SELECT "+trashColumns+" FROM trash WHERE id = ?", id))
+	if err == nil && len(items) == 0 {
+		err = ErrTrashItemNotFound
+	}
+	done(err)
+	if err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// RemoveTrashItem forgets an item that was restored from the trash.
+func (d *Database) RemoveTrashItem(ctx context.Context, id int64) error {
+	done := observeQuery("remove_trash_item")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, "DELETE FROM trash WHERE id = ?", id)
+	done(err)
+	return err
+}
+
+// PurgeTrashItem forgets an item removed from the trash for good, along with
+// the favorites, tags and other data kept for its path. That data is kept
+// if something has since been indexed at the path.
+func (d *Database) PurgeTrashItem(ctx context.Context, item *TrashItem) error {
+	done := observeQuery("purge_trash_item")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		done(err)
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM trash WHERE id = ?", item.ID); err != nil {
+		done(err)
+		return err
+	}
+
+	args := trashMatchArgs(item.OriginalPath)
+
+	var replaced bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM files WHERE "+fmt.Sprintf(trashMatch, "path")+")", args...).Scan(&replaced); err != nil {
+		done(err)
+		return err
+	}
+	if !replaced {
+		for _, pc := range pathColumns {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+pc.table+" WHERE "+fmt.Sprintf(trashMatch, pc.column), args...); err != nil {
+				done(err)
+				return err
+			}
+		}
+		// A folder's own manual order goes with it too
+		if _, err := tx.ExecContext(ctx, "DELETE FROM folder_order WHERE "+fmt.Sprintf(trashMatch, "folder_path"), args...); err != nil {
+			done(err)
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	done(err)
+	return err
+}
+
+// scanTrashItems reads trash rows selected with trashColumns
+func scanTrashItems(rows *sql.Rows, err error) ([]TrashItem, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []TrashItem{}
+	for rows.Next() {
+		var item TrashItem
+		var deletedAt int64
+		if err := rows.Scan(&item.ID, &item.OriginalPath, &item.TrashName, &item.Type, &item.Size, &deletedAt); err != nil {
+			return nil, err
+		}
+		item.Name = item.OriginalPath[strings.LastIndex(item.OriginalPath, "/")+1:]
+		item.DeletedAt = time.Unix(deletedAt, 0)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,214 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTrashIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "trips", Path: "trips", ParentPath: "", Type: FileTypeFolder},
+		{Name: "a.jpg", Path: "trips/a.jpg", ParentPath: "trips", Type: FileTypeImage},
+		{Name: "b.jpg", Path: "trips/b.jpg", ParentPath: "trips", Type: FileTypeImage},
+		{Name: "trips2", Path: "trips2", ParentPath: "", Type: FileTypeFolder},
+		{Name: "c.jpg", Path: "trips2/c.jpg", ParentPath: "trips2", Type: FileTypeImage},
+	})
+	if err := db.AddTagToFile(ctx, "trips/a.jpg", "sunset"); err != nil {
+		t.Fatalf("AddTagToFile failed: %v", err)
+	}
+
+	item := &TrashItem{OriginalPath: "trips", TrashName: "1-trips", Type: FileTypeFolder}
+	if err := db.AddTrashItem(ctx, item); err != nil {
+		t.Fatalf("AddTrashItem failed: %v", err)
+	}
+	if item.ID == 0 || item.Name != "trips" || item.Size != 2048 {
+		t.Errorf("item = %+v, want an ID, name trips and the size of both files", item)
+	}
+	if _, err := db.GetFileByPath(ctx, "trips/a.jpg"); err != nil {
+		t.Errorf("Expected trips/a.jpg indexed until the move is done: %v", err)
+	}
+	if err := db.UnindexTrashItems(ctx, []*TrashItem{item}); err != nil {
+		t.Fatalf("UnindexTrashItems failed: %v", err)
+	}
+
+	for _, path := range []string{"trips", "trips/a.jpg"} {
+		if _, err := db.GetFileByPath(ctx, path); err == nil {
+			t.Errorf("Expected %s removed from the index", path)
+		}
+	}
+	if _, err := db.GetFileByPath(ctx, "trips2/c.jpg"); err != nil {
+		t.Errorf("Expected a sibling sharing the name as a prefix kept: %v", err)
+	}
+	if tags, _ := db.GetFileTags(ctx, "trips/a.jpg"); len(tags) != 1 {
+		t.Errorf("tags = %v, want them kept while the file is in the trash", tags)
+	}
+
+	items, err := db.ListTrash(ctx)
+	if err != nil || len(items) != 1 || items[0].OriginalPath != "trips" || items[0].TrashName != "1-trips" {
+		t.Fatalf("ListTrash = %+v, %v", items, err)
+	}
+	got, err := db.GetTrashItem(ctx, item.ID)
+	if err != nil || got.Size != item.Size || !got.DeletedAt.Equal(item.DeletedAt) {
+		t.Errorf("GetTrashItem = %+v, %v, want %+v", got, err, item)
+	}
+	if _, err := db.GetTrashItem(ctx, item.ID+1); !errors.Is(err, ErrTrashItemNotFound) {
+		t.Errorf("GetTrashItem of a missing ID = %v, want ErrTrashItemNotFound", err)
+	}
+
+	if expired, _ := db.ListExpiredTrash(ctx, time.Now().Add(-time.Hour)); len(expired) != 0 {
+		t.Errorf("Expected nothing expired yet, got %+v", expired)
+	}
+	expired, err := db.ListExpiredTrash(ctx, time.Now().Add(time.Hour))
+	if err != nil || len(expired) != 1 {
+		t.Fatalf("ListExpiredTrash = %+v, %v", expired, err)
+	}
+
+	if err := db.PurgeTrashItem(ctx, &expired[0]); err != nil {
+		t.Fatalf("PurgeTrashItem failed: %v", err)
+	}
+	if items, _ := db.ListTrash(ctx); len(items) != 0 {
+		t.Errorf("Expected the trash to be empty, got %+v", items)
+	}
+	if tags, _ := db.GetFileTags(ctx, "trips/a.jpg"); len(tags) != 0 {
+		t.Errorf("tags = %v, want them removed with the purged file", tags)
+	}
+}
+
+func TestTrashRestoreIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "a.jpg", Path: "a.jpg", ParentPath: "", Type: FileTypeImage},
+	})
+	if err := db.AddTagToFile(ctx, "a.jpg", "sunset"); err != nil {
+		t.Fatalf("AddTagToFile failed: %v", err)
+	}
+
+	item := &TrashItem{OriginalPath: "a.jpg", TrashName: "1-a.jpg", Type: FileTypeImage}
+	if err := db.AddTrashItem(ctx, item); err != nil {
+		t.Fatalf("AddTrashItem failed: %v", err)
+	}
+	if err := db.AddTrashItem(ctx, &TrashItem{OriginalPath: "", TrashName: "2"}); err == nil {
+		t.Error("Expected an item without a path to be rejected")
+	}
+
+	if err := db.RemoveTrashItem(ctx, item.ID); err != nil {
+		t.Fatalf("RemoveTrashItem failed: %v", err)
+	}
+	if _, err := db.GetTrashItem(ctx, item.ID); !errors.Is(err, ErrTrashItemNotFound) {
+		t.Errorf("GetTrashItem after removal = %v, want ErrTrashItemNotFound", err)
+	}
+	if tags, _ := db.GetFileTags(ctx, "a.jpg"); len(tags) != 1 {
+		t.Errorf("tags = %v, want them kept for the restored file", tags)
+	}
+
+	// Purging keeps the data of a file indexed at the path since
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "a.jpg", Path: "a.jpg", ParentPath: "", Type: FileTypeImage},
+	})
+	if err := db.PurgeTrashItem(ctx, item); err != nil {
+		t.Fatalf("PurgeTrashItem failed: %v", err)
+	}
+	if tags, _ := db.GetFileTags(ctx, "a.jpg"); len(tags) != 1 {
+		t.Errorf("tags = %v, want them kept for the file now at the path", tags)
+	}
+}
+
+func TestUnindexTrashItemsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "a.jpg", Path: "a.jpg", ParentPath: "", Type: FileTypeImage},
+		{Name: "b.jpg", Path: "b.jpg", ParentPath: "", Type: FileTypeImage},
+	})
+
+	items := []*TrashItem{
+		{OriginalPath: "a.jpg", TrashName: "1-a.jpg", Type: FileTypeImage},
+		{OriginalPath: "b.jpg", TrashName: "1-a.jpg", Type: FileTypeImage},
+	}
+	if err := db.AddTrashItem(ctx, items[0]); err != nil {
+		t.Fatalf("AddTrashItem failed: %v", err)
+	}
+	if err := db.AddTrashItem(ctx, items[1]); err == nil {
+		t.Fatal("Expected a duplicate trash name to be rejected")
+	}
+	items[1].TrashName = "2-b.jpg"
+	if err := db.AddTrashItem(ctx, items[1]); err != nil {
+		t.Fatalf("AddTrashItem failed: %v", err)
+	}
+	if trash, _ := db.ListTrash(ctx); len(trash) != 2 {
+		t.Errorf("trash = %+v, want both items recorded before they are moved", trash)
+	}
+
+	if err := db.UnindexTrashItems(ctx, items); err != nil {
+		t.Fatalf("UnindexTrashItems failed: %v", err)
+	}
+	for _, path := range []string{"a.jpg", "b.jpg"} {
+		if _, err := db.GetFileByPath(ctx, path); err == nil {
+			t.Errorf("Expected %s removed from the index", path)
+		}
+	}
+}
+
+func TestUnindexTrashItemsKeepsTriggerClearedDataIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	files := []MediaFile{
+		{Name: "trips", Path: "trips", ParentPath: "", Type: FileTypeFolder},
+		{Name: "a.jpg", Path: "trips/a.jpg", ParentPath: "trips", Type: FileTypeImage},
+		{Name: "b.jpg", Path: "trips/b.jpg", ParentPath: "trips", Type: FileTypeImage},
+	}
+	insertOrderTestFiles(t, db, files)
+	if err := db.SetFilePalette(ctx, "trips/a.jpg", []string{"#112233"}); err != nil {
+		t.Fatalf("SetFilePalette failed: %v", err)
+	}
+	if err := db.SetFolderOrder(ctx, "trips", []string{"trips/b.jpg", "trips/a.jpg"}); err != nil {
+		t.Fatalf("SetFolderOrder failed: %v", err)
+	}
+
+	item := &TrashItem{OriginalPath: "trips", TrashName: "1-trips", Type: FileTypeFolder}
+	if err := db.AddTrashItem(ctx, item); err != nil {
+		t.Fatalf("AddTrashItem failed: %v", err)
+	}
+	if err := db.UnindexTrashItems(ctx, []*TrashItem{item}); err != nil {
+		t.Fatalf("UnindexTrashItems failed: %v", err)
+	}
+
+	// Restored and indexed again
+	if err := db.RemoveTrashItem(ctx, item.ID); err != nil {
+		t.Fatalf("RemoveTrashItem failed: %v", err)
+	}
+	insertOrderTestFiles(t, db, files)
+
+	if palette, _ := db.GetFilePalette(ctx, "trips/a.jpg"); !slices.Equal(palette, []string{"#112233"}) {
+		t.Errorf("palette = %v, want it kept through the trash", palette)
+	}
+	if order, _ := db.GetFolderOrder(ctx, "trips"); !slices.Equal(order, []string{"trips/b.jpg", "trips/a.jpg"}) {
+		t.Errorf("order = %v, want it kept through the trash", order)
+	}
+}
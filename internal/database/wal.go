@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"media-viewer/internal/metrics"
+)
+
+// CheckpointResult reports the outcome of a WAL checkpoint
+type CheckpointResult struct {
+	Busy         bool // A reader or writer prevented the checkpoint from completing
+	LogPages     int  // Pages in the WAL when the checkpoint ran
+	Checkpointed int  // Pages written back to the database
+}
+
+// connectionPragmas returns the pragmas to run on every new connection
+func connectionPragmas(opts *Options) []string {
+	if opts == nil || opts.WALAutoCheckpoint == 0 {
+		return nil
+	}
+	pages := max(opts.WALAutoCheckpoint, 0)
+	return []string{fmt.Sprintf("PRAGMA wal_autocheckpoint = %d", pages)}
+}
+
+// CheckpointWAL copies the WAL back into the database and truncates it
+// (PRAGMA wal_checkpoint(TRUNCATE)). Database writes are blocked while it
+// runs, so it is meant for idle periods such as the end of an index run.
+func (d *Database) CheckpointWAL(ctx context.Context) (CheckpointResult, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var result CheckpointResult
+	var busy int
+
+	start := time.Now()
+	done := observeQuery("wal_checkpoint")
+	err := d.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &result.LogPages, &result.Checkpointed)
+	done(err)
+	if err != nil {
+		return result, err
+	}
+	result.Busy = busy != 0
+
+	metrics.DBCheckpointDuration.Observe(time.Since(start).Seconds())
+	if !result.Busy {
+		metrics.DBLastCheckpointTimestamp.Set(float64(time.Now().Unix()))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestWALAutoCheckpointIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	tests := []struct {
+		name string
+		opts *Options
+		want int
+	}{
+		{"nil options keep default", nil, 1000},
+		{"custom page count", &Options{WALAutoCheckpoint: 50}, 50},
+		{"negative disables", &Options{WALAutoCheckpoint: -1}, 0},
+		{"combined with mmap disabled", &Options{MmapDisabled: true, WALAutoCheckpoint: 4000}, 4000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _ := setupTestDB(t, tt.opts)
+			defer db.Close()
+
+			// Hold several connections so the setting is checked beyond the first
+			ctx := context.Background()
+			for range 3 {
+				conn, err := db.db.Conn(ctx)
+				if err != nil {
+					t.Fatalf("Failed to get connection: %v", err)
+				}
+				defer conn.Close()
+
+				var pages int
+				if err := conn.QueryRowContext(ctx, "PRAGMA wal_autocheckpoint").Scan(&pages); err != nil {
+					t.Fatalf("Failed to read wal_autocheckpoint: %v", err)
+				}
+				if pages != tt.want {
+					t.Errorf("wal_autocheckpoint = %d, want %d", pages, tt.want)
+				}
+
+				if tt.opts != nil && tt.opts.MmapDisabled {
+					var mmapSize int64
+					if err := conn.QueryRowContext(ctx, "PRAGMA mmap_size").Scan(&mmapSize); err != nil {
+						t.Fatalf("Failed to read mmap_size: %v", err)
+					}
+					if mmapSize != 0 {
+						t.Errorf("mmap_size = %d, want 0 alongside wal_autocheckpoint", mmapSize)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCheckpointWALIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, dbPath := setupTestDB(t, &Options{WALAutoCheckpoint: -1})
+	defer db.Close()
+
+	insertOrderTestFiles(t, db, []MediaFile{
+		{Name: "a.jpg", Path: "a.jpg", Type: FileTypeImage},
+		{Name: "b.jpg", Path: "b.jpg", Type: FileTypeImage},
+	})
+
+	info, err := os.Stat(dbPath + "-wal")
+	if err != nil || info.Size() == 0 {
+		t.Fatalf("Expected writes to accumulate in the WAL, got %v", err)
+	}
+
+	result, err := db.CheckpointWAL(context.Background())
+	if err != nil {
+		t.Fatalf("CheckpointWAL failed: %v", err)
+	}
+	if result.Busy || result.LogPages != 0 {
+		t.Errorf("Expected a complete checkpoint leaving an empty WAL, got %+v", result)
+	}
+
+	info, err = os.Stat(dbPath + "-wal")
+	if err != nil {
+		t.Fatalf("Failed to stat WAL: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("Expected the WAL to be truncated, got %d bytes", info.Size())
+	}
+}
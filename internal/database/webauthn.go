@@ -312,8 +312,31 @@ func (d *Database) UpdateCredentialSignCount(ctx context.Context, credentialID [
 	return err
 }
 
+// ErrCredentialNotFound is returned when deleting a passkey the user doesn't have
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// ErrLastLoginMethod is returned when deleting the user's last passkey while
+// no password is set, which would leave no way to log in
+var ErrLastLoginMethod = errors.New("last login method")
+
 // DeleteWebAuthnCredential removes a passkey
 func (d *Database) DeleteWebAuthnCredential(ctx context.Context, userID, credentialID int64) error {
+	return d.deleteWebAuthnCredential(ctx, userID,
+		"DELETE FROM webauthn_credentials WHERE id = ? AND user_id = ?", credentialID)
+}
+
+// DeleteWebAuthnCredentialByCredentialID removes the passkey with a WebAuthn
+// credential ID
+func (d *Database) DeleteWebAuthnCredentialByCredentialID(ctx context.Context, userID int64, credentialID []byte) error {
+	return d.deleteWebAuthnCredential(ctx, userID,
+		"DELETE FROM webauthn_credentials WHERE credential_id = ? AND user_id = ?", credentialID)
+}
+
+// deleteWebAuthnCredential removes the user's passkey with a query taking
+// the passkey's ID and the user's, returning ErrCredentialNotFound if it
+// matched none, or ErrLastLoginMethod if it was the last passkey and the
+// user has no password
+func (d *Database) deleteWebAuthnCredential(ctx context.Context, userID int64, query string, id any) error {
 	done := observeQuery("delete_webauthn_credential")
 
 	d.mu.Lock()
@@ -322,9 +345,14 @@ func (d *Database) DeleteWebAuthnCredential(ctx context.Context, userID, credent
 	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
-	result, err := d.db.ExecContext(ctx, `
-		DELETE FROM webauthn_credentials WHERE id = ? AND user_id = ?
-	`, credentialID, userID)
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		done(err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	result, err := tx.ExecContext(ctx, query, id, userID)
 	if err != nil {
 		logging.Error("Failed to delete WebAuthn credential: %v", err)
 		done(err)
@@ -333,12 +361,33 @@ func (d *Database) DeleteWebAuthnCredential(ctx context.Context, userID, credent
 
 	rows, _ := result.RowsAffected()
 	if rows == 0 {
-		err = fmt.Errorf("credential not found")
+		done(ErrCredentialNotFound)
+		return ErrCredentialNotFound
+	}
+
+	// Checked in the transaction, so concurrent deletes can't both remove
+	// one of the last two passkeys
+	var remaining, withPassword int
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM webauthn_credentials WHERE user_id = ?),
+			(SELECT COUNT(*) FROM users WHERE id = ? AND password_hash != '')
+	`, userID, userID).Scan(&remaining, &withPassword)
+	if err != nil {
 		done(err)
-		return err
+		return fmt.Errorf("failed to count login methods: %w", err)
+	}
+	if remaining == 0 && withPassword == 0 {
+		done(ErrLastLoginMethod)
+		return ErrLastLoginMethod
+	}
+
+	if err := tx.Commit(); err != nil {
+		done(err)
+		return fmt.Errorf("failed to commit credential deletion: %w", err)
 	}
 
-	logging.Info("Deleted WebAuthn credential ID %d for user %d", credentialID, userID)
+	logging.Info("Deleted WebAuthn credential for user %d", userID)
 	done(nil)
 	return nil
 }
@@ -3,6 +3,7 @@ package database
 import (
 	"bytes"
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -179,6 +180,11 @@ func TestDeleteWebAuthnCredentialIntegration(t *testing.T) {
 
 	ctx := context.Background()
 
+	// With a password, the last passkey can go too
+	if err := db.CreateUser(ctx, "testpassword"); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
 	// Save two credentials
 	cred1 := &webauthn.Credential{
 		ID:        []byte("cred-1"),
@@ -220,8 +226,61 @@ func TestDeleteWebAuthnCredentialIntegration(t *testing.T) {
 
 	// Delete non-existent credential (should error)
 	err = db.DeleteWebAuthnCredential(ctx, 1, 99999)
-	if err == nil {
-		t.Error("Expected error when deleting non-existent credential")
+	if !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("Expected ErrCredentialNotFound when deleting non-existent credential, got %v", err)
+	}
+
+	// Delete the other one by its WebAuthn credential ID
+	if err := db.DeleteWebAuthnCredentialByCredentialID(ctx, 2, credList[0].CredentialID); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("Expected ErrCredentialNotFound for another user's credential, got %v", err)
+	}
+	if err := db.DeleteWebAuthnCredentialByCredentialID(ctx, 1, credList[0].CredentialID); err != nil {
+		t.Fatalf("DeleteWebAuthnCredentialByCredentialID failed: %v", err)
+	}
+	if count := db.CountWebAuthnCredentials(ctx); count != 0 {
+		t.Errorf("Expected no credentials left, got %d", count)
+	}
+}
+
+func TestDeleteLastWebAuthnCredentialWithoutPasswordIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	if err := db.CreateUser(ctx, "testpassword"); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if _, err := db.db.ExecContext(ctx, "UPDATE users SET password_hash = ''"); err != nil {
+		t.Fatalf("Failed to clear password: %v", err)
+	}
+
+	for _, id := range []string{"cred-1", "cred-2"} {
+		cred := &webauthn.Credential{ID: []byte(id), PublicKey: []byte("key")}
+		if err := db.SaveWebAuthnCredential(ctx, 1, cred, id); err != nil {
+			t.Fatalf("SaveWebAuthnCredential failed: %v", err)
+		}
+	}
+
+	// Another passkey is left to log in with
+	if err := db.DeleteWebAuthnCredentialByCredentialID(ctx, 1, []byte("cred-1")); err != nil {
+		t.Fatalf("DeleteWebAuthnCredentialByCredentialID failed: %v", err)
+	}
+
+	// The last one stays, as there is no password
+	if err := db.DeleteWebAuthnCredentialByCredentialID(ctx, 1, []byte("cred-2")); !errors.Is(err, ErrLastLoginMethod) {
+		t.Errorf("Expected ErrLastLoginMethod, got %v", err)
+	}
+	if count := db.CountWebAuthnCredentials(ctx); count != 1 {
+		t.Errorf("Expected the last credential to be kept, got %d", count)
+	}
+
+	// An unknown passkey is still not found
+	if err := db.DeleteWebAuthnCredential(ctx, 1, 99999); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("Expected ErrCredentialNotFound, got %v", err)
 	}
 }
 
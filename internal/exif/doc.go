@@ -0,0 +1,26 @@
+// Package exif reads the EXIF fields the indexer records, and the orientation
+// thumbnails are corrected for, from image files.
+//
+// It is a small, dependency-free reader rather than a general EXIF library:
+// it reads only the tags the application uses, and only from the file
+// headers, without decoding any image data. It has no cgo dependencies, so
+// the indexer can use it without pulling in the image processing libraries.
+//
+// # Supported Formats
+//
+//   - JPEG: the EXIF block of the APP1 segment
+//   - HEIF: HEIC and AVIF files' Exif item, found through the meta box
+//   - TIFF-based files: TIFF, DNG and most camera raw formats (NEF, CR2,
+//     ARW, ...), whose headers are TIFF structures
+//
+// Other formats return ErrNoExif.
+//
+// # Usage
+//
+//	meta, err := exif.Read(path)
+//	if errors.Is(err, exif.ErrNoExif) {
+//	    // The file has no EXIF data, or isn't a format that is read
+//	}
+//	camera := meta.Camera() // e.g. "Canon EOS R5"
+//	taken := meta.DateTimeOriginal // zero if not recorded
+package exif
@@ -0,0 +1,346 @@
+package exif
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// TIFF tags read from the image's IFDs
+const (
+	tagMake        = 0x010F // IFD0
+	tagModel       = 0x0110 // IFD0
+	tagOrientation = 0x0112 // IFD0: how the image is turned or mirrored
+	tagExifIFD     = 0x8769 // IFD0: offset of the Exif IFD
+	tagLensModel   = 0xA434 // Exif IFD
+
+	tagDateTimeOriginal    = 0x9003 // Exif IFD: when the image was taken
+	tagDateTimeDigitized   = 0x9004 // Exif IFD: when it was stored, for scans
+	tagOffsetTimeOriginal  = 0x9011 // Exif IFD: UTC offset of DateTimeOriginal
+	tagOffsetTimeDigitized = 0x9012 // Exif IFD: UTC offset of DateTimeDigitized
+)
+
+// dateTimeLayout is the layout of EXIF dates, which have no time zone
+const dateTimeLayout = "2006:01:02 15:04:05"
+
+// TIFF field types of the entries read
+const (
+	typeASCII = 2 // NUL-terminated string
+	typeShort = 3 // 16-bit unsigned integer
+)
+
+const (
+	// maxIFDEntries bounds the entries read from one IFD; real files have a
+	// few dozen, so more means a corrupt offset
+	maxIFDEntries = 1024
+
+	// maxStringLength bounds the string values read; the fields read are
+	// names, far shorter than this
+	maxStringLength = 256
+)
+
+// ErrNoExif is returned for files without EXIF data, including files in
+// formats that aren't read.
+var ErrNoExif = errors.New("no EXIF data")
+
+// errMalformed is returned for EXIF data whose structure is broken
+var errMalformed = errors.New("malformed EXIF data")
+
+// Metadata is the EXIF data read from an image. Fields the file doesn't
+// record are empty.
+type Metadata struct {
+	Make      string // Camera manufacturer
+	Model     string // Camera model, which often repeats the manufacturer
+	LensModel string
+
+	// Orientation is how the stored image must be turned or mirrored to
+	// display upright, as the EXIF value 1-8; 0 if not recorded or invalid
+	Orientation int
+
+	// DateTimeOriginal is when the image was taken, or digitized if that
+	// isn't recorded. Dates without a recorded UTC offset are read in the
+	// local time zone, like the camera's clock. Zero if neither is recorded.
+	DateTimeOriginal time.Time
+}
+
+// Camera returns the name of the camera that took the image, such as
+// "Canon EOS R5": the model, preceded by the manufacturer unless the model
+// already names it. Returns "" if neither is recorded.
+func (m *Metadata) Camera() string {
+	if m.Model == "" || m.Make == "" {
+		return m.Make + m.Model
+	}
+
+	// Makes like "NIKON CORPORATION" are named by their first word in models
+	brand, _, _ := strings.Cut(m.Make, " ")
+	if strings.HasPrefix(strings.ToLower(m.Model), strings.ToLower(brand)) {
+		return m.Model
+	}
+	return m.Make + " " + m.Model
+}
+
+// Read reads the EXIF data of an image file. Only the file's headers are
+// read. Returns ErrNoExif if the file has none.
+func Read(path string) (*Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Decode(f)
+}
+
+// Decode reads the EXIF data of a JPEG, HEIF or TIFF-based image. Returns
+// ErrNoExif if it has none or is in another format.
+func Decode(r io.ReaderAt) (*Metadata, error) {
+	var magic [4]byte
+	if err := readFull(r, magic[:], 0); err != nil {
+		return nil, noExifAtEOF(err)
+	}
+
+	switch {
+	case magic[0] == 0xFF && magic[1] == 0xD8:
+		tiff, err := findJPEGExif(r)
+		if err != nil {
+			return nil, err
+		}
+		return decodeTIFF(tiff)
+	case string(magic[:]) == "II*\x00" || string(magic[:]) == "MM\x00*":
+		return decodeTIFF(r)
+	case isISOBMFF(r):
+		tiff, err := findHEIFExif(r)
+		if err != nil {
+			return nil, err
+		}
+		return decodeTIFF(tiff)
+	default:
+		return nil, ErrNoExif
+	}
+}
+
+// findJPEGExif scans a JPEG file's markers for the APP1 segment holding EXIF
+// data and returns its TIFF structure. Metadata segments come before the
+// image data, so the scan stops where that starts.
+func findJPEGExif(r io.ReaderAt) (io.ReaderAt, error) {
+	const exifHeader = "Exif\x00\x00"
+
+	offset := int64(2)
+	for {
+		var header [4]byte
+		if err := readFull(r, header[:], offset); err != nil {
+			return nil, noExifAtEOF(err)
+		}
+		if header[0] != 0xFF {
+			return nil, ErrNoExif
+		}
+		marker := header[1]
+		size := int64(binary.BigEndian.Uint16(header[2:])) - 2
+		if marker == 0xDA || marker == 0xD9 || size < 0 {
+			// Start of scan or end of image
+			return nil, ErrNoExif
+		}
+
+		data := offset + 4
+		if marker == 0xE1 && size >= int64(len(exifHeader)) {
+			id := make([]byte, len(exifHeader))
+			if err := readFull(r, id, data); err != nil {
+				return nil, noExifAtEOF(err)
+			}
+			if string(id) == exifHeader {
+				return io.NewSectionReader(r, data+int64(len(exifHeader)), size-int64(len(exifHeader))), nil
+			}
+		}
+		offset = data + size
+	}
+}
+
+// ifdEntry is an entry of a TIFF IFD. Values of four bytes or fewer are
+// stored in value; larger ones are at the offset stored there.
+type ifdEntry struct {
+	fieldType uint16
+	count     uint32
+	value     [4]byte
+}
+
+// tiffReader reads the IFDs of a TIFF structure
+type tiffReader struct {
+	r     io.ReaderAt
+	order binary.ByteOrder
+}
+
+// decodeTIFF reads the EXIF fields from a TIFF structure starting at the
+// beginning of r. Offsets within it are relative to that start.
+func decodeTIFF(r io.ReaderAt) (*Metadata, error) {
+	var header [8]byte
+	if err := readFull(r, header[:], 0); err != nil {
+		return nil, noExifAtEOF(err)
+	}
+
+	t := &tiffReader{r: r}
+	switch string(header[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, errMalformed
+	}
+	if t.order.Uint16(header[2:]) != 0x2A {
+		return nil, errMalformed
+	}
+
+	ifd0, err := t.readIFD(int64(t.order.Uint32(header[4:])))
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &Metadata{
+		Make:  t.stringValue(ifd0[tagMake]),
+		Model: t.stringValue(ifd0[tagModel]),
+	}
+	if orientation := t.shortValue(ifd0[tagOrientation]); orientation >= 1 && orientation <= 8 {
+		meta.Orientation = orientation
+	}
+
+	// Lens details and dates are in the Exif IFD; without one the camera
+	// details stand
+	if entry, ok := ifd0[tagExifIFD]; ok {
+		if exifIFD, err := t.readIFD(int64(t.order.Uint32(entry.value[:]))); err == nil {
+			meta.LensModel = t.stringValue(exifIFD[tagLensModel])
+			meta.DateTimeOriginal = t.dateValue(exifIFD[tagDateTimeOriginal], exifIFD[tagOffsetTimeOriginal])
+			if meta.DateTimeOriginal.IsZero() {
+				meta.DateTimeOriginal = t.dateValue(exifIFD[tagDateTimeDigitized], exifIFD[tagOffsetTimeDigitized])
+			}
+		}
+	}
+	return meta, nil
+}
+
+// readIFD reads the entries of the IFD at offset, by tag
+func (t *tiffReader) readIFD(offset int64) (map[uint16]ifdEntry, error) {
+	if offset < 8 {
+		return nil, errMalformed
+	}
+
+	var count [2]byte
+	if err := readFull(t.r, count[:], offset); err != nil {
+		return nil, malformedAtEOF(err)
+	}
+	n := int(t.order.Uint16(count[:]))
+	if n > maxIFDEntries {
+		return nil, fmt.Errorf("%w: IFD with %d entries", errMalformed, n)
+	}
+
+	raw := make([]byte, n*12)
+	if err := readFull(t.r, raw, offset+2); err != nil {
+		return nil, malformedAtEOF(err)
+	}
+
+	entries := make(map[uint16]ifdEntry, n)
+	for i := range n {
+		b := raw[i*12 : (i+1)*12]
+		tag := t.order.Uint16(b)
+		if _, seen := entries[tag]; seen {
+			continue
+		}
+		entry := ifdEntry{
+			fieldType: t.order.Uint16(b[2:]),
+			count:     t.order.Uint32(b[4:]),
+		}
+		copy(entry.value[:], b[8:])
+		entries[tag] = entry
+	}
+	return entries, nil
+}
+
+// stringValue returns the value of an ASCII entry with surrounding spaces
+// removed, or "" if it isn't one or can't be read
+func (t *tiffReader) stringValue(entry ifdEntry) string {
+	if entry.fieldType != typeASCII || entry.count == 0 {
+		return ""
+	}
+
+	n := min(int(entry.count), maxStringLength)
+	var b []byte
+	if entry.count <= 4 {
+		b = entry.value[:n]
+	} else {
+		b = make([]byte, n)
+		if err := readFull(t.r, b, int64(t.order.Uint32(entry.value[:]))); err != nil {
+			return ""
+		}
+	}
+
+	s, _, _ := strings.Cut(string(b), "\x00")
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, "")
+	}
+	return strings.TrimSpace(s)
+}
+
+// shortValue returns the first value of a SHORT entry, or 0 if it isn't one
+func (t *tiffReader) shortValue(entry ifdEntry) int {
+	if entry.fieldType != typeShort || entry.count == 0 {
+		return 0
+	}
+	// Up to two values are stored in the entry itself, the first one first
+	return int(t.order.Uint16(entry.value[:]))
+}
+
+// dateValue returns the time of an EXIF date entry with its optional UTC
+// offset entry, or the zero time if it isn't set or can't be parsed. Cameras
+// without a set clock write blank or zeroed dates.
+func (t *tiffReader) dateValue(date, offset ifdEntry) time.Time {
+	value := t.stringValue(date)
+	if len(value) < len(dateTimeLayout) {
+		return time.Time{}
+	}
+	value = value[:len(dateTimeLayout)]
+
+	if tz := t.stringValue(offset); tz != "" {
+		if parsed, err := time.Parse(dateTimeLayout+"-07:00", value+tz); err == nil {
+			return parsed
+		}
+	}
+	parsed, err := time.ParseInLocation(dateTimeLayout, value, time.Local)
+	if err != nil || parsed.Year() < 1800 {
+		return time.Time{}
+	}
+	return parsed
+}
+
+// readFull reads len(b) bytes at offset; a short read is io.ErrUnexpectedEOF
+func readFull(r io.ReaderAt, b []byte, offset int64) error {
+	n, err := r.ReadAt(b, offset)
+	if n == len(b) {
+		return nil
+	}
+	if err == nil || errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// noExifAtEOF turns a read past the end of the file, which stops a scan for
+// EXIF data, into ErrNoExif
+func noExifAtEOF(err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrNoExif
+	}
+	return err
+}
+
+// malformedAtEOF turns a read past the end of the file, which an offset
+// within EXIF data should never lead to, into errMalformed
+func malformedAtEOF(err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return errMalformed
+	}
+	return err
+}
@@ -0,0 +1,385 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testEntry is an ASCII entry written by buildTIFF
+type testEntry struct {
+	tag   uint16
+	value string
+}
+
+// buildTIFF returns a TIFF structure with ASCII entries in IFD0 and, if any
+// are given, in an Exif IFD that IFD0 points to
+func buildTIFF(order binary.ByteOrder, ifd0, exifIFD []testEntry) []byte {
+	ifd0Count := len(ifd0)
+	if len(exifIFD) > 0 {
+		ifd0Count++
+	}
+	exifOffset := 8 + 2 + ifd0Count*12 + 4
+	dataOffset := exifOffset
+	if len(exifIFD) > 0 {
+		dataOffset += 2 + len(exifIFD)*12 + 4
+	}
+
+	out := make([]byte, dataOffset)
+	if order == binary.LittleEndian {
+		copy(out, "II")
+	} else {
+		copy(out, "MM")
+	}
+	order.PutUint16(out[2:], 0x2A)
+	order.PutUint32(out[4:], 8)
+
+	var data []byte
+	putIFD := func(at int, entries []testEntry, exifPointer bool) {
+		count := len(entries)
+		if exifPointer {
+			count++
+		}
+		order.PutUint16(out[at:], uint16(count))
+		p := at + 2
+		for _, e := range entries {
+			value := append([]byte(e.value), 0)
+			order.PutUint16(out[p:], e.tag)
+			order.PutUint16(out[p+2:], typeASCII)
+			order.PutUint32(out[p+4:], uint32(len(value)))
+			if len(value) <= 4 {
+				copy(out[p+8:], value)
+			} else {
+				order.PutUint32(out[p+8:], uint32(dataOffset+len(data)))
+				data = append(data, value...)
+			}
+			p += 12
+		}
+		if exifPointer {
+			order.PutUint16(out[p:], tagExifIFD)
+			order.PutUint16(out[p+2:], 4) // LONG
+			order.PutUint32(out[p+4:], 1)
+			order.PutUint32(out[p+8:], uint32(exifOffset))
+		}
+	}
+
+	putIFD(8, ifd0, len(exifIFD) > 0)
+	if len(exifIFD) > 0 {
+		putIFD(exifOffset, exifIFD, false)
+	}
+	return append(out, data...)
+}
+
+// buildJPEG returns the start of a JPEG file with a JFIF segment and, if tiff
+// isn't nil, an EXIF segment holding it
+func buildJPEG(tiff []byte) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0xFF, 0xD8})
+
+	jfif := []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00")
+	b.Write([]byte{0xFF, 0xE0})
+	_ = binary.Write(&b, binary.BigEndian, uint16(len(jfif)+2))
+	b.Write(jfif)
+
+	if tiff != nil {
+		segment := append([]byte("Exif\x00\x00"), tiff...)
+		b.Write([]byte{0xFF, 0xE1})
+		_ = binary.Write(&b, binary.BigEndian, uint16(len(segment)+2))
+		b.Write(segment)
+	}
+
+	b.Write([]byte{0xFF, 0xDA, 0x00, 0x02})
+	return b.Bytes()
+}
+
+func TestDecodeJPEG(t *testing.T) {
+	tiff := buildTIFF(binary.LittleEndian,
+		[]testEntry{{tagMake, "Canon"}, {tagModel, "Canon EOS R5"}},
+		[]testEntry{{tagLensModel, "RF24-105mm F4 L IS USM"}})
+
+	meta, err := Decode(bytes.NewReader(buildJPEG(tiff)))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := Metadata{Make: "Canon", Model: "Canon EOS R5", LensModel: "RF24-105mm F4 L IS USM"}
+	if *meta != want {
+		t.Errorf("Decode() = %+v, want %+v", *meta, want)
+	}
+}
+
+// buildOrientationTIFF returns a TIFF structure whose IFD0 holds only an
+// orientation entry
+func buildOrientationTIFF(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 0x2A)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], tagOrientation)
+	order.PutUint16(tiff[12:], typeShort)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+	return tiff
+}
+
+func TestDecodeOrientation(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		meta, err := Decode(bytes.NewReader(buildJPEG(buildOrientationTIFF(order, 6))))
+		if err != nil || meta.Orientation != 6 {
+			t.Errorf("%v: Decode() = %+v, %v; want orientation 6", order, meta, err)
+		}
+	}
+
+	// Values out of range and entries of another type aren't orientations
+	outOfRange := buildOrientationTIFF(binary.BigEndian, 9)
+	wrongType := buildOrientationTIFF(binary.BigEndian, 6)
+	binary.BigEndian.PutUint16(wrongType[12:], typeASCII)
+	for name, tiff := range map[string][]byte{"out of range": outOfRange, "wrong type": wrongType} {
+		meta, err := Decode(bytes.NewReader(buildJPEG(tiff)))
+		if err != nil || meta.Orientation != 0 {
+			t.Errorf("%s: Decode() = %+v, %v; want no orientation", name, meta, err)
+		}
+	}
+}
+
+func TestDecodeTIFF(t *testing.T) {
+	tiff := buildTIFF(binary.BigEndian,
+		[]testEntry{{tagMake, "NIKON CORPORATION"}, {tagModel, "NIKON D750 "}},
+		[]testEntry{{tagLensModel, "24.0-120.0 mm f/4.0"}})
+
+	meta, err := Decode(bytes.NewReader(tiff))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := Metadata{Make: "NIKON CORPORATION", Model: "NIKON D750", LensModel: "24.0-120.0 mm f/4.0"}
+	if *meta != want {
+		t.Errorf("Decode() = %+v, want %+v", *meta, want)
+	}
+}
+
+// buildBox returns an ISOBMFF box; full boxes start their payload with the
+// version and flags
+func buildBox(boxType string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	out := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(out, boxType...), body...)
+}
+
+// buildHEIF returns a HEIF file whose Exif item, a version 3 item info
+// entry, holds tiff behind an "Exif\0\0" prefix. The item is placed after
+// the meta box, like the media data.
+func buildHEIF(tiff []byte) []byte {
+	ftyp := buildBox("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+	iinf := buildBox("iinf", []byte{0, 0, 0, 0, 0, 2},
+		buildBox("infe", []byte{2, 0, 0, 0, 0, 1, 0, 0}, []byte("hvc1")),
+		buildBox("infe", []byte{3, 0, 0, 0, 0, 0, 0, 7, 0, 0}, []byte("Exif")))
+	item := append([]byte{0, 0, 0, 6}, append([]byte("Exif\x00\x00"), tiff...)...)
+
+	// Version 0 iloc with 4-byte offsets and lengths and no base offset;
+	// the offset is filled in once the meta box size is known
+	iloc := func(offset int) []byte {
+		b := []byte{0, 0, 0, 0, 0x44, 0x00, 0, 2}
+		b = append(b, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0) // hvc1 item
+		b = append(b, 0, 7, 0, 0, 0, 1)
+		b = binary.BigEndian.AppendUint32(b, uint32(offset))
+		return binary.BigEndian.AppendUint32(b, uint32(len(item)))
+	}
+	meta := func(offset int) []byte {
+		return buildBox("meta", []byte{0, 0, 0, 0}, buildBox("hdlr", make([]byte, 24)), iinf, buildBox("iloc", iloc(offset)))
+	}
+
+	offset := len(ftyp) + len(meta(0)) + 8
+	return bytes.Join([][]byte{ftyp, meta(offset), buildBox("mdat", item)}, nil)
+}
+
+func TestDecodeHEIF(t *testing.T) {
+	tiff := buildTIFF(binary.BigEndian,
+		[]testEntry{{tagMake, "Apple"}, {tagModel, "iPhone 12"}},
+		[]testEntry{{tagDateTimeOriginal, "2021:06:05 14:30:00"}, {tagOffsetTimeOriginal, "+02:00"}})
+
+	meta, err := Decode(bytes.NewReader(buildHEIF(tiff)))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got := meta.Camera(); got != "Apple iPhone 12" {
+		t.Errorf("Camera() = %q, want %q", got, "Apple iPhone 12")
+	}
+	want := time.Date(2021, 6, 5, 12, 30, 0, 0, time.UTC)
+	if !meta.DateTimeOriginal.Equal(want) {
+		t.Errorf("DateTimeOriginal = %v, want %v", meta.DateTimeOriginal, want)
+	}
+
+	// A HEIF file without an Exif item, and other ISOBMFF files such as
+	// videos, have no EXIF data
+	noExif := bytes.Join([][]byte{buildBox("ftyp", []byte("isom\x00\x00\x00\x00")), buildBox("moov"), buildBox("mdat")}, nil)
+	if _, err := Decode(bytes.NewReader(noExif)); !errors.Is(err, ErrNoExif) {
+		t.Errorf("Decode() of an MP4 error = %v, want ErrNoExif", err)
+	}
+}
+
+func TestDecodeDates(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []testEntry
+		want    time.Time
+	}{
+		{"original with offset", []testEntry{{tagDateTimeOriginal, "2019:12:31 23:59:59"}, {tagOffsetTimeOriginal, "-05:00"}},
+			time.Date(2020, 1, 1, 4, 59, 59, 0, time.UTC)},
+		{"original in local time", []testEntry{{tagDateTimeOriginal, "2019:12:31 23:59:59"}},
+			time.Date(2019, 12, 31, 23, 59, 59, 0, time.Local)},
+		{"digitized only", []testEntry{{tagDateTimeDigitized, "2005:03:04 10:00:00"}, {tagOffsetTimeDigitized, "+09:00"}},
+			time.Date(2005, 3, 4, 1, 0, 0, 0, time.UTC)},
+		{"original preferred", []testEntry{{tagDateTimeOriginal, "2010:01:02 03:04:05"}, {tagDateTimeDigitized, "2011:01:01 00:00:00"}},
+			time.Date(2010, 1, 2, 3, 4, 5, 0, time.Local)},
+		{"unset clock", []testEntry{{tagDateTimeOriginal, "0000:00:00 00:00:00"}}, time.Time{}},
+		{"blank", []testEntry{{tagDateTimeOriginal, "    :  :     :  :  "}}, time.Time{}},
+		{"truncated", []testEntry{{tagDateTimeOriginal, "2019:12:31"}}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tiff := buildTIFF(binary.LittleEndian, []testEntry{{tagMake, "Canon"}}, tt.entries)
+			meta, err := Decode(bytes.NewReader(buildJPEG(tiff)))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !meta.DateTimeOriginal.Equal(tt.want) {
+				t.Errorf("DateTimeOriginal = %v, want %v", meta.DateTimeOriginal, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeInlineValues(t *testing.T) {
+	// Values of up to four bytes, NUL included, are stored in the entry itself
+	tiff := buildTIFF(binary.LittleEndian, []testEntry{{tagMake, "DJI"}, {tagModel, "FC7"}}, nil)
+
+	meta, err := Decode(bytes.NewReader(tiff))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if meta.Make != "DJI" || meta.Model != "FC7" {
+		t.Errorf("Decode() = %+v, want make DJI and model FC7", *meta)
+	}
+	if meta.LensModel != "" {
+		t.Errorf("LensModel = %q without an Exif IFD, want empty", meta.LensModel)
+	}
+}
+
+func TestDecodeNoExif(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")},
+		{"jpeg without exif", buildJPEG(nil)},
+		{"truncated jpeg", buildJPEG(nil)[:8]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(bytes.NewReader(tt.data)); !errors.Is(err, ErrNoExif) {
+				t.Errorf("Decode() error = %v, want ErrNoExif", err)
+			}
+		})
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	tiff := buildTIFF(binary.LittleEndian, []testEntry{{tagMake, "Canon"}}, nil)
+
+	badOffset := bytes.Clone(tiff)
+	binary.LittleEndian.PutUint32(badOffset[4:], 1<<20)
+
+	tooMany := bytes.Clone(tiff)
+	binary.LittleEndian.PutUint16(tooMany[8:], maxIFDEntries+1)
+
+	badMagic := bytes.Clone(tiff)
+	badMagic[2] = 0x2B
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"ifd offset past end", buildJPEG(badOffset)},
+		{"too many entries", tooMany},
+		{"bad magic", buildJPEG(badMagic)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(tt.data))
+			if err == nil || errors.Is(err, ErrNoExif) {
+				t.Errorf("Decode() error = %v, want a malformed data error", err)
+			}
+		})
+	}
+}
+
+func TestDecodeStringBounds(t *testing.T) {
+	long := string(bytes.Repeat([]byte("a"), maxStringLength+50))
+	tiff := buildTIFF(binary.LittleEndian, []testEntry{{tagMake, long}, {tagModel, "bad\xffutf8"}}, nil)
+
+	meta, err := Decode(bytes.NewReader(tiff))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(meta.Make) != maxStringLength {
+		t.Errorf("len(Make) = %d, want %d", len(meta.Make), maxStringLength)
+	}
+	if meta.Model != "badutf8" {
+		t.Errorf("Model = %q, want invalid UTF-8 dropped", meta.Model)
+	}
+}
+
+func TestRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	tiff := buildTIFF(binary.BigEndian, []testEntry{{tagMake, "FUJIFILM"}, {tagModel, "X-T3"}}, nil)
+	if err := os.WriteFile(path, buildJPEG(tiff), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got := meta.Camera(); got != "FUJIFILM X-T3" {
+		t.Errorf("Camera() = %q, want %q", got, "FUJIFILM X-T3")
+	}
+
+	if _, err := Read(filepath.Join(t.TempDir(), "missing.jpg")); !os.IsNotExist(err) {
+		t.Errorf("Read() of a missing file error = %v, want not exist", err)
+	}
+}
+
+func TestCamera(t *testing.T) {
+	tests := []struct {
+		make, model string
+		want        string
+	}{
+		{"Canon", "Canon EOS R5", "Canon EOS R5"},
+		{"NIKON CORPORATION", "NIKON D750", "NIKON D750"},
+		{"OLYMPUS IMAGING CORP.", "E-M5", "OLYMPUS IMAGING CORP. E-M5"},
+		{"Apple", "iPhone 12", "Apple iPhone 12"},
+		{"samsung", "SAMSUNG SM-G991B", "SAMSUNG SM-G991B"},
+		{"", "X100V", "X100V"},
+		{"GoPro", "", "GoPro"},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		meta := Metadata{Make: tt.make, Model: tt.model}
+		if got := meta.Camera(); got != tt.want {
+			t.Errorf("Camera() with make %q and model %q = %q, want %q", tt.make, tt.model, got, tt.want)
+		}
+	}
+}
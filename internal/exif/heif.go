@@ -0,0 +1,290 @@
+package exif
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// maxTopLevelBoxes bounds the boxes scanned for the meta box, which
+	// follows the ftyp box in practice but may come after the media data
+	maxTopLevelBoxes = 32
+
+	// maxMetaBoxSize bounds the meta box read into memory; it holds item
+	// tables and properties, tens of kilobytes even for tiled images
+	maxMetaBoxSize = 4 << 20
+)
+
+// box is an ISOBMFF box: its type and where its payload is
+type box struct {
+	boxType string
+	offset  int64 // of the payload
+	size    int64 // of the payload
+}
+
+// isISOBMFF reports whether r starts with an ftyp box, as HEIC, HEIF and
+// AVIF files do
+func isISOBMFF(r io.ReaderAt) bool {
+	var header [8]byte
+	if err := readFull(r, header[:], 0); err != nil {
+		return false
+	}
+	return string(header[4:]) == "ftyp"
+}
+
+// findHEIFExif finds the Exif item of a HEIF file from the item tables of
+// its meta box and returns its TIFF structure. Only the meta box and the
+// Exif item are read.
+func findHEIFExif(r io.ReaderAt) (io.ReaderAt, error) {
+	meta, err := findTopLevelBox(r, "meta")
+	if err != nil {
+		return nil, err
+	}
+	if meta.size > maxMetaBoxSize || meta.size < 4 {
+		return nil, fmt.Errorf("%w: meta box of %d bytes", errMalformed, meta.size)
+	}
+
+	payload := make([]byte, meta.size)
+	if err := readFull(r, payload, meta.offset); err != nil {
+		return nil, malformedAtEOF(err)
+	}
+	// meta is a full box; its children follow the version and flags
+	children := payload[4:]
+
+	iinf, ok := childBox(children, "iinf")
+	if !ok {
+		return nil, ErrNoExif
+	}
+	itemID, ok := exifItemID(iinf)
+	if !ok {
+		return nil, ErrNoExif
+	}
+	iloc, ok := childBox(children, "iloc")
+	if !ok {
+		return nil, errMalformed
+	}
+	offset, length, err := itemLocation(iloc, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The item starts with the offset of the TIFF header past that field,
+	// which skips an "Exif\0\0" prefix
+	var skip [4]byte
+	if length < 4 {
+		return nil, errMalformed
+	}
+	if err := readFull(r, skip[:], offset); err != nil {
+		return nil, malformedAtEOF(err)
+	}
+	tiffOffset := 4 + int64(binary.BigEndian.Uint32(skip[:]))
+	if tiffOffset >= length {
+		return nil, errMalformed
+	}
+	return io.NewSectionReader(r, offset+tiffOffset, length-tiffOffset), nil
+}
+
+// findTopLevelBox scans the top-level boxes of a file for one of a type
+func findTopLevelBox(r io.ReaderAt, boxType string) (box, error) {
+	offset := int64(0)
+	for range maxTopLevelBoxes {
+		var header [16]byte
+		if err := readFull(r, header[:8], offset); err != nil {
+			return box{}, noExifAtEOF(err)
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			// The box extends to the end of the file, so it's the last
+			if string(header[4:8]) != boxType {
+				return box{}, ErrNoExif
+			}
+			return box{}, fmt.Errorf("%w: unbounded %s box", errMalformed, boxType)
+		case 1:
+			if err := readFull(r, header[8:], offset+8); err != nil {
+				return box{}, noExifAtEOF(err)
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:]))
+			headerSize = 16
+		}
+		if size < headerSize {
+			return box{}, errMalformed
+		}
+
+		if string(header[4:8]) == boxType {
+			return box{boxType: boxType, offset: offset + headerSize, size: size - headerSize}, nil
+		}
+		offset += size
+	}
+	return box{}, ErrNoExif
+}
+
+// childBox returns the payload of the first box of a type among the boxes in
+// data
+func childBox(data []byte, boxType string) ([]byte, bool) {
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data))
+		headerSize := uint64(8)
+		if size == 1 {
+			if len(data) < 16 {
+				return nil, false
+			}
+			size = binary.BigEndian.Uint64(data[8:])
+			headerSize = 16
+		} else if size == 0 {
+			size = uint64(len(data))
+		}
+		if size < headerSize || size > uint64(len(data)) {
+			return nil, false
+		}
+		if string(data[4:8]) == boxType {
+			return data[headerSize:size], true
+		}
+		data = data[size:]
+	}
+	return nil, false
+}
+
+// exifItemID returns the ID of the Exif item listed in an iinf box payload
+func exifItemID(iinf []byte) (uint32, bool) {
+	if len(iinf) < 4 {
+		return 0, false
+	}
+	entries := iinf[4:]
+	if iinf[0] == 0 {
+		if len(entries) < 2 {
+			return 0, false
+		}
+		entries = entries[2:]
+	} else {
+		if len(entries) < 4 {
+			return 0, false
+		}
+		entries = entries[4:]
+	}
+
+	for len(entries) >= 8 {
+		size := binary.BigEndian.Uint32(entries)
+		if size < 8 || uint64(size) > uint64(len(entries)) {
+			return 0, false
+		}
+		boxType, infe := string(entries[4:8]), entries[8:size]
+		entries = entries[size:]
+		if boxType != "infe" || len(infe) < 4 {
+			continue
+		}
+
+		// Version 2 and 3 entries name the item type; earlier ones can't
+		// describe Exif items
+		var id uint32
+		var itemType []byte
+		switch infe[0] {
+		case 2:
+			if len(infe) < 12 {
+				continue
+			}
+			id = uint32(binary.BigEndian.Uint16(infe[4:]))
+			itemType = infe[8:12]
+		case 3:
+			if len(infe) < 14 {
+				continue
+			}
+			id = binary.BigEndian.Uint32(infe[4:])
+			itemType = infe[10:14]
+		default:
+			continue
+		}
+		if string(itemType) == "Exif" {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// itemLocation returns the file offset and length of an item from an iloc
+// box payload. Only items stored as one extent in the file itself are
+// supported, as Exif items are.
+func itemLocation(iloc []byte, itemID uint32) (offset, length int64, err error) {
+	if len(iloc) < 6 {
+		return 0, 0, errMalformed
+	}
+	version := iloc[0]
+	offsetSize := int(iloc[4] >> 4)
+	lengthSize := int(iloc[4] & 0x0F)
+	baseOffsetSize := int(iloc[5] >> 4)
+	indexSize := 0
+	if version == 1 || version == 2 {
+		indexSize = int(iloc[5] & 0x0F)
+	}
+
+	p := &byteReader{data: iloc[6:]}
+	var count uint64
+	if version < 2 {
+		count = p.uint(2)
+	} else {
+		count = p.uint(4)
+	}
+
+	for range count {
+		var id uint64
+		if version < 2 {
+			id = p.uint(2)
+		} else {
+			id = p.uint(4)
+		}
+		constructionMethod := uint64(0)
+		if version == 1 || version == 2 {
+			constructionMethod = p.uint(2) & 0x0F
+		}
+		p.uint(2) // data reference index
+		baseOffset := p.uint(baseOffsetSize)
+		extents := p.uint(2)
+
+		for i := range extents {
+			p.uint(indexSize)
+			extentOffset := p.uint(offsetSize)
+			extentLength := p.uint(lengthSize)
+			if p.err || uint32(id) != itemID || i > 0 {
+				continue
+			}
+			if constructionMethod != 0 || extents != 1 || extentLength == 0 {
+				return 0, 0, ErrNoExif
+			}
+			offset = int64(baseOffset + extentOffset)
+			length = int64(extentLength)
+			if offset < 0 || length < 0 {
+				return 0, 0, errMalformed
+			}
+			return offset, length, nil
+		}
+		if p.err {
+			return 0, 0, errMalformed
+		}
+	}
+	return 0, 0, errMalformed
+}
+
+// byteReader reads big-endian integers of varying sizes from a buffer,
+// recording reads past its end
+type byteReader struct {
+	data []byte
+	err  bool
+}
+
+// uint reads an integer of size bytes; a size of 0 reads nothing and
+// returns 0
+func (b *byteReader) uint(size int) uint64 {
+	if size > len(b.data) || size > 8 {
+		b.err = true
+		b.data = nil
+		return 0
+	}
+	var v uint64
+	for _, c := range b.data[:size] {
+		v = v<<8 | uint64(c)
+	}
+	b.data = b.data[size:]
+	return v
+}
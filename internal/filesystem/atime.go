@@ -0,0 +1,17 @@
+package filesystem
+
+import (
+	"os"
+	"time"
+)
+
+// AccessTime returns when a file was last read, from its stat data. Most
+// filesystems are mounted with noatime or relatime, so it's only reliable
+// for files whose access time is set explicitly with os.Chtimes. Falls back
+// to the modification time where the platform doesn't report one.
+func AccessTime(info os.FileInfo) time.Time {
+	if atime, ok := accessTime(info); ok {
+		return atime
+	}
+	return info.ModTime()
+}
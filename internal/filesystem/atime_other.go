@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd
+
+package filesystem
+
+import (
+	"os"
+	"time"
+)
+
+func accessTime(os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
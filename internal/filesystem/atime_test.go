@@ -0,0 +1,30 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAccessTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thumb.jpg")
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	accessed := time.Now().Add(-time.Hour).Truncate(time.Second)
+	modified := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, accessed, modified); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	got := AccessTime(info)
+	if !got.Equal(accessed) && !got.Equal(modified) {
+		t.Errorf("AccessTime = %v, want %v (or the modification time where unsupported)", got, accessed)
+	}
+}
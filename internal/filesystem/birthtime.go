@@ -0,0 +1,11 @@
+package filesystem
+
+import "time"
+
+// BirthTime returns when a file was created, if the platform and filesystem
+// record it: statx on Linux (4.11+, and only on filesystems that store it,
+// such as ext4, XFS and Btrfs), the stat birth time on macOS and FreeBSD.
+// Returns false when it is unavailable.
+func BirthTime(path string) (time.Time, bool) {
+	return birthTime(path)
+}
@@ -0,0 +1,21 @@
+//go:build darwin || freebsd
+
+package filesystem
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func birthTime(path string) (time.Time, bool) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(stat.Birthtimespec.Unix()), true
+}
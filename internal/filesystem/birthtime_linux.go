@@ -0,0 +1,21 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func birthTime(path string) (time.Time, bool) {
+	var stx unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW, unix.STATX_BTIME, &stx); err != nil {
+		return time.Time{}, false
+	}
+	// Filesystems without a birth time (and NFS) leave STATX_BTIME unset
+	if stx.Mask&unix.STATX_BTIME == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec)), true
+}
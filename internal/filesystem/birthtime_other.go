@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd
+
+package filesystem
+
+import "time"
+
+func birthTime(string) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,36 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBirthTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	before := time.Now().Add(-time.Second)
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// Editing the file later must not move its birth time
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+
+	birth, ok := BirthTime(path)
+	if !ok {
+		t.Skip("filesystem does not record birth times")
+	}
+	if birth.Before(before) || birth.After(time.Now().Add(time.Second)) {
+		t.Errorf("BirthTime = %v, expected around the file's creation at %v", birth, before)
+	}
+}
+
+func TestBirthTimeMissingFile(t *testing.T) {
+	if _, ok := BirthTime(filepath.Join(t.TempDir(), "missing.jpg")); ok {
+		t.Error("Expected no birth time for a missing file")
+	}
+}
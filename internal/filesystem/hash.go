@@ -0,0 +1,167 @@
+package filesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+const (
+	// fullHashMaxSize is the largest file HashContent hashes in full. Larger
+	// files are identified by their size plus samples from the start, middle
+	// and end.
+	fullHashMaxSize = 8 << 20
+
+	// contentSampleSize is the size of each sample read from large files
+	contentSampleSize = 1 << 20
+)
+
+// HashMode selects how much of a file is read to hash its content.
+type HashMode string
+
+const (
+	// HashSampled hashes files up to 8 MiB in full, and larger ones by their
+	// size and samples from the start, middle and end
+	HashSampled HashMode = "sampled"
+
+	// HashFull hashes every byte of every file
+	HashFull HashMode = "full"
+
+	// HashPartial hashes the size and samples from the start and end only,
+	// whatever the size of the file
+	HashPartial HashMode = "partial"
+)
+
+// HashAlgorithm selects the hash function applied to what is read.
+type HashAlgorithm string
+
+const (
+	// HashSHA256 is collision resistant, so only identical content can
+	// share a hash
+	HashSHA256 HashAlgorithm = "sha256"
+
+	// HashXXHash is several times faster than SHA-256 but has a 64-bit
+	// result, so unrelated content can very occasionally collide
+	HashXXHash HashAlgorithm = "xxhash"
+)
+
+// HashOptions configures HashContentWith.
+type HashOptions struct {
+	Mode      HashMode
+	Algorithm HashAlgorithm
+}
+
+// DefaultHashOptions returns the options HashContent uses.
+func DefaultHashOptions() HashOptions {
+	return HashOptions{Mode: HashSampled, Algorithm: HashSHA256}
+}
+
+// String returns the options as "mode/algorithm". Hashes made with options
+// that stringify differently can't be compared.
+func (o HashOptions) String() string {
+	return string(o.Mode) + "/" + string(o.Algorithm)
+}
+
+// ReadsWhole reports whether a file of size bytes is hashed in full, so its
+// hash is the one HashFull gives with the same algorithm.
+func (o HashOptions) ReadsWhole(size int64) bool {
+	switch o.Mode {
+	case HashFull:
+		return true
+	case HashPartial:
+		return size <= 2*contentSampleSize
+	default:
+		return size <= fullHashMaxSize
+	}
+}
+
+// ParseHashMode parses an INDEX_HASH_MODE value. An empty value returns
+// HashSampled.
+func ParseHashMode(value string) (HashMode, error) {
+	switch mode := HashMode(strings.TrimSpace(strings.ToLower(value))); mode {
+	case "":
+		return HashSampled, nil
+	case HashSampled, HashFull, HashPartial:
+		return mode, nil
+	default:
+		return HashSampled, fmt.Errorf("unknown hash mode %q (valid modes: sampled, full, partial)", value)
+	}
+}
+
+// ParseHashAlgorithm parses an INDEX_HASH_ALGORITHM value. An empty value
+// returns HashSHA256.
+func ParseHashAlgorithm(value string) (HashAlgorithm, error) {
+	switch algorithm := HashAlgorithm(strings.TrimSpace(strings.ToLower(value))); algorithm {
+	case "":
+		return HashSHA256, nil
+	case HashSHA256, HashXXHash:
+		return algorithm, nil
+	default:
+		return HashSHA256, fmt.Errorf("unknown hash algorithm %q (valid algorithms: sha256, xxhash)", value)
+	}
+}
+
+// HashContent returns a key identifying the content of a file. Small files
+// are hashed in full; for large files (mostly videos) reading every byte
+// would dominate the time taken, so the size and three samples are hashed
+// instead. Files with the same key are identical but for, at most, bytes
+// between the samples.
+func HashContent(filePath string) (string, error) {
+	return HashContentWith(filePath, DefaultHashOptions())
+}
+
+// HashContentWith returns a key identifying the content of a file, reading
+// and hashing it as opts say. Keys are only comparable with keys made with
+// the same options.
+func HashContentWith(filePath string, opts HashOptions) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file for hashing: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file for hashing: %w", err)
+	}
+	size := info.Size()
+
+	var h hash.Hash
+	if opts.Algorithm == HashXXHash {
+		h = xxhash.New()
+	} else {
+		h = sha256.New()
+	}
+	h.Write([]byte(strconv.FormatInt(size, 10) + ":"))
+
+	var offsets []int64
+	switch {
+	case opts.ReadsWhole(size):
+		if _, err := io.Copy(h, f); err != nil {
+			return "", fmt.Errorf("failed to hash file: %w", err)
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	case opts.Mode == HashPartial:
+		offsets = []int64{0, size - contentSampleSize}
+	default:
+		offsets = []int64{0, size/2 - contentSampleSize/2, size - contentSampleSize}
+	}
+
+	buf := make([]byte, contentSampleSize)
+	for _, offset := range offsets {
+		n, err := f.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("failed to read file sample: %w", err)
+		}
+		h.Write(buf[:n])
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
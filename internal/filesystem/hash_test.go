@@ -0,0 +1,142 @@
+package filesystem
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHashContent(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	small := []byte("identical content")
+	a := write("a.jpg", small)
+	b := write("b.jpg", small)
+	c := write("c.jpg", []byte("different content"))
+
+	hashA, err := HashContent(a)
+	if err != nil {
+		t.Fatalf("HashContent failed: %v", err)
+	}
+	hashB, _ := HashContent(b)
+	hashC, _ := HashContent(c)
+
+	if hashA != hashB {
+		t.Error("Expected identical files to have the same hash")
+	}
+	if hashA == hashC {
+		t.Error("Expected different files to have different hashes")
+	}
+
+	// Large files are sampled; a change inside a sample changes the hash
+	large := bytes.Repeat([]byte{0xAB}, fullHashMaxSize+3*contentSampleSize)
+	l1 := write("l1.mp4", large)
+	large[len(large)/2] = 0xCD
+	l2 := write("l2.mp4", large)
+
+	hashL1, err := HashContent(l1)
+	if err != nil {
+		t.Fatalf("HashContent failed for large file: %v", err)
+	}
+	hashL2, _ := HashContent(l2)
+	if hashL1 == hashL2 {
+		t.Error("Expected a change in the middle sample to change the hash")
+	}
+
+	if _, err := HashContent(filepath.Join(dir, "missing.jpg")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestHashContentWith(t *testing.T) {
+	dir := t.TempDir()
+
+	large := bytes.Repeat([]byte{0xAB}, fullHashMaxSize+3*contentSampleSize)
+	original := filepath.Join(dir, "original.mp4")
+	if err := os.WriteFile(original, large, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	large[len(large)/2] = 0xCD
+	edited := filepath.Join(dir, "edited.mp4")
+	if err := os.WriteFile(edited, large, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		opts       HashOptions
+		hashLength int
+		sameHash   bool // Whether the change in the middle goes unnoticed
+	}{
+		{HashOptions{Mode: HashSampled, Algorithm: HashSHA256}, 64, false},
+		{HashOptions{Mode: HashFull, Algorithm: HashSHA256}, 64, false},
+		{HashOptions{Mode: HashPartial, Algorithm: HashSHA256}, 64, true},
+		{HashOptions{Mode: HashFull, Algorithm: HashXXHash}, 16, false},
+		{HashOptions{Mode: HashPartial, Algorithm: HashXXHash}, 16, true},
+	}
+	for _, tt := range tests {
+		hash1, err := HashContentWith(original, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: HashContentWith failed: %v", tt.opts, err)
+		}
+		hash2, _ := HashContentWith(edited, tt.opts)
+		if len(hash1) != tt.hashLength {
+			t.Errorf("%s: expected a hash of %d characters, got %q", tt.opts, tt.hashLength, hash1)
+		}
+		if (hash1 == hash2) != tt.sameHash {
+			t.Errorf("%s: expected same hash %v, got %q and %q", tt.opts, tt.sameHash, hash1, hash2)
+		}
+	}
+
+	// The defaults match HashContent
+	want, _ := HashContent(original)
+	if got, _ := HashContentWith(original, DefaultHashOptions()); got != want {
+		t.Errorf("Expected default options to match HashContent, got %q and %q", got, want)
+	}
+
+	// A file read whole hashes the same as with HashFull
+	small := filepath.Join(dir, "small.jpg")
+	if err := os.WriteFile(small, large[:contentSampleSize], 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	full := HashOptions{Mode: HashFull, Algorithm: HashXXHash}
+	for _, opts := range []HashOptions{{Mode: HashSampled, Algorithm: HashXXHash}, {Mode: HashPartial, Algorithm: HashXXHash}} {
+		if !opts.ReadsWhole(contentSampleSize) {
+			t.Errorf("%s: expected a %d byte file to be read whole", opts, contentSampleSize)
+		}
+		got, _ := HashContentWith(small, opts)
+		if want, _ := HashContentWith(small, full); got != want {
+			t.Errorf("%s: expected the full hash %q, got %q", opts, want, got)
+		}
+		if opts.ReadsWhole(int64(len(large))) {
+			t.Errorf("%s: expected a %d byte file to be sampled", opts, len(large))
+		}
+	}
+}
+
+func TestParseHashOptions(t *testing.T) {
+	if mode, err := ParseHashMode(" Partial "); err != nil || mode != HashPartial {
+		t.Errorf("ParseHashMode(\" Partial \") = %q, %v", mode, err)
+	}
+	if mode, err := ParseHashMode(""); err != nil || mode != HashSampled {
+		t.Errorf("ParseHashMode(\"\") = %q, %v", mode, err)
+	}
+	if mode, err := ParseHashMode("quick"); err == nil || mode != HashSampled {
+		t.Errorf("ParseHashMode(\"quick\") = %q, %v; want an error and the default", mode, err)
+	}
+
+	if algorithm, err := ParseHashAlgorithm("XXHASH"); err != nil || algorithm != HashXXHash {
+		t.Errorf("ParseHashAlgorithm(\"XXHASH\") = %q, %v", algorithm, err)
+	}
+	if algorithm, err := ParseHashAlgorithm("md5"); err == nil || algorithm != HashSHA256 {
+		t.Errorf("ParseHashAlgorithm(\"md5\") = %q, %v; want an error and the default", algorithm, err)
+	}
+}
@@ -0,0 +1,70 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMoveAll(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "album")
+	if err := os.MkdirAll(filepath.Join(src, "day1"), 0o755); err != nil {
+		t.Fatalf("Failed to create folders: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "day1", "photo.jpg"), []byte("photo"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	dst := filepath.Join(dir, "moved")
+	if err := MoveAll(src, dst); err != nil {
+		t.Fatalf("MoveAll failed: %v", err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("Expected the source to be gone")
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "day1", "photo.jpg")); err != nil || string(data) != "photo" {
+		t.Errorf("Expected the folder moved with its contents, got %q, %v", data, err)
+	}
+}
+
+func TestCopyAll(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "album")
+	if err := os.MkdirAll(filepath.Join(src, "day1"), 0o755); err != nil {
+		t.Fatalf("Failed to create folders: %v", err)
+	}
+	photo := filepath.Join(src, "day1", "photo.jpg")
+	if err := os.WriteFile(photo, []byte("photo"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	modTime := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(photo, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+	if err := os.Symlink("day1/photo.jpg", filepath.Join(src, "cover.jpg")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	dst := filepath.Join(dir, "copy")
+	if err := copyAll(src, dst); err != nil {
+		t.Fatalf("copyAll failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dst, "day1", "photo.jpg"))
+	if err != nil {
+		t.Fatalf("Expected the file to be copied: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 || !info.ModTime().Equal(modTime) {
+		t.Errorf("Expected mode and modification time kept, got %v, %v", info.Mode().Perm(), info.ModTime())
+	}
+	if target, err := os.Readlink(filepath.Join(dst, "cover.jpg")); err != nil || target != "day1/photo.jpg" {
+		t.Errorf("Expected the symlink copied as a link, got %q, %v", target, err)
+	}
+
+	// Existing files are never overwritten
+	if err := copyAll(src, dst); err == nil {
+		t.Error("Expected copying onto an existing folder to fail")
+	}
+}
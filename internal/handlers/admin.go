@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/logging"
+	"media-viewer/internal/media"
+	"media-viewer/internal/mediatypes"
+	"media-viewer/internal/startup"
+	"media-viewer/internal/transcoder"
+)
+
+// ConfigReloader re-reads configuration and applies the reloadable subset to
+// the running indexer, thumbnail generator, memory monitor and logger.
+type ConfigReloader func() (*startup.ReloadResult, error)
+
+// ReloadConfig handles reloading configuration without a restart.
+// POST /api/admin/reload
+func (h *Handlers) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.configReloader == nil {
+		httpError(w, r, "Configuration reload not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	result, err := h.configReloader()
+	if err != nil {
+		logging.Error("Failed to reload configuration: %v", err)
+		httpError(w, r, "Failed to reload configuration", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, result)
+}
+
+// In-memory caches that can be inspected and flushed
+const (
+	memoryCacheVideo = "video" // ffprobe results for videos
+	memoryCacheStats = "stats" // thumbnail and transcode cache directory sizes
+	memoryCacheAll   = "all"
+)
+
+// MemoryCacheStats describes the usage of one in-memory cache.
+type MemoryCacheStats struct {
+	Entries     int     `json:"entries"`
+	Hits        int64   `json:"hits"`
+	Misses      int64   `json:"misses"`
+	HitRate     float64 `json:"hitRate"`
+	MemoryBytes int64   `json:"memoryBytes"` // Estimate
+}
+
+// add accumulates the counters of a component cache.
+func (s *MemoryCacheStats) add(entries int, hits, misses, memoryBytes int64) {
+	s.Entries += entries
+	s.Hits += hits
+	s.Misses += misses
+	s.MemoryBytes += memoryBytes
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRate = float64(s.Hits) / float64(total)
+	}
+}
+
+func (s *MemoryCacheStats) addTranscoder(stats transcoder.CacheStats) {
+	s.add(stats.Entries, stats.Hits, stats.Misses, stats.MemoryBytes)
+}
+
+func (s *MemoryCacheStats) addMedia(stats media.CacheStats) {
+	s.add(stats.Entries, stats.Hits, stats.Misses, stats.MemoryBytes)
+}
+
+// GetCacheStats reports entry counts, hit rates and memory estimates for the
+// in-memory caches.
+// GET /api/admin/cache/stats
+func (h *Handlers) GetCacheStats(w http.ResponseWriter, _ *http.Request) {
+	var video, stats MemoryCacheStats
+	if h.transcoder != nil {
+		video.addTranscoder(h.transcoder.InfoCacheStats())
+		stats.addTranscoder(h.transcoder.SizeCacheStats())
+	}
+	if h.thumbGen != nil {
+		stats.addMedia(h.thumbGen.SizeCacheStats())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, map[string]MemoryCacheStats{
+		memoryCacheVideo: video,
+		memoryCacheStats: stats,
+	})
+}
+
+// FlushCaches clears one or all in-memory caches. The cache is selected with
+// the "which" query parameter (video, stats or all; default all).
+// POST /api/admin/cache/flush
+func (h *Handlers) FlushCaches(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	which := r.URL.Query().Get("which")
+	if which == "" {
+		which = memoryCacheAll
+	}
+	if which != memoryCacheVideo && which != memoryCacheStats && which != memoryCacheAll {
+		httpError(w, r, "Unknown cache (expected video, stats or all)", http.StatusBadRequest)
+		return
+	}
+
+	flushed := make(map[string]int)
+	if which == memoryCacheVideo || which == memoryCacheAll {
+		flushed[memoryCacheVideo] = 0
+		if h.transcoder != nil {
+			flushed[memoryCacheVideo] = h.transcoder.FlushInfoCache()
+		}
+	}
+	if which == memoryCacheStats || which == memoryCacheAll {
+		flushed[memoryCacheStats] = 0
+		if h.transcoder != nil {
+			flushed[memoryCacheStats] += h.transcoder.FlushSizeCache()
+		}
+		if h.thumbGen != nil {
+			flushed[memoryCacheStats] += h.thumbGen.FlushSizeCache()
+		}
+	}
+
+	logging.Info("Flushed in-memory caches (%s): %v", which, flushed)
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, map[string]interface{}{
+		"success": true,
+		"flushed": flushed,
+	})
+}
+
+// GetImageOrientation decodes an image the way its thumbnail is generated and
+// reports the image's EXIF orientation and whether the decoded image was
+// corrected for it. Nothing is cached.
+// GET /api/admin/orientation/{path}
+func (h *Handlers) GetImageOrientation(w http.ResponseWriter, r *http.Request) {
+	filePath, fullPath, ok := h.validateThumbnailPath(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.validateThumbnailFileOnDisk(w, r, filePath, fullPath) {
+		return
+	}
+
+	if mediatypes.GetFileType(mediatypes.NormalizeExt(filePath)) != mediatypes.FileTypeImage {
+		httpError(w, r, "Not an image", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.thumbGen.InspectOrientation(r.Context(), fullPath)
+	if err != nil {
+		logging.Error("Failed to inspect orientation of %s: %v", filePath, err)
+		httpError(w, r, "Failed to decode image", http.StatusUnprocessableEntity)
+		return
+	}
+	report.Path = filePath
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, report)
+}
+
+// WorkerReport lists the worker counts chosen for the background pools and
+// the CPU figures they were derived from.
+type WorkerReport struct {
+	GOMAXPROCS int                `json:"gomaxprocs"`
+	NumCPU     int                `json:"numCPU"`
+	Index      int                `json:"index"`
+	Thumbnails media.WorkerCounts `json:"thumbnails"`
+}
+
+// GetWorkers reports the effective worker counts, to check that container CPU
+// limits and the *_WORKERS overrides have the intended effect.
+// GET /api/admin/workers
+func (h *Handlers) GetWorkers(w http.ResponseWriter, _ *http.Request) {
+	report := WorkerReport{
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+	}
+	if h.indexer != nil {
+		report.Index = h.indexer.Workers()
+	}
+	if h.thumbGen != nil {
+		report.Thumbnails = h.thumbGen.WorkerCounts()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, report)
+}
+
+// RebuildFTS starts repopulating the full-text search indexes from the
+// database in the background, without rescanning the media directory. The
+// rows indexed per batch can be set with the "batchSize" query parameter.
+// Progress is reported by GetFTSRebuildStatus.
+// POST /api/admin/fts/rebuild
+func (h *Handlers) RebuildFTS(w http.ResponseWriter, r *http.Request) {
+	batchSize := database.DefaultFTSRebuildBatchSize
+	if value := r.URL.Query().Get("batchSize"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > database.MaxFTSRebuildBatchSize {
+			httpError(w, r, fmt.Sprintf("Invalid batchSize (expected 1-%d)", database.MaxFTSRebuildBatchSize), http.StatusBadRequest)
+			return
+		}
+		batchSize = n
+	}
+
+	if h.db.FTSRebuildStatus().Running {
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, map[string]string{
+			"status":  "already_running",
+			"message": "Full-text index rebuild is already in progress",
+		})
+		return
+	}
+
+	// The rebuild should continue even if the HTTP request completes
+	//nolint:contextcheck // Intentionally not passing request context - rebuild runs in background
+	go func() {
+		if err := h.db.RebuildFTSInBatches(context.Background(), batchSize); err != nil && !errors.Is(err, database.ErrFTSRebuildRunning) {
+			logging.Error("Full-text index rebuild failed: %v", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, map[string]string{
+		"status":  "started",
+		"message": "Full-text index rebuild started in background",
+	})
+}
+
+// GetFTSRebuildStatus reports the progress of the running or most recent
+// full-text index rebuild.
+// GET /api/admin/fts/status
+func (h *Handlers) GetFTSRebuildStatus(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, h.db.FTSRebuildStatus())
+}
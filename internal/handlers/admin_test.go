@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/indexer"
+	"media-viewer/internal/media"
+	"media-viewer/internal/startup"
+	"media-viewer/internal/transcoder"
+)
+
+func TestReloadConfig(t *testing.T) {
+	h := &Handlers{}
+	calls := 0
+	h.SetConfigReloader(func() (*startup.ReloadResult, error) {
+		calls++
+		return &startup.ReloadResult{
+			Changed:         []string{"POLL_INTERVAL"},
+			RestartRequired: []string{"PORT"},
+		}, nil
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/reload", http.NoBody)
+	w := httptest.NewRecorder()
+
+	h.ReloadConfig(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if calls != 1 {
+		t.Errorf("Expected reloader to be called once, got %d", calls)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", ct)
+	}
+
+	var body map[string][]string
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body["changed"]) != 1 || body["changed"][0] != "POLL_INTERVAL" {
+		t.Errorf("Unexpected changed list: %v", body["changed"])
+	}
+	if len(body["restartRequired"]) != 1 || body["restartRequired"][0] != "PORT" {
+		t.Errorf("Unexpected restartRequired list: %v", body["restartRequired"])
+	}
+}
+
+func TestReloadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		reloader   ConfigReloader
+		wantStatus int
+	}{
+		{
+			name:       "Wrong method",
+			method:     http.MethodGet,
+			reloader:   func() (*startup.ReloadResult, error) { return &startup.ReloadResult{}, nil },
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "No reloader configured",
+			method:     http.MethodPost,
+			reloader:   nil,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "Reload fails",
+			method:     http.MethodPost,
+			reloader:   func() (*startup.ReloadResult, error) { return nil, errors.New("bad config file") },
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handlers{}
+			h.SetConfigReloader(tt.reloader)
+
+			req := httptest.NewRequest(tt.method, "/api/admin/reload", http.NoBody)
+			w := httptest.NewRecorder()
+
+			h.ReloadConfig(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestGetCacheStatsAndFlush(t *testing.T) {
+	thumbDir := t.TempDir()
+	h := &Handlers{
+		transcoder: transcoder.New(t.TempDir(), "", true, "none"),
+		thumbGen:   media.NewThumbnailGenerator(thumbDir, t.TempDir(), true, nil, time.Hour, nil),
+	}
+
+	// Populate the size caches: one miss each, then one hit each
+	for range 2 {
+		if _, _, err := h.thumbGen.GetCacheSize(); err != nil {
+			t.Fatalf("thumbnail GetCacheSize failed: %v", err)
+		}
+		if _, _, err := h.transcoder.GetCacheSize(); err != nil {
+			t.Fatalf("transcoder GetCacheSize failed: %v", err)
+		}
+	}
+
+	getStats := func() map[string]MemoryCacheStats {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/cache/stats", http.NoBody)
+		w := httptest.NewRecorder()
+		h.GetCacheStats(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		var body map[string]MemoryCacheStats
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return body
+	}
+
+	stats := getStats()["stats"]
+	if stats.Entries != 2 || stats.Hits != 2 || stats.Misses != 2 {
+		t.Errorf("Unexpected stats cache usage: %+v", stats)
+	}
+	if stats.HitRate != 0.5 {
+		t.Errorf("Expected hit rate 0.5, got %v", stats.HitRate)
+	}
+	if stats.MemoryBytes <= 0 {
+		t.Errorf("Expected a memory estimate, got %d", stats.MemoryBytes)
+	}
+	if video, ok := getStats()["video"]; !ok || video.Entries != 0 {
+		t.Errorf("Expected empty video cache to be reported, got %+v", video)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/cache/flush?which=stats", http.NoBody)
+	w := httptest.NewRecorder()
+	h.FlushCaches(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var body struct {
+		Flushed map[string]int `json:"flushed"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Flushed["stats"] != 2 {
+		t.Errorf("Expected 2 stats entries flushed, got %v", body.Flushed)
+	}
+	if _, ok := body.Flushed["video"]; ok {
+		t.Errorf("Expected only the stats cache to be flushed, got %v", body.Flushed)
+	}
+
+	if stats := getStats()["stats"]; stats.Entries != 0 {
+		t.Errorf("Expected stats cache to be empty after flush, got %+v", stats)
+	}
+}
+
+func TestFlushCachesErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		query      string
+		wantStatus int
+	}{
+		{"Wrong method", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"Unknown cache", http.MethodPost, "?which=listings", http.StatusBadRequest},
+		{"All without components", http.MethodPost, "?which=all", http.StatusOK},
+		{"Default is all", http.MethodPost, "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handlers{}
+
+			req := httptest.NewRequest(tt.method, "/api/admin/cache/flush"+tt.query, http.NoBody)
+			w := httptest.NewRecorder()
+
+			h.FlushCaches(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestGetWorkers(t *testing.T) {
+	t.Setenv("INDEX_WORKERS", "5")
+	t.Setenv("THUMBNAIL_WORKERS", "4")
+	t.Setenv("THUMBNAIL_INITIAL_WORKERS", "2")
+
+	h := &Handlers{
+		indexer:  indexer.New(nil, t.TempDir(), time.Hour),
+		thumbGen: media.NewThumbnailGenerator(t.TempDir(), t.TempDir(), true, nil, time.Hour, nil),
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/workers", http.NoBody)
+	w := httptest.NewRecorder()
+	h.GetWorkers(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var report WorkerReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if report.GOMAXPROCS < 1 || report.NumCPU < 1 {
+		t.Errorf("Expected CPU counts, got %+v", report)
+	}
+	if report.Index != 5 {
+		t.Errorf("Index = %d, want 5", report.Index)
+	}
+	if thumbs := report.Thumbnails; thumbs.Count != 4 || !thumbs.Override || thumbs.Initial != 2 {
+		t.Errorf("Unexpected thumbnail workers %+v", thumbs)
+	}
+}
+
+func TestGetImageOrientation(t *testing.T) {
+	mediaDir := t.TempDir()
+	h := &Handlers{
+		mediaDir: mediaDir,
+		thumbGen: media.NewThumbnailGenerator(t.TempDir(), mediaDir, true, nil, time.Hour, nil),
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 30, 20)), nil); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(mediaDir, "photo.jpg"), buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("Failed to write image: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(mediaDir, "notes.txt"), []byte("notes"), 0o644); err != nil {
+		t.Fatalf("Failed to write text file: %v", err)
+	}
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/orientation/"+path, http.NoBody)
+		req = mux.SetURLVars(req, map[string]string{"path": path})
+		w := httptest.NewRecorder()
+		h.GetImageOrientation(w, req)
+		return w
+	}
+
+	w := request("photo.jpg")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var report media.OrientationReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if report.Path != "photo.jpg" || report.Orientation != 0 || report.NeedsCorrection || report.DecodedWidth != 30 {
+		t.Errorf("Unexpected report for an image without EXIF: %+v", report)
+	}
+
+	for path, want := range map[string]int{
+		"notes.txt":     http.StatusBadRequest,
+		"missing.jpg":   http.StatusNotFound,
+		"../escape.jpg": http.StatusBadRequest,
+	} {
+		if w := request(path); w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", path, want, w.Code)
+		}
+	}
+}
+
+func TestRebuildFTSIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	for _, batchSize := range []string{"0", "-1", "lots", "100001"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/fts/rebuild?batchSize="+batchSize, http.NoBody)
+		w := httptest.NewRecorder()
+		h.RebuildFTS(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("batchSize=%s: expected status 400, got %d", batchSize, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/fts/rebuild?batchSize=50", http.NoBody)
+	w := httptest.NewRecorder()
+	h.RebuildFTS(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The rebuild runs in the background
+	var status database.FTSRebuildStatus
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		w := httptest.NewRecorder()
+		h.GetFTSRebuildStatus(w, httptest.NewRequest(http.MethodGet, "/api/admin/fts/status", http.NoBody))
+		if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+			t.Fatalf("Failed to decode status: %v", err)
+		}
+		if status.FinishedAt != nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status.Running || status.FinishedAt == nil || status.BatchSize != 50 || status.Error != "" {
+		t.Errorf("Expected a finished rebuild with batches of 50, got %+v", status)
+	}
+}
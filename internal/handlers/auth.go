@@ -9,6 +9,8 @@ import (
 	"media-viewer/internal/database"
 	"media-viewer/internal/logging"
 	"media-viewer/internal/metrics"
+
+	"github.com/gorilla/mux"
 )
 
 // LoginRequest represents a login request with password only
@@ -39,7 +41,8 @@ type AuthResponse struct {
 type AuthCheckResponse struct {
 	Authenticated bool `json:"authenticated"`
 	SetupRequired bool `json:"setupRequired"`
-	ExpiresIn     int  `json:"expiresIn,omitempty"` // Seconds until session expires
+	ExpiresIn     int  `json:"expiresIn,omitempty"`  // Seconds until session expires
+	PublicMode    bool `json:"publicMode,omitempty"` // Read-only browsing allowed without login
 }
 
 const (
@@ -53,31 +56,31 @@ func (h *Handlers) Setup(w http.ResponseWriter, r *http.Request) {
 
 	// Only allow setup if not already complete
 	if h.db.IsSetupComplete(ctx) {
-		http.Error(w, "Setup already completed", http.StatusForbidden)
+		httpError(w, r, "Setup already completed", http.StatusForbidden)
 		return
 	}
 
 	var req SetupRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	// Validate input
 	if len(req.Password) < 6 {
-		http.Error(w, "Password must be at least 6 characters", http.StatusBadRequest)
+		httpError(w, r, "Password must be at least 6 characters", http.StatusBadRequest)
 		return
 	}
 
 	if len(req.Password) > 72 {
-		http.Error(w, "Password must not exceed 72 characters", http.StatusBadRequest)
+		httpError(w, r, "Password must not exceed 72 characters", http.StatusBadRequest)
 		return
 	}
 
 	// Create user
 	if err := h.db.CreateUser(ctx, req.Password); err != nil {
 		logging.Error("Failed to create user: %v", err)
-		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		httpError(w, r, "Failed to create user", http.StatusInternalServerError)
 		return
 	}
 
@@ -96,7 +99,7 @@ func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
 
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
@@ -105,7 +108,7 @@ func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		logging.Warn("Failed login attempt")
 		metrics.AuthAttemptsTotal.WithLabelValues("failure").Inc()
-		http.Error(w, "Invalid password", http.StatusUnauthorized)
+		httpError(w, r, "Invalid password", http.StatusUnauthorized)
 		return
 	}
 
@@ -115,7 +118,7 @@ func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
 	session, err := h.db.CreateSession(ctx, user.ID)
 	if err != nil {
 		logging.Error("Failed to create session: %v", err)
-		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		httpError(w, r, "Failed to create session", http.StatusInternalServerError)
 		return
 	}
 
@@ -182,6 +185,7 @@ func (h *Handlers) CheckAuth(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, AuthCheckResponse{
 			Authenticated: false,
 			SetupRequired: !setupComplete,
+			PublicMode:    h.publicMode,
 		})
 		return
 	}
@@ -202,6 +206,7 @@ func (h *Handlers) CheckAuth(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, AuthCheckResponse{
 			Authenticated: false,
 			SetupRequired: !setupComplete,
+			PublicMode:    h.publicMode,
 		})
 		return
 	}
@@ -212,11 +217,30 @@ func (h *Handlers) CheckAuth(w http.ResponseWriter, r *http.Request) {
 		Authenticated: true,
 		SetupRequired: false,
 		ExpiresIn:     int(database.GetSessionDuration().Seconds()),
+		PublicMode:    h.publicMode,
 	})
 }
 
-// AuthMiddleware protects routes that require authentication
-func (h *Handlers) AuthMiddleware(next http.Handler) http.Handler {
+// Public marks route as one anonymous visitors may use in public mode and
+// returns it. Routes that aren't marked require login even in public mode, so
+// a new route stays private until it is deliberately made public.
+func (h *Handlers) Public(route *mux.Route) *mux.Route {
+	if h.publicRoutes == nil {
+		h.publicRoutes = make(map[*mux.Route]bool)
+	}
+	h.publicRoutes[route] = true
+	return route
+}
+
+// IsPublic reports whether route was marked with Public
+func (h *Handlers) IsPublic(route *mux.Route) bool {
+	return h.publicRoutes[route]
+}
+
+// AuthMiddleware protects the routes of router that require authentication.
+// The router is also used to find the route a request is for, to tell
+// whether anonymous visitors may use it in public mode.
+func (h *Handlers) AuthMiddleware(router *mux.Router) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
@@ -236,16 +260,21 @@ func (h *Handlers) AuthMiddleware(next http.Handler) http.Handler {
 			r.URL.Path == "/sw.js" ||
 			strings.HasPrefix(r.URL.Path, "/icons/") ||
 			r.URL.Path == "/favicon.ico" {
-			next.ServeHTTP(w, r)
+			router.ServeHTTP(w, r)
 			return
 		}
 
 		// Check for session cookie
 		cookie, err := r.Cookie(SessionCookieName)
 		if err != nil || cookie.Value == "" {
+			if h.allowAnonymous(router, r) {
+				router.ServeHTTP(w, r)
+				return
+			}
+
 			// Redirect to login for HTML requests, return 401 for API
 			if strings.HasPrefix(r.URL.Path, "/api/") {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				httpError(w, r, "Unauthorized", http.StatusUnauthorized)
 			} else {
 				http.Redirect(w, r, "/login.html", http.StatusFound)
 			}
@@ -264,8 +293,13 @@ func (h *Handlers) AuthMiddleware(next http.Handler) http.Handler {
 				HttpOnly: true,
 			})
 
+			if h.allowAnonymous(router, r) {
+				router.ServeHTTP(w, r)
+				return
+			}
+
 			if strings.HasPrefix(r.URL.Path, "/api/") {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				httpError(w, r, "Unauthorized", http.StatusUnauthorized)
 			} else {
 				http.Redirect(w, r, "/login.html", http.StatusFound)
 			}
@@ -287,17 +321,33 @@ func (h *Handlers) AuthMiddleware(next http.Handler) http.Handler {
 			})
 		}
 
-		next.ServeHTTP(w, r)
+		router.ServeHTTP(w, r)
 	})
 }
 
+// allowAnonymous reports whether a request without a valid session may proceed.
+// This is only the case in public mode, and only for routes marked with Public,
+// which only read data; everything else (tags, favorites, cache management,
+// reindexing, administration) still requires the authenticated admin, as does
+// any request bypassing caches with ?nocache, any request setting its own
+// stream bandwidth with ?maxBytesPerSec and any request revealing a sensitive
+// thumbnail with ?reveal.
+func (h *Handlers) allowAnonymous(router *mux.Router, r *http.Request) bool {
+	if !h.publicMode || bypassCache(r) || overridesStreamRate(r) || revealsSensitive(r) {
+		return false
+	}
+
+	var match mux.RouteMatch
+	return router.Match(r, &match) && h.publicRoutes[match.Route]
+}
+
 // ChangePassword handles password change requests
 func (h *Handlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var req PasswordChangeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
@@ -305,25 +355,25 @@ func (h *Handlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	_, err := h.db.ValidatePassword(ctx, req.CurrentPassword)
 	if err != nil {
 		logging.Warn("Failed password change attempt - invalid current password")
-		http.Error(w, "Current password is incorrect", http.StatusUnauthorized)
+		httpError(w, r, "Current password is incorrect", http.StatusUnauthorized)
 		return
 	}
 
 	// Validate new password
 	if len(req.NewPassword) < 6 {
-		http.Error(w, "New password must be at least 6 characters", http.StatusBadRequest)
+		httpError(w, r, "New password must be at least 6 characters", http.StatusBadRequest)
 		return
 	}
 
 	if len(req.NewPassword) > 72 {
-		http.Error(w, "New password must not exceed 72 characters", http.StatusBadRequest)
+		httpError(w, r, "New password must not exceed 72 characters", http.StatusBadRequest)
 		return
 	}
 
 	// Update password
 	if err := h.db.UpdatePassword(ctx, req.NewPassword); err != nil {
 		logging.Error("Failed to update password: %v", err)
-		http.Error(w, "Failed to update password", http.StatusInternalServerError)
+		httpError(w, r, "Failed to update password", http.StatusInternalServerError)
 		return
 	}
 
@@ -342,21 +392,21 @@ func (h *Handlers) Keepalive(w http.ResponseWriter, r *http.Request) {
 
 	cookie, err := r.Cookie(SessionCookieName)
 	if err != nil || cookie.Value == "" {
-		http.Error(w, "No session", http.StatusUnauthorized)
+		httpError(w, r, "No session", http.StatusUnauthorized)
 		return
 	}
 
 	// Validate the session first
 	_, err = h.db.ValidateSession(ctx, cookie.Value)
 	if err != nil {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
+		httpError(w, r, "Invalid session", http.StatusUnauthorized)
 		return
 	}
 
 	// Extend the session
 	if err := h.db.ExtendSession(ctx, cookie.Value); err != nil {
 		logging.Debug("Failed to extend session in keepalive: %v", err)
-		http.Error(w, "Failed to extend session", http.StatusInternalServerError)
+		httpError(w, r, "Failed to extend session", http.StatusInternalServerError)
 		return
 	}
 
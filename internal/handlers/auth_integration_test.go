@@ -17,6 +17,8 @@ import (
 	"media-viewer/internal/media"
 	"media-viewer/internal/startup"
 	"media-viewer/internal/transcoder"
+
+	"github.com/gorilla/mux"
 )
 
 // setupAuthIntegrationTest creates a real test environment for auth testing
@@ -851,3 +853,157 @@ func TestConcurrentPasswordChangeIntegration(t *testing.T) {
 		t.Errorf("Expected valid response after concurrent changes, got %d", w.Code)
 	}
 }
+
+// =============================================================================
+// Public Mode Tests
+// =============================================================================
+
+// publicModeTestRouter routes the paths the public mode tests request, marking
+// the read-only ones public the way setupRouter does. Every route answers 200.
+func publicModeTestRouter(h *Handlers) *mux.Router {
+	ok := func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api").Subrouter()
+	h.Public(api.HandleFunc("/files", ok).Methods("GET"))
+	h.Public(api.HandleFunc("/files/check", ok).Methods("POST"))
+	h.Public(api.HandleFunc("/file/{path:.*}", ok).Methods("GET"))
+	h.Public(api.HandleFunc("/stream/{path:.*}", ok).Methods("GET", "HEAD"))
+	h.Public(api.HandleFunc("/hls/{path:.*}/{segment}", ok).Methods("GET"))
+	h.Public(api.HandleFunc("/thumbnail/{path:.*}", ok).Methods("GET"))
+	h.Public(api.HandleFunc("/tags/batch", ok).Methods("POST"))
+	api.HandleFunc("/tags/unused", ok).Methods("GET")
+	h.Public(api.HandleFunc("/tags/{tag}", ok).Methods("GET"))
+	api.HandleFunc("/tags/{tag}", ok).Methods("DELETE")
+	api.HandleFunc("/favorites", ok).Methods("POST")
+	api.HandleFunc("/reindex", ok).Methods("POST")
+	api.HandleFunc("/trash", ok).Methods("GET")
+	api.HandleFunc("/index/errors", ok).Methods("GET")
+	api.HandleFunc("/duplicates", ok).Methods("GET")
+	api.HandleFunc("/export/curation", ok).Methods("GET")
+	api.HandleFunc("/admin/cache/stats", ok).Methods("GET")
+	h.Public(r.PathPrefix("/").HandlerFunc(ok))
+	return r
+}
+
+func TestAuthMiddlewarePublicModeIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupAuthIntegrationTest(t)
+	defer cleanup()
+
+	router := publicModeTestRouter(h)
+
+	tests := []struct {
+		name       string
+		publicMode bool
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"default mode blocks reads", false, http.MethodGet, "/api/files", http.StatusUnauthorized},
+		{"default mode redirects pages", false, http.MethodGet, "/index.html", http.StatusFound},
+		{"public mode allows reads", true, http.MethodGet, "/api/files", http.StatusOK},
+		{"public mode allows HEAD", true, http.MethodHead, "/api/stream/video.mp4", http.StatusOK},
+		{"public mode allows pages", true, http.MethodGet, "/index.html", http.StatusOK},
+		{"public mode allows read-only POST", true, http.MethodPost, "/api/tags/batch", http.StatusOK},
+		{"public mode allows file check", true, http.MethodPost, "/api/files/check", http.StatusOK},
+		{"public mode blocks reindex", true, http.MethodPost, "/api/reindex", http.StatusUnauthorized},
+		{"public mode blocks tag delete", true, http.MethodDelete, "/api/tags/beach", http.StatusUnauthorized},
+		{"public mode blocks favorites add", true, http.MethodPost, "/api/favorites", http.StatusUnauthorized},
+		{"public mode blocks admin reads", true, http.MethodGet, "/api/admin/cache/stats", http.StatusUnauthorized},
+		{"public mode blocks cache bypass", true, http.MethodGet, "/api/thumbnail/a.jpg?nocache=true", http.StatusUnauthorized},
+		{"public mode ignores nocache=false", true, http.MethodGet, "/api/files?nocache=false", http.StatusOK},
+		{"public mode blocks stream rate override", true, http.MethodGet, "/api/file/a.jpg?maxBytesPerSec=0", http.StatusUnauthorized},
+		{"public mode blocks sensitive reveal", true, http.MethodGet, "/api/thumbnail/a.jpg?reveal=true", http.StatusUnauthorized},
+		{"public mode blocks sensitive file reveal", true, http.MethodGet, "/api/file/a.jpg?reveal=true", http.StatusUnauthorized},
+		{"public mode blocks sensitive stream reveal", true, http.MethodGet, "/api/stream/video.mp4?reveal=true", http.StatusUnauthorized},
+		{"public mode blocks sensitive HLS reveal", true, http.MethodGet, "/api/hls/video.mp4/playlist.m3u8?reveal=true", http.StatusUnauthorized},
+		{"public mode ignores reveal=false", true, http.MethodGet, "/api/thumbnail/a.jpg?reveal=false", http.StatusOK},
+		{"public mode blocks trash listing", true, http.MethodGet, "/api/trash", http.StatusUnauthorized},
+		{"public mode blocks index errors", true, http.MethodGet, "/api/index/errors", http.StatusUnauthorized},
+		{"public mode blocks duplicates", true, http.MethodGet, "/api/duplicates", http.StatusUnauthorized},
+		{"public mode blocks curation export", true, http.MethodGet, "/api/export/curation", http.StatusUnauthorized},
+		{"public mode allows public route with vars", true, http.MethodGet, "/api/tags/beach", http.StatusOK},
+		{"public mode blocks unmarked route matching a public pattern", true, http.MethodGet, "/api/tags/unused", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h.publicMode = tt.publicMode
+
+			req := httptest.NewRequest(tt.method, tt.path, http.NoBody)
+			w := httptest.NewRecorder()
+
+			h.AuthMiddleware(router).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewarePublicModeInvalidSessionIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupAuthIntegrationTest(t)
+	defer cleanup()
+
+	h.publicMode = true
+
+	router := publicModeTestRouter(h)
+
+	// A stale cookie should not lock an anonymous visitor out of reads
+	req := httptest.NewRequest(http.MethodGet, "/api/files", http.NoBody)
+	req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: "stale-token"})
+	w := httptest.NewRecorder()
+
+	h.AuthMiddleware(router).ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for read with stale session, got %d", w.Code)
+	}
+
+	// ...but writes still require a valid session
+	req = httptest.NewRequest(http.MethodPost, "/api/reindex", http.NoBody)
+	req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: "stale-token"})
+	w = httptest.NewRecorder()
+
+	h.AuthMiddleware(router).ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for write with stale session, got %d", w.Code)
+	}
+}
+
+func TestCheckAuthReportsPublicModeIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupAuthIntegrationTest(t)
+	defer cleanup()
+
+	h.publicMode = true
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/check", http.NoBody)
+	w := httptest.NewRecorder()
+
+	h.CheckAuth(w, req)
+
+	var response AuthCheckResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Authenticated {
+		t.Error("Expected authenticated=false without a session")
+	}
+	if !response.PublicMode {
+		t.Error("Expected publicMode=true")
+	}
+}
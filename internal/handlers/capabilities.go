@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+
+	"media-viewer/internal/media"
+	"media-viewer/internal/mediatypes"
+	"media-viewer/internal/transcoder"
+)
+
+// Capabilities describes what this deployment can display and play, which
+// depends on its libvips and FFmpeg builds as well as its configuration
+type Capabilities struct {
+	Thumbnails           bool     `json:"thumbnails"`           // Thumbnail generation is enabled
+	ImageFormats         []string `json:"imageFormats"`         // Image extensions thumbnails can be generated for
+	VideoThumbnails      bool     `json:"videoThumbnails"`      // FFmpeg is available to extract video frames
+	VideoFormats         []string `json:"videoFormats"`         // Video extensions the library indexes
+	CompatibleCodecs     []string `json:"compatibleCodecs"`     // Video codecs streamed without transcoding
+	CompatibleContainers []string `json:"compatibleContainers"` // Containers streamed without remuxing
+	Transcoding          bool     `json:"transcoding"`          // Other videos are transcoded for playback
+}
+
+// GetCapabilities reports the supported formats, so clients can tell what
+// plays natively and what needs transcoding
+// GET /api/capabilities
+func (h *Handlers) GetCapabilities(w http.ResponseWriter, _ *http.Request) {
+	caps := Capabilities{
+		ImageFormats:         media.ThumbnailImageFormats(),
+		VideoThumbnails:      media.FFmpegDecoders() != nil,
+		VideoFormats:         videoFormats(),
+		CompatibleCodecs:     transcoder.CompatibleCodecs(),
+		CompatibleContainers: transcoder.CompatibleContainers(),
+	}
+	if h.thumbGen != nil {
+		caps.Thumbnails = h.thumbGen.IsEnabled()
+	}
+	if h.transcoder != nil {
+		caps.Transcoding = h.transcoder.IsEnabled()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	writeJSON(w, caps)
+}
+
+// videoFormats returns the extensions indexed as videos, sorted
+func videoFormats() []string {
+	formats := []string{}
+	for ext := range mediatypes.VideoExtensions {
+		// Extensions listed as both are indexed as images
+		if mediatypes.GetFileType(ext) == mediatypes.FileTypeVideo {
+			formats = append(formats, ext)
+		}
+	}
+	slices.Sort(formats)
+	return formats
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"media-viewer/internal/transcoder"
+)
+
+func TestGetCapabilities(t *testing.T) {
+	t.Parallel()
+
+	h := &Handlers{transcoder: transcoder.New(t.TempDir(), "", true, "none")}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/capabilities", http.NoBody)
+	w := httptest.NewRecorder()
+
+	h.GetCapabilities(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var caps Capabilities
+	if err := json.NewDecoder(w.Body).Decode(&caps); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if !caps.Transcoding {
+		t.Error("Expected transcoding to be reported as enabled")
+	}
+	if caps.Thumbnails {
+		t.Error("Expected thumbnails to be reported as disabled without a generator")
+	}
+	if !slices.Contains(caps.ImageFormats, ".jpg") {
+		t.Errorf("Expected .jpg among image formats, got %v", caps.ImageFormats)
+	}
+	if !slices.Contains(caps.CompatibleCodecs, "h264") || !slices.Contains(caps.CompatibleContainers, "mp4") {
+		t.Errorf("Expected h264 in mp4 to be compatible, got %v and %v", caps.CompatibleCodecs, caps.CompatibleContainers)
+	}
+	if !slices.Contains(caps.VideoFormats, ".mkv") || slices.Contains(caps.VideoFormats, ".webp") {
+		t.Errorf("Expected video extensions only, got %v", caps.VideoFormats)
+	}
+}
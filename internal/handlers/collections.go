@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/logging"
+)
+
+// maxCollectionPaths limits the paths accepted by one collection request
+const maxCollectionPaths = 10000
+
+// CollectionRequest represents a request to create or rename a collection
+type CollectionRequest struct {
+	Name string `json:"name"`
+}
+
+// CollectionItemsRequest lists the paths to add to, remove from or reorder
+// within a collection
+type CollectionItemsRequest struct {
+	Paths []string `json:"paths"`
+}
+
+// GetCollections returns all collections with their item counts
+func (h *Handlers) GetCollections(w http.ResponseWriter, r *http.Request) {
+	collections, err := h.db.GetCollections(r.Context())
+	if err != nil {
+		logging.Error("GetCollections database error: %v", err)
+		httpError(w, r, "Failed to get collections", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, collections)
+}
+
+// CreateCollection creates an empty collection and returns it
+func (h *Handlers) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	var req CollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.db.CreateCollection(r.Context(), req.Name)
+	if err != nil {
+		writeCollectionError(w, r, err, "create collection")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, collection)
+}
+
+// GetCollection returns a page of a collection's items. They are in the
+// collection's order unless sort is given; page, pageSize, order and type
+// work as for favorites.
+func (h *Handlers) GetCollection(w http.ResponseWriter, r *http.Request) {
+	id, ok := collectionID(w, r)
+	if !ok {
+		return
+	}
+
+	listing, err := h.db.ListCollection(r.Context(), id, pageListOptions(r))
+	if err != nil {
+		writeCollectionError(w, r, err, "get collection")
+		return
+	}
+
+	if listing.Items == nil {
+		listing.Items = []database.MediaFile{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, listing)
+}
+
+// RenameCollection renames a collection
+func (h *Handlers) RenameCollection(w http.ResponseWriter, r *http.Request) {
+	id, ok := collectionID(w, r)
+	if !ok {
+		return
+	}
+
+	var req CollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.RenameCollection(r.Context(), id, req.Name); err != nil {
+		writeCollectionError(w, r, err, "rename collection")
+		return
+	}
+
+	writeJSONStatus(w, "ok")
+}
+
+// DeleteCollection deletes a collection; its files are untouched
+func (h *Handlers) DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	id, ok := collectionID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteCollection(r.Context(), id); err != nil {
+		writeCollectionError(w, r, err, "delete collection")
+		return
+	}
+
+	writeJSONStatus(w, "ok")
+}
+
+// AddToCollection appends paths to the end of a collection
+func (h *Handlers) AddToCollection(w http.ResponseWriter, r *http.Request) {
+	id, req, ok := collectionItemsRequest(w, r)
+	if !ok {
+		return
+	}
+
+	added, err := h.db.AddToCollection(r.Context(), id, req.Paths)
+	if err != nil {
+		writeCollectionError(w, r, err, "add to collection")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, map[string]interface{}{"status": "ok", "added": added})
+}
+
+// RemoveFromCollection removes paths from a collection
+func (h *Handlers) RemoveFromCollection(w http.ResponseWriter, r *http.Request) {
+	id, req, ok := collectionItemsRequest(w, r)
+	if !ok {
+		return
+	}
+
+	removed, err := h.db.RemoveFromCollection(r.Context(), id, req.Paths)
+	if err != nil {
+		writeCollectionError(w, r, err, "remove from collection")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, map[string]interface{}{"status": "ok", "removed": removed})
+}
+
+// SetCollectionOrder moves the listed paths to the start of a collection in
+// the given order; the rest follow in their current order
+func (h *Handlers) SetCollectionOrder(w http.ResponseWriter, r *http.Request) {
+	id, req, ok := collectionItemsRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.SetCollectionOrder(r.Context(), id, req.Paths); err != nil {
+		writeCollectionError(w, r, err, "set collection order")
+		return
+	}
+
+	writeJSONStatus(w, "ok")
+}
+
+// collectionID parses the {id} route variable, writing 400 if it isn't one
+func collectionID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id < 1 {
+		httpError(w, r, "Invalid collection ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// collectionItemsRequest parses the collection ID and paths of an items
+// request, writing 400 if either is invalid
+func collectionItemsRequest(w http.ResponseWriter, r *http.Request) (int64, CollectionItemsRequest, bool) {
+	var req CollectionItemsRequest
+
+	id, ok := collectionID(w, r)
+	if !ok {
+		return 0, req, false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
+		return 0, req, false
+	}
+
+	if len(req.Paths) > maxCollectionPaths {
+		httpError(w, r, fmt.Sprintf("Too many paths (max %d)", maxCollectionPaths), http.StatusBadRequest)
+		return 0, req, false
+	}
+
+	return id, req, true
+}
+
+// writeCollectionError maps a collection database error to a response
+func writeCollectionError(w http.ResponseWriter, r *http.Request, err error, action string) {
+	switch {
+	case errors.Is(err, database.ErrCollectionNotFound):
+		httpError(w, r, "Collection not found", http.StatusNotFound)
+	case errors.Is(err, database.ErrCollectionExists):
+		httpError(w, r, err.Error(), http.StatusConflict)
+	case errors.Is(err, database.ErrInvalidCollectionName), errors.Is(err, database.ErrInvalidCollectionOrder):
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+	default:
+		logging.Error("Failed to %s: %v", action, err)
+		httpError(w, r, "Failed to "+action, http.StatusInternalServerError)
+	}
+}
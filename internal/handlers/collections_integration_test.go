@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"media-viewer/internal/database"
+)
+
+// serveCollectionRequest calls a collection handler with the {id} route variable set
+func serveCollectionRequest(handler http.HandlerFunc, method, target, id, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if id != "" {
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+	}
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func TestCollectionsFlowIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupFavoritesIntegrationTest(t)
+	defer cleanup()
+
+	addTestFile(t, h.db, "2023/summer/beach.jpg", "beach.jpg", database.FileTypeImage)
+	addTestFile(t, h.db, "2023/winter/snow.mp4", "snow.mp4", database.FileTypeVideo)
+	addTestFile(t, h.db, "pets/cat.jpg", "cat.jpg", database.FileTypeImage)
+
+	w := serveCollectionRequest(h.CreateCollection, http.MethodPost, "/api/collections", "", `{"name":"Best of 2023"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var collection database.Collection
+	if err := json.NewDecoder(w.Body).Decode(&collection); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	id := strconv.FormatInt(collection.ID, 10)
+
+	w = serveCollectionRequest(h.CreateCollection, http.MethodPost, "/api/collections", "", `{"name":"best of 2023"}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a duplicate name, got %d", w.Code)
+	}
+
+	w = serveCollectionRequest(h.AddToCollection, http.MethodPost, "/api/collections/"+id+"/items", id,
+		`{"paths":["pets/cat.jpg","2023/winter/snow.mp4","2023/summer/beach.jpg"]}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"added":3`) {
+		t.Fatalf("Expected 3 items added, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serveCollectionRequest(h.SetCollectionOrder, http.MethodPut, "/api/collections/"+id+"/order", id,
+		`{"paths":["2023/summer/beach.jpg"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 reordering, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serveCollectionRequest(h.GetCollection, http.MethodGet, "/api/collections/"+id+"?pageSize=2", id, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var listing database.CollectionListing
+	if err := json.NewDecoder(w.Body).Decode(&listing); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if listing.Collection.Name != "Best of 2023" || listing.TotalItems != 3 || listing.TotalPages != 2 {
+		t.Errorf("Unexpected listing: %+v", listing)
+	}
+	if len(listing.Items) != 2 || listing.Items[0].Name != "beach.jpg" || listing.Items[1].Name != "cat.jpg" {
+		t.Errorf("Expected beach.jpg then cat.jpg, got %+v", listing.Items)
+	}
+
+	w = serveCollectionRequest(h.RemoveFromCollection, http.MethodDelete, "/api/collections/"+id+"/items", id,
+		`{"paths":["pets/cat.jpg"]}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"removed":1`) {
+		t.Errorf("Expected 1 item removed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serveCollectionRequest(h.RenameCollection, http.MethodPut, "/api/collections/"+id, id, `{"name":"Highlights"}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 renaming, got %d", w.Code)
+	}
+
+	w = serveCollectionRequest(h.GetCollections, http.MethodGet, "/api/collections", "", "")
+	var collections []database.Collection
+	if err := json.NewDecoder(w.Body).Decode(&collections); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(collections) != 1 || collections[0].Name != "Highlights" || collections[0].ItemCount != 2 {
+		t.Errorf("Expected the renamed collection with 2 items, got %+v", collections)
+	}
+
+	w = serveCollectionRequest(h.DeleteCollection, http.MethodDelete, "/api/collections/"+id, id, "")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 deleting, got %d", w.Code)
+	}
+	w = serveCollectionRequest(h.GetCollection, http.MethodGet, "/api/collections/"+id, id, "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after deleting, got %d", w.Code)
+	}
+}
+
+func TestCollectionsBadRequestsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupFavoritesIntegrationTest(t)
+	defer cleanup()
+
+	tooMany, _ := json.Marshal(CollectionItemsRequest{Paths: make([]string, maxCollectionPaths+1)})
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		method   string
+		id       string
+		body     string
+		expected int
+	}{
+		{"blank name", h.CreateCollection, http.MethodPost, "", `{"name":"  "}`, http.StatusBadRequest},
+		{"invalid JSON", h.CreateCollection, http.MethodPost, "", `{`, http.StatusBadRequest},
+		{"non-numeric ID", h.GetCollection, http.MethodGet, "best", "", http.StatusBadRequest},
+		{"unknown collection", h.GetCollection, http.MethodGet, "42", "", http.StatusNotFound},
+		{"add to unknown collection", h.AddToCollection, http.MethodPost, "42", `{"paths":["a.jpg"]}`, http.StatusNotFound},
+		{"too many paths", h.AddToCollection, http.MethodPost, "42", string(tooMany), http.StatusBadRequest},
+		{"rename unknown collection", h.RenameCollection, http.MethodPut, "42", `{"name":"New"}`, http.StatusNotFound},
+		{"delete unknown collection", h.DeleteCollection, http.MethodDelete, "42", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveCollectionRequest(tt.handler, tt.method, "/api/collections", tt.id, tt.body)
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// Reordering with paths outside the collection is rejected
+	w := serveCollectionRequest(h.CreateCollection, http.MethodPost, "/api/collections", "", `{"name":"Empty"}`)
+	var collection database.Collection
+	if err := json.NewDecoder(w.Body).Decode(&collection); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	id := strconv.FormatInt(collection.ID, 10)
+	w = serveCollectionRequest(h.SetCollectionOrder, http.MethodPut, "/api/collections/"+id+"/order", id, `{"paths":["a.jpg"]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 ordering a path not in the collection, got %d", w.Code)
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/logging"
+)
+
+// ExportCuration returns all favorites and tags as a JSON document for
+// ImportCuration on this or another instance
+// GET /api/export/curation
+func (h *Handlers) ExportCuration(w http.ResponseWriter, r *http.Request) {
+	export, err := h.db.ExportCuration(r.Context())
+	if err != nil {
+		logging.Error("Failed to export curation: %v", err)
+		httpError(w, r, "Failed to export curation", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("media-viewer-curation-%s.json", export.ExportedAt.Format("20060102"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	writeJSON(w, export)
+}
+
+// ImportCuration applies favorites and tags exported by ExportCuration,
+// matching files by path. The "mode" query parameter decides what happens to
+// files that already have favorites or tags: skip, overwrite or merge (the
+// default).
+// POST /api/import/curation
+func (h *Handlers) ImportCuration(w http.ResponseWriter, r *http.Request) {
+	mode, err := database.ParseCurationImportMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var data database.CurationExport
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.db.ImportCuration(r.Context(), &data, mode)
+	if err != nil {
+		if errors.Is(err, database.ErrUnsupportedCurationVersion) || errors.Is(err, database.ErrInvalidCurationMode) ||
+			errors.Is(err, database.ErrInvalidTagColor) {
+			httpError(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logging.Error("Failed to import curation: %v", err)
+		httpError(w, r, "Failed to import curation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, result)
+}
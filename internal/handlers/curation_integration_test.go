@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"media-viewer/internal/database"
+)
+
+func TestCurationExportImportIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	addTestMediaFile(t, h, "photo.jpg", database.FileTypeImage, "test content")
+	if err := h.db.AddFavorite(ctx, "photo.jpg", "photo.jpg", database.FileTypeImage); err != nil {
+		t.Fatalf("AddFavorite failed: %v", err)
+	}
+	if err := h.db.AddTagToFile(ctx, "photo.jpg", "vacation"); err != nil {
+		t.Fatalf("AddTagToFile failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	h.ExportCuration(w, httptest.NewRequest(http.MethodGet, "/api/export/curation", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.HasPrefix(got, `attachment; filename="media-viewer-curation-`) {
+		t.Errorf("expected an attachment, got Content-Disposition %q", got)
+	}
+	exported := w.Body.String()
+
+	// Clear the curation, then restore it from the export
+	if err := h.db.RemoveFavorite(ctx, "photo.jpg"); err != nil {
+		t.Fatalf("RemoveFavorite failed: %v", err)
+	}
+	if err := h.db.RemoveTagFromFile(ctx, "photo.jpg", "vacation"); err != nil {
+		t.Fatalf("RemoveTagFromFile failed: %v", err)
+	}
+
+	importCuration := func(query, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/import/curation"+query, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ImportCuration(w, req)
+		return w
+	}
+
+	w = importCuration("?mode=skip", exported)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result database.CurationImportResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if result.Imported != 1 || result.Mode != database.CurationSkip {
+		t.Errorf("expected one file imported in skip mode, got %+v", result)
+	}
+	if !h.db.IsFavorite(ctx, "photo.jpg") {
+		t.Error("expected the favorite to be restored")
+	}
+	if tags, _ := h.db.GetFileTags(ctx, "photo.jpg"); len(tags) != 1 || tags[0] != "vacation" {
+		t.Errorf("expected the tag to be restored, got %v", tags)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		body  string
+	}{
+		{"invalid mode", "?mode=replace", exported},
+		{"invalid body", "", "not json"},
+		{"unsupported version", "", `{"version":99}`},
+	}
+	for _, tt := range tests {
+		if w := importCuration(tt.query, tt.body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", tt.name, w.Code)
+		}
+	}
+}
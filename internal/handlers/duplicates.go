@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/logging"
+)
+
+// GetDuplicates returns a page of the groups of files with identical
+// content, largest first. Files smaller than the "minSize" query parameter,
+// in bytes, are left out. It's empty unless INDEX_DUPLICATES is enabled.
+// GET /api/duplicates
+func (h *Handlers) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := database.DuplicateOptions{Page: 1, PageSize: 50}
+	if p, err := strconv.Atoi(query.Get("page")); err == nil && p > 0 {
+		opts.Page = p
+	}
+	if ps, err := strconv.Atoi(query.Get("pageSize")); err == nil && ps > 0 {
+		opts.PageSize = ps
+	}
+	if value := query.Get("minSize"); value != "" {
+		minSize, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || minSize < 0 {
+			httpError(w, r, "Invalid minSize", http.StatusBadRequest)
+			return
+		}
+		opts.MinSize = minSize
+	}
+
+	result, err := h.db.GetDuplicates(r.Context(), opts)
+	if err != nil {
+		logging.Error("Failed to get duplicates: %v", err)
+		httpError(w, r, "Failed to get duplicates", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, result)
+}
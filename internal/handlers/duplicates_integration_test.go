@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"media-viewer/internal/database"
+)
+
+func TestGetDuplicatesIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	for _, query := range []string{"minSize=-1", "minSize=big"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/duplicates?"+query, http.NoBody)
+		w := httptest.NewRecorder()
+		h.GetDuplicates(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GetDuplicates(%s) status = %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/duplicates?minSize=1024&page=0&pageSize=500", http.NoBody)
+	w := httptest.NewRecorder()
+	h.GetDuplicates(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var result database.DuplicateResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if result.Groups == nil || result.TotalGroups != 0 || result.Page != 1 || result.PageSize != 200 || result.TotalPages != 1 {
+		t.Errorf("expected an empty first page of 200 groups, got %+v", result)
+	}
+}
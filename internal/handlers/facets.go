@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/logging"
+
+	"github.com/gorilla/mux"
+)
+
+// GetCameraFacets lists the cameras recorded for indexed images with their
+// file counts. It's empty unless INDEX_CAMERA is enabled.
+func (h *Handlers) GetCameraFacets(w http.ResponseWriter, r *http.Request) {
+	h.writeFacetValues(w, r, "cameras", h.db.GetCameraFacets)
+}
+
+// GetLensFacets lists the lenses recorded for indexed images with their file
+// counts. It's empty unless INDEX_CAMERA is enabled.
+func (h *Handlers) GetLensFacets(w http.ResponseWriter, r *http.Request) {
+	h.writeFacetValues(w, r, "lenses", h.db.GetLensFacets)
+}
+
+// GetFilesByCamera returns a page of the files taken with a camera
+func (h *Handlers) GetFilesByCamera(w http.ResponseWriter, r *http.Request) {
+	h.writeFilesByFacet(w, r, "camera", h.db.GetFilesByCamera)
+}
+
+// GetFilesByLens returns a page of the files taken with a lens
+func (h *Handlers) GetFilesByLens(w http.ResponseWriter, r *http.Request) {
+	h.writeFilesByFacet(w, r, "lens", h.db.GetFilesByLens)
+}
+
+// writeFacetValues writes the values of a facet
+func (h *Handlers) writeFacetValues(w http.ResponseWriter, r *http.Request, facet string, get func(context.Context) ([]database.FacetValue, error)) {
+	values, err := get(r.Context())
+	if err != nil {
+		logging.Error("Failed to get %s: %v", facet, err)
+		httpError(w, r, "Failed to get "+facet, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, values)
+}
+
+// writeFilesByFacet writes the page of files with the facet value named in
+// the request path
+func (h *Handlers) writeFilesByFacet(w http.ResponseWriter, r *http.Request, facet string, get func(context.Context, string, int, int) (*database.SearchResult, error)) {
+	name := mux.Vars(r)["name"]
+	if name == "" {
+		httpError(w, r, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	page := 1
+	pageSize := 50
+	if p, err := strconv.Atoi(query.Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	if ps, err := strconv.Atoi(query.Get("pageSize")); err == nil && ps > 0 {
+		pageSize = ps
+	}
+
+	result, err := get(r.Context(), name, page, pageSize)
+	if err != nil {
+		logging.Error("Failed to get files by %s %q: %v", facet, name, err)
+		httpError(w, r, "Failed to get files", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, result)
+}
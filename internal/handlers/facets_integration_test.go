@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+
+	"github.com/gorilla/mux"
+)
+
+// addCameraTestFiles indexes files with the given cameras and lenses
+func addCameraTestFiles(t *testing.T, db *database.Database, files []database.MediaFile) {
+	t.Helper()
+
+	ctx := context.Background()
+	tx, err := db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	for i := range files {
+		files[i].Name = filepath.Base(files[i].Path)
+		files[i].ParentPath = filepath.Dir(files[i].Path)
+		files[i].Type = database.FileTypeImage
+		files[i].ModTime = time.Now()
+		if err := db.UpsertFile(ctx, tx, &files[i]); err != nil {
+			t.Fatalf("failed to insert file: %v", err)
+		}
+	}
+	if err := db.EndBatch(tx, nil); err != nil {
+		t.Fatalf("failed to commit transaction: %v", err)
+	}
+}
+
+func TestCameraFacetsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, _, cleanup := setupTagsIntegrationTest(t)
+	defer cleanup()
+
+	addCameraTestFiles(t, h.db, []database.MediaFile{
+		{Path: "trip/a.jpg", Camera: "NIKON D750", Lens: "24.0-120.0 mm f/4.0"},
+		{Path: "trip/b.jpg", Camera: "NIKON D750"},
+		{Path: "trip/c.jpg", Camera: "NIKON D750"},
+		{Path: "home/d.jpg", Camera: "Apple iPhone 12"},
+		{Path: "home/e.png"},
+	})
+
+	get := func(handler http.HandlerFunc, target string, vars map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		if vars != nil {
+			req = mux.SetURLVars(req, vars)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	w := get(h.GetCameraFacets, "/api/facets/cameras", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetCameraFacets status = %d, body: %s", w.Code, w.Body.String())
+	}
+	var cameras []database.FacetValue
+	if err := json.Unmarshal(w.Body.Bytes(), &cameras); err != nil {
+		t.Fatalf("failed to decode cameras: %v", err)
+	}
+	if len(cameras) != 2 || cameras[0] != (database.FacetValue{Name: "NIKON D750", Count: 3}) {
+		t.Errorf("cameras = %v, want NIKON D750 (3) first of 2", cameras)
+	}
+
+	w = get(h.GetFilesByCamera, "/api/facets/cameras/NIKON%20D750?page=2&pageSize=2", map[string]string{"name": "NIKON D750"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetFilesByCamera status = %d, body: %s", w.Code, w.Body.String())
+	}
+	var result database.SearchResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode files: %v", err)
+	}
+	if result.TotalItems != 3 || result.Page != 2 || len(result.Items) != 1 || result.Items[0].Path != "trip/c.jpg" {
+		t.Errorf("GetFilesByCamera page 2 = %+v, want trip/c.jpg of 3", result)
+	}
+
+	// Lens names often contain slashes, which the route captures
+	w = get(h.GetFilesByLens, "/api/facets/lenses/24.0-120.0%20mm%20f/4.0", map[string]string{"name": "24.0-120.0 mm f/4.0"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetFilesByLens status = %d, body: %s", w.Code, w.Body.String())
+	}
+	result = database.SearchResult{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode files: %v", err)
+	}
+	if len(result.Items) != 1 || result.Items[0].Path != "trip/a.jpg" {
+		t.Errorf("GetFilesByLens items = %v, want trip/a.jpg", result.Items)
+	}
+
+	w = get(h.GetLensFacets, "/api/facets/lenses", nil)
+	if w.Code != http.StatusOK || w.Body.String() != `[{"name":"24.0-120.0 mm f/4.0","count":1}]`+"\n" {
+		t.Errorf("GetLensFacets = %d %q", w.Code, w.Body.String())
+	}
+
+	w = get(h.GetFilesByCamera, "/api/facets/cameras/", map[string]string{"name": ""})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("GetFilesByCamera without a name status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -3,8 +3,11 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"media-viewer/internal/database"
+	"media-viewer/internal/i18n"
+	"media-viewer/internal/logging"
 )
 
 // FavoriteRequest represents a request to manage favorites
@@ -34,13 +37,26 @@ type BulkFavoriteResponse struct {
 	Errors  []string `json:"errors,omitempty"`
 }
 
-// GetFavorites returns all favorite media files
+// favoritesListParams are the query parameters that select the paginated favorites listing
+var favoritesListParams = []string{"page", "pageSize", "sort", "order", "type"}
+
+// GetFavorites returns all favorite media files. With any of the listing
+// parameters (page, pageSize, sort, order, type) it instead returns a page of
+// favorites, sorted and filtered like a directory listing.
 func (h *Handlers) GetFavorites(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	query := r.URL.Query()
+	for _, param := range favoritesListParams {
+		if query.Has(param) {
+			h.listFavorites(w, r)
+			return
+		}
+	}
+
 	favorites, err := h.db.GetFavorites(ctx)
 	if err != nil {
-		http.Error(w, "Failed to get favorites", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get favorites", http.StatusInternalServerError)
 		return
 	}
 
@@ -52,23 +68,65 @@ func (h *Handlers) GetFavorites(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, favorites)
 }
 
+// listFavorites writes a page of favorites
+func (h *Handlers) listFavorites(w http.ResponseWriter, r *http.Request) {
+	listing, err := h.db.ListFavorites(r.Context(), pageListOptions(r))
+	if err != nil {
+		logging.Error("ListFavorites database error: %v", err)
+		httpError(w, r, "Failed to get favorites", http.StatusInternalServerError)
+		return
+	}
+
+	if listing.Items == nil {
+		listing.Items = []database.MediaFile{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, listing)
+}
+
+// pageListOptions reads the page, pageSize, sort, order and type parameters of
+// a paginated listing that isn't of a directory, sorting names for the
+// request's locale
+func pageListOptions(r *http.Request) database.ListOptions {
+	query := r.URL.Query()
+	opts := database.ListOptions{
+		SortField:  database.SortField(query.Get("sort")),
+		SortOrder:  database.SortOrder(query.Get("order")),
+		FilterType: query.Get("type"),
+		Page:       1,
+		PageSize:   50,
+		Locale:     i18n.FromRequest(r),
+	}
+	if page, err := strconv.Atoi(query.Get("page")); err == nil && page > 0 {
+		opts.Page = page
+	}
+	if pageSize, err := strconv.Atoi(query.Get("pageSize")); err == nil && pageSize > 0 {
+		opts.PageSize = pageSize
+	}
+	if opts.SortField != "" && opts.SortOrder == "" {
+		opts.SortOrder = database.SortAsc
+	}
+	return opts
+}
+
 // AddFavorite adds a media file to favorites
 func (h *Handlers) AddFavorite(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var req FavoriteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if req.Path == "" {
-		http.Error(w, "Path is required", http.StatusBadRequest)
+		httpError(w, r, "Path is required", http.StatusBadRequest)
 		return
 	}
 
 	if err := h.db.AddFavorite(ctx, req.Path, req.Name, req.Type); err != nil {
-		http.Error(w, "Failed to add favorite", http.StatusInternalServerError)
+		httpError(w, r, "Failed to add favorite", http.StatusInternalServerError)
 		return
 	}
 
@@ -81,17 +139,17 @@ func (h *Handlers) RemoveFavorite(w http.ResponseWriter, r *http.Request) {
 
 	var req FavoriteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if req.Path == "" {
-		http.Error(w, "Path is required", http.StatusBadRequest)
+		httpError(w, r, "Path is required", http.StatusBadRequest)
 		return
 	}
 
 	if err := h.db.RemoveFavorite(ctx, req.Path); err != nil {
-		http.Error(w, "Failed to remove favorite", http.StatusInternalServerError)
+		httpError(w, r, "Failed to remove favorite", http.StatusInternalServerError)
 		return
 	}
 
@@ -104,12 +162,12 @@ func (h *Handlers) BulkAddFavorites(w http.ResponseWriter, r *http.Request) {
 
 	var req BulkFavoriteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if len(req.Items) == 0 {
-		http.Error(w, "Items array is required", http.StatusBadRequest)
+		httpError(w, r, "Items array is required", http.StatusBadRequest)
 		return
 	}
 
@@ -154,12 +212,12 @@ func (h *Handlers) BulkRemoveFavorites(w http.ResponseWriter, r *http.Request) {
 
 	var req BulkFavoriteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if len(req.Paths) == 0 {
-		http.Error(w, "Paths array is required", http.StatusBadRequest)
+		httpError(w, r, "Paths array is required", http.StatusBadRequest)
 		return
 	}
 
@@ -204,7 +262,7 @@ func (h *Handlers) CheckFavorite(w http.ResponseWriter, r *http.Request) {
 
 	path := r.URL.Query().Get("path")
 	if path == "" {
-		http.Error(w, "Path is required", http.StatusBadRequest)
+		httpError(w, r, "Path is required", http.StatusBadRequest)
 		return
 	}
 
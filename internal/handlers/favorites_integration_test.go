@@ -174,6 +174,58 @@ func TestGetFavoritesWithDataIntegration(t *testing.T) {
 	}
 }
 
+func TestGetFavoritesPaginatedIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupFavoritesIntegrationTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for _, name := range []string{"c.jpg", "a.jpg", "b.mp4"} {
+		typ := database.FileTypeImage
+		if filepath.Ext(name) == ".mp4" {
+			typ = database.FileTypeVideo
+		}
+		addTestFile(t, h.db, "/media/"+name, name, typ)
+		if err := h.db.AddFavorite(ctx, "/media/"+name, name, typ); err != nil {
+			t.Fatalf("Failed to add favorite: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/favorites?sort=name&pageSize=2&type=image", http.NoBody)
+	w := httptest.NewRecorder()
+
+	h.GetFavorites(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var listing database.FavoritesListing
+	if err := json.NewDecoder(w.Body).Decode(&listing); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if listing.TotalItems != 2 || listing.Page != 1 || listing.PageSize != 2 || listing.TotalPages != 1 {
+		t.Errorf("Unexpected pagination: %+v", listing)
+	}
+	if len(listing.Items) != 2 || listing.Items[0].Name != "a.jpg" || listing.Items[1].Name != "c.jpg" {
+		t.Errorf("Expected a.jpg and c.jpg sorted by name, got %+v", listing.Items)
+	}
+
+	// An empty page is an empty array
+	req = httptest.NewRequest(http.MethodGet, "/api/favorites?page=5", http.NoBody)
+	w = httptest.NewRecorder()
+
+	h.GetFavorites(w, req)
+
+	if body := w.Body.String(); !strings.Contains(body, `"items":[]`) {
+		t.Errorf("Expected an empty items array, got %s", body)
+	}
+}
+
 // =============================================================================
 // Add Favorite Tests
 // =============================================================================
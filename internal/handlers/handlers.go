@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"time"
+
 	"media-viewer/internal/database"
 	"media-viewer/internal/indexer"
+	"media-viewer/internal/logging"
 	"media-viewer/internal/media"
 	"media-viewer/internal/startup"
 	"media-viewer/internal/transcoder"
+
+	"github.com/gorilla/mux"
 )
 
 // Handlers contains all HTTP request handlers and their dependencies.
@@ -16,10 +21,41 @@ type Handlers struct {
 	thumbGen   *media.ThumbnailGenerator
 	mediaDir   string
 	cacheDir   string
+	publicMode bool
+	svgMode    svgMode // How original SVG files are served
+
+	// Routes anonymous visitors may use in public mode, marked with Public
+	// while the router is set up
+	publicRoutes map[*mux.Route]bool
+
+	// Bound on ?wait=true thumbnail requests; 0 waits while the client is connected
+	thumbnailWaitTimeout time.Duration
+
+	// Whether thumbnail responses carry the thumbnail's dimensions
+	thumbnailDimensions bool
+
+	// Bandwidth cap for each file or video stream in bytes per second; 0 is unlimited
+	streamMaxBytesPerSec int64
+
+	// "Did you mean" suggestions returned for searches that find nothing; 0 disables them
+	searchDidYouMean int
+
+	// Where deleted files are kept, and for how long; 0 keeps them until restored
+	trashDir string
+	trashTTL time.Duration
+
+	// Applies reloadable configuration to running components (set by main)
+	configReloader ConfigReloader
 }
 
 // New creates a new Handlers instance with the given dependencies.
 func New(db *database.Database, idx *indexer.Indexer, trans *transcoder.Transcoder, thumbGen *media.ThumbnailGenerator, config *startup.Config) *Handlers {
+	svgMode, err := parseSVGMode(config.SVGSafety)
+	if err != nil {
+		logging.Warn("Invalid SVG_SAFETY, using %q: %v", svgModeSandbox, err)
+		svgMode = svgModeSandbox
+	}
+
 	return &Handlers{
 		db:         db,
 		indexer:    idx,
@@ -27,5 +63,19 @@ func New(db *database.Database, idx *indexer.Indexer, trans *transcoder.Transcod
 		thumbGen:   thumbGen,
 		mediaDir:   config.MediaDir,
 		cacheDir:   config.CacheDir,
+		publicMode: config.PublicMode,
+		svgMode:    svgMode,
+
+		thumbnailWaitTimeout: config.ThumbnailWaitTimeout,
+		thumbnailDimensions:  config.ThumbnailDimensions,
+		streamMaxBytesPerSec: config.StreamMaxBytesPerSec,
+		searchDidYouMean:     config.SearchDidYouMean,
+		trashDir:             config.TrashDir,
+		trashTTL:             config.TrashTTL,
 	}
 }
+
+// SetConfigReloader sets the function used by POST /api/admin/reload.
+func (h *Handlers) SetConfigReloader(reloader ConfigReloader) {
+	h.configReloader = reloader
+}
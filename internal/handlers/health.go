@@ -26,6 +26,7 @@ type HealthResponse struct {
 	// Progress info
 	FilesIndexed   int64 `json:"filesIndexed"`
 	FoldersIndexed int64 `json:"foldersIndexed"`
+	UnreadableDirs int   `json:"unreadableDirs,omitempty"`
 
 	// System info
 	GoVersion    string `json:"goVersion"`
@@ -49,6 +50,7 @@ func (h *Handlers) HealthCheck(w http.ResponseWriter, _ *http.Request) {
 		Indexing:       healthStatus.Indexing,
 		FilesIndexed:   healthStatus.FilesIndexed,
 		FoldersIndexed: healthStatus.FoldersIndexed,
+		UnreadableDirs: healthStatus.UnreadableDirs,
 		GoVersion:      runtime.Version(),
 		NumCPU:         runtime.NumCPU(),
 		NumGoroutine:   runtime.NumGoroutine(),
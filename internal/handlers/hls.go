@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"media-viewer/internal/logging"
+	"media-viewer/internal/streaming"
+	"media-viewer/internal/transcoder"
+)
+
+// GetHLS serves a video as HLS: its playlist, which starts transcoding it
+// into segments in the transcode cache if it isn't there already, and the
+// init and media segments the playlist lists. Takes ?width, ?maxBytesPerSec
+// and ?reveal as StreamVideo does. With TRANSCODE_HLS_PREFETCH set, a
+// segment request resumes a transcode that stopped early.
+// GET /api/hls/{path}/playlist.m3u8
+// GET /api/hls/{path}/{segment}
+func (h *Handlers) GetHLS(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	filePath := vars["path"]
+	name := vars["segment"]
+
+	// Reject absolute paths before joining
+	if filepath.IsAbs(filePath) {
+		httpError(w, r, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	fullPath := filepath.Join(h.mediaDir, filePath)
+
+	absPath, err := filepath.Abs(fullPath)
+	if err != nil || !isSubPath(h.mediaDir, absPath) {
+		logging.Warn("GetHLS: Invalid path attempted: %s", filePath)
+		httpError(w, r, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	retryConfig := DefaultNFSRetryConfig()
+	if _, err := StatWithRetry(fullPath, retryConfig); err != nil {
+		if os.IsNotExist(err) {
+			httpError(w, r, "File not found", http.StatusNotFound)
+		} else {
+			logging.Error("GetHLS: Failed to access file %s: %v", fullPath, err)
+			httpError(w, r, "Failed to access file", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Like GetFile, flagged videos are only streamed with ?reveal=true
+	if !revealsSensitive(r) && h.isSensitive(ctx, filePath) {
+		httpError(w, r, "File is flagged as sensitive", http.StatusForbidden)
+		return
+	}
+
+	targetWidth := 0
+	if widthStr := r.URL.Query().Get("width"); widthStr != "" {
+		targetWidth, _ = strconv.Atoi(widthStr)
+	}
+
+	rate, ok := h.streamRate(r)
+	if !ok {
+		httpError(w, r, "Invalid maxBytesPerSec", http.StatusBadRequest)
+		return
+	}
+
+	if name != transcoder.HLSPlaylistName {
+		// Also when the segment is missing, so the player's retries find it
+		h.transcoder.PrefetchHLS(fullPath, targetWidth, name)
+
+		segmentPath, err := h.transcoder.HLSFilePath(fullPath, targetWidth, name)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				logging.Error("GetHLS: Failed to find %s of %s: %v", name, fullPath, err)
+			}
+			httpError(w, r, "Segment not found", http.StatusNotFound)
+			return
+		}
+		http.ServeFile(streaming.NewThrottledWriter(ctx, w, rate), r, segmentPath)
+		return
+	}
+
+	info, err := h.transcoder.GetVideoInfo(ctx, fullPath)
+	if err != nil {
+		logging.Error("GetHLS: Failed to get video info for %s: %v", fullPath, err)
+		httpError(w, r, "Failed to get video info", http.StatusInternalServerError)
+		return
+	}
+
+	playlistPath, err := h.transcoder.GetOrStartHLS(ctx, fullPath, targetWidth, info)
+	if err != nil {
+		logging.Error("Failed to prepare HLS for %s: %v", filePath, err)
+		httpError(w, r, "Failed to prepare video", http.StatusInternalServerError)
+		return
+	}
+
+	playlist, err := os.ReadFile(playlistPath) // #nosec G304 -- path is built by the transcoder within its cache
+	if err != nil {
+		logging.Error("GetHLS: Failed to read playlist %s: %v", playlistPath, err)
+		httpError(w, r, "Failed to prepare video", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	// The playlist grows until the transcode finishes, so players reload it
+	w.Header().Set("Cache-Control", "no-cache")
+	if _, err := w.Write(withSegmentQuery(playlist, hlsSegmentQuery(r))); err != nil {
+		logging.Debug("GetHLS: Failed to write playlist: %v", err)
+	}
+}
+
+// hlsSegmentQuery returns the query a playlist request's segments must be
+// requested with to be found and served the same way: its width, bandwidth
+// and reveal.
+func hlsSegmentQuery(r *http.Request) string {
+	query := url.Values{}
+	for _, key := range []string{"width", "maxBytesPerSec", "reveal"} {
+		if r.URL.Query().Has(key) {
+			query.Set(key, r.URL.Query().Get(key))
+		}
+	}
+	return query.Encode()
+}
+
+// withSegmentQuery appends query to the URIs in an HLS playlist: the segment
+// lines and the init segment of #EXT-X-MAP. The URIs are relative, and
+// resolving them against the playlist's URL drops its query.
+func withSegmentQuery(playlist []byte, query string) []byte {
+	if query == "" {
+		return playlist
+	}
+
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(playlist))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			if start := strings.Index(line, `URI="`); start >= 0 {
+				if end := strings.IndexByte(line[start+5:], '"'); end >= 0 {
+					at := start + 5 + end
+					line = line[:at] + "?" + query + line[at:]
+				}
+			}
+		case line != "" && !strings.HasPrefix(line, "#"):
+			line += "?" + query
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestGetHLSIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	if err := os.WriteFile(filepath.Join(h.mediaDir, "clip.mkv"), []byte("test"), 0o644); err != nil {
+		t.Fatalf("failed to create video: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		segment  string
+		query    string
+		expected int
+	}{
+		{"outside media dir", "../etc/passwd", "playlist.m3u8", "", http.StatusBadRequest},
+		{"absolute path", "/etc/passwd", "playlist.m3u8", "", http.StatusBadRequest},
+		{"missing video", "missing.mkv", "playlist.m3u8", "", http.StatusNotFound},
+		{"invalid bandwidth", "clip.mkv", "segment_00000.m4s", "?maxBytesPerSec=-1", http.StatusBadRequest},
+		{"not an HLS file", "clip.mkv", "playlist.m3u8.json", "", http.StatusNotFound},
+		{"segment not written", "clip.mkv", "segment_00000.m4s", "?width=640", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/hls/clip.mkv/"+tt.segment+tt.query, http.NoBody)
+			req = mux.SetURLVars(req, map[string]string{"path": tt.path, "segment": tt.segment})
+			w := httptest.NewRecorder()
+			h.GetHLS(w, req)
+			if w.Code != tt.expected {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.expected, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithSegmentQuery(t *testing.T) {
+	t.Parallel()
+
+	playlist := "#EXTM3U\n" +
+		"#EXT-X-VERSION:7\n" +
+		"#EXT-X-TARGETDURATION:6\n" +
+		"#EXT-X-PLAYLIST-TYPE:EVENT\n" +
+		"#EXT-X-MAP:URI=\"init.mp4\"\n" +
+		"#EXTINF:6.000000,\n" +
+		"segment_00000.m4s\n" +
+		"#EXTINF:6.000000,\n" +
+		"segment_00001.m4s\n"
+
+	got := string(withSegmentQuery([]byte(playlist), "width=1280"))
+	want := "#EXTM3U\n" +
+		"#EXT-X-VERSION:7\n" +
+		"#EXT-X-TARGETDURATION:6\n" +
+		"#EXT-X-PLAYLIST-TYPE:EVENT\n" +
+		"#EXT-X-MAP:URI=\"init.mp4?width=1280\"\n" +
+		"#EXTINF:6.000000,\n" +
+		"segment_00000.m4s?width=1280\n" +
+		"#EXTINF:6.000000,\n" +
+		"segment_00001.m4s?width=1280\n"
+	if got != want {
+		t.Errorf("withSegmentQuery() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := string(withSegmentQuery([]byte(playlist), "")); got != playlist {
+		t.Errorf("withSegmentQuery() without a query changed the playlist:\n%s", got)
+	}
+}
+
+func TestHLSSegmentQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"/api/hls/a.mkv/playlist.m3u8", ""},
+		{"/api/hls/a.mkv/playlist.m3u8?width=640", "width=640"},
+		{"/api/hls/a.mkv/playlist.m3u8?width=640&maxBytesPerSec=0&nocache=true", "maxBytesPerSec=0&width=640"},
+		{"/api/hls/a.mkv/playlist.m3u8?width=%22%0A", "width=%22%0A"},
+		{"/api/hls/a.mkv/playlist.m3u8?reveal=true", "reveal=true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			t.Parallel()
+			r := httptest.NewRequest(http.MethodGet, tt.url, http.NoBody)
+			if got := hlsSegmentQuery(r); got != tt.expected {
+				t.Errorf("hlsSegmentQuery() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
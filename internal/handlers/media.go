@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"crypto/md5" //nolint:gosec // MD5 used for cache key generation, not security
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -9,9 +14,15 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"media-viewer/internal/database"
+	"media-viewer/internal/i18n"
+	"media-viewer/internal/indexer"
 	"media-viewer/internal/logging"
+	"media-viewer/internal/media"
+	"media-viewer/internal/mediatypes"
+	"media-viewer/internal/streaming"
 
 	"github.com/gorilla/mux"
 )
@@ -28,8 +39,16 @@ func (h *Handlers) ListFiles(w http.ResponseWriter, r *http.Request) {
 		FilterType: r.URL.Query().Get("type"),
 		Page:       1,
 		PageSize:   50, // Match frontend infinite scroll batch size
+		Locale:     i18n.FromRequest(r),
 	}
 
+	presence, err := parsePresenceFilter(r.URL.Query())
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.Presence = presence
+
 	if page, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && page > 0 {
 		opts.Page = page
 	}
@@ -44,13 +63,13 @@ func (h *Handlers) ListFiles(w http.ResponseWriter, r *http.Request) {
 		opts.SortOrder = database.SortAsc
 	}
 
-	logging.Debug("ListFiles options: path=%q, sort=%s, order=%s, page=%d, pageSize=%d",
-		opts.Path, opts.SortField, opts.SortOrder, opts.Page, opts.PageSize)
+	logging.Debug("ListFiles options: path=%q, sort=%s, order=%s, page=%d, pageSize=%d, %s",
+		opts.Path, opts.SortField, opts.SortOrder, opts.Page, opts.PageSize, opts.Presence)
 
 	listing, err := h.db.ListDirectory(ctx, opts)
 	if err != nil {
 		logging.Error("ListFiles database error: %v", err)
-		http.Error(w, "Failed to list directory", http.StatusInternalServerError)
+		httpError(w, r, "Failed to list directory", http.StatusInternalServerError)
 		return
 	}
 
@@ -62,7 +81,7 @@ func (h *Handlers) ListFiles(w http.ResponseWriter, r *http.Request) {
 	logging.Debug("ListFiles completed, found %d items", len(listing.Items))
 
 	// Generate ETag based on directory state for HTTP caching
-	// Include: path, sort, filter, page, pageSize, count, and latest modification time
+	// Include: path, sort, locale, filters, page, pageSize, count, and latest modification time
 	lastModTime := int64(0)
 	for i := range listing.Items {
 		if listing.Items[i].ModTime.Unix() > lastModTime {
@@ -70,20 +89,26 @@ func (h *Handlers) ListFiles(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	etagData := fmt.Sprintf("%s_%s_%s_%s_%d_%d_%d_%d_%d",
-		opts.Path, opts.SortField, opts.SortOrder, opts.FilterType,
+	etagData := fmt.Sprintf("%s_%s_%s_%s_%s_%s_%d_%d_%d_%d_%d",
+		opts.Path, opts.SortField, opts.SortOrder, opts.Locale, opts.FilterType, opts.Presence,
 		opts.Page, opts.PageSize, listing.TotalItems, len(listing.Items), lastModTime)
 	etag := fmt.Sprintf(`"%x"`, md5.Sum([]byte(etagData))) //nolint:gosec // MD5 used for cache key generation, not security
 
 	// Set cache headers
 	// Use "private" since response may include user-specific data
 	// max-age=300 (5 minutes) balances freshness with caching benefit
+	// Name order depends on the locale, so vary on what selects it
 	w.Header().Set("Cache-Control", "private, max-age=300, must-revalidate")
 	w.Header().Set("ETag", etag)
+	w.Header().Add("Vary", "Accept-Language, Cookie")
+
+	if bypassCache(r) {
+		w.Header().Set("Cache-Control", "no-store")
+	}
 
 	// Check If-None-Match header for conditional request
 	clientETag := r.Header.Get("If-None-Match")
-	if clientETag != "" && clientETag == etag {
+	if clientETag != "" && clientETag == etag && !bypassCache(r) {
 		logging.Debug("ListFiles: 304 Not Modified for %s (ETag match: %s)", opts.Path, etag)
 		w.WriteHeader(http.StatusNotModified)
 		return
@@ -95,6 +120,56 @@ func (h *Handlers) ListFiles(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, listing)
 }
 
+// FolderOrderRequest sets the manual sort order of a folder
+type FolderOrderRequest struct {
+	Path  string   `json:"path"`
+	Paths []string `json:"paths"`
+}
+
+// GetFolderOrder returns the manually ordered paths of a folder
+func (h *Handlers) GetFolderOrder(w http.ResponseWriter, r *http.Request) {
+	folderPath := r.URL.Query().Get("path")
+
+	paths, err := h.db.GetFolderOrder(r.Context(), folderPath)
+	if err != nil {
+		logging.Error("GetFolderOrder error for %s: %v", folderPath, err)
+		httpError(w, r, "Failed to get folder order", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, FolderOrderRequest{Path: folderPath, Paths: paths})
+}
+
+// SetFolderOrder stores the manual sort order of a folder, used when listing
+// with sort=manual. Items not listed sort after the ordered ones by name; an
+// empty list clears the order.
+func (h *Handlers) SetFolderOrder(w http.ResponseWriter, r *http.Request) {
+	var req FolderOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	maxPaths := 10000
+	if len(req.Paths) > maxPaths {
+		httpError(w, r, fmt.Sprintf("Too many paths (max %d)", maxPaths), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.SetFolderOrder(r.Context(), req.Path, req.Paths); err != nil {
+		if errors.Is(err, database.ErrInvalidFolderOrder) {
+			httpError(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logging.Error("SetFolderOrder error for %s: %v", req.Path, err)
+		httpError(w, r, "Failed to set folder order", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSONStatus(w, "ok")
+}
+
 // GetMediaFiles returns all media files (images and videos) in a directory for lightbox viewing
 func (h *Handlers) GetMediaFiles(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -110,12 +185,14 @@ func (h *Handlers) GetMediaFiles(w http.ResponseWriter, r *http.Request) {
 		sortOrder = database.SortAsc
 	}
 
-	logging.Debug("GetMediaFiles: path=%s, sort=%s, order=%s", parentPath, sortField, sortOrder)
+	locale := i18n.FromRequest(r)
 
-	files, err := h.db.GetMediaInDirectory(ctx, parentPath, sortField, sortOrder)
+	logging.Debug("GetMediaFiles: path=%s, sort=%s, order=%s, locale=%s", parentPath, sortField, sortOrder, locale)
+
+	files, err := h.db.GetMediaInDirectoryForLocale(ctx, parentPath, sortField, sortOrder, locale)
 	if err != nil {
 		logging.Error("GetMediaFiles error: %v", err)
-		http.Error(w, "Failed to get media files", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get media files", http.StatusInternalServerError)
 		return
 	}
 
@@ -124,7 +201,7 @@ func (h *Handlers) GetMediaFiles(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate ETag based on directory state for HTTP caching
-	// Include: path, sort params, file count, and latest modification time
+	// Include: path, sort params, locale, file count, and latest modification time
 	// This ensures the ETag changes when directory contents change
 	lastModTime := int64(0)
 	for i := range files {
@@ -133,7 +210,7 @@ func (h *Handlers) GetMediaFiles(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	etagData := fmt.Sprintf("%s_%s_%s_%d_%d", parentPath, sortField, sortOrder, len(files), lastModTime)
+	etagData := fmt.Sprintf("%s_%s_%s_%s_%d_%d", parentPath, sortField, sortOrder, locale, len(files), lastModTime)
 	etag := fmt.Sprintf(`"%x"`, md5.Sum([]byte(etagData))) //nolint:gosec // MD5 used for cache key generation, not security
 
 	// Set cache headers
@@ -142,10 +219,15 @@ func (h *Handlers) GetMediaFiles(w http.ResponseWriter, r *http.Request) {
 	// must-revalidate ensures stale cache is revalidated
 	w.Header().Set("Cache-Control", "private, max-age=300, must-revalidate")
 	w.Header().Set("ETag", etag)
+	w.Header().Add("Vary", "Accept-Language, Cookie")
+
+	if bypassCache(r) {
+		w.Header().Set("Cache-Control", "no-store")
+	}
 
 	// Check If-None-Match header for conditional request
 	clientETag := r.Header.Get("If-None-Match")
-	if clientETag != "" && clientETag == etag {
+	if clientETag != "" && clientETag == etag && !bypassCache(r) {
 		logging.Debug("GetMediaFiles: 304 Not Modified for %s (ETag match: %s)", parentPath, etag)
 		w.WriteHeader(http.StatusNotModified)
 		return
@@ -164,7 +246,7 @@ func (h *Handlers) GetFile(w http.ResponseWriter, r *http.Request) {
 
 	// Reject absolute paths before joining
 	if filepath.IsAbs(filePath) {
-		http.Error(w, "Invalid path", http.StatusBadRequest)
+		httpError(w, r, "Invalid path", http.StatusBadRequest)
 		return
 	}
 
@@ -172,7 +254,14 @@ func (h *Handlers) GetFile(w http.ResponseWriter, r *http.Request) {
 
 	absPath, err := filepath.Abs(fullPath)
 	if err != nil || !isSubPath(h.mediaDir, absPath) {
-		http.Error(w, "Invalid path", http.StatusBadRequest)
+		httpError(w, r, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	// Files flagged as sensitive are only served when asked for with
+	// ?reveal=true, which anonymous visitors in public mode can't send
+	if !revealsSensitive(r) && h.isSensitive(r.Context(), filePath) {
+		httpError(w, r, "File is flagged as sensitive", http.StatusForbidden)
 		return
 	}
 
@@ -191,7 +280,51 @@ func (h *Handlers) GetFile(w http.ResponseWriter, r *http.Request) {
 	default:
 	}
 
-	http.ServeFile(w, r, fullPath)
+	// Use the MIME type the index records for the extension, so e.g. ".HEIC"
+	// and ".heic" are served alike whatever the system MIME table contains
+	if mimeType, ok := mediatypes.MimeTypes[mediatypes.NormalizeExt(fullPath)]; ok {
+		w.Header().Set("Content-Type", mimeType)
+	}
+
+	if isSVGFile(fullPath) {
+		h.serveSVG(w, r, fullPath)
+		return
+	}
+
+	rate, ok := h.streamRate(r)
+	if !ok {
+		httpError(w, r, "Invalid maxBytesPerSec", http.StatusBadRequest)
+		return
+	}
+
+	f, err := OpenWithRetry(fullPath, DefaultNFSRetryConfig())
+	if err != nil {
+		if os.IsNotExist(err) {
+			httpError(w, r, "File not found", http.StatusNotFound)
+		} else {
+			logging.Error("Failed to open file %s: %v", filePath, err)
+			httpError(w, r, "Failed to access file", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		logging.Error("Failed to stat file %s: %v", filePath, err)
+		httpError(w, r, "Failed to access file", http.StatusInternalServerError)
+		return
+	}
+	if info.IsDir() {
+		httpError(w, r, "File not found", http.StatusNotFound)
+		return
+	}
+
+	// ServeContent answers Range and If-Range requests, so interrupted
+	// downloads can resume. The ETag changes whenever the file does, which
+	// keeps a resumed download from splicing two versions of a file.
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	http.ServeContent(streaming.NewThrottledWriter(r.Context(), w, rate), r, info.Name(), info.ModTime(), f)
 }
 
 // validateThumbnailPath validates and resolves the thumbnail file path from the request.
@@ -208,13 +341,13 @@ func (h *Handlers) validateThumbnailPath(w http.ResponseWriter, r *http.Request)
 
 	if filePath == "" {
 		logging.Error("Thumbnail: empty path")
-		http.Error(w, "Path is required", http.StatusBadRequest)
+		httpError(w, r, "Path is required", http.StatusBadRequest)
 		return "", "", false
 	}
 
 	if filepath.IsAbs(filePath) {
 		logging.Error("Thumbnail: absolute path not allowed: %s", filePath)
-		http.Error(w, "Invalid path", http.StatusBadRequest)
+		httpError(w, r, "Invalid path", http.StatusBadRequest)
 		return "", "", false
 	}
 
@@ -223,14 +356,14 @@ func (h *Handlers) validateThumbnailPath(w http.ResponseWriter, r *http.Request)
 	absPath, err := filepath.Abs(fullPath)
 	if err != nil {
 		logging.Error("Thumbnail: failed to resolve path %s: %v", filePath, err)
-		http.Error(w, "Invalid path", http.StatusBadRequest)
+		httpError(w, r, "Invalid path", http.StatusBadRequest)
 		return "", "", false
 	}
 
 	absMediaDir, _ := filepath.Abs(h.mediaDir)
 	if !strings.HasPrefix(absPath, absMediaDir) {
 		logging.Error("Thumbnail: path outside media dir: %s", filePath)
-		http.Error(w, "Invalid path", http.StatusBadRequest)
+		httpError(w, r, "Invalid path", http.StatusBadRequest)
 		return "", "", false
 	}
 
@@ -239,23 +372,23 @@ func (h *Handlers) validateThumbnailPath(w http.ResponseWriter, r *http.Request)
 
 // validateThumbnailFileOnDisk checks that a non-folder file exists on disk and is not a directory.
 // Returns true if valid, or writes an HTTP error and returns false.
-func (h *Handlers) validateThumbnailFileOnDisk(w http.ResponseWriter, _, fullPath string) bool {
+func (h *Handlers) validateThumbnailFileOnDisk(w http.ResponseWriter, r *http.Request, _, fullPath string) bool {
 	retryConfig := DefaultNFSRetryConfig()
 	fileInfo, err := StatWithRetry(fullPath, retryConfig)
 	if err != nil {
 		if os.IsNotExist(err) {
 			logging.Warn("Thumbnail: file not found: %s", fullPath)
-			http.Error(w, "File not found", http.StatusNotFound)
+			httpError(w, r, "File not found", http.StatusNotFound)
 		} else {
 			logging.Error("Thumbnail: failed to stat file %s: %v", fullPath, err)
-			http.Error(w, "Failed to access file", http.StatusInternalServerError)
+			httpError(w, r, "Failed to access file", http.StatusInternalServerError)
 		}
 		return false
 	}
 
 	if fileInfo.IsDir() {
 		logging.Warn("Thumbnail: path is a directory but not marked as folder in DB: %s", fullPath)
-		http.Error(w, "Invalid file type", http.StatusBadRequest)
+		httpError(w, r, "Invalid file type", http.StatusBadRequest)
 		return false
 	}
 
@@ -264,37 +397,51 @@ func (h *Handlers) validateThumbnailFileOnDisk(w http.ResponseWriter, _, fullPat
 
 // isThumbnailSupported checks whether the given file type supports thumbnail generation.
 // Returns true if supported, or writes an HTTP error and returns false.
-func isThumbnailSupported(w http.ResponseWriter, filePath string, fileType database.FileType) bool {
+func isThumbnailSupported(w http.ResponseWriter, r *http.Request, filePath string, fileType database.FileType) bool {
 	switch fileType {
 	case database.FileTypeImage, database.FileTypeVideo, database.FileTypeFolder:
 		return true
 	default:
 		logging.Warn("Thumbnail: unsupported file type %s for %s", fileType, filePath)
-		http.Error(w, "Unsupported file type", http.StatusBadRequest)
+		httpError(w, r, "Unsupported file type", http.StatusBadRequest)
 		return false
 	}
 }
 
+// otherFileForThumbnail returns a record standing in for a file the indexer
+// skips as not media, or nil unless thumbnails are drawn for such files
+func (h *Handlers) otherFileForThumbnail(filePath string) *database.MediaFile {
+	if !h.thumbGen.OtherThumbnailsEnabled() || mediatypes.GetFileType(mediatypes.NormalizeExt(filePath)) != mediatypes.FileTypeOther {
+		return nil
+	}
+	return &database.MediaFile{
+		Name:       filepath.Base(filePath),
+		Path:       filePath,
+		ParentPath: filepath.Dir(filePath),
+		Type:       database.FileTypeOther,
+	}
+}
+
 // writeThumbnailResponse sets caching headers, handles conditional requests, validates
 // the thumbnail data, and writes it to the response.
-func writeThumbnailResponse(w http.ResponseWriter, r *http.Request, filePath string, fileType database.FileType, thumb []byte) {
+func writeThumbnailResponse(w http.ResponseWriter, r *http.Request, filePath string, fileType database.FileType, format media.ThumbnailFormat, thumb []byte) {
 	etag := fmt.Sprintf(`"%x"`, md5.Sum(thumb)) //nolint:gosec // MD5 used for cache key generation, not security
 
-	// Set content type based on file type
-	if fileType == database.FileTypeFolder {
-		w.Header().Set("Content-Type", "image/png")
-	} else {
-		w.Header().Set("Content-Type", "image/jpeg")
-	}
+	// The format is negotiated from the Accept header, so shared caches must key on it
+	w.Header().Set("Content-Type", format.ContentType(fileType))
+	w.Header().Add("Vary", "Accept")
 
 	// Prevent browsers from MIME-sniffing the response into an executable type (XSS mitigation)
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 	w.Header().Set("Content-Security-Policy", "default-src 'none'")
 
 	// Cache headers - shorter cache for folders since they can change
-	if fileType == database.FileTypeFolder {
+	switch {
+	case w.Header().Get("Cache-Control") != "":
+		// Set by the caller, as for sensitive files
+	case fileType == database.FileTypeFolder:
 		w.Header().Set("Cache-Control", "public, max-age=300, must-revalidate")
-	} else {
+	default:
 		w.Header().Set("Cache-Control", "public, max-age=86400")
 	}
 
@@ -302,7 +449,7 @@ func writeThumbnailResponse(w http.ResponseWriter, r *http.Request, filePath str
 
 	// Check If-None-Match header for 304 Not Modified
 	if clientETag := r.Header.Get("If-None-Match"); clientETag != "" {
-		if clientETag == etag {
+		if etagMatches(clientETag, etag) {
 			logging.Debug("Thumbnail: 304 Not Modified for %s (ETag match: %s)", filePath, etag)
 			w.WriteHeader(http.StatusNotModified)
 			return
@@ -323,15 +470,28 @@ func writeThumbnailResponse(w http.ResponseWriter, r *http.Request, filePath str
 		return
 	}
 
-	//nolint:gosec // G705: thumb is validated above — isValidImageHeader confirms JPEG/PNG magic bytes,
-	// Content-Type is explicitly set to image/jpeg or image/png, and X-Content-Type-Options: nosniff
-	// prevents browser MIME-sniffing. This is safe binary image data, not user-controlled text.
-	if _, err := w.Write(thumb); err != nil {
-		logging.Error("failed to write thumbnail response: %v", err)
+	// ServeContent answers Range and If-Range requests against the ETag.
+	// thumb is validated above — isValidImageHeader confirms JPEG/PNG/WebP/AVIF
+	// magic bytes, Content-Type is explicitly set to the matching image type, and
+	// X-Content-Type-Options: nosniff prevents browser MIME-sniffing.
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(thumb))
+}
+
+// etagMatches reports whether an If-None-Match header matches etag: it is
+// "*" or lists etag. Comparison is weak, as RFC 9110 requires for
+// If-None-Match, so a W/ prefix added by a proxy still matches.
+func etagMatches(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
 	}
+	return false
 }
 
-// isValidImageHeader checks if the byte slice starts with a known image format header (JPEG or PNG).
+// isValidImageHeader checks if the byte slice starts with a known image format header (JPEG, PNG, WebP or AVIF).
 func isValidImageHeader(data []byte) bool {
 	// PNG: 8-byte header
 	if len(data) >= 8 && string(data[:8]) == "\x89PNG\r\n\x1a\n" {
@@ -341,6 +501,14 @@ func isValidImageHeader(data []byte) bool {
 	if len(data) >= 2 && data[0] == 0xFF && data[1] == 0xD8 {
 		return true
 	}
+	// WebP: RIFF container with WEBP form type
+	if len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP" {
+		return true
+	}
+	// AVIF: ISO BMFF ftyp box with an AVIF brand
+	if len(data) >= 12 && string(data[4:8]) == "ftyp" && (string(data[8:12]) == "avif" || string(data[8:12]) == "avis") {
+		return true
+	}
 	return false
 }
 
@@ -357,38 +525,207 @@ func (h *Handlers) GetThumbnail(w http.ResponseWriter, r *http.Request) {
 
 	if !h.thumbGen.IsEnabled() {
 		logging.Warn("Thumbnail: thumbnails disabled, returning 503")
-		http.Error(w, "Thumbnails disabled", http.StatusServiceUnavailable)
+		httpError(w, r, "Thumbnails disabled", http.StatusServiceUnavailable)
 		return
 	}
 
 	// Get file info from database to determine type
 	file, err := h.db.GetFileByPath(ctx, filePath)
 	if err != nil {
-		logging.Error("Thumbnail: file not found in database %s: %v", filePath, err)
-		http.Error(w, "File not found", http.StatusNotFound)
-		return
+		file = h.otherFileForThumbnail(filePath)
+		if file == nil {
+			logging.Error("Thumbnail: file not found in database %s: %v", filePath, err)
+			httpError(w, r, "File not found", http.StatusNotFound)
+			return
+		}
 	}
 
 	// Validate file exists on disk (skip for folders as they're handled differently)
 	if file.Type != database.FileTypeFolder {
-		if !h.validateThumbnailFileOnDisk(w, filePath, fullPath) {
+		if !h.validateThumbnailFileOnDisk(w, r, filePath, fullPath) {
 			return
 		}
 	}
 
-	if !isThumbnailSupported(w, filePath, file.Type) {
+	otherEnabled := file.Type == database.FileTypeOther && h.thumbGen.OtherThumbnailsEnabled()
+	if !otherEnabled && !isThumbnailSupported(w, r, filePath, file.Type) {
+		return
+	}
+
+	// High-DPI displays send their device pixel ratio for a sharper thumbnail
+	scale, err := media.ParseThumbnailScale(r.URL.Query().Get("dpr"))
+	if err != nil {
+		httpError(w, r, "Invalid dpr", http.StatusBadRequest)
 		return
 	}
 
-	// Generate or retrieve cached thumbnail
-	thumb, err := h.thumbGen.GetThumbnail(ctx, fullPath, file.Type)
+	// Responsive images ask for a size, multiplied by the ratio like the regular size
+	size, err := media.ParseRequestedSize(r.URL.Query().Get("size"))
+	if err != nil {
+		httpError(w, r, "Invalid size", http.StatusBadRequest)
+		return
+	}
+	size *= scale
+
+	// A cache bypass regenerates the thumbnail; it is cached again as usual
+	if bypassCache(r) {
+		logging.Info("Thumbnail: regenerating %s (cache bypass requested)", filePath)
+		if _, err := h.thumbGen.RegenerateThumbnail(ctx, fullPath, file.Type); err != nil {
+			logging.Error("Thumbnail: regeneration failed for %s: %v", filePath, err)
+			httpError(w, r, fmt.Sprintf("Failed to generate thumbnail: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Thumbnails of sensitive files are obscured unless revealed. Either way
+	// browsers must check back, as the flag can change at any time.
+	if (file.Type == database.FileTypeImage || file.Type == database.FileTypeVideo) && h.isSensitive(ctx, filePath) {
+		w.Header().Set("Cache-Control", "private, no-cache")
+		if !revealsSensitive(r) {
+			thumb, err := h.thumbGen.GetObscuredThumbnail(ctx, fullPath, file.Type)
+			if err != nil {
+				logging.Error("Thumbnail: obscuring failed for %s: %v", filePath, err)
+				httpError(w, r, "Failed to generate thumbnail", http.StatusInternalServerError)
+				return
+			}
+			h.setThumbnailDimensionHeaders(w, fullPath, file.Type, nil)
+			writeThumbnailResponse(w, r, filePath, file.Type, media.ThumbnailFormatDefault, thumb)
+			return
+		}
+	}
+
+	// Generate or retrieve cached thumbnail, as WebP/AVIF if the client accepts it
+	format := h.thumbGen.NegotiateFormat(r.Header.Get("Accept"))
+	var thumb []byte
+	if wantsThumbnailWait(r) {
+		thumb, format, err = h.waitForThumbnail(ctx, fullPath, file.Type, format, scale, size)
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			logging.Warn("Thumbnail: generation of %s did not finish within %v", filePath, h.thumbnailWaitTimeout)
+			httpError(w, r, "Thumbnail generation timed out", http.StatusGatewayTimeout)
+			return
+		}
+	} else if size > 0 {
+		thumb, format, err = h.thumbGen.GetSizedThumbnail(ctx, fullPath, file.Type, format, size)
+	} else {
+		thumb, format, err = h.thumbGen.GetScaledThumbnail(ctx, fullPath, file.Type, format, scale)
+	}
 	if err != nil {
 		logging.Error("Thumbnail: generation failed for %s: %v", filePath, err)
-		http.Error(w, fmt.Sprintf("Failed to generate thumbnail: %v", err), http.StatusInternalServerError)
+		httpError(w, r, fmt.Sprintf("Failed to generate thumbnail: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	writeThumbnailResponse(w, r, filePath, file.Type, thumb)
+	// A regular thumbnail is measured from its header if no dimensions were recorded
+	regular := thumb
+	if scale > 1 || size > 0 || format != media.ThumbnailFormatDefault {
+		regular = nil
+	}
+	h.setThumbnailDimensionHeaders(w, fullPath, file.Type, regular)
+	writeThumbnailResponse(w, r, filePath, file.Type, format, thumb)
+}
+
+// setThumbnailDimensionHeaders sets X-Thumbnail-Width and X-Thumbnail-Height
+// to the dimensions of a file's regular thumbnail, and X-Source-Aspect-Ratio
+// to its source's when known, if THUMBNAIL_DIMENSION_HEADERS is on. Scaled
+// and sized variants keep the regular thumbnail's shape, so its dimensions
+// are sent for them too. thumb is the regular thumbnail if the response is
+// it, so it isn't read again; otherwise nil.
+func (h *Handlers) setThumbnailDimensionHeaders(w http.ResponseWriter, fullPath string, fileType database.FileType, thumb []byte) {
+	if !h.thumbnailDimensions {
+		return
+	}
+	dims, ok := h.thumbGen.GetThumbnailDimensions(fullPath, fileType, thumb)
+	if !ok {
+		return
+	}
+	w.Header().Set("X-Thumbnail-Width", strconv.Itoa(dims.Width))
+	w.Header().Set("X-Thumbnail-Height", strconv.Itoa(dims.Height))
+	if dims.SourceAspectRatio > 0 {
+		w.Header().Set("X-Source-Aspect-Ratio", strconv.FormatFloat(dims.SourceAspectRatio, 'f', 4, 64))
+	}
+}
+
+// ThumbnailSizes lists the sizes a file's thumbnail can be requested at with
+// ?size=, for building a srcset, and those already cached
+type ThumbnailSizes struct {
+	Path   string `json:"path"`
+	Sizes  []int  `json:"sizes"`
+	Cached []int  `json:"cached"`
+}
+
+// GetThumbnailSizes reports the thumbnail sizes of a file
+// GET /api/thumbnail-sizes/{path}
+func (h *Handlers) GetThumbnailSizes(w http.ResponseWriter, r *http.Request) {
+	filePath, fullPath, ok := h.validateThumbnailPath(w, r)
+	if !ok {
+		return
+	}
+
+	file, err := h.db.GetFileByPath(r.Context(), filePath)
+	if err != nil {
+		httpError(w, r, "File not found", http.StatusNotFound)
+		return
+	}
+
+	// Other thumbnails, such as folders', are drawn at a single size
+	sizes := []int{}
+	if h.thumbGen.IsEnabled() && (file.Type == database.FileTypeImage || file.Type == database.FileTypeVideo) {
+		sizes = h.thumbGen.VariantSizes()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, ThumbnailSizes{
+		Path:   filePath,
+		Sizes:  sizes,
+		Cached: h.thumbGen.GetThumbnailSizes(fullPath, file.Type),
+	})
+}
+
+// wantsThumbnailWait reports whether a request asks to block until an
+// up-to-date thumbnail is ready with ?wait=true, for cache-warming scripts
+func wantsThumbnailWait(r *http.Request) bool {
+	wait, _ := strconv.ParseBool(r.URL.Query().Get("wait"))
+	return wait
+}
+
+// waitForThumbnail generates or retrieves a thumbnail without serving a stale
+// one, giving up after the configured wait timeout
+func (h *Handlers) waitForThumbnail(ctx context.Context, fullPath string, fileType database.FileType, format media.ThumbnailFormat, scale, size int) ([]byte, media.ThumbnailFormat, error) {
+	if h.thumbnailWaitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.thumbnailWaitTimeout)
+		defer cancel()
+	}
+	return h.thumbGen.WaitForThumbnail(ctx, fullPath, fileType, format, scale, size)
+}
+
+// bypassCache reports whether a request asks to skip caches with
+// ?nocache=true. Only the authenticated admin gets here with it set
+// (see allowAnonymous).
+func bypassCache(r *http.Request) bool {
+	nocache, _ := strconv.ParseBool(r.URL.Query().Get("nocache"))
+	return nocache
+}
+
+// overridesStreamRate reports whether a request sets its own stream bandwidth
+// with ?maxBytesPerSec. Only the authenticated admin gets here with it set
+// (see allowAnonymous).
+func overridesStreamRate(r *http.Request) bool {
+	return r.URL.Query().Has("maxBytesPerSec")
+}
+
+// streamRate returns the bandwidth limit for a stream in bytes per second:
+// the request's ?maxBytesPerSec if set, where 0 lifts the limit, or else
+// STREAM_MAX_BYTES_PER_SEC. Reports false if the parameter is invalid.
+func (h *Handlers) streamRate(r *http.Request) (int64, bool) {
+	if !overridesStreamRate(r) {
+		return h.streamMaxBytesPerSec, true
+	}
+	rate, err := strconv.ParseInt(r.URL.Query().Get("maxBytesPerSec"), 10, 64)
+	if err != nil || rate < 0 {
+		return 0, false
+	}
+	return rate, true
 }
 
 // StreamVideo streams a video file, transcoding if necessary for browser compatibility
@@ -401,7 +738,7 @@ func (h *Handlers) StreamVideo(w http.ResponseWriter, r *http.Request) {
 
 	// Reject absolute paths before joining
 	if filepath.IsAbs(filePath) {
-		http.Error(w, "Invalid path", http.StatusBadRequest)
+		httpError(w, r, "Invalid path", http.StatusBadRequest)
 		return
 	}
 
@@ -410,7 +747,7 @@ func (h *Handlers) StreamVideo(w http.ResponseWriter, r *http.Request) {
 	absPath, err := filepath.Abs(fullPath)
 	if err != nil || !isSubPath(h.mediaDir, absPath) {
 		logging.Warn("StreamVideo: Invalid path attempted: %s", filePath)
-		http.Error(w, "Invalid path", http.StatusBadRequest)
+		httpError(w, r, "Invalid path", http.StatusBadRequest)
 		return
 	}
 
@@ -418,23 +755,35 @@ func (h *Handlers) StreamVideo(w http.ResponseWriter, r *http.Request) {
 	if _, err := StatWithRetry(fullPath, retryConfig); err != nil {
 		if os.IsNotExist(err) {
 			logging.Warn("StreamVideo: File not found: %s", fullPath)
-			http.Error(w, "File not found", http.StatusNotFound)
+			httpError(w, r, "File not found", http.StatusNotFound)
 		} else {
 			logging.Error("StreamVideo: Failed to access file %s: %v", fullPath, err)
-			http.Error(w, "Failed to access file", http.StatusInternalServerError)
+			httpError(w, r, "Failed to access file", http.StatusInternalServerError)
 		}
 		return
 	}
 
+	// Like GetFile, flagged videos are only streamed with ?reveal=true
+	if !revealsSensitive(r) && h.isSensitive(ctx, filePath) {
+		httpError(w, r, "File is flagged as sensitive", http.StatusForbidden)
+		return
+	}
+
 	targetWidth := 0
 	if widthStr := r.URL.Query().Get("width"); widthStr != "" {
 		targetWidth, _ = strconv.Atoi(widthStr)
 	}
 
+	rate, ok := h.streamRate(r)
+	if !ok {
+		httpError(w, r, "Invalid maxBytesPerSec", http.StatusBadRequest)
+		return
+	}
+
 	info, err := h.transcoder.GetVideoInfo(ctx, fullPath)
 	if err != nil {
 		logging.Error("StreamVideo: Failed to get video info for %s: %v", fullPath, err)
-		http.Error(w, "Failed to get video info", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get video info", http.StatusInternalServerError)
 		return
 	}
 
@@ -442,7 +791,7 @@ func (h *Handlers) StreamVideo(w http.ResponseWriter, r *http.Request) {
 	// which handles range requests properly
 	if !info.NeedsTranscode && (targetWidth == 0 || targetWidth >= info.Width) {
 		logging.Debug("StreamVideo: Using ServeFile for %s (no transcode needed)", fullPath)
-		http.ServeFile(w, r, fullPath)
+		http.ServeFile(streaming.NewThrottledWriter(ctx, w, rate), r, fullPath)
 		return
 	}
 
@@ -452,14 +801,14 @@ func (h *Handlers) StreamVideo(w http.ResponseWriter, r *http.Request) {
 	cachePath, err := h.transcoder.GetOrStartTranscodeAndWait(ctx, fullPath, targetWidth, info)
 	if err != nil {
 		logging.Error("Failed to prepare transcode %s: %v", filePath, err)
-		http.Error(w, "Failed to prepare video", http.StatusInternalServerError)
+		httpError(w, r, "Failed to prepare video", http.StatusInternalServerError)
 		return
 	}
 
 	// Serve the cache file (complete or being written to)
 	// ServeFile handles Range requests and works fine with growing files
 	logging.Info("Serving cached video: %s", cachePath)
-	http.ServeFile(w, r, cachePath)
+	http.ServeFile(streaming.NewThrottledWriter(ctx, w, rate), r, cachePath)
 }
 
 // GetStreamInfo returns codec and dimension information about a video file
@@ -472,13 +821,13 @@ func (h *Handlers) GetStreamInfo(w http.ResponseWriter, r *http.Request) {
 
 	absPath, err := filepath.Abs(fullPath)
 	if err != nil || !isSubPath(h.mediaDir, absPath) {
-		http.Error(w, "Invalid path", http.StatusBadRequest)
+		httpError(w, r, "Invalid path", http.StatusBadRequest)
 		return
 	}
 
 	info, err := h.transcoder.GetVideoInfo(ctx, fullPath)
 	if err != nil {
-		http.Error(w, "Failed to get video info", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get video info", http.StatusInternalServerError)
 		return
 	}
 
@@ -508,29 +857,33 @@ func (h *Handlers) GetStats(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, stats)
 }
 
-// TriggerReindex starts a new media library indexing operation
+// TriggerReindex starts a new media library indexing operation, or, if one is
+// running or just finished, queues a single follow-up run
 func (h *Handlers) TriggerReindex(w http.ResponseWriter, _ *http.Request) {
-	if h.indexer.IsIndexing() {
-		w.Header().Set("Content-Type", "application/json")
-		writeJSON(w, map[string]string{
-			"status":  "already_running",
-			"message": "Indexing is already in progress",
-		})
-		return
-	}
-
 	// TriggerIndex starts a background goroutine that manages its own context
 	// The indexing operation should continue even if the HTTP request completes
 	//nolint:contextcheck // Intentionally not passing request context - indexing runs in background
-	h.indexer.TriggerIndex()
+	result := h.indexer.TriggerIndex()
+
+	message := "Re-indexing started"
+	if result == indexer.TriggerCoalesced {
+		message = "Re-indexing recently ran or is in progress; another run will follow it"
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	writeJSON(w, map[string]string{
-		"status":  "started",
-		"message": "Re-indexing started",
+		"status":  string(result),
+		"message": message,
 	})
 }
 
+// GetIndexErrors returns the files that failed to index during the running
+// or most recent scan, and why
+func (h *Handlers) GetIndexErrors(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, h.indexer.Errors())
+}
+
 func isSubPath(parent, child string) bool {
 	parent, _ = filepath.Abs(parent)
 	child, _ = filepath.Abs(child)
@@ -549,7 +902,7 @@ func (h *Handlers) InvalidateThumbnail(w http.ResponseWriter, r *http.Request) {
 	filePath := vars["path"]
 
 	if filePath == "" {
-		http.Error(w, "Path is required", http.StatusBadRequest)
+		httpError(w, r, "Path is required", http.StatusBadRequest)
 		return
 	}
 
@@ -557,18 +910,18 @@ func (h *Handlers) InvalidateThumbnail(w http.ResponseWriter, r *http.Request) {
 
 	absPath, err := filepath.Abs(fullPath)
 	if err != nil || !isSubPath(h.mediaDir, absPath) {
-		http.Error(w, "Invalid path", http.StatusBadRequest)
+		httpError(w, r, "Invalid path", http.StatusBadRequest)
 		return
 	}
 
 	if !h.thumbGen.IsEnabled() {
-		http.Error(w, "Thumbnails disabled", http.StatusServiceUnavailable)
+		httpError(w, r, "Thumbnails disabled", http.StatusServiceUnavailable)
 		return
 	}
 
 	if err := h.thumbGen.InvalidateThumbnail(fullPath); err != nil {
 		logging.Error("Failed to invalidate thumbnail for %s: %v", filePath, err)
-		http.Error(w, "Failed to invalidate thumbnail", http.StatusInternalServerError)
+		httpError(w, r, "Failed to invalidate thumbnail", http.StatusInternalServerError)
 		return
 	}
 
@@ -582,16 +935,16 @@ func (h *Handlers) InvalidateThumbnail(w http.ResponseWriter, r *http.Request) {
 }
 
 // InvalidateAllThumbnails clears the entire thumbnail cache
-func (h *Handlers) InvalidateAllThumbnails(w http.ResponseWriter, _ *http.Request) {
+func (h *Handlers) InvalidateAllThumbnails(w http.ResponseWriter, r *http.Request) {
 	if !h.thumbGen.IsEnabled() {
-		http.Error(w, "Thumbnails disabled", http.StatusServiceUnavailable)
+		httpError(w, r, "Thumbnails disabled", http.StatusServiceUnavailable)
 		return
 	}
 
 	count, err := h.thumbGen.InvalidateAll()
 	if err != nil {
 		logging.Error("Failed to invalidate all thumbnails: %v", err)
-		http.Error(w, "Failed to invalidate thumbnails", http.StatusInternalServerError)
+		httpError(w, r, "Failed to invalidate thumbnails", http.StatusInternalServerError)
 		return
 	}
 
@@ -606,9 +959,9 @@ func (h *Handlers) InvalidateAllThumbnails(w http.ResponseWriter, _ *http.Reques
 }
 
 // RebuildAllThumbnails clears the cache and regenerates all thumbnails in the background
-func (h *Handlers) RebuildAllThumbnails(w http.ResponseWriter, _ *http.Request) {
+func (h *Handlers) RebuildAllThumbnails(w http.ResponseWriter, r *http.Request) {
 	if !h.thumbGen.IsEnabled() {
-		http.Error(w, "Thumbnails disabled", http.StatusServiceUnavailable)
+		httpError(w, r, "Thumbnails disabled", http.StatusServiceUnavailable)
 		return
 	}
 
@@ -651,8 +1004,16 @@ func (h *Handlers) GetThumbnailStatus(w http.ResponseWriter, _ *http.Request) {
 	writeJSON(w, status)
 }
 
+// filePathInfo is the lightweight item returned by ListFilePaths
+type filePathInfo struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
 // ListFilePaths returns lightweight path/name/type data for all files in a directory
 // Used for bulk selection operations where full file data isn't needed
+// With recursive=true, everything below the directory is streamed in path order
 func (h *Handlers) ListFilePaths(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	logging.Debug("ListFilePaths called: %s", r.URL.String())
@@ -664,7 +1025,15 @@ func (h *Handlers) ListFilePaths(w http.ResponseWriter, r *http.Request) {
 		FilterType: r.URL.Query().Get("type"),
 		Page:       1,
 		PageSize:   100000, // Effectively unlimited - get all items
+		Locale:     i18n.FromRequest(r),
+	}
+
+	presence, err := parsePresenceFilter(r.URL.Query())
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
 	}
+	opts.Presence = presence
 
 	if opts.SortField == "" {
 		opts.SortField = database.SortByName
@@ -673,26 +1042,35 @@ func (h *Handlers) ListFilePaths(w http.ResponseWriter, r *http.Request) {
 		opts.SortOrder = database.SortAsc
 	}
 
-	logging.Debug("ListFilePaths options: path=%q, sort=%s, order=%s, filter=%s",
-		opts.Path, opts.SortField, opts.SortOrder, opts.FilterType)
+	logging.Debug("ListFilePaths options: path=%q, sort=%s, order=%s, filter=%s, %s",
+		opts.Path, opts.SortField, opts.SortOrder, opts.FilterType, opts.Presence)
+
+	if r.URL.Query().Get("recursive") == "true" {
+		rate, ok := h.streamRate(r)
+		if !ok {
+			httpError(w, r, "Invalid maxBytesPerSec", http.StatusBadRequest)
+			return
+		}
+		h.streamFilePaths(w, r, database.StreamOptions{
+			Path:       opts.Path,
+			Recursive:  true,
+			FilterType: opts.FilterType,
+			Presence:   opts.Presence,
+		}, rate)
+		return
+	}
 
 	listing, err := h.db.ListDirectory(ctx, opts)
 	if err != nil {
 		logging.Error("ListFilePaths database error: %v", err)
-		http.Error(w, "Failed to list directory", http.StatusInternalServerError)
+		httpError(w, r, "Failed to list directory", http.StatusInternalServerError)
 		return
 	}
 
 	// Build lightweight response with only path, name, type
-	type FilePathInfo struct {
-		Path string `json:"path"`
-		Name string `json:"name"`
-		Type string `json:"type"`
-	}
-
-	items := make([]FilePathInfo, 0, len(listing.Items))
+	items := make([]filePathInfo, 0, len(listing.Items))
 	for _, item := range listing.Items {
-		items = append(items, FilePathInfo{
+		items = append(items, filePathInfo{
 			Path: item.Path,
 			Name: item.Name,
 			Type: string(item.Type),
@@ -700,7 +1078,7 @@ func (h *Handlers) ListFilePaths(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := struct {
-		Items      []FilePathInfo `json:"items"`
+		Items      []filePathInfo `json:"items"`
 		TotalItems int            `json:"totalItems"`
 	}{
 		Items:      items,
@@ -712,3 +1090,105 @@ func (h *Handlers) ListFilePaths(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	writeJSON(w, response)
 }
+
+// streamFilePaths writes the ListFilePaths response for a recursive listing
+// as the rows are read, so memory use doesn't grow with the library. The
+// first item is sent as soon as it is read, so clients see the response
+// start even when the rest takes a while. It is limited to rate bytes per
+// second, if positive. Errors after the response has started leave it
+// truncated, which clients see as invalid JSON.
+func (h *Handlers) streamFilePaths(w http.ResponseWriter, r *http.Request, opts database.StreamOptions, rate int64) {
+	config := streaming.ProfileConfig(streaming.ProfileBulkDownload)
+	config.MaxBytesPerSecond = rate
+	tw := streaming.NewTimeoutWriter(r.Context(), w, config)
+	defer func() {
+		if err := tw.Close(); err != nil {
+			logging.Warn("Failed to close timeout writer: %v", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	buf := bufio.NewWriterSize(tw, 64*1024)
+	items := streaming.NewJSONArrayWriter(buf)
+
+	_, err := buf.WriteString(`{"items":`)
+	if err == nil {
+		err = h.db.StreamFiles(r.Context(), opts, func(f database.MediaFile) error {
+			if err := items.Encode(filePathInfo{Path: f.Path, Name: f.Name, Type: string(f.Type)}); err != nil {
+				return err
+			}
+			if items.Count() == 1 {
+				if err := buf.Flush(); err != nil {
+					return err
+				}
+				tw.Flush()
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = items.Close()
+	}
+	if err == nil {
+		_, err = fmt.Fprintf(buf, `,"totalItems":%d}`, items.Count())
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+
+	if err != nil {
+		if errors.Is(err, streaming.ErrClientGone) {
+			logging.Debug("ListFilePaths client disconnected after %d items", items.Count())
+			return
+		}
+		if bytesWritten, _, _ := tw.Stats(); bytesWritten == 0 {
+			logging.Error("ListFilePaths database error: %v", err)
+			httpError(w, r, "Failed to list directory", http.StatusInternalServerError)
+			return
+		}
+		logging.Error("ListFilePaths streaming failed after %d items: %v", items.Count(), err)
+		return
+	}
+
+	logging.Debug("ListFilePaths streamed %d items", items.Count())
+}
+
+// FileCheckRequest represents a request to check the current state of files
+type FileCheckRequest struct {
+	Paths []string `json:"paths"`
+}
+
+// CheckFiles reports, for each requested path, whether it is still indexed
+// and its current modification time, size and hash. Clients use it to
+// invalidate only the cached entries that changed.
+func (h *Handlers) CheckFiles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req FileCheckRequest
+	if err := decodeJSONBody(w, r, &req); err != nil {
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Paths) == 0 {
+		httpError(w, r, "Paths array is required", http.StatusBadRequest)
+		return
+	}
+
+	maxPaths := 10000
+	if len(req.Paths) > maxPaths {
+		httpError(w, r, fmt.Sprintf("Too many paths (max %d)", maxPaths), http.StatusBadRequest)
+		return
+	}
+
+	states, err := h.db.CheckFiles(ctx, req.Paths)
+	if err != nil {
+		logging.Error("CheckFiles database error: %v", err)
+		httpError(w, r, "Failed to check files", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, states)
+}
@@ -1,16 +1,22 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"image"
+	"image/jpeg"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"media-viewer/internal/database"
 	"media-viewer/internal/indexer"
@@ -153,15 +159,11 @@ func addExistingFileToDatabase(t *testing.T, h *Handlers, relativePath string, f
 		ModTime:    fileInfo.ModTime(),
 	}
 
+	// EndBatch releases the write lock BeginBatch took
 	err = h.db.UpsertFile(ctx, tx, file)
-	if err != nil {
-		tx.Rollback()
+	if err := h.db.EndBatch(tx, err); err != nil {
 		t.Fatalf("failed to upsert file: %v", err)
 	}
-
-	if err := tx.Commit(); err != nil {
-		t.Fatalf("failed to commit batch: %v", err)
-	}
 }
 
 // addTestMediaFile creates a test file in the media directory and adds it to the database
@@ -386,6 +388,85 @@ func TestGetMediaFilesIntegration(t *testing.T) {
 	}
 }
 
+// TestFolderOrderIntegration tests storing a manual order and listing with sort=manual
+func TestFolderOrderIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		addTestMediaFile(t, h, name, database.FileTypeImage, name)
+	}
+
+	body := `{"path": "", "paths": ["c.jpg", "a.jpg"]}`
+	req := httptest.NewRequest(http.MethodPut, "/api/folder/order", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.SetFolderOrder(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/folder/order?path=", http.NoBody)
+	w = httptest.NewRecorder()
+
+	h.GetFolderOrder(w, req)
+
+	var order FolderOrderRequest
+	if err := json.NewDecoder(w.Body).Decode(&order); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if strings.Join(order.Paths, ",") != "c.jpg,a.jpg" {
+		t.Errorf("expected stored order [c.jpg a.jpg], got %v", order.Paths)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/media?sort=manual", http.NoBody)
+	w = httptest.NewRecorder()
+
+	h.GetMediaFiles(w, req)
+
+	var files []database.MediaFile
+	if err := json.NewDecoder(w.Body).Decode(&files); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "c.jpg,a.jpg,b.jpg" {
+		t.Errorf("expected manual order c.jpg,a.jpg,b.jpg, got %v", names)
+	}
+}
+
+// TestSetFolderOrderInvalidIntegration tests rejection of paths outside the folder
+func TestSetFolderOrderInvalidIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	tests := []string{
+		`{"path": "album", "paths": ["other/a.jpg"]}`,
+		`{"path": "album", "paths": ["album/a.jpg", "album/a.jpg"]}`,
+		`not json`,
+	}
+
+	for _, body := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/api/folder/order", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		h.SetFolderOrder(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
+
 // TestGetMediaFilesWithPathIntegration tests retrieving media files from a specific directory
 func TestGetMediaFilesWithPathIntegration(t *testing.T) {
 	if testing.Short() {
@@ -442,6 +523,299 @@ func TestGetFileIntegration(t *testing.T) {
 	}
 }
 
+// TestGetFileRangeIntegration tests that partial requests can resume a download
+func TestGetFileRangeIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	testContent := strings.Repeat("0123456789", 30)
+	addTestMediaFile(t, h, "raw/IMG_0001.dng", database.FileTypeImage, testContent)
+
+	get := func(target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		req = mux.SetURLVars(req, map[string]string{"path": "raw/IMG_0001.dng"})
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		h.GetFile(w, req)
+		return w
+	}
+
+	w := get("/api/file/raw/IMG_0001.dng", nil)
+	if w.Code != http.StatusOK || w.Body.String() != testContent {
+		t.Fatalf("expected the full file, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("Accept-Ranges") != "bytes" {
+		t.Errorf("expected an ETag and Accept-Ranges: bytes, got %q and %q", etag, w.Header().Get("Accept-Ranges"))
+	}
+
+	for _, target := range []string{"/api/file/raw/IMG_0001.dng", "/api/file/raw/IMG_0001.dng?download=true"} {
+		w = get(target, map[string]string{"Range": "bytes=100-"})
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("%s: expected status 206, got %d", target, w.Code)
+		}
+		if got := w.Header().Get("Content-Range"); got != "bytes 100-299/300" {
+			t.Errorf("%s: expected Content-Range bytes 100-299/300, got %q", target, got)
+		}
+		if w.Body.String() != testContent[100:] {
+			t.Errorf("%s: expected the file from byte 100, got %d bytes", target, w.Body.Len())
+		}
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="IMG_0001.dng"` {
+		t.Errorf("expected the download Content-Disposition on a partial response, got %q", got)
+	}
+
+	// A resume against a changed file gets the whole new file
+	w = get("/api/file/raw/IMG_0001.dng", map[string]string{"Range": "bytes=100-", "If-Range": etag})
+	if w.Code != http.StatusPartialContent {
+		t.Errorf("expected status 206 for a matching If-Range, got %d", w.Code)
+	}
+	w = get("/api/file/raw/IMG_0001.dng", map[string]string{"Range": "bytes=100-", "If-Range": `"stale"`})
+	if w.Code != http.StatusOK || w.Body.String() != testContent {
+		t.Errorf("expected the full file for a stale If-Range, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+
+	w = get("/api/file/raw/IMG_0001.dng", map[string]string{"Range": "bytes=500-"})
+	if w.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("expected status 416 past the end of the file, got %d", w.Code)
+	}
+
+	// Directories aren't served
+	req := httptest.NewRequest(http.MethodGet, "/api/file/raw", http.NoBody)
+	req = mux.SetURLVars(req, map[string]string{"path": "raw"})
+	w = httptest.NewRecorder()
+	h.GetFile(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a directory, got %d", w.Code)
+	}
+}
+
+// TestGetThumbnailRangeIntegration tests partial requests for a thumbnail
+func TestGetThumbnailRangeIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTestWithThumbnails(t)
+	defer cleanup()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 600, 400)), nil); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(h.mediaDir, "photo.jpg"), buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to create test image: %v", err)
+	}
+	addExistingFileToDatabase(t, h, "photo.jpg", database.FileTypeImage)
+
+	get := func(rangeHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
+		req = mux.SetURLVars(req, map[string]string{"path": "photo.jpg"})
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		h.GetThumbnail(w, req)
+		return w
+	}
+
+	full := get("")
+	if full.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", full.Code, full.Body.String())
+	}
+	thumb := full.Body.Bytes()
+
+	w := get("bytes=100-")
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("expected status 206, got %d", w.Code)
+	}
+	if want := fmt.Sprintf("bytes 100-%d/%d", len(thumb)-1, len(thumb)); w.Header().Get("Content-Range") != want {
+		t.Errorf("expected Content-Range %q, got %q", want, w.Header().Get("Content-Range"))
+	}
+	if !bytes.Equal(w.Body.Bytes(), thumb[100:]) {
+		t.Errorf("expected the thumbnail from byte 100, got %d bytes", w.Body.Len())
+	}
+	if w.Header().Get("Content-Type") != "image/jpeg" {
+		t.Errorf("expected Content-Type image/jpeg, got %q", w.Header().Get("Content-Type"))
+	}
+}
+
+// TestGetThumbnailConditionalIntegration tests that image, video and folder
+// thumbnails answer a conditional request with their ETag with 304
+func TestGetThumbnailConditionalIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTestWithThumbnails(t)
+	defer cleanup()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 600, 400)), nil); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(h.mediaDir, "album"), 0o755); err != nil {
+		t.Fatalf("failed to create test folder: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(h.mediaDir, "album", "photo.jpg"), buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to create test image: %v", err)
+	}
+	addExistingFileToDatabase(t, h, "album", database.FileTypeFolder)
+	addExistingFileToDatabase(t, h, "album/photo.jpg", database.FileTypeImage)
+
+	tests := []struct {
+		name         string
+		path         string
+		cacheControl string
+	}{
+		{"image", "album/photo.jpg", "public, max-age=86400"},
+		{"video", "clip.mp4", "public, max-age=86400"},
+		{"folder", "album", "public, max-age=300, must-revalidate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "video" {
+				if _, err := exec.LookPath("ffmpeg"); err != nil {
+					t.Skip("ffmpeg not available")
+				}
+				cmd := exec.CommandContext(context.Background(), "ffmpeg", "-f", "lavfi", "-i", "color=c=red:s=320x240:d=1",
+					"-pix_fmt", "yuv420p", "-y", filepath.Join(h.mediaDir, tt.path))
+				if err := cmd.Run(); err != nil {
+					t.Fatalf("failed to create test video: %v", err)
+				}
+				addExistingFileToDatabase(t, h, tt.path, database.FileTypeVideo)
+			}
+
+			get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/"+tt.path, http.NoBody)
+				req = mux.SetURLVars(req, map[string]string{"path": tt.path})
+				if ifNoneMatch != "" {
+					req.Header.Set("If-None-Match", ifNoneMatch)
+				}
+				w := httptest.NewRecorder()
+				h.GetThumbnail(w, req)
+				return w
+			}
+
+			first := get("")
+			if first.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", first.Code, first.Body.String())
+			}
+			etag := first.Header().Get("ETag")
+			if etag == "" || strings.HasPrefix(etag, "W/") {
+				t.Fatalf("expected a strong ETag, got %q", etag)
+			}
+			if first.Header().Get("Cache-Control") != tt.cacheControl {
+				t.Errorf("expected Cache-Control %q, got %q", tt.cacheControl, first.Header().Get("Cache-Control"))
+			}
+
+			w := get(etag)
+			if w.Code != http.StatusNotModified {
+				t.Fatalf("expected status 304, got %d", w.Code)
+			}
+			if w.Body.Len() != 0 {
+				t.Errorf("expected an empty body, got %d bytes", w.Body.Len())
+			}
+			if w.Header().Get("ETag") != etag {
+				t.Errorf("expected ETag %q, got %q", etag, w.Header().Get("ETag"))
+			}
+
+			// Browsers may send every ETag they hold for the URL
+			if w := get(`"stale", ` + etag); w.Code != http.StatusNotModified {
+				t.Errorf("expected status 304 for a list of ETags, got %d", w.Code)
+			}
+		})
+	}
+}
+
+// TestGetFileThrottledIntegration tests that a stream bandwidth limit slows
+// the response without changing it, and that the request can override it
+func TestGetFileThrottledIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	testContent := strings.Repeat("x", 3000)
+	addTestMediaFile(t, h, "test.jpg", database.FileTypeImage, testContent)
+	h.streamMaxBytesPerSec = 4000
+
+	get := func(target string) (*httptest.ResponseRecorder, time.Duration) {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		req = mux.SetURLVars(req, map[string]string{"path": "test.jpg"})
+		w := httptest.NewRecorder()
+		start := time.Now()
+		h.GetFile(w, req)
+		return w, time.Since(start)
+	}
+
+	// A quarter second of data goes out at once, the rest at 4000 bytes/s
+	w, elapsed := get("/api/file/test.jpg")
+	if w.Code != http.StatusOK || w.Body.String() != testContent {
+		t.Fatalf("expected the full file, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+	if elapsed < 400*time.Millisecond {
+		t.Errorf("expected the response to be throttled, took %v", elapsed)
+	}
+
+	w, elapsed = get("/api/file/test.jpg?maxBytesPerSec=0")
+	if w.Code != http.StatusOK || w.Body.String() != testContent {
+		t.Fatalf("expected the full file, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+	if elapsed >= 400*time.Millisecond {
+		t.Errorf("expected maxBytesPerSec=0 to lift the limit, took %v", elapsed)
+	}
+
+	if w, _ = get("/api/file/test.jpg?maxBytesPerSec=-1"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a negative rate, got %d", w.Code)
+	}
+}
+
+// TestGetFileMixedCaseExtensionIntegration tests that the Content-Type of a
+// served file does not depend on the case of its extension
+func TestGetFileMixedCaseExtensionIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	tests := []struct {
+		path        string
+		fileType    database.FileType
+		contentType string
+	}{
+		{"lower.heic", database.FileTypeImage, "image/heic"},
+		{"UPPER.HEIC", database.FileTypeImage, "image/heic"},
+		{"photo.JPG", database.FileTypeImage, "image/jpeg"},
+		{"clip.Mp4", database.FileTypeVideo, "video/mp4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			addTestMediaFile(t, h, tt.path, tt.fileType, "test file content")
+
+			req := httptest.NewRequest(http.MethodGet, "/api/file/"+tt.path, http.NoBody)
+			req = mux.SetURLVars(req, map[string]string{"path": tt.path})
+			w := httptest.NewRecorder()
+
+			h.GetFile(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("expected Content-Type %q, got %q", tt.contentType, ct)
+			}
+		})
+	}
+}
+
 // TestGetFileInvalidPathIntegration tests file access with invalid paths
 func TestGetFileInvalidPathIntegration(t *testing.T) {
 	if testing.Short() {
@@ -746,6 +1120,129 @@ func TestGetThumbnailNotFoundIntegration(t *testing.T) {
 	}
 }
 
+// TestGetThumbnailOtherFileIntegration tests thumbnails of unindexed non-media files
+func TestGetThumbnailOtherFileIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTestWithThumbnails(t)
+	defer cleanup()
+
+	if err := os.WriteFile(filepath.Join(h.mediaDir, "notes.txt"), []byte("hello\n"), 0o644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	getThumbnail := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/"+path, http.NoBody)
+		req = mux.SetURLVars(req, map[string]string{"path": path})
+		w := httptest.NewRecorder()
+		h.GetThumbnail(w, req)
+		return w
+	}
+
+	// Off by default
+	if w := getThumbnail("notes.txt"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 with other-file thumbnails off, got %d", w.Code)
+	}
+
+	h.thumbGen.SetOtherThumbnailMode(media.OtherThumbnailsPreview)
+
+	w := getThumbnail("notes.txt")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/jpeg" {
+		t.Errorf("expected Content-Type image/jpeg, got %q", ct)
+	}
+
+	// Missing files and unindexed media files are still not found
+	for _, path := range []string{"missing.txt", "unindexed.jpg"} {
+		if w := getThumbnail(path); w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d", path, w.Code)
+		}
+	}
+
+	// Other files have no high-DPI variant, but a valid hint is accepted
+	for dpr, want := range map[string]int{"2": http.StatusOK, "retina": http.StatusBadRequest} {
+		req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/notes.txt?dpr="+dpr, http.NoBody)
+		req = mux.SetURLVars(req, map[string]string{"path": "notes.txt"})
+		w := httptest.NewRecorder()
+		h.GetThumbnail(w, req)
+		if w.Code != want {
+			t.Errorf("dpr=%s: expected status %d, got %d", dpr, want, w.Code)
+		}
+	}
+}
+
+// TestThumbnailSizeVariantsIntegration tests ?size= thumbnails and the sizes endpoint
+func TestThumbnailSizeVariantsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTestWithThumbnails(t)
+	defer cleanup()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1200, 800)), nil); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(h.mediaDir, "photo.jpg"), buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to create test image: %v", err)
+	}
+	addExistingFileToDatabase(t, h, "photo.jpg", database.FileTypeImage)
+	h.thumbGen.SetVariantSizes([]int{512})
+
+	get := func(handler http.HandlerFunc, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		req = mux.SetURLVars(req, map[string]string{"path": "photo.jpg"})
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	tests := []struct {
+		query     string
+		wantWidth int
+	}{
+		{"", 200},
+		{"?size=150", 200},
+		{"?size=400", 512},
+		{"?size=200&dpr=2", 512},
+		{"?size=2000", 512},
+	}
+	for _, tt := range tests {
+		w := get(h.GetThumbnail, "/api/thumbnail/photo.jpg"+tt.query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", tt.query, w.Code, w.Body.String())
+		}
+		cfg, err := jpeg.DecodeConfig(w.Body)
+		if err != nil {
+			t.Fatalf("%s: failed to decode thumbnail: %v", tt.query, err)
+		}
+		if cfg.Width != tt.wantWidth {
+			t.Errorf("%s: expected width %d, got %d", tt.query, tt.wantWidth, cfg.Width)
+		}
+	}
+
+	for _, size := range []string{"0", "-1", "big"} {
+		if w := get(h.GetThumbnail, "/api/thumbnail/photo.jpg?size="+size); w.Code != http.StatusBadRequest {
+			t.Errorf("size=%s: expected status 400, got %d", size, w.Code)
+		}
+	}
+
+	w := get(h.GetThumbnailSizes, "/api/thumbnail-sizes/photo.jpg")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var sizes ThumbnailSizes
+	if err := json.NewDecoder(w.Body).Decode(&sizes); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if fmt.Sprint(sizes.Sizes) != "[200 512]" || fmt.Sprint(sizes.Cached) != "[200 512]" {
+		t.Errorf("expected sizes and cached sizes [200 512], got %+v", sizes)
+	}
+}
+
 // TestStreamVideoNotFoundIntegration tests streaming a non-existent video
 func TestStreamVideoNotFoundIntegration(t *testing.T) {
 	if testing.Short() {
@@ -878,19 +1375,66 @@ func TestTriggerReindexIntegration(t *testing.T) {
 		t.Fatalf("failed to decode response: %v", err)
 	}
 
-	if response["status"] != "started" && response["status"] != "already_running" {
-		t.Errorf("expected status 'started' or 'already_running', got %q", response["status"])
+	if response["status"] != "started" {
+		t.Errorf("expected status 'started', got %q", response["status"])
+	}
+}
+
+// TestGetIndexErrorsIntegration tests retrieving the indexer error list
+func TestGetIndexErrorsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/index/errors", http.NoBody)
+	w := httptest.NewRecorder()
+
+	h.GetIndexErrors(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response indexer.ErrorReport
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response.Total != 0 || response.Errors == nil || len(response.Errors) != 0 {
+		t.Errorf("expected an empty error list, got %+v", response)
 	}
 }
 
-// TestTriggerReindexAlreadyRunningIntegration tests reindex when already indexing
-func TestTriggerReindexAlreadyRunningIntegration(t *testing.T) {
+// TestTriggerReindexCoalescedIntegration tests a reindex requested while one
+// is running or within the quiet period after it
+func TestTriggerReindexCoalescedIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
 	}
-	// Note: With an empty directory, indexing completes almost instantly,
-	// so this test can't reliably test the "already_running" case.
-	t.Skip("Cannot reliably test already_running with empty directory - indexing completes too quickly")
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	statuses := make([]string, 2)
+	for i := range statuses {
+		req := httptest.NewRequest(http.MethodPost, "/api/reindex", http.NoBody)
+		w := httptest.NewRecorder()
+
+		h.TriggerReindex(w, req)
+
+		var response map[string]string
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		statuses[i] = response["status"]
+	}
+
+	// With the default quiet period, the second request comes too soon after
+	// the first run, however quickly it finished
+	if statuses[0] != "started" || statuses[1] != "coalesced" {
+		t.Errorf("expected statuses [started coalesced], got %v", statuses)
+	}
 }
 
 // TestInvalidateThumbnailDisabledIntegration tests thumbnail invalidation when disabled
@@ -1167,6 +1711,61 @@ func TestListFilesFilterByTypeIntegration(t *testing.T) {
 	}
 }
 
+// TestListFilesFilterByPresenceIntegration tests listing untagged or unfavorited files
+func TestListFilesFilterByPresenceIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	addTestMediaFile(t, h, "tagged.jpg", database.FileTypeImage, "tagged")
+	addTestMediaFile(t, h, "loved.jpg", database.FileTypeImage, "loved")
+	addTestMediaFile(t, h, "plain.jpg", database.FileTypeImage, "plain")
+	if err := h.db.AddTagToFile(ctx, "tagged.jpg", "keep"); err != nil {
+		t.Fatalf("AddTagToFile failed: %v", err)
+	}
+	if err := h.db.AddFavorite(ctx, "loved.jpg", "loved.jpg", database.FileTypeImage); err != nil {
+		t.Fatalf("AddFavorite failed: %v", err)
+	}
+
+	list := func(query string) (database.DirectoryListing, string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/files?"+query, http.NoBody)
+		w := httptest.NewRecorder()
+		h.ListFiles(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", query, w.Code, w.Body.String())
+		}
+		var listing database.DirectoryListing
+		if err := json.NewDecoder(w.Body).Decode(&listing); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return listing, w.Header().Get("ETag")
+	}
+
+	untagged, untaggedETag := list("untagged=true&favorited=false")
+	if len(untagged.Items) != 1 || untagged.Items[0].Path != "plain.jpg" {
+		t.Errorf("expected only plain.jpg, got %v", untagged.Items)
+	}
+
+	all, allETag := list("")
+	if len(all.Items) != 3 {
+		t.Errorf("expected 3 files unfiltered, got %d", len(all.Items))
+	}
+	if allETag == untaggedETag {
+		t.Error("expected the filters to change the ETag")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/files?favorited=often", http.NoBody)
+	w := httptest.NewRecorder()
+	h.ListFiles(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid favorited value, got %d", w.Code)
+	}
+}
+
 // TestListFilesFilterWithFoldersIntegration tests that filtering includes folders for navigation
 func TestListFilesFilterWithFoldersIntegration(t *testing.T) {
 	if testing.Short() {
@@ -1503,6 +2102,80 @@ func TestListFilesConditionalRequestIntegration(t *testing.T) {
 	}
 }
 
+// TestListFilesNoCacheIntegration tests that ?nocache=true always returns the full listing
+func TestListFilesNoCacheIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	addTestMediaFile(t, h, "image1.jpg", database.FileTypeImage, "image1")
+
+	req1 := httptest.NewRequest(http.MethodGet, "/api/files?nocache=true", http.NoBody)
+	w1 := httptest.NewRecorder()
+	h.ListFiles(w1, req1)
+
+	if got := w1.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("expected Cache-Control no-store, got %q", got)
+	}
+
+	req2 := httptest.NewRequest(http.MethodGet, "/api/files?nocache=true", http.NoBody)
+	req2.Header.Set("If-None-Match", w1.Header().Get("ETag"))
+	w2 := httptest.NewRecorder()
+	h.ListFiles(w2, req2)
+
+	if w2.Code != http.StatusOK || w2.Body.Len() == 0 {
+		t.Errorf("expected full 200 response despite matching ETag, got %d with %d bytes", w2.Code, w2.Body.Len())
+	}
+}
+
+// TestCheckFilesIntegration tests the batched file state check
+func TestCheckFilesIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	addTestMediaFile(t, h, "image1.jpg", database.FileTypeImage, "image1")
+
+	body := strings.NewReader(`{"paths": ["image1.jpg", "deleted.jpg"]}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/files/check", body)
+	w := httptest.NewRecorder()
+	h.CheckFiles(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var states map[string]database.FileState
+	if err := json.NewDecoder(w.Body).Decode(&states); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if state := states["image1.jpg"]; !state.Exists || state.ModTime == nil {
+		t.Errorf("expected image1.jpg to exist with a modification time, got %+v", state)
+	}
+	if state, ok := states["deleted.jpg"]; !ok || state.Exists {
+		t.Errorf("expected deleted.jpg to be reported as missing, got %+v", state)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/files/check", strings.NewReader(`{"paths": []}`))
+	w = httptest.NewRecorder()
+	h.CheckFiles(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for empty paths, got %d", w.Code)
+	}
+
+	oversized := `{"paths": ["` + strings.Repeat("a", maxJSONBodySize) + `"]}`
+	req = httptest.NewRequest(http.MethodPost, "/api/files/check", strings.NewReader(oversized))
+	w = httptest.NewRecorder()
+	h.CheckFiles(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an oversized body, got %d", w.Code)
+	}
+}
+
 // TestListFilesETagChangeOnModificationIntegration tests that ETag changes when content changes
 func TestListFilesETagChangeOnModificationIntegration(t *testing.T) {
 	if testing.Short() {
@@ -2493,6 +3166,107 @@ func TestListFilePathsTypeFilterIntegration(t *testing.T) {
 	}
 }
 
+// TestListFilePathsRecursiveIntegration tests streaming everything below a folder
+func TestListFilePathsRecursiveIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	addTestMediaFile(t, h, "trips/beach.jpg", database.FileTypeImage, "beach")
+	addTestMediaFile(t, h, "trips/2023/dunes.jpg", database.FileTypeImage, "dunes")
+	addTestMediaFile(t, h, "trips/2023/waves.mp4", database.FileTypeVideo, "waves")
+	addTestMediaFile(t, h, "trips-old/pier.jpg", database.FileTypeImage, "pier")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/files/paths?path=trips&recursive=true&type=image", http.NoBody)
+	w := httptest.NewRecorder()
+
+	h.ListFilePaths(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Items []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+		} `json:"items"`
+		TotalItems int `json:"totalItems"`
+	}
+
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	var paths []string
+	for _, item := range response.Items {
+		paths = append(paths, item.Path)
+	}
+	expected := []string{"trips/2023/dunes.jpg", "trips/beach.jpg"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+	if response.TotalItems != len(expected) {
+		t.Errorf("expected totalItems %d, got %d", len(expected), response.TotalItems)
+	}
+}
+
+// TestListFilePathsRecursiveEmptyIntegration tests a recursive listing with no matches
+func TestListFilePathsRecursiveEmptyIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/files/paths?path=nowhere&recursive=true", http.NoBody)
+	w := httptest.NewRecorder()
+
+	h.ListFilePaths(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != `{"items":[],"totalItems":0}` {
+		t.Errorf("unexpected body %s", body)
+	}
+}
+
+// TestListFilePathsRecursiveRateIntegration tests the bandwidth limit of recursive listings
+func TestListFilePathsRecursiveRateIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	for i := range 20 {
+		addTestMediaFile(t, h, fmt.Sprintf("trips/photo-%02d.jpg", i), database.FileTypeImage, "photo")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/files/paths?path=trips&recursive=true&maxBytesPerSec=-1", http.NoBody)
+	w := httptest.NewRecorder()
+	h.ListFilePaths(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid rate, got %d", w.Code)
+	}
+
+	// About 1.4KB of JSON at 1000 bytes/s, after a 250-byte burst
+	h.streamMaxBytesPerSec = 1000
+	req = httptest.NewRequest(http.MethodGet, "/api/files/paths?path=trips&recursive=true", http.NoBody)
+	w = httptest.NewRecorder()
+	start := time.Now()
+	h.ListFilePaths(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if elapsed, size := time.Since(start), w.Body.Len(); elapsed < time.Duration(size-300)*time.Millisecond {
+		t.Errorf("expected %d bytes to take about %dms at 1000 bytes/s, took %v", size, size-250, elapsed)
+	}
+}
+
 // TestListFilePathsSortingIntegration tests sorting functionality
 func TestListFilePathsSortingIntegration(t *testing.T) {
 	if testing.Short() {
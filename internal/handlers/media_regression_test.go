@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"media-viewer/internal/database"
+	"media-viewer/internal/media"
 )
 
 // =============================================================================
@@ -52,7 +53,7 @@ func TestWriteThumbnailResponse_RegressionJPEGNotRejected(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/test", http.NoBody)
 			w := httptest.NewRecorder()
 
-			writeThumbnailResponse(w, req, "test", tt.fileType, tt.thumb)
+			writeThumbnailResponse(w, req, "test", tt.fileType, media.ThumbnailFormatDefault, tt.thumb)
 
 			// The critical assertion: valid thumbnails MUST produce a non-empty response.
 			// The original bug caused this to be 0 for JPEG thumbnails.
@@ -98,7 +99,7 @@ func TestWriteThumbnailResponse_RegressionInvalidFormatStillRejected(t *testing.
 			req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/test", http.NoBody)
 			w := httptest.NewRecorder()
 
-			writeThumbnailResponse(w, req, "test", database.FileTypeImage, tt.data)
+			writeThumbnailResponse(w, req, "test", database.FileTypeImage, media.ThumbnailFormatDefault, tt.data)
 
 			if w.Body.Len() != 0 {
 				t.Errorf("invalid payload %q should have been rejected but %d bytes were written",
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"media-viewer/internal/database"
+	"media-viewer/internal/media"
 
 	"github.com/gorilla/mux"
 )
@@ -781,6 +782,26 @@ func TestIsValidImageHeader(t *testing.T) {
 			data:     []byte{0x52, 0x49, 0x46, 0x46, 0x00, 0x00, 0x00, 0x00},
 			expected: false,
 		},
+		{
+			name:     "Valid WebP header",
+			data:     []byte("RIFF\x24\x00\x00\x00WEBPVP8 "),
+			expected: true,
+		},
+		{
+			name:     "Valid AVIF header",
+			data:     []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00"),
+			expected: true,
+		},
+		{
+			name:     "RIFF without WEBP form type",
+			data:     []byte("RIFF\x24\x00\x00\x00WAVEfmt "),
+			expected: false,
+		},
+		{
+			name:     "ISO BMFF without AVIF brand",
+			data:     []byte("\x00\x00\x00\x1cftypisom\x00\x00\x00\x00"),
+			expected: false,
+		},
 		{
 			name:     "Random bytes",
 			data:     []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07},
@@ -855,10 +876,10 @@ func TestIsThumbnailSupported(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 			w := httptest.NewRecorder()
-			result := isThumbnailSupported(w, "test/file.ext", tt.fileType)
+			result := isThumbnailSupported(w, httptest.NewRequest("GET", "/api/thumbnail/test/file.ext", http.NoBody), "test/file.ext", tt.fileType)
 
 			if result != tt.expectedOK {
-				t.Errorf("isThumbnailSupported(_, _, %s) = %v, want %v", tt.fileType, result, tt.expectedOK)
+				t.Errorf("isThumbnailSupported(_, _, _, %s) = %v, want %v", tt.fileType, result, tt.expectedOK)
 			}
 
 			if !tt.expectedOK && w.Code != tt.expectedStatus {
@@ -1048,7 +1069,7 @@ func TestValidateThumbnailFileOnDisk(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 			w := httptest.NewRecorder()
-			result := h.validateThumbnailFileOnDisk(w, tt.filePath, tt.fullPath)
+			result := h.validateThumbnailFileOnDisk(w, httptest.NewRequest("GET", "/api/thumbnail/"+tt.filePath, http.NoBody), tt.filePath, tt.fullPath)
 
 			if result != tt.expectedOK {
 				t.Errorf("validateThumbnailFileOnDisk() = %v, want %v", result, tt.expectedOK)
@@ -1088,7 +1109,7 @@ func TestValidateThumbnailFileOnDisk_PermissionError(t *testing.T) {
 	})
 
 	w := httptest.NewRecorder()
-	result := h.validateThumbnailFileOnDisk(w, "restricted/secret.jpg", restrictedFile)
+	result := h.validateThumbnailFileOnDisk(w, httptest.NewRequest("GET", "/api/thumbnail/restricted/secret.jpg", http.NoBody), "restricted/secret.jpg", restrictedFile)
 
 	if result {
 		t.Error("expected validation to fail for inaccessible file")
@@ -1134,7 +1155,7 @@ func TestWriteThumbnailResponse_ImageFile(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 	w := httptest.NewRecorder()
 
-	writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, thumb)
+	writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, thumb)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("expected status 200, got %d", w.Code)
@@ -1170,6 +1191,30 @@ func TestWriteThumbnailResponse_ImageFile(t *testing.T) {
 	}
 }
 
+func TestWriteThumbnailResponse_NegotiatedFormat(t *testing.T) {
+	t.Parallel()
+
+	thumb := []byte("RIFF\x24\x00\x00\x00WEBPVP8 ")
+	req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
+	req.Header.Set("Accept", "image/webp,*/*")
+	w := httptest.NewRecorder()
+
+	writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatWebP, thumb)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "image/webp" {
+		t.Errorf("expected Content-Type image/webp, got %q", contentType)
+	}
+	if vary := w.Header().Get("Vary"); vary != "Accept" {
+		t.Errorf("expected Vary: Accept, got %q", vary)
+	}
+	if w.Body.Len() != len(thumb) {
+		t.Errorf("expected body length %d, got %d", len(thumb), w.Body.Len())
+	}
+}
+
 func TestWriteThumbnailResponse_VideoFile(t *testing.T) {
 	t.Parallel()
 
@@ -1177,7 +1222,7 @@ func TestWriteThumbnailResponse_VideoFile(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/clip.mp4", http.NoBody)
 	w := httptest.NewRecorder()
 
-	writeThumbnailResponse(w, req, "clip.mp4", database.FileTypeVideo, thumb)
+	writeThumbnailResponse(w, req, "clip.mp4", database.FileTypeVideo, media.ThumbnailFormatDefault, thumb)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("expected status 200, got %d", w.Code)
@@ -1204,7 +1249,7 @@ func TestWriteThumbnailResponse_Folder(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photos", http.NoBody)
 	w := httptest.NewRecorder()
 
-	writeThumbnailResponse(w, req, "photos", database.FileTypeFolder, thumb)
+	writeThumbnailResponse(w, req, "photos", database.FileTypeFolder, media.ThumbnailFormatDefault, thumb)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("expected status 200, got %d", w.Code)
@@ -1234,7 +1279,7 @@ func TestWriteThumbnailResponse_ConditionalRequest304(t *testing.T) {
 	// First request to get the ETag
 	req1 := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 	w1 := httptest.NewRecorder()
-	writeThumbnailResponse(w1, req1, "photo.jpg", database.FileTypeImage, thumb)
+	writeThumbnailResponse(w1, req1, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, thumb)
 
 	etag := w1.Header().Get("ETag")
 	if etag == "" {
@@ -1245,7 +1290,7 @@ func TestWriteThumbnailResponse_ConditionalRequest304(t *testing.T) {
 	req2 := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 	req2.Header.Set("If-None-Match", etag)
 	w2 := httptest.NewRecorder()
-	writeThumbnailResponse(w2, req2, "photo.jpg", database.FileTypeImage, thumb)
+	writeThumbnailResponse(w2, req2, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, thumb)
 
 	if w2.Code != http.StatusNotModified {
 		t.Errorf("expected status 304, got %d", w2.Code)
@@ -1261,6 +1306,31 @@ func TestWriteThumbnailResponse_ConditionalRequest304(t *testing.T) {
 	}
 }
 
+func TestEtagMatches(t *testing.T) {
+	t.Parallel()
+
+	etag := `"abc123"`
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{`"abc123"`, true},
+		{`W/"abc123"`, true},
+		{`"other", "abc123"`, true},
+		{`"other",W/"abc123"`, true},
+		{`*`, true},
+		{`"other"`, false},
+		{`"abc1234"`, false},
+		{`abc123`, false},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.ifNoneMatch, etag); got != tt.want {
+			t.Errorf("etagMatches(%q, %q) = %v, want %v", tt.ifNoneMatch, etag, got, tt.want)
+		}
+	}
+}
+
 func TestWriteThumbnailResponse_ConditionalRequestStaleETag(t *testing.T) {
 	t.Parallel()
 
@@ -1270,7 +1340,7 @@ func TestWriteThumbnailResponse_ConditionalRequestStaleETag(t *testing.T) {
 	req.Header.Set("If-None-Match", `"stale-etag-value"`)
 	w := httptest.NewRecorder()
 
-	writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, thumb)
+	writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, thumb)
 
 	// Should return 200 with full body since ETag doesn't match
 	if w.Code != http.StatusOK {
@@ -1288,7 +1358,7 @@ func TestWriteThumbnailResponse_EmptyThumbnail(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 	w := httptest.NewRecorder()
 
-	writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, []byte{})
+	writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, []byte{})
 
 	// Should not write any body for empty thumbnail
 	if w.Body.Len() != 0 {
@@ -1304,7 +1374,7 @@ func TestWriteThumbnailResponse_InvalidFormat(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 	w := httptest.NewRecorder()
 
-	writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, invalidData)
+	writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, invalidData)
 
 	// Should not write body for invalid image format
 	if w.Body.Len() != 0 {
@@ -1320,11 +1390,11 @@ func TestWriteThumbnailResponse_ETagStability(t *testing.T) {
 	// Same thumbnail data should produce same ETag
 	req1 := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 	w1 := httptest.NewRecorder()
-	writeThumbnailResponse(w1, req1, "photo.jpg", database.FileTypeImage, thumb)
+	writeThumbnailResponse(w1, req1, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, thumb)
 
 	req2 := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 	w2 := httptest.NewRecorder()
-	writeThumbnailResponse(w2, req2, "photo.jpg", database.FileTypeImage, thumb)
+	writeThumbnailResponse(w2, req2, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, thumb)
 
 	etag1 := w1.Header().Get("ETag")
 	etag2 := w2.Header().Get("ETag")
@@ -1342,11 +1412,11 @@ func TestWriteThumbnailResponse_ETagDiffersForDifferentData(t *testing.T) {
 
 	req1 := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 	w1 := httptest.NewRecorder()
-	writeThumbnailResponse(w1, req1, "photo.jpg", database.FileTypeImage, thumb1)
+	writeThumbnailResponse(w1, req1, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, thumb1)
 
 	req2 := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 	w2 := httptest.NewRecorder()
-	writeThumbnailResponse(w2, req2, "photo.jpg", database.FileTypeFolder, thumb2)
+	writeThumbnailResponse(w2, req2, "photo.jpg", database.FileTypeFolder, media.ThumbnailFormatDefault, thumb2)
 
 	etag1 := w1.Header().Get("ETag")
 	etag2 := w2.Header().Get("ETag")
@@ -1457,7 +1527,7 @@ func BenchmarkWriteThumbnailResponse(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 		w := httptest.NewRecorder()
-		writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, thumb)
+		writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, thumb)
 	}
 }
 
@@ -1467,7 +1537,7 @@ func BenchmarkWriteThumbnailResponse_304(b *testing.B) {
 	// Get the ETag first
 	req0 := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 	w0 := httptest.NewRecorder()
-	writeThumbnailResponse(w0, req0, "photo.jpg", database.FileTypeImage, thumb)
+	writeThumbnailResponse(w0, req0, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, thumb)
 	etag := w0.Header().Get("ETag")
 
 	b.ResetTimer()
@@ -1475,7 +1545,7 @@ func BenchmarkWriteThumbnailResponse_304(b *testing.B) {
 		req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 		req.Header.Set("If-None-Match", etag)
 		w := httptest.NewRecorder()
-		writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, thumb)
+		writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, thumb)
 	}
 }
 
@@ -1510,7 +1580,7 @@ func TestWriteThumbnailResponse_SecurityHeaders(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/test", http.NoBody)
 			w := httptest.NewRecorder()
 
-			writeThumbnailResponse(w, req, "test", tt.fileType, tt.thumb)
+			writeThumbnailResponse(w, req, "test", tt.fileType, media.ThumbnailFormatDefault, tt.thumb)
 
 			assertSecurityHeaders(t, w)
 		})
@@ -1525,14 +1595,14 @@ func TestWriteThumbnailResponse_SecurityHeadersOn304(t *testing.T) {
 	// First request to get ETag
 	req1 := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 	w1 := httptest.NewRecorder()
-	writeThumbnailResponse(w1, req1, "photo.jpg", database.FileTypeImage, thumb)
+	writeThumbnailResponse(w1, req1, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, thumb)
 	etag := w1.Header().Get("ETag")
 
 	// Second request with matching ETag
 	req2 := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 	req2.Header.Set("If-None-Match", etag)
 	w2 := httptest.NewRecorder()
-	writeThumbnailResponse(w2, req2, "photo.jpg", database.FileTypeImage, thumb)
+	writeThumbnailResponse(w2, req2, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, thumb)
 
 	if w2.Code != http.StatusNotModified {
 		t.Fatalf("expected 304, got %d", w2.Code)
@@ -1548,7 +1618,7 @@ func TestWriteThumbnailResponse_SecurityHeadersOnEmptyThumbnail(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 	w := httptest.NewRecorder()
 
-	writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, []byte{})
+	writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, []byte{})
 
 	// Security headers must be present even when thumbnail is empty
 	assertSecurityHeaders(t, w)
@@ -1562,7 +1632,7 @@ func TestWriteThumbnailResponse_SecurityHeadersOnInvalidFormat(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/photo.jpg", http.NoBody)
 	w := httptest.NewRecorder()
 
-	writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, invalidData)
+	writeThumbnailResponse(w, req, "photo.jpg", database.FileTypeImage, media.ThumbnailFormatDefault, invalidData)
 
 	// Security headers must be present even when format is invalid
 	assertSecurityHeaders(t, w)
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"media-viewer/internal/logging"
+)
+
+// moveRequest is the body of a move request. Both paths are relative to the
+// media directory.
+type moveRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MoveFile moves or renames a file or folder within the media directory.
+// The index follows without a full scan: the moved entries keep their
+// favorites, tags and other data, cached thumbnails move with them, and the
+// folders moved out of and into are re-indexed. The destination must not
+// exist, and its folder must.
+// POST /api/move
+func (h *Handlers) MoveFile(w http.ResponseWriter, r *http.Request) {
+	// A client going away once the file is moved mustn't leave the index
+	// half updated
+	ctx := context.WithoutCancel(r.Context())
+
+	var req moveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	from, ok := h.resolveMediaPath(req.From)
+	if !ok {
+		httpError(w, r, "Invalid source path", http.StatusBadRequest)
+		return
+	}
+	to, ok := h.resolveMediaPath(req.To)
+	if !ok {
+		httpError(w, r, "Invalid destination path", http.StatusBadRequest)
+		return
+	}
+	if to == from || strings.HasPrefix(to, from+string(filepath.Separator)) {
+		httpError(w, r, "Cannot move a folder into itself", http.StatusBadRequest)
+		return
+	}
+
+	fromPath := filepath.Join(h.mediaDir, from)
+	toPath := filepath.Join(h.mediaDir, to)
+
+	if _, err := os.Lstat(fromPath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			httpError(w, r, "File not found", http.StatusNotFound)
+		} else {
+			logging.Error("MoveFile: failed to access %s: %v", from, err)
+			httpError(w, r, "Failed to access file", http.StatusInternalServerError)
+		}
+		return
+	}
+	if _, err := os.Lstat(toPath); err == nil {
+		httpError(w, r, "Destination already exists", http.StatusConflict)
+		return
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		logging.Error("MoveFile: failed to access %s: %v", to, err)
+		httpError(w, r, "Failed to access file", http.StatusInternalServerError)
+		return
+	}
+	if info, err := os.Stat(filepath.Dir(toPath)); err != nil || !info.IsDir() {
+		httpError(w, r, "Destination folder not found", http.StatusNotFound)
+		return
+	}
+
+	if err := os.Rename(fromPath, toPath); err != nil {
+		logging.Error("MoveFile: failed to move %s to %s: %v", from, to, err)
+		httpError(w, r, "Failed to move file", http.StatusInternalServerError)
+		return
+	}
+	logging.Info("Moved %s to %s", from, to)
+
+	fromRel, toRel := filepath.ToSlash(from), filepath.ToSlash(to)
+	moved, err := h.db.MoveFiles(ctx, fromRel, toRel)
+	if err != nil {
+		// The move itself succeeded; a full index brings the index in line,
+		// though what was kept by path stays with the old path
+		logging.Error("MoveFile: failed to update the index for %s: %v", from, err)
+		//nolint:contextcheck // Intentionally not passing request context - indexing runs in background
+		h.indexer.TriggerIndex()
+	}
+
+	if h.thumbGen != nil {
+		for _, file := range moved {
+			newPath := toRel + strings.TrimPrefix(file.Path, fromRel)
+			if err := h.thumbGen.MoveThumbnail(filepath.Join(h.mediaDir, file.Path), filepath.Join(h.mediaDir, newPath), file.Type); err != nil {
+				logging.Warn("Failed to move thumbnail of %s: %v", file.Path, err)
+			}
+		}
+	}
+	h.invalidateFolderThumbnails(from)
+	h.invalidateFolderThumbnails(to)
+
+	// The folders moved out of and into changed; everything below them
+	// was moved in the index above
+	dirs := []string{filepath.Dir(from)}
+	if dir := filepath.Dir(to); dir != dirs[0] {
+		dirs = append(dirs, dir)
+	}
+	for _, dir := range dirs {
+		if err := h.indexer.IndexDirectory(dir); err != nil {
+			logging.Warn("Failed to re-index %s after a move: %v", dir, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, map[string]interface{}{
+		"status": "ok",
+		"from":   fromRel,
+		"to":     toRel,
+		"moved":  len(moved),
+	})
+}
+
+// resolveMediaPath cleans a path given to MoveFile, DeleteFile or
+// DeleteFiles, relative to the media directory. It must be inside the media
+// directory and not be its root.
+func (h *Handlers) resolveMediaPath(path string) (string, bool) {
+	if path == "" || filepath.IsAbs(path) {
+		return "", false
+	}
+
+	absPath, err := filepath.Abs(filepath.Join(h.mediaDir, path))
+	if err != nil || !isSubPath(h.mediaDir, absPath) {
+		return "", false
+	}
+	mediaDir, err := filepath.Abs(h.mediaDir)
+	if err != nil {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(mediaDir, absPath)
+	if err != nil || rel == "." {
+		return "", false
+	}
+	return rel, true
+}
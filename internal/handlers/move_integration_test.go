@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"media-viewer/internal/database"
+)
+
+func TestMoveFileIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, dir := range []string{"trip", "archive"} {
+		if err := os.MkdirAll(filepath.Join(h.mediaDir, dir), 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+	for _, name := range []string{"trip/beach.jpg", "trip/sea.jpg", "archive/old.jpg"} {
+		if err := os.WriteFile(filepath.Join(h.mediaDir, name), []byte("test"), 0o644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	addExistingFileToDatabase(t, h, "trip", database.FileTypeFolder)
+	addExistingFileToDatabase(t, h, "trip/beach.jpg", database.FileTypeImage)
+	addExistingFileToDatabase(t, h, "trip/sea.jpg", database.FileTypeImage)
+	if err := h.db.AddTagToFile(ctx, "trip/beach.jpg", "summer"); err != nil {
+		t.Fatalf("failed to tag file: %v", err)
+	}
+
+	move := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/move", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.MoveFile(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"invalid body", `{`, http.StatusBadRequest},
+		{"missing source", `{"to":"x.jpg"}`, http.StatusBadRequest},
+		{"outside media dir", `{"from":"../etc/passwd","to":"passwd"}`, http.StatusBadRequest},
+		{"absolute destination", `{"from":"trip/sea.jpg","to":"/tmp/sea.jpg"}`, http.StatusBadRequest},
+		{"into itself", `{"from":"trip","to":"trip/inner"}`, http.StatusBadRequest},
+		{"not found", `{"from":"trip/missing.jpg","to":"missing.jpg"}`, http.StatusNotFound},
+		{"destination exists", `{"from":"trip/sea.jpg","to":"archive/old.jpg"}`, http.StatusConflict},
+		{"destination folder missing", `{"from":"trip/sea.jpg","to":"nowhere/sea.jpg"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := move(tt.body); w.Code != tt.expected {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.expected, w.Body.String())
+			}
+		})
+	}
+
+	w := move(`{"from":"trip","to":"archive/trip-2024"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp struct {
+		To    string `json:"to"`
+		Moved int    `json:"moved"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.To != "archive/trip-2024" || resp.Moved != 3 {
+		t.Errorf("response = %+v, want 3 entries moved to archive/trip-2024", resp)
+	}
+
+	if _, err := os.Stat(filepath.Join(h.mediaDir, "archive/trip-2024/beach.jpg")); err != nil {
+		t.Errorf("Expected the folder to be moved on disk: %v", err)
+	}
+	if _, err := h.db.GetFileByPath(ctx, "archive/trip-2024/beach.jpg"); err != nil {
+		t.Errorf("Expected the index to follow the move: %v", err)
+	}
+	if tags, _ := h.db.GetFileTags(ctx, "archive/trip-2024/beach.jpg"); len(tags) != 1 || tags[0] != "summer" {
+		t.Errorf("tags = %v, want the tag to move with the file", tags)
+	}
+	// The folder moved into was re-indexed, picking up its other file
+	if _, err := h.db.GetFileByPath(ctx, "archive/old.jpg"); err != nil {
+		t.Errorf("Expected the destination folder to be re-indexed: %v", err)
+	}
+}
+
+func TestMoveFileClientGoneIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	if err := os.WriteFile(filepath.Join(h.mediaDir, "beach.jpg"), []byte("test"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	addExistingFileToDatabase(t, h, "beach.jpg", database.FileTypeImage)
+	if err := h.db.AddTagToFile(context.Background(), "beach.jpg", "summer"); err != nil {
+		t.Fatalf("failed to tag file: %v", err)
+	}
+
+	// The client is gone before the index is updated
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodPost, "/api/move", strings.NewReader(`{"from":"beach.jpg","to":"sea.jpg"}`)).WithContext(ctx)
+	w := httptest.NewRecorder()
+	h.MoveFile(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	tags, err := h.db.GetFileTags(context.Background(), "sea.jpg")
+	if err != nil {
+		t.Fatalf("GetFileTags failed: %v", err)
+	}
+	if len(tags) != 1 || tags[0] != "summer" {
+		t.Errorf("tags of the moved file = %v, want [summer]", tags)
+	}
+}
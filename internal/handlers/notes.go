@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/logging"
+)
+
+// NoteRequest represents a request to set the note on a file
+type NoteRequest struct {
+	Path        string `json:"path"`
+	Description string `json:"description"`
+}
+
+// SetFileNote attaches a note to a file, or removes it if the description is empty
+func (h *Handlers) SetFileNote(w http.ResponseWriter, r *http.Request) {
+	var req NoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Path == "" {
+		httpError(w, r, "Path is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.SetFileDescription(r.Context(), req.Path, req.Description); err != nil {
+		if errors.Is(err, database.ErrDescriptionTooLong) {
+			httpError(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logging.Error("SetFileNote error for %s: %v", req.Path, err)
+		httpError(w, r, "Failed to set note", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSONStatus(w, "ok")
+}
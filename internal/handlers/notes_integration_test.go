@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"media-viewer/internal/database"
+)
+
+// TestSetFileNoteIntegration tests setting, searching and clearing a note
+func TestSetFileNoteIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, mediaDir, cleanup := setupTagsIntegrationTest(t)
+	defer cleanup()
+
+	addTagTestFile(t, h.db, mediaDir, "IMG_1234.jpg", database.FileTypeImage)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/file/note", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		h.SetFileNote(w, req)
+		return w
+	}
+
+	if w := put(`{"path":"IMG_1234.jpg","description":"First day of school"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search?q=school", http.NoBody)
+	w := httptest.NewRecorder()
+	h.Search(w, req)
+
+	var result database.SearchResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(result.Items) != 1 || result.Items[0].Description != "First day of school" {
+		t.Fatalf("expected the note to be found with its description, got %+v", result.Items)
+	}
+
+	if w := put(`{"path":"IMG_1234.jpg","description":""}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 clearing the note, got %d", w.Code)
+	}
+	if got, _ := h.db.GetFileDescription(context.Background(), "IMG_1234.jpg"); got != "" {
+		t.Errorf("expected the note to be cleared, got %q", got)
+	}
+}
+
+// TestSetFileNoteInvalidIntegration tests rejected note requests
+func TestSetFileNoteInvalidIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, _, cleanup := setupTagsIntegrationTest(t)
+	defer cleanup()
+
+	tooLong, _ := json.Marshal(NoteRequest{Path: "a.jpg", Description: strings.Repeat("x", database.MaxDescriptionLength+1)})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid JSON", "{"},
+		{"missing path", `{"description":"note"}`},
+		{"too long", string(tooLong)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/file/note", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			h.SetFileNote(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
@@ -15,7 +15,7 @@ import (
 func (h *Handlers) ListPlaylists(w http.ResponseWriter, r *http.Request) {
 	playlists, err := h.db.GetAllPlaylists(r.Context())
 	if err != nil {
-		http.Error(w, "Failed to get playlists", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get playlists", http.StatusInternalServerError)
 		return
 	}
 
@@ -36,7 +36,7 @@ func (h *Handlers) GetPlaylist(w http.ResponseWriter, r *http.Request) {
 	// Find the playlist file
 	playlists, err := h.db.GetAllPlaylists(r.Context())
 	if err != nil {
-		http.Error(w, "Failed to get playlists", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get playlists", http.StatusInternalServerError)
 		return
 	}
 
@@ -50,13 +50,13 @@ func (h *Handlers) GetPlaylist(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if playlistPath == "" {
-		http.Error(w, "Playlist not found", http.StatusNotFound)
+		httpError(w, r, "Playlist not found", http.StatusNotFound)
 		return
 	}
 
 	pl, err := playlist.ParseWPL(playlistPath, h.mediaDir)
 	if err != nil {
-		http.Error(w, "Failed to parse playlist", http.StatusInternalServerError)
+		httpError(w, r, "Failed to parse playlist", http.StatusInternalServerError)
 		return
 	}
 
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/logging"
+)
+
+// SetVideoPoster sets the time a video's thumbnail is taken from, overriding
+// THUMBNAIL_VIDEO_SEEK for that video. The cached thumbnail is dropped so the
+// new frame is shown the next time it's requested.
+// PUT /api/poster/{path}?time={seconds or duration}
+func (h *Handlers) SetVideoPoster(w http.ResponseWriter, r *http.Request) {
+	filePath, ok := h.validatePosterRequest(w, r)
+	if !ok {
+		return
+	}
+
+	offset, err := parsePosterTime(r.URL.Query().Get("time"))
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.SetVideoPoster(r.Context(), filePath, offset); err != nil {
+		logging.Error("SetVideoPoster error for %s: %v", filePath, err)
+		httpError(w, r, "Failed to set poster time", http.StatusInternalServerError)
+		return
+	}
+
+	h.invalidatePosterThumbnails(filePath)
+	writeJSONStatus(w, "ok")
+}
+
+// ClearVideoPoster removes a video's poster time, returning its thumbnail to
+// the configured seek strategy
+// DELETE /api/poster/{path}
+func (h *Handlers) ClearVideoPoster(w http.ResponseWriter, r *http.Request) {
+	filePath, ok := h.validatePosterRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.ClearVideoPoster(r.Context(), filePath); err != nil {
+		logging.Error("ClearVideoPoster error for %s: %v", filePath, err)
+		httpError(w, r, "Failed to clear poster time", http.StatusInternalServerError)
+		return
+	}
+
+	h.invalidatePosterThumbnails(filePath)
+	writeJSONStatus(w, "ok")
+}
+
+// validatePosterRequest checks that a poster request names an indexed video.
+// Returns its path, or writes an HTTP error and returns false.
+func (h *Handlers) validatePosterRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	filePath := mux.Vars(r)["path"]
+	if filePath == "" {
+		httpError(w, r, "Path is required", http.StatusBadRequest)
+		return "", false
+	}
+
+	file, err := h.db.GetFileByPath(r.Context(), filePath)
+	if err != nil {
+		httpError(w, r, "File not found", http.StatusNotFound)
+		return "", false
+	}
+	if file.Type != database.FileTypeVideo {
+		httpError(w, r, "Poster times can only be set for videos", http.StatusBadRequest)
+		return "", false
+	}
+	return filePath, true
+}
+
+// invalidatePosterThumbnails drops the cached thumbnails showing a video's
+// poster frame: its own, and those of the folders it appears in
+func (h *Handlers) invalidatePosterThumbnails(filePath string) {
+	if h.thumbGen == nil {
+		return
+	}
+	if err := h.thumbGen.InvalidateThumbnail(filepath.Join(h.mediaDir, filePath)); err != nil {
+		logging.Warn("Failed to invalidate thumbnail for %s: %v", filePath, err)
+	}
+	h.invalidateFolderThumbnails(filePath)
+}
+
+// parsePosterTime parses a poster time given in seconds ("90", "12.5") or as
+// a duration ("1m30s")
+func parsePosterTime(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, errors.New("time is required")
+	}
+
+	var offset time.Duration
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+			return 0, fmt.Errorf("invalid time %q", value)
+		}
+		offset = time.Duration(seconds * float64(time.Second))
+	} else if offset, err = time.ParseDuration(value); err != nil {
+		return 0, fmt.Errorf("invalid time %q (use seconds like \"90\" or a duration like \"1m30s\")", value)
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("time %q must not be negative", value)
+	}
+	return offset, nil
+}
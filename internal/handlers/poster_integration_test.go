@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"media-viewer/internal/database"
+)
+
+func TestParsePosterTime(t *testing.T) {
+	valid := map[string]time.Duration{
+		"0":     0,
+		"90":    90 * time.Second,
+		" 12.5": 12500 * time.Millisecond,
+		"1m30s": 90 * time.Second,
+		"250ms": 250 * time.Millisecond,
+	}
+	for value, want := range valid {
+		got, err := parsePosterTime(value)
+		if err != nil {
+			t.Errorf("parsePosterTime(%q) returned error: %v", value, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("parsePosterTime(%q) = %v, want %v", value, got, want)
+		}
+	}
+
+	for _, value := range []string{"", "-5", "-1s", "soon", "NaN", "inf"} {
+		if _, err := parsePosterTime(value); err == nil {
+			t.Errorf("parsePosterTime(%q) expected error", value)
+		}
+	}
+}
+
+func TestVideoPosterIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTestWithThumbnails(t)
+	defer cleanup()
+
+	for _, name := range []string{"clip.mp4", "photo.jpg"} {
+		if err := os.WriteFile(filepath.Join(h.mediaDir, name), []byte("test"), 0o644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	addExistingFileToDatabase(t, h, "clip.mp4", database.FileTypeVideo)
+	addExistingFileToDatabase(t, h, "photo.jpg", database.FileTypeImage)
+
+	request := func(handler http.HandlerFunc, method, path, offset string) *httptest.ResponseRecorder {
+		query := url.Values{}
+		if offset != "" {
+			query.Set("time", offset)
+		}
+		req := httptest.NewRequest(method, "/api/poster/"+path+"?"+query.Encode(), http.NoBody)
+		req = mux.SetURLVars(req, map[string]string{"path": path})
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		offset   string
+		expected int
+	}{
+		{"missing path", "", "10", http.StatusBadRequest},
+		{"not indexed", "missing.mp4", "10", http.StatusNotFound},
+		{"image", "photo.jpg", "10", http.StatusBadRequest},
+		{"missing time", "clip.mp4", "", http.StatusBadRequest},
+		{"negative time", "clip.mp4", "-3", http.StatusBadRequest},
+		{"seconds", "clip.mp4", "42.5", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := request(h.SetVideoPoster, http.MethodPut, tt.path, tt.offset); w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	ctx := context.Background()
+	offset, ok, err := h.db.GetVideoPoster(ctx, "clip.mp4")
+	if err != nil || !ok || offset != 42500*time.Millisecond {
+		t.Errorf("GetVideoPoster = %v, %v, %v; want 42.5s", offset, ok, err)
+	}
+
+	if w := request(h.ClearVideoPoster, http.MethodDelete, "clip.mp4", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 clearing the poster, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, ok, _ := h.db.GetVideoPoster(ctx, "clip.mp4"); ok {
+		t.Error("Expected the poster to be cleared")
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"media-viewer/internal/database"
+)
+
+// errConflictingTagFilters is returned when untagged and hasTag disagree
+var errConflictingTagFilters = errors.New("untagged and hasTag conflict")
+
+// parsePresenceFilter reads the hasTag, untagged and favorited query
+// parameters shared by listings and search. untagged=true is shorthand for
+// hasTag=false; giving both is allowed as long as they agree.
+func parsePresenceFilter(query url.Values) (database.PresenceFilter, error) {
+	var filter database.PresenceFilter
+
+	tagged, err := optionalBool(query, "hasTag")
+	if err != nil {
+		return filter, err
+	}
+	untagged, err := optionalBool(query, "untagged")
+	if err != nil {
+		return filter, err
+	}
+	if untagged != nil {
+		notUntagged := !*untagged
+		if tagged != nil && *tagged != notUntagged {
+			return filter, errConflictingTagFilters
+		}
+		tagged = &notUntagged
+	}
+	filter.Tagged = tagged
+
+	filter.Favorited, err = optionalBool(query, "favorited")
+	return filter, err
+}
+
+// optionalBool parses a boolean query parameter, returning nil if it's absent
+func optionalBool(query url.Values, name string) (*bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: must be true or false", name)
+	}
+	return &b, nil
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestParsePresenceFilter(t *testing.T) {
+	tests := []struct {
+		query     string
+		tagged    string
+		favorited string
+		wantErr   bool
+	}{
+		{"", "any", "any", false},
+		{"untagged=true", "false", "any", false},
+		{"untagged=false", "true", "any", false},
+		{"hasTag=false", "false", "any", false},
+		{"hasTag=1&untagged=0", "true", "any", false},
+		{"favorited=false", "any", "false", false},
+		{"untagged=true&favorited=true", "false", "true", false},
+		{"untagged=true&hasTag=true", "", "", true},
+		{"hasTag=sometimes", "", "", true},
+		{"favorited=maybe", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("failed to parse query: %v", err)
+			}
+			filter, err := parsePresenceFilter(values)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", filter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := "tagged=" + tt.tagged + " favorited=" + tt.favorited; filter.String() != want {
+				t.Errorf("expected %q, got %q", want, filter.String())
+			}
+		})
+	}
+
+	values, _ := url.ParseQuery("untagged=true&hasTag=true")
+	if _, err := parsePresenceFilter(values); !errors.Is(err, errConflictingTagFilters) {
+		t.Errorf("expected errConflictingTagFilters, got %v", err)
+	}
+}
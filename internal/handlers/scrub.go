@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/md5" //nolint:gosec // MD5 used for cache key generation, not security
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/logging"
+)
+
+// GetScrubVTT returns a WebVTT track of thumbnail previews for scrubbing
+// through a video. Each cue points to a region of the sprite sheet served by
+// GetScrubSprite.
+// GET /api/scrub/{path}
+func (h *Handlers) GetScrubVTT(w http.ResponseWriter, r *http.Request) {
+	filePath, fullPath, ok := h.validateScrubRequest(w, r)
+	if !ok {
+		return
+	}
+
+	vtt, err := h.thumbGen.GetScrubVTT(r.Context(), fullPath, scrubSpriteURL(filePath))
+	if err != nil {
+		logging.Error("Scrub: generation failed for %s: %v", filePath, err)
+		httpError(w, r, "Failed to generate scrub previews", http.StatusInternalServerError)
+		return
+	}
+
+	writeScrubResponse(w, r, "text/vtt; charset=utf-8", vtt)
+}
+
+// GetScrubSprite returns the JPEG sprite sheet of a video's scrub previews
+// GET /api/scrub-sprite/{path}
+func (h *Handlers) GetScrubSprite(w http.ResponseWriter, r *http.Request) {
+	filePath, fullPath, ok := h.validateScrubRequest(w, r)
+	if !ok {
+		return
+	}
+
+	sprite, err := h.thumbGen.GetScrubSprite(r.Context(), fullPath, scrubSpriteURL(filePath))
+	if err != nil {
+		logging.Error("Scrub: generation failed for %s: %v", filePath, err)
+		httpError(w, r, "Failed to generate scrub previews", http.StatusInternalServerError)
+		return
+	}
+
+	writeScrubResponse(w, r, "image/jpeg", sprite)
+}
+
+// validateScrubRequest checks that a scrub request is for a video on disk
+// that isn't flagged as sensitive, whose previews would give it away.
+// Returns the relative and absolute path, or writes an HTTP error and
+// returns false.
+func (h *Handlers) validateScrubRequest(w http.ResponseWriter, r *http.Request) (filePath, fullPath string, ok bool) {
+	filePath, fullPath, ok = h.validateThumbnailPath(w, r)
+	if !ok {
+		return "", "", false
+	}
+
+	if !h.thumbGen.IsEnabled() {
+		httpError(w, r, "Thumbnails disabled", http.StatusServiceUnavailable)
+		return "", "", false
+	}
+
+	file, err := h.db.GetFileByPath(r.Context(), filePath)
+	if err != nil {
+		httpError(w, r, "File not found", http.StatusNotFound)
+		return "", "", false
+	}
+	if file.Type != database.FileTypeVideo {
+		httpError(w, r, "Scrub previews are only available for videos", http.StatusBadRequest)
+		return "", "", false
+	}
+	if h.isSensitive(r.Context(), filePath) {
+		httpError(w, r, "Scrub previews are not available for sensitive files", http.StatusForbidden)
+		return "", "", false
+	}
+
+	if !h.validateThumbnailFileOnDisk(w, r, filePath, fullPath) {
+		return "", "", false
+	}
+	return filePath, fullPath, true
+}
+
+// scrubSpriteURL returns the URL GetScrubSprite serves a video's sprite sheet at
+func scrubSpriteURL(filePath string) string {
+	segments := strings.Split(filePath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return "/api/scrub-sprite/" + strings.Join(segments, "/")
+}
+
+// writeScrubResponse writes a sprite sheet or track. Both change whenever the
+// video does, so browsers revalidate them against the ETag.
+func writeScrubResponse(w http.ResponseWriter, r *http.Request, contentType string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=3600, must-revalidate")
+	w.Header().Set("ETag", fmt.Sprintf(`"%x"`, md5.Sum(data))) //nolint:gosec // MD5 used for cache key generation, not security
+
+	// ServeContent answers If-None-Match and Range requests against the ETag
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"media-viewer/internal/database"
+
+	"github.com/gorilla/mux"
+)
+
+func TestScrubSpriteURL(t *testing.T) {
+	tests := map[string]string{
+		"clip.mp4":               "/api/scrub-sprite/clip.mp4",
+		"holiday 2024/clip.mp4":  "/api/scrub-sprite/holiday%202024/clip.mp4",
+		"a/b#1/clip?.mp4":        "/api/scrub-sprite/a/b%231/clip%3F.mp4",
+		"nested/deeper/clip.mkv": "/api/scrub-sprite/nested/deeper/clip.mkv",
+	}
+	for path, want := range tests {
+		if got := scrubSpriteURL(path); got != want {
+			t.Errorf("scrubSpriteURL(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestGetScrubVTTValidationIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTestWithThumbnails(t)
+	defer cleanup()
+
+	for _, name := range []string{"photo.jpg", "clip.mp4", "private.mp4"} {
+		if err := os.WriteFile(filepath.Join(h.mediaDir, name), []byte("test"), 0o644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	addExistingFileToDatabase(t, h, "photo.jpg", database.FileTypeImage)
+	addExistingFileToDatabase(t, h, "clip.mp4", database.FileTypeVideo)
+	addExistingFileToDatabase(t, h, "private.mp4", database.FileTypeVideo)
+	if err := h.db.SetFileSensitive(context.Background(), "private.mp4", true); err != nil {
+		t.Fatalf("SetFileSensitive failed: %v", err)
+	}
+	if err := os.Remove(filepath.Join(h.mediaDir, "clip.mp4")); err != nil {
+		t.Fatalf("failed to remove clip.mp4: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"photo.jpg", http.StatusBadRequest},
+		{"private.mp4", http.StatusForbidden},
+		{"clip.mp4", http.StatusNotFound},
+		{"missing.mp4", http.StatusNotFound},
+		{"../outside.mp4", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			for name, handler := range map[string]http.HandlerFunc{"vtt": h.GetScrubVTT, "sprite": h.GetScrubSprite} {
+				req := httptest.NewRequest(http.MethodGet, "/api/scrub/"+tt.path, http.NoBody)
+				req = mux.SetURLVars(req, map[string]string{"path": tt.path})
+				w := httptest.NewRecorder()
+				handler(w, req)
+
+				if w.Code != tt.expected {
+					t.Errorf("%s: expected status %d, got %d: %s", name, tt.expected, w.Code, w.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -2,18 +2,32 @@ package handlers
 
 import (
 	"net/http"
+	"sort"
 	"strconv"
 
 	"media-viewer/internal/database"
+	"media-viewer/internal/i18n"
+	"media-viewer/internal/logging"
+	"media-viewer/internal/media"
 )
 
-// Search searches for media files matching a query
+// defaultColorThreshold is the maximum palette distance for color search results.
+// Distances range from 0 (identical) to about 765 (black vs white).
+const defaultColorThreshold = 100.0
+
+// maxColorPageSize caps the page size of color search results, as the
+// database caps the other searches
+const maxColorPageSize = 200
+
+// Search searches for media files matching a query. Results are ranked by
+// relevance unless sort=name is given.
 func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
 	opts := database.SearchOptions{
 		Query:      r.URL.Query().Get("q"),
 		FilterType: r.URL.Query().Get("type"),
 		Page:       1,
 		PageSize:   50,
+		Locale:     i18n.FromRequest(r),
 	}
 
 	if page, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && page > 0 {
@@ -23,7 +37,26 @@ func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
 		opts.PageSize = pageSize
 	}
 
-	if opts.Query == "" {
+	mode, err := database.ParseSearchMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.Mode = mode
+
+	opts.SortField, err = database.ParseSearchSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts.Presence, err = parsePresenceFilter(r.URL.Query())
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if opts.Query == "" && !opts.Presence.IsSet() {
 		w.Header().Set("Content-Type", "application/json")
 		writeJSON(w, database.SearchResult{
 			Items:      []database.MediaFile{},
@@ -51,6 +84,15 @@ func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if result.TotalItems == 0 && h.searchDidYouMean > 0 {
+		suggestions, err := h.db.SimilarSuggestions(r.Context(), opts.Query, h.searchDidYouMean)
+		if err != nil {
+			logging.Warn("Failed to find suggestions for search %q: %v", opts.Query, err)
+		} else {
+			result.Suggestions = suggestions
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	writeJSON(w, result)
 }
@@ -65,7 +107,7 @@ func (h *Handlers) SearchSuggestions(w http.ResponseWriter, r *http.Request) {
 
 	suggestions, err := h.db.SearchSuggestions(r.Context(), query, limit)
 	if err != nil {
-		http.Error(w, "Search suggestions failed", http.StatusInternalServerError)
+		httpError(w, r, "Search suggestions failed", http.StatusInternalServerError)
 		return
 	}
 
@@ -76,3 +118,75 @@ func (h *Handlers) SearchSuggestions(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	writeJSON(w, suggestions)
 }
+
+// SearchByColor ranks files by how close their dominant colors are to a target color.
+// Only files with a stored palette (PALETTE_EXTRACTION enabled) can match.
+func (h *Handlers) SearchByColor(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	target, err := media.ParseHexColor(query.Get("hex"))
+	if err != nil {
+		httpError(w, r, "Invalid or missing hex color", http.StatusBadRequest)
+		return
+	}
+
+	threshold := defaultColorThreshold
+	if value := query.Get("threshold"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 {
+			httpError(w, r, "Invalid threshold", http.StatusBadRequest)
+			return
+		}
+		threshold = parsed
+	}
+
+	page := 1
+	pageSize := 50
+	if p, err := strconv.Atoi(query.Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	if ps, err := strconv.Atoi(query.Get("pageSize")); err == nil && ps > 0 {
+		pageSize = min(ps, maxColorPageSize)
+	}
+
+	palettes, err := h.db.GetAllFilePalettes(r.Context())
+	if err != nil {
+		httpError(w, r, "Color search failed", http.StatusInternalServerError)
+		return
+	}
+
+	matches := make([]database.ColorMatch, 0)
+	for _, entry := range palettes {
+		distance := media.PaletteDistance(target, entry.Colors)
+		if distance > threshold {
+			continue
+		}
+		matches = append(matches, database.ColorMatch{
+			MediaFile: entry.File,
+			Palette:   entry.Colors,
+			Distance:  distance,
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].Path < matches[j].Path
+	})
+
+	total := len(matches)
+	start := min(min(page-1, total)*pageSize, total)
+	end := min(start+pageSize, total)
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, database.ColorSearchResult{
+		Items:      matches[start:end],
+		Color:      media.FormatHexColor(target),
+		Threshold:  threshold,
+		TotalItems: total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: (total + pageSize - 1) / pageSize,
+	})
+}
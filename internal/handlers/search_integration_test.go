@@ -3,10 +3,14 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
 
 	"media-viewer/internal/database"
@@ -276,6 +280,179 @@ func TestSearchWithPaginationIntegration(t *testing.T) {
 	}
 }
 
+// TestSearchWithModeIntegration tests the mode parameter
+func TestSearchWithModeIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, mediaDir, cleanup := setupSearchIntegrationTest(t)
+	defer cleanup()
+
+	addSearchTestFile(t, h.db, mediaDir, "vacation/beach.jpg", database.FileTypeImage)
+	addSearchTestFile(t, h.db, mediaDir, "pets/cat.jpg", database.FileTypeImage)
+	addSearchTestFile(t, h.db, mediaDir, "pets/concatenate.jpg", database.FileTypeImage)
+
+	tests := []struct {
+		mode     string
+		expected int
+	}{
+		{"", 3},
+		{"substring", 3},
+		{"exact", 1},
+		{"filename", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/search?q=cat&mode="+tt.mode, http.NoBody)
+			w := httptest.NewRecorder()
+
+			h.Search(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+
+			var result database.SearchResult
+			if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if result.TotalItems != tt.expected {
+				t.Errorf("expected %d items, got %d", tt.expected, result.TotalItems)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search?q=cat&mode=fuzzy", http.NoBody)
+	w := httptest.NewRecorder()
+	h.Search(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown mode, got %d", w.Code)
+	}
+}
+
+// TestSearchWithSortIntegration tests the sort parameter
+func TestSearchWithSortIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, mediaDir, cleanup := setupSearchIntegrationTest(t)
+	defer cleanup()
+
+	addSearchTestFile(t, h.db, mediaDir, "albums/harbor/day one/anchor.jpg", database.FileTypeImage)
+	addSearchTestFile(t, h.db, mediaDir, "harbor.jpg", database.FileTypeImage)
+
+	tests := []struct {
+		sort  string
+		first string
+	}{
+		{"", "harbor.jpg"},
+		{"relevance", "harbor.jpg"},
+		{"name", "albums/harbor/day one/anchor.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/search?q=harbor&sort="+tt.sort, http.NoBody)
+			w := httptest.NewRecorder()
+
+			h.Search(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+
+			var result database.SearchResult
+			if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(result.Items) != 2 {
+				t.Fatalf("expected 2 items, got %d", len(result.Items))
+			}
+			if result.Items[0].Path != tt.first {
+				t.Errorf("expected %s first, got %s", tt.first, result.Items[0].Path)
+			}
+			if result.Items[0].Score <= 0 {
+				t.Errorf("expected a relevance score, got %v", result.Items[0].Score)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search?q=harbor&sort=date", http.NoBody)
+	w := httptest.NewRecorder()
+	h.Search(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown sort, got %d", w.Code)
+	}
+}
+
+// TestSearchPresenceIntegration tests the untagged, hasTag and favorited filters
+func TestSearchPresenceIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, mediaDir, cleanup := setupSearchIntegrationTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	addSearchTestFile(t, h.db, mediaDir, "sorted.jpg", database.FileTypeImage)
+	addSearchTestFile(t, h.db, mediaDir, "loved.jpg", database.FileTypeImage)
+	addSearchTestFile(t, h.db, mediaDir, "pile.jpg", database.FileTypeImage)
+	if err := h.db.AddTagToFile(ctx, "sorted.jpg", "done"); err != nil {
+		t.Fatalf("AddTagToFile failed: %v", err)
+	}
+	if err := h.db.AddFavorite(ctx, "loved.jpg", "loved.jpg", database.FileTypeImage); err != nil {
+		t.Fatalf("AddFavorite failed: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"untagged=true", []string{"loved.jpg", "pile.jpg"}},
+		{"hasTag=true", []string{"sorted.jpg"}},
+		{"q=jpg&untagged=true&favorited=false", []string{"pile.jpg"}},
+		{"type=image&favorited=true", []string{"loved.jpg"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/search?"+tt.query, http.NoBody)
+			w := httptest.NewRecorder()
+
+			h.Search(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var result database.SearchResult
+			if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			got := make([]string, 0, len(result.Items))
+			for _, item := range result.Items {
+				got = append(got, item.Path)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	for _, query := range []string{"untagged=maybe", "untagged=true&hasTag=true"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/search?"+query, http.NoBody)
+		w := httptest.NewRecorder()
+
+		h.Search(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
+
 // TestSearchNoResultsIntegration tests search with no matches
 func TestSearchNoResultsIntegration(t *testing.T) {
 	if testing.Short() {
@@ -699,3 +876,131 @@ func TestSearchSuggestionsInvalidLimitIntegration(t *testing.T) {
 		})
 	}
 }
+
+// TestSearchDidYouMeanIntegration tests suggestions for searches that find nothing
+func TestSearchDidYouMeanIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, mediaDir, cleanup := setupSearchIntegrationTest(t)
+	defer cleanup()
+
+	addSearchTestFile(t, h.db, mediaDir, "beach/sunset.jpg", database.FileTypeImage)
+
+	search := func(query string) database.SearchResult {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/search?q="+query, http.NoBody)
+		w := httptest.NewRecorder()
+		h.Search(w, req)
+
+		var result database.SearchResult
+		if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return result
+	}
+
+	if result := search("sunste"); len(result.Suggestions) != 0 {
+		t.Errorf("expected no suggestions while disabled, got %+v", result.Suggestions)
+	}
+
+	h.searchDidYouMean = 5
+	result := search("sunste")
+	if result.TotalItems != 0 || len(result.Suggestions) != 1 || result.Suggestions[0].Path != "beach/sunset.jpg" {
+		t.Errorf("expected a suggestion for sunset.jpg, got %+v", result)
+	}
+
+	if result := search("sunset"); result.TotalItems != 1 || len(result.Suggestions) != 0 {
+		t.Errorf("expected no suggestions when the search matches, got %+v", result)
+	}
+}
+
+// TestSearchByColorIntegration tests ranking files by dominant color
+func TestSearchByColorIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, mediaDir, cleanup := setupSearchIntegrationTest(t)
+	defer cleanup()
+
+	addSearchTestFile(t, h.db, mediaDir, "ocean.jpg", database.FileTypeImage)
+	addSearchTestFile(t, h.db, mediaDir, "sky.jpg", database.FileTypeImage)
+	addSearchTestFile(t, h.db, mediaDir, "forest.jpg", database.FileTypeImage)
+
+	ctx := context.Background()
+	palettes := map[string][]string{
+		"ocean.jpg":  {"#0000f0", "#ffffff"},
+		"sky.jpg":    {"#ffffff", "#1010f0"},
+		"forest.jpg": {"#008000", "#402000"},
+	}
+	for path, colors := range palettes {
+		if err := h.db.SetFilePalette(ctx, path, colors); err != nil {
+			t.Fatalf("failed to set palette: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search/color?hex=%230000ff", http.NoBody)
+	w := httptest.NewRecorder()
+
+	h.SearchByColor(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var result database.ColorSearchResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if result.Color != "#0000ff" {
+		t.Errorf("expected color #0000ff, got %q", result.Color)
+	}
+	if result.TotalItems != 2 || len(result.Items) != 2 {
+		t.Fatalf("expected 2 matches, got %d", result.TotalItems)
+	}
+	if result.Items[0].Path != "ocean.jpg" || result.Items[1].Path != "sky.jpg" {
+		t.Errorf("unexpected ranking: %s, %s", result.Items[0].Path, result.Items[1].Path)
+	}
+	if result.Items[0].Distance > result.Items[1].Distance {
+		t.Error("expected results sorted by ascending distance")
+	}
+
+	// Page sizes are capped, and pages far past the end are empty
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/search/color?hex=%%230000ff&pageSize=100000&page=%d", math.MaxInt), http.NoBody)
+	w = httptest.NewRecorder()
+	h.SearchByColor(w, req)
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if result.PageSize != maxColorPageSize || len(result.Items) != 0 {
+		t.Errorf("expected an empty page of %d, got %d items in a page of %d", maxColorPageSize, len(result.Items), result.PageSize)
+	}
+}
+
+// TestSearchByColorInvalidParamsIntegration tests parameter validation for color search
+func TestSearchByColorInvalidParamsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, _, cleanup := setupSearchIntegrationTest(t)
+	defer cleanup()
+
+	tests := []string{
+		"/api/search/color",
+		"/api/search/color?hex=zzzzzz",
+		"/api/search/color?hex=%23ff0000&threshold=-1",
+		"/api/search/color?hex=%23ff0000&threshold=abc",
+	}
+
+	for _, target := range tests {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		w := httptest.NewRecorder()
+
+		h.SearchByColor(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", target, w.Code)
+		}
+	}
+}
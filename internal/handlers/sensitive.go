@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"media-viewer/internal/logging"
+)
+
+// SensitiveRequest represents a request to flag a file as sensitive
+type SensitiveRequest struct {
+	Path      string `json:"path"`
+	Sensitive bool   `json:"sensitive"`
+}
+
+// SetFileSensitive flags a file as sensitive, so its thumbnail is served
+// obscured, or clears the flag
+func (h *Handlers) SetFileSensitive(w http.ResponseWriter, r *http.Request) {
+	var req SensitiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Path == "" {
+		httpError(w, r, "Path is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.SetFileSensitive(r.Context(), req.Path, req.Sensitive); err != nil {
+		logging.Error("SetFileSensitive error for %s: %v", req.Path, err)
+		httpError(w, r, "Failed to set sensitive flag", http.StatusInternalServerError)
+		return
+	}
+
+	h.invalidateFolderThumbnails(req.Path)
+	writeJSONStatus(w, "ok")
+}
+
+// invalidateFolderThumbnails drops the cached thumbnails of the folders
+// containing a file, which are drawn from the files inside at any depth
+func (h *Handlers) invalidateFolderThumbnails(filePath string) {
+	for dir := filepath.Dir(filePath); h.thumbGen != nil && dir != "." && dir != "/"; dir = filepath.Dir(dir) {
+		if err := h.thumbGen.InvalidateThumbnail(filepath.Join(h.mediaDir, dir)); err != nil {
+			logging.Warn("Failed to invalidate folder thumbnail for %s: %v", dir, err)
+		}
+	}
+}
+
+// revealsSensitive reports whether a request asks for the clear thumbnail,
+// or the file itself, of a file flagged as sensitive with ?reveal=true.
+// Anonymous visitors in public mode can't (see allowAnonymous).
+func revealsSensitive(r *http.Request) bool {
+	reveal, _ := strconv.ParseBool(r.URL.Query().Get("reveal"))
+	return reveal
+}
+
+// isSensitive reports whether a file is flagged as sensitive. If the flag
+// can't be read the file is treated as flagged, to be safe.
+func (h *Handlers) isSensitive(ctx context.Context, filePath string) bool {
+	sensitive, err := h.db.IsFileSensitive(ctx, filePath)
+	if err != nil {
+		logging.Error("Failed to check sensitive flag of %s: %v", filePath, err)
+		return true
+	}
+	return sensitive
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"media-viewer/internal/database"
+
+	"github.com/gorilla/mux"
+)
+
+func TestSensitiveThumbnailIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTestWithThumbnails(t)
+	defer cleanup()
+
+	// Sharp stripes, which blurring smooths out
+	img := image.NewRGBA(image.Rect(0, 0, 600, 400))
+	for x := range 600 {
+		for y := range 400 {
+			if x/20%2 == 0 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(h.mediaDir, "private"), 0o755); err != nil {
+		t.Fatalf("failed to create test dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(h.mediaDir, "private", "photo.jpg"), buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to create test image: %v", err)
+	}
+	addExistingFileToDatabase(t, h, "private/photo.jpg", database.FileTypeImage)
+
+	setSensitive := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/file/sensitive", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.SetFileSensitive(w, req)
+		return w
+	}
+	getThumbnail := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/private/photo.jpg"+query, http.NoBody)
+		req = mux.SetURLVars(req, map[string]string{"path": "private/photo.jpg"})
+		w := httptest.NewRecorder()
+		h.GetThumbnail(w, req)
+		return w
+	}
+	// contrast returns the difference between the lightest and darkest pixel
+	// of the middle row of a thumbnail
+	contrast := func(w *httptest.ResponseRecorder) uint8 {
+		t.Helper()
+		thumb, err := jpeg.Decode(bytes.NewReader(w.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("failed to decode thumbnail: %v", err)
+		}
+		lightest, darkest := uint8(0), uint8(255)
+		bounds := thumb.Bounds()
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray := color.GrayModel.Convert(thumb.At(x, bounds.Dy()/2)).(color.Gray).Y
+			lightest, darkest = max(lightest, gray), min(darkest, gray)
+		}
+		return lightest - darkest
+	}
+
+	w := getThumbnail("")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	clear := contrast(w)
+
+	if w := setSensitive(`{"path":"private/photo.jpg","sensitive":true}`); w.Code != http.StatusOK {
+		t.Fatalf("SetFileSensitive status = %d, body: %s", w.Code, w.Body.String())
+	}
+
+	w = getThumbnail("")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Cache-Control"); got != "private, no-cache" {
+		t.Errorf("Cache-Control = %q, want private, no-cache", got)
+	}
+	if obscured := contrast(w); obscured >= clear/2 {
+		t.Errorf("expected an obscured thumbnail, got contrast %d of %d", obscured, clear)
+	}
+
+	// Size and format variants are obscured too
+	if w := getThumbnail("?size=400"); contrast(w) >= clear/2 {
+		t.Error("expected an obscured thumbnail for a size variant")
+	}
+
+	w = getThumbnail("?reveal=true")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if revealed := contrast(w); revealed != clear {
+		t.Errorf("expected the clear thumbnail with reveal, got contrast %d of %d", revealed, clear)
+	}
+
+	// The flag is listed with the file
+	items, err := h.db.GetMediaInDirectory(context.Background(), "private", database.SortByName, database.SortAsc)
+	if err != nil {
+		t.Fatalf("GetMediaInDirectory failed: %v", err)
+	}
+	if len(items) != 1 || !items[0].Sensitive {
+		t.Errorf("expected the file to be listed as sensitive, got %+v", items)
+	}
+
+	if w := setSensitive(`{"path":"private/photo.jpg","sensitive":false}`); w.Code != http.StatusOK {
+		t.Fatalf("SetFileSensitive status = %d, body: %s", w.Code, w.Body.String())
+	}
+	w = getThumbnail("")
+	if w.Header().Get("Cache-Control") == "private, no-cache" || contrast(w) != clear {
+		t.Error("expected the clear thumbnail once the flag is cleared")
+	}
+
+	if w := setSensitive(`{"sensitive":true}`); w.Code != http.StatusBadRequest {
+		t.Errorf("SetFileSensitive without a path status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := setSensitive(`not json`); w.Code != http.StatusBadRequest {
+		t.Errorf("SetFileSensitive with an invalid body status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	var status map[string]string
+	w = setSensitive(`{"path":"private/photo.jpg","sensitive":true}`)
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil || status["status"] != "ok" {
+		t.Errorf("expected status ok, got %v (error: %v)", status, err)
+	}
+}
+
+func TestSensitiveFileRevealIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	if err := os.WriteFile(filepath.Join(h.mediaDir, "photo.jpg"), []byte("fake jpeg"), 0o644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	addExistingFileToDatabase(t, h, "photo.jpg", database.FileTypeImage)
+
+	getFile := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/file/photo.jpg"+query, http.NoBody)
+		req = mux.SetURLVars(req, map[string]string{"path": "photo.jpg"})
+		w := httptest.NewRecorder()
+		h.GetFile(w, req)
+		return w
+	}
+
+	if w := getFile(""); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 before flagging, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if err := h.db.SetFileSensitive(context.Background(), "photo.jpg", true); err != nil {
+		t.Fatalf("SetFileSensitive failed: %v", err)
+	}
+
+	for _, query := range []string{"", "?download=true", "?reveal=false"} {
+		if w := getFile(query); w.Code != http.StatusForbidden {
+			t.Errorf("GetFile%s status = %d, want %d", query, w.Code, http.StatusForbidden)
+		}
+	}
+	for _, query := range []string{"?reveal=true", "?download=true&reveal=true"} {
+		w := getFile(query)
+		if w.Code != http.StatusOK {
+			t.Errorf("GetFile%s status = %d, want %d", query, w.Code, http.StatusOK)
+		}
+		if w.Body.String() != "fake jpeg" {
+			t.Errorf("GetFile%s body = %q, want the file", query, w.Body.String())
+		}
+	}
+}
+
+func TestSensitiveVideoRevealIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	if err := os.WriteFile(filepath.Join(h.mediaDir, "clip.mkv"), []byte("fake video"), 0o644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	addExistingFileToDatabase(t, h, "clip.mkv", database.FileTypeVideo)
+	if err := h.db.SetFileSensitive(context.Background(), "clip.mkv", true); err != nil {
+		t.Fatalf("SetFileSensitive failed: %v", err)
+	}
+
+	routes := []struct {
+		name  string
+		url   string
+		vars  map[string]string
+		serve http.HandlerFunc
+	}{
+		{"StreamVideo", "/api/stream/clip.mkv", map[string]string{"path": "clip.mkv"}, h.StreamVideo},
+		{"GetHLS playlist", "/api/hls/clip.mkv/playlist.m3u8", map[string]string{"path": "clip.mkv", "segment": "playlist.m3u8"}, h.GetHLS},
+		{"GetHLS segment", "/api/hls/clip.mkv/segment_00000.m4s", map[string]string{"path": "clip.mkv", "segment": "segment_00000.m4s"}, h.GetHLS},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			for _, query := range []string{"", "?reveal=false", "?width=640"} {
+				req := httptest.NewRequest(http.MethodGet, route.url+query, http.NoBody)
+				req = mux.SetURLVars(req, route.vars)
+				w := httptest.NewRecorder()
+				route.serve(w, req)
+				if w.Code != http.StatusForbidden {
+					t.Errorf("%s%s status = %d, want %d", route.name, query, w.Code, http.StatusForbidden)
+				}
+			}
+
+			// Past the check the fake video fails to probe or has no
+			// segments yet, but it isn't refused
+			req := httptest.NewRequest(http.MethodGet, route.url+"?reveal=true", http.NoBody)
+			req = mux.SetURLVars(req, route.vars)
+			w := httptest.NewRecorder()
+			route.serve(w, req)
+			if w.Code == http.StatusForbidden {
+				t.Errorf("%s?reveal=true status = %d, want the video served", route.name, w.Code)
+			}
+		})
+	}
+}
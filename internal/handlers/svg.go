@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"media-viewer/internal/logging"
+	"media-viewer/internal/mediatypes"
+)
+
+// svgMode controls how original SVG files are served by GetFile. SVGs can
+// carry scripts and event handlers that run when the file is opened directly,
+// so they are never served as plain inline images unless the mode is "off".
+// Thumbnails are rasterized and unaffected.
+type svgMode string
+
+const (
+	// svgModeOff serves SVGs unchanged
+	svgModeOff svgMode = "off"
+	// svgModeSandbox serves SVGs inline under a sandboxing Content-Security-Policy
+	svgModeSandbox svgMode = "sandbox"
+	// svgModeSanitize strips scripting from SVGs and serves them sandboxed
+	svgModeSanitize svgMode = "sanitize"
+	// svgModeAttachment serves SVGs as downloads
+	svgModeAttachment svgMode = "attachment"
+)
+
+// svgSandboxPolicy blocks scripts, plugins and external loads while still
+// letting the SVG's own inline styles and embedded data: images render
+const svgSandboxPolicy = "sandbox; default-src 'none'; style-src 'unsafe-inline'; img-src data:"
+
+// maxSanitizedSVGSize caps the SVGs sanitized in memory. Larger files are
+// served as downloads instead.
+const maxSanitizedSVGSize = 16 << 20
+
+// parseSVGMode parses an SVG_SAFETY value. An empty value selects the default,
+// svgModeSandbox.
+func parseSVGMode(value string) (svgMode, error) {
+	switch mode := svgMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return svgModeSandbox, nil
+	case svgModeOff, svgModeSandbox, svgModeSanitize, svgModeAttachment:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown SVG mode %q (expected off, sandbox, sanitize or attachment)", value)
+	}
+}
+
+// isSVGFile reports whether a path names an SVG file
+func isSVGFile(path string) bool {
+	return mediatypes.NormalizeExt(path) == ".svg"
+}
+
+// serveSVG serves an original SVG file according to the configured SVG mode
+func (h *Handlers) serveSVG(w http.ResponseWriter, r *http.Request, fullPath string) {
+	switch h.svgMode {
+	case svgModeOff:
+		http.ServeFile(w, r, fullPath)
+		return
+	case svgModeSanitize:
+		if h.serveSanitizedSVG(w, r, fullPath) {
+			return
+		}
+	case svgModeAttachment:
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(fullPath)))
+	}
+
+	setSVGSecurityHeaders(w)
+	http.ServeFile(w, r, fullPath)
+}
+
+// serveSanitizedSVG serves a sanitized copy of an SVG. It returns false without
+// writing a response when the file can't be sanitized, after marking the
+// response as a download so the original is not rendered inline.
+func (h *Handlers) serveSanitizedSVG(w http.ResponseWriter, r *http.Request, fullPath string) bool {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		// Leave the error response to ServeFile
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	var sanitized []byte
+	if info.Size() > maxSanitizedSVGSize {
+		err = fmt.Errorf("file exceeds %d bytes", maxSanitizedSVGSize)
+	} else {
+		sanitized, err = sanitizeSVG(f)
+	}
+	if err != nil {
+		logging.Warn("Serving SVG %s as a download, could not sanitize it: %v", fullPath, err)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(fullPath)))
+		return false
+	}
+
+	setSVGSecurityHeaders(w)
+	w.Header().Set("Content-Type", "image/svg+xml")
+	http.ServeContent(w, r, filepath.Base(fullPath), info.ModTime(), bytes.NewReader(sanitized))
+	return true
+}
+
+// setSVGSecurityHeaders prevents scripts in a served SVG from running
+func setSVGSecurityHeaders(w http.ResponseWriter) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", svgSandboxPolicy)
+}
+
+// svgScriptElements are removed from sanitized SVGs along with their content
+var svgScriptElements = map[string]bool{
+	"script":        true,
+	"foreignobject": true,
+	"iframe":        true,
+	"embed":         true,
+	"object":        true,
+	"handler":       true,
+	"listener":      true,
+}
+
+// svgAnimationElements can set attributes, so they are removed when they
+// target an event handler or link
+var svgAnimationElements = map[string]bool{
+	"set":              true,
+	"animate":          true,
+	"animatemotion":    true,
+	"animatetransform": true,
+}
+
+var (
+	svgTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	svgAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;")
+)
+
+// sanitizeSVG re-serializes an SVG without scripting: script-capable elements,
+// event handler attributes, javascript: and non-image data: URLs, doctypes
+// (and the entities they declare) and processing instructions other than the
+// XML declaration are dropped. Malformed documents are rejected.
+func sanitizeSVG(r io.Reader) ([]byte, error) {
+	dec := xml.NewDecoder(r)
+	dec.Entity = xml.HTMLEntity
+
+	var (
+		out   bytes.Buffer
+		open  []xml.Name // Elements not yet closed
+		skip  int        // Depth within a removed element
+		found bool       // Whether a root element was seen
+	)
+
+	for {
+		tok, err := dec.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			found = true
+			open = append(open, t.Name)
+			if skip > 0 || isScriptingElement(t) {
+				skip++
+				continue
+			}
+			out.WriteString("<" + qualifiedName(t.Name))
+			for _, attr := range t.Attr {
+				if isScriptingAttr(attr) {
+					continue
+				}
+				out.WriteString(" " + qualifiedName(attr.Name) + `="` + svgAttrEscaper.Replace(attr.Value) + `"`)
+			}
+			out.WriteString(">")
+
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != t.Name {
+				return nil, fmt.Errorf("unexpected closing tag </%s>", qualifiedName(t.Name))
+			}
+			open = open[:len(open)-1]
+			if skip > 0 {
+				skip--
+				continue
+			}
+			out.WriteString("</" + qualifiedName(t.Name) + ">")
+
+		case xml.CharData:
+			if skip == 0 {
+				out.WriteString(svgTextEscaper.Replace(string(t)))
+			}
+
+		case xml.ProcInst:
+			if t.Target == "xml" && out.Len() == 0 {
+				out.WriteString("<?xml " + string(t.Inst) + "?>")
+			}
+		}
+	}
+
+	if !found || len(open) > 0 {
+		return nil, errors.New("incomplete SVG document")
+	}
+	return out.Bytes(), nil
+}
+
+// isScriptingElement reports whether an element is removed with its content
+func isScriptingElement(el xml.StartElement) bool {
+	name := strings.ToLower(el.Name.Local)
+	if svgScriptElements[name] {
+		return true
+	}
+	if !svgAnimationElements[name] {
+		return false
+	}
+	for _, attr := range el.Attr {
+		if strings.EqualFold(attr.Name.Local, "attributeName") {
+			target := strings.ToLower(attr.Value)
+			if i := strings.IndexByte(target, ':'); i >= 0 {
+				target = target[i+1:]
+			}
+			return target == "href" || strings.HasPrefix(target, "on")
+		}
+	}
+	return false
+}
+
+// isScriptingAttr reports whether an attribute is an event handler or holds a
+// URL that runs script
+func isScriptingAttr(attr xml.Attr) bool {
+	if strings.HasPrefix(strings.ToLower(attr.Name.Local), "on") {
+		return true
+	}
+
+	// Browsers ignore whitespace and control characters within a URL scheme
+	value := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(attr.Value))
+
+	if strings.HasPrefix(value, "javascript:") || strings.HasPrefix(value, "vbscript:") {
+		return true
+	}
+	if strings.HasPrefix(value, "data:") {
+		// Embedded SVG images can carry scripts of their own
+		return !strings.HasPrefix(value, "data:image/") || strings.HasPrefix(value, "data:image/svg")
+	}
+	return false
+}
+
+// qualifiedName formats a raw (unresolved) XML name as prefix:local
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"media-viewer/internal/database"
+
+	"github.com/gorilla/mux"
+)
+
+const maliciousSVG = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE svg [<!ENTITY x "y">]>
+<?xml-stylesheet type="text/xsl" href="evil.xsl"?>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="10" height="10" onload="alert(1)">
+  <script type="text/javascript"><![CDATA[alert(2)]]></script>
+  <foreignObject><div xmlns="http://www.w3.org/1999/xhtml"><iframe src="https://evil.example"></iframe></div></foreignObject>
+  <a xlink:href=" java&#x09;script:alert(3)"><rect width="10" height="10" fill="red" OnClick="alert(4)"/></a>
+  <set attributeName="onmouseover" to="alert(5)"/>
+  <animate attributeName="xlink:href" values="javascript:alert(6)"/>
+  <animate attributeName="opacity" from="0" to="1" dur="1s"/>
+  <image href="data:image/png;base64,AAAA" width="1" height="1"/>
+  <image href="data:image/svg+xml;base64,AAAA" width="1" height="1"/>
+  <text x="0" y="5">a &amp; b &lt; c</text>
+</svg>`
+
+func TestParseSVGMode(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected svgMode
+		wantErr  bool
+	}{
+		{"", svgModeSandbox, false},
+		{"off", svgModeOff, false},
+		{"Sanitize", svgModeSanitize, false},
+		{" attachment ", svgModeAttachment, false},
+		{"strict", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSVGMode(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSVGMode(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if got != tt.expected {
+			t.Errorf("parseSVGMode(%q) = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestSanitizeSVG(t *testing.T) {
+	out, err := sanitizeSVG(strings.NewReader(maliciousSVG))
+	if err != nil {
+		t.Fatalf("sanitizeSVG failed: %v", err)
+	}
+	sanitized := string(out)
+
+	for _, unwanted := range []string{"alert", "<script", "foreignObject", "iframe", "DOCTYPE", "ENTITY", "xml-stylesheet", "data:image/svg", "xlink:href"} {
+		if strings.Contains(sanitized, unwanted) {
+			t.Errorf("Expected %q removed, got %s", unwanted, sanitized)
+		}
+	}
+	for _, wanted := range []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`xmlns:xlink="http://www.w3.org/1999/xlink"`,
+		`<rect width="10" height="10" fill="red"></rect>`,
+		`<animate attributeName="opacity" from="0" to="1" dur="1s"></animate>`,
+		`href="data:image/png;base64,AAAA"`,
+		`a &amp; b &lt; c`,
+	} {
+		if !strings.Contains(sanitized, wanted) {
+			t.Errorf("Expected %q kept, got %s", wanted, sanitized)
+		}
+	}
+
+	// The result is well-formed XML
+	dec := xml.NewDecoder(strings.NewReader(sanitized))
+	for {
+		if _, err := dec.Token(); err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Errorf("Sanitized SVG is not well-formed: %v", err)
+			}
+			break
+		}
+	}
+}
+
+func TestSanitizeSVGRejectsMalformed(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"not xml",
+		`<svg><script></g>alert(1)</script></svg>`,
+		`<svg><rect>`,
+	} {
+		if _, err := sanitizeSVG(strings.NewReader(input)); err == nil {
+			t.Errorf("Expected sanitizeSVG(%q) to fail", input)
+		}
+	}
+}
+
+// TestGetFileSVGModesIntegration tests how SVG originals are served in each mode
+func TestGetFileSVGModesIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+
+	addTestMediaFile(t, h, "drawing.svg", database.FileTypeImage, maliciousSVG)
+	addTestMediaFile(t, h, "broken.SVG", database.FileTypeImage, "<svg><script>alert(1)")
+
+	tests := []struct {
+		mode        svgMode
+		path        string
+		sandboxed   bool
+		attachment  bool
+		wantScripts bool
+	}{
+		{svgModeOff, "drawing.svg", false, false, true},
+		{svgModeSandbox, "drawing.svg", true, false, true},
+		{svgModeSanitize, "drawing.svg", true, false, false},
+		{svgModeSanitize, "broken.SVG", true, true, true},
+		{svgModeAttachment, "drawing.svg", true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode)+"/"+tt.path, func(t *testing.T) {
+			h.svgMode = tt.mode
+
+			req := httptest.NewRequest(http.MethodGet, "/api/file/"+tt.path, http.NoBody)
+			req = mux.SetURLVars(req, map[string]string{"path": tt.path})
+			w := httptest.NewRecorder()
+
+			h.GetFile(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" {
+				t.Errorf("expected Content-Type image/svg+xml, got %q", ct)
+			}
+			if csp := w.Header().Get("Content-Security-Policy"); (csp == svgSandboxPolicy) != tt.sandboxed {
+				t.Errorf("expected sandboxed=%v, got Content-Security-Policy %q", tt.sandboxed, csp)
+			}
+			if cd := w.Header().Get("Content-Disposition"); strings.HasPrefix(cd, "attachment") != tt.attachment {
+				t.Errorf("expected attachment=%v, got Content-Disposition %q", tt.attachment, cd)
+			}
+			if hasScripts := strings.Contains(w.Body.String(), "alert"); hasScripts != tt.wantScripts {
+				t.Errorf("expected scripts in body=%v, got %s", tt.wantScripts, w.Body.String())
+			}
+		})
+	}
+}
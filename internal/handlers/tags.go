@@ -43,7 +43,7 @@ func (h *Handlers) GetAllTags(w http.ResponseWriter, r *http.Request) {
 
 	tags, err := h.db.GetAllTags(ctx)
 	if err != nil {
-		http.Error(w, "Failed to get tags", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get tags", http.StatusInternalServerError)
 		return
 	}
 
@@ -61,13 +61,13 @@ func (h *Handlers) GetFileTags(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Query().Get("path")
 
 	if path == "" {
-		http.Error(w, "Path is required", http.StatusBadRequest)
+		httpError(w, r, "Path is required", http.StatusBadRequest)
 		return
 	}
 
 	tags, err := h.db.GetFileTags(ctx, path)
 	if err != nil {
-		http.Error(w, "Failed to get tags", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get tags", http.StatusInternalServerError)
 		return
 	}
 
@@ -85,19 +85,19 @@ func (h *Handlers) GetBatchFileTags(w http.ResponseWriter, r *http.Request) {
 
 	var req BatchTagsRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if len(req.Paths) == 0 {
-		http.Error(w, "Paths array is required", http.StatusBadRequest)
+		httpError(w, r, "Paths array is required", http.StatusBadRequest)
 		return
 	}
 
 	// Allow large batch requests for bulk operations
 	maxPaths := 10000
 	if len(req.Paths) > maxPaths {
-		http.Error(w, fmt.Sprintf("Too many paths (max %d)", maxPaths), http.StatusBadRequest)
+		httpError(w, r, fmt.Sprintf("Too many paths (max %d)", maxPaths), http.StatusBadRequest)
 		return
 	}
 
@@ -133,17 +133,17 @@ func (h *Handlers) AddTagToFile(w http.ResponseWriter, r *http.Request) {
 
 	var req TagRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if req.Path == "" || req.Tag == "" {
-		http.Error(w, "Path and tag are required", http.StatusBadRequest)
+		httpError(w, r, "Path and tag are required", http.StatusBadRequest)
 		return
 	}
 
 	if err := h.db.AddTagToFile(ctx, req.Path, req.Tag); err != nil {
-		http.Error(w, "Failed to add tag", http.StatusInternalServerError)
+		httpError(w, r, "Failed to add tag", http.StatusInternalServerError)
 		return
 	}
 
@@ -156,17 +156,17 @@ func (h *Handlers) RemoveTagFromFile(w http.ResponseWriter, r *http.Request) {
 
 	var req TagRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if req.Path == "" || req.Tag == "" {
-		http.Error(w, "Path and tag are required", http.StatusBadRequest)
+		httpError(w, r, "Path and tag are required", http.StatusBadRequest)
 		return
 	}
 
 	if err := h.db.RemoveTagFromFile(ctx, req.Path, req.Tag); err != nil {
-		http.Error(w, "Failed to remove tag", http.StatusInternalServerError)
+		httpError(w, r, "Failed to remove tag", http.StatusInternalServerError)
 		return
 	}
 
@@ -179,23 +179,23 @@ func (h *Handlers) BulkAddTag(w http.ResponseWriter, r *http.Request) {
 
 	var req BulkTagRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if len(req.Paths) == 0 {
-		http.Error(w, "Paths array is required", http.StatusBadRequest)
+		httpError(w, r, "Paths array is required", http.StatusBadRequest)
 		return
 	}
 
 	if req.Tag == "" {
-		http.Error(w, "Tag is required", http.StatusBadRequest)
+		httpError(w, r, "Tag is required", http.StatusBadRequest)
 		return
 	}
 
 	maxPaths := 10000
 	if len(req.Paths) > maxPaths {
-		http.Error(w, fmt.Sprintf("Too many paths (max %d)", maxPaths), http.StatusBadRequest)
+		httpError(w, r, fmt.Sprintf("Too many paths (max %d)", maxPaths), http.StatusBadRequest)
 		return
 	}
 
@@ -234,17 +234,17 @@ func (h *Handlers) BulkRemoveTag(w http.ResponseWriter, r *http.Request) {
 
 	var req BulkTagRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if len(req.Paths) == 0 {
-		http.Error(w, "Paths array is required", http.StatusBadRequest)
+		httpError(w, r, "Paths array is required", http.StatusBadRequest)
 		return
 	}
 
 	if req.Tag == "" {
-		http.Error(w, "Tag is required", http.StatusBadRequest)
+		httpError(w, r, "Tag is required", http.StatusBadRequest)
 		return
 	}
 
@@ -291,17 +291,17 @@ func (h *Handlers) SetFileTags(w http.ResponseWriter, r *http.Request) {
 
 	var req TagRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if req.Path == "" {
-		http.Error(w, "Path is required", http.StatusBadRequest)
+		httpError(w, r, "Path is required", http.StatusBadRequest)
 		return
 	}
 
 	if err := h.db.SetFileTags(ctx, req.Path, req.Tags); err != nil {
-		http.Error(w, "Failed to set tags", http.StatusInternalServerError)
+		httpError(w, r, "Failed to set tags", http.StatusInternalServerError)
 		return
 	}
 
@@ -315,7 +315,7 @@ func (h *Handlers) GetFilesByTag(w http.ResponseWriter, r *http.Request) {
 	tagName := vars["tag"]
 
 	if tagName == "" {
-		http.Error(w, "Tag name is required", http.StatusBadRequest)
+		httpError(w, r, "Tag name is required", http.StatusBadRequest)
 		return
 	}
 
@@ -325,7 +325,7 @@ func (h *Handlers) GetFilesByTag(w http.ResponseWriter, r *http.Request) {
 
 	result, err := h.db.GetFilesByTag(ctx, tagName, page, pageSize)
 	if err != nil {
-		http.Error(w, "Failed to get files", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get files", http.StatusInternalServerError)
 		return
 	}
 
@@ -340,12 +340,12 @@ func (h *Handlers) DeleteTag(w http.ResponseWriter, r *http.Request) {
 	tagName := vars["tag"]
 
 	if tagName == "" {
-		http.Error(w, "Tag name is required", http.StatusBadRequest)
+		httpError(w, r, "Tag name is required", http.StatusBadRequest)
 		return
 	}
 
 	if err := h.db.DeleteTag(ctx, tagName); err != nil {
-		http.Error(w, "Failed to delete tag", http.StatusInternalServerError)
+		httpError(w, r, "Failed to delete tag", http.StatusInternalServerError)
 		return
 	}
 
@@ -360,17 +360,17 @@ func (h *Handlers) RenameTag(w http.ResponseWriter, r *http.Request) {
 
 	var req TagRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if tagName == "" || req.NewName == "" {
-		http.Error(w, "Tag name and new name are required", http.StatusBadRequest)
+		httpError(w, r, "Tag name and new name are required", http.StatusBadRequest)
 		return
 	}
 
 	if err := h.db.RenameTag(ctx, tagName, req.NewName); err != nil {
-		http.Error(w, "Failed to rename tag", http.StatusInternalServerError)
+		httpError(w, r, "Failed to rename tag", http.StatusInternalServerError)
 		return
 	}
 
@@ -383,7 +383,7 @@ func (h *Handlers) GetAllTagsWithCounts(w http.ResponseWriter, r *http.Request)
 
 	tags, err := h.db.GetAllTagsWithCounts(ctx)
 	if err != nil {
-		http.Error(w, "Failed to get tags with counts", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get tags with counts", http.StatusInternalServerError)
 		return
 	}
 
@@ -401,7 +401,7 @@ func (h *Handlers) GetUnusedTags(w http.ResponseWriter, r *http.Request) {
 
 	tags, err := h.db.GetUnusedTags(ctx)
 	if err != nil {
-		http.Error(w, "Failed to get unused tags", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get unused tags", http.StatusInternalServerError)
 		return
 	}
 
@@ -421,18 +421,18 @@ func (h *Handlers) RenameTagEverywhere(w http.ResponseWriter, r *http.Request) {
 
 	var req TagRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if tagName == "" || req.NewName == "" {
-		http.Error(w, "Tag name and new name are required", http.StatusBadRequest)
+		httpError(w, r, "Tag name and new name are required", http.StatusBadRequest)
 		return
 	}
 
 	count, err := h.db.RenameTagEverywhere(ctx, tagName, req.NewName)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to rename tag: %v", err), http.StatusInternalServerError)
+		httpError(w, r, fmt.Sprintf("Failed to rename tag: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -454,13 +454,13 @@ func (h *Handlers) DeleteTagEverywhere(w http.ResponseWriter, r *http.Request) {
 	tagName := vars["tag"]
 
 	if tagName == "" {
-		http.Error(w, "Tag name is required", http.StatusBadRequest)
+		httpError(w, r, "Tag name is required", http.StatusBadRequest)
 		return
 	}
 
 	count, err := h.db.DeleteTagEverywhere(ctx, tagName)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete tag: %v", err), http.StatusInternalServerError)
+		httpError(w, r, fmt.Sprintf("Failed to delete tag: %v", err), http.StatusInternalServerError)
 		return
 	}
 
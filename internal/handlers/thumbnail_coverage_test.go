@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"image"
+	"image/png"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -703,3 +706,61 @@ func TestGetThumbnailVideoType(t *testing.T) {
 		t.Logf("Got status %d for video thumbnail (expected 500 or 200)", w.Code)
 	}
 }
+
+// TestGetThumbnailDimensionHeaders tests the dimension headers sent with THUMBNAIL_DIMENSION_HEADERS
+func TestGetThumbnailDimensionHeaders(t *testing.T) {
+	h, mediaDir, cleanup := setupThumbnailCoverageTest(t)
+	defer cleanup()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 300, 150))); err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(mediaDir, "wide.png"), buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to create image file: %v", err)
+	}
+
+	ctx := context.Background()
+	tx, err := h.db.BeginBatch(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	err = h.db.UpsertFile(ctx, tx, &database.MediaFile{
+		Name:    "wide.png",
+		Path:    "wide.png",
+		Type:    database.FileTypeImage,
+		Size:    int64(buf.Len()),
+		ModTime: time.Now(),
+	})
+	if err = h.db.EndBatch(tx, err); err != nil {
+		t.Fatalf("failed to add file to database: %v", err)
+	}
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/thumbnail/wide.png", http.NoBody)
+		req = mux.SetURLVars(req, map[string]string{"path": "wide.png"})
+		w := httptest.NewRecorder()
+		h.GetThumbnail(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		return w
+	}
+
+	if w := get(); w.Header().Get("X-Thumbnail-Width") != "" {
+		t.Error("Expected no dimension headers by default")
+	}
+
+	h.thumbnailDimensions = true
+	w := get()
+	want := map[string]string{
+		"X-Thumbnail-Width":     "200",
+		"X-Thumbnail-Height":    "100",
+		"X-Source-Aspect-Ratio": "2.0000",
+	}
+	for header, value := range want {
+		if got := w.Header().Get(header); got != value {
+			t.Errorf("%s = %q, want %q", header, got, value)
+		}
+	}
+}
@@ -10,14 +10,14 @@ import (
 // POST /api/transcode/clear
 func (h *Handlers) ClearTranscodeCache(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		httpError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	freedBytes, err := h.transcoder.ClearCache()
 	if err != nil {
 		logging.Error("Failed to clear transcode cache: %v", err)
-		http.Error(w, "Failed to clear transcode cache", http.StatusInternalServerError)
+		httpError(w, r, "Failed to clear transcode cache", http.StatusInternalServerError)
 		return
 	}
 
@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/filesystem"
+	"media-viewer/internal/logging"
+	"media-viewer/internal/mediatypes"
+)
+
+// maxTrashName is the longest name a trashed file can be kept under, the 255
+// bytes file systems allow
+const maxTrashName = 255
+
+// restoreTrashRequest is the body of a trash restore request
+type restoreTrashRequest struct {
+	ID int64 `json:"id"`
+}
+
+// deleteFilesRequest is the body of a batch delete request. Paths are
+// relative to the media directory.
+type deleteFilesRequest struct {
+	Paths []string `json:"paths"`
+}
+
+// deleteFileResult reports what a batch delete did with one path. A file can
+// be gone from the media directory while still indexed if recording it in
+// the trash failed and it couldn't be moved back.
+type deleteFileResult struct {
+	Path             string `json:"path"`
+	TrashID          int64  `json:"trashId,omitempty"`
+	RemovedFromDisk  bool   `json:"removedFromDisk"`
+	RemovedFromIndex bool   `json:"removedFromIndex"`
+	Error            string `json:"error,omitempty"`
+}
+
+// deleteFilesResponse is the response to a batch delete request
+type deleteFilesResponse struct {
+	Deleted int                `json:"deleted"`
+	Failed  int                `json:"failed"`
+	Results []deleteFileResult `json:"results"`
+}
+
+// DeleteFile moves a file or folder, with everything in it, to the trash
+// directory and removes it from the index. Its favorites, tags and other
+// data are kept until the trash is purged, so RestoreTrash brings them back.
+// DELETE /api/file/{path}
+func (h *Handlers) DeleteFile(w http.ResponseWriter, r *http.Request) {
+	// A client going away mid-move mustn't leave the index half updated
+	ctx := context.WithoutCancel(r.Context())
+
+	rel, ok := h.resolveMediaPath(mux.Vars(r)["path"])
+	if !ok {
+		httpError(w, r, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.moveToTrash(ctx, rel)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			httpError(w, r, "File not found", http.StatusNotFound)
+		} else {
+			logging.Error("DeleteFile: %v", err)
+			httpError(w, r, "Failed to delete file", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.db.UnindexTrashItems(ctx, []*database.TrashItem{item}); err != nil {
+		logging.Error("DeleteFile: failed to remove %s from the index: %v", rel, err)
+		h.moveBackFromTrash(ctx, item)
+		httpError(w, r, "Failed to delete file", http.StatusInternalServerError)
+		return
+	}
+	logging.Info("Moved %s to the trash", rel)
+
+	h.invalidateFolderThumbnails(rel)
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, item)
+}
+
+// DeleteFiles moves many files and folders to the trash at once, as
+// DeleteFile does, and reports the outcome for each path. The index is
+// updated in a single transaction: if that fails, nothing is removed from it
+// and the files are moved back.
+// POST /api/files/delete
+func (h *Handlers) DeleteFiles(w http.ResponseWriter, r *http.Request) {
+	ctx := context.WithoutCancel(r.Context())
+
+	var req deleteFilesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Paths) == 0 {
+		httpError(w, r, "Paths array is required", http.StatusBadRequest)
+		return
+	}
+
+	maxPaths := 10000
+	if len(req.Paths) > maxPaths {
+		httpError(w, r, fmt.Sprintf("Too many paths (max %d)", maxPaths), http.StatusBadRequest)
+		return
+	}
+
+	response := deleteFilesResponse{Results: make([]deleteFileResult, 0, len(req.Paths))}
+	var items []*database.TrashItem
+	var trashed []int // Index in Results of each of items
+	seen := make(map[string]bool, len(req.Paths))
+
+	for _, path := range req.Paths {
+		result := deleteFileResult{Path: path}
+
+		rel, ok := h.resolveMediaPath(path)
+		switch {
+		case !ok:
+			result.Error = "Invalid path"
+		case seen[rel]:
+			result.Error = "Duplicate path"
+		default:
+			seen[rel] = true
+			item, err := h.moveToTrash(ctx, rel)
+			switch {
+			case errors.Is(err, fs.ErrNotExist):
+				// Also the contents of a folder deleted earlier in the batch
+				result.Error = "File not found"
+			case err != nil:
+				logging.Error("DeleteFiles: %v", err)
+				result.Error = "Failed to move to the trash"
+			default:
+				result.RemovedFromDisk = true
+				items = append(items, item)
+				trashed = append(trashed, len(response.Results))
+			}
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	if len(items) > 0 {
+		if err := h.db.UnindexTrashItems(ctx, items); err != nil {
+			logging.Error("DeleteFiles: failed to remove %d files from the index: %v", len(items), err)
+			for i, item := range items {
+				result := &response.Results[trashed[i]]
+				result.Error = "Failed to update the index"
+				if h.moveBackFromTrash(ctx, item) {
+					result.RemovedFromDisk = false
+				} else {
+					result.TrashID = item.ID
+				}
+			}
+		} else {
+			for i, item := range items {
+				result := &response.Results[trashed[i]]
+				result.TrashID = item.ID
+				result.RemovedFromIndex = true
+				h.invalidateFolderThumbnails(item.OriginalPath)
+			}
+			logging.Info("Moved %d files and folders to the trash", len(items))
+		}
+	}
+
+	for _, result := range response.Results {
+		if result.Error == "" {
+			response.Deleted++
+		} else {
+			response.Failed++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, response)
+}
+
+// moveToTrash moves a file or folder, relative to the media directory, into
+// the trash directory and returns its recorded trash item. The item is
+// recorded before the move, and kept if the move leaves anything in the
+// trash directory, so even a move cut short can be restored. The error wraps
+// fs.ErrNotExist if there is nothing at the path.
+func (h *Handlers) moveToTrash(ctx context.Context, rel string) (*database.TrashItem, error) {
+	fullPath := filepath.Join(h.mediaDir, rel)
+
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access %s: %w", rel, err)
+	}
+
+	item := &database.TrashItem{
+		OriginalPath: filepath.ToSlash(rel),
+		Type:         mediatypes.GetFileType(filepath.Ext(rel)),
+	}
+	if info.IsDir() {
+		item.Type = database.FileTypeFolder
+	}
+
+	if err := os.MkdirAll(h.trashDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create trash directory %s: %w", h.trashDir, err)
+	}
+
+	// Unique even for files deleted from the same path again, or with the
+	// same name within one batch
+	stamp := time.Now().UnixNano()
+	for {
+		item.TrashName = trashName(stamp, filepath.Base(rel))
+		if _, err := os.Lstat(filepath.Join(h.trashDir, item.TrashName)); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		stamp++
+	}
+
+	if err := h.db.AddTrashItem(ctx, item); err != nil {
+		return nil, fmt.Errorf("failed to record %s in the trash: %w", rel, err)
+	}
+
+	trashPath := filepath.Join(h.trashDir, item.TrashName)
+	if err := filesystem.MoveAll(fullPath, trashPath); err != nil {
+		if _, statErr := os.Lstat(trashPath); statErr == nil {
+			// Copied across devices but not all removed from the media
+			// directory; the copy in the trash is complete
+			return nil, fmt.Errorf("failed to move %s to the trash, left it there as item %d: %w", rel, item.ID, err)
+		}
+		h.forgetTrashItem(ctx, item)
+		return nil, fmt.Errorf("failed to move %s to the trash: %w", rel, err)
+	}
+	return item, nil
+}
+
+// trashName returns the name a file deleted at stamp is kept under in the
+// trash: the stamp, then the file's own name. Names too long for that are cut
+// before their extension; restoring uses the path recorded in the trash
+// table, so the full name isn't needed.
+func trashName(stamp int64, base string) string {
+	prefix := strconv.FormatInt(stamp, 36) + "-"
+	if len(prefix)+len(base) <= maxTrashName {
+		return prefix + base
+	}
+
+	ext := filepath.Ext(base)
+	if len(prefix)+len(ext) >= maxTrashName {
+		ext = ""
+	}
+	keep := maxTrashName - len(prefix) - len(ext)
+	// Don't split a multi-byte character
+	for keep > 0 && !utf8.RuneStart(base[keep]) {
+		keep--
+	}
+	return prefix + base[:keep] + ext
+}
+
+// moveBackFromTrash undoes moveToTrash for an item that couldn't be removed
+// from the index. Returns false, after logging where the file was left, if
+// it couldn't be moved back; the item stays recorded so it can be restored.
+func (h *Handlers) moveBackFromTrash(ctx context.Context, item *database.TrashItem) bool {
+	trashPath := filepath.Join(h.trashDir, item.TrashName)
+	if err := filesystem.MoveAll(trashPath, filepath.Join(h.mediaDir, filepath.FromSlash(item.OriginalPath))); err != nil {
+		logging.Error("Failed to move %s back from the trash (%s): %v", item.OriginalPath, trashPath, err)
+		return false
+	}
+	h.forgetTrashItem(ctx, item)
+	return true
+}
+
+// forgetTrashItem removes the record of an item that isn't in the trash
+// after all
+func (h *Handlers) forgetTrashItem(ctx context.Context, item *database.TrashItem) {
+	if err := h.db.RemoveTrashItem(ctx, item.ID); err != nil {
+		logging.Warn("Failed to forget trash item %d of %s: %v", item.ID, item.OriginalPath, err)
+	}
+}
+
+// ListTrash returns the files and folders in the trash, most recently
+// deleted first.
+// GET /api/trash
+func (h *Handlers) ListTrash(w http.ResponseWriter, r *http.Request) {
+	items, err := h.db.ListTrash(r.Context())
+	if err != nil {
+		logging.Error("ListTrash: %v", err)
+		httpError(w, r, "Failed to list trash", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, map[string]interface{}{"items": items})
+}
+
+// RestoreTrash moves a file or folder in the trash back to where it was
+// deleted from, recreating the folders above it if they are gone too, and
+// indexes it again.
+// POST /api/trash/restore
+func (h *Handlers) RestoreTrash(w http.ResponseWriter, r *http.Request) {
+	ctx := context.WithoutCancel(r.Context())
+
+	var req restoreTrashRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+		httpError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.db.GetTrashItem(ctx, req.ID)
+	if errors.Is(err, database.ErrTrashItemNotFound) {
+		httpError(w, r, "Trash item not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logging.Error("RestoreTrash: failed to get trash item %d: %v", req.ID, err)
+		httpError(w, r, "Failed to restore file", http.StatusInternalServerError)
+		return
+	}
+
+	fullPath := filepath.Join(h.mediaDir, filepath.FromSlash(item.OriginalPath))
+	if _, err := os.Lstat(fullPath); err == nil {
+		httpError(w, r, "A file already exists at the original path", http.StatusConflict)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		logging.Error("RestoreTrash: failed to recreate the folder of %s: %v", item.OriginalPath, err)
+		httpError(w, r, "Failed to restore file", http.StatusInternalServerError)
+		return
+	}
+
+	if err := filesystem.MoveAll(filepath.Join(h.trashDir, item.TrashName), fullPath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// Removed from the trash directory by hand
+			if err := h.db.RemoveTrashItem(ctx, item.ID); err != nil {
+				logging.Warn("Failed to forget missing trash item %d: %v", item.ID, err)
+			}
+			httpError(w, r, "File is no longer in the trash", http.StatusNotFound)
+			return
+		}
+		logging.Error("RestoreTrash: failed to restore %s: %v", item.OriginalPath, err)
+		httpError(w, r, "Failed to restore file", http.StatusInternalServerError)
+		return
+	}
+	logging.Info("Restored %s from the trash", item.OriginalPath)
+
+	if err := h.db.RemoveTrashItem(ctx, item.ID); err != nil {
+		logging.Warn("Failed to remove restored item %d from the trash: %v", item.ID, err)
+	}
+
+	h.invalidateFolderThumbnails(item.OriginalPath)
+	if err := h.indexer.IndexDirectory(filepath.Dir(filepath.FromSlash(item.OriginalPath))); err != nil {
+		logging.Warn("Failed to re-index the folder of %s after a restore: %v", item.OriginalPath, err)
+	}
+	if item.Type == database.FileTypeFolder {
+		// Only the folder itself was indexed above
+		//nolint:contextcheck // Intentionally not passing request context - indexing runs in background
+		h.indexer.TriggerIndex()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, map[string]interface{}{
+		"status": "ok",
+		"path":   item.OriginalPath,
+	})
+}
+
+// PurgeTrash removes the files and folders kept in the trash longer than
+// TRASH_TTL for good, along with the data kept for their paths. Returns how
+// many were removed.
+func (h *Handlers) PurgeTrash(ctx context.Context) (int, error) {
+	if h.trashTTL <= 0 {
+		return 0, nil
+	}
+
+	items, err := h.db.ListExpiredTrash(ctx, time.Now().Add(-h.trashTTL))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range items {
+		item := &items[i]
+		if err := os.RemoveAll(filepath.Join(h.trashDir, item.TrashName)); err != nil {
+			logging.Warn("Failed to purge %s from the trash: %v", item.OriginalPath, err)
+			continue
+		}
+		if err := h.db.PurgeTrashItem(ctx, item); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	if purged > 0 {
+		logging.Info("Purged %d items from the trash", purged)
+	}
+	return purged, nil
+}
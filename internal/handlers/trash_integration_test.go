@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+
+	"media-viewer/internal/database"
+)
+
+func TestTrashIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+	h.trashDir = filepath.Join(t.TempDir(), ".trash")
+
+	ctx := context.Background()
+	if err := os.MkdirAll(filepath.Join(h.mediaDir, "trip"), 0o755); err != nil {
+		t.Fatalf("failed to create folder: %v", err)
+	}
+	for _, name := range []string{"trip/beach.jpg", "trip/sea.jpg"} {
+		if err := os.WriteFile(filepath.Join(h.mediaDir, name), []byte("test"), 0o644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	addExistingFileToDatabase(t, h, "trip", database.FileTypeFolder)
+	addExistingFileToDatabase(t, h, "trip/beach.jpg", database.FileTypeImage)
+	addExistingFileToDatabase(t, h, "trip/sea.jpg", database.FileTypeImage)
+	if err := h.db.AddTagToFile(ctx, "trip/beach.jpg", "summer"); err != nil {
+		t.Fatalf("failed to tag file: %v", err)
+	}
+
+	deleteFile := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/api/file/"+path, http.NoBody)
+		req = mux.SetURLVars(req, map[string]string{"path": path})
+		w := httptest.NewRecorder()
+		h.DeleteFile(w, req)
+		return w
+	}
+	restore := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/trash/restore", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.RestoreTrash(w, req)
+		return w
+	}
+
+	for _, tt := range []struct {
+		name, path string
+		expected   int
+	}{
+		{"outside media dir", "../etc/passwd", http.StatusBadRequest},
+		{"media dir itself", ".", http.StatusBadRequest},
+		{"not found", "trip/missing.jpg", http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := deleteFile(tt.path); w.Code != tt.expected {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.expected, w.Body.String())
+			}
+		})
+	}
+
+	w := deleteFile("trip")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var deleted database.TrashItem
+	if err := json.NewDecoder(w.Body).Decode(&deleted); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if deleted.OriginalPath != "trip" || deleted.Type != database.FileTypeFolder {
+		t.Errorf("deleted = %+v, want the trip folder", deleted)
+	}
+	if _, err := os.Stat(filepath.Join(h.mediaDir, "trip")); !os.IsNotExist(err) {
+		t.Error("Expected the folder to be gone from the media directory")
+	}
+	if _, err := h.db.GetFileByPath(ctx, "trip/beach.jpg"); err == nil {
+		t.Error("Expected the folder's files to be removed from the index")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/trash", http.NoBody)
+	w = httptest.NewRecorder()
+	h.ListTrash(w, req)
+	var list struct {
+		Items []database.TrashItem `json:"items"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode trash list: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].ID != deleted.ID {
+		t.Fatalf("trash = %+v, want the deleted folder", list.Items)
+	}
+
+	for _, tt := range []struct {
+		name, body string
+		expected   int
+	}{
+		{"invalid body", `{`, http.StatusBadRequest},
+		{"missing id", `{}`, http.StatusBadRequest},
+		{"unknown id", `{"id":999}`, http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := restore(tt.body); w.Code != tt.expected {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.expected, w.Body.String())
+			}
+		})
+	}
+
+	body := `{"id":` + strconv.FormatInt(deleted.ID, 10) + `}`
+	if w := restore(body); w.Code != http.StatusOK {
+		t.Fatalf("restore status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(h.mediaDir, "trip/beach.jpg")); err != nil {
+		t.Errorf("Expected the folder restored with its files: %v", err)
+	}
+	if _, err := h.db.GetFileByPath(ctx, "trip"); err != nil {
+		t.Errorf("Expected the restored folder to be indexed: %v", err)
+	}
+	if tags, _ := h.db.GetFileTags(ctx, "trip/beach.jpg"); len(tags) != 1 || tags[0] != "summer" {
+		t.Errorf("tags = %v, want the tag back with the file", tags)
+	}
+	if w := restore(body); w.Code != http.StatusNotFound {
+		t.Errorf("restoring twice: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// Restoring over a file created since is refused
+	if w := deleteFile("trip/sea.jpg"); w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if err := os.WriteFile(filepath.Join(h.mediaDir, "trip/sea.jpg"), []byte("new"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	items, _ := h.db.ListTrash(ctx)
+	if len(items) != 1 {
+		t.Fatalf("trash = %+v, want the deleted file", items)
+	}
+	if w := restore(`{"id":` + strconv.FormatInt(items[0].ID, 10) + `}`); w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	// Nothing is purged before it expires, or without a TTL
+	if purged, err := h.PurgeTrash(ctx); err != nil || purged != 0 {
+		t.Errorf("PurgeTrash without a TTL = %d, %v, want nothing purged", purged, err)
+	}
+	h.trashTTL = time.Hour
+	if purged, err := h.PurgeTrash(ctx); err != nil || purged != 0 {
+		t.Errorf("PurgeTrash = %d, %v, want nothing purged yet", purged, err)
+	}
+	h.trashTTL = time.Nanosecond
+	time.Sleep(1100 * time.Millisecond) // Deletion times are kept in seconds
+	if purged, err := h.PurgeTrash(ctx); err != nil || purged != 1 {
+		t.Errorf("PurgeTrash = %d, %v, want the expired file purged", purged, err)
+	}
+	if _, err := os.Stat(filepath.Join(h.trashDir, items[0].TrashName)); !os.IsNotExist(err) {
+		t.Error("Expected the purged file to be removed from the trash directory")
+	}
+}
+
+func TestTrashFolderKeepsTagsThroughIndexIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+	h.trashDir = filepath.Join(t.TempDir(), ".trash")
+
+	ctx := context.Background()
+	if err := os.MkdirAll(filepath.Join(h.mediaDir, "trip"), 0o755); err != nil {
+		t.Fatalf("failed to create folder: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(h.mediaDir, "trip/beach.jpg"), []byte("test"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	// Indexed, so the indexer remembers the folder as a top-level one
+	if err := h.indexer.Index(); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if err := h.db.AddTagToFile(ctx, "trip/beach.jpg", "summer"); err != nil {
+		t.Fatalf("failed to tag file: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/file/trip", http.NoBody)
+	req = mux.SetURLVars(req, map[string]string{"path": "trip"})
+	w := httptest.NewRecorder()
+	h.DeleteFile(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var deleted database.TrashItem
+	if err := json.NewDecoder(w.Body).Decode(&deleted); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	// The folder is gone from the media directory, but it's in the trash
+	// rather than vanished
+	if err := h.indexer.Index(); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/trash/restore", strings.NewReader(`{"id":`+strconv.FormatInt(deleted.ID, 10)+`}`))
+	w = httptest.NewRecorder()
+	h.RestoreTrash(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("restore status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if tags, _ := h.db.GetFileTags(ctx, "trip/beach.jpg"); len(tags) != 1 || tags[0] != "summer" {
+		t.Errorf("tags = %v, want the tag kept through the index run", tags)
+	}
+}
+
+func TestTrashLongFileNameIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+	h.trashDir = filepath.Join(t.TempDir(), ".trash")
+
+	// 250 bytes, which the trash name's prefix would take over the limit
+	name := strings.Repeat("a", 246) + ".jpg"
+	if err := os.WriteFile(filepath.Join(h.mediaDir, name), []byte("test"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	addExistingFileToDatabase(t, h, name, database.FileTypeImage)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/file/"+name, http.NoBody)
+	req = mux.SetURLVars(req, map[string]string{"path": name})
+	w := httptest.NewRecorder()
+	h.DeleteFile(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var deleted database.TrashItem
+	if err := json.NewDecoder(w.Body).Decode(&deleted); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	entries, err := os.ReadDir(h.trashDir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("trash directory = %v, %v, want the deleted file", entries, err)
+	}
+	if trashed := entries[0].Name(); len(trashed) > maxTrashName || !strings.HasSuffix(trashed, ".jpg") {
+		t.Errorf("trash name = %q (%d bytes), want at most %d bytes keeping the extension", trashed, len(trashed), maxTrashName)
+	}
+
+	// Restored under its full name
+	req = httptest.NewRequest(http.MethodPost, "/api/trash/restore", strings.NewReader(`{"id":`+strconv.FormatInt(deleted.ID, 10)+`}`))
+	w = httptest.NewRecorder()
+	h.RestoreTrash(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("restore status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(h.mediaDir, name)); err != nil {
+		t.Errorf("Expected the file restored under its full name: %v", err)
+	}
+}
+
+func TestTrashName(t *testing.T) {
+	stamp := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano()
+	prefix := strconv.FormatInt(stamp, 36) + "-"
+
+	if got := trashName(stamp, "beach.jpg"); got != prefix+"beach.jpg" {
+		t.Errorf("trashName() = %q, want the name kept whole", got)
+	}
+
+	for _, tt := range []struct {
+		name, base, ext string
+	}{
+		{"long stem", strings.Repeat("a", 300) + ".jpg", ".jpg"},
+		{"multi-byte characters", strings.Repeat("é", 150) + ".mp4", ".mp4"},
+		{"long extension", "a." + strings.Repeat("b", 300), ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := trashName(stamp, tt.base)
+			if len(got) > maxTrashName {
+				t.Errorf("trashName() is %d bytes, want at most %d", len(got), maxTrashName)
+			}
+			if !strings.HasPrefix(got, prefix) || !strings.HasSuffix(got, tt.ext) {
+				t.Errorf("trashName() = %q, want the stamp prefix and extension %q kept", got, tt.ext)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("trashName() = %q, split a character", got)
+			}
+		})
+	}
+}
+
+func TestDeleteFilesIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	h, cleanup := setupMediaIntegrationTest(t)
+	defer cleanup()
+	h.trashDir = filepath.Join(t.TempDir(), ".trash")
+
+	ctx := context.Background()
+	for _, name := range []string{"a/beach.jpg", "b/beach.jpg"} {
+		if err := os.MkdirAll(filepath.Join(h.mediaDir, filepath.Dir(name)), 0o755); err != nil {
+			t.Fatalf("failed to create folder: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(h.mediaDir, name), []byte("test"), 0o644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		addExistingFileToDatabase(t, h, name, database.FileTypeImage)
+	}
+
+	deleteFiles := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/files/delete", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.DeleteFiles(w, req)
+		return w
+	}
+
+	for _, tt := range []struct {
+		name, body string
+	}{
+		{"invalid body", `{`},
+		{"no paths", `{"paths":[]}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := deleteFiles(tt.body); w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+
+	w := deleteFiles(`{"paths":["a/beach.jpg","b/beach.jpg","a/beach.jpg","../etc/passwd","a/missing.jpg"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp deleteFilesResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Deleted != 2 || resp.Failed != 3 || len(resp.Results) != 5 {
+		t.Fatalf("response = %+v, want 2 deleted and 3 failed", resp)
+	}
+	for i, want := range []string{"", "", "Duplicate path", "Invalid path", "File not found"} {
+		result := resp.Results[i]
+		if result.Error != want {
+			t.Errorf("result %d = %+v, want error %q", i, result, want)
+		}
+		deleted := want == ""
+		if result.RemovedFromDisk != deleted || result.RemovedFromIndex != deleted || (result.TrashID != 0) != deleted {
+			t.Errorf("result %d = %+v, want removed %v", i, result, deleted)
+		}
+	}
+
+	// Both files share a name, yet each keeps its own place in the trash
+	items, _ := h.db.ListTrash(ctx)
+	if len(items) != 2 || items[0].TrashName == items[1].TrashName {
+		t.Fatalf("trash = %+v, want both files under distinct names", items)
+	}
+	for _, item := range items {
+		if _, err := os.Stat(filepath.Join(h.trashDir, item.TrashName)); err != nil {
+			t.Errorf("Expected %s in the trash directory: %v", item.OriginalPath, err)
+		}
+		if _, err := h.db.GetFileByPath(ctx, item.OriginalPath); err == nil {
+			t.Errorf("Expected %s removed from the index", item.OriginalPath)
+		}
+	}
+}
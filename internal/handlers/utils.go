@@ -4,9 +4,20 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"media-viewer/internal/i18n"
 	"media-viewer/internal/logging"
 )
 
+// maxJSONBodySize caps the size of JSON request bodies read with
+// decodeJSONBody. A batch of 10000 long paths fits comfortably.
+const maxJSONBodySize = 8 << 20
+
+// decodeJSONBody decodes a JSON request body into v, reading no more than
+// maxJSONBodySize bytes of it.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJSONBodySize)).Decode(v)
+}
+
 // writeJSON encodes v as JSON and writes it to the response writer.
 // Any encoding or write errors are logged since we typically cannot
 // recover from them in an HTTP handler context.
@@ -16,12 +27,30 @@ func writeJSON(w http.ResponseWriter, v interface{}) {
 	}
 }
 
-// writeJSONError writes an error response as JSON with the given status code.
+// writeJSONError writes an error response as JSON with the given status code,
+// translating the message like httpError.
 // nolint:unused // kept for future use in error response handlers
-func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
+func writeJSONError(w http.ResponseWriter, r *http.Request, message string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	writeJSON(w, map[string]string{"error": message})
+	writeJSON(w, map[string]string{"error": localizeError(w, r, message)})
+}
+
+// httpError writes a plain text error response like http.Error, with the
+// message translated into the request's locale if the catalog has it.
+func httpError(w http.ResponseWriter, r *http.Request, message string, statusCode int) {
+	http.Error(w, localizeError(w, r, message), statusCode)
+}
+
+// localizeError translates message into the request's locale, labelling the
+// response with the language when it has a translation
+func localizeError(w http.ResponseWriter, r *http.Request, message string) string {
+	locale := i18n.FromRequest(r)
+	translated := locale.Translate(message)
+	if translated != message {
+		w.Header().Set("Content-Language", string(locale))
+	}
+	return translated
 }
 
 // writeJSONStatus writes a simple status response as JSON.
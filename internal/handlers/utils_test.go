@@ -3,8 +3,12 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"media-viewer/internal/i18n"
 )
 
 // =============================================================================
@@ -409,3 +413,53 @@ func TestWriteJSONStatusWithSpecialCharacters(t *testing.T) {
 		})
 	}
 }
+
+// =============================================================================
+// httpError Tests
+// =============================================================================
+
+func TestHTTPErrorLocalized(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		acceptLanguage  string
+		cookie          string
+		message         string
+		expectedBody    string
+		expectedContent string
+	}{
+		{"default English", "", "", "File not found", "File not found", ""},
+		{"Accept-Language", "de-DE,de;q=0.9", "", "File not found", "Datei nicht gefunden", "de"},
+		{"cookie overrides header", "de", "fr", "File not found", "Fichier introuvable", "fr"},
+		{"unsupported language", "ja", "", "File not found", "File not found", ""},
+		{"message not in catalog", "es", "", "Failed to frobnicate", "Failed to frobnicate", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/file/missing.jpg", http.NoBody)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: i18n.CookieName, Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+
+			httpError(w, req, tt.message, http.StatusNotFound)
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("Status = %d, want %d", w.Code, http.StatusNotFound)
+			}
+			if body := strings.TrimSpace(w.Body.String()); body != tt.expectedBody {
+				t.Errorf("Body = %q, want %q", body, tt.expectedBody)
+			}
+			if got := w.Header().Get("Content-Language"); got != tt.expectedContent {
+				t.Errorf("Content-Language = %q, want %q", got, tt.expectedContent)
+			}
+		})
+	}
+}
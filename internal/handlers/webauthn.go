@@ -6,11 +6,14 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gorilla/mux"
 
 	"media-viewer/internal/database"
 	"media-viewer/internal/logging"
@@ -184,27 +187,27 @@ func (h *Handlers) BeginWebAuthnRegistration(w http.ResponseWriter, r *http.Requ
 
 	if !webAuthnEnabled {
 		logging.Debug("WebAuthn registration attempted but not configured")
-		http.Error(w, "WebAuthn not configured", http.StatusServiceUnavailable)
+		httpError(w, r, "WebAuthn not configured", http.StatusServiceUnavailable)
 		return
 	}
 
 	// Verify user is authenticated
 	cookie, err := r.Cookie(SessionCookieName)
 	if err != nil || cookie.Value == "" {
-		http.Error(w, "Must be logged in to register passkey", http.StatusUnauthorized)
+		httpError(w, r, "Must be logged in to register passkey", http.StatusUnauthorized)
 		return
 	}
 
 	_, err = h.db.ValidateSession(ctx, cookie.Value)
 	if err != nil {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
+		httpError(w, r, "Invalid session", http.StatusUnauthorized)
 		return
 	}
 
 	user, err := h.db.GetWebAuthnUser(ctx)
 	if err != nil {
 		logging.Error("Failed to get WebAuthn user: %v", err)
-		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get user", http.StatusInternalServerError)
 		return
 	}
 
@@ -236,19 +239,19 @@ func (h *Handlers) BeginWebAuthnRegistration(w http.ResponseWriter, r *http.Requ
 	)
 	if err != nil {
 		logging.Error("Failed to begin WebAuthn registration: %v", err)
-		http.Error(w, "Failed to start registration", http.StatusInternalServerError)
+		httpError(w, r, "Failed to start registration", http.StatusInternalServerError)
 		return
 	}
 
 	sessionData, err := json.Marshal(session)
 	if err != nil {
-		http.Error(w, "Failed to store session", http.StatusInternalServerError)
+		httpError(w, r, "Failed to store session", http.StatusInternalServerError)
 		return
 	}
 
 	sessionID := generateWebAuthnSessionID()
 	if err := h.db.SaveWebAuthnSession(ctx, sessionID, sessionData, 5*time.Minute); err != nil {
-		http.Error(w, "Failed to store session", http.StatusInternalServerError)
+		httpError(w, r, "Failed to store session", http.StatusInternalServerError)
 		return
 	}
 
@@ -264,19 +267,19 @@ func (h *Handlers) FinishWebAuthnRegistration(w http.ResponseWriter, r *http.Req
 	ctx := r.Context()
 
 	if !webAuthnEnabled {
-		http.Error(w, "WebAuthn not configured", http.StatusServiceUnavailable)
+		httpError(w, r, "WebAuthn not configured", http.StatusServiceUnavailable)
 		return
 	}
 
 	cookie, err := r.Cookie(SessionCookieName)
 	if err != nil || cookie.Value == "" {
-		http.Error(w, "Must be logged in", http.StatusUnauthorized)
+		httpError(w, r, "Must be logged in", http.StatusUnauthorized)
 		return
 	}
 
 	_, err = h.db.ValidateSession(ctx, cookie.Value)
 	if err != nil {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
+		httpError(w, r, "Invalid session", http.StatusUnauthorized)
 		return
 	}
 
@@ -286,7 +289,7 @@ func (h *Handlers) FinishWebAuthnRegistration(w http.ResponseWriter, r *http.Req
 		Credential json.RawMessage `json:"credential"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		httpError(w, r, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
@@ -296,19 +299,19 @@ func (h *Handlers) FinishWebAuthnRegistration(w http.ResponseWriter, r *http.Req
 
 	sessionData, err := h.db.GetWebAuthnSession(ctx, req.SessionID)
 	if err != nil {
-		http.Error(w, "Invalid or expired session", http.StatusBadRequest)
+		httpError(w, r, "Invalid or expired session", http.StatusBadRequest)
 		return
 	}
 
 	var session webauthn.SessionData
 	if err := json.Unmarshal(sessionData, &session); err != nil {
-		http.Error(w, "Invalid session data", http.StatusInternalServerError)
+		httpError(w, r, "Invalid session data", http.StatusInternalServerError)
 		return
 	}
 
 	user, err := h.db.GetWebAuthnUser(ctx)
 	if err != nil {
-		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get user", http.StatusInternalServerError)
 		return
 	}
 
@@ -317,20 +320,20 @@ func (h *Handlers) FinishWebAuthnRegistration(w http.ResponseWriter, r *http.Req
 	)
 	if err != nil {
 		logging.Warn("Failed to parse credential: %v", err)
-		http.Error(w, "Invalid credential", http.StatusBadRequest)
+		httpError(w, r, "Invalid credential", http.StatusBadRequest)
 		return
 	}
 
 	credential, err := webAuthnInstance.CreateCredential(user, session, credentialResponse)
 	if err != nil {
 		logging.Warn("Failed to create credential: %v", err)
-		http.Error(w, "Failed to verify credential", http.StatusBadRequest)
+		httpError(w, r, "Failed to verify credential", http.StatusBadRequest)
 		return
 	}
 
 	if err := h.db.SaveWebAuthnCredential(ctx, user.GetUser().ID, credential, req.Name); err != nil {
 		logging.Error("Failed to save credential: %v", err)
-		http.Error(w, "Failed to save credential", http.StatusInternalServerError)
+		httpError(w, r, "Failed to save credential", http.StatusInternalServerError)
 		return
 	}
 
@@ -349,23 +352,23 @@ func (h *Handlers) BeginWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
 
 	if !webAuthnEnabled {
 		logging.Debug("WebAuthn login attempted but not configured")
-		http.Error(w, "WebAuthn not configured", http.StatusServiceUnavailable)
+		httpError(w, r, "WebAuthn not configured", http.StatusServiceUnavailable)
 		return
 	}
 
 	if !h.db.HasWebAuthnCredentials(ctx) {
-		http.Error(w, "No passkeys registered", http.StatusNotFound)
+		httpError(w, r, "No passkeys registered", http.StatusNotFound)
 		return
 	}
 
 	user, err := h.db.GetWebAuthnUser(ctx)
 	if err != nil {
-		http.Error(w, "No user found", http.StatusNotFound)
+		httpError(w, r, "No user found", http.StatusNotFound)
 		return
 	}
 
 	if len(user.WebAuthnCredentials()) == 0 {
-		http.Error(w, "No passkeys registered", http.StatusNotFound)
+		httpError(w, r, "No passkeys registered", http.StatusNotFound)
 		return
 	}
 
@@ -375,19 +378,19 @@ func (h *Handlers) BeginWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
 	)
 	if err != nil {
 		logging.Error("Failed to begin WebAuthn login: %v", err)
-		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		httpError(w, r, "Failed to start login", http.StatusInternalServerError)
 		return
 	}
 
 	sessionData, err := json.Marshal(session)
 	if err != nil {
-		http.Error(w, "Failed to store session", http.StatusInternalServerError)
+		httpError(w, r, "Failed to store session", http.StatusInternalServerError)
 		return
 	}
 
 	sessionID := generateWebAuthnSessionID()
 	if err := h.db.SaveWebAuthnSession(ctx, sessionID, sessionData, 5*time.Minute); err != nil {
-		http.Error(w, "Failed to store session", http.StatusInternalServerError)
+		httpError(w, r, "Failed to store session", http.StatusInternalServerError)
 		return
 	}
 
@@ -403,7 +406,7 @@ func (h *Handlers) FinishWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	if !webAuthnEnabled {
-		http.Error(w, "WebAuthn not configured", http.StatusServiceUnavailable)
+		httpError(w, r, "WebAuthn not configured", http.StatusServiceUnavailable)
 		return
 	}
 
@@ -412,25 +415,25 @@ func (h *Handlers) FinishWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
 		Credential json.RawMessage `json:"credential"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		httpError(w, r, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
 	sessionData, err := h.db.GetWebAuthnSession(ctx, req.SessionID)
 	if err != nil {
-		http.Error(w, "Invalid or expired session", http.StatusBadRequest)
+		httpError(w, r, "Invalid or expired session", http.StatusBadRequest)
 		return
 	}
 
 	var session webauthn.SessionData
 	if err := json.Unmarshal(sessionData, &session); err != nil {
-		http.Error(w, "Invalid session data", http.StatusInternalServerError)
+		httpError(w, r, "Invalid session data", http.StatusInternalServerError)
 		return
 	}
 
 	user, err := h.db.GetWebAuthnUser(ctx)
 	if err != nil {
-		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get user", http.StatusInternalServerError)
 		return
 	}
 
@@ -439,14 +442,14 @@ func (h *Handlers) FinishWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
 	)
 	if err != nil {
 		logging.Warn("Failed to parse credential: %v", err)
-		http.Error(w, "Invalid credential", http.StatusBadRequest)
+		httpError(w, r, "Invalid credential", http.StatusBadRequest)
 		return
 	}
 
 	credential, err := webAuthnInstance.ValidateLogin(user, session, credentialResponse)
 	if err != nil {
 		logging.Warn("WebAuthn login failed: %v", err)
-		http.Error(w, "Authentication failed", http.StatusUnauthorized)
+		httpError(w, r, "Authentication failed", http.StatusUnauthorized)
 		return
 	}
 
@@ -459,7 +462,7 @@ func (h *Handlers) FinishWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
 	authSession, err := h.db.CreateSession(ctx, user.GetUser().ID)
 	if err != nil {
 		logging.Error("Failed to create session: %v", err)
-		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		httpError(w, r, "Failed to create session", http.StatusInternalServerError)
 		return
 	}
 
@@ -481,72 +484,86 @@ func (h *Handlers) FinishWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// ListPasskeys returns all registered passkeys
-func (h *Handlers) ListPasskeys(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
+// passkeySession reports whether a passkey management request carries a
+// valid session, answering 401 if not
+func (h *Handlers) passkeySession(w http.ResponseWriter, r *http.Request) bool {
 	cookie, err := r.Cookie(SessionCookieName)
 	if err != nil || cookie.Value == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
+		httpError(w, r, "Unauthorized", http.StatusUnauthorized)
+		return false
 	}
 
-	_, err = h.db.ValidateSession(ctx, cookie.Value)
-	if err != nil {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
+	if _, err := h.db.ValidateSession(r.Context(), cookie.Value); err != nil {
+		httpError(w, r, "Invalid session", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// passkeyInfo describes a registered passkey without its key material
+type passkeyInfo struct {
+	ID           int64  `json:"id"`
+	CredentialID string `json:"credentialId"` // Base64url, as in WebAuthn responses
+	Name         string `json:"name"`
+	CreatedAt    string `json:"createdAt"`
+	LastUsedAt   string `json:"lastUsedAt"`
+	SignCount    uint32 `json:"signCount"`
+}
+
+// ListPasskeys returns all registered passkeys
+func (h *Handlers) ListPasskeys(w http.ResponseWriter, r *http.Request) {
+	h.listPasskeys(w, r, "passkeys")
+}
+
+// ListWebAuthnCredentials returns the registered passkeys with when each was
+// created and last used, under "credentials" like the rest of the credential
+// endpoints
+func (h *Handlers) ListWebAuthnCredentials(w http.ResponseWriter, r *http.Request) {
+	h.listPasskeys(w, r, "credentials")
+}
+
+// listPasskeys writes the user's passkeys as a JSON object with the list
+// under key
+func (h *Handlers) listPasskeys(w http.ResponseWriter, r *http.Request, key string) {
+	ctx := r.Context()
+
+	if !h.passkeySession(w, r) {
 		return
 	}
 
 	user, err := h.db.GetWebAuthnUser(ctx)
 	if err != nil {
-		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get user", http.StatusInternalServerError)
 		return
 	}
 
 	credentials, err := h.db.ListWebAuthnCredentials(ctx, user.GetUser().ID)
 	if err != nil {
-		http.Error(w, "Failed to list passkeys", http.StatusInternalServerError)
+		httpError(w, r, "Failed to list passkeys", http.StatusInternalServerError)
 		return
 	}
 
-	type PasskeyInfo struct {
-		ID         int64  `json:"id"`
-		Name       string `json:"name"`
-		CreatedAt  string `json:"createdAt"`
-		LastUsedAt string `json:"lastUsedAt"`
-		SignCount  uint32 `json:"signCount"`
-	}
-
-	passkeys := make([]PasskeyInfo, 0, len(credentials))
+	passkeys := make([]passkeyInfo, 0, len(credentials))
 	for _, c := range credentials {
-		passkeys = append(passkeys, PasskeyInfo{
-			ID:         c.ID,
-			Name:       c.Name,
-			CreatedAt:  c.CreatedAt.Format(time.RFC3339),
-			LastUsedAt: c.LastUsedAt.Format(time.RFC3339),
-			SignCount:  c.SignCount,
+		passkeys = append(passkeys, passkeyInfo{
+			ID:           c.ID,
+			CredentialID: base64.RawURLEncoding.EncodeToString(c.CredentialID),
+			Name:         c.Name,
+			CreatedAt:    c.CreatedAt.Format(time.RFC3339),
+			LastUsedAt:   c.LastUsedAt.Format(time.RFC3339),
+			SignCount:    c.SignCount,
 		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	writeJSON(w, map[string]interface{}{
-		"passkeys": passkeys,
+		key: passkeys,
 	})
 }
 
-// DeletePasskey removes a registered passkey
+// DeletePasskey removes a registered passkey by the ID in the request body
 func (h *Handlers) DeletePasskey(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	cookie, err := r.Cookie(SessionCookieName)
-	if err != nil || cookie.Value == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	_, err = h.db.ValidateSession(ctx, cookie.Value)
-	if err != nil {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
+	if !h.passkeySession(w, r) {
 		return
 	}
 
@@ -554,23 +571,58 @@ func (h *Handlers) DeletePasskey(w http.ResponseWriter, r *http.Request) {
 		ID int64 `json:"id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		httpError(w, r, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	h.removePasskey(w, r, func(ctx context.Context, userID int64) error {
+		return h.db.DeleteWebAuthnCredential(ctx, userID, req.ID)
+	})
+}
+
+// DeleteWebAuthnCredential removes a registered passkey by the base64url
+// WebAuthn credential ID in the path
+func (h *Handlers) DeleteWebAuthnCredential(w http.ResponseWriter, r *http.Request) {
+	if !h.passkeySession(w, r) {
+		return
+	}
+
+	credentialID, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(mux.Vars(r)["id"], "="))
+	if err != nil || len(credentialID) == 0 {
+		httpError(w, r, "Invalid credential ID", http.StatusBadRequest)
 		return
 	}
 
+	h.removePasskey(w, r, func(ctx context.Context, userID int64) error {
+		return h.db.DeleteWebAuthnCredentialByCredentialID(ctx, userID, credentialID)
+	})
+}
+
+// removePasskey removes one of the user's passkeys with remove, answering
+// 404 if there is no such passkey and 409 if it is the last one while no
+// password is set, as that would leave no way to log in
+func (h *Handlers) removePasskey(w http.ResponseWriter, r *http.Request, remove func(ctx context.Context, userID int64) error) {
+	ctx := r.Context()
+
 	user, err := h.db.GetWebAuthnUser(ctx)
 	if err != nil {
-		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		httpError(w, r, "Failed to get user", http.StatusInternalServerError)
 		return
 	}
 
-	if err := h.db.DeleteWebAuthnCredential(ctx, user.GetUser().ID, req.ID); err != nil {
-		http.Error(w, "Failed to delete passkey", http.StatusInternalServerError)
+	if err := remove(ctx, user.GetUser().ID); err != nil {
+		if errors.Is(err, database.ErrCredentialNotFound) {
+			httpError(w, r, "Passkey not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, database.ErrLastLoginMethod) {
+			httpError(w, r, "Cannot remove the last passkey while no password is set", http.StatusConflict)
+			return
+		}
+		httpError(w, r, "Failed to delete passkey", http.StatusInternalServerError)
 		return
 	}
 
-	logging.Info("Deleted passkey ID %d", req.ID)
-
 	w.Header().Set("Content-Type", "application/json")
 	writeJSON(w, map[string]interface{}{
 		"success": true,
@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,6 +11,9 @@ import (
 	"os"
 	"testing"
 
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gorilla/mux"
+
 	"media-viewer/internal/database"
 	"media-viewer/internal/indexer"
 	"media-viewer/internal/media"
@@ -234,7 +238,7 @@ func TestDeletePasskeySuccess(t *testing.T) {
 		t.Fatalf("failed to create session: %v", err)
 	}
 
-	// Try to delete a non-existent passkey (should not error, just succeed silently or return error)
+	// Try to delete a non-existent passkey
 	reqBody := `{"id": 999}`
 	req := httptest.NewRequest(http.MethodDelete, "/api/webauthn/passkeys", bytes.NewReader([]byte(reqBody)))
 	req.Header.Set("Content-Type", "application/json")
@@ -243,10 +247,8 @@ func TestDeletePasskeySuccess(t *testing.T) {
 
 	h.DeletePasskey(w, req)
 
-	// Should either succeed (no error for non-existent) or return error
-	// The implementation doesn't check if ID exists, so it likely succeeds
-	if w.Code != http.StatusOK && w.Code != http.StatusInternalServerError {
-		t.Errorf("status = %d, want 200 or 500", w.Code)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
 	}
 }
 
@@ -287,6 +289,90 @@ func TestDeletePasskeyWithDatabaseError(t *testing.T) {
 	}
 }
 
+// TestWebAuthnCredentialsListAndDelete tests listing passkeys and revoking one by credential ID
+func TestWebAuthnCredentialsListAndDelete(t *testing.T) {
+	h, cleanup := setupWebAuthnCoverageTest(t, true)
+	defer cleanup()
+
+	ctx := context.Background()
+	if err := h.db.CreateUser(ctx, "testpass123"); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	session, err := h.db.CreateSession(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	credentialID := []byte{0xfb, 0xff, 0x01, 0x02}
+	cred := &webauthn.Credential{ID: credentialID, PublicKey: []byte("key")}
+	if err := h.db.SaveWebAuthnCredential(ctx, 1, cred, "Laptop"); err != nil {
+		t.Fatalf("failed to save credential: %v", err)
+	}
+	encodedID := base64.RawURLEncoding.EncodeToString(credentialID)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/webauthn/credentials", http.NoBody)
+	req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: session.Token})
+	w := httptest.NewRecorder()
+	h.ListWebAuthnCredentials(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("list status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var resp struct {
+		Credentials []passkeyInfo `json:"credentials"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Credentials) != 1 || resp.Credentials[0].CredentialID != encodedID || resp.Credentials[0].Name != "Laptop" {
+		t.Fatalf("credentials = %+v, want the Laptop passkey with ID %s", resp.Credentials, encodedID)
+	}
+
+	deleteCredential := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/api/auth/webauthn/credentials/"+id, http.NoBody)
+		req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: session.Token})
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		w := httptest.NewRecorder()
+		h.DeleteWebAuthnCredential(w, req)
+		return w
+	}
+
+	if w := deleteCredential("not*base64"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid ID status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := deleteCredential(base64.RawURLEncoding.EncodeToString([]byte("other"))); w.Code != http.StatusNotFound {
+		t.Errorf("unknown ID status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// The user has a password, so the only passkey can go
+	if w := deleteCredential(encodedID); w.Code != http.StatusOK {
+		t.Fatalf("delete status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if count := h.db.CountWebAuthnCredentials(ctx); count != 0 {
+		t.Errorf("expected the passkey to be removed, %d left", count)
+	}
+}
+
+// TestWebAuthnCredentialsUnauthorized tests the credential endpoints without a session
+func TestWebAuthnCredentialsUnauthorized(t *testing.T) {
+	h, cleanup := setupWebAuthnCoverageTest(t, true)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	h.ListWebAuthnCredentials(w, httptest.NewRequest(http.MethodGet, "/api/auth/webauthn/credentials", http.NoBody))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("list status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/auth/webauthn/credentials/AQID", http.NoBody)
+	req = mux.SetURLVars(req, map[string]string{"id": "AQID"})
+	w = httptest.NewRecorder()
+	h.DeleteWebAuthnCredential(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("delete status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
 // TestBeginWebAuthnLoginNoCredentialsInDatabase tests login when no credentials registered
 func TestBeginWebAuthnLoginNoCredentialsInDatabase(t *testing.T) {
 	h, cleanup := setupWebAuthnCoverageTest(t, true)
@@ -0,0 +1,96 @@
+package i18n
+
+// catalog holds the translations of each locale other than English, keyed by
+// the English message
+var catalog = map[Locale]map[string]string{
+	German: {
+		"Invalid request body":                       "Ungültiger Anfragetext",
+		"Invalid request":                            "Ungültige Anfrage",
+		"Method not allowed":                         "Methode nicht erlaubt",
+		"Unauthorized":                               "Nicht autorisiert",
+		"Must be logged in":                          "Anmeldung erforderlich",
+		"Invalid session":                            "Ungültige Sitzung",
+		"Invalid or expired session":                 "Ungültige oder abgelaufene Sitzung",
+		"Invalid password":                           "Ungültiges Passwort",
+		"Current password is incorrect":              "Das aktuelle Passwort ist falsch",
+		"Password must be at least 6 characters":     "Das Passwort muss mindestens 6 Zeichen lang sein",
+		"New password must be at least 6 characters": "Das neue Passwort muss mindestens 6 Zeichen lang sein",
+		"Password must not exceed 72 characters":     "Das Passwort darf höchstens 72 Zeichen lang sein",
+		"Setup already completed":                    "Die Einrichtung ist bereits abgeschlossen",
+		"Path is required":                           "Pfad ist erforderlich",
+		"Paths array is required":                    "Eine Liste von Pfaden ist erforderlich",
+		"Invalid path":                               "Ungültiger Pfad",
+		"Name is required":                           "Name ist erforderlich",
+		"Tag name is required":                       "Tag-Name ist erforderlich",
+		"File not found":                             "Datei nicht gefunden",
+		"Playlist not found":                         "Playlist nicht gefunden",
+		"Unsupported file type":                      "Nicht unterstützter Dateityp",
+		"Failed to access file":                      "Zugriff auf die Datei fehlgeschlagen",
+		"Failed to list directory":                   "Ordner konnte nicht aufgelistet werden",
+		"Thumbnails disabled":                        "Vorschaubilder sind deaktiviert",
+		"Thumbnail generation timed out":             "Zeitüberschreitung beim Erstellen des Vorschaubilds",
+	},
+	Spanish: {
+		"Invalid request body":                       "Cuerpo de la solicitud no válido",
+		"Invalid request":                            "Solicitud no válida",
+		"Method not allowed":                         "Método no permitido",
+		"Unauthorized":                               "No autorizado",
+		"Must be logged in":                          "Debe iniciar sesión",
+		"Invalid session":                            "Sesión no válida",
+		"Invalid or expired session":                 "Sesión no válida o caducada",
+		"Invalid password":                           "Contraseña no válida",
+		"Current password is incorrect":              "La contraseña actual es incorrecta",
+		"Password must be at least 6 characters":     "La contraseña debe tener al menos 6 caracteres",
+		"New password must be at least 6 characters": "La nueva contraseña debe tener al menos 6 caracteres",
+		"Password must not exceed 72 characters":     "La contraseña no puede superar los 72 caracteres",
+		"Setup already completed":                    "La configuración ya se completó",
+		"Path is required":                           "La ruta es obligatoria",
+		"Paths array is required":                    "Se requiere una lista de rutas",
+		"Invalid path":                               "Ruta no válida",
+		"Name is required":                           "El nombre es obligatorio",
+		"Tag name is required":                       "El nombre de la etiqueta es obligatorio",
+		"File not found":                             "Archivo no encontrado",
+		"Playlist not found":                         "Lista de reproducción no encontrada",
+		"Unsupported file type":                      "Tipo de archivo no compatible",
+		"Failed to access file":                      "No se pudo acceder al archivo",
+		"Failed to list directory":                   "No se pudo listar la carpeta",
+		"Thumbnails disabled":                        "Las miniaturas están desactivadas",
+		"Thumbnail generation timed out":             "Se agotó el tiempo al generar la miniatura",
+	},
+	French: {
+		"Invalid request body":                       "Corps de la requête invalide",
+		"Invalid request":                            "Requête invalide",
+		"Method not allowed":                         "Méthode non autorisée",
+		"Unauthorized":                               "Non autorisé",
+		"Must be logged in":                          "Vous devez être connecté",
+		"Invalid session":                            "Session invalide",
+		"Invalid or expired session":                 "Session invalide ou expirée",
+		"Invalid password":                           "Mot de passe invalide",
+		"Current password is incorrect":              "Le mot de passe actuel est incorrect",
+		"Password must be at least 6 characters":     "Le mot de passe doit contenir au moins 6 caractères",
+		"New password must be at least 6 characters": "Le nouveau mot de passe doit contenir au moins 6 caractères",
+		"Password must not exceed 72 characters":     "Le mot de passe ne doit pas dépasser 72 caractères",
+		"Setup already completed":                    "La configuration est déjà terminée",
+		"Path is required":                           "Le chemin est requis",
+		"Paths array is required":                    "Une liste de chemins est requise",
+		"Invalid path":                               "Chemin invalide",
+		"Name is required":                           "Le nom est requis",
+		"Tag name is required":                       "Le nom de l'étiquette est requis",
+		"File not found":                             "Fichier introuvable",
+		"Playlist not found":                         "Playlist introuvable",
+		"Unsupported file type":                      "Type de fichier non pris en charge",
+		"Failed to access file":                      "Impossible d'accéder au fichier",
+		"Failed to list directory":                   "Impossible de lister le dossier",
+		"Thumbnails disabled":                        "Les miniatures sont désactivées",
+		"Thumbnail generation timed out":             "Délai dépassé lors de la génération de la miniature",
+	},
+}
+
+// Translate returns message in the locale, or message itself if the catalog
+// has no translation for it.
+func (l Locale) Translate(message string) string {
+	if translated, ok := catalog[l][message]; ok {
+		return translated
+	}
+	return message
+}
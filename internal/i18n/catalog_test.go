@@ -0,0 +1,70 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// handlersDir holds the handlers whose error messages the catalog translates
+const handlersDir = "../handlers"
+
+// handlerStrings returns every string literal in the handlers' source
+func handlerStrings(t *testing.T) map[string]bool {
+	t.Helper()
+	entries, err := os.ReadDir(handlersDir)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", handlersDir, err)
+	}
+
+	strs := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(handlersDir, name), nil, 0)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", name, err)
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			if lit, ok := n.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				if s, err := strconv.Unquote(lit.Value); err == nil {
+					strs[s] = true
+				}
+			}
+			return true
+		})
+	}
+	return strs
+}
+
+func TestCatalogKeysAreHandlerMessages(t *testing.T) {
+	strs := handlerStrings(t)
+	for locale, messages := range catalog {
+		for message := range messages {
+			if !strs[message] {
+				t.Errorf("%s: %q is not a message the handlers send", locale, message)
+			}
+		}
+	}
+}
+
+func TestCatalogLocalesMatch(t *testing.T) {
+	for locale, messages := range catalog {
+		for message := range catalog[German] {
+			if _, ok := messages[message]; !ok {
+				t.Errorf("%s: missing a translation of %q", locale, message)
+			}
+		}
+		if len(messages) != len(catalog[German]) {
+			t.Errorf("%s: %d translations, German has %d", locale, len(messages), len(catalog[German]))
+		}
+	}
+}
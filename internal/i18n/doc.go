@@ -0,0 +1,34 @@
+// Package i18n provides per-request locales for the media-viewer
+// application: translated error messages and locale-aware name sorting.
+//
+// # Locales
+//
+// A Locale is one of the languages the message catalog covers. English is
+// the default and the fallback for anything unrecognized. FromRequest picks
+// the locale for a request from the "locale" cookie, which holds a language
+// the user chose over their browser's, or otherwise from the Accept-Language
+// header:
+//
+//	locale := i18n.FromRequest(r)
+//
+// # Messages
+//
+// The catalog is keyed by the English text, so handlers keep writing their
+// messages in English and translate them on the way out:
+//
+//	http.Error(w, locale.Translate("File not found"), http.StatusNotFound)
+//
+// Messages missing from the catalog, including ones built at run time, are
+// returned unchanged. Every catalog key must be a message the handlers send
+// word for word, which the package tests check.
+//
+// # Sorting
+//
+// Each locale other than English has a SQLite collation ordering names by
+// its conventions, with runs of digits compared by value so "IMG_2.jpg"
+// sorts before "IMG_10.jpg". English keeps SQLite's NOCASE collation.
+// RegisterCollations adds the collations to a connection, and
+// Locale.Collation names the one to use in ORDER BY:
+//
+//	query := "SELECT name FROM files ORDER BY name COLLATE " + locale.Collation()
+package i18n
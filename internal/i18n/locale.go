@@ -0,0 +1,125 @@
+package i18n
+
+import (
+	"net/http"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// Locale is a language the application can respond in.
+type Locale string
+
+// Supported locales
+const (
+	English Locale = "en"
+	German  Locale = "de"
+	Spanish Locale = "es"
+	French  Locale = "fr"
+)
+
+// CookieName is the cookie holding a user's chosen locale, which takes
+// precedence over Accept-Language.
+const CookieName = "locale"
+
+// defaultCollation is the SQLite collation English names sort by
+const defaultCollation = "NOCASE"
+
+// locales lists the supported locales, the default first, with the
+// language tags they match
+var locales = []struct {
+	locale Locale
+	tag    language.Tag
+}{
+	{English, language.English},
+	{German, language.German},
+	{Spanish, language.Spanish},
+	{French, language.French},
+}
+
+var matcher = func() language.Matcher {
+	tags := make([]language.Tag, len(locales))
+	for i, l := range locales {
+		tags[i] = l.tag
+	}
+	return language.NewMatcher(tags)
+}()
+
+// Locales returns the supported locales, English first.
+func Locales() []Locale {
+	result := make([]Locale, len(locales))
+	for i, l := range locales {
+		result[i] = l.locale
+	}
+	return result
+}
+
+// Negotiate returns the supported locale best matching an Accept-Language
+// header, or English if none does.
+func Negotiate(acceptLanguage string) Locale {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return English
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return English
+	}
+	return locales[index].locale
+}
+
+// Parse returns the supported locale for a language tag such as "de" or
+// "fr-CA", and false if there isn't one.
+func Parse(value string) (Locale, bool) {
+	tag, err := language.Parse(value)
+	if err != nil {
+		return English, false
+	}
+	_, index, confidence := matcher.Match(tag)
+	if confidence == language.No {
+		return English, false
+	}
+	return locales[index].locale, true
+}
+
+// FromRequest returns the locale to respond to r in: the one in its locale
+// cookie if that's supported, otherwise the best match for its
+// Accept-Language header.
+func FromRequest(r *http.Request) Locale {
+	if cookie, err := r.Cookie(CookieName); err == nil {
+		if locale, ok := Parse(cookie.Value); ok {
+			return locale
+		}
+	}
+	return Negotiate(r.Header.Get("Accept-Language"))
+}
+
+// Collation returns the name of the SQLite collation that sorts names for
+// the locale. Unsupported locales get English's NOCASE.
+func (l Locale) Collation() string {
+	for _, supported := range locales[1:] {
+		if supported.locale == l {
+			return collationName(l)
+		}
+	}
+	return defaultCollation
+}
+
+// collationName returns the name a locale's collation is registered under
+func collationName(l Locale) string {
+	return "locale_" + string(l)
+}
+
+// RegisterCollations registers a collation for each locale other than
+// English through register, which is typically the RegisterCollation method
+// of a SQLite connection. Collators aren't safe for concurrent use, so each
+// call creates its own; a connection runs one statement at a time.
+func RegisterCollations(register func(name string, cmp func(a, b string) int) error) error {
+	for _, l := range locales[1:] {
+		collator := collate.New(l.tag, collate.Numeric)
+		if err := register(collationName(l.locale), collator.CompareString); err != nil {
+			return err
+		}
+	}
+	return nil
+}
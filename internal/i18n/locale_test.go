@@ -0,0 +1,115 @@
+package i18n
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		want           Locale
+	}{
+		{"", English},
+		{"en-US,en;q=0.9", English},
+		{"de-DE,de;q=0.9,en;q=0.8", German},
+		{"fr-CA", French},
+		{"es-419", Spanish},
+		{"ja, fr;q=0.5", French},
+		{"ja", English},
+		{"de;q=0.2, es;q=0.8", Spanish},
+		{"not a language", English},
+	}
+
+	for _, tt := range tests {
+		if got := Negotiate(tt.acceptLanguage); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.acceptLanguage, got, tt.want)
+		}
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/files", http.NoBody)
+	req.Header.Set("Accept-Language", "fr")
+	if got := FromRequest(req); got != French {
+		t.Errorf("Expected Accept-Language to give %q, got %q", French, got)
+	}
+
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: "de"})
+	if got := FromRequest(req); got != German {
+		t.Errorf("Expected the cookie to take precedence, got %q", got)
+	}
+
+	req = httptest.NewRequest("GET", "/api/files", http.NoBody)
+	req.Header.Set("Accept-Language", "es")
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: "xx-unknown"})
+	if got := FromRequest(req); got != Spanish {
+		t.Errorf("Expected an unsupported cookie to be ignored, got %q", got)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if got := German.Translate("File not found"); got != "Datei nicht gefunden" {
+		t.Errorf("Unexpected German translation %q", got)
+	}
+	if got := English.Translate("File not found"); got != "File not found" {
+		t.Errorf("Expected English to be unchanged, got %q", got)
+	}
+	if got := French.Translate("Failed to frobnicate"); got != "Failed to frobnicate" {
+		t.Errorf("Expected an unknown message to be unchanged, got %q", got)
+	}
+}
+
+func TestCatalogComplete(t *testing.T) {
+	for _, locale := range Locales()[1:] {
+		messages, ok := catalog[locale]
+		if !ok {
+			t.Errorf("Locale %q has no catalog", locale)
+			continue
+		}
+		for _, other := range Locales()[1:] {
+			for message := range catalog[other] {
+				if _, ok := messages[message]; !ok {
+					t.Errorf("Locale %q is missing %q", locale, message)
+				}
+			}
+		}
+	}
+}
+
+func TestCollations(t *testing.T) {
+	if got := English.Collation(); got != "NOCASE" {
+		t.Errorf("Expected English to use NOCASE, got %q", got)
+	}
+	if got := Locale("xx").Collation(); got != "NOCASE" {
+		t.Errorf("Expected an unsupported locale to use NOCASE, got %q", got)
+	}
+
+	collations := make(map[string]func(a, b string) int)
+	err := RegisterCollations(func(name string, cmp func(a, b string) int) error {
+		collations[name] = cmp
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterCollations failed: %v", err)
+	}
+	for _, locale := range Locales()[1:] {
+		if collations[locale.Collation()] == nil {
+			t.Errorf("No collation registered for %q", locale)
+		}
+	}
+
+	names := []string{"IMG_10.jpg", "Zebra.jpg", "Äpfel.jpg", "IMG_2.jpg", "apple.jpg", "Ost.jpg"}
+	// NOCASE would put Äpfel last and IMG_10 before IMG_2
+	cmp := collations[German.Collation()]
+	sort.Slice(names, func(i, j int) bool { return cmp(names[i], names[j]) < 0 })
+
+	want := []string{"Äpfel.jpg", "apple.jpg", "IMG_2.jpg", "IMG_10.jpg", "Ost.jpg", "Zebra.jpg"}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("Expected German order %v, got %v", want, names)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package indexer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/logging"
+)
+
+// IndexDirectory re-indexes one folder and the entries directly in it,
+// without walking the rest of the library. It is for changes made through
+// the API, such as moves, whose effect on the index is already known. relDir
+// is relative to the media directory; "" is the root. A running full index
+// picks the changes up itself, so nothing is done then.
+func (idx *Indexer) IndexDirectory(relDir string) error {
+	if idx.IsIndexing() {
+		return nil
+	}
+
+	relDir = filepath.Clean(relDir)
+	if relDir == "." {
+		relDir = ""
+	}
+
+	// Load the .mediaignore files of the folders above, as a walk would
+	ignore := idx.newIgnoreMatcher()
+	dir := idx.mediaDir
+	if relDir != "" {
+		for _, name := range strings.Split(relDir, string(filepath.Separator)) {
+			dir = filepath.Join(dir, name)
+			if strings.HasPrefix(name, ".") || ignore.skips(dir, true) {
+				return nil
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	var files []database.MediaFile
+	if relDir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", dir, err)
+		}
+		if file, ok := idx.createMediaFile(relDir, info); ok {
+			files = append(files, file)
+		}
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if strings.HasPrefix(entry.Name(), ".") || ignore.skips(path, entry.IsDir()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			logging.Debug("Failed to stat %s: %v", path, err)
+			continue
+		}
+		if file, ok := idx.createMediaFile(filepath.Join(relDir, entry.Name()), info); ok {
+			files = append(files, file)
+		}
+	}
+
+	return idx.processBatch(files)
+}
@@ -30,7 +30,8 @@
 //   - Newest modification timestamp against the last index time
 //
 // When changes are detected, a full re-index is triggered automatically.
-// The polling interval is configurable via [Indexer.SetPollInterval].
+// The polling interval is configurable via [Indexer.SetPollInterval]; both it and
+// the re-index interval ([Indexer.SetIndexInterval]) can be changed while running.
 //
 // # Parallel Processing
 //
@@ -42,15 +43,37 @@
 // # Cleanup
 //
 // Files that no longer exist on disk are automatically removed from the
-// index during each scan. Hidden files and directories (prefixed with '.')
+// index during each scan. A top-level folder that has disappeared since the
+// last scan is removed with everything in it before the walk starts, using
+// [database.Database.DeleteFilesByPrefix]. Hidden files and directories (prefixed with '.')
 // are excluded from indexing.
 //
+// # Ignoring Paths
+//
+// Paths matching the gitignore-style patterns of a .mediaignore file are
+// skipped too. One at the media root applies to the whole library and one in
+// a folder to what's below it, with deeper files overriding shallower ones.
+// [Indexer.SetIgnorePatterns] adds patterns relative to the media root that
+// override both. Hidden paths are skipped whatever the patterns say.
+//
+// # Sidecars
+//
+// With [Indexer.SetSidecarImport] enabled, the ratings, rejects and color
+// labels in the XMP sidecars of images are imported as tags after each batch
+// is committed, using [database.Database.SyncSidecarTags] so only what changed
+// in a sidecar since the last run is applied.
+//
 // # Integration
 //
 // The indexer can notify other components when indexing completes via
 // [Indexer.SetOnIndexComplete]. This is used to trigger incremental
 // thumbnail generation after the index is updated.
 //
+// [Indexer.SetOnProgress] reports progress during a run, every
+// [Indexer.SetProgressInterval] files and folders, with the folder being
+// scanned. Events are delivered on a separate goroutine and dropped while the
+// callback is busy, so a slow consumer never holds up the walk.
+//
 // # Example Usage
 //
 //	idx := indexer.New(db, "/media", 30*time.Minute)
@@ -0,0 +1,168 @@
+package indexer
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/filesystem"
+	"media-viewer/internal/logging"
+	"media-viewer/internal/metrics"
+)
+
+// hashBatchSize is the number of files hashed between database writes
+const hashBatchSize = 200
+
+// SetDuplicateHashing enables hashing the content of files that share their
+// size with another file after each index run, for finding duplicates. Only
+// such files are read, and each once until it changes, but the first run on
+// a large library can read a lot, so it's off by default.
+func (idx *Indexer) SetDuplicateHashing(enabled bool) {
+	idx.hashDuplicates.Store(enabled)
+}
+
+// SetHashOptions sets how duplicate candidates are read and hashed. Hashes
+// made with other options can't be compared, so the next run clears them
+// and hashes every candidate again.
+func (idx *Indexer) SetHashOptions(opts filesystem.HashOptions) {
+	idx.settingsMu.Lock()
+	defer idx.settingsMu.Unlock()
+	idx.hashOptions = opts
+}
+
+// getHashOptions returns the options duplicate candidates are hashed with
+func (idx *Indexer) getHashOptions() filesystem.HashOptions {
+	idx.settingsMu.RLock()
+	defer idx.settingsMu.RUnlock()
+	return idx.hashOptions
+}
+
+// syncHashOptions clears the recorded content hashes if they were made with
+// options other than opts, and records opts. Hashes recorded before the
+// options were first stored were made with the defaults.
+func (idx *Indexer) syncHashOptions(ctx context.Context, opts filesystem.HashOptions) error {
+	recorded, err := idx.db.GetContentHashOptions(ctx)
+	if err != nil {
+		return err
+	}
+	if recorded == "" {
+		recorded = filesystem.DefaultHashOptions().String()
+	}
+
+	if recorded != opts.String() {
+		cleared, err := idx.db.ClearContentHashes(ctx)
+		if err != nil {
+			return err
+		}
+		if cleared > 0 {
+			logging.Info("Hash options changed from %s to %s, cleared %d content hashes to compute again", recorded, opts, cleared)
+		}
+	}
+	return idx.db.SetContentHashOptions(ctx, opts.String())
+}
+
+// startDuplicateHashing hashes duplicate candidates in the background, so
+// the first pass over a large library doesn't hold up the index run. If the
+// last pass is still running, files indexed since are left to the next run.
+func (idx *Indexer) startDuplicateHashing() {
+	if !idx.hashDuplicates.Load() || !idx.hashing.CompareAndSwap(false, true) {
+		return
+	}
+
+	idx.hashWG.Add(1)
+	go func() {
+		defer idx.hashWG.Done()
+		defer idx.hashing.Store(false)
+		idx.hashDuplicateCandidates()
+	}()
+}
+
+// hashDuplicateCandidates records the content hash of every indexed file that
+// could have a duplicate and hasn't been hashed since it last changed, then
+// confirms the files whose content hash matches another's with a full hash.
+// Files that can't be read are skipped until the next run.
+func (idx *Indexer) hashDuplicateCandidates() {
+	ctx := context.Background()
+	opts := idx.getHashOptions()
+	if err := idx.syncHashOptions(ctx, opts); err != nil {
+		logging.Error("Failed to check the options of recorded content hashes: %v", err)
+		return
+	}
+
+	start := time.Now()
+	hashed, ok := idx.hashCandidates(ctx, idx.db.GetHashCandidates, idx.db.SetContentHashes, func(c database.HashCandidate) (string, error) {
+		return filesystem.HashContentWith(filepath.Join(idx.mediaDir, c.Path), opts)
+	})
+	if hashed > 0 {
+		logging.Info("Hashed %d files with matching sizes for duplicate detection in %v", hashed, time.Since(start).Round(time.Millisecond))
+	}
+	if !ok {
+		return
+	}
+
+	// Files read whole already have their full hash
+	start = time.Now()
+	full := filesystem.HashOptions{Mode: filesystem.HashFull, Algorithm: opts.Algorithm}
+	confirmed, _ := idx.hashCandidates(ctx, idx.db.GetConfirmCandidates, idx.db.SetFullHashes, func(c database.HashCandidate) (string, error) {
+		if opts.ReadsWhole(c.Size) {
+			return c.ContentHash, nil
+		}
+		return filesystem.HashContentWith(filepath.Join(idx.mediaDir, c.Path), full)
+	})
+	if confirmed > 0 {
+		logging.Info("Confirmed %d files with matching content hashes in %v", confirmed, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// hashCandidates hashes the files list returns with hashFile, in batches,
+// and records the hashes with set. It returns how many were hashed, and
+// false if it stopped early on Stop or an error.
+func (idx *Indexer) hashCandidates(
+	ctx context.Context,
+	list func(ctx context.Context, afterID int64, limit int) ([]database.HashCandidate, error),
+	set func(ctx context.Context, candidates []database.HashCandidate) error,
+	hashFile func(c database.HashCandidate) (string, error),
+) (int, bool) {
+	hashed := 0
+	for afterID := int64(0); ; {
+		candidates, err := list(ctx, afterID, hashBatchSize)
+		if err != nil {
+			logging.Error("Failed to list files to hash for duplicates: %v", err)
+			return hashed, false
+		}
+		if len(candidates) == 0 {
+			return hashed, true
+		}
+
+		// On Stop, the hashes made so far are still recorded
+		stopped := false
+		for i := 0; i < len(candidates) && !stopped; i++ {
+			select {
+			case <-idx.stopChan:
+				stopped = true
+				continue
+			default:
+			}
+
+			hashStart := time.Now()
+			hash, err := hashFile(candidates[i])
+			if err != nil {
+				logging.Debug("Failed to hash %s: %v", candidates[i].Path, err)
+				continue
+			}
+			metrics.FileHashComputeDuration.Observe(time.Since(hashStart).Seconds())
+			candidates[i].Hash = hash
+			hashed++
+		}
+
+		if err := set(ctx, candidates); err != nil {
+			logging.Error("Failed to record content hashes: %v", err)
+			return hashed, false
+		}
+		if stopped {
+			return hashed, false
+		}
+		afterID = candidates[len(candidates)-1].ID
+	}
+}
@@ -0,0 +1,116 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/filesystem"
+)
+
+func TestDuplicateHashingIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	mediaDir := t.TempDir()
+	write := func(path, content string, modTime time.Time) {
+		fullPath := filepath.Join(mediaDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		if err := os.Chtimes(fullPath, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+	}
+
+	past := time.Now().Add(-time.Hour)
+	write("2023/beach.jpg", "same bytes", past)
+	write("backup/beach copy.jpg", "same bytes", past)
+	write("2024/dunes.jpg", "diff bytes", past) // Same size, different content
+	write("2024/pier.jpg", "a file of its own size", past)
+
+	db, _, err := database.New(context.Background(), filepath.Join(t.TempDir(), "test.db"), &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	idx := New(db, mediaDir, time.Hour)
+	if err := idx.Index(); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+
+	ctx := context.Background()
+	if result, _ := db.GetDuplicates(ctx, database.DuplicateOptions{}); result.TotalGroups != 0 {
+		t.Errorf("Expected no duplicates with hashing disabled, got %+v", result.Groups)
+	}
+
+	idx.SetDuplicateHashing(true)
+	if err := idx.Index(); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	idx.hashWG.Wait()
+
+	result, err := db.GetDuplicates(ctx, database.DuplicateOptions{})
+	if err != nil {
+		t.Fatalf("GetDuplicates failed: %v", err)
+	}
+	if result.TotalGroups != 1 || len(result.Groups) != 1 || len(result.Groups[0].Files) != 2 {
+		t.Fatalf("Expected one group of two files, got %+v", result.Groups)
+	}
+	if files := result.Groups[0].Files; files[0].Path != "2023/beach.jpg" || files[1].Path != "backup/beach copy.jpg" {
+		t.Errorf("Expected the two beach files, got %+v", files)
+	}
+
+	// Only files sharing a size are hashed
+	candidates, err := db.GetHashCandidates(ctx, 0, 10)
+	if err != nil || len(candidates) != 0 {
+		t.Errorf("Expected every candidate to be hashed, got %+v (error: %v)", candidates, err)
+	}
+
+	// Changing the hash options hashes every candidate again with the new ones
+	idx.SetHashOptions(filesystem.HashOptions{Mode: filesystem.HashPartial, Algorithm: filesystem.HashXXHash})
+	if err := idx.Index(); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	idx.hashWG.Wait()
+	result, err = db.GetDuplicates(ctx, database.DuplicateOptions{})
+	if err != nil || len(result.Groups) != 1 {
+		t.Fatalf("Expected one group after changing hash options, got %+v (error: %v)", result.Groups, err)
+	}
+	if hash := result.Groups[0].Hash; len(hash) != 16 {
+		t.Errorf("Expected a 64-bit xxhash, got %q", hash)
+	}
+
+	// Files whose samples match but whose content differs between them are
+	// not duplicates once the full hash is compared
+	large := strings.Repeat("x", 3<<20)
+	write("video/a.mp4", large, past)
+	write("video/b.mp4", large[:len(large)/2]+"y"+large[len(large)/2+1:], past)
+	if err := idx.Index(); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	idx.hashWG.Wait()
+	result, err = db.GetDuplicates(ctx, database.DuplicateOptions{})
+	if err != nil || len(result.Groups) != 1 || result.Groups[0].Files[0].Path != "2023/beach.jpg" {
+		t.Fatalf("Expected only the beach files to be duplicates, got %+v (error: %v)", result.Groups, err)
+	}
+
+	// A changed file is hashed again
+	write("backup/beach copy.jpg", "edit bytes", time.Now())
+	if err := idx.Index(); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	idx.hashWG.Wait()
+	if result, _ := db.GetDuplicates(ctx, database.DuplicateOptions{}); result.TotalGroups != 0 {
+		t.Errorf("Expected no duplicates after the copy changed, got %+v", result.Groups)
+	}
+}
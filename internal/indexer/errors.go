@@ -0,0 +1,174 @@
+package indexer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"media-viewer/internal/logging"
+)
+
+// maxRecordedErrors bounds the per-file errors kept for a scan. Once full, the
+// oldest are dropped; ErrorReport.Total still counts them.
+const maxRecordedErrors = 500
+
+// FileError is a failure to index a single file or directory.
+type FileError struct {
+	Path  string    `json:"path"` // Relative to the media directory
+	Error string    `json:"error"`
+	Time  time.Time `json:"time"`
+}
+
+// ErrorReport lists the per-file errors of the running or most recent scan.
+type ErrorReport struct {
+	ScanStartedAt time.Time   `json:"scanStartedAt,omitempty"`
+	Total         int         `json:"total"` // Errors during the scan, including those no longer listed
+	Errors        []FileError `json:"errors"`
+
+	// Directories skipped because they couldn't be read for lack of
+	// permission; each is listed once in Errors
+	UnreadableDirs int `json:"unreadableDirs"`
+}
+
+// errorLog records the per-file errors of a scan
+type errorLog struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	total     int
+	errors    []FileError
+
+	// Directories a permission error was recorded for
+	unreadableDirs map[string]bool
+}
+
+// reset clears the log for a scan starting at startedAt
+func (l *errorLog) reset(startedAt time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.startedAt = startedAt
+	l.total = 0
+	l.errors = nil
+	l.unreadableDirs = nil
+}
+
+// record adds an error, dropping the oldest once the log is full
+func (l *errorLog) record(path string, err error) {
+	entry := FileError{Path: path, Error: describeFileError(err), Time: time.Now()}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.add(entry)
+}
+
+// recordUnreadableDir adds a permission error for dir, unless one was
+// already recorded for it. Returns whether it was new.
+func (l *errorLog) recordUnreadableDir(dir string, err error) bool {
+	entry := FileError{Path: dir, Error: describeFileError(err), Time: time.Now()}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.unreadableDirs[dir] {
+		return false
+	}
+	if l.unreadableDirs == nil {
+		l.unreadableDirs = make(map[string]bool)
+	}
+	l.unreadableDirs[dir] = true
+	l.add(entry)
+	return true
+}
+
+// add appends an entry, dropping the oldest once the log is full. The
+// caller holds l.mu.
+func (l *errorLog) add(entry FileError) {
+	l.total++
+	if len(l.errors) >= maxRecordedErrors {
+		l.errors = append(l.errors[:0], l.errors[1:]...)
+	}
+	l.errors = append(l.errors, entry)
+}
+
+// report returns a copy of the log, oldest error first
+func (l *errorLog) report() ErrorReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	errs := make([]FileError, len(l.errors))
+	copy(errs, l.errors)
+	return ErrorReport{ScanStartedAt: l.startedAt, Total: l.total, Errors: errs, UnreadableDirs: len(l.unreadableDirs)}
+}
+
+// unreadableDirCount returns how many directories permission errors were
+// recorded for
+func (l *errorLog) unreadableDirCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.unreadableDirs)
+}
+
+// describeFileError returns the message for an error. Filesystem errors are
+// reduced to the failed operation and its cause, since they repeat the
+// absolute path the entry already identifies.
+func describeFileError(err error) string {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Op + ": " + pathErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// relativeErrorPath converts a path from a directory walk to one relative to
+// mediaDir, as stored in the index
+func relativeErrorPath(mediaDir, path string) string {
+	if rel, err := filepath.Rel(mediaDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// recordError adds a per-file error to the current scan's error list
+func (idx *Indexer) recordError(path string, err error) {
+	idx.fileErrors.record(relativeErrorPath(idx.mediaDir, path), err)
+}
+
+// handleWalkError records an error reported by the directory walk for path.
+// A permission error stands for the directory that couldn't be read: the
+// directory itself, or the parent of an entry that couldn't be accessed. That
+// directory is skipped and logged once per scan however many of its entries
+// fail, unless SetStrictPermissions made permission errors fatal, in which
+// case the error to abort the walk with is returned.
+func (idx *Indexer) handleWalkError(path string, isDir bool, err error) error {
+	if !errors.Is(err, fs.ErrPermission) {
+		logging.WarnSampled("indexer:walk", "Error accessing path %s: %v", path, err)
+		idx.recordError(path, err)
+		return nil
+	}
+
+	dir := path
+	if !isDir {
+		dir = filepath.Dir(path)
+	}
+	dir = relativeErrorPath(idx.mediaDir, dir)
+
+	isNew := idx.fileErrors.recordUnreadableDir(dir, err)
+	if idx.strictPermissions.Load() {
+		return fmt.Errorf("permission denied reading %s: %w", dir, err)
+	}
+	if isNew {
+		logging.Warn("Skipping directory %s, which can't be read: %v", dir, err)
+	}
+	return nil
+}
+
+// Errors returns the per-file errors recorded during the running or most
+// recent scan. The list is cleared when a scan starts.
+func (idx *Indexer) Errors() ErrorReport {
+	return idx.fileErrors.report()
+}
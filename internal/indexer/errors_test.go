@@ -0,0 +1,142 @@
+package indexer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestErrorLogRecordsAndResets(t *testing.T) {
+	idx := New(nil, "/media", time.Hour)
+
+	started := time.Now()
+	idx.resetCounters(started)
+	idx.recordError("/media/photos/locked", &fs.PathError{Op: "open", Path: "/media/photos/locked", Err: syscall.EACCES})
+	idx.fileErrors.record("videos/clip.mp4", errors.New("database is locked"))
+
+	report := idx.Errors()
+	if !report.ScanStartedAt.Equal(started) || report.Total != 2 || len(report.Errors) != 2 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if got := report.Errors[0]; got.Path != "photos/locked" || got.Error != "open: permission denied" || got.Time.IsZero() {
+		t.Errorf("Unexpected walk error entry: %+v", got)
+	}
+	if got := report.Errors[1]; got.Path != "videos/clip.mp4" || got.Error != "database is locked" {
+		t.Errorf("Unexpected upsert error entry: %+v", got)
+	}
+
+	// The report is a copy
+	report.Errors[0].Path = "changed"
+	if idx.Errors().Errors[0].Path != "photos/locked" {
+		t.Error("Expected the report not to share the recorded errors")
+	}
+
+	// A new scan starts with an empty list
+	idx.resetCounters(started.Add(time.Minute))
+	if report := idx.Errors(); report.Total != 0 || len(report.Errors) != 0 {
+		t.Errorf("Expected errors cleared at the start of a scan, got %+v", report)
+	}
+}
+
+func TestErrorLogBounded(t *testing.T) {
+	var log errorLog
+	for i := range maxRecordedErrors + 10 {
+		log.record(fmt.Sprintf("file%d.jpg", i), errors.New("failed"))
+	}
+
+	report := log.report()
+	if report.Total != maxRecordedErrors+10 {
+		t.Errorf("Total = %d, want %d", report.Total, maxRecordedErrors+10)
+	}
+	if len(report.Errors) != maxRecordedErrors {
+		t.Fatalf("Expected %d listed errors, got %d", maxRecordedErrors, len(report.Errors))
+	}
+	if first, last := report.Errors[0].Path, report.Errors[len(report.Errors)-1].Path; first != "file10.jpg" || last != fmt.Sprintf("file%d.jpg", maxRecordedErrors+9) {
+		t.Errorf("Expected the oldest errors dropped, got %s ... %s", first, last)
+	}
+}
+
+func TestRelativeErrorPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/media/a/b.jpg", "a/b.jpg"},
+		{"/media", "."},
+		{"/elsewhere/c.jpg", "/elsewhere/c.jpg"},
+	}
+	for _, tt := range tests {
+		if got := relativeErrorPath("/media", tt.path); got != tt.expected {
+			t.Errorf("relativeErrorPath(%q) = %q, want %q", tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestParallelWalkerReportsErrors(t *testing.T) {
+	var reported []string
+	pw := NewParallelWalker(t.TempDir(), DefaultParallelWalkerConfig())
+	abort := errors.New("abort")
+	pw.onError = func(path string, _ bool, _ error) error {
+		reported = append(reported, path)
+		return abort
+	}
+
+	if err := pw.reportError("/media/x", false, errors.New("boom")); !errors.Is(err, abort) {
+		t.Errorf("Expected the callback's error returned, got %v", err)
+	}
+	if len(reported) != 1 || reported[0] != "/media/x" {
+		t.Errorf("Expected the error passed to onError, got %v", reported)
+	}
+
+	// Without a callback errors are only logged
+	if err := NewParallelWalker(t.TempDir(), DefaultParallelWalkerConfig()).reportError("/media/x", false, errors.New("boom")); err != nil {
+		t.Errorf("Expected the walk to continue without a callback, got %v", err)
+	}
+}
+
+func TestHandleWalkErrorPermissionDenied(t *testing.T) {
+	idx := New(nil, "/media", time.Hour)
+	idx.resetCounters(time.Now())
+
+	denied := func(path string) error {
+		return &fs.PathError{Op: "lstat", Path: path, Err: syscall.EACCES}
+	}
+
+	// Entries of a directory that can't be searched are one unreadable
+	// directory, recorded once
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		if err := idx.handleWalkError("/media/shared/private/"+name, false, denied(name)); err != nil {
+			t.Fatalf("Expected the walk to continue, got %v", err)
+		}
+	}
+	if err := idx.handleWalkError("/media/shared/locked", true, denied("locked")); err != nil {
+		t.Fatalf("Expected the walk to continue, got %v", err)
+	}
+	if err := idx.handleWalkError("/media/gone.jpg", false, &fs.PathError{Op: "lstat", Path: "gone.jpg", Err: syscall.ENOENT}); err != nil {
+		t.Fatalf("Expected the walk to continue, got %v", err)
+	}
+
+	report := idx.Errors()
+	if report.UnreadableDirs != 2 || report.Total != 3 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if report.Errors[0].Path != "shared/private" || report.Errors[1].Path != "shared/locked" || report.Errors[2].Path != "gone.jpg" {
+		t.Errorf("Unexpected errors: %+v", report.Errors)
+	}
+
+	idx.SetStrictPermissions(true)
+	if err := idx.handleWalkError("/media/other", true, denied("other")); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Expected a permission error to abort the walk in strict mode, got %v", err)
+	}
+	if err := idx.handleWalkError("/media/gone2.jpg", false, &fs.PathError{Op: "lstat", Path: "gone2.jpg", Err: syscall.ENOENT}); err != nil {
+		t.Errorf("Expected other errors to stay non-fatal in strict mode, got %v", err)
+	}
+
+	idx.resetCounters(time.Now())
+	if report := idx.Errors(); report.UnreadableDirs != 0 {
+		t.Errorf("Expected unreadable directories cleared at the start of a scan, got %+v", report)
+	}
+}
@@ -0,0 +1,216 @@
+package indexer
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"media-viewer/internal/logging"
+)
+
+// ignoreFileName is the file listing patterns of paths the indexer skips.
+// One at the media root applies to the whole library; one in a folder
+// applies below that folder.
+const ignoreFileName = ".mediaignore"
+
+// ignoreRule is one pattern of a .mediaignore file or SetIgnorePatterns,
+// with gitignore semantics
+type ignoreRule struct {
+	// base is the folder the pattern is relative to, "" for the media root
+	base string
+	// segments are the pattern's path segments; "**" matches any number
+	segments []string
+	// anchored patterns contain a slash and match from base; others match
+	// a name at any depth below it
+	anchored bool
+	// dirOnly patterns end in a slash and only match folders
+	dirOnly bool
+	// negate patterns start with "!" and re-include what earlier ones skip
+	negate bool
+}
+
+// parseIgnoreRule parses one line of a .mediaignore file. ok is false for
+// blank lines and comments.
+func parseIgnoreRule(line, base string) (rule ignoreRule, ok bool, err error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false, nil
+	}
+
+	rule.base = base
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	rule.anchored = strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return rule, false, errors.New("empty pattern")
+	}
+
+	rule.segments = strings.Split(line, "/")
+	for _, segment := range rule.segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return rule, false, err
+		}
+	}
+	return rule, true, nil
+}
+
+// matches reports whether the rule matches rel, a slash-separated path
+// relative to the media root
+func (r ignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+
+	if !r.anchored {
+		matched, _ := path.Match(r.segments[0], path.Base(rel))
+		return matched
+	}
+	return matchSegments(r.segments, strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments against pattern segments, where "**"
+// matches any number of segments, or at least one at the end of a pattern
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		if len(pattern) == 1 {
+			return len(segments) > 0
+		}
+		for i := range len(segments) + 1 {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	matched, _ := path.Match(pattern[0], segments[0])
+	return matched && matchSegments(pattern[1:], segments[1:])
+}
+
+// parseIgnorePatterns parses patterns relative to base, logging and
+// dropping invalid ones. source names where they came from, for the log.
+func parseIgnorePatterns(patterns []string, base, source string) []ignoreRule {
+	var rules []ignoreRule
+	for _, pattern := range patterns {
+		rule, ok, err := parseIgnoreRule(pattern, base)
+		if err != nil {
+			logging.Warn("Ignoring invalid pattern %q in %s: %v", pattern, source, err)
+			continue
+		}
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// ignoreMatcher decides which paths a walk skips. It reads the .mediaignore
+// file of each folder as the walk enters it, so it's used by one walk at a
+// time and made afresh for each, picking up edited files.
+type ignoreMatcher struct {
+	root string
+
+	// folders maps folders with a .mediaignore file, relative to root, to
+	// its rules
+	folders map[string][]ignoreRule
+
+	// explicit are the rules from SetIgnorePatterns, applied after the files
+	explicit []ignoreRule
+}
+
+// newIgnoreMatcher returns a matcher for a walk of root, with the rules of
+// the .mediaignore file at root already read
+func newIgnoreMatcher(root string, explicit []ignoreRule) *ignoreMatcher {
+	m := &ignoreMatcher{
+		root:     root,
+		folders:  make(map[string][]ignoreRule),
+		explicit: explicit,
+	}
+	m.load("")
+	return m
+}
+
+// skips reports whether the walk should skip the file or folder at path.
+// For a folder it doesn't skip, it reads the folder's .mediaignore file, so
+// it must be called for a folder before anything in it. A nil matcher skips
+// nothing.
+func (m *ignoreMatcher) skips(path string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	if m.ignored(rel, isDir) {
+		return true
+	}
+	if isDir {
+		m.load(rel)
+	}
+	return false
+}
+
+// ignored applies the rules of rel's ancestors from the root down, then the
+// explicit ones. As in gitignore, the last matching rule decides, so deeper
+// files override shallower ones and explicit patterns override them all.
+func (m *ignoreMatcher) ignored(rel string, isDir bool) bool {
+	ignored := false
+	apply := func(rules []ignoreRule) {
+		for _, rule := range rules {
+			if rule.matches(rel, isDir) {
+				ignored = !rule.negate
+			}
+		}
+	}
+
+	apply(m.folders[""])
+	for i := range len(rel) {
+		if rel[i] == '/' {
+			apply(m.folders[rel[:i]])
+		}
+	}
+	apply(m.explicit)
+	return ignored
+}
+
+// load reads the .mediaignore file of the folder rel, if it has one
+func (m *ignoreMatcher) load(rel string) {
+	file := filepath.Join(m.root, filepath.FromSlash(rel), ignoreFileName)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logging.Warn("Failed to read %s: %v", file, err)
+		}
+		return
+	}
+
+	if rules := parseIgnorePatterns(strings.Split(string(data), "\n"), rel, file); len(rules) > 0 {
+		m.folders[rel] = rules
+	}
+}
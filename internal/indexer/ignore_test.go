@@ -0,0 +1,128 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestIgnoreRuleMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		base    string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.tmp", "", "a/b/c.tmp", false, true},
+		{"*.tmp", "", "a/b/c.jpg", false, false},
+		{"scratch/", "", "photos/scratch", true, true},
+		{"scratch/", "", "photos/scratch", false, false},
+		{"/scratch", "", "scratch", true, true},
+		{"/scratch", "", "photos/scratch", true, false},
+		{"photos/*.raw", "", "photos/a.raw", false, true},
+		{"photos/*.raw", "", "photos/2024/a.raw", false, false},
+		{"photos/**/*.raw", "", "photos/2024/06/a.raw", false, true},
+		{"photos/**/*.raw", "", "photos/a.raw", false, true},
+		{"**/cache", "", "x/y/cache", true, true},
+		{"exports/**", "", "exports", true, false},
+		{"exports/**", "", "exports/a.jpg", false, true},
+		{"draft?.jpg", "", "draft1.jpg", false, true},
+		{"*.jpg", "trips", "trips/a.jpg", false, true},
+		{"*.jpg", "trips", "other/a.jpg", false, false},
+		{"/a.jpg", "trips", "trips/a.jpg", false, true},
+		{"/a.jpg", "trips", "trips/x/a.jpg", false, false},
+	}
+
+	for _, tt := range tests {
+		rule, ok, err := parseIgnoreRule(tt.pattern, tt.base)
+		if err != nil || !ok {
+			t.Fatalf("parseIgnoreRule(%q) = %v, %v", tt.pattern, ok, err)
+		}
+		if got := rule.matches(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%q (in %q) matching %q: got %v, want %v", tt.pattern, tt.base, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseIgnoreRule(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment"} {
+		if _, ok, err := parseIgnoreRule(line, ""); ok || err != nil {
+			t.Errorf("parseIgnoreRule(%q) = %v, %v; want skipped", line, ok, err)
+		}
+	}
+	for _, line := range []string{"[", "/", "!"} {
+		if _, _, err := parseIgnoreRule(line, ""); err == nil {
+			t.Errorf("parseIgnoreRule(%q): expected an error", line)
+		}
+	}
+
+	rule, ok, _ := parseIgnoreRule(`\#1.jpg`, "")
+	if !ok || rule.negate || !rule.matches("#1.jpg", false) {
+		t.Errorf(`Expected \# to escape a literal #, got %+v`, rule)
+	}
+	rule, _, _ = parseIgnoreRule("!keep.tmp", "")
+	if !rule.negate || !rule.matches("keep.tmp", false) {
+		t.Errorf("Expected a negated rule, got %+v", rule)
+	}
+}
+
+func TestIgnoreMatcherWalk(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, path := range []string{
+		"a.jpg", "b_draft.jpg", "keep_draft.jpg",
+		"scratch/x.jpg",
+		"trips/c.jpg", "trips/d.jpg", "trips/raw/e.jpg",
+		"exports/f.jpg",
+	} {
+		full := filepath.Join(tempDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("Failed to create folder: %v", err)
+		}
+		if err := os.WriteFile(full, []byte("data"), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	writeIgnore := func(folder, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(tempDir, folder, ignoreFileName), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", ignoreFileName, err)
+		}
+	}
+	writeIgnore("", "# scratch space\nscratch/\n*_draft.jpg\n!keep_draft.jpg\n")
+	// A folder's own file applies below it only, and after the root's
+	writeIgnore("trips", "d.jpg\nraw/\n")
+
+	idx := New(&database.Database{}, tempDir, 5*time.Minute)
+	idx.SetIgnorePatterns([]string{"exports", "!trips/raw", "[invalid"})
+
+	walker := NewParallelWalker(tempDir, DefaultParallelWalkerConfig())
+	walker.ignore = idx.newIgnoreMatcher()
+	files, err := walker.Walk()
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, filepath.ToSlash(file.Path))
+	}
+	sort.Strings(paths)
+
+	// trips/raw is skipped by trips/.mediaignore but re-included by the
+	// explicit patterns, which are applied last
+	want := []string{"a.jpg", "keep_draft.jpg", "trips", "trips/c.jpg", "trips/raw", "trips/raw/e.jpg"}
+	if len(paths) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, paths)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, paths)
+			break
+		}
+	}
+}
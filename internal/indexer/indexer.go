@@ -8,15 +8,19 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"media-viewer/internal/database"
+	"media-viewer/internal/exif"
+	"media-viewer/internal/filesystem"
 	"media-viewer/internal/logging"
 	"media-viewer/internal/mediatypes"
 	"media-viewer/internal/metrics"
+	"media-viewer/internal/xmp"
 )
 
 const (
@@ -31,6 +35,9 @@ const (
 
 	// Default polling interval for change detection
 	defaultPollInterval = 30 * time.Second
+
+	// Default time after an index run before a coalesced re-index starts
+	defaultQuietPeriod = 10 * time.Second
 )
 
 // Indexer manages the indexing of media files in the media directory.
@@ -47,6 +54,18 @@ type Indexer struct {
 	initialIndexError    error
 	startTime            time.Time
 
+	// Guarded by indexMu: whether coalesced re-index requests are waiting for
+	// a follow-up run, and when the last run finished
+	reindexPending bool
+	lastFinishTime time.Time
+
+	// Guards intervals, quietPeriod, parallelConfig, ignoreRules and
+	// hashOptions, which can change at runtime
+	settingsMu  sync.RWMutex
+	pollReset   chan struct{}
+	indexReset  chan struct{}
+	quietPeriod time.Duration
+
 	// Progress tracking
 	filesIndexed   atomic.Int64
 	foldersIndexed atomic.Int64
@@ -56,9 +75,39 @@ type Indexer struct {
 	parallelConfig ParallelWalkerConfig
 	useParallel    bool
 
+	// Patterns from SetIgnorePatterns, applied after .mediaignore files
+	ignoreRules []ignoreRule
+
+	// Capture file creation times for SortByCreated
+	captureBirthTime atomic.Bool
+	captureCamera    atomic.Bool
+	captureExifDate  atomic.Bool
+
+	// Import ratings and color labels from XMP sidecars as tags
+	importSidecars atomic.Bool
+
+	// Hash files that may have duplicates after each run, with hashOptions,
+	// in the background; hashing is set while a pass runs
+	hashDuplicates atomic.Bool
+	hashOptions    filesystem.HashOptions
+	hashing        atomic.Bool
+	hashWG         sync.WaitGroup
+
+	// Per-file errors of the running or last scan
+	fileErrors errorLog
+
+	// Abort the walk on a directory that can't be read for lack of permission
+	strictPermissions atomic.Bool
+
 	// Callback when indexing completes
 	onIndexComplete func()
 
+	// Progress events: the callback, how many entries pass between events,
+	// and the reporter of the running index
+	onProgress       func(ProgressEvent)
+	progressInterval atomic.Int64
+	progress         *progressReporter
+
 	// Last known state for lightweight change detection
 	stateMu            sync.RWMutex
 	lastRootModTime    time.Time
@@ -81,20 +130,69 @@ func New(db *database.Database, mediaDir string, indexInterval time.Duration) *I
 		mediaDir:           mediaDir,
 		indexInterval:      indexInterval,
 		pollInterval:       defaultPollInterval,
+		quietPeriod:        defaultQuietPeriod,
 		stopChan:           make(chan struct{}),
+		pollReset:          make(chan struct{}, 1),
+		indexReset:         make(chan struct{}, 1),
 		startTime:          time.Now(),
 		parallelConfig:     DefaultParallelWalkerConfig(),
 		useParallel:        true,
+		hashOptions:        filesystem.DefaultHashOptions(),
 		lastSubdirModTimes: make(map[string]time.Time),
 	}
 	idx.indexProgress.Store(IndexProgress{})
+	idx.progressInterval.Store(DefaultProgressInterval)
 	return idx
 }
 
 // SetPollInterval sets the interval for polling-based change detection.
+// If polling is already running, the new interval takes effect immediately.
 func (idx *Indexer) SetPollInterval(interval time.Duration) {
-	if interval > 0 {
-		idx.pollInterval = interval
+	if interval <= 0 {
+		return
+	}
+
+	idx.settingsMu.Lock()
+	idx.pollInterval = interval
+	idx.settingsMu.Unlock()
+
+	signalReset(idx.pollReset)
+}
+
+// SetIndexInterval sets the interval for periodic full re-indexing.
+// If the periodic loop is already running, the new interval takes effect immediately.
+func (idx *Indexer) SetIndexInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	idx.settingsMu.Lock()
+	idx.indexInterval = interval
+	idx.settingsMu.Unlock()
+
+	signalReset(idx.indexReset)
+}
+
+// getPollInterval returns the current polling interval.
+func (idx *Indexer) getPollInterval() time.Duration {
+	idx.settingsMu.RLock()
+	defer idx.settingsMu.RUnlock()
+	return idx.pollInterval
+}
+
+// getIndexInterval returns the current periodic re-index interval.
+func (idx *Indexer) getIndexInterval() time.Duration {
+	idx.settingsMu.RLock()
+	defer idx.settingsMu.RUnlock()
+	return idx.indexInterval
+}
+
+// signalReset notifies a ticker loop that its interval changed without blocking.
+func signalReset(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+		// A reset is already pending
 	}
 }
 
@@ -104,10 +202,86 @@ func (idx *Indexer) SetParallelWalking(enabled bool) {
 }
 
 // SetParallelConfig sets the parallel walker configuration.
+// A change made while indexing applies from the next index run.
 func (idx *Indexer) SetParallelConfig(config ParallelWalkerConfig) {
+	idx.settingsMu.Lock()
+	defer idx.settingsMu.Unlock()
 	idx.parallelConfig = config
 }
 
+// Workers returns the number of parallel directory walkers the next index
+// run uses.
+func (idx *Indexer) Workers() int {
+	return idx.getParallelConfig().NumWorkers
+}
+
+// getParallelConfig returns a copy of the current parallel walker configuration.
+func (idx *Indexer) getParallelConfig() ParallelWalkerConfig {
+	idx.settingsMu.RLock()
+	defer idx.settingsMu.RUnlock()
+	return idx.parallelConfig
+}
+
+// SetIgnorePatterns sets gitignore-style patterns of paths to skip, matched
+// against paths relative to the media directory. They're applied after the
+// patterns of .mediaignore files, so they override them; hidden files are
+// skipped regardless. Invalid patterns are logged and dropped. A change made
+// while indexing applies from the next run.
+func (idx *Indexer) SetIgnorePatterns(patterns []string) {
+	rules := parseIgnorePatterns(patterns, "", "ignore patterns")
+	idx.settingsMu.Lock()
+	defer idx.settingsMu.Unlock()
+	idx.ignoreRules = rules
+}
+
+// newIgnoreMatcher returns the ignore matcher for a walk of the media directory
+func (idx *Indexer) newIgnoreMatcher() *ignoreMatcher {
+	idx.settingsMu.RLock()
+	defer idx.settingsMu.RUnlock()
+	return newIgnoreMatcher(idx.mediaDir, idx.ignoreRules)
+}
+
+// SetBirthTimeIndexing enables recording each file's creation time where the
+// filesystem provides one. It costs an extra stat per file, so it's off by
+// default. A change made while indexing applies from the next batch.
+func (idx *Indexer) SetBirthTimeIndexing(enabled bool) {
+	idx.captureBirthTime.Store(enabled)
+}
+
+// SetCameraIndexing enables recording the camera and lens of each image from
+// its EXIF data, for browsing by them. It reads the header of every image on
+// every run, so it's off by default. A change made while indexing applies
+// from the next batch.
+func (idx *Indexer) SetCameraIndexing(enabled bool) {
+	idx.captureCamera.Store(enabled)
+}
+
+// SetExifDateIndexing enables recording when each image was taken from its
+// EXIF data, for SortByCaptured. Like camera indexing it reads image headers
+// on every run, so it's off by default; with both enabled each header is
+// read once. A change made while indexing applies from the next batch.
+func (idx *Indexer) SetExifDateIndexing(enabled bool) {
+	idx.captureExifDate.Store(enabled)
+}
+
+// SetSidecarImport enables importing the star ratings, rejects and color
+// labels that Lightroom and darktable write to XMP sidecars, as tags such as
+// "rating:4", "rejected" and "label:Red". Sidecars are read on every run and
+// the tags kept in step with them, so it's off by default. A change made
+// while indexing applies from the next batch.
+func (idx *Indexer) SetSidecarImport(enabled bool) {
+	idx.importSidecars.Store(enabled)
+}
+
+// SetStrictPermissions makes a directory the indexer isn't permitted to read
+// fail the whole index run, instead of being skipped while the rest of the
+// media directory is indexed. A failed run removes nothing from the index,
+// so files in such a directory aren't dropped from it. A change made while
+// indexing applies from the next run.
+func (idx *Indexer) SetStrictPermissions(enabled bool) {
+	idx.strictPermissions.Store(enabled)
+}
+
 // SetOnIndexComplete sets a callback to be invoked when indexing completes.
 func (idx *Indexer) SetOnIndexComplete(callback func()) {
 	idx.onIndexComplete = callback
@@ -135,9 +309,11 @@ func (idx *Indexer) Start() error {
 	return nil
 }
 
-// Stop stops the indexing process.
+// Stop stops the indexing process, and waits for background duplicate
+// hashing to finish the file it is reading.
 func (idx *Indexer) Stop() {
 	close(idx.stopChan)
+	idx.hashWG.Wait()
 }
 
 // IsReady returns true if the server is ready to accept traffic.
@@ -174,6 +350,7 @@ func (idx *Indexer) GetHealthStatus() HealthStatus {
 		LastIndexed:    idx.lastIndexTime,
 		FilesIndexed:   idx.filesIndexed.Load(),
 		FoldersIndexed: idx.foldersIndexed.Load(),
+		UnreadableDirs: idx.fileErrors.unreadableDirCount(),
 	}
 
 	if idx.isIndexing {
@@ -197,6 +374,7 @@ type HealthStatus struct {
 	InitialIndexError string         `json:"initialIndexError,omitempty"`
 	FilesIndexed      int64          `json:"filesIndexed"`
 	FoldersIndexed    int64          `json:"foldersIndexed"`
+	UnreadableDirs    int            `json:"unreadableDirs,omitempty"` // Skipped by the running or last scan
 	IndexProgress     *IndexProgress `json:"indexProgress,omitempty"`
 }
 
@@ -211,24 +389,31 @@ func (idx *Indexer) pollForChanges() {
 		}
 	}
 
-	logging.Info("Starting change detection polling (interval: %v)", idx.pollInterval)
+	pollInterval := idx.getPollInterval()
+	logging.Info("Starting change detection polling (interval: %v)", pollInterval)
 
-	ticker := time.NewTicker(idx.pollInterval)
+	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
+		case <-idx.pollReset:
+			pollInterval = idx.getPollInterval()
+			ticker.Reset(pollInterval)
+			logging.Info("Change detection polling interval changed to %v", pollInterval)
 		case <-ticker.C:
+			// The known state is updated when a run finishes, so until then
+			// every check would report the changes the run is indexing
+			if idx.IsIndexing() {
+				continue
+			}
 			changed, err := idx.detectChanges()
 			if err != nil {
 				logging.Error("Error detecting changes: %v", err)
 				continue
 			}
 			if changed {
-				logging.Info("File changes detected, triggering re-index")
-				if err := idx.Index(); err != nil {
-					logging.Error("Re-index after change detection failed: %v", err)
-				}
+				logging.Info("File changes detected, re-index %s", idx.TriggerIndex())
 			}
 		case <-idx.stopChan:
 			logging.Info("Change detection polling stopped")
@@ -381,6 +566,12 @@ func (idx *Indexer) Index() error {
 		logging.Info("Index already in progress, skipping...")
 		return nil
 	}
+	return idx.runIndex()
+}
+
+// runIndex performs a full index, once the caller has marked indexing as
+// started.
+func (idx *Indexer) runIndex() error {
 	defer idx.finishIndexing()
 
 	metrics.IndexerIsRunning.Set(1)
@@ -392,6 +583,9 @@ func (idx *Indexer) Index() error {
 
 	idx.resetCounters(startTime)
 
+	idx.progress = idx.startProgress(startTime)
+	defer idx.progress.stop()
+
 	// Start heartbeat to show progress on slow filesystems
 	heartbeatDone := make(chan struct{})
 	go func() {
@@ -414,6 +608,10 @@ func (idx *Indexer) Index() error {
 
 	indexTime := time.Now()
 
+	// Drop folders that are gone before the walk, rather than leaving their
+	// files in listings until the cleanup after it
+	idx.removeVanishedFolders()
+
 	var result indexResult
 	var err error
 
@@ -439,6 +637,8 @@ func (idx *Indexer) Index() error {
 	// Update last known state for change detection
 	idx.updateLastKnownState()
 
+	idx.startDuplicateHashing()
+
 	// Update metrics
 	duration := time.Since(startTime)
 	metrics.IndexerLastRunTimestamp.Set(float64(time.Now().Unix()))
@@ -458,9 +658,18 @@ func (idx *Indexer) Index() error {
 
 // parallelWalkAndIndex uses parallel directory walking for faster indexing.
 func (idx *Indexer) parallelWalkAndIndex(startTime time.Time) (indexResult, error) {
-	logging.Info("Using parallel directory walking with %d workers", idx.parallelConfig.NumWorkers)
-	metrics.IndexerParallelWorkers.Set(float64(idx.parallelConfig.NumWorkers))
-	walker := NewParallelWalker(idx.mediaDir, idx.parallelConfig)
+	config := idx.getParallelConfig()
+	logging.Info("Using parallel directory walking with %d workers", config.NumWorkers)
+	metrics.IndexerParallelWorkers.Set(float64(config.NumWorkers))
+	walker := NewParallelWalker(idx.mediaDir, config)
+	walker.ignore = idx.newIgnoreMatcher()
+	walker.onError = idx.handleWalkError
+	walker.onWalked = func(walked int64, folder string) {
+		if idx.progress.due(walked) {
+			files, folders, _ := walker.Stats()
+			idx.progress.report(ProgressStageScan, files, folders, folder)
+		}
+	}
 
 	defer walker.Stop()
 
@@ -483,7 +692,7 @@ func (idx *Indexer) parallelWalkAndIndex(startTime time.Time) (indexResult, erro
 	idx.foldersIndexed.Store(totalFolders)
 	idx.updateProgress(startTime)
 
-	if err := idx.processBatchedFiles(files, startTime); err != nil {
+	if err := idx.processBatchedFiles(files, config.BatchSize, startTime); err != nil {
 		return indexResult{}, err
 	}
 
@@ -494,18 +703,20 @@ func (idx *Indexer) parallelWalkAndIndex(startTime time.Time) (indexResult, erro
 }
 
 // processBatchedFiles inserts files into the database in batches.
-func (idx *Indexer) processBatchedFiles(files []database.MediaFile, startTime time.Time) error {
+func (idx *Indexer) processBatchedFiles(files []database.MediaFile, batchSize int, startTime time.Time) error {
 	totalFiles := len(files)
-	logging.Info("Processing %d files in batches of %d", totalFiles, idx.parallelConfig.BatchSize)
+	logging.Info("Processing %d files in batches of %d", totalFiles, batchSize)
+
+	var storedFiles, storedFolders int64
 
-	for i := 0; i < totalFiles; i += idx.parallelConfig.BatchSize {
+	for i := 0; i < totalFiles; i += batchSize {
 		select {
 		case <-idx.stopChan:
 			return fs.SkipAll
 		default:
 		}
 
-		end := i + idx.parallelConfig.BatchSize
+		end := i + batchSize
 		if end > totalFiles {
 			end = totalFiles
 		}
@@ -516,11 +727,23 @@ func (idx *Indexer) processBatchedFiles(files []database.MediaFile, startTime ti
 			logging.Error("Error processing batch: %v", err)
 		}
 
+		for _, file := range batch {
+			if file.Type == database.FileTypeFolder {
+				storedFolders++
+			} else {
+				storedFiles++
+			}
+		}
+		// Report once for each interval boundary the batch crossed
+		if idx.progress != nil && int64(end)/idx.progress.interval > int64(i)/idx.progress.interval {
+			idx.progress.report(ProgressStageStore, storedFiles, storedFolders, batch[len(batch)-1].ParentPath)
+		}
+
 		idx.updateProgress(startTime)
 
 		time.Sleep(batchDelay)
 
-		if (i+idx.parallelConfig.BatchSize)%5000 == 0 || end == totalFiles {
+		if (i+batchSize)%5000 == 0 || end == totalFiles {
 			logging.Info("Database insert progress: %d/%d files", end, totalFiles)
 		}
 	}
@@ -537,22 +760,30 @@ func (idx *Indexer) tryStartIndexing() bool {
 		return false
 	}
 	idx.isIndexing = true
+	// This run sees every change requested so far
+	idx.reindexPending = false
 	return true
 }
 
-// finishIndexing marks indexing as complete.
+// finishIndexing marks indexing as complete, scheduling the follow-up run
+// if re-indexes were requested while it ran.
 func (idx *Indexer) finishIndexing() {
 	idx.indexMu.Lock()
 	defer idx.indexMu.Unlock()
 
 	idx.isIndexing = false
 	idx.initialIndexComplete = true
+	idx.lastFinishTime = time.Now()
+	if idx.reindexPending {
+		time.AfterFunc(idx.getQuietPeriod(), idx.runPendingIndex)
+	}
 }
 
 // resetCounters resets the indexing counters.
 func (idx *Indexer) resetCounters(startTime time.Time) {
 	idx.filesIndexed.Store(0)
 	idx.foldersIndexed.Store(0)
+	idx.fileErrors.reset(startTime)
 	idx.indexProgress.Store(IndexProgress{
 		IsIndexing: true,
 		StartedAt:  startTime,
@@ -571,9 +802,10 @@ func (idx *Indexer) walkAndIndex(startTime time.Time) (indexResult, error) {
 
 	var currentBatch []database.MediaFile
 	var result indexResult
+	ignore := idx.newIgnoreMatcher()
 
 	err := filepath.Walk(idx.mediaDir, func(path string, info os.FileInfo, err error) error {
-		return idx.processPath(path, info, err, &currentBatch, &result, startTime)
+		return idx.processPath(path, info, err, ignore, &currentBatch, &result, startTime)
 	})
 
 	if err != nil && !errors.Is(err, fs.SkipAll) {
@@ -595,6 +827,7 @@ func (idx *Indexer) processPath(
 	path string,
 	info os.FileInfo,
 	err error,
+	ignore *ignoreMatcher,
 	currentBatch *[]database.MediaFile,
 	result *indexResult,
 	startTime time.Time,
@@ -606,11 +839,10 @@ func (idx *Indexer) processPath(
 	}
 
 	if err != nil {
-		logging.Warn("Error accessing path %s: %v", path, err)
-		return nil
+		return idx.handleWalkError(path, info != nil && info.IsDir(), err)
 	}
 
-	if strings.HasPrefix(info.Name(), ".") {
+	if strings.HasPrefix(info.Name(), ".") || ignore.skips(path, info.IsDir()) {
 		if info.IsDir() {
 			return filepath.SkipDir
 		}
@@ -639,6 +871,10 @@ func (idx *Indexer) processPath(
 		idx.filesIndexed.Add(1)
 	}
 
+	if total := result.totalFiles + result.totalFolders; idx.progress.due(total) {
+		idx.progress.report(ProgressStageScan, result.totalFiles, result.totalFolders, file.ParentPath)
+	}
+
 	*currentBatch = append(*currentBatch, file)
 
 	if len(*currentBatch) >= batchSize {
@@ -679,7 +915,7 @@ func (idx *Indexer) createMediaFile(relPath string, info os.FileInfo) (database.
 		}, true
 	}
 
-	ext := strings.ToLower(filepath.Ext(info.Name()))
+	ext := mediatypes.NormalizeExt(info.Name())
 	fileType := mediatypes.GetFileType(ext)
 
 	if fileType == mediatypes.FileTypeOther {
@@ -742,6 +978,18 @@ func (idx *Indexer) processBatch(files []database.MediaFile) error {
 
 	ctx := context.Background()
 
+	// Stat and read before the transaction so the extra I/O doesn't hold the write lock
+	if idx.captureBirthTime.Load() {
+		idx.fillBirthTimes(files)
+	}
+	if cameras, dates := idx.captureCamera.Load(), idx.captureExifDate.Load(); cameras || dates {
+		idx.fillExif(files, cameras, dates)
+	}
+	var sidecars map[string][]string
+	if idx.importSidecars.Load() {
+		sidecars = idx.readSidecars(files)
+	}
+
 	start := time.Now()
 	tx, err := idx.db.BeginBatch(ctx)
 	if err != nil {
@@ -750,7 +998,8 @@ func (idx *Indexer) processBatch(files []database.MediaFile) error {
 
 	for i := range files {
 		if err := idx.db.UpsertFile(ctx, tx, &files[i]); err != nil {
-			logging.Warn("Error upserting file %s: %v", files[i].Path, err)
+			logging.WarnSampled("indexer:upsert", "Error upserting file %s: %v", files[i].Path, err)
+			idx.fileErrors.record(files[i].Path, err)
 		}
 	}
 
@@ -758,10 +1007,49 @@ func (idx *Indexer) processBatch(files []database.MediaFile) error {
 		return fmt.Errorf("failed to commit batch: %w", err)
 	}
 
+	if err := idx.db.SyncSidecarTags(ctx, sidecars); err != nil {
+		logging.Error("Failed to import sidecar tags: %v", err)
+	}
+
 	metrics.IndexerBatchProcessingDuration.Observe(time.Since(start).Seconds())
 	return nil
 }
 
+// fillBirthTimes sets the creation time of each file the filesystem reports
+// one for. The others keep a zero BirthTime and sort by modification time.
+func (idx *Indexer) fillBirthTimes(files []database.MediaFile) {
+	for i := range files {
+		if birth, ok := filesystem.BirthTime(filepath.Join(idx.mediaDir, files[i].Path)); ok {
+			files[i].BirthTime = birth
+		}
+	}
+}
+
+// fillExif sets the camera and lens, the capture date, or both, of each image
+// with EXIF data that records them. Images without EXIF data, or with data
+// that can't be read, are indexed without them.
+func (idx *Indexer) fillExif(files []database.MediaFile, cameras, dates bool) {
+	for i := range files {
+		if files[i].Type != database.FileTypeImage {
+			continue
+		}
+		meta, err := exif.Read(filepath.Join(idx.mediaDir, files[i].Path))
+		if err != nil {
+			if !errors.Is(err, exif.ErrNoExif) {
+				logging.Debug("Failed to read EXIF data of %s: %v", files[i].Path, err)
+			}
+			continue
+		}
+		if cameras {
+			files[i].Camera = meta.Camera()
+			files[i].Lens = meta.LensModel
+		}
+		if dates {
+			files[i].CapturedAt = meta.DateTimeOriginal
+		}
+	}
+}
+
 // cleanupMissingFiles removes files from the database that no longer exist on disk.
 func (idx *Indexer) cleanupMissingFiles(indexTime time.Time) error {
 	ctx := context.Background()
@@ -789,17 +1077,82 @@ func (idx *Indexer) cleanupMissingFiles(indexTime time.Time) error {
 	return nil
 }
 
+// removeVanishedFolders removes the top-level folders seen by the last
+// index that are no longer in the media directory, with everything in them.
+// Folders moved to the trash are skipped: they are already out of the index,
+// and their tags are kept for a restore. Failures are logged;
+// cleanupMissingFiles catches anything left behind.
+func (idx *Indexer) removeVanishedFolders() {
+	idx.stateMu.RLock()
+	var vanished []string
+	for name := range idx.lastSubdirModTimes {
+		if _, err := os.Stat(filepath.Join(idx.mediaDir, name)); os.IsNotExist(err) {
+			vanished = append(vanished, name)
+		}
+	}
+	idx.stateMu.RUnlock()
+
+	if len(vanished) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	trashed, err := idx.db.ListTrash(ctx)
+	if err != nil {
+		logging.Error("Failed to list the trash before removing vanished folders: %v", err)
+		metrics.IndexerErrors.Inc()
+		return
+	}
+	vanished = slices.DeleteFunc(vanished, func(name string) bool {
+		return slices.ContainsFunc(trashed, func(item database.TrashItem) bool {
+			return item.OriginalPath == filepath.ToSlash(name)
+		})
+	})
+	if len(vanished) == 0 {
+		return
+	}
+
+	tx, err := idx.db.BeginBatch(ctx)
+	if err != nil {
+		logging.Error("Failed to begin folder removal transaction: %v", err)
+		return
+	}
+
+	var total int64
+	for _, name := range vanished {
+		deleted, err := idx.db.DeleteFilesByPrefix(ctx, tx, name)
+		if err != nil {
+			if endErr := idx.db.EndBatch(tx, err); endErr != nil {
+				logging.Error("Failed to remove vanished folder %s: %v", name, endErr)
+			}
+			metrics.IndexerErrors.Inc()
+			return
+		}
+		logging.Debug("Removed %d entries of vanished folder %s", deleted, name)
+		total += deleted
+	}
+
+	if err := idx.db.EndBatch(tx, nil); err != nil {
+		logging.Error("Failed to commit folder removal: %v", err)
+		metrics.IndexerErrors.Inc()
+		return
+	}
+
+	logging.Info("Removed %d vanished folders (%d entries) from index", len(vanished), total)
+}
+
 func (idx *Indexer) periodicIndex() {
-	ticker := time.NewTicker(idx.indexInterval)
+	ticker := time.NewTicker(idx.getIndexInterval())
 	defer ticker.Stop()
 
 	for {
 		select {
+		case <-idx.indexReset:
+			interval := idx.getIndexInterval()
+			ticker.Reset(interval)
+			logging.Info("Periodic re-index interval changed to %v", interval)
 		case <-ticker.C:
-			logging.Debug("Periodic re-index triggered")
-			if err := idx.Index(); err != nil {
-				logging.Error("periodic re-index failed: %v", err)
-			}
+			logging.Debug("Periodic re-index triggered: %s", idx.TriggerIndex())
 		case <-idx.stopChan:
 			return
 		}
@@ -820,16 +1173,45 @@ func (idx *Indexer) LastIndexTime() time.Time {
 	return idx.lastIndexTime
 }
 
-// TriggerIndex manually triggers a re-index.
-func (idx *Indexer) TriggerIndex() {
-	go func() {
-		if err := idx.Index(); err != nil {
-			logging.Error("manually triggered re-index failed: %v", err)
-		}
-	}()
-}
-
 // GetProgress returns the current indexing progress.
 func (idx *Indexer) GetProgress() IndexProgress {
 	return idx.getProgress()
 }
+
+// readSidecars returns the tags called for by the XMP sidecar of each image,
+// keyed by path. Images without a sidecar map to no tags, so tags imported
+// from a sidecar since deleted are removed; ones whose sidecar can't be read,
+// perhaps as it's being saved, are left out and keep their tags.
+func (idx *Indexer) readSidecars(files []database.MediaFile) map[string][]string {
+	sidecars := make(map[string][]string)
+	for i := range files {
+		if files[i].Type != database.FileTypeImage {
+			continue
+		}
+		sidecar, err := xmp.Read(filepath.Join(idx.mediaDir, files[i].Path))
+		if errors.Is(err, xmp.ErrNoSidecar) {
+			sidecars[files[i].Path] = nil
+			continue
+		}
+		if err != nil {
+			logging.Debug("Failed to read XMP sidecar of %s: %v", files[i].Path, err)
+			continue
+		}
+		sidecars[files[i].Path] = sidecarTags(sidecar)
+	}
+	return sidecars
+}
+
+// sidecarTags returns the tags that stand for a sidecar's rating and labels
+func sidecarTags(sidecar *xmp.Sidecar) []string {
+	var tags []string
+	if sidecar.Rejected {
+		tags = append(tags, "rejected")
+	} else if sidecar.Rating > 0 {
+		tags = append(tags, fmt.Sprintf("rating:%d", sidecar.Rating))
+	}
+	for _, label := range sidecar.Labels {
+		tags = append(tags, "label:"+label)
+	}
+	return tags
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -446,6 +448,159 @@ func TestIndexerIncrementalUpdatesIntegration(t *testing.T) {
 	}
 }
 
+// TestIndexerRemoveVanishedFoldersIntegration tests that a deleted top-level
+// folder is removed from the index in one pass
+func TestIndexerRemoveVanishedFoldersIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	for _, path := range []string{"trip/beach.jpg", "trip/day 1/dunes.jpg", "kept/photo.jpg"} {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte("data"), 0o644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	db, _, err := database.New(context.Background(), filepath.Join(t.TempDir(), "test.db"), &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	idx := New(db, tempDir, 1*time.Hour)
+	if err := idx.Index(); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+
+	if err := os.RemoveAll(filepath.Join(tempDir, "trip")); err != nil {
+		t.Fatalf("Failed to remove folder: %v", err)
+	}
+	idx.removeVanishedFolders()
+
+	ctx := context.Background()
+	for _, path := range []string{"trip", "trip/beach.jpg", "trip/day 1", "trip/day 1/dunes.jpg"} {
+		if _, err := db.GetFileByPath(ctx, path); err == nil {
+			t.Errorf("Expected %s to be removed from the index", path)
+		}
+	}
+	for _, path := range []string{"kept", "kept/photo.jpg"} {
+		if _, err := db.GetFileByPath(ctx, path); err != nil {
+			t.Errorf("Expected %s to be kept, got %v", path, err)
+		}
+	}
+}
+
+func TestIndexerMediaIgnoreIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	for _, path := range []string{"trip/beach.jpg", "trip/scratch/crop.jpg", "temp/render.mp4"} {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte("data"), 0o644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	db, _, err := database.New(context.Background(), filepath.Join(t.TempDir(), "test.db"), &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	idx := New(db, tempDir, 1*time.Hour)
+	for _, parallel := range []bool{true, false} {
+		idx.SetParallelWalking(parallel)
+		if err := idx.Index(); err != nil {
+			t.Fatalf("Index failed: %v", err)
+		}
+		if _, err := db.GetFileByPath(ctx, "temp/render.mp4"); err != nil {
+			t.Fatalf("Expected temp/render.mp4 to be indexed before it's ignored, got %v", err)
+		}
+
+		// Ignoring files removes them from the index on the next run. The
+		// cleanup compares whole seconds, so that run must start in a later one.
+		time.Sleep(1100 * time.Millisecond)
+		if err := os.WriteFile(filepath.Join(tempDir, ignoreFileName), []byte("/temp\nscratch/\n"), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", ignoreFileName, err)
+		}
+		if err := idx.Index(); err != nil {
+			t.Fatalf("Index failed: %v", err)
+		}
+		for _, path := range []string{"temp", "temp/render.mp4", "trip/scratch", "trip/scratch/crop.jpg"} {
+			if _, err := db.GetFileByPath(ctx, path); err == nil {
+				t.Errorf("parallel=%v: expected %s to be ignored", parallel, path)
+			}
+		}
+		if _, err := db.GetFileByPath(ctx, "trip/beach.jpg"); err != nil {
+			t.Errorf("parallel=%v: expected trip/beach.jpg to be indexed, got %v", parallel, err)
+		}
+
+		if err := os.Remove(filepath.Join(tempDir, ignoreFileName)); err != nil {
+			t.Fatalf("Failed to remove %s: %v", ignoreFileName, err)
+		}
+	}
+}
+
+// TestIndexDirectoryIntegration tests re-indexing a single folder
+func TestIndexDirectoryIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	for _, path := range []string{"trip/beach.jpg", "trip/day1/sea.jpg", "trip/scratch/crop.jpg", "other/clip.mp4"} {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte("data"), 0o644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, ignoreFileName), []byte("scratch/\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", ignoreFileName, err)
+	}
+
+	db, _, err := database.New(context.Background(), filepath.Join(t.TempDir(), "test.db"), &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	idx := New(db, tempDir, 1*time.Hour)
+	if err := idx.IndexDirectory("trip"); err != nil {
+		t.Fatalf("IndexDirectory failed: %v", err)
+	}
+
+	for _, path := range []string{"trip", "trip/beach.jpg", "trip/day1"} {
+		if _, err := db.GetFileByPath(ctx, path); err != nil {
+			t.Errorf("Expected %s to be indexed, got %v", path, err)
+		}
+	}
+	// Only the folder's own entries are read, and ignored ones are skipped
+	for _, path := range []string{"trip/day1/sea.jpg", "trip/scratch", "other", "other/clip.mp4"} {
+		if _, err := db.GetFileByPath(ctx, path); err == nil {
+			t.Errorf("Expected %s not to be indexed", path)
+		}
+	}
+
+	if err := idx.IndexDirectory("missing"); err == nil {
+		t.Error("Expected an error for a folder that doesn't exist")
+	}
+}
+
 // TestIndexerChangeDetectionIntegration tests change detection polling
 func TestIndexerChangeDetectionIntegration(t *testing.T) {
 	if testing.Short() {
@@ -626,6 +781,53 @@ func TestIndexerWithCallbackIntegration(t *testing.T) {
 	}
 }
 
+// TestIndexerTriggerCoalescingIntegration tests that requests made while an
+// index runs are served by a single follow-up run
+func TestIndexerTriggerCoalescingIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	os.WriteFile(filepath.Join(tempDir, "photo1.jpg"), []byte("data"), 0o644)
+
+	db, _, err := database.New(context.Background(), dbPath, &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	idx := New(db, tempDir, 1*time.Hour)
+	defer idx.Stop()
+	idx.SetQuietPeriod(100 * time.Millisecond)
+
+	var runs atomic.Int32
+	idx.SetOnIndexComplete(func() {
+		runs.Add(1)
+	})
+
+	if got := idx.TriggerIndex(); got != TriggerStarted {
+		t.Fatalf("First TriggerIndex = %q, want %q", got, TriggerStarted)
+	}
+	for range 5 {
+		if got := idx.TriggerIndex(); got != TriggerCoalesced {
+			t.Fatalf("Repeated TriggerIndex = %q, want %q", got, TriggerCoalesced)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runs.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	if got := runs.Load(); got != 2 {
+		t.Errorf("Expected the initial run and one follow-up, got %d runs", got)
+	}
+}
+
 // TestParallelWalkerErrorHandling tests error handling during parallel walk
 func TestParallelWalkerErrorHandling(t *testing.T) {
 	if testing.Short() {
@@ -1158,3 +1360,79 @@ func TestParallelWalkerVeryDeepNestingIntegration(t *testing.T) {
 		t.Error("Deep file was not found")
 	}
 }
+
+// TestIndexerProgressEventsIntegration tests progress events in both walk modes
+func TestIndexerProgressEventsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	for _, parallel := range []bool{true, false} {
+		name := "sequential"
+		if parallel {
+			name = "parallel"
+		}
+		t.Run(name, func(t *testing.T) {
+			mediaDir := t.TempDir()
+			albumDir := filepath.Join(mediaDir, "album")
+			if err := os.MkdirAll(albumDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			for i := range 25 {
+				name := filepath.Join(albumDir, "photo"+strconv.Itoa(i)+".jpg")
+				if err := os.WriteFile(name, []byte("data"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			db, _, err := database.New(context.Background(), filepath.Join(t.TempDir(), "test.db"), &database.Options{})
+			if err != nil {
+				t.Fatalf("Failed to create database: %v", err)
+			}
+			defer db.Close()
+
+			events := make(chan ProgressEvent, 100)
+			idx := New(db, mediaDir, time.Hour)
+			idx.SetParallelWalking(parallel)
+			idx.SetProgressInterval(10)
+			idx.SetOnProgress(func(event ProgressEvent) {
+				events <- event
+			})
+
+			if err := idx.Index(); err != nil {
+				t.Fatalf("Index failed: %v", err)
+			}
+
+			// Events are delivered asynchronously, so collect what arrives
+			stages := make(map[string]int)
+			timeout := time.After(2 * time.Second)
+		collect:
+			for {
+				select {
+				case event := <-events:
+					stages[event.Stage]++
+					if event.Elapsed <= 0 {
+						t.Errorf("Expected elapsed time in %+v", event)
+					}
+					// The parallel walker's workers may not have processed
+					// anything yet when the walk reports
+					if event.FilesProcessed == 0 && (!parallel || event.Stage == ProgressStageStore) {
+						t.Errorf("Expected processed files in %+v", event)
+					}
+					if event.Stage == ProgressStageScan && event.CurrentFolder != "album" {
+						t.Errorf("Expected the scan to be in album, got %q", event.CurrentFolder)
+					}
+				case <-timeout:
+					break collect
+				}
+			}
+
+			if stages[ProgressStageScan] == 0 {
+				t.Errorf("Expected scan progress events, got %v", stages)
+			}
+			if parallel && stages[ProgressStageStore] == 0 {
+				t.Errorf("Expected store progress events from the parallel walker, got %v", stages)
+			}
+		})
+	}
+}
@@ -2,8 +2,10 @@ package indexer
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -544,6 +546,54 @@ func TestSetPollInterval(t *testing.T) {
 	}
 }
 
+func TestSetIndexInterval(t *testing.T) {
+	tempDir := t.TempDir()
+	db := &database.Database{}
+	idx := New(db, tempDir, 5*time.Minute)
+
+	idx.SetIndexInterval(0)
+	if idx.getIndexInterval() != 5*time.Minute {
+		t.Errorf("Expected zero interval to be ignored, got %v", idx.getIndexInterval())
+	}
+
+	idx.SetIndexInterval(time.Hour)
+	if idx.getIndexInterval() != time.Hour {
+		t.Errorf("Expected indexInterval=1h, got %v", idx.getIndexInterval())
+	}
+
+	// A running loop should be told to reset its ticker
+	select {
+	case <-idx.indexReset:
+	default:
+		t.Error("Expected index interval reset to be signaled")
+	}
+}
+
+func TestSetPollIntervalSignalsReset(t *testing.T) {
+	tempDir := t.TempDir()
+	db := &database.Database{}
+	idx := New(db, tempDir, 5*time.Minute)
+
+	// Multiple changes collapse into a single pending reset
+	idx.SetPollInterval(10 * time.Second)
+	idx.SetPollInterval(20 * time.Second)
+
+	select {
+	case <-idx.pollReset:
+	default:
+		t.Fatal("Expected poll interval reset to be signaled")
+	}
+	select {
+	case <-idx.pollReset:
+		t.Error("Expected only one pending reset")
+	default:
+	}
+
+	if idx.getPollInterval() != 20*time.Second {
+		t.Errorf("Expected pollInterval=20s, got %v", idx.getPollInterval())
+	}
+}
+
 func TestSetParallelWalking(t *testing.T) {
 	tempDir := t.TempDir()
 	db := &database.Database{}
@@ -595,6 +645,167 @@ func TestSetParallelConfig(t *testing.T) {
 	}
 }
 
+func TestFillBirthTimes(t *testing.T) {
+	tempDir := t.TempDir()
+	db := &database.Database{}
+	idx := New(db, tempDir, 5*time.Minute)
+
+	if idx.captureBirthTime.Load() {
+		t.Error("Expected birth time indexing to be off by default")
+	}
+	idx.SetBirthTimeIndexing(true)
+	if !idx.captureBirthTime.Load() {
+		t.Error("Expected birth time indexing after SetBirthTimeIndexing(true)")
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, "photo.jpg"), []byte("data"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	files := []database.MediaFile{{Path: "photo.jpg"}, {Path: "missing.jpg"}}
+	idx.fillBirthTimes(files)
+
+	if !files[1].BirthTime.IsZero() {
+		t.Error("Expected no birth time for a missing file")
+	}
+	if files[0].BirthTime.IsZero() {
+		t.Log("Filesystem does not record birth times; only the fallback was checked")
+	}
+}
+
+func TestFillCameras(t *testing.T) {
+	tempDir := t.TempDir()
+	db := &database.Database{}
+	idx := New(db, tempDir, 5*time.Minute)
+
+	if idx.captureCamera.Load() {
+		t.Error("Expected camera indexing to be off by default")
+	}
+	idx.SetCameraIndexing(true)
+	if !idx.captureCamera.Load() {
+		t.Error("Expected camera indexing after SetCameraIndexing(true)")
+	}
+
+	// A JPEG whose EXIF data records make "DJI" and model "FC7"
+	tiff := []byte("MM\x00\x2A\x00\x00\x00\x08\x00\x02" +
+		"\x01\x0F\x00\x02\x00\x00\x00\x04DJI\x00" +
+		"\x01\x10\x00\x02\x00\x00\x00\x04FC7\x00" +
+		"\x00\x00\x00\x00")
+	jpeg := append([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, byte(2 + 6 + len(tiff))}, "Exif\x00\x00"...)
+	jpeg = append(jpeg, tiff...)
+	jpeg = append(jpeg, 0xFF, 0xDA, 0x00, 0x02)
+
+	for name, data := range map[string][]byte{"photo.jpg": jpeg, "plain.jpg": []byte("data"), "clip.mp4": jpeg} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), data, 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	files := []database.MediaFile{
+		{Path: "photo.jpg", Type: database.FileTypeImage},
+		{Path: "plain.jpg", Type: database.FileTypeImage},
+		{Path: "missing.jpg", Type: database.FileTypeImage},
+		{Path: "clip.mp4", Type: database.FileTypeVideo},
+	}
+	idx.fillExif(files, true, false)
+
+	if files[0].Camera != "DJI FC7" {
+		t.Errorf("Expected camera %q, got %q", "DJI FC7", files[0].Camera)
+	}
+	for _, file := range files[1:] {
+		if file.Camera != "" || file.Lens != "" {
+			t.Errorf("Expected no camera for %s, got %q and lens %q", file.Path, file.Camera, file.Lens)
+		}
+	}
+}
+
+func TestReadSidecars(t *testing.T) {
+	tempDir := t.TempDir()
+	db := &database.Database{}
+	idx := New(db, tempDir, 5*time.Minute)
+
+	if idx.importSidecars.Load() {
+		t.Error("Expected sidecar import to be off by default")
+	}
+	idx.SetSidecarImport(true)
+	if !idx.importSidecars.Load() {
+		t.Error("Expected sidecar import after SetSidecarImport(true)")
+	}
+
+	const rdf = `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:xmp="http://ns.adobe.com/xap/1.0/">
+	  <rdf:Description xmp:Rating="%s" xmp:Label="Green"/>
+	</rdf:RDF>`
+	for name, data := range map[string]string{
+		"rated.CR2.xmp":  fmt.Sprintf(rdf, "4"),
+		"reject.xmp":     fmt.Sprintf(rdf, "-1"),
+		"broken.jpg.xmp": "<rdf:RDF",
+		"clip.mp4.xmp":   fmt.Sprintf(rdf, "5"),
+	} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(data), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	files := []database.MediaFile{
+		{Path: "rated.CR2", Type: database.FileTypeImage},
+		{Path: "reject.NEF", Type: database.FileTypeImage},
+		{Path: "plain.jpg", Type: database.FileTypeImage},
+		{Path: "broken.jpg", Type: database.FileTypeImage},
+		{Path: "clip.mp4", Type: database.FileTypeVideo},
+	}
+
+	want := map[string][]string{
+		"rated.CR2":  {"rating:4", "label:Green"},
+		"reject.NEF": {"rejected", "label:Green"},
+		"plain.jpg":  nil,
+	}
+	if got := idx.readSidecars(files); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected sidecar tags %v, got %v", want, got)
+	}
+}
+
+func TestFillExifDates(t *testing.T) {
+	tempDir := t.TempDir()
+	db := &database.Database{}
+	idx := New(db, tempDir, 5*time.Minute)
+
+	if idx.captureExifDate.Load() {
+		t.Error("Expected EXIF date indexing to be off by default")
+	}
+	idx.SetExifDateIndexing(true)
+	if !idx.captureExifDate.Load() {
+		t.Error("Expected EXIF date indexing after SetExifDateIndexing(true)")
+	}
+
+	// A JPEG whose Exif IFD records DateTimeOriginal "2021:06:05 14:30:00"
+	tiff := []byte("MM\x00\x2A\x00\x00\x00\x08\x00\x01" +
+		"\x87\x69\x00\x04\x00\x00\x00\x01\x00\x00\x00\x1A" +
+		"\x00\x00\x00\x00" +
+		"\x00\x01" +
+		"\x90\x03\x00\x02\x00\x00\x00\x14\x00\x00\x00\x2C" +
+		"\x00\x00\x00\x00" +
+		"2021:06:05 14:30:00\x00")
+	jpeg := append([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, byte(2 + 6 + len(tiff))}, "Exif\x00\x00"...)
+	jpeg = append(jpeg, tiff...)
+	jpeg = append(jpeg, 0xFF, 0xDA, 0x00, 0x02)
+
+	for name, data := range map[string][]byte{"photo.jpg": jpeg, "plain.jpg": []byte("data")} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), data, 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	files := []database.MediaFile{
+		{Path: "photo.jpg", Type: database.FileTypeImage},
+		{Path: "plain.jpg", Type: database.FileTypeImage},
+	}
+	idx.fillExif(files, false, true)
+
+	want := time.Date(2021, 6, 5, 14, 30, 0, 0, time.Local)
+	if !files[0].CapturedAt.Equal(want) {
+		t.Errorf("Expected capture date %v, got %v", want, files[0].CapturedAt)
+	}
+	if !files[1].CapturedAt.IsZero() {
+		t.Errorf("Expected no capture date for plain.jpg, got %v", files[1].CapturedAt)
+	}
+}
+
 func TestSetOnIndexComplete(t *testing.T) {
 	tempDir := t.TempDir()
 	db := &database.Database{}
@@ -645,6 +856,69 @@ func TestIsIndexing(t *testing.T) {
 	}
 }
 
+func TestTriggerIndexCoalesces(t *testing.T) {
+	tempDir := t.TempDir()
+	db := &database.Database{}
+	idx := New(db, tempDir, 5*time.Minute)
+	defer idx.Stop()
+
+	// Requests while an index runs wait for one follow-up run
+	idx.indexMu.Lock()
+	idx.isIndexing = true
+	idx.indexMu.Unlock()
+
+	for range 3 {
+		if got := idx.TriggerIndex(); got != TriggerCoalesced {
+			t.Fatalf("TriggerIndex while indexing = %q, want %q", got, TriggerCoalesced)
+		}
+	}
+
+	// A run that starts afterwards covers them
+	idx.indexMu.Lock()
+	idx.isIndexing = false
+	idx.indexMu.Unlock()
+	if !idx.tryStartIndexing() {
+		t.Fatal("Expected tryStartIndexing to succeed")
+	}
+	idx.indexMu.Lock()
+	pending := idx.reindexPending
+	idx.isIndexing = false
+	idx.indexMu.Unlock()
+	if pending {
+		t.Error("Expected a started run to clear the pending re-index")
+	}
+
+	// So do requests during the quiet period after a run
+	idx.SetQuietPeriod(time.Hour)
+	idx.indexMu.Lock()
+	idx.lastFinishTime = time.Now()
+	idx.indexMu.Unlock()
+	if got := idx.TriggerIndex(); got != TriggerCoalesced {
+		t.Errorf("TriggerIndex during the quiet period = %q, want %q", got, TriggerCoalesced)
+	}
+}
+
+func TestSetQuietPeriod(t *testing.T) {
+	tempDir := t.TempDir()
+	db := &database.Database{}
+	idx := New(db, tempDir, 5*time.Minute)
+
+	if got := idx.getQuietPeriod(); got != defaultQuietPeriod {
+		t.Errorf("Default quiet period = %v, want %v", got, defaultQuietPeriod)
+	}
+
+	idx.SetQuietPeriod(0)
+	if got := idx.getQuietPeriod(); got != 0 {
+		t.Errorf("Quiet period = %v, want 0", got)
+	}
+
+	// Negative values are ignored
+	idx.SetQuietPeriod(-time.Second)
+	if got := idx.getQuietPeriod(); got != 0 {
+		t.Errorf("Quiet period = %v after a negative value, want 0", got)
+	}
+}
+
 func TestLastIndexTime(t *testing.T) {
 	tempDir := t.TempDir()
 	db := &database.Database{}
@@ -82,6 +82,17 @@ type ParallelWalker struct {
 	filesProcessed   atomic.Int64
 	foldersProcessed atomic.Int64
 	errorsCount      atomic.Int64
+
+	// Paths skipped by .mediaignore files and ignore patterns (nil skips none)
+	ignore *ignoreMatcher
+
+	// Called with the path of each file or directory that can't be read. A
+	// non-nil return aborts the walk with that error.
+	onError func(path string, isDir bool, err error) error
+
+	// Called from the walking goroutine after each entry is queued, with
+	// the number queued so far and the folder being walked
+	onWalked func(walked int64, folder string)
 }
 
 // NewParallelWalker creates a new parallel directory walker
@@ -166,6 +177,7 @@ func (pw *ParallelWalker) Walk() ([]database.MediaFile, error) {
 
 // walkAndEnqueue walks the directory tree and sends jobs to workers
 func (pw *ParallelWalker) walkAndEnqueue() error {
+	var walked int64
 	return filepath.WalkDir(pw.mediaDir, func(path string, d fs.DirEntry, err error) error {
 		// Check for cancellation
 		select {
@@ -175,12 +187,11 @@ func (pw *ParallelWalker) walkAndEnqueue() error {
 		}
 
 		if err != nil {
-			logging.Warn("Error accessing path %s: %v", path, err)
-			return nil // Continue walking
+			return pw.reportError(path, d != nil && d.IsDir(), err)
 		}
 
-		// Skip hidden files and directories
-		if pw.config.SkipHidden && strings.HasPrefix(d.Name(), ".") {
+		// Skip hidden files and directories, then ignored ones
+		if (pw.config.SkipHidden && strings.HasPrefix(d.Name(), ".")) || pw.ignore.skips(path, d.IsDir()) {
 			if d.IsDir() {
 				return filepath.SkipDir
 			}
@@ -203,8 +214,7 @@ func (pw *ParallelWalker) walkAndEnqueue() error {
 		// Get file info
 		info, err := d.Info()
 		if err != nil {
-			logging.Warn("Error getting info for %s: %v", path, err)
-			return nil
+			return pw.reportError(path, d.IsDir(), err)
 		}
 
 		// Send job to workers
@@ -214,10 +224,32 @@ func (pw *ParallelWalker) walkAndEnqueue() error {
 			return fs.SkipAll
 		}
 
+		if pw.onWalked != nil {
+			walked++
+			folder := relPath
+			if !d.IsDir() {
+				if folder = filepath.Dir(relPath); folder == "." {
+					folder = ""
+				}
+			}
+			pw.onWalked(walked, folder)
+		}
+
 		return nil
 	})
 }
 
+// reportError passes a per-file error to the onError callback, returning
+// the error to abort the walk with. Without a callback the error is logged
+// and the walk continues.
+func (pw *ParallelWalker) reportError(path string, isDir bool, err error) error {
+	if pw.onError != nil {
+		return pw.onError(path, isDir, err)
+	}
+	logging.WarnSampled("indexer:walk", "Error accessing path %s: %v", path, err)
+	return nil
+}
+
 // worker processes files from the jobs channel
 func (pw *ParallelWalker) worker(id int) {
 	defer pw.wg.Done()
@@ -275,7 +307,7 @@ func (pw *ParallelWalker) processFile(job fileJob) fileResult {
 		}
 	}
 
-	ext := strings.ToLower(filepath.Ext(job.info.Name()))
+	ext := mediatypes.NormalizeExt(job.info.Name())
 	fileType := mediatypes.GetFileType(ext)
 
 	if fileType == mediatypes.FileTypeOther {
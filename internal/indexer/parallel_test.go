@@ -554,6 +554,51 @@ func TestParallelWalkerMixedFileTypes(t *testing.T) {
 	}
 }
 
+// TestParallelWalkerMixedCaseExtensions tests that extensions are matched
+// regardless of case
+func TestParallelWalkerMixedCaseExtensions(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+
+	files := map[string]struct {
+		fileType database.FileType
+		mimeType string
+	}{
+		"IMG_0001.JPG":  {database.FileTypeImage, "image/jpeg"},
+		"IMG_0002.HEIC": {database.FileTypeImage, "image/heic"},
+		"clip.Mp4":      {database.FileTypeVideo, "video/mp4"},
+		"Party.WPL":     {database.FileTypePlaylist, "application/vnd.ms-wpl"},
+	}
+
+	for filename := range files {
+		os.WriteFile(filepath.Join(tempDir, filename), []byte("data"), 0o644)
+	}
+	os.WriteFile(filepath.Join(tempDir, "NOTES.TXT"), []byte("text"), 0o644)
+
+	walker := NewParallelWalker(tempDir, DefaultParallelWalkerConfig())
+
+	results, err := walker.Walk()
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+
+	if len(results) != len(files) {
+		t.Errorf("Expected %d media files, got %d", len(files), len(results))
+	}
+
+	for _, file := range results {
+		want, ok := files[file.Name]
+		if !ok {
+			t.Errorf("Unexpected file %s", file.Name)
+			continue
+		}
+		if file.Type != want.fileType || file.MimeType != want.mimeType {
+			t.Errorf("File %s: expected %s (%s), got %s (%s)", file.Name, want.fileType, want.mimeType, file.Type, file.MimeType)
+		}
+	}
+}
+
 // TestParallelWalkerDeepNesting tests walking deeply nested directories
 func TestParallelWalkerDeepNesting(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,114 @@
+package indexer
+
+import (
+	"sync/atomic"
+	"time"
+
+	"media-viewer/internal/logging"
+)
+
+// DefaultProgressInterval is how many files and folders pass between
+// progress events
+const DefaultProgressInterval = 10000
+
+// Index stages reported in progress events
+const (
+	// ProgressStageScan is the directory walk
+	ProgressStageScan = "scan"
+	// ProgressStageStore is writing walked files to the database, which the
+	// parallel walker does after the walk
+	ProgressStageStore = "store"
+)
+
+// ProgressEvent reports how far a running index has got. During a parallel
+// scan the counts trail the walk by the files still queued for the workers.
+type ProgressEvent struct {
+	Stage            string        `json:"stage"`
+	FilesProcessed   int64         `json:"filesProcessed"`
+	FoldersProcessed int64         `json:"foldersProcessed"`
+	CurrentFolder    string        `json:"currentFolder"`
+	Elapsed          time.Duration `json:"elapsed"`
+}
+
+// SetOnProgress sets a callback invoked with a ProgressEvent every
+// progress interval during indexing. The callback runs on its own goroutine
+// and never holds up the walk: events that arrive while it is still busy are
+// dropped.
+func (idx *Indexer) SetOnProgress(callback func(ProgressEvent)) {
+	idx.onProgress = callback
+}
+
+// SetProgressInterval sets how many files and folders pass between progress
+// events. Zero or less disables them. Takes effect from the next index run.
+func (idx *Indexer) SetProgressInterval(interval int) {
+	idx.progressInterval.Store(int64(interval))
+}
+
+// progressReporter hands progress events of one index run to the callback
+type progressReporter struct {
+	interval  int64
+	startTime time.Time
+	events    chan ProgressEvent
+	dropped   atomic.Int64
+}
+
+// startProgress starts delivering progress events for an index run. It
+// returns nil when there is no callback or events are disabled; a nil
+// reporter ignores all calls.
+func (idx *Indexer) startProgress(startTime time.Time) *progressReporter {
+	interval := idx.progressInterval.Load()
+	if idx.onProgress == nil || interval <= 0 {
+		return nil
+	}
+
+	r := &progressReporter{
+		interval:  interval,
+		startTime: startTime,
+		events:    make(chan ProgressEvent, 1),
+	}
+	callback := idx.onProgress
+	go func() {
+		for event := range r.events {
+			callback(event)
+		}
+	}()
+	return r
+}
+
+// due reports whether the count-th file or folder should send an event
+func (r *progressReporter) due(count int64) bool {
+	return r != nil && count > 0 && count%r.interval == 0
+}
+
+// report sends an event without blocking, dropping it if the callback is
+// still handling the previous one
+func (r *progressReporter) report(stage string, files, folders int64, folder string) {
+	if r == nil {
+		return
+	}
+
+	event := ProgressEvent{
+		Stage:            stage,
+		FilesProcessed:   files,
+		FoldersProcessed: folders,
+		CurrentFolder:    folder,
+		Elapsed:          time.Since(r.startTime),
+	}
+	select {
+	case r.events <- event:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// stop ends the run's events. A slow callback may still be handling the
+// last one; stop doesn't wait for it.
+func (r *progressReporter) stop() {
+	if r == nil {
+		return
+	}
+	close(r.events)
+	if dropped := r.dropped.Load(); dropped > 0 {
+		logging.Debug("Dropped %d indexer progress events while the callback was busy", dropped)
+	}
+}
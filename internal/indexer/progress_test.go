@@ -0,0 +1,83 @@
+package indexer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressReporterNil(t *testing.T) {
+	idx := &Indexer{}
+	idx.SetProgressInterval(10)
+
+	// No callback means no reporter, and a nil reporter ignores calls
+	r := idx.startProgress(time.Now())
+	if r != nil {
+		t.Fatal("Expected no reporter without a callback")
+	}
+	if r.due(10) {
+		t.Error("Expected a nil reporter never to be due")
+	}
+	r.report(ProgressStageScan, 1, 1, "")
+	r.stop()
+
+	idx.SetOnProgress(func(ProgressEvent) {})
+	idx.SetProgressInterval(0)
+	if idx.startProgress(time.Now()) != nil {
+		t.Error("Expected no reporter with progress events disabled")
+	}
+}
+
+func TestProgressReporterDue(t *testing.T) {
+	r := &progressReporter{interval: 100}
+	for count, expected := range map[int64]bool{0: false, 1: false, 99: false, 100: true, 150: false, 300: true} {
+		if got := r.due(count); got != expected {
+			t.Errorf("due(%d) = %v, want %v", count, got, expected)
+		}
+	}
+}
+
+func TestProgressReporterDropsWhenBusy(t *testing.T) {
+	release := make(chan struct{})
+	received := make(chan ProgressEvent, 10)
+
+	idx := &Indexer{}
+	idx.SetProgressInterval(1)
+	idx.SetOnProgress(func(event ProgressEvent) {
+		received <- event
+		<-release
+	})
+
+	r := idx.startProgress(time.Now())
+
+	// The first event occupies the callback
+	r.report(ProgressStageScan, 1, 0, "a")
+	select {
+	case event := <-received:
+		if event.FilesProcessed != 1 || event.CurrentFolder != "a" || event.Stage != ProgressStageScan {
+			t.Errorf("Unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the first event")
+	}
+
+	// While it is busy, one event is buffered and the rest are dropped
+	// without blocking
+	done := make(chan struct{})
+	go func() {
+		for i := int64(2); i <= 10; i++ {
+			r.report(ProgressStageScan, i, 0, "b")
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("report blocked on a busy callback")
+	}
+	if dropped := r.dropped.Load(); dropped != 8 {
+		t.Errorf("Expected 8 dropped events, got %d", dropped)
+	}
+
+	close(release)
+	r.stop()
+}
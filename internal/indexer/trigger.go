@@ -0,0 +1,110 @@
+package indexer
+
+import (
+	"time"
+
+	"media-viewer/internal/logging"
+)
+
+// TriggerResult reports what TriggerIndex did with a re-index request.
+type TriggerResult string
+
+const (
+	// TriggerStarted means an index run started for the request
+	TriggerStarted TriggerResult = "started"
+
+	// TriggerCoalesced means the request joined the single follow-up run
+	// due once the running index finishes and the quiet period has passed
+	TriggerCoalesced TriggerResult = "coalesced"
+)
+
+// SetQuietPeriod sets how long after an index run finishes another
+// triggered one may start. Requests that arrive while an index runs, or
+// during the quiet period after it, are coalesced into one follow-up run.
+// Zero starts the follow-up as soon as the running index finishes.
+func (idx *Indexer) SetQuietPeriod(period time.Duration) {
+	if period < 0 {
+		return
+	}
+
+	idx.settingsMu.Lock()
+	defer idx.settingsMu.Unlock()
+	idx.quietPeriod = period
+}
+
+// getQuietPeriod returns the current quiet period between triggered runs.
+func (idx *Indexer) getQuietPeriod() time.Duration {
+	idx.settingsMu.RLock()
+	defer idx.settingsMu.RUnlock()
+	return idx.quietPeriod
+}
+
+// TriggerIndex requests a re-index in the background. It starts one unless
+// an index is running or the last finished within the quiet period, in
+// which case the request is coalesced: however many arrive, they're served
+// by a single follow-up run once the quiet period has passed.
+func (idx *Indexer) TriggerIndex() TriggerResult {
+	idx.indexMu.Lock()
+	defer idx.indexMu.Unlock()
+
+	if idx.reindexPending {
+		return TriggerCoalesced
+	}
+
+	wait := idx.quietRemainingLocked()
+	if !idx.isIndexing && wait <= 0 {
+		idx.isIndexing = true
+		go func() {
+			if err := idx.runIndex(); err != nil {
+				logging.Error("triggered re-index failed: %v", err)
+			}
+		}()
+		return TriggerStarted
+	}
+
+	idx.reindexPending = true
+	if !idx.isIndexing {
+		// Otherwise finishIndexing schedules the follow-up
+		time.AfterFunc(wait, idx.runPendingIndex)
+	}
+	return TriggerCoalesced
+}
+
+// quietRemainingLocked returns how much of the quiet period after the last
+// run is left. Caller must hold indexMu.
+func (idx *Indexer) quietRemainingLocked() time.Duration {
+	if idx.lastFinishTime.IsZero() {
+		return 0
+	}
+	return idx.getQuietPeriod() - time.Since(idx.lastFinishTime)
+}
+
+// runPendingIndex runs the follow-up index coalesced requests are waiting
+// for, unless another run has started since and so covers them.
+func (idx *Indexer) runPendingIndex() {
+	select {
+	case <-idx.stopChan:
+		return
+	default:
+	}
+
+	idx.indexMu.Lock()
+	if idx.isIndexing || !idx.reindexPending {
+		idx.indexMu.Unlock()
+		return
+	}
+	if wait := idx.quietRemainingLocked(); wait > 0 {
+		// The quiet period was lengthened while waiting
+		time.AfterFunc(wait, idx.runPendingIndex)
+		idx.indexMu.Unlock()
+		return
+	}
+	idx.reindexPending = false
+	idx.isIndexing = true
+	idx.indexMu.Unlock()
+
+	logging.Info("Starting re-index coalesced from requests during the last run")
+	if err := idx.runIndex(); err != nil {
+		logging.Error("coalesced re-index failed: %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // LogLevel represents the severity of a log message
@@ -23,44 +24,62 @@ const (
 )
 
 var (
-	currentLevel LogLevel
+	currentLevel atomic.Int32
 	levelOnce    sync.Once
 )
 
 // initLevel initializes the log level from environment variables
 func initLevel() {
 	levelOnce.Do(func() {
-		// Check DEBUG environment variable first
-		if debug := os.Getenv("DEBUG"); debug != "" {
-			switch strings.ToLower(debug) {
-			case "1", "true", "yes", "on":
-				currentLevel = LevelDebug
-				return
-			}
-		}
+		currentLevel.Store(int32(levelFromEnv()))
+	})
+}
 
-		// Check LOG_LEVEL environment variable
-		levelStr := strings.ToLower(os.Getenv("LOG_LEVEL"))
-		switch levelStr {
-		case "debug":
-			currentLevel = LevelDebug
-		case "info":
-			currentLevel = LevelInfo
-		case "warn", "warning":
-			currentLevel = LevelWarn
-		case "error":
-			currentLevel = LevelError
-		default:
-			// Default to Info level (no debug logs)
-			currentLevel = LevelInfo
+// levelFromEnv resolves the log level from DEBUG and LOG_LEVEL
+func levelFromEnv() LogLevel {
+	// Check DEBUG environment variable first
+	if debug := os.Getenv("DEBUG"); debug != "" {
+		switch strings.ToLower(debug) {
+		case "1", "true", "yes", "on":
+			return LevelDebug
 		}
-	})
+	}
+
+	// Check LOG_LEVEL environment variable
+	levelStr := strings.ToLower(os.Getenv("LOG_LEVEL"))
+	switch levelStr {
+	case "debug":
+		return LevelDebug
+	case "info":
+		return LevelInfo
+	case "warn", "warning":
+		return LevelWarn
+	case "error":
+		return LevelError
+	default:
+		// Default to Info level (no debug logs)
+		return LevelInfo
+	}
 }
 
 // GetLevel returns the current log level
 func GetLevel() LogLevel {
 	initLevel()
-	return currentLevel
+	return LogLevel(currentLevel.Load())
+}
+
+// SetLevel changes the log level at runtime
+func SetLevel(level LogLevel) {
+	initLevel()
+	currentLevel.Store(int32(level))
+}
+
+// ReloadLevel re-reads DEBUG and LOG_LEVEL and applies the result.
+// Returns the new level.
+func ReloadLevel() LogLevel {
+	level := levelFromEnv()
+	SetLevel(level)
+	return level
 }
 
 // IsDebugEnabled returns true if debug logging is enabled
@@ -184,3 +184,40 @@ func TestLogLevelString(t *testing.T) {
 		})
 	}
 }
+
+func TestSetLevel(t *testing.T) {
+	original := GetLevel()
+	defer SetLevel(original)
+
+	SetLevel(LevelError)
+	if GetLevel() != LevelError {
+		t.Errorf("GetLevel() = %v, want %v", GetLevel(), LevelError)
+	}
+	if IsDebugEnabled() {
+		t.Error("IsDebugEnabled should be false at error level")
+	}
+
+	SetLevel(LevelDebug)
+	if !IsDebugEnabled() {
+		t.Error("IsDebugEnabled should be true at debug level")
+	}
+}
+
+func TestReloadLevel(t *testing.T) {
+	original := GetLevel()
+	defer SetLevel(original)
+
+	t.Setenv("DEBUG", "")
+	t.Setenv("LOG_LEVEL", "warn")
+	if level := ReloadLevel(); level != LevelWarn {
+		t.Errorf("ReloadLevel() = %v, want %v", level, LevelWarn)
+	}
+	if GetLevel() != LevelWarn {
+		t.Errorf("GetLevel() = %v, want %v after reload", GetLevel(), LevelWarn)
+	}
+
+	t.Setenv("DEBUG", "true")
+	if level := ReloadLevel(); level != LevelDebug {
+		t.Errorf("ReloadLevel() with DEBUG=true = %v, want %v", level, LevelDebug)
+	}
+}
@@ -0,0 +1,203 @@
+package logging
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultSampleInterval is how often a sampled message may repeat per key
+	DefaultSampleInterval = time.Minute
+
+	// maxSampleKeys bounds the sampler's memory when callers use many distinct keys
+	maxSampleKeys = 1000
+)
+
+// sampleState tracks a single sampling key
+type sampleState struct {
+	level       LogLevel
+	lastLogged  time.Time
+	suppressed  int
+	lastMessage string
+	timer       *time.Timer
+}
+
+var (
+	sampleInterval     atomic.Int64
+	sampleIntervalOnce sync.Once
+
+	samplesMu sync.Mutex
+	samples   = make(map[string]*sampleState)
+)
+
+// initSampleInterval initializes the sampling interval from LOG_SAMPLE_INTERVAL
+func initSampleInterval() {
+	sampleIntervalOnce.Do(func() {
+		sampleInterval.Store(int64(sampleIntervalFromEnv()))
+	})
+}
+
+// sampleIntervalFromEnv parses LOG_SAMPLE_INTERVAL, falling back to the default
+func sampleIntervalFromEnv() time.Duration {
+	value := os.Getenv("LOG_SAMPLE_INTERVAL")
+	if value == "" {
+		return DefaultSampleInterval
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		log.Printf("[WARN] Invalid LOG_SAMPLE_INTERVAL %q, using default: %v", value, DefaultSampleInterval)
+		return DefaultSampleInterval
+	}
+	return interval
+}
+
+// GetSampleInterval returns the current sampling interval (0 = sampling disabled)
+func GetSampleInterval() time.Duration {
+	initSampleInterval()
+	return time.Duration(sampleInterval.Load())
+}
+
+// SetSampleInterval changes the sampling interval at runtime. An interval of 0
+// disables sampling so every sampled message is logged.
+func SetSampleInterval(interval time.Duration) {
+	initSampleInterval()
+	if interval < 0 {
+		interval = 0
+	}
+	sampleInterval.Store(int64(interval))
+}
+
+// ReloadSampleInterval re-reads LOG_SAMPLE_INTERVAL and applies the result.
+// Returns the new interval.
+func ReloadSampleInterval() time.Duration {
+	interval := sampleIntervalFromEnv()
+	SetSampleInterval(interval)
+	return interval
+}
+
+// WarnSampled logs a warning at most once per sample interval for the given key.
+// The first occurrence is logged immediately; repeats within the interval are
+// counted and reported as a single "(suppressed N similar)" line when it ends.
+// Use a key that identifies the kind of problem, not the individual file.
+func WarnSampled(key, format string, args ...interface{}) {
+	logSampled(LevelWarn, key, format, args...)
+}
+
+// ErrorSampled is the error-level counterpart of WarnSampled.
+func ErrorSampled(key, format string, args ...interface{}) {
+	logSampled(LevelError, key, format, args...)
+}
+
+// logSampled implements rate-limited logging for a key
+func logSampled(level LogLevel, key, format string, args ...interface{}) {
+	if GetLevel() > level {
+		return
+	}
+
+	interval := GetSampleInterval()
+	if interval == 0 {
+		log.Printf(levelPrefix(level)+format, args...)
+		return
+	}
+
+	now := time.Now()
+
+	samplesMu.Lock()
+	state, ok := samples[key]
+	if !ok || now.Sub(state.lastLogged) >= interval {
+		if !ok {
+			pruneSamples(now, interval)
+			state = &sampleState{level: level}
+			samples[key] = state
+		}
+		state.lastLogged = now
+		samplesMu.Unlock()
+
+		log.Printf(levelPrefix(level)+format, args...)
+		return
+	}
+
+	state.suppressed++
+	state.lastMessage = fmt.Sprintf(format, args...)
+	if state.timer == nil {
+		state.timer = time.AfterFunc(state.lastLogged.Add(interval).Sub(now), func() {
+			flushSample(key)
+		})
+	}
+	samplesMu.Unlock()
+}
+
+// flushSample logs the suppression summary for a key once its interval ends
+func flushSample(key string) {
+	samplesMu.Lock()
+	state, ok := samples[key]
+	if !ok || state.suppressed == 0 {
+		if ok {
+			state.timer = nil
+		}
+		samplesMu.Unlock()
+		return
+	}
+
+	suppressed := state.suppressed
+	message := state.lastMessage
+	level := state.level
+	state.suppressed = 0
+	state.lastMessage = ""
+	state.lastLogged = time.Now()
+	state.timer = nil
+	samplesMu.Unlock()
+
+	log.Printf("%s%s (suppressed %d similar)", levelPrefix(level), message, suppressed)
+}
+
+// FlushSampled immediately logs the summary for every key with suppressed
+// messages, e.g. when a batch job finishes or during shutdown.
+func FlushSampled() {
+	samplesMu.Lock()
+	keys := make([]string, 0, len(samples))
+	for key, state := range samples {
+		if state.suppressed > 0 {
+			if state.timer != nil {
+				state.timer.Stop()
+			}
+			keys = append(keys, key)
+		}
+	}
+	samplesMu.Unlock()
+
+	for _, key := range keys {
+		flushSample(key)
+	}
+}
+
+// pruneSamples drops idle keys once the map grows large. Caller must hold samplesMu.
+func pruneSamples(now time.Time, interval time.Duration) {
+	if len(samples) < maxSampleKeys {
+		return
+	}
+	for key, state := range samples {
+		if state.suppressed == 0 && state.timer == nil && now.Sub(state.lastLogged) >= interval {
+			delete(samples, key)
+		}
+	}
+}
+
+// levelPrefix returns the log line prefix for a level
+func levelPrefix(level LogLevel) string {
+	switch level {
+	case LevelDebug:
+		return "[DEBUG] "
+	case LevelInfo:
+		return "[INFO] "
+	case LevelWarn:
+		return "[WARN] "
+	default:
+		return "[ERROR] "
+	}
+}
@@ -0,0 +1,162 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a goroutine-safe buffer for capturing log output from timers
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureSampledLogs redirects log output and resets sampler state for a test
+func captureSampledLogs(t *testing.T, interval time.Duration) *syncBuffer {
+	t.Helper()
+
+	out := &syncBuffer{}
+	originalWriter := log.Writer()
+	log.SetOutput(out)
+
+	originalLevel := GetLevel()
+	originalInterval := GetSampleInterval()
+	SetLevel(LevelInfo)
+	SetSampleInterval(interval)
+
+	resetSamples := func() {
+		samplesMu.Lock()
+		for _, state := range samples {
+			if state.timer != nil {
+				state.timer.Stop()
+			}
+		}
+		samples = make(map[string]*sampleState)
+		samplesMu.Unlock()
+	}
+	resetSamples()
+
+	t.Cleanup(func() {
+		resetSamples()
+		log.SetOutput(originalWriter)
+		SetLevel(originalLevel)
+		SetSampleInterval(originalInterval)
+	})
+
+	return out
+}
+
+func TestWarnSampled_SuppressesRepeats(t *testing.T) {
+	out := captureSampledLogs(t, time.Hour)
+
+	for i := 0; i < 5; i++ {
+		WarnSampled("bad-path", "file %d has unsafe characters", i)
+	}
+
+	output := out.String()
+	if count := strings.Count(output, "[WARN]"); count != 1 {
+		t.Fatalf("Expected 1 warning before flush, got %d:\n%s", count, output)
+	}
+	if !strings.Contains(output, "file 0 has unsafe characters") {
+		t.Errorf("Expected first occurrence to be logged, got:\n%s", output)
+	}
+
+	FlushSampled()
+
+	output = out.String()
+	if !strings.Contains(output, "file 4 has unsafe characters (suppressed 4 similar)") {
+		t.Errorf("Expected suppression summary with latest message, got:\n%s", output)
+	}
+}
+
+func TestWarnSampled_KeysAreIndependent(t *testing.T) {
+	out := captureSampledLogs(t, time.Hour)
+
+	WarnSampled("codec", "missing codec")
+	WarnSampled("permissions", "permission denied")
+	WarnSampled("codec", "missing codec")
+
+	output := out.String()
+	if !strings.Contains(output, "missing codec") || !strings.Contains(output, "permission denied") {
+		t.Errorf("Expected one line per key, got:\n%s", output)
+	}
+	if count := strings.Count(output, "[WARN]"); count != 2 {
+		t.Errorf("Expected 2 warnings, got %d:\n%s", count, output)
+	}
+}
+
+func TestWarnSampled_SummaryAfterInterval(t *testing.T) {
+	out := captureSampledLogs(t, 50*time.Millisecond)
+
+	WarnSampled("flood", "first")
+	WarnSampled("flood", "second")
+	WarnSampled("flood", "third")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if strings.Contains(out.String(), "(suppressed 2 similar)") {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Expected summary to be logged when the interval ended, got:\n%s", out.String())
+}
+
+func TestWarnSampled_DisabledLogsEverything(t *testing.T) {
+	out := captureSampledLogs(t, 0)
+
+	for i := 0; i < 3; i++ {
+		WarnSampled("key", "message %d", i)
+	}
+
+	if count := strings.Count(out.String(), "[WARN]"); count != 3 {
+		t.Errorf("Expected all 3 warnings with sampling disabled, got %d", count)
+	}
+}
+
+func TestWarnSampled_RespectsLevel(t *testing.T) {
+	out := captureSampledLogs(t, time.Hour)
+	SetLevel(LevelError)
+
+	WarnSampled("key", "should not appear")
+	ErrorSampled("other", "should appear")
+
+	output := out.String()
+	if strings.Contains(output, "should not appear") {
+		t.Error("Expected warning to be filtered at error level")
+	}
+	if !strings.Contains(output, "[ERROR] should appear") {
+		t.Errorf("Expected error to be logged, got:\n%s", output)
+	}
+}
+
+func TestReloadSampleInterval(t *testing.T) {
+	original := GetSampleInterval()
+	defer SetSampleInterval(original)
+
+	t.Setenv("LOG_SAMPLE_INTERVAL", "30s")
+	if got := ReloadSampleInterval(); got != 30*time.Second {
+		t.Errorf("ReloadSampleInterval() = %v, want 30s", got)
+	}
+
+	t.Setenv("LOG_SAMPLE_INTERVAL", "bogus")
+	if got := ReloadSampleInterval(); got != DefaultSampleInterval {
+		t.Errorf("ReloadSampleInterval() with invalid value = %v, want default", got)
+	}
+}
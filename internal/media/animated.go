@@ -0,0 +1,50 @@
+package media
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"media-viewer/internal/logging"
+	"media-viewer/internal/mediatypes"
+)
+
+// SetAnimatedDetection enables recording whether each GIF, PNG and WebP image
+// is animated when its thumbnail is generated, so listings can mark it
+func (t *ThumbnailGenerator) SetAnimatedDetection(enabled bool) {
+	t.animatedDetection.Store(enabled)
+}
+
+// mayBeAnimated reports whether an image's format supports animation
+func mayBeAnimated(filePath string) bool {
+	switch mediatypes.NormalizeExt(filePath) {
+	case ".gif", ".png", ".apng", ".webp":
+		return true
+	default:
+		return false
+	}
+}
+
+// storeAnimated records whether an image is animated against its index
+// path, from the page count libvips reported when the image was decoded for
+// its thumbnail. Nothing is recorded if the count isn't known. Failures are
+// logged and otherwise ignored, like storePalette.
+func (t *ThumbnailGenerator) storeAnimated(ctx context.Context, filePath string, pages int) {
+	if t.withoutDatabase("animated image detection") {
+		return
+	}
+
+	relPath, err := filepath.Rel(t.mediaDir, filePath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return
+	}
+
+	if pages == 0 {
+		logging.Debug("Page count of %s unknown, not recording whether it is animated", relPath)
+		return
+	}
+
+	if err := t.db.SetFileAnimated(ctx, relPath, pages > 1); err != nil {
+		logging.Debug("Failed to store animated flag for %s: %v", relPath, err)
+	}
+}
@@ -0,0 +1,154 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+// gifBytes encodes a GIF with the given number of frames
+func gifBytes(t *testing.T, frames int) []byte {
+	t.Helper()
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{}
+	for i := range frames {
+		frame := image.NewPaletted(image.Rect(0, 0, 8, 8), palette)
+		frame.SetColorIndex(i%8, 0, 1)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatalf("failed to encode GIF: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestMayBeAnimated(t *testing.T) {
+	for path, want := range map[string]bool{
+		"a.gif": true, "b.PNG": true, "c.apng": true, "d.webp": true,
+		"e.jpg": false, "f.heic": false,
+	} {
+		if got := mayBeAnimated(path); got != want {
+			t.Errorf("mayBeAnimated(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestAnimatedDetectionWithVipsIfAvailable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	if !IsVipsAvailable() {
+		if err := InitVips(); err != nil {
+			t.Skip("libvips not available, which reports the page count")
+		}
+	}
+
+	mediaDir := t.TempDir()
+	db, _, err := database.New(context.Background(), filepath.Join(t.TempDir(), "animated_test.db"), &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	for name, frames := range map[string]int{"dance.gif": 3, "still.gif": 1} {
+		if err := os.WriteFile(filepath.Join(mediaDir, name), gifBytes(t, frames), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		upsertTestFile(ctx, t, db, database.MediaFile{
+			Path:    name,
+			Name:    name,
+			Type:    database.FileTypeImage,
+			ModTime: time.Now().Add(-time.Hour),
+		})
+	}
+
+	// The page count comes from the libvips decode
+	if _, _, err := loadImageWithVips(filepath.Join(mediaDir, "dance.gif"), 8, 8); err != nil {
+		t.Skipf("libvips can't load GIFs: %v", err)
+	}
+
+	animated := func() map[string]bool {
+		t.Helper()
+		files, err := db.GetMediaInDirectory(ctx, "", database.SortByName, database.SortAsc)
+		if err != nil {
+			t.Fatalf("GetMediaInDirectory failed: %v", err)
+		}
+		flags := make(map[string]bool)
+		for _, file := range files {
+			flags[file.Path] = file.Animated
+		}
+		return flags
+	}
+
+	// Off by default
+	gen := NewThumbnailGenerator(t.TempDir(), mediaDir, true, db, time.Hour, nil)
+	if _, err := gen.GetThumbnail(ctx, filepath.Join(mediaDir, "dance.gif"), database.FileTypeImage); err != nil {
+		t.Fatalf("GetThumbnail failed: %v", err)
+	}
+	if got := animated(); got["dance.gif"] {
+		t.Errorf("Expected no detection while disabled, got %v", got)
+	}
+
+	gen = NewThumbnailGenerator(t.TempDir(), mediaDir, true, db, time.Hour, nil)
+	gen.SetAnimatedDetection(true)
+	for _, name := range []string{"dance.gif", "still.gif"} {
+		if _, err := gen.GetThumbnail(ctx, filepath.Join(mediaDir, name), database.FileTypeImage); err != nil {
+			t.Fatalf("GetThumbnail(%s) failed: %v", name, err)
+		}
+	}
+	if got := animated(); !got["dance.gif"] || got["still.gif"] {
+		t.Errorf("Expected only dance.gif to be animated, got %v", got)
+	}
+}
+
+func TestStoreAnimated(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	mediaDir := t.TempDir()
+	db, _, err := database.New(context.Background(), filepath.Join(t.TempDir(), "animated_test.db"), &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	upsertTestFile(ctx, t, db, database.MediaFile{Path: "dance.gif", Name: "dance.gif", Type: database.FileTypeImage, ModTime: time.Now()})
+	gen := NewThumbnailGenerator(t.TempDir(), mediaDir, true, db, time.Hour, nil)
+
+	animated := func() bool {
+		t.Helper()
+		files, err := db.GetMediaInDirectory(ctx, "", database.SortByName, database.SortAsc)
+		if err != nil || len(files) != 1 {
+			t.Fatalf("GetMediaInDirectory = %+v, %v", files, err)
+		}
+		return files[0].Animated
+	}
+
+	path := filepath.Join(mediaDir, "dance.gif")
+	for _, step := range []struct {
+		pages int
+		want  bool
+	}{
+		{3, true},
+		{0, true}, // Unknown: left as it was
+		{1, false},
+	} {
+		gen.storeAnimated(ctx, path, step.pages)
+		if got := animated(); got != step.want {
+			t.Errorf("After storing %d pages, animated = %v, want %v", step.pages, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"media-viewer/internal/logging"
+	"media-viewer/internal/mediatypes"
+)
+
+// nativeImageExtensions are the image formats Go's registered decoders read,
+// so their thumbnails need neither libvips nor FFmpeg
+var nativeImageExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+	".bmp":  true,
+	".tiff": true,
+	".tif":  true,
+	".webp": true,
+}
+
+// ffmpegImageDecoders lists, for image formats Go can't decode, the FFmpeg
+// decoders generateImageWithFFmpeg can read them with. Any one will do.
+var ffmpegImageDecoders = map[string][]string{
+	".heic": {"hevc"},
+	".heif": {"hevc"},
+	".avif": {"libdav1d", "libaom-av1", "av1"},
+	".jxl":  {"libjxl"},
+	".ico":  {"bmp"},
+	".svg":  {"librsvg"},
+	".dng":  {"tiff"},
+}
+
+var (
+	ffmpegDecodersOnce sync.Once
+	ffmpegDecoders     map[string]bool
+)
+
+// FFmpegDecoders returns the decoders of the FFmpeg on PATH, or nil if there
+// is none. The list is read once, as the binary doesn't change while running.
+func FFmpegDecoders() map[string]bool {
+	ffmpegDecodersOnce.Do(func() {
+		ffmpegPath, err := exec.LookPath("ffmpeg")
+		if err != nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		// #nosec G204 -- ffmpegPath is from exec.LookPath and the arguments are fixed
+		out, err := exec.CommandContext(ctx, ffmpegPath, "-hide_banner", "-decoders").Output()
+		if err != nil {
+			logging.Warn("Failed to list FFmpeg decoders: %v", err)
+			return
+		}
+		ffmpegDecoders = parseFFmpegDecoders(out)
+	})
+	return ffmpegDecoders
+}
+
+// parseFFmpegDecoders reads the decoder names from the output of
+// "ffmpeg -decoders": after a legend ending in a dashed line, one decoder per
+// line as its capability flags, name and description.
+func parseFFmpegDecoders(out []byte) map[string]bool {
+	decoders := make(map[string]bool)
+	listing := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if !listing {
+			listing = len(fields) == 1 && strings.HasPrefix(fields[0], "---")
+			continue
+		}
+		if len(fields) >= 2 {
+			decoders[fields[1]] = true
+		}
+	}
+	return decoders
+}
+
+// ThumbnailImageFormats returns the image extensions, sorted, whose
+// thumbnails can be generated with the decoders available: Go's own, or
+// those of the FFmpeg on PATH for the rest.
+func ThumbnailImageFormats() []string {
+	return thumbnailImageFormats(FFmpegDecoders())
+}
+
+// thumbnailImageFormats returns the image extensions, sorted, that Go or one
+// of the given FFmpeg decoders can read
+func thumbnailImageFormats(decoders map[string]bool) []string {
+	var formats []string
+	for ext := range mediatypes.ImageExtensions {
+		if nativeImageExtensions[ext] || slices.ContainsFunc(ffmpegImageDecoders[ext], func(name string) bool { return decoders[name] }) {
+			formats = append(formats, ext)
+		}
+	}
+	slices.Sort(formats)
+	return formats
+}
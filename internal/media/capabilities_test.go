@@ -0,0 +1,57 @@
+package media
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseFFmpegDecoders(t *testing.T) {
+	out := []byte(`Decoders:
+ V..... = Video
+ A..... = Audio
+ S..... = Subtitle
+ .F.... = Frame-level multithreading
+ ------
+ V....D hevc                 HEVC (High Efficiency Video Coding)
+ V....D libdav1d             dav1d AV1 decoder by VideoLAN/VLC (codec av1)
+ A....D aac                  AAC (Advanced Audio Coding)
+`)
+
+	decoders := parseFFmpegDecoders(out)
+
+	for _, name := range []string{"hevc", "libdav1d", "aac"} {
+		if !decoders[name] {
+			t.Errorf("Expected decoder %q in %v", name, decoders)
+		}
+	}
+	if decoders["V....."] || decoders["="] || len(decoders) != 3 {
+		t.Errorf("Expected only the listed decoders, got %v", decoders)
+	}
+}
+
+func TestThumbnailImageFormats(t *testing.T) {
+	native := thumbnailImageFormats(nil)
+	for _, ext := range []string{".jpg", ".png", ".webp", ".tif"} {
+		if !slices.Contains(native, ext) {
+			t.Errorf("Expected %s without FFmpeg, got %v", ext, native)
+		}
+	}
+	for _, ext := range []string{".heic", ".avif", ".cr2"} {
+		if slices.Contains(native, ext) {
+			t.Errorf("Expected no %s without FFmpeg, got %v", ext, native)
+		}
+	}
+	if !slices.IsSorted(native) {
+		t.Errorf("Expected sorted formats, got %v", native)
+	}
+
+	withFFmpeg := thumbnailImageFormats(map[string]bool{"hevc": true, "av1": true})
+	for _, ext := range []string{".heic", ".heif", ".avif"} {
+		if !slices.Contains(withFFmpeg, ext) {
+			t.Errorf("Expected %s with HEVC and AV1 decoders, got %v", ext, withFFmpeg)
+		}
+	}
+	if slices.Contains(withFFmpeg, ".jxl") {
+		t.Errorf("Expected no .jxl without libjxl, got %v", withFFmpeg)
+	}
+}
@@ -5,15 +5,33 @@
 //
 // The [ThumbnailGenerator] creates and caches thumbnail images for media files:
 //   - Images: Resized using libvips (preferred) or imaging library with auto-orientation
-//   - Videos: Frame extraction at 1 second using FFmpeg
-//   - Folders: Composite grid of up to 4 contained images/videos
+//   - Videos: Frame extraction using FFmpeg (representative frame, fixed offset, or percentage; see SetVideoSeekStrategy)
+//   - Folders: Composite grid of up to 4 contained images/videos (optionally several frames per video; see SetFolderVideoFrames)
 //
 // For JPEG images, libvips provides decode-time shrinking which dramatically
 // reduces memory usage by never loading the full-resolution image into memory.
 //
 // Thumbnails are cached to disk with MD5-hashed filenames. Each thumbnail
 // has an associated .meta sidecar file tracking the source path for orphan
-// detection and cleanup.
+// detection and cleanup. The sidecar also records the dimensions the
+// thumbnail came out at ([ThumbnailGenerator.GetThumbnailDimensions]).
+//
+// With deduplication enabled ([ThumbnailGenerator.SetDeduplication]), image and
+// video thumbnails are stored once per source content hash in a content/
+// subdirectory, and the .meta sidecar of each source path references the
+// shared file. Orphan cleanup reference-counts shared thumbnails across all
+// sidecars before deleting them.
+//
+// Thumbnails can also be served as WebP or AVIF ([ThumbnailGenerator.NegotiateFormat],
+// [ThumbnailGenerator.GetThumbnailInFormat]). These variants are encoded from
+// the cached thumbnail with libvips on first request and cached next to it
+// under the same hash with the format's extension, sharing its .meta sidecar.
+// A variant older than its thumbnail is encoded again.
+//
+// The cache can be capped in size ([ThumbnailGenerator.SetCacheLimit]). Reads
+// then move each file's access time forward, and
+// [ThumbnailGenerator.EnforceCacheLimit], run with the cache metrics update,
+// evicts the least recently read files, skipping any being written.
 //
 // # Incremental Generation
 //
@@ -21,6 +39,9 @@
 //   - Listens for index completion notifications
 //   - Only processes files changed since the last run
 //   - Automatically regenerates folder thumbnails when contents change
+//   - Regenerates thumbnails older than their source; with
+//     [ThumbnailGenerator.SetStaleWhileRevalidate] the old thumbnail is served
+//     until the background regeneration replaces it
 //   - Cleans up orphaned thumbnails for deleted files
 //   - Removes legacy thumbnails without meta file tracking
 //
@@ -61,6 +82,17 @@
 //   - Image decoding for formats not supported by Go's image package
 //   - 30 second timeout per FFmpeg operation
 //
+// # Scrub Previews
+//
+// [ThumbnailGenerator.GetScrubVTT] and [ThumbnailGenerator.GetScrubSprite]
+// serve the previews shown while scrubbing through a video: a JPEG sprite
+// sheet of frames every [ScrubFrameInterval] (at most [MaxScrubFrames]),
+// rendered in one FFmpeg run with the fps and tile filters, and a WebVTT
+// track mapping each frame's time range to its region of the sheet. Both are
+// cached in a subdirectory of the thumbnail cache, named after the video's
+// path and modification time, and removed with its thumbnail when the video
+// leaves the index.
+//
 // # Background Processing
 //
 // The generator runs background goroutines for:
@@ -69,6 +101,12 @@
 //   - Periodic full scans as a fallback (configurable interval)
 //   - Cache metrics updates every minute
 //
+// Start and Stop move the generator between stopped and running, and can be
+// repeated. Stop signals a run in progress, waits for it up to a grace period
+// ([ThumbnailGenerator.SetStopGracePeriod]) and leaves its stats marked as
+// interrupted; the next Start clears them. Close stops the generator for good
+// and releases libvips.
+//
 // # Metrics
 //
 // Thumbnail operations are instrumented with Prometheus metrics:
@@ -91,7 +129,7 @@
 //	    memMonitor,
 //	)
 //	thumbGen.Start()
-//	defer thumbGen.Stop() // Cleans up libvips resources
+//	defer thumbGen.Close() // Stops generation and cleans up libvips resources
 //
 //	// Get or generate a thumbnail
 //	data, err := thumbGen.GetThumbnail(ctx, "/media/photo.jpg", database.FileTypeImage)
@@ -0,0 +1,108 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"os/exec"
+	"time"
+
+	"media-viewer/internal/logging"
+	"media-viewer/internal/metrics"
+)
+
+// SetFolderVideoFrames enables sampling several frames per video for folder
+// composites. Each video contributes frames from across its duration instead
+// of its single thumbnail frame, and cells that images and videos leave empty
+// are filled with further frames. Costs an FFmpeg run per frame.
+func (t *ThumbnailGenerator) SetFolderVideoFrames(enabled bool) {
+	t.folderVideoFrames.Store(enabled)
+}
+
+// framesPerVideo spreads the composite cells left free by the other
+// components over the videos, one extra frame per video in turn. Every video
+// gets at least one frame.
+func framesPerVideo(videos, freeCells int) []int {
+	counts := make([]int, videos)
+	for i := range counts {
+		counts[i] = 1
+	}
+	for i := 0; i < freeCells && videos > 0; i++ {
+		counts[i%videos]++
+	}
+	return counts
+}
+
+// extractVideoFrames extracts count frames spread evenly across a video, at
+// 1/(count+1), 2/(count+1), ... of its duration. Falls back to the regular
+// video thumbnail if the duration is unknown; frames that fail are skipped.
+func (t *ThumbnailGenerator) extractVideoFrames(ctx context.Context, filePath string, count int) ([]image.Image, error) {
+	duration, err := t.getVideoDuration(ctx, filePath)
+	if err != nil {
+		logging.Debug("Could not probe video duration for %s: %v, using its thumbnail frame", filePath, err)
+		img, err := t.generateVideoThumbnail(ctx, filePath)
+		if err != nil {
+			return nil, err
+		}
+		return []image.Image{img}, nil
+	}
+
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+	if err := validateFilePath(filePath); err != nil {
+		return nil, fmt.Errorf("invalid file path for ffmpeg: %w", err)
+	}
+
+	frames := make([]image.Image, 0, count)
+	for i := range count {
+		if err := ctx.Err(); err != nil {
+			break
+		}
+
+		seek := VideoSeekStrategy{Mode: VideoSeekPercent, Percent: float64(i+1) * 100 / float64(count+1)}
+		img, err := extractFrame(ctx, ffmpegPath, seek.ffmpegArgs(filePath, duration))
+		if err != nil {
+			logging.Debug("Failed to extract frame at %s of %s: %v", seek, filePath, err)
+			continue
+		}
+		frames = append(frames, img)
+	}
+
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames could be extracted from %s", filePath)
+	}
+	return frames, nil
+}
+
+// extractFrame runs FFmpeg with arguments that write a single PNG frame to
+// stdout and decodes it
+func extractFrame(ctx context.Context, ffmpegPath string, args []string) (image.Image, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	// #nosec G204 -- the file path is from the indexed media library and validated by the caller
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	metrics.ThumbnailFFmpegDuration.WithLabelValues("video").Observe(time.Since(start).Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w, stderr: %s", err, stderr.String())
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("ffmpeg produced no output")
+	}
+
+	img, _, err := image.Decode(&stdout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ffmpeg output: %w", err)
+	}
+	return img, nil
+}
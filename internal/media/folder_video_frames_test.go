@@ -0,0 +1,69 @@
+package media
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFramesPerVideo(t *testing.T) {
+	tests := []struct {
+		name      string
+		videos    int
+		freeCells int
+		expected  []int
+	}{
+		{"single video fills grid", 1, 3, []int{4}},
+		{"two videos share free cells", 2, 2, []int{2, 2}},
+		{"uneven split favors first videos", 2, 1, []int{2, 1}},
+		{"full grid", 4, 0, []int{1, 1, 1, 1}},
+		{"no videos", 0, 3, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := framesPerVideo(tt.videos, tt.freeCells); !slices.Equal(got, tt.expected) {
+				t.Errorf("framesPerVideo(%d, %d) = %v, want %v", tt.videos, tt.freeCells, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractVideoFrames(t *testing.T) {
+	argsLog := installMockFFmpeg(t, "100.000000")
+	gen := &ThumbnailGenerator{}
+
+	frames, err := gen.extractVideoFrames(context.Background(), "/media/clip.mp4", 3)
+	if err != nil {
+		t.Fatalf("extractVideoFrames failed: %v", err)
+	}
+	if len(frames) != 3 {
+		t.Fatalf("Expected 3 frames, got %d", len(frames))
+	}
+
+	data, err := os.ReadFile(argsLog)
+	if err != nil {
+		t.Fatalf("Failed to read ffmpeg args: %v", err)
+	}
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for i, seek := range []string{"00:00:25.000", "00:00:50.000", "00:01:15.000"} {
+		if i >= len(calls) || !strings.HasPrefix(calls[i], "-ss "+seek+" ") {
+			t.Errorf("Expected frame %d at %s, got ffmpeg calls %q", i, seek, calls)
+		}
+	}
+}
+
+func TestExtractVideoFramesUnknownDuration(t *testing.T) {
+	installMockFFmpeg(t, "N/A")
+	gen := &ThumbnailGenerator{}
+
+	frames, err := gen.extractVideoFrames(context.Background(), "/media/live.ts", 4)
+	if err != nil {
+		t.Fatalf("extractVideoFrames failed: %v", err)
+	}
+	if len(frames) != 1 {
+		t.Errorf("Expected the single thumbnail frame without a duration, got %d frames", len(frames))
+	}
+}
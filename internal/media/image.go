@@ -4,11 +4,12 @@ import (
 	"fmt"
 	"image"
 	"image/jpeg"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"media-viewer/internal/logging"
+	"media-viewer/internal/mediatypes"
 
 	// Image format decoders
 	_ "image/gif"
@@ -37,8 +38,16 @@ const (
 )
 
 // LoadJPEGDownsampled loads a JPEG with optimized memory usage
-// Uses JPEG-specific DCT-based decoding for better memory efficiency
+// Uses JPEG-specific DCT-based decoding for better memory efficiency.
+// The target size is of the image as stored; its EXIF orientation is applied
+// after resizing, which may swap the result's width and height.
 func LoadJPEGDownsampled(path string, targetWidth, targetHeight int) (image.Image, error) {
+	return loadJPEGDownsampled(path, nil, targetWidth, targetHeight)
+}
+
+// loadJPEGDownsampled implements LoadJPEGDownsampled. The header is read from
+// the file unless the caller already has it.
+func loadJPEGDownsampled(path string, header *imageHeader, targetWidth, targetHeight int) (image.Image, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open JPEG: %w", err)
@@ -49,24 +58,28 @@ func LoadJPEGDownsampled(path string, targetWidth, targetHeight int) (image.Imag
 		}
 	}()
 
-	// Decode config to get dimensions without loading full image
-	config, err := jpeg.DecodeConfig(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode JPEG config: %w", err)
+	// Dimensions without loading the full image, and the orientation
+	// jpeg.Decode ignores, to apply it ourselves
+	if header == nil {
+		h, err := decodeImageHeader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode JPEG config: %w", err)
+		}
+		header = &h
 	}
+	orientation := header.Orientation
 
-	// Seek back to start for actual decode
-	if _, err := file.Seek(0, 0); err != nil {
-		return nil, fmt.Errorf("failed to seek: %w", err)
+	if orientation > 1 {
+		logging.Debug("JPEG %s has EXIF orientation %s", filepath.Base(path), describeOrientation(orientation))
 	}
 
 	// Calculate if we should use an intermediate downscale
 	// For very large JPEGs, we decode and immediately downscale aggressively
-	if config.Width > targetWidth*4 || config.Height > targetHeight*4 {
+	if header.Width > targetWidth*4 || header.Height > targetHeight*4 {
 		// For 4x+ larger images, use two-stage resize
 		// Stage 1: Fast resize to 2x target (reduces memory before Lanczos)
 		logging.Debug("JPEG two-stage resize %s: %dx%d -> intermediate -> %dx%d",
-			filepath.Base(path), config.Width, config.Height, targetWidth, targetHeight)
+			filepath.Base(path), header.Width, header.Height, targetWidth, targetHeight)
 
 		img, err := jpeg.Decode(file)
 		if err != nil {
@@ -80,7 +93,7 @@ func LoadJPEGDownsampled(path string, targetWidth, targetHeight int) (image.Imag
 		intermediate := imaging.Resize(img, intermediateWidth, intermediateHeight, imaging.Box)
 
 		// Stage 2: High-quality resize to final size
-		return imaging.Resize(intermediate, targetWidth, targetHeight, imaging.Lanczos), nil
+		return applyOrientation(imaging.Resize(intermediate, targetWidth, targetHeight, imaging.Lanczos), orientation), nil
 	}
 
 	// For smaller images, single-stage decode and resize
@@ -89,21 +102,33 @@ func LoadJPEGDownsampled(path string, targetWidth, targetHeight int) (image.Imag
 		return nil, fmt.Errorf("failed to decode JPEG: %w", err)
 	}
 
-	return imaging.Resize(img, targetWidth, targetHeight, imaging.Lanczos), nil
+	return applyOrientation(imaging.Resize(img, targetWidth, targetHeight, imaging.Lanczos), orientation), nil
 }
 
 // LoadImageConstrained loads an image, downscaling if it exceeds size limits
 // This prevents OOM when processing very large images
 func LoadImageConstrained(path string, maxDimension, maxPixels int) (image.Image, error) {
-	// First, try to get image dimensions without fully decoding
-	dimensions, err := GetImageDimensions(path)
+	header, err := readImageHeader(path)
 	if err != nil {
 		logging.Debug("Could not get image dimensions for %s: %v, loading with constraints", path, err)
+	}
+	img, _, err := loadImageConstrained(path, header, maxDimension, maxPixels, false)
+	return img, err
+}
+
+// loadImageConstrained implements LoadImageConstrained for an image whose
+// header was read beforehand, or nil if it couldn't be. With countPages, an
+// image within the limits is loaded with libvips too, if available, so the
+// number of pages (frames) it reports is returned with the image; pages is
+// 0 whenever another loader is used.
+func loadImageConstrained(path string, header *imageHeader, maxDimension, maxPixels int, countPages bool) (img image.Image, pages int, err error) {
+	if header == nil {
 		// Fall back to loading with auto-orientation and hope for the best
-		return imaging.Open(path, imaging.AutoOrientation(true))
+		img, err = imaging.Open(path, imaging.AutoOrientation(true))
+		return img, 0, err
 	}
 
-	width, height := dimensions.Width, dimensions.Height
+	width, height := header.Width, header.Height
 	pixels := width * height
 
 	logging.Debug("Image %s dimensions: %dx%d (%d pixels)", path, width, height, pixels)
@@ -112,8 +137,14 @@ func LoadImageConstrained(path string, maxDimension, maxPixels int) (image.Image
 	needsConstraint := width > maxDimension || height > maxDimension || pixels > maxPixels
 
 	if !needsConstraint {
+		if countPages && IsVipsAvailable() {
+			if img, pages, err := loadImageWithVips(path, width, height); err == nil {
+				return img, pages, nil
+			}
+		}
 		// Image is within limits, load normally
-		return imaging.Open(path, imaging.AutoOrientation(true))
+		img, err = imaging.Open(path, imaging.AutoOrientation(true))
+		return img, 0, err
 	}
 
 	// Calculate target dimensions for constrained images
@@ -143,33 +174,33 @@ func LoadImageConstrained(path string, maxDimension, maxPixels int) (image.Image
 	// Try libvips first for all supported formats (most memory efficient with decode-time shrinking)
 	// vips supports: JPEG, PNG, WebP, HEIF/HEIC, GIF, TIFF, SVG, PDF, JP2K, JXL, and more
 	if IsVipsAvailable() {
-		img, err := LoadImageWithVips(path, targetWidth, targetHeight)
+		img, pages, err := loadImageWithVips(path, targetWidth, targetHeight)
 		if err == nil {
 			logging.Debug("Successfully loaded %s using libvips", filepath.Base(path))
-			return img, nil
+			return img, pages, nil
 		}
 		logging.Debug("libvips loading failed for %s: %v, falling back to standard loader", filepath.Base(path), err)
 	}
 
 	// For JPEG files specifically, try optimized JPEG two-stage loading as fallback
-	ext := strings.ToLower(filepath.Ext(path))
+	ext := mediatypes.NormalizeExt(path)
 	if ext == jpegExt || ext == jpegExtLong {
-		img, err := LoadJPEGDownsampled(path, targetWidth, targetHeight)
+		img, err := loadJPEGDownsampled(path, header, targetWidth, targetHeight)
 		if err == nil {
-			return img, nil
+			return img, 0, nil
 		}
 		logging.Debug("JPEG optimized loading failed for %s: %v, falling back to standard method", path, err)
 	}
 
 	// Load and resize in one operation using imaging library
 	// Note: imaging.Open still loads full image, but we resize immediately
-	img, err := imaging.Open(path, imaging.AutoOrientation(true))
+	img, err = imaging.Open(path, imaging.AutoOrientation(true))
 	if err != nil {
-		return nil, fmt.Errorf("failed to open image: %w", err)
+		return nil, 0, fmt.Errorf("failed to open image: %w", err)
 	}
 
 	// Resize to constrained dimensions
-	return imaging.Resize(img, targetWidth, targetHeight, imaging.Lanczos), nil
+	return imaging.Resize(img, targetWidth, targetHeight, imaging.Lanczos), 0, nil
 }
 
 // ImageDimensions holds image width and height
@@ -178,6 +209,50 @@ type ImageDimensions struct {
 	Height int
 }
 
+// imageHeader is what is read from an image file before it is decoded
+type imageHeader struct {
+	Width       int // As stored, before orientation
+	Height      int
+	Orientation int // EXIF orientation (1-8) of a JPEG; 0 if it has none or isn't a JPEG
+}
+
+// readImageHeader reads the header of an image file
+func readImageHeader(path string) (*imageHeader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			logging.Warn("failed to close image file %s: %v", path, err)
+		}
+	}()
+
+	header, err := decodeImageHeader(file)
+	if err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// decodeImageHeader reads the dimensions and, of a JPEG, the EXIF orientation
+// of an open image file, and seeks back to its start for decoding
+func decodeImageHeader(file *os.File) (imageHeader, error) {
+	config, format, err := image.DecodeConfig(file)
+	if err != nil {
+		return imageHeader{}, err
+	}
+	header := imageHeader{Width: config.Width, Height: config.Height}
+	if format == "jpeg" {
+		// Unreadable EXIF leaves the image as stored
+		header.Orientation, _ = jpegOrientation(file)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return imageHeader{}, fmt.Errorf("failed to seek: %w", err)
+	}
+	return header, nil
+}
+
 // GetImageDimensions returns image dimensions without fully decoding the image
 func GetImageDimensions(path string) (*ImageDimensions, error) {
 	file, err := os.Open(path)
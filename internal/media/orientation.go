@@ -0,0 +1,176 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"strconv"
+
+	"media-viewer/internal/exif"
+	"media-viewer/internal/metrics"
+
+	"github.com/disintegration/imaging"
+)
+
+// Image decoders reported by decodeImage, in the order they are tried
+const (
+	decoderConstrained = "constrained" // LoadImageConstrained: libvips, JPEG downsampling or imaging
+	decoderImaging     = "imaging"
+	decoderFFmpeg      = "ffmpeg"
+)
+
+// orientationNames describe the EXIF orientation values
+var orientationNames = map[int]string{
+	0: "none",
+	1: "normal",
+	2: "mirror horizontal",
+	3: "rotate 180",
+	4: "mirror vertical",
+	5: "mirror horizontal and rotate 270 CW",
+	6: "rotate 90 CW",
+	7: "mirror horizontal and rotate 90 CW",
+	8: "rotate 270 CW",
+}
+
+// OrientationReport describes how a thumbnail's source image is oriented and
+// whether the thumbnail decoders corrected it.
+type OrientationReport struct {
+	Path            string `json:"path"`
+	Format          string `json:"format"`
+	Orientation     int    `json:"orientation"` // EXIF value 1-8; 0 if the file has none
+	OrientationName string `json:"orientationName"`
+	NeedsCorrection bool   `json:"needsCorrection"`
+	Corrected       bool   `json:"corrected"`
+	Decoder         string `json:"decoder"`
+	SourceWidth     int    `json:"sourceWidth"` // As stored, before orientation
+	SourceHeight    int    `json:"sourceHeight"`
+	DecodedWidth    int    `json:"decodedWidth"`
+	DecodedHeight   int    `json:"decodedHeight"`
+}
+
+// InspectOrientation decodes an image the way its thumbnail is generated and
+// reports its EXIF orientation and whether the decoded image was corrected.
+// Nothing is cached.
+func (t *ThumbnailGenerator) InspectOrientation(ctx context.Context, filePath string) (*OrientationReport, error) {
+	decoded, err := t.decodeSourceImage(ctx, filePath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	report := checkOrientation(filePath, decoded)
+	if decoded.header != nil {
+		report.SourceWidth, report.SourceHeight = decoded.header.Width, decoded.header.Height
+	}
+	return report, nil
+}
+
+// checkOrientation reports whether a decoded image was corrected for the
+// orientation read from its header
+func checkOrientation(filePath string, decoded decodedImage) *OrientationReport {
+	var orientation int
+	if decoded.header != nil {
+		orientation = decoded.header.Orientation
+	}
+
+	report := &OrientationReport{
+		Path:            filePath,
+		Format:          detectImageFormat(filePath),
+		Orientation:     orientation,
+		OrientationName: orientationNames[orientation],
+		NeedsCorrection: orientation > 1,
+		Decoder:         decoded.decoder,
+		DecodedWidth:    decoded.img.Bounds().Dx(),
+		DecodedHeight:   decoded.img.Bounds().Dy(),
+	}
+	if !report.NeedsCorrection {
+		return report
+	}
+
+	// Orientations 5-8 swap width and height, so the result shows whether they
+	// were applied. Mirroring and 180° turns can't be told from the result;
+	// every decoder but ffmpeg, whose handling varies by version, applies them.
+	report.Corrected = decoded.decoder != decoderFFmpeg
+	if header := decoded.header; orientation >= 5 && header.Width != header.Height {
+		report.Corrected = (header.Width > header.Height) != (report.DecodedWidth > report.DecodedHeight)
+	}
+	return report
+}
+
+// recordOrientation counts a thumbnail that needed its orientation corrected
+func recordOrientation(report *OrientationReport) {
+	if !report.NeedsCorrection {
+		return
+	}
+	result := "corrected"
+	if !report.Corrected {
+		result = "uncorrected"
+	}
+	metrics.ThumbnailOrientationCorrections.WithLabelValues(strconv.Itoa(report.Orientation), result).Inc()
+}
+
+// ReadOrientation returns the EXIF orientation (1-8) of a JPEG file, or 0 if
+// it has none. Other formats aren't read and return 0; libvips carries their
+// orientation through to the thumbnail decoders itself.
+func ReadOrientation(filePath string) (int, error) {
+	if detectImageFormat(filePath) != formatJPEG {
+		return 0, nil
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return jpegOrientation(f)
+}
+
+// jpegOrientation returns the EXIF orientation (1-8) of a JPEG, or 0 if it
+// has none. EXIF data that can't be read is taken as none, leaving the image
+// as stored.
+func jpegOrientation(r io.ReaderAt) (int, error) {
+	var soi [2]byte
+	if _, err := r.ReadAt(soi[:], 0); err != nil {
+		return 0, err
+	}
+	if soi != [2]byte{0xFF, 0xD8} {
+		return 0, errors.New("not a JPEG file")
+	}
+
+	meta, err := exif.Decode(r)
+	if err != nil {
+		return 0, nil
+	}
+	return meta.Orientation, nil
+}
+
+// applyOrientation transforms an image decoded without regard for its EXIF
+// orientation so that it displays upright
+func applyOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.Transpose(img)
+	case 6:
+		return imaging.Rotate270(img)
+	case 7:
+		return imaging.Transverse(img)
+	case 8:
+		return imaging.Rotate90(img)
+	default:
+		return img
+	}
+}
+
+// describeOrientation formats an orientation for log messages
+func describeOrientation(orientation int) string {
+	return fmt.Sprintf("%d (%s)", orientation, orientationNames[orientation])
+}
@@ -0,0 +1,184 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// exifSegment builds an APP1 segment whose IFD0 holds only an orientation tag
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 0x2A)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112) // Orientation
+	order.PutUint16(tiff[12:], 3)      // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// writeOrientedJPEG writes a width x height JPEG, red on its left half, with
+// the given EXIF orientation (none if 0)
+func writeOrientedJPEG(t *testing.T, path string, width, height int, orientation uint16) {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			c := color.RGBA{B: 255, A: 255}
+			if x < width/2 {
+				c = color.RGBA{R: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+
+	data := buf.Bytes()
+	if orientation != 0 {
+		// The EXIF segment goes right after the start-of-image marker
+		data = append(append(append([]byte{}, data[:2]...), exifSegment(binary.BigEndian, orientation)...), data[2:]...)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write JPEG: %v", err)
+	}
+}
+
+func TestReadOrientation(t *testing.T) {
+	dir := t.TempDir()
+
+	rotated := filepath.Join(dir, "rotated.JPG")
+	writeOrientedJPEG(t, rotated, 8, 4, 6)
+	if got, err := ReadOrientation(rotated); err != nil || got != 6 {
+		t.Errorf("ReadOrientation = %d, %v; want 6", got, err)
+	}
+
+	plain := filepath.Join(dir, "plain.jpg")
+	writeOrientedJPEG(t, plain, 8, 4, 0)
+	if got, err := ReadOrientation(plain); err != nil || got != 0 {
+		t.Errorf("ReadOrientation without EXIF = %d, %v; want 0", got, err)
+	}
+
+	// Other formats aren't read
+	if got, err := ReadOrientation(filepath.Join(dir, "missing.png")); err != nil || got != 0 {
+		t.Errorf("ReadOrientation of a PNG = %d, %v; want 0", got, err)
+	}
+
+	notJPEG := filepath.Join(dir, "fake.jpg")
+	if err := os.WriteFile(notJPEG, []byte("not an image"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := ReadOrientation(notJPEG); err == nil {
+		t.Error("Expected an error for a file that isn't a JPEG")
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+
+	for orientation := 0; orientation <= 8; orientation++ {
+		b := applyOrientation(img, orientation).Bounds()
+		wantW, wantH := 4, 2
+		if orientation >= 5 {
+			wantW, wantH = 2, 4
+		}
+		if b.Dx() != wantW || b.Dy() != wantH {
+			t.Errorf("Orientation %d: got %dx%d, want %dx%d", orientation, b.Dx(), b.Dy(), wantW, wantH)
+		}
+	}
+}
+
+func TestLoadJPEGDownsampledAppliesOrientation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "phone.jpg")
+	writeOrientedJPEG(t, path, 80, 40, 6)
+
+	img, err := LoadJPEGDownsampled(path, 40, 20)
+	if err != nil {
+		t.Fatalf("LoadJPEGDownsampled failed: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 40 {
+		t.Fatalf("Expected a 20x40 upright image, got %dx%d", b.Dx(), b.Dy())
+	}
+
+	// Rotating 90° clockwise moves the red left half to the top
+	if r, _, b, _ := img.At(10, 5).RGBA(); r < b {
+		t.Error("Expected the stored left half on top after rotation")
+	}
+}
+
+func TestInspectOrientation(t *testing.T) {
+	dir := t.TempDir()
+	gen := NewThumbnailGenerator(t.TempDir(), dir, true, nil, time.Hour, nil)
+
+	tests := []struct {
+		name          string
+		orientation   uint16
+		wantCorrected bool
+		wantWidth     int
+	}{
+		{"rotated", 6, true, 20},
+		{"mirrored", 2, true, 40},
+		{"upright", 1, false, 40},
+		{"no EXIF", 0, false, 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".jpg")
+			writeOrientedJPEG(t, path, 40, 20, tt.orientation)
+
+			report, err := gen.InspectOrientation(context.Background(), path)
+			if err != nil {
+				t.Fatalf("InspectOrientation failed: %v", err)
+			}
+			if report.Orientation != int(tt.orientation) || report.NeedsCorrection != (tt.orientation > 1) {
+				t.Errorf("Unexpected orientation in %+v", report)
+			}
+			if report.Corrected != tt.wantCorrected || report.DecodedWidth != tt.wantWidth {
+				t.Errorf("Expected corrected=%v and width %d, got %+v", tt.wantCorrected, tt.wantWidth, report)
+			}
+			if report.SourceWidth != 40 || report.SourceHeight != 20 {
+				t.Errorf("Expected the stored 40x20 size, got %dx%d", report.SourceWidth, report.SourceHeight)
+			}
+		})
+	}
+}
+
+func TestCheckOrientationDetectsUncorrected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rotated.jpg")
+	writeOrientedJPEG(t, path, 40, 20, 8)
+
+	// A decoder that ignored the orientation returns the stored shape, which
+	// gives it away even if the decoder normally handles orientation
+	header, err := readImageHeader(path)
+	if err != nil {
+		t.Fatalf("readImageHeader failed: %v", err)
+	}
+	decoded := decodedImage{img: image.NewRGBA(image.Rect(0, 0, 40, 20)), decoder: decoderImaging, header: header}
+	report := checkOrientation(path, decoded)
+	if !report.NeedsCorrection || report.Corrected {
+		t.Errorf("Expected an uncorrected orientation, got %+v", report)
+	}
+}
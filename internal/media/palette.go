@@ -0,0 +1,159 @@
+package media
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultPaletteSize is the number of dominant colors stored per file
+	DefaultPaletteSize = 5
+
+	// paletteQuantizeShift reduces each channel to 4 bits (4096 buckets) when counting colors
+	paletteQuantizeShift = 4
+
+	// paletteMinSeparation is the minimum distance between two colors in the same palette,
+	// so near-identical shades of the dominant color don't crowd out everything else
+	paletteMinSeparation = 40.0
+)
+
+// paletteBucket accumulates pixels that fall into one quantized color cell.
+type paletteBucket struct {
+	r, g, b uint64
+	count   uint64
+}
+
+// ExtractPalette returns up to n dominant colors in img as "#rrggbb" strings,
+// most common first. It is intended to run on an already-downscaled thumbnail;
+// mostly transparent pixels are ignored.
+func ExtractPalette(img image.Image, n int) []string {
+	if img == nil || n <= 0 {
+		return []string{}
+	}
+
+	buckets := make(map[uint16]*paletteBucket)
+	bounds := img.Bounds()
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c, ok := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if !ok || c.A < 128 {
+				continue
+			}
+
+			key := uint16(c.R>>paletteQuantizeShift)<<8 | uint16(c.G>>paletteQuantizeShift)<<4 | uint16(c.B>>paletteQuantizeShift)
+			bucket, ok := buckets[key]
+			if !ok {
+				bucket = &paletteBucket{}
+				buckets[key] = bucket
+			}
+			bucket.r += uint64(c.R)
+			bucket.g += uint64(c.G)
+			bucket.b += uint64(c.B)
+			bucket.count++
+		}
+	}
+
+	sorted := make([]*paletteBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		sorted = append(sorted, bucket)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].count > sorted[j].count
+	})
+
+	selected := make([]color.RGBA, 0, n)
+	for _, bucket := range sorted {
+		if len(selected) >= n {
+			break
+		}
+
+		avg := color.RGBA{
+			R: uint8(bucket.r / bucket.count), //nolint:gosec // G115: average of uint8 values fits in uint8
+			G: uint8(bucket.g / bucket.count), //nolint:gosec // G115: average of uint8 values fits in uint8
+			B: uint8(bucket.b / bucket.count), //nolint:gosec // G115: average of uint8 values fits in uint8
+			A: 255,
+		}
+
+		distinct := true
+		for _, existing := range selected {
+			if ColorDistance(avg, existing) < paletteMinSeparation {
+				distinct = false
+				break
+			}
+		}
+		if distinct {
+			selected = append(selected, avg)
+		}
+	}
+
+	palette := make([]string, len(selected))
+	for i, c := range selected {
+		palette[i] = FormatHexColor(c)
+	}
+	return palette
+}
+
+// FormatHexColor formats a color as a lowercase "#rrggbb" string.
+func FormatHexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// ParseHexColor parses a "#rrggbb" or "rrggbb" string (shorthand "#rgb" is also accepted).
+func ParseHexColor(s string) (color.RGBA, error) {
+	digits := strings.TrimPrefix(strings.TrimSpace(s), "#")
+
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	if len(digits) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid hex color %q", s)
+	}
+
+	rgb, err := hex.DecodeString(digits)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid hex color %q: %w", s, err)
+	}
+
+	return color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 255}, nil
+}
+
+// ColorDistance returns the perceptual distance between two colors using the
+// "redmean" weighted Euclidean approximation. Identical colors return 0 and
+// black vs white is roughly 765.
+func ColorDistance(a, b color.RGBA) float64 {
+	rMean := (float64(a.R) + float64(b.R)) / 2
+	dr := float64(a.R) - float64(b.R)
+	dg := float64(a.G) - float64(b.G)
+	db := float64(a.B) - float64(b.B)
+
+	return math.Sqrt((2+rMean/256)*dr*dr + 4*dg*dg + (2+(255-rMean)/256)*db*db)
+}
+
+// PaletteDistance returns the distance from target to the closest color in palette.
+// Colors earlier in the palette (more dominant) are slightly favored so that a
+// photo that is mostly blue ranks above one with a small blue accent.
+// Returns math.MaxFloat64 if the palette has no parseable colors.
+func PaletteDistance(target color.RGBA, palette []string) float64 {
+	best := math.MaxFloat64
+
+	for i, value := range palette {
+		c, err := ParseHexColor(value)
+		if err != nil {
+			continue
+		}
+
+		// 5% penalty per rank below the dominant color
+		d := ColorDistance(target, c) * (1 + 0.05*float64(i))
+		if d < best {
+			best = d
+		}
+	}
+
+	return best
+}
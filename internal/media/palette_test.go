@@ -0,0 +1,147 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestExtractPalette_DominantColorFirst(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	red := color.RGBA{R: 200, G: 20, B: 20, A: 255}
+	blue := color.RGBA{R: 20, G: 20, B: 200, A: 255}
+
+	// 70 red pixels, 30 blue pixels
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			if y < 7 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+
+	palette := ExtractPalette(img, DefaultPaletteSize)
+	if len(palette) != 2 {
+		t.Fatalf("expected 2 colors, got %d: %v", len(palette), palette)
+	}
+	if palette[0] != "#c81414" {
+		t.Errorf("expected dominant color #c81414, got %s", palette[0])
+	}
+	if palette[1] != "#1414c8" {
+		t.Errorf("expected second color #1414c8, got %s", palette[1])
+	}
+}
+
+func TestExtractPalette_MergesSimilarShades(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{R: 100, G: 100, B: 100, A: 255})
+	img.Set(1, 0, color.RGBA{R: 112, G: 112, B: 112, A: 255})
+
+	palette := ExtractPalette(img, DefaultPaletteSize)
+	if len(palette) != 1 {
+		t.Errorf("expected near-identical shades to collapse to 1 color, got %v", palette)
+	}
+}
+
+func TestExtractPalette_IgnoresTransparentPixels(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	img.Set(0, 0, color.NRGBA{R: 0, G: 255, B: 0, A: 255})
+	for x := 1; x < 4; x++ {
+		img.Set(x, 0, color.NRGBA{R: 255, G: 0, B: 0, A: 0})
+	}
+
+	palette := ExtractPalette(img, DefaultPaletteSize)
+	if len(palette) != 1 || palette[0] != "#00ff00" {
+		t.Errorf("expected only the opaque green pixel, got %v", palette)
+	}
+}
+
+func TestExtractPalette_EmptyInput(t *testing.T) {
+	if got := ExtractPalette(nil, 5); len(got) != 0 {
+		t.Errorf("expected empty palette for nil image, got %v", got)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	if got := ExtractPalette(img, 0); len(got) != 0 {
+		t.Errorf("expected empty palette for n=0, got %v", got)
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    color.RGBA
+		wantErr bool
+	}{
+		{"#ff8000", color.RGBA{R: 255, G: 128, B: 0, A: 255}, false},
+		{"FF8000", color.RGBA{R: 255, G: 128, B: 0, A: 255}, false},
+		{"#f80", color.RGBA{R: 255, G: 136, B: 0, A: 255}, false},
+		{" #000000 ", color.RGBA{A: 255}, false},
+		{"", color.RGBA{}, true},
+		{"#12345", color.RGBA{}, true},
+		{"#gggggg", color.RGBA{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseHexColor(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHexColor(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseHexColor(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatHexColorRoundTrip(t *testing.T) {
+	c := color.RGBA{R: 18, G: 52, B: 171, A: 255}
+	parsed, err := ParseHexColor(FormatHexColor(c))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed != c {
+		t.Errorf("round trip mismatch: got %v, want %v", parsed, c)
+	}
+}
+
+func TestColorDistance(t *testing.T) {
+	black := color.RGBA{A: 255}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	gray := color.RGBA{R: 128, G: 128, B: 128, A: 255}
+
+	if d := ColorDistance(black, black); d != 0 {
+		t.Errorf("expected 0 distance for identical colors, got %f", d)
+	}
+	if ColorDistance(black, white) != ColorDistance(white, black) {
+		t.Error("expected distance to be symmetric")
+	}
+	if ColorDistance(black, gray) >= ColorDistance(black, white) {
+		t.Error("expected gray to be closer to black than white is")
+	}
+	if d := ColorDistance(black, white); d < 760 || d > 770 {
+		t.Errorf("expected black/white distance near 765, got %f", d)
+	}
+}
+
+func TestPaletteDistance(t *testing.T) {
+	blue := color.RGBA{R: 0, G: 0, B: 255, A: 255}
+
+	// Same color as the dominant entry vs. as a minor accent
+	dominant := PaletteDistance(blue, []string{"#0000f0", "#ff0000"})
+	accent := PaletteDistance(blue, []string{"#ff0000", "#00ff00", "#0000f0"})
+	if dominant >= accent {
+		t.Errorf("expected dominant match (%f) to rank ahead of accent match (%f)", dominant, accent)
+	}
+
+	if d := PaletteDistance(blue, []string{"not-a-color"}); d != math.MaxFloat64 {
+		t.Errorf("expected MaxFloat64 for unparseable palette, got %f", d)
+	}
+	if d := PaletteDistance(blue, nil); d != math.MaxFloat64 {
+		t.Errorf("expected MaxFloat64 for empty palette, got %f", d)
+	}
+}
@@ -0,0 +1,313 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"media-viewer/internal/filesystem"
+	"media-viewer/internal/logging"
+	"media-viewer/internal/metrics"
+)
+
+const (
+	// ScrubFrameInterval is the spacing of scrub preview frames. Videos
+	// longer than MaxScrubFrames intervals have their frames spread further
+	// apart.
+	ScrubFrameInterval = 10 * time.Second
+
+	// MaxScrubFrames caps the frames in a scrub sprite sheet
+	MaxScrubFrames = 100
+
+	// scrubColumns is the width of a sprite sheet in frames
+	scrubColumns = 10
+
+	// scrubFrameWidth is the width of a sprite sheet frame in pixels; the
+	// height follows the video's aspect ratio
+	scrubFrameWidth = 160
+
+	// scrubTimeout bounds the FFmpeg run, which decodes the whole video
+	// rather than a single frame
+	scrubTimeout = 2 * time.Minute
+
+	// scrubDir is the subdirectory of the thumbnail cache holding sprite
+	// sheets and their tracks. Thumbnail cleanup skips directories, so it
+	// is cleaned up separately (see cleanupOrphanedScrubs).
+	scrubDir = "scrub"
+)
+
+// scrubLayout describes how the frames of a video are laid out in its sprite
+// sheet
+type scrubLayout struct {
+	frames   int
+	columns  int
+	rows     int
+	interval float64 // Seconds between frames
+	duration float64 // Seconds
+}
+
+// newScrubLayout returns the layout for a video of the given duration: one
+// frame per ScrubFrameInterval, at least one and at most MaxScrubFrames
+func newScrubLayout(duration float64) scrubLayout {
+	frames := int(math.Ceil(duration / ScrubFrameInterval.Seconds()))
+	frames = min(max(frames, 1), MaxScrubFrames)
+	columns := min(frames, scrubColumns)
+	return scrubLayout{
+		frames:   frames,
+		columns:  columns,
+		rows:     (frames + columns - 1) / columns,
+		interval: duration / float64(frames),
+		duration: duration,
+	}
+}
+
+// ffmpegArgs returns the arguments that render the sprite sheet of filePath
+// as a JPEG on stdout. Only keyframes are decoded, which is much faster and
+// close enough for a preview.
+func (l scrubLayout) ffmpegArgs(filePath string) []string {
+	filter := fmt.Sprintf("fps=%s,scale=%d:-2,tile=%dx%d",
+		strconv.FormatFloat(1/l.interval, 'f', -1, 64), scrubFrameWidth, l.columns, l.rows)
+	return []string{
+		"-skip_frame", "nokey",
+		"-i", filePath,
+		"-an",
+		"-vf", filter,
+		"-frames:v", "1",
+		"-q:v", "5",
+		"-f", "image2pipe",
+		"-vcodec", "mjpeg",
+		"-",
+	}
+}
+
+// vtt returns the WebVTT track mapping each frame's time range to its region
+// of the sprite sheet at spriteURL, given the sheet's size in pixels
+func (l scrubLayout) vtt(spriteURL string, width, height int) []byte {
+	frameWidth := width / l.columns
+	frameHeight := height / l.rows
+
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for i := range l.frames {
+		start := float64(i) * l.interval
+		end := float64(i+1) * l.interval
+		if i == l.frames-1 {
+			end = l.duration
+		}
+		fmt.Fprintf(&b, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			formatVTTTimestamp(start), formatVTTTimestamp(end), spriteURL,
+			(i%l.columns)*frameWidth, (i/l.columns)*frameHeight, frameWidth, frameHeight)
+	}
+	return []byte(b.String())
+}
+
+// formatVTTTimestamp formats seconds as a WebVTT timestamp, HH:MM:SS.mmm
+func formatVTTTimestamp(seconds float64) string {
+	ms := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// scrubBase returns the path, without extension, of the cached sprite sheet
+// and track of a video at a modification time. A changed video gets new
+// files; the old ones are removed when they are generated.
+func (t *ThumbnailGenerator) scrubBase(filePath string, modTime time.Time) string {
+	return filepath.Join(t.cacheDir, scrubDir, fmt.Sprintf("%s-%x", scrubKey(filePath), modTime.UnixNano()))
+}
+
+// scrubKey returns the part of a video's scrub cache filenames derived from
+// its path
+func scrubKey(filePath string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(filePath))) //nolint:gosec // MD5 used for cache key generation, not security
+}
+
+// GetScrubVTT returns the WebVTT track of a video's scrub previews, generating
+// the sprite sheet it points to at spriteURL if it isn't cached yet
+func (t *ThumbnailGenerator) GetScrubVTT(ctx context.Context, filePath, spriteURL string) ([]byte, error) {
+	base, err := t.getScrub(ctx, filePath, spriteURL)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(base + ".vtt")
+}
+
+// GetScrubSprite returns the JPEG sprite sheet of a video's scrub previews,
+// generating it if it isn't cached yet. spriteURL is where it is served, for
+// the track generated along with it.
+func (t *ThumbnailGenerator) GetScrubSprite(ctx context.Context, filePath, spriteURL string) ([]byte, error) {
+	base, err := t.getScrub(ctx, filePath, spriteURL)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(base + ".jpg")
+}
+
+// getScrub returns the cache path base of a video's sprite sheet and track,
+// generating both if they are missing
+func (t *ThumbnailGenerator) getScrub(ctx context.Context, filePath, spriteURL string) (string, error) {
+	if !t.enabled {
+		return "", fmt.Errorf("thumbnails disabled")
+	}
+
+	info, err := filesystem.StatWithRetry(filePath, filesystem.DefaultRetryConfig())
+	if err != nil {
+		return "", fmt.Errorf("file not accessible: %w", err)
+	}
+
+	base := t.scrubBase(filePath, info.ModTime())
+	if scrubCached(base) {
+		return base, nil
+	}
+
+	lockKey := "scrub:" + filePath
+	fileLock := t.getLock(lockKey)
+	fileLock.Lock()
+	defer func() {
+		fileLock.Unlock()
+		t.releaseLock(lockKey)
+	}()
+
+	if scrubCached(base) {
+		return base, nil
+	}
+	if err := t.generateScrub(ctx, filePath, spriteURL, base); err != nil {
+		return "", err
+	}
+	return base, nil
+}
+
+// scrubCached reports whether both the sprite sheet and track at base exist
+func scrubCached(base string) bool {
+	for _, ext := range []string{".jpg", ".vtt"} {
+		if _, err := os.Stat(base + ext); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// generateScrub renders the sprite sheet of a video with FFmpeg and writes it
+// and its track to base, replacing those of earlier versions of the video
+func (t *ThumbnailGenerator) generateScrub(ctx context.Context, filePath, spriteURL, base string) error {
+	duration, err := t.getVideoDuration(ctx, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to probe video duration: %w", err)
+	}
+
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return fmt.Errorf("ffmpeg not found: %w", err)
+	}
+
+	// Validate file path before passing to external process
+	if err := validateFilePath(filePath); err != nil {
+		return fmt.Errorf("invalid file path for ffmpeg: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, scrubTimeout)
+	defer cancel()
+
+	layout := newScrubLayout(duration)
+	logging.Debug("Generating scrub sprite of %d frames every %.1fs for %s", layout.frames, layout.interval, filePath)
+
+	start := time.Now()
+	// #nosec G204 -- filePath is from the indexed media library, validated above
+	cmd := exec.CommandContext(ctx, ffmpegPath, layout.ffmpegArgs(filePath)...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	metrics.ThumbnailFFmpegDuration.WithLabelValues("video").Observe(time.Since(start).Seconds())
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed: %w, stderr: %s", err, stderr.String())
+	}
+	if stdout.Len() == 0 {
+		return fmt.Errorf("ffmpeg produced no output for %s", filePath)
+	}
+
+	sprite := stdout.Bytes()
+	config, _, err := image.DecodeConfig(bytes.NewReader(sprite))
+	if err != nil {
+		return fmt.Errorf("failed to decode ffmpeg output: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(base), 0o755); err != nil {
+		return fmt.Errorf("failed to create scrub cache dir: %w", err)
+	}
+	t.removeScrubs(filePath)
+
+	// The track is written last, so a cached track always has its sprite
+	if err := writeFileAtomic(base+".jpg", sprite); err != nil {
+		return fmt.Errorf("failed to cache scrub sprite: %w", err)
+	}
+	if err := writeFileAtomic(base+".vtt", layout.vtt(spriteURL, config.Width, config.Height)); err != nil {
+		return fmt.Errorf("failed to cache scrub track: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file renamed over path, so
+// readers never see a partly written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// removeScrubs removes the cached sprite sheets and tracks of every version of
+// a video
+func (t *ThumbnailGenerator) removeScrubs(filePath string) {
+	matches, err := filepath.Glob(filepath.Join(t.cacheDir, scrubDir, scrubKey(filePath)+"-*"))
+	if err != nil {
+		return
+	}
+	for _, match := range matches {
+		if err := os.Remove(match); err != nil && !os.IsNotExist(err) {
+			logging.Debug("Failed to remove scrub file %s: %v", match, err)
+		}
+	}
+}
+
+// cleanupOrphanedScrubs removes the sprite sheets and tracks of videos no
+// longer in the index. Returns the number of files removed.
+func (t *ThumbnailGenerator) cleanupOrphanedScrubs(indexedPaths map[string]struct{}) int {
+	entries, err := os.ReadDir(filepath.Join(t.cacheDir, scrubDir))
+	if err != nil {
+		return 0
+	}
+
+	keys := make(map[string]struct{}, len(indexedPaths))
+	for path := range indexedPaths {
+		keys[scrubKey(filepath.Join(t.mediaDir, path))] = struct{}{}
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		key, _, _ := strings.Cut(entry.Name(), "-")
+		if _, exists := keys[key]; exists {
+			continue
+		}
+		if err := os.Remove(filepath.Join(t.cacheDir, scrubDir, entry.Name())); err != nil {
+			logging.Debug("Failed to remove orphaned scrub file %s: %v", entry.Name(), err)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
@@ -0,0 +1,165 @@
+package media
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewScrubLayout(t *testing.T) {
+	tests := []struct {
+		duration float64
+		frames   int
+		columns  int
+		rows     int
+		interval float64
+	}{
+		{3, 1, 1, 1, 3},
+		{10, 1, 1, 1, 10},
+		{25, 3, 3, 1, 25.0 / 3},
+		{120, 12, 10, 2, 10},
+		{1000, 100, 10, 10, 10},
+		{3600, 100, 10, 10, 36},
+	}
+
+	for _, tt := range tests {
+		got := newScrubLayout(tt.duration)
+		if got.frames != tt.frames || got.columns != tt.columns || got.rows != tt.rows || got.interval != tt.interval {
+			t.Errorf("newScrubLayout(%v) = %+v, want %d frames in %dx%d every %vs",
+				tt.duration, got, tt.frames, tt.columns, tt.rows, tt.interval)
+		}
+	}
+}
+
+func TestScrubLayoutFFmpegArgs(t *testing.T) {
+	args := strings.Join(newScrubLayout(120).ffmpegArgs("/media/clip.mp4"), " ")
+	for _, want := range []string{"-i /media/clip.mp4", "fps=0.1,scale=160:-2,tile=10x2", "-frames:v 1"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("ffmpeg args %q missing %q", args, want)
+		}
+	}
+}
+
+func TestScrubLayoutVTT(t *testing.T) {
+	vtt := string(newScrubLayout(25).vtt("/api/scrub-sprite/clip.mp4", 480, 90))
+
+	want := `WEBVTT
+
+00:00:00.000 --> 00:00:08.333
+/api/scrub-sprite/clip.mp4#xywh=0,0,160,90
+
+00:00:08.333 --> 00:00:16.667
+/api/scrub-sprite/clip.mp4#xywh=160,0,160,90
+
+00:00:16.667 --> 00:00:25.000
+/api/scrub-sprite/clip.mp4#xywh=320,0,160,90
+`
+	if vtt != want {
+		t.Errorf("vtt =\n%s\nwant\n%s", vtt, want)
+	}
+}
+
+func TestScrubLayoutVTTRows(t *testing.T) {
+	vtt := string(newScrubLayout(120).vtt("sprite.jpg", 1600, 180))
+
+	// The 11th frame starts the second row
+	if !strings.Contains(vtt, "00:01:40.000 --> 00:01:50.000\nsprite.jpg#xywh=0,90,160,90\n") {
+		t.Errorf("vtt missing second row cue:\n%s", vtt)
+	}
+	if !strings.HasSuffix(vtt, "00:01:50.000 --> 00:02:00.000\nsprite.jpg#xywh=160,90,160,90\n") {
+		t.Errorf("vtt doesn't end at the video's duration:\n%s", vtt)
+	}
+}
+
+func TestFormatVTTTimestamp(t *testing.T) {
+	tests := map[float64]string{
+		0:       "00:00:00.000",
+		8.3333:  "00:00:08.333",
+		61.5:    "00:01:01.500",
+		3725.25: "01:02:05.250",
+	}
+	for seconds, want := range tests {
+		if got := formatVTTTimestamp(seconds); got != want {
+			t.Errorf("formatVTTTimestamp(%v) = %q, want %q", seconds, got, want)
+		}
+	}
+}
+
+func TestGetScrubCached(t *testing.T) {
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(t.TempDir(), mediaDir, true, nil, time.Hour, nil)
+
+	video := filepath.Join(mediaDir, "clip.mp4")
+	if err := os.WriteFile(video, []byte("not a video"), 0o644); err != nil {
+		t.Fatalf("failed to create test video: %v", err)
+	}
+	info, err := os.Stat(video)
+	if err != nil {
+		t.Fatalf("failed to stat test video: %v", err)
+	}
+
+	// A cached sprite sheet and track are served without FFmpeg
+	base := gen.scrubBase(video, info.ModTime())
+	if err := os.MkdirAll(filepath.Dir(base), 0o755); err != nil {
+		t.Fatalf("failed to create scrub dir: %v", err)
+	}
+	for ext, data := range map[string]string{".jpg": "sprite", ".vtt": "WEBVTT\n"} {
+		if err := os.WriteFile(base+ext, []byte(data), 0o644); err != nil {
+			t.Fatalf("failed to write cached %s: %v", ext, err)
+		}
+	}
+
+	ctx := context.Background()
+	if vtt, err := gen.GetScrubVTT(ctx, video, "sprite.jpg"); err != nil || string(vtt) != "WEBVTT\n" {
+		t.Errorf("GetScrubVTT = %q, %v; want the cached track", vtt, err)
+	}
+	if sprite, err := gen.GetScrubSprite(ctx, video, "sprite.jpg"); err != nil || string(sprite) != "sprite" {
+		t.Errorf("GetScrubSprite = %q, %v; want the cached sprite", sprite, err)
+	}
+
+	// A changed video has another cache path
+	later := info.ModTime().Add(time.Minute)
+	if err := os.Chtimes(video, later, later); err != nil {
+		t.Fatalf("failed to touch test video: %v", err)
+	}
+	if gen.scrubBase(video, later) == base {
+		t.Error("expected a new cache path after the video changed")
+	}
+}
+
+func TestGetScrubDisabled(t *testing.T) {
+	gen := NewThumbnailGenerator(t.TempDir(), t.TempDir(), false, nil, time.Hour, nil)
+	if _, err := gen.GetScrubVTT(context.Background(), "/media/clip.mp4", "sprite.jpg"); err == nil {
+		t.Error("expected an error with thumbnails disabled")
+	}
+}
+
+func TestCleanupOrphanedScrubs(t *testing.T) {
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(t.TempDir(), mediaDir, true, nil, time.Hour, nil)
+
+	kept := gen.scrubBase(filepath.Join(mediaDir, "kept.mp4"), time.Unix(1, 0))
+	gone := gen.scrubBase(filepath.Join(mediaDir, "gone.mp4"), time.Unix(1, 0))
+	if err := os.MkdirAll(filepath.Dir(kept), 0o755); err != nil {
+		t.Fatalf("failed to create scrub dir: %v", err)
+	}
+	for _, path := range []string{kept + ".jpg", kept + ".vtt", gone + ".jpg", gone + ".vtt"} {
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	removed := gen.cleanupOrphanedScrubs(map[string]struct{}{"kept.mp4": {}})
+	if removed != 2 {
+		t.Errorf("cleanupOrphanedScrubs removed %d files, want 2", removed)
+	}
+	if !scrubCached(kept) {
+		t.Error("expected the indexed video's scrub files to be kept")
+	}
+	if _, err := os.Stat(gone + ".jpg"); !os.IsNotExist(err) {
+		t.Error("expected the orphaned sprite sheet to be removed")
+	}
+}
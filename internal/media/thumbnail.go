@@ -24,8 +24,10 @@ import (
 	"media-viewer/internal/database"
 	"media-viewer/internal/filesystem"
 	"media-viewer/internal/logging"
+	"media-viewer/internal/mediatypes"
 	"media-viewer/internal/memory"
 	"media-viewer/internal/metrics"
+	"media-viewer/internal/transcoder"
 	"media-viewer/internal/workers"
 
 	// Image format decoders - required for image.Decode to support these formats
@@ -41,7 +43,7 @@ var (
 )
 
 const (
-	folderThumbSize    = 200
+	folderThumbSize    = 200 // Folder composites are drawn this many pixels wide
 	folderGridCellSize = 80
 	folderGridGap      = 4
 	folderGridPadding  = 20
@@ -61,22 +63,48 @@ const (
 
 	// Metadata file extension for tracking source paths
 	metaFileExtension = ".meta"
+
+	// contentLockPrefix namespaces per-content locks in fileLocks
+	contentLockPrefix = "content:"
 )
 
+// DefaultStopGracePeriod is how long Stop waits for a generation run to finish
+// its current files unless changed with SetStopGracePeriod
+const DefaultStopGracePeriod = 10 * time.Second
+
+// stopPollInterval is how often Stop checks whether a run has finished
+const stopPollInterval = 50 * time.Millisecond
+
 // ThumbnailGenerator generates and caches thumbnail images for media files.
 type ThumbnailGenerator struct {
 	cacheDir           string
 	mediaDir           string
 	enabled            bool
-	db                 *database.Database
+	db                 *database.Database // nil in database-less mode, see withoutDatabase
 	generationInterval time.Duration
 	memoryMonitor      *memory.Monitor
 
+	// Logs once that database-backed features are disabled
+	noDatabaseWarning sync.Once
+
+	// Guards generationInterval, which can change at runtime via config reload
+	intervalMu    sync.RWMutex
+	intervalReset chan struct{}
+
+	// Background lifecycle (see Start). lifecycleMu guards stopChan, which is
+	// replaced when a stopped generator is started again, and running.
+	lifecycleMu sync.Mutex
+	running     bool
+	stopChan    chan struct{}
+	stopGrace   atomic.Int64 // time.Duration Stop waits for a run to finish
+
 	// Background generation state
-	stopChan        chan struct{}
 	generationMu    sync.RWMutex
 	isGenerating    atomic.Bool
+	initialRun      atomic.Bool // Current run is the initial full generation
+	largeFilePass   atomic.Bool // Current run is processing deferred large files
 	generationStats GenerationStats
+	checkpoint      *generationCheckpoint // Progress of the current run; only touched by the run itself
 
 	// Cache metrics state
 	cacheMetricsMu  sync.RWMutex
@@ -85,12 +113,81 @@ type ThumbnailGenerator struct {
 	cachedSize      atomic.Int64
 	cachedCount     atomic.Int64
 	lastCacheUpdate atomic.Int64 // Unix timestamp
+	sizeCacheHits   atomic.Int64
+	sizeCacheMisses atomic.Int64
+
+	// Cache size limit in bytes (0 = unlimited) and the files being written,
+	// which EnforceCacheLimit skips
+	cacheLimit    atomic.Int64
+	cacheWritesMu sync.Mutex
+	cacheWrites   map[string]int
 
 	// Per-file locks to allow parallel generation of different files
 	fileLocks sync.Map
 
+	// Format variants: encoder (replaced in tests) and formats it can't encode
+	encodeVariant      func(data []byte, format ThumbnailFormat) ([]byte, error)
+	unsupportedFormats sync.Map
+
+	// Variant format encoded with every generated thumbnail (nil = none)
+	outputFormat atomic.Pointer[ThumbnailFormat]
+
+	// Obscuring of sensitive files' thumbnails: style (nil = blur) and
+	// function (replaced in tests)
+	sensitiveStyle   atomic.Pointer[SensitiveStyle]
+	obscureThumbnail func(data []byte, style SensitiveStyle) ([]byte, error)
+
 	// Callback for post-index generation
 	onIndexComplete chan struct{}
+
+	// Opt-in dominant color extraction for color search
+	paletteEnabled atomic.Bool
+
+	// Opt-in detection of animated images, see SetAnimatedDetection
+	animatedDetection atomic.Bool
+
+	// Which frame of a video becomes its thumbnail (nil = default strategy)
+	videoSeek atomic.Pointer[VideoSeekStrategy]
+
+	// Sample several frames per video for folder composites
+	folderVideoFrames atomic.Bool
+
+	// Opt-in thumbnails for files of no media type (nil = off)
+	otherThumbnails atomic.Pointer[OtherThumbnailMode]
+
+	// Handling of files that change while their thumbnail is generated (nil = retry)
+	changedFiles atomic.Pointer[ChangedFileMode]
+
+	// Opt-in content-addressed storage shared by identical source files
+	dedupeEnabled atomic.Bool
+
+	// Opt-in rounded corners and border (nil = none), the styler (replaced in
+	// tests) and whether it failed
+	style          atomic.Pointer[ThumbnailStyle]
+	styleThumbnail func(image.Image, ThumbnailStyle) (image.Image, error)
+	styleFailed    atomic.Bool
+
+	// Serve stale thumbnails while regenerating them in the background
+	staleWhileRevalidate atomic.Bool
+
+	// Images above this size (bytes, 0 = off) are generated last, with at most
+	// largeFileWorkers workers
+	largeFileThreshold atomic.Int64
+	largeFileWorkers   atomic.Int64
+
+	// Paths with a background revalidation in flight
+	revalidating sync.Map
+
+	// Interval of the background freshness pass (time.Duration, 0 = off) and
+	// the signal that resets its timer, see SetFreshnessInterval
+	freshnessInterval atomic.Int64
+	freshnessReset    chan struct{}
+
+	// Longest edge of image and video thumbnails (0 = DefaultThumbnailSize)
+	size atomic.Int32
+
+	// Sizes thumbnails are rendered at on request, ascending (nil = none)
+	variantSizes atomic.Pointer[[]int]
 }
 
 // thumbnailResult holds the result of a thumbnail generation attempt
@@ -114,13 +211,19 @@ type GenerationStats struct {
 	FoldersUpdated     int       `json:"foldersUpdated"`
 	CurrentFile        string    `json:"currentFile,omitempty"`
 	IsIncremental      bool      `json:"isIncremental"`
-	TotalMemoryUsed    uint64    `json:"-"` // Not exposed in JSON, internal tracking
-	MemoryTrackedCount int       `json:"-"` // Count of images where memory was tracked
+	IsInitial          bool      `json:"isInitial"`
+	Interrupted        bool      `json:"interrupted"`
+	Resumed            bool      `json:"resumed"`            // Continues a run interrupted by a restart
+	LargeFilesDeferred int       `json:"largeFilesDeferred"` // Large files held back until the end of the run
+	LargeFilesPending  int       `json:"largeFilesPending"`  // Deferred large files not yet processed
+	TotalMemoryUsed    uint64    `json:"-"`                  // Not exposed in JSON, internal tracking
+	MemoryTrackedCount int       `json:"-"`                  // Count of images where memory was tracked
 }
 
 // ThumbnailStatus represents the current thumbnail system status
 type ThumbnailStatus struct {
 	Enabled        bool             `json:"enabled"`
+	DatabaseLess   bool             `json:"databaseLess,omitempty"` // Only on-demand generation, see withoutDatabase
 	CacheDir       string           `json:"cacheDir"`
 	CacheCount     int              `json:"cacheCount"`
 	CacheSize      int64            `json:"cacheSize"`
@@ -128,6 +231,14 @@ type ThumbnailStatus struct {
 	Generation     *GenerationStats `json:"generation,omitempty"`
 }
 
+// CacheStats describes the usage of an in-memory cache.
+type CacheStats struct {
+	Entries     int
+	Hits        int64
+	Misses      int64
+	MemoryBytes int64 // Estimate
+}
+
 // Folder colors
 var (
 	folderBodyColor  = color.RGBA{R: 240, G: 200, B: 100, A: 255}
@@ -135,7 +246,9 @@ var (
 	folderInnerColor = color.RGBA{R: 250, G: 235, B: 180, A: 255}
 )
 
-// NewThumbnailGenerator creates a new ThumbnailGenerator instance.
+// NewThumbnailGenerator creates a new ThumbnailGenerator instance. db may be
+// nil, for database-less mode: thumbnails are generated on demand, but the
+// features that need the index are disabled (see withoutDatabase).
 func NewThumbnailGenerator(cacheDir, mediaDir string, enabled bool, db *database.Database, generationInterval time.Duration, memMonitor *memory.Monitor) *ThumbnailGenerator {
 	if enabled {
 		logging.Debug("ThumbnailGenerator: enabled, cache dir: %s", cacheDir)
@@ -155,7 +268,7 @@ func NewThumbnailGenerator(cacheDir, mediaDir string, enabled bool, db *database
 		generationInterval = 6 * time.Hour
 	}
 
-	return &ThumbnailGenerator{
+	t := &ThumbnailGenerator{
 		cacheDir:           cacheDir,
 		mediaDir:           mediaDir,
 		enabled:            enabled,
@@ -164,7 +277,12 @@ func NewThumbnailGenerator(cacheDir, mediaDir string, enabled bool, db *database
 		memoryMonitor:      memMonitor,
 		stopChan:           make(chan struct{}),
 		onIndexComplete:    make(chan struct{}, 1),
+		intervalReset:      make(chan struct{}, 1),
+		freshnessReset:     make(chan struct{}, 1),
+		encodeVariant:      encodeVariantWithVips,
 	}
+	t.stopGrace.Store(int64(DefaultStopGracePeriod))
+	return t
 }
 
 // IsEnabled returns whether thumbnail generation is enabled.
@@ -172,6 +290,51 @@ func (t *ThumbnailGenerator) IsEnabled() bool {
 	return t.enabled
 }
 
+// SetPaletteExtraction enables or disables storing a dominant-color palette
+// for each image and video thumbnail that is generated.
+func (t *ThumbnailGenerator) SetPaletteExtraction(enabled bool) {
+	t.paletteEnabled.Store(enabled)
+}
+
+// SetVideoSeekStrategy sets how the thumbnail frame of a video is chosen.
+// Only affects thumbnails generated after the call.
+func (t *ThumbnailGenerator) SetVideoSeekStrategy(strategy VideoSeekStrategy) {
+	t.videoSeek.Store(&strategy)
+}
+
+// getVideoSeekStrategy returns the configured video seek strategy
+func (t *ThumbnailGenerator) getVideoSeekStrategy() VideoSeekStrategy {
+	if strategy := t.videoSeek.Load(); strategy != nil {
+		return *strategy
+	}
+	return DefaultVideoSeekStrategy()
+}
+
+// SetGenerationInterval changes the periodic generation interval. If the
+// background loop is already running, its timer is reset to the new interval.
+func (t *ThumbnailGenerator) SetGenerationInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	t.intervalMu.Lock()
+	t.generationInterval = interval
+	t.intervalMu.Unlock()
+
+	select {
+	case t.intervalReset <- struct{}{}:
+	default:
+		// A reset is already pending
+	}
+}
+
+// getGenerationInterval returns the current periodic generation interval.
+func (t *ThumbnailGenerator) getGenerationInterval() time.Duration {
+	t.intervalMu.RLock()
+	defer t.intervalMu.RUnlock()
+	return t.generationInterval
+}
+
 // NotifyIndexComplete signals that indexing has completed and thumbnails should be updated.
 func (t *ThumbnailGenerator) NotifyIndexComplete() {
 	select {
@@ -198,13 +361,15 @@ func (t *ThumbnailGenerator) releaseLock(path string) {
 	t.fileLocks.Delete(path)
 }
 
-// getCacheKey returns the cache filename for a given file path
+// getCacheKey returns the cache filename for a given file path at the
+// current thumbnail size
 func (t *ThumbnailGenerator) getCacheKey(filePath string, fileType database.FileType) string {
 	hash := md5.Sum([]byte(filePath))
+	suffix := sizeKeySuffix(t.thumbnailSize())
 	if fileType == database.FileTypeFolder {
-		return fmt.Sprintf("%x.png", hash)
+		return fmt.Sprintf("%x%s.png", hash, suffix)
 	}
-	return fmt.Sprintf("%x.jpg", hash)
+	return fmt.Sprintf("%x%s.jpg", hash, suffix)
 }
 
 // getMetaPath returns the metadata file path for a cache key
@@ -213,20 +378,12 @@ func (t *ThumbnailGenerator) getMetaPath(cacheKey string) string {
 	return filepath.Join(t.cacheDir, base+metaFileExtension)
 }
 
-// writeMetaFile writes the source path to a metadata file
-func (t *ThumbnailGenerator) writeMetaFile(cacheKey, sourcePath string) error {
-	metaPath := t.getMetaPath(cacheKey)
-	return os.WriteFile(metaPath, []byte(sourcePath), 0o644)
-}
-
-// readMetaFile reads the source path from a metadata file
-func (t *ThumbnailGenerator) readMetaFile(cacheKey string) (string, error) {
-	metaPath := t.getMetaPath(cacheKey)
-	data, err := os.ReadFile(metaPath)
-	if err != nil {
-		return "", err
-	}
-	return string(data), nil
+// writeMetaFile writes the source path, and the size and style the
+// thumbnail was rendered with, to a metadata file. sourceHash, if set, is
+// the content hash of the source the thumbnail was generated from; dims, if
+// set, the dimensions it came out at.
+func (t *ThumbnailGenerator) writeMetaFile(cacheKey, sourcePath, sourceHash string, style ThumbnailStyle, dims ThumbnailDimensions) error {
+	return t.newMetaFile(sourcePath, "", sourceHash, style, dims).save(t.getMetaPath(cacheKey))
 }
 
 // deleteMetaFile removes the metadata file for a cache key
@@ -238,7 +395,15 @@ func (t *ThumbnailGenerator) deleteMetaFile(cacheKey string) {
 }
 
 // GetThumbnail generates or retrieves a cached thumbnail for the given file.
+// A cached thumbnail older than its source is regenerated, or, with
+// stale-while-revalidate enabled, returned while it is regenerated in the background.
 func (t *ThumbnailGenerator) GetThumbnail(ctx context.Context, filePath string, fileType database.FileType) ([]byte, error) {
+	return t.getThumbnail(ctx, filePath, fileType, t.staleWhileRevalidate.Load())
+}
+
+// getThumbnail implements GetThumbnail. allowStale selects whether a stale
+// cached thumbnail is returned immediately instead of waiting for regeneration.
+func (t *ThumbnailGenerator) getThumbnail(ctx context.Context, filePath string, fileType database.FileType, allowStale bool) ([]byte, error) {
 	if !t.enabled {
 		return nil, fmt.Errorf("thumbnails disabled")
 	}
@@ -251,24 +416,36 @@ func (t *ThumbnailGenerator) GetThumbnail(ctx context.Context, filePath string,
 	start := time.Now()
 	fileTypeStr := string(fileType)
 
-	// Folders don't need file existence check
+	// Folders don't need file existence check. Their thumbnails are
+	// invalidated when their contents change, so they never go stale by mtime.
+	var sourceModTime time.Time
 	if fileType != database.FileTypeFolder {
 		retryConfig := filesystem.DefaultRetryConfig()
-		if _, err := filesystem.StatWithRetry(filePath, retryConfig); err != nil {
+		info, err := filesystem.StatWithRetry(filePath, retryConfig)
+		if err != nil {
 			metrics.ThumbnailGenerationsTotal.WithLabelValues(fileTypeStr, "error_not_found").Inc()
 			return nil, fmt.Errorf("file not accessible: %w", err)
 		}
+		sourceModTime = info.ModTime()
 	}
 
 	cacheKey := t.getCacheKey(filePath, fileType)
-	cachePath := filepath.Join(t.cacheDir, cacheKey)
 
 	// Check cache first
 	cacheReadStart := time.Now()
-	if data, err := os.ReadFile(cachePath); err == nil {
-		metrics.ThumbnailCacheReadLatency.Observe(time.Since(cacheReadStart).Seconds())
-		metrics.ThumbnailCacheHits.Inc()
-		return data, nil
+	if data, err := t.readCachedThumbnail(cacheKey); err == nil {
+		if !t.isThumbnailStale(cacheKey, sourceModTime) {
+			metrics.ThumbnailCacheReadLatency.Observe(time.Since(cacheReadStart).Seconds())
+			metrics.ThumbnailCacheHits.Inc()
+			return data, nil
+		}
+
+		if allowStale {
+			metrics.ThumbnailStaleServed.Inc()
+			t.revalidateInBackground(filePath, fileType)
+			return data, nil
+		}
+		logging.Debug("Thumbnail stale, regenerating: %s", filePath)
 	}
 	metrics.ThumbnailCacheMisses.Inc()
 
@@ -281,11 +458,118 @@ func (t *ThumbnailGenerator) GetThumbnail(ctx context.Context, filePath string,
 	}()
 
 	// Double-check cache after acquiring lock
-	if data, err := os.ReadFile(cachePath); err == nil {
+	if data, err := t.readCachedThumbnail(cacheKey); err == nil && !t.isThumbnailStale(cacheKey, sourceModTime) {
 		metrics.ThumbnailCacheHits.Inc()
 		return data, nil
 	}
 
+	return t.generateAndCache(ctx, filePath, fileType, cacheKey, sourceModTime, start)
+}
+
+// generateAndCache generates a thumbnail and writes it to the cache,
+// replacing any existing one. The caller must hold the file lock.
+//
+// A file that changes while its thumbnail is generated is handled as set
+// with SetChangedFileMode, so a thumbnail of a partly written file isn't cached.
+func (t *ThumbnailGenerator) generateAndCache(ctx context.Context, filePath string, fileType database.FileType, cacheKey string, sourceModTime, start time.Time) ([]byte, error) {
+	mode := t.changedFileMode()
+	if fileType == database.FileTypeFolder || mode == ChangedFilesOff {
+		return t.generateAndCacheOnce(ctx, filePath, fileType, cacheKey, sourceModTime, start, nil)
+	}
+
+	attempts := 1
+	if mode == ChangedFilesRetry {
+		attempts = 2
+	}
+
+	for attempt := 1; ; attempt++ {
+		before, err := statSource(filePath)
+		if err != nil {
+			metrics.ThumbnailGenerationsTotal.WithLabelValues(string(fileType), "error_not_found").Inc()
+			return nil, fmt.Errorf("file not accessible: %w", err)
+		}
+
+		data, err := t.generateAndCacheOnce(ctx, filePath, fileType, cacheKey, before.modTime, start, &before)
+		if !errors.Is(err, errSourceChanged) {
+			return data, err
+		}
+		if attempt >= attempts {
+			// Still being written; the next request or run tries again
+			logging.Info("Thumbnail not cached, file changed during generation: %s", filePath)
+			return data, nil
+		}
+		logging.Debug("File changed during thumbnail generation, retrying: %s", filePath)
+	}
+}
+
+// generateAndCacheOnce implements generateAndCache. If source is set and the
+// file no longer matches it once the thumbnail is generated, the thumbnail
+// is returned with errSourceChanged instead of being cached.
+func (t *ThumbnailGenerator) generateAndCacheOnce(ctx context.Context, filePath string, fileType database.FileType, cacheKey string, sourceModTime, start time.Time, source *sourceState) ([]byte, error) {
+	fileTypeStr := string(fileType)
+	cachePath := filepath.Join(t.cacheDir, cacheKey)
+
+	// Folders are never styled; their composites are drawn with their own frame.
+	var style ThumbnailStyle
+	if fileType != database.FileTypeFolder {
+		style = t.currentStyle()
+	}
+
+	// A video's frame is chosen once, for both the decision below and the
+	// extraction
+	var seek VideoSeekStrategy
+	var poster bool
+	if fileType == database.FileTypeVideo {
+		seek, poster = t.videoSeekStrategyFor(ctx, filePath)
+	}
+
+	// With deduplication, a source identical to one already cached shares its
+	// thumbnail. Folders are composites, badges for other files depend on
+	// their extension, and a video's poster frame is chosen for that path,
+	// so all are stored per path.
+	var contentKey, sourceHash string
+	if t.dedupeEnabled.Load() && fileType != database.FileTypeFolder && fileType != database.FileTypeOther && !poster {
+		if key, err := filesystem.HashContent(filePath); err != nil {
+			logging.Debug("Content hash failed for %s, caching thumbnail per path: %v", filePath, err)
+		} else {
+			sourceHash = key
+			contentKey = key + sizeKeySuffix(t.thumbnailSize()) + style.contentKeySuffix()
+
+			// Serialize generation of identical content from different paths
+			contentLock := t.getLock(contentLockPrefix + contentKey)
+			contentLock.Lock()
+			defer func() {
+				contentLock.Unlock()
+				t.releaseLock(contentLockPrefix + contentKey)
+			}()
+
+			if data := t.reuseSharedThumbnail(ctx, cacheKey, filePath, contentKey, sourceHash, style); data != nil {
+				logging.Debug("Thumbnail shared with identical content: %s", filePath)
+				metrics.ThumbnailDedupeHits.Inc()
+				t.removeReplacedThumbnail(cacheKey)
+				t.markThumbnailFresh(cacheKey, sourceModTime)
+				return data, nil
+			}
+
+			cachePath = t.getContentPath(contentKey)
+			if err := os.MkdirAll(t.contentDir(), 0o755); err != nil {
+				logging.Debug("Failed to create shared thumbnail directory, caching thumbnail per path: %v", err)
+				contentKey = ""
+				cachePath = filepath.Join(t.cacheDir, cacheKey)
+			}
+		}
+	}
+
+	// The freshness pass compares the recorded hash with the source's to
+	// find edits that kept the modification time
+	if sourceHash == "" && t.FreshnessInterval() > 0 && fileType != database.FileTypeFolder {
+		if key, err := filesystem.HashContent(filePath); err != nil {
+			logging.Debug("Content hash failed for %s, not recording it: %v", filePath, err)
+		} else {
+			sourceHash = key
+		}
+	}
+
 	logging.Debug("Thumbnail generating: %s (type: %s)", filePath, fileType)
 
 	// Add 30-second timeout for thumbnail generation to prevent hung FFmpeg processes
@@ -299,15 +583,26 @@ func (t *ThumbnailGenerator) GetThumbnail(ctx context.Context, filePath string,
 	var img image.Image
 	var err error
 
+	// An animated image's page count comes with its decode
+	detectAnimation := fileType == database.FileTypeImage && t.animatedDetection.Load() && mayBeAnimated(filePath)
+	var pages int
+
 	// Decode phase with timing
 	decodeStart := time.Now()
 	switch fileType {
 	case database.FileTypeImage:
-		img, err = t.generateImageThumbnail(genCtx, filePath)
+		var decoded decodedImage
+		decoded, err = t.decodeSourceImage(genCtx, filePath, detectAnimation)
+		if err == nil {
+			img, pages = decoded.img, decoded.pages
+			recordOrientation(checkOrientation(filePath, decoded))
+		}
 	case database.FileTypeVideo:
-		img, err = t.generateVideoThumbnail(genCtx, filePath)
+		img, err = t.extractVideoThumbnail(genCtx, filePath, seek)
 	case database.FileTypeFolder:
 		img, err = t.generateFolderThumbnail(genCtx, filePath)
+	case database.FileTypeOther:
+		img, err = t.generateOtherThumbnail(genCtx, filePath)
 	default:
 		logging.Error("Thumbnail generation failed for %s: unsupported file type %s", filePath, fileType)
 		metrics.ThumbnailGenerationsTotal.WithLabelValues(fileTypeStr, "error_unsupported").Inc()
@@ -316,7 +611,7 @@ func (t *ThumbnailGenerator) GetThumbnail(ctx context.Context, filePath string,
 	metrics.ThumbnailGenerationDurationDetailed.WithLabelValues(fileTypeStr, "decode").Observe(time.Since(decodeStart).Seconds())
 
 	if err != nil {
-		logging.Error("Thumbnail generation failed for %s (type: %s): %v", filePath, fileType, err)
+		logging.ErrorSampled("thumbnail:"+fileTypeStr, "Thumbnail generation failed for %s (type: %s): %v", filePath, fileType, err)
 		metrics.ThumbnailGenerationsTotal.WithLabelValues(fileTypeStr, "error").Inc()
 		return nil, fmt.Errorf("thumbnail generation failed: %w", err)
 	}
@@ -332,12 +627,35 @@ func (t *ThumbnailGenerator) GetThumbnail(ctx context.Context, filePath string,
 	resizeStart := time.Now()
 	var thumb image.Image
 	if fileType == database.FileTypeFolder {
-		thumb = img // Folders already at correct size
+		thumb = img // Folders are drawn at folderThumbSize
+		if size := t.folderThumbnailSize(); size < folderThumbSize {
+			thumb = imaging.Resize(img, size, size, imaging.Lanczos)
+		}
 	} else {
-		thumb = imaging.Fit(img, 200, 200, imaging.Lanczos)
+		size := t.thumbnailSize()
+		thumb = imaging.Fit(img, size, size, imaging.Lanczos)
 	}
 	metrics.ThumbnailGenerationDurationDetailed.WithLabelValues(fileTypeStr, "resize").Observe(time.Since(resizeStart).Seconds())
 
+	// Other files aren't indexed, so there is no row to store a palette on
+	if fileType != database.FileTypeFolder && fileType != database.FileTypeOther && t.paletteEnabled.Load() {
+		t.storePalette(ctx, filePath, thumb)
+	}
+	if detectAnimation {
+		t.storeAnimated(ctx, filePath, pages)
+	}
+
+	// Palettes are taken from the plain thumbnail, so border and corner fill
+	// colors don't count
+	wantStyle := style
+	thumb, style = t.applyStyle(thumb, style)
+	dims := newThumbnailDimensions(thumb, img)
+	if style != wantStyle && contentKey != "" {
+		// The shared key names the style that failed; keep the plain thumbnail per path
+		contentKey = ""
+		cachePath = filepath.Join(t.cacheDir, cacheKey)
+	}
+
 	var buf bytes.Buffer
 
 	// Encode phase with timing
@@ -357,8 +675,14 @@ func (t *ThumbnailGenerator) GetThumbnail(ctx context.Context, filePath string,
 	}
 	metrics.ThumbnailGenerationDurationDetailed.WithLabelValues(fileTypeStr, "encode").Observe(time.Since(encodeStart).Seconds())
 
+	if source != nil && !sourceUnchanged(filePath, *source) {
+		metrics.ThumbnailGenerationsTotal.WithLabelValues(fileTypeStr, "source_changed").Inc()
+		return buf.Bytes(), errSourceChanged
+	}
+
 	// Cache the result (with NFS retry protection and write metrics)
 	cacheWriteStart := time.Now()
+	defer t.beginCacheWrite(cachePath)()
 	retryConfig := filesystem.DefaultRetryConfig()
 	if err := filesystem.WriteFileWithRetry(cachePath, buf.Bytes(), 0o644, retryConfig); err != nil {
 		logging.Warn("Failed to cache thumbnail %s: %v", cachePath, err)
@@ -367,9 +691,20 @@ func (t *ThumbnailGenerator) GetThumbnail(ctx context.Context, filePath string,
 		metrics.ThumbnailGenerationDurationDetailed.WithLabelValues(fileTypeStr, "cache").Observe(time.Since(cacheWriteStart).Seconds())
 
 		// Write metadata file for orphan tracking
-		if err := t.writeMetaFile(cacheKey, filePath); err != nil {
-			logging.Debug("Failed to write meta file for %s: %v", cacheKey, err)
+		var metaErr error
+		if contentKey != "" {
+			metaErr = t.writeSharedMetaFile(cacheKey, filePath, contentKey, sourceHash, style, dims)
+		} else {
+			metaErr = t.writeMetaFile(cacheKey, filePath, sourceHash, style, dims)
+		}
+		if metaErr != nil {
+			logging.Debug("Failed to write meta file for %s: %v", cacheKey, metaErr)
 		}
+		if contentKey != "" {
+			t.removeReplacedThumbnail(cacheKey)
+		}
+		t.markThumbnailFresh(cacheKey, sourceModTime)
+		t.encodeOutputFormat(cacheKey, buf.Bytes())
 	}
 
 	// Track memory used
@@ -394,66 +729,124 @@ func (t *ThumbnailGenerator) GetThumbnail(ctx context.Context, filePath string,
 	return buf.Bytes(), nil
 }
 
+// storePalette extracts the dominant colors from a resized thumbnail and saves
+// them against the file's index path. Failures are logged and otherwise ignored
+// so they never block thumbnail generation.
+func (t *ThumbnailGenerator) storePalette(ctx context.Context, filePath string, thumb image.Image) {
+	if t.withoutDatabase("palette extraction") {
+		return
+	}
+
+	relPath, err := filepath.Rel(t.mediaDir, filePath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		logging.Debug("Skipping palette for %s: not under media directory", filePath)
+		return
+	}
+
+	palette := ExtractPalette(thumb, DefaultPaletteSize)
+	if len(palette) == 0 {
+		return
+	}
+
+	if err := t.db.SetFilePalette(ctx, relPath, palette); err != nil {
+		logging.Debug("Failed to store palette for %s: %v", relPath, err)
+	}
+}
+
 // =============================================================================
 // IMAGE THUMBNAIL GENERATION
 // =============================================================================
 
 func (t *ThumbnailGenerator) generateImageThumbnail(ctx context.Context, filePath string) (image.Image, error) {
+	img, _, err := t.decodeImage(ctx, filePath)
+	return img, err
+}
+
+// decodeImage decodes an image for its thumbnail, trying each decoder in turn,
+// and returns the name of the one that succeeded
+func (t *ThumbnailGenerator) decodeImage(ctx context.Context, filePath string) (image.Image, string, error) {
+	decoded, err := t.decodeSourceImage(ctx, filePath, false)
+	return decoded.img, decoded.decoder, err
+}
+
+// decodedImage is an image decoded for its thumbnail, with what the decoders
+// learned about its file
+type decodedImage struct {
+	img     image.Image
+	decoder string       // decoder* constant of the decoder that succeeded
+	header  *imageHeader // Read before decoding; nil if it couldn't be
+	pages   int          // Pages (frames) reported by libvips; 0 if not known
+}
+
+// decodeSourceImage implements decodeImage. The file's header is read once
+// and shared by the decoders. With countPages, the image is loaded with
+// libvips if possible so its page count is known.
+func (t *ThumbnailGenerator) decodeSourceImage(ctx context.Context, filePath string, countPages bool) (decodedImage, error) {
 	logging.Debug("Opening image: %s", filePath)
 
 	// Check if context is canceled before starting
 	if err := ctx.Err(); err != nil {
-		return nil, fmt.Errorf("context canceled: %w", err)
+		return decodedImage{}, fmt.Errorf("context canceled: %w", err)
 	}
 
 	// Check memory before processing
 	if t.memoryMonitor != nil && !t.memoryMonitor.WaitIfPaused() {
-		return nil, fmt.Errorf("thumbnail generation stopped")
+		return decodedImage{}, fmt.Errorf("thumbnail generation stopped")
 	}
 
+	header, err := readImageHeader(filePath)
+	if err != nil {
+		logging.Debug("Could not get image dimensions for %s: %v, loading with constraints", filePath, err)
+	}
+	decoded := decodedImage{header: header}
+
 	// Detect format from file extension for metrics labeling
 	format := detectImageFormat(filePath)
 
 	// Use constrained image loading to prevent OOM
 	decodeStart := time.Now()
-	img, err := LoadImageConstrained(filePath, MaxImageDimension, MaxImagePixels)
+	maxDimension, maxPixels := t.decodeLimits()
+	decoded.img, decoded.pages, err = loadImageConstrained(filePath, header, maxDimension, maxPixels, countPages)
 	if err == nil {
 		metrics.ThumbnailImageDecodeByFormat.WithLabelValues(format).Observe(time.Since(decodeStart).Seconds())
-		return img, nil
+		decoded.decoder = decoderConstrained
+		return decoded, nil
 	}
 
 	logging.Debug("Constrained load failed for %s: %v, trying fallback methods", filePath, err)
 
 	// Check if context is canceled before trying fallback
 	if err := ctx.Err(); err != nil {
-		return nil, fmt.Errorf("context canceled: %w", err)
+		return decodedImage{}, fmt.Errorf("context canceled: %w", err)
 	}
 
 	// Try standard imaging library
 	decodeStart = time.Now()
-	img, err = imaging.Open(filePath, imaging.AutoOrientation(true))
+	decoded.img, err = imaging.Open(filePath, imaging.AutoOrientation(true))
 	if err == nil {
 		metrics.ThumbnailImageDecodeByFormat.WithLabelValues(format).Observe(time.Since(decodeStart).Seconds())
-		return img, nil
+		decoded.decoder = decoderImaging
+		return decoded, nil
 	}
 
 	logging.Debug("imaging.Open failed for %s: %v, trying ffmpeg fallback", filePath, err)
 
 	// Check if context is canceled before trying ffmpeg
 	if err := ctx.Err(); err != nil {
-		return nil, fmt.Errorf("context canceled: %w", err)
+		return decodedImage{}, fmt.Errorf("context canceled: %w", err)
 	}
 
 	// FFmpeg fallback — format recorded as "ffmpeg_<original>" to distinguish
 	decodeStart = time.Now()
-	img, err = t.generateImageWithFFmpeg(ctx, filePath)
+	decoded.img, err = t.generateImageWithFFmpeg(ctx, filePath)
 	if err != nil {
 		logging.Error("Image thumbnail failed for %s: all decode methods exhausted (constrained load, imaging.Open, ffmpeg): %v", filePath, err)
-		return nil, fmt.Errorf("all image decode methods failed for %s: %w", filePath, err)
+		return decodedImage{}, fmt.Errorf("all image decode methods failed for %s: %w", filePath, err)
 	}
 
 	metrics.ThumbnailImageDecodeByFormat.WithLabelValues(format).Observe(time.Since(decodeStart).Seconds())
-	return img, nil
+	decoded.decoder = decoderFFmpeg
+	return decoded, nil
 }
 
 func (t *ThumbnailGenerator) generateImageWithFFmpeg(ctx context.Context, filePath string) (image.Image, error) {
@@ -475,14 +868,7 @@ func (t *ThumbnailGenerator) generateImageWithFFmpeg(ctx context.Context, filePa
 
 	ffmpegStart := time.Now()
 	// #nosec G204 -- filePath is from the indexed media library, validated above
-	cmd := exec.CommandContext(ctx, ffmpegPath,
-		"-i", filePath,
-		"-vframes", "1",
-		"-f", "image2pipe",
-		"-vcodec", "png",
-		"-pix_fmt", "rgb24",
-		"-",
-	)
+	cmd := exec.CommandContext(ctx, ffmpegPath, ffmpegImageArgs(filePath)...)
 
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
@@ -512,11 +898,47 @@ func (t *ThumbnailGenerator) generateImageWithFFmpeg(ctx context.Context, filePa
 	return img, nil
 }
 
+// ffmpegImageArgs returns the FFmpeg arguments that decode the first frame of
+// an image to PNG on stdout. HEIF/HEIC and AVIF files are ISO BMFF containers
+// rather than plain image files, so they're read with the mov demuxer, the
+// one FFmpeg decodes them with, instead of relying on probing. Formats that
+// can carry transparency keep it in the output.
+func ffmpegImageArgs(filePath string) []string {
+	var args []string
+	pixFmt := "rgb24"
+
+	switch detectImageFormat(filePath) {
+	case "heic", "avif":
+		args = append(args, "-f", "mov")
+		pixFmt = "rgba"
+	case "webp":
+		// Animated WebP gives its first frame, if the FFmpeg build decodes them
+		pixFmt = "rgba"
+	}
+
+	return append(args,
+		"-i", filePath,
+		"-vframes", "1",
+		"-f", "image2pipe",
+		"-vcodec", "png",
+		"-pix_fmt", pixFmt,
+		"-",
+	)
+}
+
 // =============================================================================
 // VIDEO THUMBNAIL GENERATION
 // =============================================================================
 
+// generateVideoThumbnail extracts the thumbnail frame of a video, chosen by
+// videoSeekStrategyFor
 func (t *ThumbnailGenerator) generateVideoThumbnail(ctx context.Context, filePath string) (image.Image, error) {
+	strategy, _ := t.videoSeekStrategyFor(ctx, filePath)
+	return t.extractVideoThumbnail(ctx, filePath, strategy)
+}
+
+// extractVideoThumbnail extracts the frame of a video strategy picks
+func (t *ThumbnailGenerator) extractVideoThumbnail(ctx context.Context, filePath string, strategy VideoSeekStrategy) (image.Image, error) {
 	logging.Debug("Extracting video frame: %s", filePath)
 
 	ffmpegPath, err := exec.LookPath("ffmpeg")
@@ -534,17 +956,24 @@ func (t *ThumbnailGenerator) generateVideoThumbnail(ctx context.Context, filePat
 	timeoutCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	// First attempt: Use 1 second seek (fast, works for most videos)
+	// Percentage and smart seeks need the duration; probe it once and reuse
+	// it for the retry below
+	var duration float64
+	var probeErr error
+	probed := false
+	if strategy.needsDuration() {
+		duration, probeErr = t.getVideoDuration(ctx, filePath)
+		probed = true
+		if probeErr != nil {
+			logging.Debug("Could not probe video duration for %s: %v, using the first frame", filePath, probeErr)
+		}
+	}
+
+	// First attempt: seek according to the configured strategy
+	logging.Debug("Extracting video frame with seek strategy %s for %s", strategy, filePath)
 	ffmpegStart := time.Now()
 	// #nosec G204 -- filePath is from the indexed media library, validated above
-	cmd := exec.CommandContext(timeoutCtx, ffmpegPath,
-		"-i", filePath,
-		"-ss", "00:00:01",
-		"-vframes", "1",
-		"-f", "image2pipe",
-		"-vcodec", "png",
-		"-",
-	)
+	cmd := exec.CommandContext(timeoutCtx, ffmpegPath, strategy.ffmpegArgs(filePath, duration)...)
 
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
@@ -564,7 +993,8 @@ func (t *ThumbnailGenerator) generateVideoThumbnail(ctx context.Context, filePat
 		return img, nil
 	}
 
-	// First attempt failed or produced no output - likely a short video
+	// First attempt failed or produced no output - likely a short video or
+	// a stream the thumbnail filter could not handle
 	logging.Debug("FFmpeg first attempt failed or produced no output for %s: %v, stderr: %s", filePath, err, stderr.String())
 
 	if err := ctx.Err(); err != nil {
@@ -572,8 +1002,10 @@ func (t *ThumbnailGenerator) generateVideoThumbnail(ctx context.Context, filePat
 	}
 
 	// Second attempt: Probe duration and use intelligent seek time
-	duration, probeErr := t.getVideoDuration(ctx, filePath)
-	if probeErr == nil && duration > 0 {
+	if !probed {
+		duration, probeErr = t.getVideoDuration(ctx, filePath)
+	}
+	if probeErr == nil && transcoder.IsKnownDuration(duration) {
 		seekTime := duration * 0.1
 		if seekTime < 0.1 {
 			seekTime = 0.1
@@ -692,6 +1124,9 @@ func (t *ThumbnailGenerator) getVideoDuration(ctx context.Context, filePath stri
 	if err != nil {
 		return 0, fmt.Errorf("failed to parse duration '%s': %w", durationStr, err)
 	}
+	if !transcoder.IsKnownDuration(duration) {
+		return 0, fmt.Errorf("video reports unusable duration '%s'", durationStr)
+	}
 
 	return duration, nil
 }
@@ -730,8 +1165,7 @@ func (t *ThumbnailGenerator) generateFolderThumbnail(ctx context.Context, folder
 func (t *ThumbnailGenerator) findImagesForFolder(ctx context.Context, relativePath string, maxImages int) []image.Image {
 	images := make([]image.Image, 0, maxImages)
 
-	if t.db == nil {
-		logging.Debug("Database not available for folder thumbnail")
+	if t.withoutDatabase("folder thumbnail preview") {
 		return images
 	}
 
@@ -779,6 +1213,19 @@ func (t *ThumbnailGenerator) findImagesForFolder(ctx context.Context, relativePa
 		candidates = candidates[:maxImages]
 	}
 
+	// Optionally sample several frames per video, filling the free cells
+	var videoFrames []int
+	if t.folderVideoFrames.Load() {
+		videos := 0
+		for _, f := range candidates {
+			if f.Type == database.FileTypeVideo {
+				videos++
+			}
+		}
+		videoFrames = framesPerVideo(videos, maxImages-len(candidates))
+	}
+	videoIndex := 0
+
 	// Generate thumbnails for each candidate
 	for _, f := range candidates {
 		// Check if context is canceled
@@ -789,31 +1236,42 @@ func (t *ThumbnailGenerator) findImagesForFolder(ctx context.Context, relativePa
 
 		fullPath := filepath.Join(t.mediaDir, f.Path)
 
-		var img image.Image
+		var components []image.Image
 		var err error
 
 		switch f.Type {
 		case database.FileTypeImage:
+			var img image.Image
 			img, err = t.generateImageThumbnail(ctx, fullPath)
+			components = []image.Image{img}
 		case database.FileTypeVideo:
-			img, err = t.generateVideoThumbnail(ctx, fullPath)
+			if videoFrames != nil {
+				components, err = t.extractVideoFrames(ctx, fullPath, videoFrames[videoIndex])
+				videoIndex++
+			} else {
+				var img image.Image
+				img, err = t.generateVideoThumbnail(ctx, fullPath)
+				components = []image.Image{img}
+			}
 		default:
 			continue
 		}
 
 		if err != nil {
-			logging.Warn("Folder thumbnail: failed to generate component thumbnail for %s (type: %s): %v", f.Path, f.Type, err)
+			logging.WarnSampled("thumbnail:folder-component", "Folder thumbnail: failed to generate component thumbnail for %s (type: %s): %v", f.Path, f.Type, err)
 			continue
 		}
 
-		// Crop to square
-		squareImg := t.cropToSquare(img)
-		// Resize to cell size
-		resizedImg := imaging.Resize(squareImg, folderGridCellSize, folderGridCellSize, imaging.Lanczos)
-		images = append(images, resizedImg)
+		for _, img := range components {
+			// Crop to square
+			squareImg := t.cropToSquare(img)
+			// Resize to cell size
+			resizedImg := imaging.Resize(squareImg, folderGridCellSize, folderGridCellSize, imaging.Lanczos)
+			images = append(images, resizedImg)
 
-		if len(images) >= maxImages {
-			break
+			if len(images) >= maxImages {
+				return images
+			}
 		}
 	}
 
@@ -1140,25 +1598,90 @@ func (t *ThumbnailGenerator) drawEmptyFolderIcon(canvas *image.RGBA) {
 // BACKGROUND GENERATION
 // =============================================================================
 
-// Start begins background thumbnail generation
+// Start begins background thumbnail generation.
+//
+// The background lifecycle is a two-state machine:
+//
+//	stopped --Start--> running --Stop--> stopped
+//
+// A new generator is stopped. Start on a running generator and Stop on a
+// stopped one are no-ops, so the generator can be cycled any number of times
+// (e.g. around a config reload). Start resets the transient progress counters
+// of GenerationStats but keeps LastCompleted; Stop finalizes them (see Stop).
+// Close ends the lifecycle for good.
 func (t *ThumbnailGenerator) Start() {
 	if !t.enabled {
 		logging.Info("Thumbnail generation disabled, skipping background generation")
 		return
 	}
 
+	t.lifecycleMu.Lock()
+	if t.running {
+		t.lifecycleMu.Unlock()
+		logging.Debug("Thumbnail generator already running")
+		return
+	}
+	if isClosed(t.stopChan) {
+		t.stopChan = make(chan struct{})
+	}
+	t.running = true
+	stop := t.stopChan
+	t.lifecycleMu.Unlock()
+
+	t.resetGenerationStats()
+
 	logging.Info("Initializing thumbnail cache metrics...")
 	t.UpdateCacheMetrics()
 
-	go t.backgroundGenerationLoop()
-	go t.cacheMetricsLoop()
+	if !t.withoutDatabase("background generation") {
+		go t.backgroundGenerationLoop(stop)
+	}
+	go t.cacheMetricsLoop(stop)
+	go t.freshnessLoop(stop)
 }
 
-// Stop stops background thumbnail generation
+// withoutDatabase reports whether the generator is in database-less mode,
+// where thumbnails are only generated on demand. Features that need the
+// index call it before using the database: background generation, orphan
+// cleanup, palette extraction and the previews in folder thumbnails, which
+// then show an empty folder. The first call in this mode logs a warning;
+// every call logs the skipped feature at debug level.
+func (t *ThumbnailGenerator) withoutDatabase(feature string) bool {
+	if t.db != nil {
+		return false
+	}
+	t.noDatabaseWarning.Do(func() {
+		logging.Warn("ThumbnailGenerator: no database, generating thumbnails on demand only; background generation, orphan cleanup, palettes and folder previews are disabled")
+	})
+	logging.Debug("ThumbnailGenerator: skipping %s without a database", feature)
+	return true
+}
+
+// Stop stops background thumbnail generation. A run in progress is signalled
+// to stop and given the grace period (see SetStopGracePeriod) to finish its
+// current files; its stats are then finalized: no longer in progress, marked
+// interrupted, and otherwise kept as the last-run summary until the next Start.
+// A run that outlives the grace period keeps going until its current file
+// completes, but is no longer reported as in progress.
 func (t *ThumbnailGenerator) Stop() {
-	if t.stopChan != nil {
+	t.lifecycleMu.Lock()
+	if !isClosed(t.stopChan) {
 		close(t.stopChan)
 	}
+	t.running = false
+	t.lifecycleMu.Unlock()
+
+	grace := time.Duration(t.stopGrace.Load())
+	if !t.waitForGeneration(grace) {
+		logging.Warn("Thumbnail generation still running %v after stop, finalizing its stats", grace)
+	}
+	t.finalizeGenerationStats()
+}
+
+// Close stops the generator and shuts down libvips. Unlike Stop it is final:
+// libvips can't be started again, so call it only on application shutdown.
+func (t *ThumbnailGenerator) Close() {
+	t.Stop()
 
 	// Shutdown libvips if it was initialized
 	if t.enabled {
@@ -1166,24 +1689,88 @@ func (t *ThumbnailGenerator) Stop() {
 	}
 }
 
-// cacheMetricsLoop periodically updates cache metrics
-func (t *ThumbnailGenerator) cacheMetricsLoop() {
+// SetStopGracePeriod sets how long Stop waits for a generation run to finish
+// its current files before finalizing the run's stats. Zero doesn't wait.
+func (t *ThumbnailGenerator) SetStopGracePeriod(d time.Duration) {
+	t.stopGrace.Store(int64(max(d, 0)))
+}
+
+// stopSignal returns the channel closed by Stop for the current lifecycle.
+// Goroutines take it once when they start, so a restart doesn't revive them.
+func (t *ThumbnailGenerator) stopSignal() <-chan struct{} {
+	t.lifecycleMu.Lock()
+	defer t.lifecycleMu.Unlock()
+	return t.stopChan
+}
+
+// isClosed reports whether a stop channel has been closed
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitForGeneration waits up to timeout for an in-progress generation run to
+// return. Reports whether no run is in progress.
+func (t *ThumbnailGenerator) waitForGeneration(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for t.isGenerating.Load() {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(stopPollInterval)
+	}
+	return true
+}
+
+// resetGenerationStats clears the progress counters of the previous run,
+// keeping when it completed. Left alone while a run is still in progress.
+func (t *ThumbnailGenerator) resetGenerationStats() {
+	if t.isGenerating.Load() {
+		return
+	}
+
+	t.generationMu.Lock()
+	t.generationStats = GenerationStats{LastCompleted: t.generationStats.LastCompleted}
+	t.generationMu.Unlock()
+}
+
+// finalizeGenerationStats marks a run cut short by Stop as interrupted and no
+// longer in progress
+func (t *ThumbnailGenerator) finalizeGenerationStats() {
+	t.generationMu.Lock()
+	defer t.generationMu.Unlock()
+
+	if t.generationStats.InProgress {
+		t.generationStats.InProgress = false
+		t.generationStats.Interrupted = true
+		t.generationStats.CurrentFile = ""
+	}
+}
+
+// cacheMetricsLoop periodically enforces the cache size limit and updates
+// cache metrics
+func (t *ThumbnailGenerator) cacheMetricsLoop(stop <-chan struct{}) {
 	ticker := time.NewTicker(cacheMetricsInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
+			t.EnforceCacheLimit()
 			t.UpdateCacheMetrics()
-		case <-t.stopChan:
+		case <-stop:
 			return
 		}
 	}
 }
 
 // backgroundGenerationLoop runs thumbnail generation on index completion and periodic timer
-func (t *ThumbnailGenerator) backgroundGenerationLoop() {
-	logging.Info("Thumbnail generator started (periodic interval: %v)", t.generationInterval)
+func (t *ThumbnailGenerator) backgroundGenerationLoop(stop <-chan struct{}) {
+	logging.Info("Thumbnail generator started (periodic interval: %v)", t.getGenerationInterval())
 
 	// Check if there's already a pending notification (index completed before we started listening)
 	select {
@@ -1197,17 +1784,22 @@ func (t *ThumbnailGenerator) backgroundGenerationLoop() {
 		case <-t.onIndexComplete:
 			logging.Info("Initial index complete, starting full thumbnail generation")
 			t.runGeneration(false)
-		case <-t.stopChan:
+		case <-stop:
 			return
 		}
 	}
 
 	// Set up periodic timer
-	ticker := time.NewTicker(t.generationInterval)
+	ticker := time.NewTicker(t.getGenerationInterval())
 	defer ticker.Stop()
 
 	for {
 		select {
+		case <-t.intervalReset:
+			interval := t.getGenerationInterval()
+			ticker.Reset(interval)
+			logging.Info("Thumbnail generation interval changed to %v", interval)
+
 		case <-t.onIndexComplete:
 			logging.Info("Index complete, running incremental thumbnail generation")
 			t.runGeneration(true)
@@ -1216,7 +1808,7 @@ func (t *ThumbnailGenerator) backgroundGenerationLoop() {
 			logging.Info("Periodic thumbnail generation triggered")
 			t.runGeneration(true)
 
-		case <-t.stopChan:
+		case <-stop:
 			logging.Info("Thumbnail generator stopped")
 			return
 		}
@@ -1225,7 +1817,7 @@ func (t *ThumbnailGenerator) backgroundGenerationLoop() {
 
 // runGeneration performs thumbnail generation (incremental or full)
 func (t *ThumbnailGenerator) runGeneration(incremental bool) {
-	if !t.enabled || t.db == nil {
+	if !t.enabled || t.withoutDatabase("background generation") {
 		return
 	}
 
@@ -1240,6 +1832,7 @@ func (t *ThumbnailGenerator) runGeneration(incremental bool) {
 
 	ctx := context.Background()
 	startTime := time.Now()
+	stop := t.stopSignal()
 
 	metrics.ThumbnailGeneratorRunning.Set(1)
 	defer metrics.ThumbnailGeneratorRunning.Set(0)
@@ -1256,13 +1849,35 @@ func (t *ThumbnailGenerator) runGeneration(incremental bool) {
 			logging.Info("No previous thumbnail run found, performing full generation")
 			incremental = false
 		}
+	} else {
+		lastRun, err = t.db.GetLastThumbnailRun(ctx)
+	}
+
+	// Without a previous run (first start, or after RebuildAll) every
+	// thumbnail is generated, so the initial worker cap applies
+	initial := !incremental && err == nil && lastRun.IsZero()
+	t.initialRun.Store(initial)
+	defer t.initialRun.Store(false)
+
+	// A run interrupted by a restart is picked up where it left off. It
+	// counts as starting when the interrupted run did, so the next
+	// incremental run covers changes made since then.
+	runStart := startTime
+	resume := t.loadCheckpoint(ctx, incremental)
+	if resume != nil {
+		runStart = resume.StartedAt
 	}
+	t.startCheckpoint(runStart, incremental, resume != nil)
+	completed := false
+	defer func() { t.clearCheckpoint(ctx, completed) }()
 
 	t.generationMu.Lock()
 	t.generationStats = GenerationStats{
 		InProgress:    true,
 		StartedAt:     startTime,
 		IsIncremental: incremental,
+		IsInitial:     initial,
+		Resumed:       resume != nil,
 	}
 	t.generationMu.Unlock()
 
@@ -1301,18 +1916,54 @@ func (t *ThumbnailGenerator) runGeneration(incremental bool) {
 		logging.Info("Processing %d files for thumbnail generation", len(files))
 	}
 
+	files, largeFiles := t.splitLargeFiles(files)
+
+	if resume != nil {
+		var skipped int
+		files, folders, largeFiles, skipped = skipCompleted(resume, files, folders, largeFiles)
+		logging.Info("Resuming thumbnail generation interrupted during %s pass (started %v, last done %q): skipping %d completed files",
+			resume.Phase, resume.StartedAt.Format(time.RFC3339), resume.LastPath, skipped)
+	}
+
 	t.generationMu.Lock()
-	t.generationStats.TotalFiles = len(files) + len(folders)
+	t.generationStats.TotalFiles = len(files) + len(largeFiles) + len(folders)
+	t.generationStats.LargeFilesDeferred = len(largeFiles)
+	t.generationStats.LargeFilesPending = len(largeFiles)
 	t.generationMu.Unlock()
 
 	// Process updated files
 	if len(files) > 0 {
-		t.processFilesForGeneration(ctx, files, incremental)
+		t.processFilesForGeneration(ctx, stop, files, incremental)
 	}
 
 	// Process folders with updated contents (invalidate and regenerate)
-	if len(folders) > 0 {
-		t.processFoldersForGeneration(ctx, folders)
+	if len(folders) > 0 && !isClosed(stop) {
+		t.advanceCheckpoint(ctx, checkpointPhaseFolders, "")
+		t.processFoldersForGeneration(ctx, stop, folders)
+	}
+
+	// Large files last, so the rest of the library is usable meanwhile
+	if len(largeFiles) > 0 && !isClosed(stop) {
+		t.advanceCheckpoint(ctx, checkpointPhaseLarge, "")
+		t.processLargeFiles(ctx, stop, largeFiles, incremental)
+	}
+
+	// A stopped run leaves the last run time alone, so the next run picks up
+	// the files it didn't get to, and saves its checkpoint so the next run
+	// skips the ones it did
+	if isClosed(stop) {
+		t.saveCheckpoint(ctx)
+		t.generationMu.Lock()
+		t.generationStats.Interrupted = true
+		t.generationMu.Unlock()
+		t.finishGeneration(startTime)
+		return
+	}
+
+	// Thumbnails cached before palette extraction was enabled have no
+	// palette and aren't regenerated, so take one from the cached copy
+	if t.paletteEnabled.Load() {
+		t.backfillPalettes(ctx, stop)
 	}
 
 	// Clean up orphaned thumbnails
@@ -1323,18 +1974,20 @@ func (t *ThumbnailGenerator) runGeneration(incremental bool) {
 	t.generationMu.Unlock()
 
 	// Update last run time
-	if err := t.db.SetLastThumbnailRun(ctx, startTime); err != nil {
+	if err := t.db.SetLastThumbnailRun(ctx, runStart); err != nil {
 		logging.Error("Failed to update last thumbnail run time: %v", err)
 	}
+	completed = true
 
 	t.finishGeneration(startTime)
 }
 
-// processFilesForGeneration processes files for thumbnail generation
-func (t *ThumbnailGenerator) processFilesForGeneration(ctx context.Context, files []database.MediaFile, incremental bool) {
+// processFilesForGeneration processes files for thumbnail generation until
+// the run's stop channel is closed
+func (t *ThumbnailGenerator) processFilesForGeneration(ctx context.Context, stop <-chan struct{}, files []database.MediaFile, incremental bool) {
 	for i := 0; i < len(files); i += generationBatchSize {
 		select {
-		case <-t.stopChan:
+		case <-stop:
 			return
 		case <-ctx.Done():
 			return
@@ -1348,15 +2001,27 @@ func (t *ThumbnailGenerator) processFilesForGeneration(ctx context.Context, file
 
 		batch := files[i:end]
 
-		// For incremental updates, invalidate existing thumbnails first
-		if incremental {
+		// For incremental updates, invalidate existing thumbnails first. With
+		// stale-while-revalidate they are kept and replaced in place, so
+		// requests get the old thumbnail until the new one is written.
+		if incremental && !t.staleWhileRevalidate.Load() {
 			for _, file := range batch {
 				fullPath := filepath.Join(t.mediaDir, file.Path)
 				_ = t.InvalidateThumbnail(fullPath)
 			}
 		}
 
-		t.processBatch(ctx, batch)
+		t.processBatch(ctx, stop, batch)
+
+		// A batch cut short by a stop isn't complete, so the checkpoint stays
+		// at the previous one
+		if !isClosed(stop) {
+			phase := checkpointPhaseFiles
+			if t.largeFilePass.Load() {
+				phase = checkpointPhaseLarge
+			}
+			t.advanceCheckpoint(ctx, phase, batch[len(batch)-1].Path)
+		}
 		time.Sleep(generationBatchDelay)
 
 		if (i+generationBatchSize)%500 == 0 || end == len(files) {
@@ -1373,23 +2038,30 @@ func (t *ThumbnailGenerator) processFilesForGeneration(ctx context.Context, file
 }
 
 // processFoldersForGeneration invalidates and regenerates folder thumbnails
-func (t *ThumbnailGenerator) processFoldersForGeneration(ctx context.Context, folders []database.MediaFile) {
+// until the run's stop channel is closed
+func (t *ThumbnailGenerator) processFoldersForGeneration(ctx context.Context, stop <-chan struct{}, folders []database.MediaFile) {
 	logging.Info("Regenerating %d folder thumbnails due to content changes", len(folders))
 
 	for _, folder := range folders {
 		select {
-		case <-t.stopChan:
+		case <-stop:
 			return
 		default:
 		}
 
 		fullPath := filepath.Join(t.mediaDir, folder.Path)
 
-		// Invalidate existing thumbnail
-		_ = t.InvalidateThumbnail(fullPath)
+		var err error
+		if t.staleWhileRevalidate.Load() {
+			// Replace in place so the old composite is served meanwhile
+			_, err = t.regenerateThumbnail(ctx, fullPath, database.FileTypeFolder)
+		} else {
+			// Invalidate existing thumbnail
+			_ = t.InvalidateThumbnail(fullPath)
 
-		// Generate new thumbnail
-		_, err := t.GetThumbnail(ctx, fullPath, database.FileTypeFolder)
+			// Generate new thumbnail
+			_, err = t.GetThumbnail(ctx, fullPath, database.FileTypeFolder)
+		}
 
 		t.generationMu.Lock()
 		t.generationStats.Processed++
@@ -1408,8 +2080,7 @@ func (t *ThumbnailGenerator) processFoldersForGeneration(ctx context.Context, fo
 func (t *ThumbnailGenerator) cleanupOrphanedThumbnails(ctx context.Context) (orphansRemoved, legacyRemoved int) {
 	logging.Info("Checking for orphaned thumbnails...")
 
-	if t.db == nil {
-		logging.Debug("Database not available for orphan cleanup")
+	if t.withoutDatabase("orphan cleanup") {
 		return 0, 0
 	}
 
@@ -1433,8 +2104,9 @@ func (t *ThumbnailGenerator) cleanupOrphanedThumbnails(ctx context.Context) (orp
 
 		name := entry.Name()
 
-		// Skip non-thumbnail files (including .meta files)
-		if !strings.HasSuffix(name, ".jpg") && !strings.HasSuffix(name, ".png") {
+		// Skip non-thumbnail files (including .meta files) and scaled
+		// variants, which cleanupOrphanedVariants handles
+		if (!strings.HasSuffix(name, ".jpg") && !strings.HasSuffix(name, ".png")) || isVariantFile(name) {
 			continue
 		}
 
@@ -1442,7 +2114,18 @@ func (t *ThumbnailGenerator) cleanupOrphanedThumbnails(ctx context.Context) (orp
 		cachePath := filepath.Join(t.cacheDir, cacheKey)
 
 		// Read the metadata file to get the source path
-		sourcePath, err := t.readMetaFile(cacheKey)
+		meta, err := t.readMetaFile(cacheKey)
+		if err == nil && t.hasOtherSize(meta) {
+			// Generated for a previous THUMBNAIL_SIZE; its cache key is no
+			// longer used, and the current size is generated on demand
+			if err := os.Remove(cachePath); err != nil {
+				logging.Debug("Failed to remove thumbnail of another size %s: %v", cacheKey, err)
+			} else {
+				t.deleteMetaFile(cacheKey)
+				orphansRemoved++
+			}
+			continue
+		}
 		if err != nil {
 			// No meta file - this is a legacy thumbnail without tracking
 			// Remove it; it will be regenerated on demand if source still exists
@@ -1456,7 +2139,7 @@ func (t *ThumbnailGenerator) cleanupOrphanedThumbnails(ctx context.Context) (orp
 		}
 
 		// Check if source path is still in the index
-		relativePath := strings.TrimPrefix(sourcePath, t.mediaDir)
+		relativePath := strings.TrimPrefix(meta.SourcePath, t.mediaDir)
 		relativePath = strings.TrimPrefix(relativePath, "/")
 
 		if _, exists := indexedPaths[relativePath]; !exists {
@@ -1471,6 +2154,18 @@ func (t *ThumbnailGenerator) cleanupOrphanedThumbnails(ctx context.Context) (orp
 		}
 	}
 
+	// Shared thumbnails are reference-counted across all .meta files and only
+	// removed once no indexed source references them
+	referencesRemoved, sharedRemoved := t.cleanupSharedThumbnails(indexedPaths)
+	orphansRemoved += referencesRemoved
+
+	// Format variants go with the thumbnails (and .meta files) removed above
+	orphansRemoved += t.cleanupOrphanedVariants()
+	orphansRemoved += t.cleanupOrphanedScrubs(indexedPaths)
+	if sharedRemoved > 0 {
+		logging.Info("Thumbnail cleanup: removed %d unreferenced shared thumbnails", sharedRemoved)
+	}
+
 	if orphansRemoved > 0 || legacyRemoved > 0 {
 		logging.Info("Thumbnail cleanup: removed %d orphaned, %d legacy (no meta file)", orphansRemoved, legacyRemoved)
 	}
@@ -1484,12 +2179,19 @@ func (t *ThumbnailGenerator) finishGeneration(startTime time.Time) {
 
 	t.generationMu.Lock()
 	t.generationStats.InProgress = false
-	t.generationStats.LastCompleted = time.Now()
+	if !t.generationStats.Interrupted {
+		t.generationStats.LastCompleted = time.Now()
+	}
 	t.generationStats.CurrentFile = ""
 	stats := t.generationStats
 	t.generationMu.Unlock()
 
-	logging.Info("Thumbnail generation complete in %v: generated %d, skipped %d, failed %d, folders updated %d, orphans removed %d",
+	outcome := "complete"
+	if stats.Interrupted {
+		outcome = "stopped"
+	}
+	logging.Info("Thumbnail generation %s in %v: generated %d, skipped %d, failed %d, folders updated %d, orphans removed %d",
+		outcome,
 		duration,
 		stats.Generated,
 		stats.Skipped,
@@ -1518,12 +2220,16 @@ func (t *ThumbnailGenerator) finishGeneration(startTime time.Time) {
 }
 
 // processBatch processes a batch of files for thumbnail generation using parallel workers
-func (t *ThumbnailGenerator) processBatch(ctx context.Context, files []database.MediaFile) {
+func (t *ThumbnailGenerator) processBatch(ctx context.Context, stop <-chan struct{}, files []database.MediaFile) {
 	if len(files) == 0 {
 		return
 	}
 
 	numWorkers := workers.ForMixed(maxThumbnailWorkers)
+	if t.initialRun.Load() {
+		numWorkers = workers.LimitInitial(numWorkers)
+	}
+	numWorkers = t.limitLargeFileWorkers(numWorkers)
 
 	if t.memoryMonitor != nil && t.memoryMonitor.ShouldThrottle() {
 		numWorkers = max(1, numWorkers/2)
@@ -1540,7 +2246,7 @@ func (t *ThumbnailGenerator) processBatch(ctx context.Context, files []database.
 	var wg sync.WaitGroup
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
-		go t.thumbnailWorker(ctx, i, jobs, results, &wg)
+		go t.thumbnailWorker(ctx, stop, i, jobs, results, &wg)
 	}
 
 	go func() {
@@ -1548,7 +2254,7 @@ func (t *ThumbnailGenerator) processBatch(ctx context.Context, files []database.
 		for _, file := range files {
 			select {
 			case jobs <- file:
-			case <-t.stopChan:
+			case <-stop:
 				return
 			case <-ctx.Done():
 				return
@@ -1564,6 +2270,9 @@ func (t *ThumbnailGenerator) processBatch(ctx context.Context, files []database.
 	for result := range results {
 		t.generationMu.Lock()
 		t.generationStats.Processed++
+		if t.largeFilePass.Load() {
+			t.generationStats.LargeFilesPending--
+		}
 
 		switch {
 		case result.err != nil && errors.Is(result.err, errSkipped):
@@ -1582,7 +2291,7 @@ func (t *ThumbnailGenerator) processBatch(ctx context.Context, files []database.
 }
 
 // thumbnailWorker processes thumbnail generation jobs
-func (t *ThumbnailGenerator) thumbnailWorker(ctx context.Context, workerID int, jobs <-chan database.MediaFile, results chan<- thumbnailResult, wg *sync.WaitGroup) {
+func (t *ThumbnailGenerator) thumbnailWorker(ctx context.Context, stop <-chan struct{}, workerID int, jobs <-chan database.MediaFile, results chan<- thumbnailResult, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	logging.Debug("Thumbnail worker %d started", workerID)
@@ -1594,7 +2303,7 @@ func (t *ThumbnailGenerator) thumbnailWorker(ctx context.Context, workerID int,
 
 	go func() {
 		select {
-		case <-t.stopChan:
+		case <-stop:
 			cancel()
 		case <-workerCtx.Done():
 		}
@@ -1602,7 +2311,7 @@ func (t *ThumbnailGenerator) thumbnailWorker(ctx context.Context, workerID int,
 
 	for file := range jobs {
 		select {
-		case <-t.stopChan:
+		case <-stop:
 			return
 		case <-workerCtx.Done():
 			return
@@ -1617,14 +2326,15 @@ func (t *ThumbnailGenerator) thumbnailWorker(ctx context.Context, workerID int,
 		t.generationStats.CurrentFile = file.Path
 		t.generationMu.Unlock()
 
-		// Check if thumbnail already exists (for non-incremental runs)
-		if t.thumbnailExists(file.Path, file.Type) {
+		// Check if an up-to-date thumbnail already exists
+		if t.thumbnailUpToDate(file) {
 			results <- thumbnailResult{path: file.Path, skipped: true, err: errSkipped}
 			continue
 		}
 
+		// Background generation always waits for the fresh thumbnail
 		fullPath := filepath.Join(t.mediaDir, file.Path)
-		_, err := t.GetThumbnail(workerCtx, fullPath, file.Type)
+		_, err := t.getThumbnail(workerCtx, fullPath, file.Type, false)
 
 		results <- thumbnailResult{
 			path:    file.Path,
@@ -1642,10 +2352,8 @@ func (t *ThumbnailGenerator) thumbnailWorker(ctx context.Context, workerID int,
 func (t *ThumbnailGenerator) thumbnailExists(filePath string, fileType database.FileType) bool {
 	fullPath := filepath.Join(t.mediaDir, filePath)
 	cacheKey := t.getCacheKey(fullPath, fileType)
-	cachePath := filepath.Join(t.cacheDir, cacheKey)
 
-	_, err := os.Stat(cachePath)
-	return err == nil
+	return t.cachedThumbnailExists(cacheKey)
 }
 
 // =============================================================================
@@ -1662,10 +2370,16 @@ func (t *ThumbnailGenerator) InvalidateThumbnail(filePath string) error {
 	for _, fileType := range []database.FileType{database.FileTypeImage, database.FileTypeFolder} {
 		cacheKey := t.getCacheKey(filePath, fileType)
 		cachePath := filepath.Join(t.cacheDir, cacheKey)
+		t.removeVariants(cacheKey)
 
 		if err := os.Remove(cachePath); err == nil {
 			t.deleteMetaFile(cacheKey)
 			logging.Debug("Invalidated thumbnail: %s", cachePath)
+		} else if t.readMetaContentKey(cacheKey) != "" {
+			// Shared thumbnail: drop only this path's reference. Orphan cleanup
+			// removes the shared file once no source references it.
+			t.deleteMetaFile(cacheKey)
+			logging.Debug("Invalidated shared thumbnail reference: %s", filePath)
 		}
 	}
 
@@ -1706,6 +2420,14 @@ func (t *ThumbnailGenerator) InvalidateAll() (int, error) {
 		}
 	}
 
+	// Remove shared thumbnails
+	if contentEntries, err := os.ReadDir(t.contentDir()); err == nil {
+		count += len(contentEntries)
+		if err := os.RemoveAll(t.contentDir()); err != nil {
+			logging.Warn("Failed to delete shared thumbnails: %v", err)
+		}
+	}
+
 	logging.Info("Invalidated %d cached thumbnails", count)
 	t.UpdateCacheMetrics()
 
@@ -1750,6 +2472,19 @@ func (t *ThumbnailGenerator) UpdateCacheMetrics() {
 		}
 	}
 
+	// Shared thumbnails from deduplication
+	if contentEntries, err := os.ReadDir(t.contentDir()); err == nil {
+		for _, entry := range contentEntries {
+			if entry.IsDir() {
+				continue
+			}
+			cacheCount++
+			if info, err := entry.Info(); err == nil {
+				cacheSize += info.Size()
+			}
+		}
+	}
+
 	t.cacheMetricsMu.Lock()
 	t.lastCacheSize = cacheSize
 	t.lastCacheCount = cacheCount
@@ -1776,8 +2511,9 @@ func (t *ThumbnailGenerator) IsGenerating() bool {
 // GetStatus returns the current status of the thumbnail generator
 func (t *ThumbnailGenerator) GetStatus() ThumbnailStatus {
 	status := ThumbnailStatus{
-		Enabled:  t.enabled,
-		CacheDir: t.cacheDir,
+		Enabled:      t.enabled,
+		DatabaseLess: t.db == nil,
+		CacheDir:     t.cacheDir,
 	}
 
 	if !t.enabled {
@@ -1817,12 +2553,16 @@ func (t *ThumbnailGenerator) RebuildAll() {
 		logging.Info("Cleared %d thumbnails, starting rebuild", count)
 	}
 
-	// Clear last run time to force full generation
+	// Clear last run time to force full generation, and any checkpoint so
+	// the rebuild doesn't skip files whose thumbnails were just removed
 	if t.db != nil {
 		ctx := context.Background()
 		if err := t.db.SetLastThumbnailRun(ctx, time.Time{}); err != nil {
 			logging.Error("Failed to clear last thumbnail run time: %v", err)
 		}
+		if err := t.db.SetThumbnailCheckpoint(ctx, nil); err != nil {
+			logging.Error("Failed to clear thumbnail generation checkpoint: %v", err)
+		}
 	}
 
 	go t.runGeneration(false)
@@ -1839,6 +2579,7 @@ func (t *ThumbnailGenerator) GetCacheSize() (size int64, count int, err error) {
 	now := time.Now().Unix()
 	if lastUpdate > 0 && (now-lastUpdate) < 120 {
 		// Return cached values
+		t.sizeCacheHits.Add(1)
 		return t.cachedSize.Load(), int(t.cachedCount.Load()), nil
 	}
 
@@ -1850,8 +2591,10 @@ func (t *ThumbnailGenerator) GetCacheSize() (size int64, count int, err error) {
 	// Double-check after acquiring lock
 	lastUpdate = t.lastCacheUpdate.Load()
 	if lastUpdate > 0 && (now-lastUpdate) < 120 {
+		t.sizeCacheHits.Add(1)
 		return t.cachedSize.Load(), int(t.cachedCount.Load()), nil
 	}
+	t.sizeCacheMisses.Add(1)
 
 	// Walk directory to calculate fresh values
 	var newSize int64
@@ -1886,6 +2629,28 @@ func (t *ThumbnailGenerator) GetCacheSize() (size int64, count int, err error) {
 	return 0, 0, err
 }
 
+// SizeCacheStats reports the usage of the cached GetCacheSize result.
+func (t *ThumbnailGenerator) SizeCacheStats() CacheStats {
+	stats := CacheStats{
+		Hits:   t.sizeCacheHits.Load(),
+		Misses: t.sizeCacheMisses.Load(),
+	}
+	if t.lastCacheUpdate.Load() > 0 {
+		stats.Entries = 1
+		stats.MemoryBytes = 24 // size, count and timestamp
+	}
+	return stats
+}
+
+// FlushSizeCache discards the cached cache size so the next GetCacheSize call
+// walks the cache directory. Returns the number of entries removed.
+func (t *ThumbnailGenerator) FlushSizeCache() int {
+	if t.lastCacheUpdate.Swap(0) > 0 {
+		return 1
+	}
+	return 0
+}
+
 // =============================================================================
 // UTILITY FUNCTIONS
 // =============================================================================
@@ -1928,7 +2693,7 @@ func validateFilePath(filePath string) error {
 // detectImageFormat returns a normalized format label from a file path's extension.
 // Returns "unknown" for unrecognized extensions.
 func detectImageFormat(filePath string) string {
-	ext := strings.ToLower(filepath.Ext(filePath))
+	ext := mediatypes.NormalizeExt(filePath)
 	switch ext {
 	case ".jpg", ".jpeg":
 		return formatJPEG
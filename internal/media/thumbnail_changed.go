@@ -0,0 +1,82 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"media-viewer/internal/filesystem"
+)
+
+// ChangedFileMode selects what happens when a source file changes while its
+// thumbnail is generated, as it does while it is still being copied or
+// uploaded. The thumbnail of a half-written file is never cached, except
+// with ChangedFilesOff.
+type ChangedFileMode string
+
+const (
+	// ChangedFilesRetry generates the thumbnail again; if the file changes
+	// again meanwhile, the thumbnail is returned but not cached.
+	ChangedFilesRetry ChangedFileMode = "retry"
+	// ChangedFilesSkip returns the thumbnail without caching it, leaving the
+	// file to the next request or generation run.
+	ChangedFilesSkip ChangedFileMode = "skip"
+	// ChangedFilesOff doesn't check whether files change.
+	ChangedFilesOff ChangedFileMode = "off"
+)
+
+// errSourceChanged reports that a source file changed while its thumbnail
+// was generated, so the thumbnail was not cached
+var errSourceChanged = errors.New("source file changed during thumbnail generation")
+
+// ParseChangedFileMode parses a THUMBNAIL_CHANGED_FILES value. An empty value
+// selects ChangedFilesRetry.
+func ParseChangedFileMode(value string) (ChangedFileMode, error) {
+	switch mode := ChangedFileMode(strings.TrimSpace(strings.ToLower(value))); mode {
+	case "":
+		return ChangedFilesRetry, nil
+	case ChangedFilesRetry, ChangedFilesSkip, ChangedFilesOff:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q (use retry, skip or off)", value)
+	}
+}
+
+// SetChangedFileMode sets what happens when a file changes while its
+// thumbnail is generated.
+func (t *ThumbnailGenerator) SetChangedFileMode(mode ChangedFileMode) {
+	t.changedFiles.Store(&mode)
+}
+
+// changedFileMode returns the mode set with SetChangedFileMode
+func (t *ThumbnailGenerator) changedFileMode() ChangedFileMode {
+	if mode := t.changedFiles.Load(); mode != nil {
+		return *mode
+	}
+	return ChangedFilesRetry
+}
+
+// sourceState is the size and modification time of a source file, which
+// change while it is written
+type sourceState struct {
+	size    int64
+	modTime time.Time
+}
+
+// statSource returns the current state of a source file
+func statSource(filePath string) (sourceState, error) {
+	info, err := filesystem.StatWithRetry(filePath, filesystem.DefaultRetryConfig())
+	if err != nil {
+		return sourceState{}, err
+	}
+	return sourceState{size: info.Size(), modTime: info.ModTime()}, nil
+}
+
+// sourceUnchanged reports whether a source file still has the state it had
+// before its thumbnail was generated. A file that can no longer be read has
+// changed.
+func sourceUnchanged(filePath string, before sourceState) bool {
+	after, err := statSource(filePath)
+	return err == nil && after.size == before.size && after.modTime.Equal(before.modTime)
+}
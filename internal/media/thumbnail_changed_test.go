@@ -0,0 +1,114 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestParseChangedFileMode(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected ChangedFileMode
+		wantErr  bool
+	}{
+		{"", ChangedFilesRetry, false},
+		{"retry", ChangedFilesRetry, false},
+		{"Skip", ChangedFilesSkip, false},
+		{" off ", ChangedFilesOff, false},
+		{"wait", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseChangedFileMode(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseChangedFileMode(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if got != tt.expected {
+			t.Errorf("ParseChangedFileMode(%q) = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestSourceUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upload.txt")
+	if err := os.WriteFile(path, []byte("partial"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	before, err := statSource(path)
+	if err != nil {
+		t.Fatalf("statSource failed: %v", err)
+	}
+	if !sourceUnchanged(path, before) {
+		t.Error("Expected an untouched file to be unchanged")
+	}
+
+	if err := os.WriteFile(path, []byte("partial, then complete"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if sourceUnchanged(path, before) {
+		t.Error("Expected a file that grew to have changed")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if sourceUnchanged(path, before) {
+		t.Error("Expected a removed file to have changed")
+	}
+}
+
+func TestGenerateAndCacheChangedSource(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(t.TempDir(), mediaDir, true, nil, time.Hour, nil)
+	gen.SetOtherThumbnailMode(OtherThumbnailsBadge)
+	ctx := context.Background()
+
+	path := filepath.Join(mediaDir, "copying.txt")
+	if err := os.WriteFile(path, []byte("first chunk"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	cacheKey := gen.getCacheKey(path, database.FileTypeOther)
+	cachePath := filepath.Join(gen.cacheDir, cacheKey)
+
+	// A file that changed since it was stat'ed gets a thumbnail, but not a cached one
+	current, err := statSource(path)
+	if err != nil {
+		t.Fatalf("statSource failed: %v", err)
+	}
+	stale := sourceState{size: current.size - 1, modTime: current.modTime.Add(-time.Second)}
+	data, err := gen.generateAndCacheOnce(ctx, path, database.FileTypeOther, cacheKey, stale.modTime, time.Now(), &stale)
+	if !errors.Is(err, errSourceChanged) {
+		t.Fatalf("Expected errSourceChanged, got %v", err)
+	}
+	if len(data) == 0 {
+		t.Error("Expected the thumbnail returned despite the change")
+	}
+	if _, err := os.Stat(cachePath); !os.IsNotExist(err) {
+		t.Errorf("Expected no cached thumbnail, stat error = %v", err)
+	}
+
+	// An unchanged file is cached as usual, whatever the mode
+	for _, mode := range []ChangedFileMode{ChangedFilesSkip, ChangedFilesRetry, ChangedFilesOff} {
+		gen.SetChangedFileMode(mode)
+		_ = os.Remove(cachePath)
+
+		data, err := gen.generateAndCache(ctx, path, database.FileTypeOther, cacheKey, current.modTime, time.Now())
+		if err != nil || len(data) == 0 {
+			t.Fatalf("%s: generateAndCache = %d bytes, %v", mode, len(data), err)
+		}
+		if _, err := os.Stat(cachePath); err != nil {
+			t.Errorf("%s: expected a cached thumbnail: %v", mode, err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package media
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/logging"
+)
+
+// checkpointInterval is the least time between saved checkpoints. A run
+// killed outright redoes at most this much work; a stopped run saves its
+// checkpoint on the way out. Runs shorter than this never save one.
+const checkpointInterval = 30 * time.Second
+
+// Passes of a generation run, in the order they run
+const (
+	checkpointPhaseFiles   = "files"
+	checkpointPhaseFolders = "folders"
+	checkpointPhaseLarge   = "large"
+)
+
+// generationCheckpoint tracks the progress of the running generation
+type generationCheckpoint struct {
+	state  database.ThumbnailCheckpoint
+	saved  time.Time
+	stored bool // A checkpoint is in the database, saved or resumed from
+}
+
+// loadCheckpoint returns the checkpoint of an interrupted run that this run
+// can resume, or nil. A checkpoint from a run of the other kind is discarded:
+// full and incremental runs process different files in a different order,
+// and once this run completes the other run's progress no longer applies.
+func (t *ThumbnailGenerator) loadCheckpoint(ctx context.Context, incremental bool) *database.ThumbnailCheckpoint {
+	checkpoint, err := t.db.GetThumbnailCheckpoint(ctx)
+	if err != nil {
+		logging.Warn("Failed to read thumbnail generation checkpoint, starting over: %v", err)
+		return nil
+	}
+	if checkpoint == nil {
+		return nil
+	}
+	if checkpoint.Incremental != incremental {
+		logging.Info("Discarding thumbnail generation checkpoint from a different kind of run")
+		if err := t.db.SetThumbnailCheckpoint(ctx, nil); err != nil {
+			logging.Warn("Failed to clear thumbnail generation checkpoint: %v", err)
+		}
+		return nil
+	}
+	return checkpoint
+}
+
+// startCheckpoint begins tracking the progress of a run; resumed is whether
+// it continues from a stored checkpoint
+func (t *ThumbnailGenerator) startCheckpoint(startedAt time.Time, incremental, resumed bool) {
+	t.checkpoint = &generationCheckpoint{
+		state: database.ThumbnailCheckpoint{
+			StartedAt:   startedAt,
+			Incremental: incremental,
+			Phase:       checkpointPhaseFiles,
+		},
+		saved:  time.Now(),
+		stored: resumed,
+	}
+}
+
+// advanceCheckpoint records that the run has completed every file up to
+// lastPath in phase, saving it if checkpointInterval has passed since the
+// last save. Does nothing outside a run.
+func (t *ThumbnailGenerator) advanceCheckpoint(ctx context.Context, phase, lastPath string) {
+	if t.checkpoint == nil {
+		return
+	}
+	t.checkpoint.state.Phase = phase
+	t.checkpoint.state.LastPath = lastPath
+	if time.Since(t.checkpoint.saved) >= checkpointInterval {
+		t.saveCheckpoint(ctx)
+	}
+}
+
+// saveCheckpoint stores the run's progress
+func (t *ThumbnailGenerator) saveCheckpoint(ctx context.Context) {
+	if t.checkpoint == nil {
+		return
+	}
+	state := t.checkpoint.state
+	if err := t.db.SetThumbnailCheckpoint(ctx, &state); err != nil {
+		logging.Warn("Failed to save thumbnail generation checkpoint: %v", err)
+		return
+	}
+	t.checkpoint.saved = time.Now()
+	t.checkpoint.stored = true
+}
+
+// clearCheckpoint stops tracking the run's progress and, if the run
+// completed, removes the stored checkpoint
+func (t *ThumbnailGenerator) clearCheckpoint(ctx context.Context, completed bool) {
+	stored := t.checkpoint != nil && t.checkpoint.stored
+	t.checkpoint = nil
+	if !completed || !stored {
+		return
+	}
+	if err := t.db.SetThumbnailCheckpoint(ctx, nil); err != nil {
+		logging.Warn("Failed to clear thumbnail generation checkpoint: %v", err)
+	}
+}
+
+// skipCompleted drops the files, folders and large files an interrupted run
+// already processed, returning what is left and how many were dropped
+func skipCompleted(checkpoint *database.ThumbnailCheckpoint, files, folders, large []database.MediaFile) (remainingFiles, remainingFolders, remainingLarge []database.MediaFile, skipped int) {
+	done := func(file database.MediaFile) bool {
+		return checkpoint.LastPath != "" && !runOrderLess(checkpoint.Incremental, checkpoint.LastPath, file.Path)
+	}
+
+	switch checkpoint.Phase {
+	case checkpointPhaseFiles:
+		remainingFiles = filterFiles(files, done)
+		remainingFolders, remainingLarge = folders, large
+	case checkpointPhaseFolders:
+		// Folders are regenerated as a whole; they have no stable order to resume from
+		remainingFolders, remainingLarge = folders, large
+	case checkpointPhaseLarge:
+		remainingLarge = filterFiles(large, done)
+	default:
+		return files, folders, large, 0
+	}
+
+	skipped = len(files) + len(folders) + len(large) -
+		len(remainingFiles) - len(remainingFolders) - len(remainingLarge)
+	return remainingFiles, remainingFolders, remainingLarge, skipped
+}
+
+// filterFiles returns the files for which done reports false
+func filterFiles(files []database.MediaFile, done func(database.MediaFile) bool) []database.MediaFile {
+	remaining := make([]database.MediaFile, 0, len(files))
+	for _, file := range files {
+		if !done(file) {
+			remaining = append(remaining, file)
+		}
+	}
+	return remaining
+}
+
+// runOrderLess reports whether path a is processed before path b. Full runs
+// go shallowest first (GetAllMediaFilesForThumbnails), incremental runs by
+// path (GetFilesUpdatedSince).
+func runOrderLess(incremental bool, a, b string) bool {
+	if !incremental {
+		if depthA, depthB := strings.Count(a, "/"), strings.Count(b, "/"); depthA != depthB {
+			return depthA < depthB
+		}
+	}
+	return a < b
+}
@@ -0,0 +1,172 @@
+package media
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestRunOrderLess(t *testing.T) {
+	tests := []struct {
+		incremental bool
+		a, b        string
+		want        bool
+	}{
+		{false, "z.jpg", "a/b.jpg", true},
+		{false, "a/b.jpg", "z.jpg", false},
+		{false, "a/b.jpg", "a/c.jpg", true},
+		{true, "z.jpg", "a/b.jpg", false},
+		{true, "a/b.jpg", "z.jpg", true},
+		{true, "a.jpg", "a.jpg", false},
+	}
+
+	for _, tt := range tests {
+		if got := runOrderLess(tt.incremental, tt.a, tt.b); got != tt.want {
+			t.Errorf("runOrderLess(%v, %q, %q) = %v, want %v", tt.incremental, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSkipCompleted(t *testing.T) {
+	mediaFiles := func(paths ...string) []database.MediaFile {
+		files := make([]database.MediaFile, len(paths))
+		for i, path := range paths {
+			files[i] = database.MediaFile{Path: path}
+		}
+		return files
+	}
+	files := mediaFiles("a.jpg", "b.jpg", "x/a.jpg", "x/b.jpg")
+	folders := mediaFiles("x")
+	large := mediaFiles("big.tif", "x/big.tif")
+
+	tests := []struct {
+		name                       string
+		checkpoint                 database.ThumbnailCheckpoint
+		wantFiles, wantFolders     int
+		wantLarge, wantSkipped     int
+		wantFirstFile, wantFirstLg string
+	}{
+		{"files", database.ThumbnailCheckpoint{Phase: checkpointPhaseFiles, LastPath: "b.jpg"}, 2, 1, 2, 2, "x/a.jpg", "big.tif"},
+		{"nothing done", database.ThumbnailCheckpoint{Phase: checkpointPhaseFiles}, 4, 1, 2, 0, "a.jpg", "big.tif"},
+		{"folders", database.ThumbnailCheckpoint{Phase: checkpointPhaseFolders}, 0, 1, 2, 4, "", "big.tif"},
+		{"large", database.ThumbnailCheckpoint{Phase: checkpointPhaseLarge, LastPath: "big.tif"}, 0, 0, 1, 6, "", "x/big.tif"},
+		{"unknown phase", database.ThumbnailCheckpoint{Phase: "other", LastPath: "x/b.jpg"}, 4, 1, 2, 0, "a.jpg", "big.tif"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotFiles, gotFolders, gotLarge, skipped := skipCompleted(&tt.checkpoint, files, folders, large)
+			if len(gotFiles) != tt.wantFiles || len(gotFolders) != tt.wantFolders || len(gotLarge) != tt.wantLarge || skipped != tt.wantSkipped {
+				t.Fatalf("Got %d files, %d folders, %d large, %d skipped; want %d, %d, %d, %d",
+					len(gotFiles), len(gotFolders), len(gotLarge), skipped,
+					tt.wantFiles, tt.wantFolders, tt.wantLarge, tt.wantSkipped)
+			}
+			if len(gotFiles) > 0 && gotFiles[0].Path != tt.wantFirstFile {
+				t.Errorf("Expected to resume at %s, got %s", tt.wantFirstFile, gotFiles[0].Path)
+			}
+			if len(gotLarge) > 0 && gotLarge[0].Path != tt.wantFirstLg {
+				t.Errorf("Expected large files to resume at %s, got %s", tt.wantFirstLg, gotLarge[0].Path)
+			}
+		})
+	}
+}
+
+func TestRunGenerationResumesFromCheckpoint(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+
+	db, _, err := database.New(context.Background(), filepath.Join(t.TempDir(), "checkpoint_test.db"), &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, db, time.Hour, nil)
+	ctx := context.Background()
+
+	paths := []string{"a.jpg", "b.jpg", "sub/c.jpg"}
+	for _, path := range paths {
+		filename := filepath.Join(mediaDir, path)
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		createTestImageFile(t, filename, 400, 300, "jpeg", 85)
+		upsertTestFile(ctx, t, db, database.MediaFile{
+			Path:       path,
+			Name:       filepath.Base(path),
+			ParentPath: filepath.Dir(path),
+			Type:       database.FileTypeImage,
+			ModTime:    time.Now().Add(-time.Hour),
+		})
+	}
+
+	// A full run was interrupted after finishing the top-level files
+	interruptedAt := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	if err := db.SetThumbnailCheckpoint(ctx, &database.ThumbnailCheckpoint{
+		StartedAt: interruptedAt,
+		Phase:     checkpointPhaseFiles,
+		LastPath:  "b.jpg",
+	}); err != nil {
+		t.Fatalf("Failed to store checkpoint: %v", err)
+	}
+
+	gen.runGeneration(false)
+
+	stats := gen.GetStatus().Generation
+	if !stats.Resumed || stats.TotalFiles != 1 || stats.Generated != 1 {
+		t.Errorf("Expected a resumed run generating 1 file, got %+v", stats)
+	}
+	for _, path := range paths {
+		_, err := os.Stat(filepath.Join(cacheDir, gen.getCacheKey(filepath.Join(mediaDir, path), database.FileTypeImage)))
+		if exists := err == nil; exists != (path == "sub/c.jpg") {
+			t.Errorf("%s: thumbnail exists = %v, expected only the unfinished file to be generated", path, exists)
+		}
+	}
+
+	// The completed run clears the checkpoint and counts from the interrupted start
+	if checkpoint, err := db.GetThumbnailCheckpoint(ctx); err != nil || checkpoint != nil {
+		t.Errorf("Expected the checkpoint to be cleared, got %+v, %v", checkpoint, err)
+	}
+	if lastRun, err := db.GetLastThumbnailRun(ctx); err != nil || !lastRun.Equal(interruptedAt) {
+		t.Errorf("Expected the last run to be %v, got %v, %v", interruptedAt, lastRun, err)
+	}
+}
+
+func TestLoadCheckpointDiscardsOtherKindOfRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	db, _, err := database.New(context.Background(), filepath.Join(t.TempDir(), "checkpoint_test.db"), &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	gen := NewThumbnailGenerator(t.TempDir(), t.TempDir(), true, db, time.Hour, nil)
+	ctx := context.Background()
+
+	if err := db.SetThumbnailCheckpoint(ctx, &database.ThumbnailCheckpoint{
+		StartedAt:   time.Now().Add(-time.Hour),
+		Incremental: true,
+		Phase:       checkpointPhaseFiles,
+		LastPath:    "z.jpg",
+	}); err != nil {
+		t.Fatalf("Failed to store checkpoint: %v", err)
+	}
+
+	if got := gen.loadCheckpoint(ctx, false); got != nil {
+		t.Errorf("Expected an incremental checkpoint not to resume a full run, got %+v", got)
+	}
+	if checkpoint, err := db.GetThumbnailCheckpoint(ctx); err != nil || checkpoint != nil {
+		t.Errorf("Expected the checkpoint to be discarded, got %+v, %v", checkpoint, err)
+	}
+}
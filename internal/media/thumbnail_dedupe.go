@@ -0,0 +1,196 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"media-viewer/internal/logging"
+)
+
+const (
+	// contentDirName is the cache subdirectory holding thumbnails shared by
+	// source files with identical content
+	contentDirName = "content"
+
+	// sharedThumbnailGracePeriod protects recently written or reused shared
+	// thumbnails from orphan cleanup while their .meta files are being written
+	sharedThumbnailGracePeriod = time.Hour
+)
+
+// SetDeduplication enables content-addressed storage, so source files with
+// identical content share one cached thumbnail. Thumbnails already cached
+// per path are still served; only newly generated thumbnails are shared.
+func (t *ThumbnailGenerator) SetDeduplication(enabled bool) {
+	t.dedupeEnabled.Store(enabled)
+}
+
+// contentDir returns the directory holding shared thumbnails
+func (t *ThumbnailGenerator) contentDir() string {
+	return filepath.Join(t.cacheDir, contentDirName)
+}
+
+// getContentPath returns the path of the shared thumbnail for a content key
+func (t *ThumbnailGenerator) getContentPath(contentKey string) string {
+	return filepath.Join(t.contentDir(), contentKey+".jpg")
+}
+
+// writeSharedMetaFile writes a .meta file pointing a source path at a shared thumbnail
+func (t *ThumbnailGenerator) writeSharedMetaFile(cacheKey, sourcePath, contentKey, sourceHash string, style ThumbnailStyle, dims ThumbnailDimensions) error {
+	return t.newMetaFile(sourcePath, contentKey, sourceHash, style, dims).save(t.getMetaPath(cacheKey))
+}
+
+// readMetaContentKey returns the shared thumbnail referenced by a .meta file,
+// or an empty string if the thumbnail is stored per path
+func (t *ThumbnailGenerator) readMetaContentKey(cacheKey string) string {
+	meta, err := t.readMetaFile(cacheKey)
+	if err != nil {
+		return ""
+	}
+	return meta.ContentKey
+}
+
+// readCachedThumbnail returns a cached thumbnail stored either per path or,
+// via the .meta reference, as a shared thumbnail
+func (t *ThumbnailGenerator) readCachedThumbnail(cacheKey string) ([]byte, error) {
+	cachePath := filepath.Join(t.cacheDir, cacheKey)
+	data, err := os.ReadFile(cachePath)
+	if err == nil {
+		t.touchCacheFile(cachePath)
+		return data, nil
+	}
+
+	contentKey := t.readMetaContentKey(cacheKey)
+	if contentKey == "" {
+		return nil, err
+	}
+	contentPath := t.getContentPath(contentKey)
+	data, err = os.ReadFile(contentPath)
+	if err == nil {
+		t.touchCacheFile(contentPath)
+	}
+	return data, err
+}
+
+// cachedThumbnailExists reports whether a thumbnail is cached per path or shared
+func (t *ThumbnailGenerator) cachedThumbnailExists(cacheKey string) bool {
+	if _, err := os.Stat(filepath.Join(t.cacheDir, cacheKey)); err == nil {
+		return true
+	}
+
+	contentKey := t.readMetaContentKey(cacheKey)
+	if contentKey == "" {
+		return false
+	}
+	_, err := os.Stat(t.getContentPath(contentKey))
+	return err == nil
+}
+
+// reuseSharedThumbnail links a source path to an existing shared thumbnail
+// rendered with style. Returns the thumbnail data, or nil if no thumbnail
+// exists for the content.
+func (t *ThumbnailGenerator) reuseSharedThumbnail(ctx context.Context, cacheKey, filePath, contentKey, sourceHash string, style ThumbnailStyle) []byte {
+	contentPath := t.getContentPath(contentKey)
+	data, err := os.ReadFile(contentPath)
+	if err != nil {
+		return nil
+	}
+
+	if err := t.writeSharedMetaFile(cacheKey, filePath, contentKey, sourceHash, style, sharedThumbnailDimensions(data, style)); err != nil {
+		logging.Debug("Failed to write meta file for %s: %v", cacheKey, err)
+		return nil
+	}
+
+	// Refresh the modification time so orphan cleanup treats it as in use
+	now := time.Now()
+	if err := os.Chtimes(contentPath, now, now); err != nil {
+		logging.Debug("Failed to touch shared thumbnail %s: %v", contentPath, err)
+	}
+
+	if t.paletteEnabled.Load() {
+		if thumb, err := jpeg.Decode(bytes.NewReader(data)); err == nil {
+			t.storePalette(ctx, filePath, thumb)
+		}
+	}
+
+	return data
+}
+
+// cleanupSharedThumbnails drops .meta references from sources that are no
+// longer indexed or were generated for another thumbnail size, then removes
+// shared thumbnails that no remaining source references. Returns the number
+// of source references and shared thumbnails removed.
+func (t *ThumbnailGenerator) cleanupSharedThumbnails(indexedPaths map[string]struct{}) (referencesRemoved, sharedRemoved int) {
+	entries, err := os.ReadDir(t.cacheDir)
+	if err != nil {
+		return 0, 0
+	}
+
+	// Count the remaining references to each shared thumbnail
+	references := make(map[string]int)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), metaFileExtension) {
+			continue
+		}
+
+		metaPath := filepath.Join(t.cacheDir, entry.Name())
+		meta, err := loadMetaFile(metaPath)
+		if err != nil {
+			continue
+		}
+
+		contentKey := meta.ContentKey
+		if contentKey == "" {
+			continue
+		}
+
+		relativePath := strings.TrimPrefix(meta.SourcePath, t.mediaDir)
+		relativePath = strings.TrimPrefix(relativePath, "/")
+
+		if _, exists := indexedPaths[relativePath]; !exists || t.hasOtherSize(meta) {
+			if err := os.Remove(metaPath); err != nil {
+				logging.Debug("Failed to remove orphaned meta file %s: %v", entry.Name(), err)
+				references[contentKey]++
+			} else {
+				referencesRemoved++
+				logging.Debug("Removed orphaned shared thumbnail reference for: %s", relativePath)
+			}
+			continue
+		}
+
+		references[contentKey]++
+	}
+
+	contentEntries, err := os.ReadDir(t.contentDir())
+	if err != nil {
+		return referencesRemoved, 0
+	}
+
+	for _, entry := range contentEntries {
+		if entry.IsDir() {
+			continue
+		}
+
+		contentKey := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if references[contentKey] > 0 {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < sharedThumbnailGracePeriod {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(t.contentDir(), entry.Name())); err != nil {
+			logging.Debug("Failed to remove unreferenced shared thumbnail %s: %v", entry.Name(), err)
+		} else {
+			sharedRemoved++
+		}
+	}
+
+	return referencesRemoved, sharedRemoved
+}
@@ -0,0 +1,156 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestGetThumbnailDeduplicationIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+
+	db, _, err := database.New(context.Background(), filepath.Join(t.TempDir(), "dedupe_test.db"), &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, db, time.Hour, nil)
+	gen.SetDeduplication(true)
+	ctx := context.Background()
+
+	original := filepath.Join(mediaDir, "original.jpg")
+	createTestImageFile(t, original, 300, 200, "jpeg", 85)
+	data, err := os.ReadFile(original)
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	copy1 := filepath.Join(mediaDir, "copy.jpg")
+	if err := os.WriteFile(copy1, data, 0o644); err != nil {
+		t.Fatalf("Failed to write duplicate: %v", err)
+	}
+
+	for _, name := range []string{"original.jpg", "copy.jpg"} {
+		upsertTestFile(ctx, t, db, database.MediaFile{Path: name, Name: name, ParentPath: ".", Type: database.FileTypeImage})
+	}
+
+	thumb1, err := gen.GetThumbnail(ctx, original, database.FileTypeImage)
+	if err != nil {
+		t.Fatalf("GetThumbnail for original failed: %v", err)
+	}
+	thumb2, err := gen.GetThumbnail(ctx, copy1, database.FileTypeImage)
+	if err != nil {
+		t.Fatalf("GetThumbnail for copy failed: %v", err)
+	}
+	if !bytes.Equal(thumb1, thumb2) {
+		t.Error("Expected duplicates to share the same thumbnail")
+	}
+
+	entries, err := os.ReadDir(gen.contentDir())
+	if err != nil {
+		t.Fatalf("Failed to read shared thumbnail directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 shared thumbnail, got %d", len(entries))
+	}
+	sharedPath := filepath.Join(gen.contentDir(), entries[0].Name())
+
+	// No per-path thumbnails are written, only .meta references
+	for _, path := range []string{original, copy1} {
+		cacheKey := gen.getCacheKey(path, database.FileTypeImage)
+		if _, err := os.Stat(filepath.Join(cacheDir, cacheKey)); !os.IsNotExist(err) {
+			t.Errorf("Expected no per-path thumbnail for %s", path)
+		}
+		if !gen.thumbnailExists(filepath.Base(path), database.FileTypeImage) {
+			t.Errorf("Expected thumbnailExists to find the shared thumbnail for %s", path)
+		}
+	}
+
+	// Make the shared thumbnail old enough for cleanup to consider it
+	old := time.Now().Add(-2 * sharedThumbnailGracePeriod)
+	if err := os.Chtimes(sharedPath, old, old); err != nil {
+		t.Fatalf("Failed to age shared thumbnail: %v", err)
+	}
+
+	// Removing one duplicate keeps the shared thumbnail for the other
+	deleteTestFile(ctx, t, db, []database.MediaFile{{Path: "copy.jpg", Name: "copy.jpg", ParentPath: ".", Type: database.FileTypeImage}})
+	orphansRemoved, _ := gen.cleanupOrphanedThumbnails(ctx)
+	if orphansRemoved != 1 {
+		t.Errorf("Expected 1 orphaned reference removed, got %d", orphansRemoved)
+	}
+	if _, err := os.Stat(sharedPath); err != nil {
+		t.Fatal("Shared thumbnail should be kept while a source still references it")
+	}
+	if got, err := gen.GetThumbnail(ctx, copy1, database.FileTypeImage); err != nil || !bytes.Equal(got, thumb1) {
+		t.Errorf("Expected remaining duplicate to still use the shared thumbnail (err: %v)", err)
+	}
+
+	// Invalidating the last reference leaves the file for cleanup to remove
+	if err := gen.InvalidateThumbnail(copy1); err != nil {
+		t.Fatalf("InvalidateThumbnail failed: %v", err)
+	}
+	if err := os.Chtimes(sharedPath, old, old); err != nil {
+		t.Fatalf("Failed to age shared thumbnail: %v", err)
+	}
+	gen.cleanupOrphanedThumbnails(ctx)
+	if _, err := os.Stat(sharedPath); !os.IsNotExist(err) {
+		t.Error("Expected unreferenced shared thumbnail to be removed")
+	}
+}
+
+func TestCleanupSharedThumbnailsGracePeriod(t *testing.T) {
+	cacheDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, t.TempDir(), true, nil, time.Hour, nil)
+
+	if err := os.MkdirAll(gen.contentDir(), 0o755); err != nil {
+		t.Fatalf("Failed to create content dir: %v", err)
+	}
+	fresh := gen.getContentPath("fresh")
+	if err := os.WriteFile(fresh, []byte("thumb"), 0o644); err != nil {
+		t.Fatalf("Failed to write shared thumbnail: %v", err)
+	}
+
+	_, removed := gen.cleanupSharedThumbnails(map[string]struct{}{})
+	if removed != 0 {
+		t.Errorf("Expected recently written shared thumbnail to be kept, removed %d", removed)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Error("Recently written shared thumbnail should not be deleted")
+	}
+}
+
+func TestInvalidateAllRemovesSharedThumbnails(t *testing.T) {
+	cacheDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, t.TempDir(), true, nil, time.Hour, nil)
+
+	if err := os.MkdirAll(gen.contentDir(), 0o755); err != nil {
+		t.Fatalf("Failed to create content dir: %v", err)
+	}
+	if err := os.WriteFile(gen.getContentPath("abc"), []byte("thumb"), 0o644); err != nil {
+		t.Fatalf("Failed to write shared thumbnail: %v", err)
+	}
+	if err := gen.writeSharedMetaFile("0123.jpg", "/media/a.jpg", "abc", "", ThumbnailStyle{}, ThumbnailDimensions{}); err != nil {
+		t.Fatalf("Failed to write meta file: %v", err)
+	}
+
+	count, err := gen.InvalidateAll()
+	if err != nil {
+		t.Fatalf("InvalidateAll failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 thumbnail invalidated, got %d", count)
+	}
+	if _, err := os.Stat(gen.contentDir()); !os.IsNotExist(err) {
+		t.Error("Expected shared thumbnail directory to be removed")
+	}
+}
@@ -0,0 +1,92 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"strconv"
+
+	"media-viewer/internal/database"
+)
+
+// ThumbnailDimensions describes a cached thumbnail, so clients can lay it
+// out before it loads
+type ThumbnailDimensions struct {
+	Width  int
+	Height int
+
+	// SourceAspectRatio is the width of the source divided by its height,
+	// as displayed; 0 if unknown
+	SourceAspectRatio float64
+}
+
+// newThumbnailDimensions returns the dimensions of a thumbnail generated
+// from source
+func newThumbnailDimensions(thumb, source image.Image) ThumbnailDimensions {
+	dims := ThumbnailDimensions{Width: thumb.Bounds().Dx(), Height: thumb.Bounds().Dy()}
+	if bounds := source.Bounds(); bounds.Dy() > 0 {
+		dims.SourceAspectRatio = float64(bounds.Dx()) / float64(bounds.Dy())
+	}
+	return dims
+}
+
+// String formats the dimensions as written to the .meta line
+func (d ThumbnailDimensions) String() string {
+	return fmt.Sprintf("%dx%d %s", d.Width, d.Height, strconv.FormatFloat(d.SourceAspectRatio, 'f', 4, 64))
+}
+
+// parseThumbnailDimensions parses a .meta dimensions line written by String
+func parseThumbnailDimensions(value string) (ThumbnailDimensions, bool) {
+	var d ThumbnailDimensions
+	if _, err := fmt.Sscanf(value, "%dx%d %g", &d.Width, &d.Height, &d.SourceAspectRatio); err != nil {
+		return ThumbnailDimensions{}, false
+	}
+	if d.Width <= 0 || d.Height <= 0 || d.SourceAspectRatio < 0 {
+		return ThumbnailDimensions{}, false
+	}
+	return d, true
+}
+
+// sharedThumbnailDimensions returns the dimensions of a shared thumbnail
+// being reused. Its source isn't decoded then, so the aspect ratio is only
+// known when no style changed the thumbnail's shape.
+func sharedThumbnailDimensions(data []byte, style ThumbnailStyle) ThumbnailDimensions {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Height == 0 {
+		return ThumbnailDimensions{}
+	}
+	dims := ThumbnailDimensions{Width: config.Width, Height: config.Height}
+	if style.IsZero() {
+		dims.SourceAspectRatio = float64(config.Width) / float64(config.Height)
+	}
+	return dims
+}
+
+// GetThumbnailDimensions returns the dimensions of the cached thumbnail of
+// a file, as recorded when it was generated. The thumbnail's own header is
+// read for thumbnails cached before dimensions were recorded; their source
+// aspect ratio is unknown. thumb, if not nil, is that thumbnail as the caller
+// already read it, so it isn't read again. Returns false if the thumbnail
+// isn't cached.
+func (t *ThumbnailGenerator) GetThumbnailDimensions(filePath string, fileType database.FileType, thumb []byte) (ThumbnailDimensions, bool) {
+	if !t.enabled {
+		return ThumbnailDimensions{}, false
+	}
+
+	cacheKey := t.getCacheKey(filePath, fileType)
+	meta, err := t.readMetaFile(cacheKey)
+	if err == nil && meta.Dimensions.Width > 0 {
+		return meta.Dimensions, true
+	}
+
+	if thumb == nil {
+		if thumb, err = t.readCachedThumbnail(cacheKey); err != nil {
+			return ThumbnailDimensions{}, false
+		}
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil {
+		return ThumbnailDimensions{}, false
+	}
+	return ThumbnailDimensions{Width: config.Width, Height: config.Height}, true
+}
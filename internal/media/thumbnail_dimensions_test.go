@@ -0,0 +1,67 @@
+package media
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestParseThumbnailDimensions(t *testing.T) {
+	dims := ThumbnailDimensions{Width: 640, Height: 427, SourceAspectRatio: 1.5}
+	if got, ok := parseThumbnailDimensions(dims.String()); !ok || got != dims {
+		t.Errorf("parseThumbnailDimensions(%q) = (%+v, %v), want %+v", dims.String(), got, ok, dims)
+	}
+
+	for _, value := range []string{"", "640", "0x427 1.5", "640x427 -1"} {
+		if _, ok := parseThumbnailDimensions(value); ok {
+			t.Errorf("parseThumbnailDimensions(%q) succeeded, want failure", value)
+		}
+	}
+}
+
+func TestGetThumbnailDimensions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, nil, time.Hour, nil)
+	ctx := context.Background()
+
+	filePath := filepath.Join(mediaDir, "wide.jpg")
+	createTestImageFile(t, filePath, 300, 150, "jpeg", 85)
+
+	if _, ok := gen.GetThumbnailDimensions(filePath, database.FileTypeImage, nil); ok {
+		t.Error("Expected no dimensions before the thumbnail is generated")
+	}
+
+	if _, err := gen.GetThumbnail(ctx, filePath, database.FileTypeImage); err != nil {
+		t.Fatalf("GetThumbnail failed: %v", err)
+	}
+	want := ThumbnailDimensions{Width: DefaultThumbnailSize, Height: DefaultThumbnailSize / 2, SourceAspectRatio: 2}
+	if dims, ok := gen.GetThumbnailDimensions(filePath, database.FileTypeImage, nil); !ok || dims != want {
+		t.Errorf("GetThumbnailDimensions = (%+v, %v), want %+v", dims, ok, want)
+	}
+
+	// Thumbnails cached before dimensions were recorded are measured instead
+	cacheKey := gen.getCacheKey(filePath, database.FileTypeImage)
+	if err := gen.writeMetaFile(cacheKey, filePath, "", ThumbnailStyle{}, ThumbnailDimensions{}); err != nil {
+		t.Fatalf("writeMetaFile failed: %v", err)
+	}
+	want.SourceAspectRatio = 0
+	if dims, ok := gen.GetThumbnailDimensions(filePath, database.FileTypeImage, nil); !ok || dims != want {
+		t.Errorf("GetThumbnailDimensions without recorded dimensions = (%+v, %v), want %+v", dims, ok, want)
+	}
+
+	if err := os.Remove(filepath.Join(cacheDir, cacheKey)); err != nil {
+		t.Fatalf("Failed to remove thumbnail: %v", err)
+	}
+	if _, ok := gen.GetThumbnailDimensions(filePath, database.FileTypeImage, nil); ok {
+		t.Error("Expected no dimensions once the thumbnail is gone")
+	}
+}
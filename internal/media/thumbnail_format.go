@@ -0,0 +1,297 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/filesystem"
+	"media-viewer/internal/logging"
+)
+
+// ThumbnailFormat is an encoding a thumbnail can be served in. Thumbnails are
+// generated as JPEG (PNG for folders); other formats are variants encoded from
+// that thumbnail on first request and cached next to it.
+type ThumbnailFormat string
+
+// Thumbnail formats
+const (
+	ThumbnailFormatDefault ThumbnailFormat = ""     // JPEG, or PNG for folders
+	ThumbnailFormatWebP    ThumbnailFormat = "webp" // Variant for browsers accepting image/webp
+	ThumbnailFormatAVIF    ThumbnailFormat = "avif" // Variant for browsers accepting image/avif
+)
+
+// variantFormats lists the variant formats in order of preference when a
+// client accepts several equally
+var variantFormats = []ThumbnailFormat{ThumbnailFormatAVIF, ThumbnailFormatWebP}
+
+// ContentType returns the MIME type of a thumbnail in this format.
+// The default format depends on the file type.
+func (f ThumbnailFormat) ContentType(fileType database.FileType) string {
+	switch f {
+	case ThumbnailFormatWebP:
+		return "image/webp"
+	case ThumbnailFormatAVIF:
+		return "image/avif"
+	default:
+		if fileType == database.FileTypeFolder {
+			return "image/png"
+		}
+		return "image/jpeg"
+	}
+}
+
+// acceptQuality returns the quality value an Accept header gives a MIME type,
+// or 0 if it is not listed. Wildcards are ignored: clients sending only
+// image/* or */* may not decode the newer formats.
+func acceptQuality(accept, mimeType string) float64 {
+	for part := range strings.SplitSeq(accept, ",") {
+		params := strings.Split(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), mimeType) {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.TrimSpace(key) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				quality = q
+			}
+		}
+		return quality
+	}
+	return 0
+}
+
+// NegotiateFormat picks the thumbnail format for a request's Accept header:
+// the supported variant the client rates highest (the output format, then
+// AVIF, on a tie), or the default format if it accepts neither.
+func (t *ThumbnailGenerator) NegotiateFormat(accept string) ThumbnailFormat {
+	best := ThumbnailFormatDefault
+	bestQuality := 0.0
+	for _, format := range t.preferredFormats() {
+		if _, unsupported := t.unsupportedFormats.Load(format); unsupported {
+			continue
+		}
+		if q := acceptQuality(accept, format.ContentType("")); q > bestQuality {
+			best, bestQuality = format, q
+		}
+	}
+	return best
+}
+
+// getVariantKey returns the cache filename of a format variant. Variants share
+// the base name, and so the .meta file, of the thumbnail they are encoded from.
+func getVariantKey(cacheKey string, format ThumbnailFormat) string {
+	return strings.TrimSuffix(cacheKey, filepath.Ext(cacheKey)) + "." + string(format)
+}
+
+// isVariantFile reports whether a cache filename is a format or scaled variant
+func isVariantFile(name string) bool {
+	if _, ok := scaledVariantBase(name); ok {
+		return true
+	}
+	for _, format := range variantFormats {
+		if strings.HasSuffix(name, "."+string(format)) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetThumbnailInFormat returns the thumbnail for a file in the requested
+// format, encoding and caching the variant on first use. If the variant can't
+// be encoded the default format is returned instead; the returned format is
+// the one actually used.
+func (t *ThumbnailGenerator) GetThumbnailInFormat(ctx context.Context, filePath string, fileType database.FileType, format ThumbnailFormat) ([]byte, ThumbnailFormat, error) {
+	return t.getThumbnailInFormat(ctx, filePath, fileType, format, t.staleWhileRevalidate.Load())
+}
+
+// getThumbnailInFormat implements GetThumbnailInFormat; allowStale is passed
+// to getThumbnail.
+func (t *ThumbnailGenerator) getThumbnailInFormat(ctx context.Context, filePath string, fileType database.FileType, format ThumbnailFormat, allowStale bool) ([]byte, ThumbnailFormat, error) {
+	data, err := t.getThumbnail(ctx, filePath, fileType, allowStale)
+	if err != nil || format == ThumbnailFormatDefault {
+		return data, ThumbnailFormatDefault, err
+	}
+
+	variant, ok := t.formatVariant(t.getCacheKey(filePath, fileType), data, format)
+	if !ok {
+		return data, ThumbnailFormatDefault, nil
+	}
+	return variant, format, nil
+}
+
+// formatVariant returns the cached variant of the thumbnail data for a cache
+// key in a format, encoding it if needed. Reports false if the thumbnail
+// isn't cached or the format can't be encoded.
+func (t *ThumbnailGenerator) formatVariant(cacheKey string, data []byte, format ThumbnailFormat) ([]byte, bool) {
+	baseTime, err := t.cachedThumbnailModTime(cacheKey)
+	if err != nil {
+		// Not cached (e.g. the write failed); nothing to keep a variant in sync with
+		return nil, false
+	}
+
+	variantPath := filepath.Join(t.cacheDir, getVariantKey(cacheKey, format))
+	variant, err := t.cachedVariant(variantPath, baseTime, func() ([]byte, error) {
+		return t.encodeFormat(data, format)
+	})
+	if err != nil {
+		logging.Warn("Failed to encode %s thumbnail, serving the default format instead: %v", format, err)
+		t.encodeFailed(format)
+		return nil, false
+	}
+	return variant, true
+}
+
+// probeThumbnail is a small JPEG encoded to tell an encoder that can't work
+// from one that failed on a particular thumbnail
+var probeThumbnail = sync.OnceValue(func() []byte {
+	var buf bytes.Buffer
+	_ = jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil)
+	return buf.Bytes()
+})
+
+// encodeFailed stops offering a format after encoding a thumbnail in it
+// failed, but only if the probe thumbnail can't be encoded either. That is
+// usually a libvips build without the encoder; a failure on one thumbnail
+// alone doesn't disable the format until restart.
+func (t *ThumbnailGenerator) encodeFailed(format ThumbnailFormat) {
+	if _, err := t.encodeFormat(probeThumbnail(), format); err != nil {
+		logging.Warn("Can't encode %s thumbnails, no longer offering the format: %v", format, err)
+		t.unsupportedFormats.Store(format, struct{}{})
+	}
+}
+
+// encodeFormat encodes a thumbnail in a variant format
+func (t *ThumbnailGenerator) encodeFormat(data []byte, format ThumbnailFormat) ([]byte, error) {
+	encode := t.encodeVariant
+	if encode == nil {
+		encode = encodeVariantWithVips
+	}
+	return encode(data, format)
+}
+
+// cachedVariant returns the variant cached at variantPath if it is at least
+// as new as the thumbnail it derives from, written at baseTime. Otherwise it
+// produces the variant and caches it; a failed write is only logged.
+func (t *ThumbnailGenerator) cachedVariant(variantPath string, baseTime time.Time, produce func() ([]byte, error)) ([]byte, error) {
+	if variant, ok := readFreshVariant(variantPath, baseTime); ok {
+		t.touchCacheFile(variantPath)
+		return variant, nil
+	}
+
+	variantLock := t.getLock(variantPath)
+	variantLock.Lock()
+	defer func() {
+		variantLock.Unlock()
+		t.releaseLock(variantPath)
+	}()
+
+	if variant, ok := readFreshVariant(variantPath, baseTime); ok {
+		return variant, nil
+	}
+
+	variant, err := produce()
+	if err != nil {
+		return nil, err
+	}
+
+	defer t.beginCacheWrite(variantPath)()
+	if err := filesystem.WriteFileWithRetry(variantPath, variant, 0o644, filesystem.DefaultRetryConfig()); err != nil {
+		logging.Warn("Failed to cache thumbnail variant %s: %v", variantPath, err)
+	} else if baseTime.After(time.Now()) {
+		// Keep the variant as new as a future-dated base thumbnail (see markThumbnailFresh)
+		if err := os.Chtimes(variantPath, baseTime, baseTime); err != nil {
+			logging.Debug("Failed to set thumbnail time for %s: %v", variantPath, err)
+		}
+	}
+
+	return variant, nil
+}
+
+// readFreshVariant reads a cached variant unless it is older than the
+// thumbnail it was encoded from
+func readFreshVariant(variantPath string, baseTime time.Time) ([]byte, bool) {
+	info, err := os.Stat(variantPath)
+	if err != nil || info.ModTime().Before(baseTime) {
+		return nil, false
+	}
+	data, err := os.ReadFile(variantPath)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// removeVariants deletes the cached format, scaled, sized and obscured
+// variants of a thumbnail. Sized variants are only found for the current
+// variant sizes; others are left to orphan cleanup.
+func (t *ThumbnailGenerator) removeVariants(cacheKey string) {
+	sizes := t.VariantSizes()[1:]
+	keys := make([]string, 0, (len(variantFormats)+1)*(maxThumbnailScale+len(sizes))+2)
+	keys = append(keys, getObscuredKey(cacheKey, SensitiveBlur), getObscuredKey(cacheKey, SensitivePixelate))
+	for _, format := range variantFormats {
+		keys = append(keys, getVariantKey(cacheKey, format))
+	}
+	for scale := 2; scale <= maxThumbnailScale; scale++ {
+		keys = append(keys, getScaledKey(cacheKey, scale, ThumbnailFormatDefault))
+		for _, format := range variantFormats {
+			keys = append(keys, getScaledKey(cacheKey, scale, format))
+		}
+	}
+	for _, size := range sizes {
+		keys = append(keys, getSizedKey(cacheKey, size, ThumbnailFormatDefault))
+		for _, format := range variantFormats {
+			keys = append(keys, getSizedKey(cacheKey, size, format))
+		}
+	}
+
+	for _, key := range keys {
+		variantPath := filepath.Join(t.cacheDir, key)
+		if err := os.Remove(variantPath); err != nil && !os.IsNotExist(err) {
+			logging.Debug("Failed to remove thumbnail variant %s: %v", variantPath, err)
+		}
+	}
+}
+
+// cleanupOrphanedVariants removes format and scaled variants whose thumbnail
+// is no longer tracked by a .meta file. Returns the number of variants removed.
+func (t *ThumbnailGenerator) cleanupOrphanedVariants() int {
+	entries, err := os.ReadDir(t.cacheDir)
+	if err != nil {
+		return 0
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !isVariantFile(name) {
+			continue
+		}
+		metaKey := name
+		if base, ok := scaledVariantBase(name); ok {
+			metaKey = base
+		}
+		if _, err := os.Stat(t.getMetaPath(metaKey)); err == nil {
+			continue
+		}
+		if err := os.Remove(filepath.Join(t.cacheDir, name)); err != nil {
+			logging.Debug("Failed to remove orphaned thumbnail variant %s: %v", name, err)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
@@ -0,0 +1,226 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestAcceptQuality(t *testing.T) {
+	tests := []struct {
+		accept   string
+		mimeType string
+		expected float64
+	}{
+		{"image/avif,image/webp,*/*;q=0.8", "image/avif", 1},
+		{"image/avif,image/webp,*/*;q=0.8", "image/webp", 1},
+		{"image/webp;q=0.9, image/png", "image/webp", 0.9},
+		{"IMAGE/WEBP", "image/webp", 1},
+		{"image/webp;q=0", "image/webp", 0},
+		{"image/*,*/*;q=0.8", "image/webp", 0},
+		{"", "image/avif", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept+" "+tt.mimeType, func(t *testing.T) {
+			if got := acceptQuality(tt.accept, tt.mimeType); got != tt.expected {
+				t.Errorf("acceptQuality(%q, %q) = %v, want %v", tt.accept, tt.mimeType, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	gen := &ThumbnailGenerator{}
+
+	tests := []struct {
+		accept   string
+		expected ThumbnailFormat
+	}{
+		{"image/avif,image/webp,image/apng,*/*;q=0.8", ThumbnailFormatAVIF},
+		{"image/webp,*/*", ThumbnailFormatWebP},
+		{"image/avif;q=0.5,image/webp", ThumbnailFormatWebP},
+		{"image/png,image/*;q=0.8", ThumbnailFormatDefault},
+		{"*/*", ThumbnailFormatDefault},
+		{"", ThumbnailFormatDefault},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := gen.NegotiateFormat(tt.accept); got != tt.expected {
+				t.Errorf("NegotiateFormat(%q) = %q, want %q", tt.accept, got, tt.expected)
+			}
+		})
+	}
+
+	// Formats the encoder failed on are no longer offered
+	gen.unsupportedFormats.Store(ThumbnailFormatAVIF, struct{}{})
+	if got := gen.NegotiateFormat("image/avif,image/webp"); got != ThumbnailFormatWebP {
+		t.Errorf("Expected WebP once AVIF is unsupported, got %q", got)
+	}
+}
+
+func TestThumbnailFormatContentType(t *testing.T) {
+	tests := []struct {
+		format   ThumbnailFormat
+		fileType database.FileType
+		expected string
+	}{
+		{ThumbnailFormatDefault, database.FileTypeImage, "image/jpeg"},
+		{ThumbnailFormatDefault, database.FileTypeVideo, "image/jpeg"},
+		{ThumbnailFormatDefault, database.FileTypeFolder, "image/png"},
+		{ThumbnailFormatWebP, database.FileTypeFolder, "image/webp"},
+		{ThumbnailFormatAVIF, database.FileTypeImage, "image/avif"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.format.ContentType(tt.fileType); got != tt.expected {
+			t.Errorf("%q.ContentType(%s) = %q, want %q", tt.format, tt.fileType, got, tt.expected)
+		}
+	}
+}
+
+func TestGetThumbnailInFormat(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, nil, time.Hour, nil)
+	ctx := context.Background()
+
+	encodes := 0
+	gen.encodeVariant = func(data []byte, format ThumbnailFormat) ([]byte, error) {
+		encodes++
+		return append([]byte(string(format)+":"), data...), nil
+	}
+
+	filename := filepath.Join(mediaDir, "photo.jpg")
+	createTestImageFile(t, filename, 300, 200, "jpeg", 85)
+
+	base, format, err := gen.GetThumbnailInFormat(ctx, filename, database.FileTypeImage, ThumbnailFormatDefault)
+	if err != nil {
+		t.Fatalf("GetThumbnailInFormat failed: %v", err)
+	}
+	if format != ThumbnailFormatDefault || encodes != 0 {
+		t.Fatalf("Expected the default thumbnail without encoding, got %q after %d encodes", format, encodes)
+	}
+
+	for range 2 {
+		variant, format, err := gen.GetThumbnailInFormat(ctx, filename, database.FileTypeImage, ThumbnailFormatWebP)
+		if err != nil {
+			t.Fatalf("GetThumbnailInFormat failed: %v", err)
+		}
+		if format != ThumbnailFormatWebP || !bytes.Equal(variant, append([]byte("webp:"), base...)) {
+			t.Fatalf("Expected the WebP variant, got format %q", format)
+		}
+	}
+	if encodes != 1 {
+		t.Errorf("Expected the variant to be encoded once and then cached, got %d encodes", encodes)
+	}
+
+	cacheKey := gen.getCacheKey(filename, database.FileTypeImage)
+	variantPath := filepath.Join(cacheDir, getVariantKey(cacheKey, ThumbnailFormatWebP))
+	if _, err := os.Stat(variantPath); err != nil {
+		t.Fatalf("Expected cached variant at %s: %v", variantPath, err)
+	}
+
+	// A variant older than its thumbnail is encoded again
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(variantPath, old, old); err != nil {
+		t.Fatalf("Failed to age variant: %v", err)
+	}
+	if _, _, err := gen.GetThumbnailInFormat(ctx, filename, database.FileTypeImage, ThumbnailFormatWebP); err != nil {
+		t.Fatalf("GetThumbnailInFormat failed: %v", err)
+	}
+	if encodes != 2 {
+		t.Errorf("Expected an outdated variant to be re-encoded, got %d encodes", encodes)
+	}
+
+	// Invalidation removes the variants along with the thumbnail
+	if err := gen.InvalidateThumbnail(filename); err != nil {
+		t.Fatalf("InvalidateThumbnail failed: %v", err)
+	}
+	if _, err := os.Stat(variantPath); !os.IsNotExist(err) {
+		t.Errorf("Expected variant to be removed on invalidation, got %v", err)
+	}
+}
+
+func TestGetThumbnailInFormatEncodeFailure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(t.TempDir(), mediaDir, true, nil, time.Hour, nil)
+	filename := filepath.Join(mediaDir, "photo.jpg")
+	createTestImageFile(t, filename, 300, 200, "jpeg", 85)
+
+	// A failure on one thumbnail while the probe encodes keeps the format
+	failures := 1
+	gen.encodeVariant = func(data []byte, format ThumbnailFormat) ([]byte, error) {
+		if failures > 0 {
+			failures--
+			return nil, errors.New("encode interrupted")
+		}
+		return data, nil
+	}
+	if _, format, err := gen.GetThumbnailInFormat(context.Background(), filename, database.FileTypeImage, ThumbnailFormatAVIF); err != nil || format != ThumbnailFormatDefault {
+		t.Fatalf("GetThumbnailInFormat = %q, %v, want the default format", format, err)
+	}
+	if got := gen.NegotiateFormat("image/avif,image/webp"); got != ThumbnailFormatAVIF {
+		t.Errorf("Expected AVIF to still be offered after one failed encode, got %q", got)
+	}
+
+	gen.encodeVariant = func([]byte, ThumbnailFormat) ([]byte, error) {
+		return nil, errors.New("no AVIF encoder")
+	}
+	data, format, err := gen.GetThumbnailInFormat(context.Background(), filename, database.FileTypeImage, ThumbnailFormatAVIF)
+	if err != nil {
+		t.Fatalf("GetThumbnailInFormat failed: %v", err)
+	}
+	if format != ThumbnailFormatDefault || len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		t.Errorf("Expected the JPEG thumbnail as fallback, got format %q", format)
+	}
+	if got := gen.NegotiateFormat("image/avif,image/webp"); got != ThumbnailFormatWebP {
+		t.Errorf("Expected AVIF to stop being offered after a failed encode, got %q", got)
+	}
+}
+
+func TestCleanupOrphanedVariants(t *testing.T) {
+	cacheDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, t.TempDir(), true, nil, time.Hour, nil)
+
+	tracked := gen.getCacheKey("/media/kept.jpg", database.FileTypeImage)
+	if err := gen.writeMetaFile(tracked, "/media/kept.jpg", "", ThumbnailStyle{}, ThumbnailDimensions{}); err != nil {
+		t.Fatalf("Failed to write meta file: %v", err)
+	}
+	orphan := gen.getCacheKey("/media/gone.jpg", database.FileTypeImage)
+
+	for _, key := range []string{tracked, orphan} {
+		for _, format := range variantFormats {
+			if err := os.WriteFile(filepath.Join(cacheDir, getVariantKey(key, format)), []byte("variant"), 0o644); err != nil {
+				t.Fatalf("Failed to write variant: %v", err)
+			}
+		}
+	}
+
+	if removed := gen.cleanupOrphanedVariants(); removed != len(variantFormats) {
+		t.Errorf("Expected %d orphaned variants removed, got %d", len(variantFormats), removed)
+	}
+	for _, format := range variantFormats {
+		if _, err := os.Stat(filepath.Join(cacheDir, getVariantKey(tracked, format))); err != nil {
+			t.Errorf("Expected tracked %s variant to be kept: %v", format, err)
+		}
+		if _, err := os.Stat(filepath.Join(cacheDir, getVariantKey(orphan, format))); !os.IsNotExist(err) {
+			t.Errorf("Expected orphaned %s variant to be removed", format)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package media
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/filesystem"
+	"media-viewer/internal/logging"
+	"media-viewer/internal/mediatypes"
+	"media-viewer/internal/metrics"
+)
+
+const (
+	// freshnessFileDelay is the pause between files in a freshness pass, so
+	// hashing sources doesn't compete with requests for disk time
+	freshnessFileDelay = 50 * time.Millisecond
+
+	// freshnessBusyDelay is how long a freshness pass waits before checking
+	// again whether background generation has finished or memory pressure eased
+	freshnessBusyDelay = 5 * time.Second
+)
+
+// freshnessResult is the outcome of checking one cached thumbnail
+type freshnessResult int
+
+const (
+	freshnessSkipped     freshnessResult = iota // Not checked: folder, other size, evicted or missing source
+	freshnessCurrent                            // Matches its source
+	freshnessRecorded                           // Had no source hash; the current one was recorded
+	freshnessRegenerated                        // Was stale and has been regenerated
+	freshnessFailed                             // Was stale, but regeneration failed
+)
+
+// SetFreshnessInterval sets how often the background freshness pass checks
+// every cached thumbnail against its source and regenerates those that no
+// longer match (0 = never). Unlike the staleness check on request, it also
+// finds edits that kept the source's modification time, by comparing content
+// hashes. If the generator is running, the pass's timer restarts.
+func (t *ThumbnailGenerator) SetFreshnessInterval(interval time.Duration) {
+	t.freshnessInterval.Store(int64(max(interval, 0)))
+
+	select {
+	case t.freshnessReset <- struct{}{}:
+	default:
+		// A reset is already pending
+	}
+}
+
+// FreshnessInterval returns the interval set with SetFreshnessInterval
+func (t *ThumbnailGenerator) FreshnessInterval() time.Duration {
+	return time.Duration(t.freshnessInterval.Load())
+}
+
+// freshnessLoop runs the freshness pass at the interval set with
+// SetFreshnessInterval until stop is closed
+func (t *ThumbnailGenerator) freshnessLoop(stop <-chan struct{}) {
+	for {
+		var timer *time.Timer
+		var fire <-chan time.Time
+		if interval := t.FreshnessInterval(); interval > 0 {
+			timer = time.NewTimer(interval)
+			fire = timer.C
+		}
+
+		select {
+		case <-fire:
+			t.runFreshnessPass(stop)
+		case <-t.freshnessReset:
+			logging.Info("Thumbnail freshness interval changed to %v", t.FreshnessInterval())
+		case <-stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// runFreshnessPass checks every cached thumbnail against its source and
+// regenerates the stale ones. It is low priority: it pauses between files,
+// and while background generation runs or memory is short.
+func (t *ThumbnailGenerator) runFreshnessPass(stop <-chan struct{}) {
+	if !t.enabled {
+		return
+	}
+
+	entries, err := os.ReadDir(t.cacheDir)
+	if err != nil {
+		logging.Warn("Thumbnail freshness pass: failed to read cache directory: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	logging.Info("Thumbnail freshness pass started")
+	start := time.Now()
+	counts := make(map[freshnessResult]int)
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, metaFileExtension) {
+			continue
+		}
+		if !t.waitForFreshnessTurn(stop) {
+			logging.Info("Thumbnail freshness pass stopped")
+			return
+		}
+		counts[t.checkFreshness(ctx, filepath.Join(t.cacheDir, name))]++
+	}
+
+	logging.Info("Thumbnail freshness pass complete in %v: %d current, %d recorded, %d regenerated, %d failed",
+		time.Since(start).Round(time.Millisecond), counts[freshnessCurrent], counts[freshnessRecorded], counts[freshnessRegenerated], counts[freshnessFailed])
+}
+
+// waitForFreshnessTurn pauses before the next file of a freshness pass, for
+// longer while background generation runs or memory is under pressure.
+// Returns false once stop is closed.
+func (t *ThumbnailGenerator) waitForFreshnessTurn(stop <-chan struct{}) bool {
+	for {
+		if t.memoryMonitor != nil && !t.memoryMonitor.WaitIfPaused() {
+			return false
+		}
+		if !t.isGenerating.Load() && (t.memoryMonitor == nil || !t.memoryMonitor.ShouldThrottle()) {
+			break
+		}
+		select {
+		case <-stop:
+			return false
+		case <-time.After(freshnessBusyDelay):
+		}
+	}
+
+	select {
+	case <-stop:
+		return false
+	case <-time.After(freshnessFileDelay):
+		return true
+	}
+}
+
+// checkFreshness checks the thumbnail of a .meta file against its source,
+// and regenerates it if the source is newer, its content hash differs from
+// the recorded one, or the thumbnail has an outdated style or format. A
+// thumbnail without a recorded hash gets the source's current one, so later
+// passes can compare against it.
+func (t *ThumbnailGenerator) checkFreshness(ctx context.Context, metaPath string) freshnessResult {
+	meta, err := loadMetaFile(metaPath)
+	if err != nil {
+		return freshnessSkipped
+	}
+	sourcePath := meta.SourcePath
+
+	info, err := os.Stat(sourcePath)
+	if err != nil || info.IsDir() {
+		// Removed sources are left to orphan cleanup; folder composites
+		// are invalidated when their contents change
+		return freshnessSkipped
+	}
+
+	fileType := mediatypes.GetFileType(filepath.Ext(sourcePath))
+	switch fileType {
+	case database.FileTypeImage, database.FileTypeVideo:
+	case database.FileTypeOther:
+		if !t.OtherThumbnailsEnabled() {
+			return freshnessSkipped
+		}
+	default:
+		return freshnessSkipped
+	}
+
+	// .meta files of another thumbnail size are left to orphan cleanup, and
+	// evicted thumbnails are generated when next requested
+	cacheKey := t.getCacheKey(sourcePath, fileType)
+	if t.getMetaPath(cacheKey) != metaPath || !t.cachedThumbnailExists(cacheKey) {
+		return freshnessSkipped
+	}
+
+	if !t.isThumbnailStale(cacheKey, info.ModTime()) {
+		hash, err := filesystem.HashContent(sourcePath)
+		if err != nil {
+			logging.Debug("Thumbnail freshness pass: failed to hash %s: %v", sourcePath, err)
+			return freshnessSkipped
+		}
+		if meta.SourceHash == "" {
+			meta.SourceHash = hash
+			if err := meta.save(metaPath); err != nil {
+				logging.Debug("Thumbnail freshness pass: failed to record hash of %s: %v", sourcePath, err)
+			}
+			return freshnessRecorded
+		}
+		if hash == meta.SourceHash {
+			return freshnessCurrent
+		}
+	}
+
+	logging.Debug("Thumbnail freshness pass: regenerating stale thumbnail of %s", sourcePath)
+	if _, err := t.regenerateThumbnail(ctx, sourcePath, fileType); err != nil {
+		logging.Debug("Thumbnail freshness pass: failed to regenerate %s: %v", sourcePath, err)
+		return freshnessFailed
+	}
+	metrics.ThumbnailFreshnessRegenerations.Inc()
+	return freshnessRegenerated
+}
@@ -0,0 +1,126 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestSetFreshnessInterval(t *testing.T) {
+	gen := NewThumbnailGenerator(t.TempDir(), t.TempDir(), true, nil, time.Hour, nil)
+
+	if got := gen.FreshnessInterval(); got != 0 {
+		t.Errorf("Expected the freshness pass to be off by default, got %v", got)
+	}
+
+	gen.SetFreshnessInterval(24 * time.Hour)
+	if got := gen.FreshnessInterval(); got != 24*time.Hour {
+		t.Errorf("FreshnessInterval() = %v, want 24h", got)
+	}
+	select {
+	case <-gen.freshnessReset:
+	default:
+		t.Error("Expected the freshness loop to be signalled")
+	}
+
+	gen.SetFreshnessInterval(-time.Minute)
+	if got := gen.FreshnessInterval(); got != 0 {
+		t.Errorf("Expected a negative interval to turn the pass off, got %v", got)
+	}
+}
+
+func TestCheckFreshness(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, nil, time.Hour, nil)
+	ctx := context.Background()
+
+	filename := filepath.Join(mediaDir, "photo.jpg")
+	createTestImageFile(t, filename, 300, 200, "jpeg", 85)
+	modTime := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filename, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set source time: %v", err)
+	}
+
+	// Generated before the pass was enabled, so no hash is recorded
+	original, err := gen.GetThumbnail(ctx, filename, database.FileTypeImage)
+	if err != nil {
+		t.Fatalf("GetThumbnail failed: %v", err)
+	}
+	gen.SetFreshnessInterval(time.Hour)
+
+	cacheKey := gen.getCacheKey(filename, database.FileTypeImage)
+	metaPath := gen.getMetaPath(cacheKey)
+
+	if got := gen.checkFreshness(ctx, metaPath); got != freshnessRecorded {
+		t.Fatalf("checkFreshness() = %v, want the hash recorded", got)
+	}
+	if got := gen.checkFreshness(ctx, metaPath); got != freshnessCurrent {
+		t.Fatalf("checkFreshness() = %v, want current", got)
+	}
+
+	// Edit the source but keep its modification time, as rsync -t does
+	createTestImageFile(t, filename, 200, 300, "jpeg", 85)
+	if err := os.Chtimes(filename, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set source time: %v", err)
+	}
+	if gen.isThumbnailStale(cacheKey, modTime) {
+		t.Fatal("Expected the edit to go unnoticed by the modification time")
+	}
+
+	if got := gen.checkFreshness(ctx, metaPath); got != freshnessRegenerated {
+		t.Fatalf("checkFreshness() = %v, want regenerated", got)
+	}
+	regenerated, err := gen.GetThumbnail(ctx, filename, database.FileTypeImage)
+	if err != nil {
+		t.Fatalf("GetThumbnail after the pass failed: %v", err)
+	}
+	if bytes.Equal(regenerated, original) {
+		t.Error("Expected the thumbnail of the edited file to be regenerated")
+	}
+	if got := gen.checkFreshness(ctx, metaPath); got != freshnessCurrent {
+		t.Errorf("Expected the regenerated thumbnail to record the new hash, got %v", got)
+	}
+
+	// Sources that are gone are left to orphan cleanup
+	if err := os.Remove(filename); err != nil {
+		t.Fatalf("Failed to remove source: %v", err)
+	}
+	if got := gen.checkFreshness(ctx, metaPath); got != freshnessSkipped {
+		t.Errorf("checkFreshness() = %v for a removed source, want skipped", got)
+	}
+}
+
+func TestRunFreshnessPassStopped(t *testing.T) {
+	cacheDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, t.TempDir(), true, nil, time.Hour, nil)
+
+	cacheKey := gen.getCacheKey("/media/photo.jpg", database.FileTypeImage)
+	if err := gen.writeMetaFile(cacheKey, "/media/photo.jpg", "", ThumbnailStyle{}, ThumbnailDimensions{}); err != nil {
+		t.Fatalf("Failed to write meta file: %v", err)
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+
+	done := make(chan struct{})
+	go func() {
+		gen.runFreshnessPass(stop)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a stopped freshness pass to return")
+	}
+}
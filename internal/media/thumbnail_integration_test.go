@@ -196,6 +196,49 @@ func TestGenerateImageWithFFmpegTimeout(t *testing.T) {
 	}
 }
 
+// TestGenerateImageWithFFmpegModernFormats tests decoding AVIF and HEIC, which
+// neither Go's image package nor a libvips build without libheif can read
+func TestGenerateImageWithFFmpegModernFormats(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not available, skipping integration test")
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		encoders []string
+	}{
+		{"AVIF", "test.avif", []string{"libaom-av1", "libsvtav1", "librav1e"}},
+		{"HEIC", "test.heic", []string{"libx265"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mediaDir := t.TempDir()
+			gen := NewThumbnailGenerator(t.TempDir(), mediaDir, true, nil, time.Hour, nil)
+
+			filename := filepath.Join(mediaDir, tt.file)
+			if err := createStillImageWithFFmpeg(filename, tt.encoders); err != nil {
+				t.Skipf("ffmpeg can't encode %s: %v", tt.name, err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			img, err := gen.generateImageWithFFmpeg(ctx, filename)
+			if err != nil {
+				t.Fatalf("generateImageWithFFmpeg failed: %v", err)
+			}
+			if bounds := img.Bounds(); bounds.Dx() != 320 || bounds.Dy() != 240 {
+				t.Errorf("Expected a 320x240 image, got %dx%d", bounds.Dx(), bounds.Dy())
+			}
+		})
+	}
+}
+
 func TestGenerateVideoThumbnailIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
@@ -706,6 +749,32 @@ func createTestImageFile(t testing.TB, path string, width, height int, format st
 	}
 }
 
+// createStillImageWithFFmpeg encodes a single red frame with the first of
+// encoders the FFmpeg build has, muxed by the extension of path
+func createStillImageWithFFmpeg(path string, encoders []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var err error
+	for _, encoder := range encoders {
+		cmd := exec.CommandContext(ctx, "ffmpeg",
+			"-f", "lavfi",
+			"-i", "color=c=red:s=320x240",
+			"-frames:v", "1",
+			"-c:v", encoder,
+			"-pix_fmt", "yuv420p",
+			"-y",
+			path,
+		)
+		var out []byte
+		if out, err = cmd.CombinedOutput(); err == nil {
+			return nil
+		}
+		err = fmt.Errorf("%s: %w: %s", encoder, err, out)
+	}
+	return err
+}
+
 func createTestVideoFile(path string) error {
 	// Create a 3-second test video with a red background
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -828,7 +897,7 @@ func TestProcessBatchIntegration(t *testing.T) {
 	gen.generationMu.Unlock()
 
 	// Process batch
-	gen.processBatch(ctx, files)
+	gen.processBatch(ctx, gen.stopSignal(), files)
 
 	// Verify stats
 	stats := gen.GetStatus().Generation
@@ -856,7 +925,7 @@ func TestProcessBatchEmptyIntegration(t *testing.T) {
 	ctx := context.Background()
 
 	// Process empty batch (should not panic)
-	gen.processBatch(ctx, []database.MediaFile{})
+	gen.processBatch(ctx, gen.stopSignal(), []database.MediaFile{})
 
 	// Should have no stats changes
 	stats := gen.GetStatus().Generation
@@ -896,7 +965,7 @@ func TestProcessBatchCancellation(t *testing.T) {
 	gen.generationMu.Unlock()
 
 	// Process batch (should be canceled mid-way)
-	gen.processBatch(ctx, files)
+	gen.processBatch(ctx, gen.stopSignal(), files)
 
 	// Verify processing was canceled before completion
 	stats := gen.GetStatus().Generation
@@ -933,7 +1002,7 @@ func TestProcessBatchWithExistingThumbnails(t *testing.T) {
 	}
 
 	// First pass - generate all thumbnails
-	gen.processBatch(ctx, files)
+	gen.processBatch(ctx, gen.stopSignal(), files)
 
 	firstStats := gen.GetStatus().Generation
 	firstGenerated := firstStats.Generated
@@ -944,7 +1013,7 @@ func TestProcessBatchWithExistingThumbnails(t *testing.T) {
 	gen.generationMu.Unlock()
 
 	// Second pass - should skip existing thumbnails
-	gen.processBatch(ctx, files)
+	gen.processBatch(ctx, gen.stopSignal(), files)
 
 	secondStats := gen.GetStatus().Generation
 
@@ -999,7 +1068,7 @@ func TestProcessBatchMixedTypes(t *testing.T) {
 	gen.generationMu.Unlock()
 
 	// Process batch
-	gen.processBatch(ctx, files)
+	gen.processBatch(ctx, gen.stopSignal(), files)
 
 	// Verify processing
 	stats := gen.GetStatus().Generation
@@ -1043,7 +1112,7 @@ func TestWorkerPoolScaling(t *testing.T) {
 			gen.generationMu.Unlock()
 
 			start := time.Now()
-			gen.processBatch(ctx, files)
+			gen.processBatch(ctx, gen.stopSignal(), files)
 			elapsed := time.Since(start)
 
 			stats := gen.GetStatus().Generation
@@ -1089,7 +1158,7 @@ func TestWorkerPoolContextCancellation(t *testing.T) {
 
 	// Process should respect context cancellation
 	start := time.Now()
-	gen.processBatch(ctx, files)
+	gen.processBatch(ctx, gen.stopSignal(), files)
 	elapsed := time.Since(start)
 
 	// Should finish quickly due to cancellation
@@ -1135,7 +1204,7 @@ func TestWorkerPoolStopChannel(t *testing.T) {
 	// Start processing in goroutine
 	done := make(chan struct{})
 	go func() {
-		gen.processBatch(ctx, files)
+		gen.processBatch(ctx, gen.stopSignal(), files)
 		close(done)
 	}()
 
@@ -1155,6 +1224,21 @@ func TestWorkerPoolStopChannel(t *testing.T) {
 
 	stats := gen.GetStatus().Generation
 	t.Logf("Processed %d/%d files before stop signal", stats.Processed, numFiles)
+
+	// After a restart the workers run again with fresh counters
+	<-done
+	gen.Start()
+	defer gen.Stop()
+
+	if stats := gen.GetStatus().Generation; stats.Processed != 0 {
+		t.Errorf("Expected Start to reset Processed, got %d", stats.Processed)
+	}
+
+	gen.processBatch(ctx, gen.stopSignal(), files[:3])
+
+	if stats := gen.GetStatus().Generation; stats.Processed != 3 {
+		t.Errorf("Expected 3 files processed after restart, got %d", stats.Processed)
+	}
 }
 
 func BenchmarkProcessBatch(b *testing.B) {
@@ -1188,7 +1272,7 @@ func BenchmarkProcessBatch(b *testing.B) {
 		gen.generationStats = GenerationStats{}
 		gen.generationMu.Unlock()
 
-		gen.processBatch(ctx, files)
+		gen.processBatch(ctx, gen.stopSignal(), files)
 	}
 }
 
@@ -1662,6 +1746,12 @@ func TestRunGenerationIncrementalWithDB(t *testing.T) {
 
 	firstStats := gen.GetStatus().Generation
 	t.Logf("Full generation: generated=%d, skipped=%d", firstStats.Generated, firstStats.Skipped)
+	if !firstStats.IsInitial {
+		t.Error("Expected the first full generation to be the initial run")
+	}
+	if gen.initialRun.Load() {
+		t.Error("Expected the initial run flag to be cleared after generation")
+	}
 
 	// Small delay to ensure timestamp difference
 	time.Sleep(100 * time.Millisecond)
@@ -1688,6 +1778,10 @@ func TestRunGenerationIncrementalWithDB(t *testing.T) {
 	t.Logf("Incremental generation: generated=%d, skipped=%d, total=%d, isIncremental=%v",
 		incrStats.Generated, incrStats.Skipped, incrStats.TotalFiles, incrStats.IsIncremental)
 
+	if incrStats.IsInitial {
+		t.Error("Expected incremental generation not to be the initial run")
+	}
+
 	// Incremental should process fewer files than full
 	// (only the new files, not the already-thumbnailed ones)
 	if incrStats.TotalFiles > initialFiles+newFiles {
@@ -2194,7 +2288,7 @@ func TestProcessFoldersForGenerationIntegration(t *testing.T) {
 	folders := []database.MediaFile{
 		{Path: "testfolder", Name: "testfolder", Type: database.FileTypeFolder},
 	}
-	gen.processFoldersForGeneration(ctx, folders)
+	gen.processFoldersForGeneration(ctx, gen.stopSignal(), folders)
 
 	// Verify stats
 	gen.generationMu.RLock()
@@ -2317,7 +2411,7 @@ func TestProcessFilesForGenerationIncrementalIntegration(t *testing.T) {
 	gen.generationMu.Lock()
 	gen.generationStats = GenerationStats{}
 	gen.generationMu.Unlock()
-	gen.processBatch(ctx, files)
+	gen.processBatch(ctx, gen.stopSignal(), files)
 
 	// Verify all generated
 	gen.generationMu.RLock()
@@ -2333,7 +2427,7 @@ func TestProcessFilesForGenerationIncrementalIntegration(t *testing.T) {
 	gen.generationStats = GenerationStats{}
 	gen.generationMu.Unlock()
 
-	gen.processFilesForGeneration(ctx, files[:2], true) // Only process first 2 files
+	gen.processFilesForGeneration(ctx, gen.stopSignal(), files[:2], true) // Only process first 2 files
 
 	gen.generationMu.RLock()
 	stats := gen.generationStats
@@ -2403,3 +2497,56 @@ func deleteTestFile(ctx context.Context, t *testing.T, db *database.Database, ke
 	}
 	t.Logf("DeleteMissingFiles removed %d rows", deleted)
 }
+
+func TestThumbnailGeneratorWithoutDatabaseIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, nil, time.Hour, nil)
+	gen.SetPaletteExtraction(true)
+
+	if status := gen.GetStatus(); !status.DatabaseLess {
+		t.Errorf("Expected GetStatus to report database-less mode, got %+v", status)
+	}
+
+	// On-demand generation works
+	ctx := context.Background()
+	filename := filepath.Join(mediaDir, "photo.jpg")
+	createTestImageFile(t, filename, 400, 300, "jpeg", 85)
+	if _, err := gen.GetThumbnail(ctx, filename, database.FileTypeImage); err != nil {
+		t.Fatalf("GetThumbnail failed: %v", err)
+	}
+
+	// Folder thumbnails are drawn without previews
+	if err := os.Mkdir(filepath.Join(mediaDir, "album"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.GetThumbnail(ctx, filepath.Join(mediaDir, "album"), database.FileTypeFolder); err != nil {
+		t.Fatalf("GetThumbnail for a folder failed: %v", err)
+	}
+
+	// Orphan cleanup keeps every thumbnail, as nothing is known to be indexed
+	entries, _ := os.ReadDir(cacheDir)
+	if orphans, legacy := gen.cleanupOrphanedThumbnails(ctx); orphans != 0 || legacy != 0 {
+		t.Errorf("Expected no cleanup without a database, removed %d orphans and %d legacy", orphans, legacy)
+	}
+	if after, _ := os.ReadDir(cacheDir); len(after) != len(entries) {
+		t.Errorf("Expected %d cached thumbnails to be kept, found %d", len(entries), len(after))
+	}
+
+	// Background generation doesn't run
+	gen.Start()
+	gen.NotifyIndexComplete()
+	gen.runGeneration(false)
+	gen.Stop()
+	if stats := gen.GetStatus().Generation; stats == nil || stats.InProgress || stats.Processed != 0 {
+		t.Errorf("Expected no background generation, got %+v", stats)
+	}
+
+	if status := NewThumbnailGenerator(cacheDir, mediaDir, true, &database.Database{}, time.Hour, nil).GetStatus(); status.DatabaseLess {
+		t.Error("Expected a generator with a database not to report database-less mode")
+	}
+}
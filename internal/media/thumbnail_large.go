@@ -0,0 +1,63 @@
+package media
+
+import (
+	"context"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/logging"
+)
+
+// defaultLargeFileWorkers is the worker cap for deferred large files when none is set
+const defaultLargeFileWorkers = 1
+
+// SetLargeFileDeferral defers background generation of thumbnails for images
+// larger than threshold bytes (huge RAW and TIFF files) until the other files
+// and folders of a run are done, and generates them with at most workers
+// workers so they don't spike memory. A threshold of 0 disables deferral.
+// Videos are never deferred: their thumbnail is a single seeked frame, which
+// costs about the same regardless of file size.
+func (t *ThumbnailGenerator) SetLargeFileDeferral(threshold int64, workers int) {
+	if workers <= 0 {
+		workers = defaultLargeFileWorkers
+	}
+	t.largeFileThreshold.Store(max(threshold, 0))
+	t.largeFileWorkers.Store(int64(workers))
+}
+
+// splitLargeFiles separates the files whose generation is deferred from the rest,
+// keeping the order of both
+func (t *ThumbnailGenerator) splitLargeFiles(files []database.MediaFile) (regular, large []database.MediaFile) {
+	threshold := t.largeFileThreshold.Load()
+	if threshold <= 0 {
+		return files, nil
+	}
+
+	regular = make([]database.MediaFile, 0, len(files))
+	for _, file := range files {
+		if file.Type == database.FileTypeImage && file.Size > threshold {
+			large = append(large, file)
+		} else {
+			regular = append(regular, file)
+		}
+	}
+	return regular, large
+}
+
+// processLargeFiles generates the thumbnails deferred by splitLargeFiles with
+// the large-file worker cap
+func (t *ThumbnailGenerator) processLargeFiles(ctx context.Context, stop <-chan struct{}, files []database.MediaFile, incremental bool) {
+	logging.Info("Generating %d deferred thumbnails for large files with up to %d workers", len(files), t.largeFileWorkers.Load())
+
+	t.largeFilePass.Store(true)
+	defer t.largeFilePass.Store(false)
+
+	t.processFilesForGeneration(ctx, stop, files, incremental)
+}
+
+// limitLargeFileWorkers caps a worker count while deferred large files are processed
+func (t *ThumbnailGenerator) limitLargeFileWorkers(count int) int {
+	if !t.largeFilePass.Load() {
+		return count
+	}
+	return min(count, int(max(t.largeFileWorkers.Load(), 1)))
+}
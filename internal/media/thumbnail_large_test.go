@@ -0,0 +1,137 @@
+package media
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestSplitLargeFiles(t *testing.T) {
+	files := []database.MediaFile{
+		{Path: "small.jpg", Type: database.FileTypeImage, Size: 1 << 20},
+		{Path: "huge.tif", Type: database.FileTypeImage, Size: 300 << 20},
+		{Path: "movie.mkv", Type: database.FileTypeVideo, Size: 4 << 30},
+		{Path: "raw.cr2", Type: database.FileTypeImage, Size: 100<<20 + 1},
+		{Path: "exact.jpg", Type: database.FileTypeImage, Size: 100 << 20},
+	}
+
+	gen := &ThumbnailGenerator{}
+	if regular, large := gen.splitLargeFiles(files); len(regular) != len(files) || len(large) != 0 {
+		t.Errorf("Expected nothing deferred by default, got %d regular and %d large", len(regular), len(large))
+	}
+
+	gen.SetLargeFileDeferral(100<<20, 0)
+	regular, large := gen.splitLargeFiles(files)
+
+	var regularPaths, largePaths []string
+	for _, f := range regular {
+		regularPaths = append(regularPaths, f.Path)
+	}
+	for _, f := range large {
+		largePaths = append(largePaths, f.Path)
+	}
+	if want := []string{"small.jpg", "movie.mkv", "exact.jpg"}; !slices.Equal(regularPaths, want) {
+		t.Errorf("regular = %v, want %v", regularPaths, want)
+	}
+	if want := []string{"huge.tif", "raw.cr2"}; !slices.Equal(largePaths, want) {
+		t.Errorf("large = %v, want %v", largePaths, want)
+	}
+}
+
+func TestLimitLargeFileWorkers(t *testing.T) {
+	gen := &ThumbnailGenerator{}
+	gen.SetLargeFileDeferral(1<<20, 2)
+
+	if got := gen.limitLargeFileWorkers(6); got != 6 {
+		t.Errorf("Expected no cap outside the large-file pass, got %d", got)
+	}
+
+	gen.largeFilePass.Store(true)
+	if got := gen.limitLargeFileWorkers(6); got != 2 {
+		t.Errorf("Expected 2 workers during the large-file pass, got %d", got)
+	}
+	if got := gen.limitLargeFileWorkers(1); got != 1 {
+		t.Errorf("Expected the cap not to raise a lower count, got %d", got)
+	}
+
+	// An unset worker count defaults to one
+	gen.SetLargeFileDeferral(1<<20, 0)
+	if got := gen.limitLargeFileWorkers(6); got != defaultLargeFileWorkers {
+		t.Errorf("Expected %d worker by default, got %d", defaultLargeFileWorkers, got)
+	}
+}
+
+func TestRunGenerationDefersLargeFiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+
+	db, _, err := database.New(context.Background(), filepath.Join(t.TempDir(), "large_test.db"), &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, db, time.Hour, nil)
+	ctx := context.Background()
+
+	var largeFile string
+	for i, name := range []string{"a.jpg", "b.jpg", "c.jpg", "big.jpg"} {
+		filename := filepath.Join(mediaDir, name)
+		createTestImageFile(t, filename, 400, 300, "jpeg", 85)
+
+		info, err := os.Stat(filename)
+		if err != nil {
+			t.Fatalf("Failed to stat test file: %v", err)
+		}
+		size := info.Size()
+		if name == "big.jpg" {
+			// Index it as larger than it is, so it crosses the threshold
+			largeFile = filename
+			size = 50 << 20
+		}
+		upsertTestFile(ctx, t, db, database.MediaFile{
+			Path:       name,
+			Name:       name,
+			ParentPath: ".",
+			Type:       database.FileTypeImage,
+			Size:       size,
+			ModTime:    time.Now().Add(-time.Duration(i+1) * time.Hour),
+		})
+	}
+
+	gen.SetLargeFileDeferral(10<<20, 1)
+	gen.runGeneration(false)
+
+	stats := gen.GetStatus().Generation
+	if stats.LargeFilesDeferred != 1 || stats.LargeFilesPending != 0 {
+		t.Errorf("Expected 1 large file deferred and none pending, got deferred=%d pending=%d",
+			stats.LargeFilesDeferred, stats.LargeFilesPending)
+	}
+	if stats.TotalFiles != 4 || stats.Generated != 4 {
+		t.Errorf("Expected all 4 files generated, got total=%d generated=%d", stats.TotalFiles, stats.Generated)
+	}
+
+	// The large file is generated after the others
+	largeInfo, err := os.Stat(filepath.Join(cacheDir, gen.getCacheKey(largeFile, database.FileTypeImage)))
+	if err != nil {
+		t.Fatalf("Expected a thumbnail for the large file: %v", err)
+	}
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		info, err := os.Stat(filepath.Join(cacheDir, gen.getCacheKey(filepath.Join(mediaDir, name), database.FileTypeImage)))
+		if err != nil {
+			t.Fatalf("Expected a thumbnail for %s: %v", name, err)
+		}
+		if largeInfo.ModTime().Before(info.ModTime()) {
+			t.Errorf("Expected the large file to be generated after %s", name)
+		}
+	}
+}
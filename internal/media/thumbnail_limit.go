@@ -0,0 +1,175 @@
+package media
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"media-viewer/internal/filesystem"
+	"media-viewer/internal/logging"
+	"media-viewer/internal/metrics"
+)
+
+// cacheTouchInterval is how old a cached file's access time must be before a
+// read moves it forward, so popular thumbnails aren't touched on every request
+const cacheTouchInterval = time.Minute
+
+// cacheEntry is an evictable file in the thumbnail cache
+type cacheEntry struct {
+	path     string
+	metaPath string // .meta file removed with the thumbnail; empty for variants and shared thumbnails
+	size     int64
+	accessed time.Time
+}
+
+// SetCacheLimit caps the total size of the thumbnail cache at maxBytes (0 =
+// unlimited). EnforceCacheLimit evicts the least recently read thumbnails
+// while the cache is larger.
+func (t *ThumbnailGenerator) SetCacheLimit(maxBytes int64) {
+	t.cacheLimit.Store(max(maxBytes, 0))
+}
+
+// touchCacheFile records that a cached file was read by moving its access
+// time forward. Few filesystems keep access times current by themselves, so
+// it is set explicitly; the modification time, which staleness checks rely
+// on, is kept. Only done while a cache limit is set.
+func (t *ThumbnailGenerator) touchCacheFile(path string) {
+	if t.cacheLimit.Load() <= 0 {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	if now.Sub(filesystem.AccessTime(info)) < cacheTouchInterval {
+		return
+	}
+	if err := os.Chtimes(path, now, info.ModTime()); err != nil {
+		logging.Debug("Failed to record access to cached thumbnail %s: %v", path, err)
+	}
+}
+
+// beginCacheWrite marks a cache file as being written until the returned
+// function is called, so EnforceCacheLimit doesn't evict it meanwhile
+func (t *ThumbnailGenerator) beginCacheWrite(path string) func() {
+	t.cacheWritesMu.Lock()
+	if t.cacheWrites == nil {
+		t.cacheWrites = make(map[string]int)
+	}
+	t.cacheWrites[path]++
+	t.cacheWritesMu.Unlock()
+
+	return func() {
+		t.cacheWritesMu.Lock()
+		defer t.cacheWritesMu.Unlock()
+		if t.cacheWrites[path]--; t.cacheWrites[path] <= 0 {
+			delete(t.cacheWrites, path)
+		}
+	}
+}
+
+// EnforceCacheLimit evicts the least recently read files from the thumbnail
+// cache until it fits the limit set with SetCacheLimit. A thumbnail goes
+// with its .meta file; format and size variants are evicted on their own,
+// and shared thumbnails are left to their .meta references, which find
+// them missing. Evicted files are generated again when next requested.
+// Files being written are never evicted. Returns the number evicted.
+func (t *ThumbnailGenerator) EnforceCacheLimit() int {
+	limit := t.cacheLimit.Load()
+	if !t.enabled || limit <= 0 {
+		return 0
+	}
+
+	entries, total := t.listCacheEntries()
+	if total <= limit {
+		return 0
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].accessed.Before(entries[j].accessed)
+	})
+
+	evicted := 0
+	var freed int64
+	for _, entry := range entries {
+		if total-freed <= limit {
+			break
+		}
+		if t.evictCacheEntry(entry) {
+			evicted++
+			freed += entry.size
+		}
+	}
+
+	if evicted > 0 {
+		metrics.ThumbnailCacheEvictions.Add(float64(evicted))
+		t.lastCacheUpdate.Store(0)
+		logging.Info("Thumbnail cache over its %s limit: evicted %d files (%s)", formatBytes(limit), evicted, formatBytes(freed))
+	}
+	return evicted
+}
+
+// listCacheEntries returns the evictable files in the cache directory and
+// its shared thumbnails, with their total size. .meta files are small and
+// go with their thumbnails, so they aren't counted.
+func (t *ThumbnailGenerator) listCacheEntries() (entries []cacheEntry, total int64) {
+	add := func(dir string, tracked bool) {
+		dirEntries, err := os.ReadDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logging.Debug("Failed to read cache directory %s: %v", dir, err)
+			}
+			return
+		}
+		for _, dirEntry := range dirEntries {
+			name := dirEntry.Name()
+			if !dirEntry.Type().IsRegular() || strings.HasSuffix(name, metaFileExtension) {
+				continue
+			}
+			info, err := dirEntry.Info()
+			if err != nil {
+				continue
+			}
+			entry := cacheEntry{
+				path:     filepath.Join(dir, name),
+				size:     info.Size(),
+				accessed: filesystem.AccessTime(info),
+			}
+			if tracked && !isVariantFile(name) {
+				entry.metaPath = t.getMetaPath(name)
+			}
+			entries = append(entries, entry)
+			total += entry.size
+		}
+	}
+
+	add(t.cacheDir, true)
+	add(t.contentDir(), false)
+	return entries, total
+}
+
+// evictCacheEntry removes a cache file, and its .meta file, unless it is
+// being written. Reports whether it was removed.
+func (t *ThumbnailGenerator) evictCacheEntry(entry cacheEntry) bool {
+	t.cacheWritesMu.Lock()
+	defer t.cacheWritesMu.Unlock()
+
+	if t.cacheWrites[entry.path] > 0 {
+		return false
+	}
+	if err := os.Remove(entry.path); err != nil {
+		if !os.IsNotExist(err) {
+			logging.Debug("Failed to evict cached thumbnail %s: %v", entry.path, err)
+		}
+		return false
+	}
+	if entry.metaPath != "" {
+		if err := os.Remove(entry.metaPath); err != nil && !os.IsNotExist(err) {
+			logging.Debug("Failed to remove meta file %s: %v", entry.metaPath, err)
+		}
+	}
+	logging.Debug("Evicted cached thumbnail: %s", entry.path)
+	return true
+}
@@ -0,0 +1,139 @@
+package media
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/filesystem"
+)
+
+// writeCacheFile writes a cache file of size bytes last read at accessed
+func writeCacheFile(t *testing.T, path string, size int, accessed time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, accessed, accessed); err != nil {
+		t.Fatalf("Failed to set times of %s: %v", path, err)
+	}
+}
+
+func TestEnforceCacheLimit(t *testing.T) {
+	setup := func(t *testing.T) (*ThumbnailGenerator, string) {
+		t.Helper()
+		cacheDir := t.TempDir()
+		gen := NewThumbnailGenerator(cacheDir, t.TempDir(), true, nil, time.Hour, nil)
+
+		now := time.Now()
+		writeCacheFile(t, filepath.Join(cacheDir, "aaa.jpg"), 100, now.Add(-3*time.Hour))
+		writeCacheFile(t, filepath.Join(cacheDir, "aaa.meta"), 10, now.Add(-3*time.Hour))
+		writeCacheFile(t, filepath.Join(cacheDir, "aaa.webp"), 100, now.Add(-2*time.Hour))
+		writeCacheFile(t, filepath.Join(cacheDir, "bbb.jpg"), 100, now.Add(-time.Hour))
+		writeCacheFile(t, filepath.Join(cacheDir, "bbb.meta"), 10, now.Add(-time.Hour))
+		writeCacheFile(t, filepath.Join(cacheDir, "ccc.png"), 100, now)
+		writeCacheFile(t, filepath.Join(cacheDir, "ccc.meta"), 10, now)
+		return gen, cacheDir
+	}
+	exists := func(cacheDir, name string) bool {
+		_, err := os.Stat(filepath.Join(cacheDir, name))
+		return err == nil
+	}
+
+	t.Run("unlimited", func(t *testing.T) {
+		gen, _ := setup(t)
+		if evicted := gen.EnforceCacheLimit(); evicted != 0 {
+			t.Errorf("Expected no evictions without a limit, got %d", evicted)
+		}
+	})
+
+	t.Run("least recently read first", func(t *testing.T) {
+		gen, cacheDir := setup(t)
+		gen.SetCacheLimit(200)
+
+		if evicted := gen.EnforceCacheLimit(); evicted != 2 {
+			t.Errorf("Expected 2 evictions, got %d", evicted)
+		}
+		for _, name := range []string{"aaa.jpg", "aaa.meta", "aaa.webp"} {
+			if exists(cacheDir, name) {
+				t.Errorf("Expected %s to be evicted", name)
+			}
+		}
+		for _, name := range []string{"bbb.jpg", "bbb.meta", "ccc.png", "ccc.meta"} {
+			if !exists(cacheDir, name) {
+				t.Errorf("Expected %s to be kept", name)
+			}
+		}
+
+		// Already under the limit
+		if evicted := gen.EnforceCacheLimit(); evicted != 0 {
+			t.Errorf("Expected no further evictions, got %d", evicted)
+		}
+	})
+
+	t.Run("skips files being written", func(t *testing.T) {
+		gen, cacheDir := setup(t)
+		gen.SetCacheLimit(100)
+
+		done := gen.beginCacheWrite(filepath.Join(cacheDir, "aaa.jpg"))
+		gen.EnforceCacheLimit()
+		done()
+
+		if !exists(cacheDir, "aaa.jpg") || !exists(cacheDir, "aaa.meta") {
+			t.Error("Expected the thumbnail being written to be kept")
+		}
+		for _, name := range []string{"aaa.webp", "bbb.jpg", "ccc.png"} {
+			if exists(cacheDir, name) {
+				t.Errorf("Expected %s to be evicted", name)
+			}
+		}
+	})
+
+	t.Run("shared thumbnails", func(t *testing.T) {
+		gen, _ := setup(t)
+		if err := os.MkdirAll(gen.contentDir(), 0o755); err != nil {
+			t.Fatalf("Failed to create content directory: %v", err)
+		}
+		shared := filepath.Join(gen.contentDir(), "0123abcd.jpg")
+		writeCacheFile(t, shared, 100, time.Now().Add(-4*time.Hour))
+		gen.SetCacheLimit(400)
+
+		if evicted := gen.EnforceCacheLimit(); evicted != 1 {
+			t.Errorf("Expected 1 eviction, got %d", evicted)
+		}
+		if _, err := os.Stat(shared); !os.IsNotExist(err) {
+			t.Error("Expected the least recently read shared thumbnail to be evicted")
+		}
+	})
+}
+
+func TestReadCachedThumbnailRecordsAccess(t *testing.T) {
+	cacheDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, t.TempDir(), true, nil, time.Hour, nil)
+	gen.SetCacheLimit(1 << 20)
+
+	path := filepath.Join(cacheDir, "thumb.jpg")
+	written := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	writeCacheFile(t, path, 10, written)
+	// Last read after it was written, so relatime mounts leave it alone
+	if err := os.Chtimes(path, written.Add(time.Hour), written); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+
+	start := time.Now().Add(-time.Second)
+	if _, err := gen.readCachedThumbnail("thumb.jpg"); err != nil {
+		t.Fatalf("readCachedThumbnail failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat thumbnail: %v", err)
+	}
+	if accessed := filesystem.AccessTime(info); accessed.Before(start) {
+		t.Errorf("Expected the read to be recorded, access time is still %v", accessed)
+	}
+	if !info.ModTime().Equal(written) {
+		t.Errorf("Expected the modification time to stay %v, got %v", written, info.ModTime())
+	}
+}
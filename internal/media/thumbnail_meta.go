@@ -0,0 +1,136 @@
+package media
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Lines of a .meta file after the source path, in the order they are
+// written. Each is left out when it has its default value, so files written
+// before a line was added still read the same.
+const (
+	// metaContentPrefix starts the line that references a shared thumbnail
+	metaContentPrefix = "\ncontent:"
+
+	// metaDimensionsPrefix starts the line recording the dimensions of the
+	// thumbnail and the aspect ratio of its source
+	metaDimensionsPrefix = "\ndimensions:"
+
+	// metaSourceHashPrefix starts the line holding the content hash of the
+	// source a thumbnail was generated from
+	metaSourceHashPrefix = "\nsource:"
+
+	// metaFormatPrefix starts the line recording the output format set when
+	// a thumbnail was generated, unless it was ThumbnailFormatDefault
+	metaFormatPrefix = "\nformat:"
+
+	// metaSizePrefix starts the line recording the size a thumbnail was
+	// generated for, unless it was DefaultThumbnailSize
+	metaSizePrefix = "\nsize:"
+
+	// metaStylePrefix starts the line recording the style a thumbnail was
+	// rendered with, unless it had none
+	metaStylePrefix = "\nstyle:"
+)
+
+// metaFile is the .meta sidecar of a cached thumbnail
+type metaFile struct {
+	SourcePath string              // Source the thumbnail was generated from
+	ContentKey string              // Shared thumbnail referenced; empty if stored per path
+	Dimensions ThumbnailDimensions // Zero if not recorded
+	SourceHash string              // Content hash of the source; empty if not recorded
+	Format     ThumbnailFormat     // Output format set at generation
+	Size       int                 // Thumbnail size set at generation
+	Style      string              // ThumbnailStyle.String of the style rendered with
+}
+
+// parseMetaFile parses .meta file contents written by metaFile.String. The
+// lines are taken off the end, so a source path may contain newlines.
+func parseMetaFile(data string) metaFile {
+	meta := metaFile{Format: ThumbnailFormatDefault, Size: DefaultThumbnailSize}
+
+	data, meta.Style = cutMetaLine(data, metaStylePrefix)
+	data, value := cutMetaLine(data, metaSizePrefix)
+	if size, err := strconv.Atoi(value); err == nil {
+		meta.Size = size
+	}
+	data, value = cutMetaLine(data, metaFormatPrefix)
+	if value != "" {
+		meta.Format = ThumbnailFormat(value)
+	}
+	data, meta.SourceHash = cutMetaLine(data, metaSourceHashPrefix)
+	data, value = cutMetaLine(data, metaDimensionsPrefix)
+	if value != "" {
+		meta.Dimensions, _ = parseThumbnailDimensions(value)
+	}
+	meta.SourcePath, meta.ContentKey = cutMetaLine(data, metaContentPrefix)
+	return meta
+}
+
+// cutMetaLine separates the last line starting with prefix from the rest of
+// a .meta file
+func cutMetaLine(data, prefix string) (rest, value string) {
+	if idx := strings.LastIndex(data, prefix); idx >= 0 {
+		return data[:idx], data[idx+len(prefix):]
+	}
+	return data, ""
+}
+
+// String returns the .meta file contents parseMetaFile reads back
+func (m metaFile) String() string {
+	var b strings.Builder
+	b.WriteString(m.SourcePath)
+	if m.ContentKey != "" {
+		b.WriteString(metaContentPrefix + m.ContentKey)
+	}
+	if m.Dimensions.Width > 0 && m.Dimensions.Height > 0 {
+		b.WriteString(metaDimensionsPrefix + m.Dimensions.String())
+	}
+	if m.SourceHash != "" {
+		b.WriteString(metaSourceHashPrefix + m.SourceHash)
+	}
+	if m.Format != ThumbnailFormatDefault {
+		b.WriteString(metaFormatPrefix + string(m.Format))
+	}
+	if m.Size != DefaultThumbnailSize {
+		b.WriteString(metaSizePrefix + strconv.Itoa(m.Size))
+	}
+	if m.Style != "" {
+		b.WriteString(metaStylePrefix + m.Style)
+	}
+	return b.String()
+}
+
+// loadMetaFile reads and parses the .meta file at path
+func loadMetaFile(path string) (metaFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return metaFile{}, err
+	}
+	return parseMetaFile(string(data)), nil
+}
+
+// save writes the .meta file to path
+func (m metaFile) save(path string) error {
+	return os.WriteFile(path, []byte(m.String()), 0o644)
+}
+
+// newMetaFile returns the .meta file of a thumbnail of sourcePath generated
+// now, with the current output format and size
+func (t *ThumbnailGenerator) newMetaFile(sourcePath, contentKey, sourceHash string, style ThumbnailStyle, dims ThumbnailDimensions) metaFile {
+	return metaFile{
+		SourcePath: sourcePath,
+		ContentKey: contentKey,
+		Dimensions: dims,
+		SourceHash: sourceHash,
+		Format:     t.currentOutputFormat(),
+		Size:       t.thumbnailSize(),
+		Style:      style.String(),
+	}
+}
+
+// readMetaFile reads the .meta file of a cache key
+func (t *ThumbnailGenerator) readMetaFile(cacheKey string) (metaFile, error) {
+	return loadMetaFile(t.getMetaPath(cacheKey))
+}
@@ -0,0 +1,81 @@
+package media
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestMetaFile(t *testing.T) {
+	style := ThumbnailStyle{CornerRadius: 8, Background: color.RGBA{A: 255}}
+	full := metaFile{
+		SourcePath: "/media/photo.jpg",
+		ContentKey: "abc123",
+		Dimensions: ThumbnailDimensions{Width: 640, Height: 427, SourceAspectRatio: 1.5},
+		SourceHash: "f00d",
+		Format:     ThumbnailFormatAVIF,
+		Size:       640,
+		Style:      style.String(),
+	}
+
+	// The lines are written in the order files have always had them
+	want := "/media/photo.jpg\ncontent:abc123\ndimensions:640x427 1.5000\nsource:f00d\nformat:avif\nsize:640\nstyle:" + style.String()
+	if got := full.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want metaFile
+	}{
+		{
+			"every line",
+			want,
+			full,
+		},
+		{
+			// Written before anything but the source path was recorded
+			"source path only",
+			"/media/photo.jpg",
+			metaFile{SourcePath: "/media/photo.jpg", Format: ThumbnailFormatDefault, Size: DefaultThumbnailSize},
+		},
+		{
+			"shared thumbnail",
+			"/media/photo.jpg\ncontent:abc123",
+			metaFile{SourcePath: "/media/photo.jpg", ContentKey: "abc123", Size: DefaultThumbnailSize},
+		},
+		{
+			"newline in the source path",
+			"/media/odd\nname.jpg\ncontent:abc123\nsize:640",
+			metaFile{SourcePath: "/media/odd\nname.jpg", ContentKey: "abc123", Size: 640},
+		},
+		{
+			"unparsable dimensions",
+			"/media/photo.jpg\ndimensions:0x427 1.5\nsource:f00d",
+			metaFile{SourcePath: "/media/photo.jpg", SourceHash: "f00d", Size: DefaultThumbnailSize},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMetaFile(tt.data); got != tt.want {
+				t.Errorf("parseMetaFile(%q) = %+v, want %+v", tt.data, got, tt.want)
+			}
+		})
+	}
+
+	// Default values are left out, so such files hold only the source path
+	plain := metaFile{SourcePath: "/media/photo.jpg", Format: ThumbnailFormatDefault, Size: DefaultThumbnailSize}
+	if got := plain.String(); got != "/media/photo.jpg" {
+		t.Errorf("String() = %q, want only the source path", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "photo.meta")
+	if err := full.save(path); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if got, err := loadMetaFile(path); err != nil || got != full {
+		t.Errorf("loadMetaFile = (%+v, %v), want %+v", got, err, full)
+	}
+}
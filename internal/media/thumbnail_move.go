@@ -0,0 +1,63 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/logging"
+)
+
+// MoveThumbnail carries the cached thumbnail of a file or folder moved from
+// oldPath to newPath over to the new path, so it isn't generated again. Only
+// the thumbnail itself moves: its other formats, scales and sizes are
+// removed and regenerated on demand. A shared thumbnail stays where it is
+// and the new path refers to it.
+func (t *ThumbnailGenerator) MoveThumbnail(oldPath, newPath string, fileType database.FileType) error {
+	if !t.enabled {
+		return nil
+	}
+
+	oldKey := t.getCacheKey(oldPath, fileType)
+	newKey := t.getCacheKey(newPath, fileType)
+	t.removeVariants(oldKey)
+	t.removeVariants(newKey)
+
+	meta, err := t.readMetaFile(oldKey)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // Not cached
+		}
+		return fmt.Errorf("failed to read thumbnail metadata: %w", err)
+	}
+
+	if meta.SourcePath != oldPath {
+		// Written for another path; left to orphan cleanup
+		return nil
+	}
+
+	if meta.ContentKey == "" {
+		err := os.Rename(filepath.Join(t.cacheDir, oldKey), filepath.Join(t.cacheDir, newKey))
+		if err != nil {
+			t.deleteMetaFile(oldKey)
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return fmt.Errorf("failed to move thumbnail: %w", err)
+		}
+	}
+
+	meta.SourcePath = newPath
+	if err := meta.save(t.getMetaPath(newKey)); err != nil {
+		_ = os.Remove(filepath.Join(t.cacheDir, newKey))
+		t.deleteMetaFile(oldKey)
+		return fmt.Errorf("failed to write thumbnail metadata: %w", err)
+	}
+	t.deleteMetaFile(oldKey)
+
+	logging.Debug("Moved thumbnail of %s to %s", oldPath, newPath)
+	return nil
+}
@@ -0,0 +1,64 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestMoveThumbnail(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, nil, time.Hour, nil)
+	ctx := context.Background()
+
+	oldPath := filepath.Join(mediaDir, "photo.jpg")
+	newPath := filepath.Join(mediaDir, "renamed.jpg")
+	createTestImageFile(t, oldPath, 300, 200, "jpeg", 85)
+
+	original, err := gen.GetThumbnail(ctx, oldPath, database.FileTypeImage)
+	if err != nil {
+		t.Fatalf("GetThumbnail failed: %v", err)
+	}
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatalf("Failed to move source: %v", err)
+	}
+	if err := gen.MoveThumbnail(oldPath, newPath, database.FileTypeImage); err != nil {
+		t.Fatalf("MoveThumbnail failed: %v", err)
+	}
+
+	oldKey := gen.getCacheKey(oldPath, database.FileTypeImage)
+	newKey := gen.getCacheKey(newPath, database.FileTypeImage)
+	if _, err := os.Stat(filepath.Join(cacheDir, oldKey)); !os.IsNotExist(err) {
+		t.Error("Expected nothing left under the old key")
+	}
+	if meta, err := gen.readMetaFile(newKey); err != nil || meta.SourcePath != newPath {
+		t.Errorf("readMetaFile = (%+v, %v), want source %q", meta, err, newPath)
+	}
+	if gen.isThumbnailStale(newKey, time.Now().Add(-time.Hour)) {
+		t.Error("Expected the moved thumbnail to be current")
+	}
+
+	moved, err := gen.GetThumbnail(ctx, newPath, database.FileTypeImage)
+	if err != nil {
+		t.Fatalf("GetThumbnail after the move failed: %v", err)
+	}
+	if !bytes.Equal(moved, original) {
+		t.Error("Expected the cached thumbnail to be served from the new path")
+	}
+
+	// Paths without a cached thumbnail are left alone
+	if err := gen.MoveThumbnail(filepath.Join(mediaDir, "none.jpg"), filepath.Join(mediaDir, "other.jpg"), database.FileTypeImage); err != nil {
+		t.Errorf("MoveThumbnail of an uncached path failed: %v", err)
+	}
+}
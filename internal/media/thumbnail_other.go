@@ -0,0 +1,257 @@
+package media
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"media-viewer/internal/mediatypes"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// OtherThumbnailMode selects what thumbnail, if any, is drawn for files that
+// are not images, videos or playlists (FileTypeOther).
+type OtherThumbnailMode string
+
+const (
+	// OtherThumbnailsOff generates no thumbnails for other files.
+	OtherThumbnailsOff OtherThumbnailMode = "off"
+	// OtherThumbnailsBadge draws a page icon labeled with the file's extension.
+	OtherThumbnailsBadge OtherThumbnailMode = "badge"
+	// OtherThumbnailsPreview draws the first lines of text files, and a badge
+	// for everything else.
+	OtherThumbnailsPreview OtherThumbnailMode = "preview"
+)
+
+const (
+	// otherThumbnailSize is the width and height of other-file thumbnails
+	otherThumbnailSize = 200
+
+	// textPreviewBytes is how much of a file is read for a text preview, and
+	// to decide whether it is text
+	textPreviewBytes = 4096
+
+	// maxBadgeLabel is the number of extension characters drawn on a badge
+	maxBadgeLabel = 5
+)
+
+var (
+	// otherBackgroundColor matches the gallery, like the default style's corner fill
+	otherBackgroundColor = DefaultThumbnailStyle().Background
+	pageColor            = color.RGBA{R: 245, G: 245, B: 240, A: 255}
+	pageFoldColor        = color.RGBA{R: 210, G: 210, B: 200, A: 255}
+	previewTextColor     = color.RGBA{R: 60, G: 60, B: 70, A: 255}
+
+	// badgeColors are picked from by extension, so each extension keeps its color
+	badgeColors = []color.RGBA{
+		{R: 0x3b, G: 0x82, B: 0xf6, A: 255},
+		{R: 0x10, G: 0xb9, B: 0x81, A: 255},
+		{R: 0xf5, G: 0x9e, B: 0x0b, A: 255},
+		{R: 0xef, G: 0x44, B: 0x44, A: 255},
+		{R: 0x8b, G: 0x5c, B: 0xf6, A: 255},
+		{R: 0x06, G: 0xb6, B: 0xd4, A: 255},
+		{R: 0xec, G: 0x48, B: 0x99, A: 255},
+		{R: 0x64, G: 0x74, B: 0x8b, A: 255},
+	}
+)
+
+// ParseOtherThumbnailMode parses a THUMBNAIL_OTHER_FILES value. An empty value
+// selects OtherThumbnailsOff.
+func ParseOtherThumbnailMode(value string) (OtherThumbnailMode, error) {
+	switch mode := OtherThumbnailMode(strings.TrimSpace(strings.ToLower(value))); mode {
+	case "":
+		return OtherThumbnailsOff, nil
+	case OtherThumbnailsOff, OtherThumbnailsBadge, OtherThumbnailsPreview:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q (use off, badge or preview)", value)
+	}
+}
+
+// SetOtherThumbnailMode sets how thumbnails are drawn for other files. They
+// are not indexed, so they are only drawn on request and are removed again by
+// the orphan cleanup of the next generation run.
+func (t *ThumbnailGenerator) SetOtherThumbnailMode(mode OtherThumbnailMode) {
+	t.otherThumbnails.Store(&mode)
+}
+
+// otherThumbnailMode returns the mode set with SetOtherThumbnailMode
+func (t *ThumbnailGenerator) otherThumbnailMode() OtherThumbnailMode {
+	if mode := t.otherThumbnails.Load(); mode != nil {
+		return *mode
+	}
+	return OtherThumbnailsOff
+}
+
+// OtherThumbnailsEnabled reports whether thumbnails are drawn for other files.
+func (t *ThumbnailGenerator) OtherThumbnailsEnabled() bool {
+	return t.otherThumbnailMode() != OtherThumbnailsOff
+}
+
+// generateOtherThumbnail draws the thumbnail of a file of no media type
+func (t *ThumbnailGenerator) generateOtherThumbnail(_ context.Context, filePath string) (image.Image, error) {
+	mode := t.otherThumbnailMode()
+	if mode == OtherThumbnailsOff {
+		return nil, errors.New("thumbnails for other files are disabled")
+	}
+
+	if mode == OtherThumbnailsPreview {
+		lines, err := readTextPreview(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if lines != nil {
+			return drawTextPreview(lines), nil
+		}
+	}
+	return drawExtensionBadge(filePath), nil
+}
+
+// readTextPreview returns the first lines of a text file, or nil if the file
+// doesn't look like text
+func readTextPreview(filePath string) ([]string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, textPreviewBytes)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	buf = buf[:n]
+
+	// Drop a character cut off by the read limit before checking the encoding
+	if n == textPreviewBytes {
+		for i := 0; i < utf8.UTFMax && len(buf) > 0 && !utf8.Valid(buf); i++ {
+			buf = buf[:len(buf)-1]
+		}
+	}
+	if len(buf) == 0 || !utf8.Valid(buf) || !strings.HasPrefix(http.DetectContentType(buf), "text/") {
+		return nil, nil
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(string(buf)))
+	for scanner.Scan() {
+		lines = append(lines, strings.ReplaceAll(scanner.Text(), "\t", "    "))
+	}
+	return lines, nil
+}
+
+// newPageCanvas returns a thumbnail canvas with a page, its top-right corner
+// folded, drawn over the given area
+func newPageCanvas(page image.Rectangle) *image.RGBA {
+	canvas := image.NewRGBA(image.Rect(0, 0, otherThumbnailSize, otherThumbnailSize))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{otherBackgroundColor}, image.Point{}, draw.Src)
+	draw.Draw(canvas, page, &image.Uniform{pageColor}, image.Point{}, draw.Src)
+
+	// Fold the top-right corner: cut it to the background and shade the flap
+	fold := pageFoldSize(page)
+	for y := range fold {
+		for x := range fold {
+			c := pageFoldColor
+			if x > y {
+				c = otherBackgroundColor
+			}
+			canvas.Set(page.Max.X-fold+x, page.Min.Y+y, c)
+		}
+	}
+	return canvas
+}
+
+// pageFoldSize returns the width and height of a page's folded corner
+func pageFoldSize(page image.Rectangle) int {
+	return page.Dx() / 5
+}
+
+// drawExtensionBadge draws a page icon with the file's extension on a band
+// colored by the extension
+func drawExtensionBadge(filePath string) image.Image {
+	page := image.Rect(50, 20, 150, 180)
+	canvas := newPageCanvas(page)
+
+	label := strings.ToUpper(strings.TrimPrefix(mediatypes.NormalizeExt(filePath), "."))
+	if label == "" {
+		label = "FILE"
+	}
+	if utf8.RuneCountInString(label) > maxBadgeLabel {
+		label = string([]rune(label)[:maxBadgeLabel])
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(label))
+	bandColor := badgeColors[hash.Sum32()%uint32(len(badgeColors))]
+
+	band := image.Rect(page.Min.X-10, 110, page.Max.X+10, 150)
+	draw.Draw(canvas, band, &image.Uniform{bandColor}, image.Point{}, draw.Src)
+
+	// Render the label at the font's size, then scale it up to fill the band
+	face := basicfont.Face7x13
+	text := image.NewRGBA(image.Rect(0, 0, face.Advance*len([]rune(label)), face.Height))
+	drawText(text, label, 0, face.Ascent, color.White)
+
+	scale := min(band.Dx()*8/10/text.Bounds().Dx(), band.Dy()*8/10/text.Bounds().Dy())
+	scale = max(scale, 1)
+	scaled := imaging.Resize(text, text.Bounds().Dx()*scale, text.Bounds().Dy()*scale, imaging.NearestNeighbor)
+	offset := image.Pt(
+		band.Min.X+(band.Dx()-scaled.Bounds().Dx())/2,
+		band.Min.Y+(band.Dy()-scaled.Bounds().Dy())/2,
+	)
+	draw.Draw(canvas, scaled.Bounds().Add(offset), scaled, image.Point{}, draw.Over)
+
+	return canvas
+}
+
+// drawTextPreview draws the lines of a text file onto a page, clipped to it
+func drawTextPreview(lines []string) image.Image {
+	page := image.Rect(12, 8, otherThumbnailSize-12, otherThumbnailSize-8)
+	canvas := newPageCanvas(page)
+
+	face := basicfont.Face7x13
+	padding := 8
+	fold := pageFoldSize(page)
+	maxLines := (page.Dy() - 2*padding) / face.Height
+
+	for i, line := range lines[:min(len(lines), maxLines)] {
+		top := page.Min.Y + padding + i*face.Height
+
+		// Lines beside the folded corner end before it
+		right := page.Max.X - padding
+		if top < page.Min.Y+fold {
+			right = page.Max.X - fold
+		}
+		if maxChars, runes := (right-page.Min.X-padding)/face.Advance, []rune(line); len(runes) > maxChars {
+			line = string(runes[:maxChars])
+		}
+		drawText(canvas, line, page.Min.X+padding, top+face.Ascent, previewTextColor)
+	}
+	return canvas
+}
+
+// drawText draws a line of text with its baseline at y
+func drawText(dst draw.Image, text string, x, y int, c color.Color) {
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
@@ -0,0 +1,137 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestParseOtherThumbnailMode(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected OtherThumbnailMode
+		wantErr  bool
+	}{
+		{"", OtherThumbnailsOff, false},
+		{"off", OtherThumbnailsOff, false},
+		{"Badge", OtherThumbnailsBadge, false},
+		{" preview ", OtherThumbnailsPreview, false},
+		{"icons", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseOtherThumbnailMode(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseOtherThumbnailMode(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if got != tt.expected {
+			t.Errorf("ParseOtherThumbnailMode(%q) = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestReadTextPreview(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	lines, err := readTextPreview(write("notes.txt", []byte("first line\n\tindented\nlast")))
+	if err != nil {
+		t.Fatalf("readTextPreview failed: %v", err)
+	}
+	if want := []string{"first line", "    indented", "last"}; strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected lines %q, got %q", want, lines)
+	}
+
+	// A multi-byte character cut by the read limit still counts as text
+	long := append(bytes.Repeat([]byte("a"), textPreviewBytes-1), "é"...)
+	if lines, err := readTextPreview(write("long.txt", long)); err != nil || len(lines) != 1 {
+		t.Errorf("Expected a long text file previewed, got %d lines, err %v", len(lines), err)
+	}
+
+	for name, data := range map[string][]byte{
+		"empty.txt":  nil,
+		"binary.bin": {0x00, 0x01, 0x02, 0xff, 0xfe},
+		"latin1.txt": []byte("caf\xe9"),
+	} {
+		if lines, err := readTextPreview(write(name, data)); err != nil || lines != nil {
+			t.Errorf("Expected no preview for %s, got %q, err %v", name, lines, err)
+		}
+	}
+
+	if _, err := readTextPreview(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestDrawExtensionBadge(t *testing.T) {
+	bandColor := func(img image.Image) color.Color {
+		return img.At(45, 112)
+	}
+
+	pdf := drawExtensionBadge("/media/report.pdf")
+	if b := pdf.Bounds(); b.Dx() != otherThumbnailSize || b.Dy() != otherThumbnailSize {
+		t.Fatalf("Expected a %dpx badge, got %v", otherThumbnailSize, b)
+	}
+
+	// The band color follows the extension, whatever its case
+	if bandColor(pdf) != bandColor(drawExtensionBadge("/other/SCAN.PDF")) {
+		t.Error("Expected badges of the same extension to share a color")
+	}
+
+	// Files without an extension get a badge too
+	if bandColor(drawExtensionBadge("/media/README")) == color.Color(otherBackgroundColor) {
+		t.Error("Expected a band on a badge for a file without an extension")
+	}
+}
+
+func TestGetThumbnailOtherFiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(t.TempDir(), mediaDir, true, nil, time.Hour, nil)
+	ctx := context.Background()
+
+	textFile := filepath.Join(mediaDir, "notes.txt")
+	if err := os.WriteFile(textFile, []byte("shopping list\n- milk\n- eggs\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write text file: %v", err)
+	}
+
+	// Off by default
+	if _, err := gen.GetThumbnail(ctx, textFile, database.FileTypeOther); err == nil {
+		t.Fatal("Expected no thumbnail with other-file thumbnails off")
+	}
+
+	gen.SetOtherThumbnailMode(OtherThumbnailsBadge)
+	badge, err := gen.GetThumbnail(ctx, textFile, database.FileTypeOther)
+	if err != nil {
+		t.Fatalf("Badge thumbnail failed: %v", err)
+	}
+	if len(badge) < 2 || badge[0] != 0xFF || badge[1] != 0xD8 {
+		t.Error("Expected a JPEG thumbnail")
+	}
+
+	gen.SetOtherThumbnailMode(OtherThumbnailsPreview)
+	preview, err := gen.RegenerateThumbnail(ctx, textFile, database.FileTypeOther)
+	if err != nil {
+		t.Fatalf("Preview thumbnail failed: %v", err)
+	}
+	if bytes.Equal(preview, badge) {
+		t.Error("Expected a text preview to differ from the badge")
+	}
+}
@@ -0,0 +1,74 @@
+package media
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseOutputFormat parses a THUMBNAIL_FORMAT value: "jpeg" (or empty) for
+// no output format, "webp" or "avif".
+func ParseOutputFormat(value string) (ThumbnailFormat, error) {
+	switch format := strings.TrimSpace(strings.ToLower(value)); format {
+	case "", "jpeg", "jpg":
+		return ThumbnailFormatDefault, nil
+	case string(ThumbnailFormatWebP), string(ThumbnailFormatAVIF):
+		return ThumbnailFormat(format), nil
+	default:
+		return "", fmt.Errorf("unknown format %q (use jpeg, webp or avif)", value)
+	}
+}
+
+// SetOutputFormat sets the format thumbnails are encoded in as they are
+// generated, besides the default format, which clients that don't accept it
+// are still served. It is also the format NegotiateFormat prefers when a
+// client accepts several equally. Folder thumbnails get it too, as both
+// formats keep their transparency. Cached thumbnails generated with another
+// output format are regenerated like stale ones. ThumbnailFormatDefault
+// encodes variants only on request.
+func (t *ThumbnailGenerator) SetOutputFormat(format ThumbnailFormat) {
+	t.outputFormat.Store(&format)
+}
+
+// currentOutputFormat returns the format set with SetOutputFormat
+func (t *ThumbnailGenerator) currentOutputFormat() ThumbnailFormat {
+	if format := t.outputFormat.Load(); format != nil {
+		return *format
+	}
+	return ThumbnailFormatDefault
+}
+
+// preferredFormats returns the variant formats in order of preference: the
+// output format, then the others in the order of variantFormats
+func (t *ThumbnailGenerator) preferredFormats() []ThumbnailFormat {
+	output := t.currentOutputFormat()
+	if output == ThumbnailFormatDefault {
+		return variantFormats
+	}
+	formats := []ThumbnailFormat{output}
+	for _, format := range variantFormats {
+		if format != output {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+// encodeOutputFormat caches the output format variant of a thumbnail that
+// was just written, so requests for it don't wait for the encoder. Formats
+// the encoder can't encode are skipped.
+func (t *ThumbnailGenerator) encodeOutputFormat(cacheKey string, data []byte) {
+	format := t.currentOutputFormat()
+	if format == ThumbnailFormatDefault {
+		return
+	}
+	if _, unsupported := t.unsupportedFormats.Load(format); unsupported {
+		return
+	}
+	t.formatVariant(cacheKey, data, format)
+}
+
+// hasOtherOutputFormat reports whether the thumbnail of a .meta file was
+// generated with a different output format than new thumbnails are
+func (t *ThumbnailGenerator) hasOtherOutputFormat(meta metaFile) bool {
+	return meta.Format != t.currentOutputFormat()
+}
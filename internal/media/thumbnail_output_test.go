@@ -0,0 +1,112 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected ThumbnailFormat
+		wantErr  bool
+	}{
+		{"", ThumbnailFormatDefault, false},
+		{"jpeg", ThumbnailFormatDefault, false},
+		{"JPG", ThumbnailFormatDefault, false},
+		{"webp", ThumbnailFormatWebP, false},
+		{" AVIF ", ThumbnailFormatAVIF, false},
+		{"png", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseOutputFormat(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.expected {
+			t.Errorf("ParseOutputFormat(%q) = %q, %v; want %q (error: %v)", tt.value, got, err, tt.expected, tt.wantErr)
+		}
+	}
+}
+
+func TestNegotiateFormatPrefersOutputFormat(t *testing.T) {
+	gen := &ThumbnailGenerator{}
+	gen.SetOutputFormat(ThumbnailFormatWebP)
+
+	tests := []struct {
+		accept   string
+		expected ThumbnailFormat
+	}{
+		{"image/avif,image/webp,image/apng,*/*;q=0.8", ThumbnailFormatWebP},
+		{"image/avif,*/*", ThumbnailFormatAVIF},
+		{"image/webp;q=0.5,image/avif", ThumbnailFormatAVIF},
+		{"*/*", ThumbnailFormatDefault},
+	}
+
+	for _, tt := range tests {
+		if got := gen.NegotiateFormat(tt.accept); got != tt.expected {
+			t.Errorf("NegotiateFormat(%q) = %q, want %q", tt.accept, got, tt.expected)
+		}
+	}
+}
+
+func TestGenerateEncodesOutputFormat(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, nil, time.Hour, nil)
+	gen.SetOutputFormat(ThumbnailFormatWebP)
+
+	encodes := 0
+	gen.encodeVariant = func(data []byte, format ThumbnailFormat) ([]byte, error) {
+		encodes++
+		return append([]byte(string(format)+":"), data...), nil
+	}
+
+	filename := filepath.Join(mediaDir, "photo.jpg")
+	createTestImageFile(t, filename, 300, 200, "jpeg", 85)
+
+	ctx := context.Background()
+	base, err := gen.GetThumbnail(ctx, filename, database.FileTypeImage)
+	if err != nil {
+		t.Fatalf("GetThumbnail failed: %v", err)
+	}
+	if encodes != 1 {
+		t.Fatalf("Expected the output format to be encoded with the thumbnail, got %d encodes", encodes)
+	}
+
+	// The variant is served from the cache
+	variant, format, err := gen.GetThumbnailInFormat(ctx, filename, database.FileTypeImage, ThumbnailFormatWebP)
+	if err != nil {
+		t.Fatalf("GetThumbnailInFormat failed: %v", err)
+	}
+	if format != ThumbnailFormatWebP || !bytes.Equal(variant, append([]byte("webp:"), base...)) || encodes != 1 {
+		t.Errorf("Expected the cached WebP variant, got format %q after %d encodes", format, encodes)
+	}
+
+	cacheKey := gen.getCacheKey(filename, database.FileTypeImage)
+	if meta, err := gen.readMetaFile(cacheKey); err != nil || gen.hasOtherOutputFormat(meta) {
+		t.Errorf("Expected the .meta file to record the output format, got %+v (error: %v)", meta, err)
+	}
+
+	// Changing the output format makes cached thumbnails stale
+	gen.SetOutputFormat(ThumbnailFormatAVIF)
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gen.isThumbnailStale(cacheKey, info.ModTime()) {
+		t.Error("Expected a thumbnail generated with another output format to be stale")
+	}
+	gen.SetOutputFormat(ThumbnailFormatDefault)
+	if !gen.isThumbnailStale(cacheKey, info.ModTime()) {
+		t.Error("Expected a thumbnail generated with an output format to be stale once it is unset")
+	}
+}
@@ -0,0 +1,87 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+
+	"github.com/disintegration/imaging"
+
+	"media-viewer/internal/logging"
+)
+
+// backfillPalettes stores palettes for indexed images and videos that have a
+// cached thumbnail but no palette, which is the case for every thumbnail
+// cached before PALETTE_EXTRACTION was enabled. Thumbnails are decoded from
+// the cache rather than regenerated; files without one get their palette when
+// it is generated. Stops early when the run's stop channel is closed. Returns
+// how many palettes were stored.
+func (t *ThumbnailGenerator) backfillPalettes(ctx context.Context, stop <-chan struct{}) int {
+	files, err := t.db.GetFilesWithoutPalette(ctx)
+	if err != nil {
+		logging.Warn("Failed to get files without palette: %v", err)
+		return 0
+	}
+
+	stored := 0
+	for _, file := range files {
+		if isClosed(stop) || ctx.Err() != nil {
+			break
+		}
+
+		fullPath := filepath.Join(t.mediaDir, file.Path)
+		cacheKey := t.getCacheKey(fullPath, file.Type)
+		// Stale thumbnails get a palette when they are regenerated
+		if t.isThumbnailStale(cacheKey, file.ModTime) {
+			continue
+		}
+		data, err := t.cachedThumbnailData(cacheKey)
+		if err != nil {
+			continue
+		}
+		thumb, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			logging.Debug("Failed to decode cached thumbnail for palette of %s: %v", file.Path, err)
+			continue
+		}
+
+		t.storePalette(ctx, fullPath, paletteArea(thumb, t.currentStyle()))
+		stored++
+	}
+
+	if stored > 0 {
+		logging.Info("Stored palettes for %d cached thumbnails", stored)
+	}
+	return stored
+}
+
+// cachedThumbnailData reads a cached thumbnail, per path or shared, without
+// refreshing its modification time the way readCachedThumbnail does for
+// requests
+func (t *ThumbnailGenerator) cachedThumbnailData(cacheKey string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(t.cacheDir, cacheKey))
+	if err == nil {
+		return data, nil
+	}
+	contentKey := t.readMetaContentKey(cacheKey)
+	if contentKey == "" {
+		return nil, err
+	}
+	return os.ReadFile(t.getContentPath(contentKey))
+}
+
+// paletteArea crops a cached thumbnail rendered with style to the part inside
+// its border and rounded corners, so their colors don't count, as they don't
+// for palettes taken when a thumbnail is generated
+func paletteArea(thumb image.Image, style ThumbnailStyle) image.Image {
+	// The corner fill reaches about 0.3 of the radius in from the edges
+	inset := style.BorderWidth + style.CornerRadius*3/10
+	bounds := thumb.Bounds()
+	if inset == 0 || bounds.Dx() <= 2*inset || bounds.Dy() <= 2*inset {
+		return thumb
+	}
+	return imaging.Crop(thumb, bounds.Inset(inset))
+}
@@ -0,0 +1,85 @@
+package media
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestRunGenerationBackfillsPalettes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+
+	db, _, err := database.New(context.Background(), filepath.Join(t.TempDir(), "palette_test.db"), &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, db, time.Hour, nil)
+	ctx := context.Background()
+
+	paths := []string{"a.jpg", "b.jpg"}
+	for _, path := range paths {
+		createTestImageFile(t, filepath.Join(mediaDir, path), 400, 300, "jpeg", 85)
+		upsertTestFile(ctx, t, db, database.MediaFile{
+			Path:       path,
+			Name:       path,
+			ParentPath: ".",
+			Type:       database.FileTypeImage,
+			ModTime:    time.Now().Add(-time.Hour),
+		})
+	}
+
+	// The cache is populated before palette extraction is enabled
+	gen.runGeneration(false)
+	for _, path := range paths {
+		if colors, err := db.GetFilePalette(ctx, path); err != nil || len(colors) != 0 {
+			t.Fatalf("%s: expected no palette with extraction disabled, got %v, %v", path, colors, err)
+		}
+	}
+
+	// The thumbnails are up to date, so nothing is regenerated, but the
+	// cached thumbnails get palettes
+	gen.SetPaletteExtraction(true)
+	gen.runGeneration(false)
+
+	if stats := gen.GetStatus().Generation; stats.Generated != 0 {
+		t.Errorf("Expected no thumbnails to be regenerated, got %+v", stats)
+	}
+	for _, path := range paths {
+		if colors, err := db.GetFilePalette(ctx, path); err != nil || len(colors) == 0 {
+			t.Errorf("%s: expected a backfilled palette, got %v, %v", path, colors, err)
+		}
+	}
+	if files, err := db.GetFilesWithoutPalette(ctx); err != nil || len(files) != 0 {
+		t.Errorf("Expected every file to have a palette, got %d left, %v", len(files), err)
+	}
+}
+
+func TestPaletteAreaSkipsStyleEdges(t *testing.T) {
+	thumb := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 200; x++ {
+			thumb.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	if area := paletteArea(thumb, ThumbnailStyle{}); area.Bounds() != thumb.Bounds() {
+		t.Errorf("Expected an unstyled thumbnail to be used whole, got %v", area.Bounds())
+	}
+
+	area := paletteArea(thumb, ThumbnailStyle{CornerRadius: 20, BorderWidth: 4})
+	if got := area.Bounds(); got.Dx() != 180 || got.Dy() != 180 {
+		t.Errorf("Expected the border and corners to be cropped to 180x180, got %v", got)
+	}
+}
@@ -0,0 +1,155 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/filesystem"
+	"media-viewer/internal/logging"
+)
+
+// SetStaleWhileRevalidate selects what happens when a cached thumbnail is
+// older than its source. When enabled, the stale thumbnail is returned
+// immediately and regenerated in the background, so the new one is served on
+// the next request. When disabled, the request waits for regeneration.
+func (t *ThumbnailGenerator) SetStaleWhileRevalidate(enabled bool) {
+	t.staleWhileRevalidate.Store(enabled)
+}
+
+// cachedThumbnailModTime returns when the cached thumbnail for a cache key
+// was written. Shared thumbnails are written once per content, so the .meta
+// reference of the path is used for them instead.
+func (t *ThumbnailGenerator) cachedThumbnailModTime(cacheKey string) (time.Time, error) {
+	info, err := os.Stat(filepath.Join(t.cacheDir, cacheKey))
+	if err != nil {
+		info, err = os.Stat(t.getMetaPath(cacheKey))
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	return info.ModTime(), nil
+}
+
+// isThumbnailStale reports whether the cached thumbnail predates the
+// modification time of its source or was rendered with another style or
+// output format. A zero source time (folders) is never stale.
+func (t *ThumbnailGenerator) isThumbnailStale(cacheKey string, sourceModTime time.Time) bool {
+	if sourceModTime.IsZero() {
+		return false
+	}
+	// Thumbnails without a .meta file are left alone
+	if meta, err := t.readMetaFile(cacheKey); err == nil && (t.hasOutdatedStyle(meta) || t.hasOtherOutputFormat(meta)) {
+		return true
+	}
+	thumbTime, err := t.cachedThumbnailModTime(cacheKey)
+	if err != nil {
+		return false
+	}
+	return thumbTime.Before(sourceModTime)
+}
+
+// markThumbnailFresh moves the timestamp of a just written thumbnail forward
+// to its source's when the source has a modification time in the future
+// (clock skew, bad camera clocks). Otherwise it would be regenerated on every
+// request until the clock caught up.
+func (t *ThumbnailGenerator) markThumbnailFresh(cacheKey string, sourceModTime time.Time) {
+	if !sourceModTime.After(time.Now()) {
+		return
+	}
+
+	path := filepath.Join(t.cacheDir, cacheKey)
+	if _, err := os.Stat(path); err != nil {
+		path = t.getMetaPath(cacheKey)
+	}
+	if err := os.Chtimes(path, sourceModTime, sourceModTime); err != nil {
+		logging.Debug("Failed to set thumbnail time for %s: %v", cacheKey, err)
+	}
+}
+
+// removeReplacedThumbnail removes the per-path thumbnail for a cache key once
+// it has been replaced by a shared one, so reads don't keep finding the old file.
+func (t *ThumbnailGenerator) removeReplacedThumbnail(cacheKey string) {
+	if err := os.Remove(filepath.Join(t.cacheDir, cacheKey)); err != nil && !os.IsNotExist(err) {
+		logging.Debug("Failed to remove replaced thumbnail %s: %v", cacheKey, err)
+	}
+}
+
+// thumbnailUpToDate reports whether an indexed file has a cached thumbnail
+// that is not older than the file's indexed modification time.
+func (t *ThumbnailGenerator) thumbnailUpToDate(file database.MediaFile) bool {
+	if !t.thumbnailExists(file.Path, file.Type) {
+		return false
+	}
+	if file.Type == database.FileTypeFolder {
+		return true
+	}
+	cacheKey := t.getCacheKey(filepath.Join(t.mediaDir, file.Path), file.Type)
+	return !t.isThumbnailStale(cacheKey, file.ModTime)
+}
+
+// RegenerateThumbnail generates a file's thumbnail afresh, ignoring the cached
+// one, and caches the result so later requests are served from it.
+func (t *ThumbnailGenerator) RegenerateThumbnail(ctx context.Context, filePath string, fileType database.FileType) ([]byte, error) {
+	return t.regenerateThumbnail(ctx, filePath, fileType)
+}
+
+// regenerateThumbnail generates a new thumbnail and replaces the cached one
+// in place, without invalidating it first.
+func (t *ThumbnailGenerator) regenerateThumbnail(ctx context.Context, filePath string, fileType database.FileType) ([]byte, error) {
+	if !t.enabled {
+		return nil, fmt.Errorf("thumbnails disabled")
+	}
+
+	var sourceModTime time.Time
+	if fileType != database.FileTypeFolder {
+		info, err := filesystem.StatWithRetry(filePath, filesystem.DefaultRetryConfig())
+		if err != nil {
+			return nil, fmt.Errorf("file not accessible: %w", err)
+		}
+		sourceModTime = info.ModTime()
+	}
+
+	fileLock := t.getLock(filePath)
+	fileLock.Lock()
+	defer func() {
+		fileLock.Unlock()
+		t.releaseLock(filePath)
+	}()
+
+	return t.generateAndCache(ctx, filePath, fileType, t.getCacheKey(filePath, fileType), sourceModTime, time.Now())
+}
+
+// revalidateInBackground regenerates a stale thumbnail without blocking the
+// caller. At most one revalidation runs per path; the stale thumbnail stays
+// in the cache until the new one replaces it.
+func (t *ThumbnailGenerator) revalidateInBackground(filePath string, fileType database.FileType) {
+	if _, inFlight := t.revalidating.LoadOrStore(filePath, struct{}{}); inFlight {
+		return
+	}
+
+	go func() {
+		defer t.revalidating.Delete(filePath)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		if stop := t.stopSignal(); stop != nil {
+			go func() {
+				select {
+				case <-stop:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+		}
+
+		logging.Debug("Revalidating stale thumbnail in background: %s", filePath)
+		if _, err := t.getThumbnail(ctx, filePath, fileType, false); err != nil {
+			logging.Debug("Background thumbnail revalidation failed for %s: %v", filePath, err)
+		}
+	}()
+}
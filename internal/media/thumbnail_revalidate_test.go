@@ -0,0 +1,208 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestIsThumbnailStale(t *testing.T) {
+	cacheDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, t.TempDir(), true, nil, time.Hour, nil)
+
+	cacheKey := gen.getCacheKey("/media/photo.jpg", database.FileTypeImage)
+	cachePath := filepath.Join(cacheDir, cacheKey)
+	if err := os.WriteFile(cachePath, []byte("thumb"), 0o644); err != nil {
+		t.Fatalf("Failed to write thumbnail: %v", err)
+	}
+	written := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(cachePath, written, written); err != nil {
+		t.Fatalf("Failed to set thumbnail time: %v", err)
+	}
+
+	if gen.isThumbnailStale(cacheKey, written.Add(-time.Minute)) {
+		t.Error("Expected thumbnail newer than its source to be fresh")
+	}
+	if !gen.isThumbnailStale(cacheKey, written.Add(time.Minute)) {
+		t.Error("Expected thumbnail older than its source to be stale")
+	}
+	if gen.isThumbnailStale(cacheKey, time.Time{}) {
+		t.Error("Expected unknown source time to never be stale")
+	}
+	if gen.isThumbnailStale("missing.jpg", time.Now()) {
+		t.Error("Expected missing thumbnail not to be reported stale")
+	}
+
+	// Shared thumbnails are dated by their .meta reference
+	sharedKey := gen.getCacheKey("/media/copy.jpg", database.FileTypeImage)
+	if err := gen.writeSharedMetaFile(sharedKey, "/media/copy.jpg", "abc", "", ThumbnailStyle{}, ThumbnailDimensions{}); err != nil {
+		t.Fatalf("Failed to write meta file: %v", err)
+	}
+	if err := os.Chtimes(gen.getMetaPath(sharedKey), written, written); err != nil {
+		t.Fatalf("Failed to set meta time: %v", err)
+	}
+	if !gen.isThumbnailStale(sharedKey, written.Add(time.Minute)) {
+		t.Error("Expected shared thumbnail reference older than its source to be stale")
+	}
+}
+
+func TestMarkThumbnailFreshFutureSource(t *testing.T) {
+	cacheDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, t.TempDir(), true, nil, time.Hour, nil)
+
+	cacheKey := gen.getCacheKey("/media/photo.jpg", database.FileTypeImage)
+	if err := os.WriteFile(filepath.Join(cacheDir, cacheKey), []byte("thumb"), 0o644); err != nil {
+		t.Fatalf("Failed to write thumbnail: %v", err)
+	}
+
+	future := time.Now().Add(24 * time.Hour)
+	if !gen.isThumbnailStale(cacheKey, future) {
+		t.Fatal("Expected source from the future to make the thumbnail stale")
+	}
+
+	gen.markThumbnailFresh(cacheKey, future)
+	if gen.isThumbnailStale(cacheKey, future) {
+		t.Error("Expected thumbnail to be fresh after marking")
+	}
+}
+
+func TestGetThumbnailStaleWhileRevalidate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	tests := []struct {
+		name       string
+		serveStale bool
+	}{
+		{"always fresh", false},
+		{"stale while revalidate", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			mediaDir := t.TempDir()
+			gen := NewThumbnailGenerator(cacheDir, mediaDir, true, nil, time.Hour, nil)
+			gen.SetStaleWhileRevalidate(tt.serveStale)
+			ctx := context.Background()
+
+			filename := filepath.Join(mediaDir, "photo.jpg")
+			createTestImageFile(t, filename, 300, 200, "jpeg", 85)
+
+			original, err := gen.GetThumbnail(ctx, filename, database.FileTypeImage)
+			if err != nil {
+				t.Fatalf("GetThumbnail failed: %v", err)
+			}
+
+			// Edit the source after its thumbnail was cached
+			createTestImageFile(t, filename, 200, 300, "jpeg", 85)
+			edited := time.Now().Add(time.Minute)
+			if err := os.Chtimes(filename, edited, edited); err != nil {
+				t.Fatalf("Failed to set source time: %v", err)
+			}
+
+			got, err := gen.GetThumbnail(ctx, filename, database.FileTypeImage)
+			if err != nil {
+				t.Fatalf("GetThumbnail after edit failed: %v", err)
+			}
+
+			if !tt.serveStale {
+				if bytes.Equal(got, original) {
+					t.Error("Expected the edited file's thumbnail to be regenerated before returning")
+				}
+				return
+			}
+
+			if !bytes.Equal(got, original) {
+				t.Error("Expected the stale thumbnail to be returned immediately")
+			}
+
+			// The next request after background revalidation gets the new thumbnail
+			deadline := time.Now().Add(10 * time.Second)
+			for {
+				if _, inFlight := gen.revalidating.Load(filename); !inFlight {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("Background revalidation did not finish")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			got, err = gen.GetThumbnail(ctx, filename, database.FileTypeImage)
+			if err != nil {
+				t.Fatalf("GetThumbnail after revalidation failed: %v", err)
+			}
+			if bytes.Equal(got, original) {
+				t.Error("Expected the regenerated thumbnail after background revalidation")
+			}
+		})
+	}
+}
+
+func TestRegenerateThumbnail(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, nil, time.Hour, nil)
+	ctx := context.Background()
+
+	filename := filepath.Join(mediaDir, "photo.jpg")
+	createTestImageFile(t, filename, 300, 200, "jpeg", 85)
+
+	// A cached thumbnail that is newer than its source but wrong
+	cachePath := filepath.Join(cacheDir, gen.getCacheKey(filename, database.FileTypeImage))
+	if err := os.WriteFile(cachePath, []byte("corrupt"), 0o644); err != nil {
+		t.Fatalf("Failed to write thumbnail: %v", err)
+	}
+
+	regenerated, err := gen.RegenerateThumbnail(ctx, filename, database.FileTypeImage)
+	if err != nil {
+		t.Fatalf("RegenerateThumbnail failed: %v", err)
+	}
+	if bytes.Equal(regenerated, []byte("corrupt")) {
+		t.Fatal("Expected a freshly generated thumbnail")
+	}
+
+	// The result replaces the cached thumbnail for later requests
+	cached, err := gen.GetThumbnail(ctx, filename, database.FileTypeImage)
+	if err != nil {
+		t.Fatalf("GetThumbnail failed: %v", err)
+	}
+	if !bytes.Equal(cached, regenerated) {
+		t.Error("Expected GetThumbnail to return the regenerated thumbnail")
+	}
+}
+
+func TestThumbnailUpToDate(t *testing.T) {
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, nil, time.Hour, nil)
+
+	file := database.MediaFile{Path: "photo.jpg", Type: database.FileTypeImage, ModTime: time.Now().Add(-time.Hour)}
+	if gen.thumbnailUpToDate(file) {
+		t.Error("Expected missing thumbnail not to be up to date")
+	}
+
+	cacheKey := gen.getCacheKey(filepath.Join(mediaDir, file.Path), file.Type)
+	if err := os.WriteFile(filepath.Join(cacheDir, cacheKey), []byte("thumb"), 0o644); err != nil {
+		t.Fatalf("Failed to write thumbnail: %v", err)
+	}
+	if !gen.thumbnailUpToDate(file) {
+		t.Error("Expected thumbnail written after the indexed time to be up to date")
+	}
+
+	file.ModTime = time.Now().Add(time.Hour)
+	if gen.thumbnailUpToDate(file) {
+		t.Error("Expected thumbnail older than the indexed time to need regeneration")
+	}
+}
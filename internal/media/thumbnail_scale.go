@@ -0,0 +1,191 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/logging"
+)
+
+// maxThumbnailScale caps the device pixel ratio thumbnails are rendered for.
+// Image thumbnails are decoded from at most MaxImageDimension pixels (or the
+// thumbnail size, if larger), so larger scales would add bytes but no detail.
+const maxThumbnailScale = 3
+
+// ParseThumbnailScale parses a device pixel ratio hint into the scale a
+// thumbnail is rendered at. Fractional ratios round up, so thumbnails are
+// never blurrier than the display, and the result is clamped to 1 through 3.
+// An empty value is scale 1.
+func ParseThumbnailScale(value string) (int, error) {
+	if value == "" {
+		return 1, nil
+	}
+	ratio, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || math.IsNaN(ratio) || ratio <= 0 {
+		return 0, fmt.Errorf("invalid device pixel ratio %q", value)
+	}
+	return int(min(max(math.Ceil(ratio), 1), maxThumbnailScale)), nil
+}
+
+// getScaledKey returns the cache filename of a thumbnail rendered at a scale,
+// in a format. Like format variants, scaled variants are named after, and
+// tracked by the .meta file of, the regular thumbnail.
+func getScaledKey(cacheKey string, scale int, format ThumbnailFormat) string {
+	return getRenderedKey(cacheKey, fmt.Sprintf("%dx", scale), format)
+}
+
+// getRenderedKey returns the cache filename of a variant rendered from the
+// source, named after the regular thumbnail with "@" and the variant's
+// suffix: a scale such as "2x" or a size such as "512px"
+func getRenderedKey(cacheKey, suffix string, format ThumbnailFormat) string {
+	ext := filepath.Ext(cacheKey)
+	if format != ThumbnailFormatDefault {
+		ext = "." + string(format)
+	}
+	return strings.TrimSuffix(cacheKey, filepath.Ext(cacheKey)) + "@" + suffix + ext
+}
+
+// scaledVariantBase returns the cache key of the regular thumbnail a scaled,
+// sized or obscured variant's filename belongs to, or false if it isn't one
+func scaledVariantBase(name string) (string, bool) {
+	ext := filepath.Ext(name)
+	base, suffix, ok := strings.Cut(strings.TrimSuffix(name, ext), "@")
+	if !ok {
+		return "", false
+	}
+	if isObscuredSuffix(suffix) {
+		return base + ext, true
+	}
+	number, isSize := strings.CutSuffix(suffix, "px")
+	if !isSize {
+		var isScale bool
+		if number, isScale = strings.CutSuffix(suffix, "x"); !isScale {
+			return "", false
+		}
+	}
+	if _, err := strconv.Atoi(number); err != nil {
+		return "", false
+	}
+	return base + ext, true
+}
+
+// renderedVariant is a thumbnail rendered from the source at a size other
+// than the regular one
+type renderedVariant struct {
+	suffix string         // Cache key suffix, also used in log messages
+	size   int            // Longest edge in pixels
+	style  ThumbnailStyle // The current style, scaled to match the size
+}
+
+// GetScaledThumbnail returns the thumbnail of a file rendered for a display
+// with the given scale (device pixel ratio, see ParseThumbnailScale), in the
+// requested format if possible. Scaled thumbnails are rendered from the
+// source on first request and cached until the regular thumbnail is
+// regenerated. Scale 1, folders and other files get the regular thumbnail.
+// The returned format is the one actually used.
+func (t *ThumbnailGenerator) GetScaledThumbnail(ctx context.Context, filePath string, fileType database.FileType, format ThumbnailFormat, scale int) ([]byte, ThumbnailFormat, error) {
+	return t.getScaledThumbnail(ctx, filePath, fileType, format, scale, t.staleWhileRevalidate.Load())
+}
+
+// getScaledThumbnail implements GetScaledThumbnail; allowStale is passed to
+// getThumbnail.
+func (t *ThumbnailGenerator) getScaledThumbnail(ctx context.Context, filePath string, fileType database.FileType, format ThumbnailFormat, scale int, allowStale bool) ([]byte, ThumbnailFormat, error) {
+	if scale <= 1 || (fileType != database.FileTypeImage && fileType != database.FileTypeVideo) {
+		return t.getThumbnailInFormat(ctx, filePath, fileType, format, allowStale)
+	}
+	scale = min(scale, maxThumbnailScale)
+	return t.getRenderedVariant(ctx, filePath, fileType, format, renderedVariant{
+		suffix: fmt.Sprintf("%dx", scale),
+		size:   t.thumbnailSize() * scale,
+		style:  t.currentStyle().scaled(scale),
+	}, allowStale)
+}
+
+// getRenderedVariant returns a variant of an image or video thumbnail
+// rendered from the source, rendering and caching it if the cached one is
+// missing or older than the regular thumbnail. Falls back to the regular
+// thumbnail if rendering fails.
+func (t *ThumbnailGenerator) getRenderedVariant(ctx context.Context, filePath string, fileType database.FileType, format ThumbnailFormat, variant renderedVariant, allowStale bool) ([]byte, ThumbnailFormat, error) {
+	// The regular thumbnail keeps the .meta file and decides when the
+	// variant is outdated
+	data, err := t.getThumbnail(ctx, filePath, fileType, allowStale)
+	if err != nil {
+		return nil, ThumbnailFormatDefault, err
+	}
+	cacheKey := t.getCacheKey(filePath, fileType)
+	baseTime, err := t.cachedThumbnailModTime(cacheKey)
+	if err != nil {
+		// Not cached; nothing to keep a variant in sync with
+		return data, ThumbnailFormatDefault, nil
+	}
+
+	renderedPath := filepath.Join(t.cacheDir, getRenderedKey(cacheKey, variant.suffix, ThumbnailFormatDefault))
+	rendered, err := t.cachedVariant(renderedPath, baseTime, func() ([]byte, error) {
+		return t.renderVariant(ctx, filePath, fileType, variant)
+	})
+	if err != nil {
+		logging.Warn("Failed to render %s thumbnail for %s, serving the regular one: %v", variant.suffix, filePath, err)
+		return t.getThumbnailInFormat(ctx, filePath, fileType, format, allowStale)
+	}
+
+	if format == ThumbnailFormatDefault {
+		return rendered, format, nil
+	}
+	if _, unsupported := t.unsupportedFormats.Load(format); unsupported {
+		return rendered, ThumbnailFormatDefault, nil
+	}
+
+	variantPath := filepath.Join(t.cacheDir, getRenderedKey(cacheKey, variant.suffix, format))
+	encoded, err := t.cachedVariant(variantPath, baseTime, func() ([]byte, error) {
+		return t.encodeFormat(rendered, format)
+	})
+	if err != nil {
+		logging.Warn("Failed to encode %s thumbnail, serving the default format instead: %v", format, err)
+		t.encodeFailed(format)
+		return rendered, ThumbnailFormatDefault, nil
+	}
+	return encoded, format, nil
+}
+
+// renderVariant renders the JPEG thumbnail of an image or video at the
+// variant's size and style. Sources smaller than that are not enlarged.
+func (t *ThumbnailGenerator) renderVariant(ctx context.Context, filePath string, fileType database.FileType, variant renderedVariant) ([]byte, error) {
+	genCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var img image.Image
+	var err error
+	switch fileType {
+	case database.FileTypeImage:
+		img, _, err = t.decodeImage(genCtx, filePath)
+	case database.FileTypeVideo:
+		img, err = t.generateVideoThumbnail(genCtx, filePath)
+	default:
+		return nil, fmt.Errorf("no %s thumbnails for file type %s", variant.suffix, fileType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var thumb image.Image = imaging.Fit(img, variant.size, variant.size, imaging.Lanczos)
+	if !variant.style.IsZero() {
+		thumb, _ = t.applyStyle(thumb, variant.style)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail as JPEG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,144 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestParseThumbnailScale(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int
+		wantErr  bool
+	}{
+		{"", 1, false},
+		{"1", 1, false},
+		{"2", 2, false},
+		{"1.25", 2, false},
+		{" 2.625 ", 3, false},
+		{"4", 3, false},
+		{"0.5", 1, false},
+		{"0", 0, true},
+		{"-2", 0, true},
+		{"NaN", 0, true},
+		{"retina", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseThumbnailScale(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseThumbnailScale(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if got != tt.expected {
+			t.Errorf("ParseThumbnailScale(%q) = %d, want %d", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestScaledKey(t *testing.T) {
+	if got := getScaledKey("abc.jpg", 2, ThumbnailFormatDefault); got != "abc@2x.jpg" {
+		t.Errorf("getScaledKey = %q, want abc@2x.jpg", got)
+	}
+	if got := getScaledKey("abc.jpg", 3, ThumbnailFormatWebP); got != "abc@3x.webp" {
+		t.Errorf("getScaledKey = %q, want abc@3x.webp", got)
+	}
+
+	for name, want := range map[string]string{"abc@2x.jpg": "abc.jpg", "abc@3x.avif": "abc.avif", "abc@512px.webp": "abc.webp"} {
+		if got, ok := scaledVariantBase(name); !ok || got != want {
+			t.Errorf("scaledVariantBase(%q) = %q, %v; want %q", name, got, ok, want)
+		}
+	}
+	for _, name := range []string{"abc.jpg", "abc.webp", "abc@x.jpg", "abc@2.jpg", "abc@twox.jpg", "abc@px.jpg"} {
+		if _, ok := scaledVariantBase(name); ok {
+			t.Errorf("Expected %q not to be a scaled variant", name)
+		}
+	}
+}
+
+func TestGetScaledThumbnail(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, nil, time.Hour, nil)
+	gen.encodeVariant = func(data []byte, format ThumbnailFormat) ([]byte, error) {
+		return append([]byte(string(format)+":"), data...), nil
+	}
+	ctx := context.Background()
+
+	filename := filepath.Join(mediaDir, "photo.jpg")
+	createTestImageFile(t, filename, 1200, 800, "jpeg", 85)
+
+	width := func(data []byte) int {
+		t.Helper()
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Failed to decode thumbnail: %v", err)
+		}
+		return cfg.Width
+	}
+
+	regular, format, err := gen.GetScaledThumbnail(ctx, filename, database.FileTypeImage, ThumbnailFormatDefault, 1)
+	if err != nil || format != ThumbnailFormatDefault {
+		t.Fatalf("GetScaledThumbnail at 1x = %q, %v", format, err)
+	}
+	if got := width(regular); got != DefaultThumbnailSize {
+		t.Errorf("Expected a %dpx regular thumbnail, got %d", DefaultThumbnailSize, got)
+	}
+
+	scaled, _, err := gen.GetScaledThumbnail(ctx, filename, database.FileTypeImage, ThumbnailFormatDefault, 2)
+	if err != nil {
+		t.Fatalf("GetScaledThumbnail at 2x failed: %v", err)
+	}
+	if got := width(scaled); got != 2*DefaultThumbnailSize {
+		t.Errorf("Expected a %dpx 2x thumbnail, got %d", 2*DefaultThumbnailSize, got)
+	}
+
+	cacheKey := gen.getCacheKey(filename, database.FileTypeImage)
+	scaledPath := filepath.Join(cacheDir, getScaledKey(cacheKey, 2, ThumbnailFormatDefault))
+	if _, err := os.Stat(scaledPath); err != nil {
+		t.Fatalf("Expected a cached 2x thumbnail: %v", err)
+	}
+
+	// Formats are encoded from the scaled thumbnail
+	webp, format, err := gen.GetScaledThumbnail(ctx, filename, database.FileTypeImage, ThumbnailFormatWebP, 2)
+	if err != nil || format != ThumbnailFormatWebP || !bytes.Equal(webp, append([]byte("webp:"), scaled...)) {
+		t.Fatalf("Expected the 2x WebP variant, got format %q, err %v", format, err)
+	}
+	webpPath := filepath.Join(cacheDir, getScaledKey(cacheKey, 2, ThumbnailFormatWebP))
+
+	// Folders are drawn at a fixed size
+	folder := filepath.Join(mediaDir, "album")
+	if err := os.Mkdir(folder, 0o755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+	if _, _, err := gen.GetScaledThumbnail(ctx, folder, database.FileTypeFolder, ThumbnailFormatDefault, 3); err != nil {
+		t.Fatalf("GetScaledThumbnail for a folder failed: %v", err)
+	}
+	folderKey := gen.getCacheKey(folder, database.FileTypeFolder)
+	if _, err := os.Stat(filepath.Join(cacheDir, getScaledKey(folderKey, 3, ThumbnailFormatDefault))); !os.IsNotExist(err) {
+		t.Error("Expected no scaled folder thumbnail")
+	}
+
+	// Orphan cleanup keeps scaled variants of tracked thumbnails; invalidation removes them
+	if removed := gen.cleanupOrphanedVariants(); removed != 0 {
+		t.Errorf("Expected tracked scaled variants kept, %d removed", removed)
+	}
+	if err := gen.InvalidateThumbnail(filename); err != nil {
+		t.Fatalf("InvalidateThumbnail failed: %v", err)
+	}
+	for _, path := range []string{scaledPath, webpPath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s removed on invalidation", filepath.Base(path))
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"path/filepath"
+	"strings"
+
+	"media-viewer/internal/database"
+	"media-viewer/internal/logging"
+
+	"github.com/disintegration/imaging"
+)
+
+// SensitiveStyle is how the thumbnails of files flagged as sensitive are
+// obscured.
+type SensitiveStyle string
+
+// Sensitive thumbnail styles
+const (
+	SensitiveBlur     SensitiveStyle = "blur"     // Heavy Gaussian blur
+	SensitivePixelate SensitiveStyle = "pixelate" // Large square blocks
+)
+
+const (
+	// sensitiveBlurDivisor sets the blur radius as a fraction of the
+	// thumbnail's longest edge, so sized variants are blurred alike
+	sensitiveBlurDivisor = 16
+
+	// sensitivePixelBlocks is the number of blocks across the longest edge
+	sensitivePixelBlocks = 12
+)
+
+// ParseSensitiveStyle parses a THUMBNAIL_SENSITIVE value: "blur" (or empty)
+// or "pixelate".
+func ParseSensitiveStyle(value string) (SensitiveStyle, error) {
+	switch style := strings.TrimSpace(strings.ToLower(value)); style {
+	case "", string(SensitiveBlur):
+		return SensitiveBlur, nil
+	case string(SensitivePixelate):
+		return SensitivePixelate, nil
+	default:
+		return "", fmt.Errorf("unknown sensitive thumbnail style %q (use blur or pixelate)", value)
+	}
+}
+
+// SetSensitiveStyle sets how GetObscuredThumbnail obscures thumbnails.
+// Obscured thumbnails cached with the other style are kept until the
+// thumbnail is regenerated, but no longer served.
+func (t *ThumbnailGenerator) SetSensitiveStyle(style SensitiveStyle) {
+	t.sensitiveStyle.Store(&style)
+}
+
+// currentSensitiveStyle returns the style set with SetSensitiveStyle
+func (t *ThumbnailGenerator) currentSensitiveStyle() SensitiveStyle {
+	if style := t.sensitiveStyle.Load(); style != nil {
+		return *style
+	}
+	return SensitiveBlur
+}
+
+// getObscuredKey returns the cache filename of an obscured thumbnail, named
+// after the regular thumbnail with "@" and the style, like rendered variants
+func getObscuredKey(cacheKey string, style SensitiveStyle) string {
+	return getRenderedKey(cacheKey, string(style), ThumbnailFormatDefault)
+}
+
+// isObscuredSuffix reports whether a variant suffix names an obscured
+// thumbnail
+func isObscuredSuffix(suffix string) bool {
+	return suffix == string(SensitiveBlur) || suffix == string(SensitivePixelate)
+}
+
+// GetObscuredThumbnail returns the regular thumbnail of a file, blurred or
+// pixelated beyond recognition, for files flagged as sensitive. It is always
+// a JPEG, cached next to the thumbnail it was obscured from until that is
+// regenerated. Unlike other variants, failures return an error rather than
+// the clear thumbnail.
+func (t *ThumbnailGenerator) GetObscuredThumbnail(ctx context.Context, filePath string, fileType database.FileType) ([]byte, error) {
+	data, err := t.getThumbnail(ctx, filePath, fileType, t.staleWhileRevalidate.Load())
+	if err != nil {
+		return nil, err
+	}
+
+	style := t.currentSensitiveStyle()
+	produce := func() ([]byte, error) {
+		obscured, err := t.obscure(data, style)
+		if err != nil {
+			return nil, fmt.Errorf("failed to obscure thumbnail: %w", err)
+		}
+		return obscured, nil
+	}
+
+	cacheKey := t.getCacheKey(filePath, fileType)
+	baseTime, err := t.cachedThumbnailModTime(cacheKey)
+	if err != nil {
+		// Not cached (e.g. the write failed); obscure it without caching
+		return produce()
+	}
+	return t.cachedVariant(filepath.Join(t.cacheDir, getObscuredKey(cacheKey, style)), baseTime, produce)
+}
+
+// obscure obscures an encoded thumbnail with libvips if available, falling
+// back to a slower pure Go method, so a flagged file's thumbnail is never
+// served clear
+func (t *ThumbnailGenerator) obscure(data []byte, style SensitiveStyle) ([]byte, error) {
+	if t.obscureThumbnail != nil {
+		return t.obscureThumbnail(data, style)
+	}
+	if IsVipsAvailable() {
+		obscured, err := obscureWithVips(data, style)
+		if err == nil {
+			return obscured, nil
+		}
+		logging.Debug("libvips obscuring failed: %v, falling back to standard method", err)
+	}
+	return obscureWithImaging(data, style)
+}
+
+// obscureWithImaging obscures an encoded thumbnail without libvips
+func obscureWithImaging(data []byte, style SensitiveStyle) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode thumbnail: %w", err)
+	}
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	longest := max(width, height)
+
+	var obscured image.Image
+	switch style {
+	case SensitivePixelate:
+		blocks := imaging.Resize(img, max(width*sensitivePixelBlocks/longest, 1), max(height*sensitivePixelBlocks/longest, 1), imaging.Box)
+		obscured = imaging.Resize(blocks, width, height, imaging.NearestNeighbor)
+	default:
+		obscured = imaging.Blur(img, float64(longest)/sensitiveBlurDivisor)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, obscured, &jpeg.Options{Quality: 75}); err != nil {
+		return nil, fmt.Errorf("failed to encode obscured thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
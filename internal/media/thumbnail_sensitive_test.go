@@ -0,0 +1,121 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestParseSensitiveStyle(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected SensitiveStyle
+		wantErr  bool
+	}{
+		{"", SensitiveBlur, false},
+		{"blur", SensitiveBlur, false},
+		{" Pixelate ", SensitivePixelate, false},
+		{"hide", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSensitiveStyle(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.expected {
+			t.Errorf("ParseSensitiveStyle(%q) = %q, %v; want %q (error: %v)", tt.value, got, err, tt.expected, tt.wantErr)
+		}
+	}
+}
+
+func TestScaledVariantBaseObscured(t *testing.T) {
+	for _, name := range []string{"abc123@blur.jpg", "abc123@pixelate.jpg"} {
+		if base, ok := scaledVariantBase(name); !ok || base != "abc123.jpg" {
+			t.Errorf("scaledVariantBase(%q) = %q, %v; want abc123.jpg", name, base, ok)
+		}
+	}
+}
+
+func TestObscureWithImaging(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "photo.jpg")
+	createTestImageFile(t, filename, 300, 200, "jpeg", 85)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, style := range []SensitiveStyle{SensitiveBlur, SensitivePixelate} {
+		obscured, err := obscureWithImaging(data, style)
+		if err != nil {
+			t.Fatalf("obscureWithImaging(%s) failed: %v", style, err)
+		}
+		img, format, err := image.Decode(bytes.NewReader(obscured))
+		if err != nil {
+			t.Fatalf("failed to decode %s thumbnail: %v", style, err)
+		}
+		if format != "jpeg" || img.Bounds().Dx() != 300 || img.Bounds().Dy() != 200 {
+			t.Errorf("%s thumbnail is a %dx%d %s, want a 300x200 jpeg", style, img.Bounds().Dx(), img.Bounds().Dy(), format)
+		}
+	}
+}
+
+func TestGetObscuredThumbnail(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, nil, time.Hour, nil)
+
+	obscures := 0
+	gen.obscureThumbnail = func(data []byte, style SensitiveStyle) ([]byte, error) {
+		obscures++
+		return append([]byte(string(style)+":"), data...), nil
+	}
+
+	filename := filepath.Join(mediaDir, "photo.jpg")
+	createTestImageFile(t, filename, 300, 200, "jpeg", 85)
+
+	ctx := context.Background()
+	obscured, err := gen.GetObscuredThumbnail(ctx, filename, database.FileTypeImage)
+	if err != nil {
+		t.Fatalf("GetObscuredThumbnail failed: %v", err)
+	}
+	base, err := gen.GetThumbnail(ctx, filename, database.FileTypeImage)
+	if err != nil {
+		t.Fatalf("GetThumbnail failed: %v", err)
+	}
+	if !bytes.Equal(obscured, append([]byte("blur:"), base...)) {
+		t.Error("Expected the blurred regular thumbnail")
+	}
+
+	// The obscured thumbnail is cached
+	if _, err := gen.GetObscuredThumbnail(ctx, filename, database.FileTypeImage); err != nil || obscures != 1 {
+		t.Errorf("Expected the cached obscured thumbnail, got %d obscures (error: %v)", obscures, err)
+	}
+	cacheKey := gen.getCacheKey(filename, database.FileTypeImage)
+	if _, err := os.Stat(filepath.Join(cacheDir, getObscuredKey(cacheKey, SensitiveBlur))); err != nil {
+		t.Errorf("Expected the obscured thumbnail in the cache: %v", err)
+	}
+
+	// Each style is cached separately
+	gen.SetSensitiveStyle(SensitivePixelate)
+	obscured, err = gen.GetObscuredThumbnail(ctx, filename, database.FileTypeImage)
+	if err != nil || !bytes.HasPrefix(obscured, []byte("pixelate:")) || obscures != 2 {
+		t.Errorf("Expected a pixelated thumbnail, got %d obscures (error: %v)", obscures, err)
+	}
+
+	// A newer thumbnail is obscured again
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(cacheDir, cacheKey), future, future); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.GetObscuredThumbnail(ctx, filename, database.FileTypeImage); err != nil || obscures != 3 {
+		t.Errorf("Expected the thumbnail to be obscured again after it changed, got %d obscures (error: %v)", obscures, err)
+	}
+}
@@ -0,0 +1,64 @@
+package media
+
+import "fmt"
+
+const (
+	// DefaultThumbnailSize is the longest edge of image and video thumbnails
+	// unless changed with SetThumbnailSize
+	DefaultThumbnailSize = 200
+
+	// MinThumbnailSize and MaxThumbnailSize bound SetThumbnailSize
+	MinThumbnailSize = 128
+	MaxThumbnailSize = 4096
+)
+
+// ClampThumbnailSize limits a thumbnail size to MinThumbnailSize through
+// MaxThumbnailSize.
+func ClampThumbnailSize(size int) int {
+	return min(max(size, MinThumbnailSize), MaxThumbnailSize)
+}
+
+// SetThumbnailSize sets the longest edge of image and video thumbnails,
+// clamped with ClampThumbnailSize. Folder composites are drawn for
+// DefaultThumbnailSize and only shrink to smaller sizes. The size is part of
+// every cache key, so thumbnails cached for another size are never served;
+// orphan cleanup removes them. Call it before the generator is used.
+func (t *ThumbnailGenerator) SetThumbnailSize(size int) {
+	t.size.Store(int32(ClampThumbnailSize(size)))
+}
+
+// thumbnailSize returns the size set with SetThumbnailSize
+func (t *ThumbnailGenerator) thumbnailSize() int {
+	if size := t.size.Load(); size > 0 {
+		return int(size)
+	}
+	return DefaultThumbnailSize
+}
+
+// folderThumbnailSize returns the size folder composites are scaled to
+func (t *ThumbnailGenerator) folderThumbnailSize() int {
+	return min(t.thumbnailSize(), folderThumbSize)
+}
+
+// decodeLimits returns the largest dimension and pixel count images are
+// decoded at. Thumbnails larger than MaxImageDimension raise both, so they
+// aren't upscaled from a smaller decode.
+func (t *ThumbnailGenerator) decodeLimits() (maxDimension, maxPixels int) {
+	maxDimension = max(MaxImageDimension, t.thumbnailSize())
+	return maxDimension, max(MaxImagePixels, maxDimension*maxDimension)
+}
+
+// sizeKeySuffix returns what cache keys of thumbnails for size end in, which
+// is nothing for DefaultThumbnailSize so existing caches stay valid
+func sizeKeySuffix(size int) string {
+	if size == DefaultThumbnailSize {
+		return ""
+	}
+	return fmt.Sprintf("-s%d", size)
+}
+
+// hasOtherSize reports whether the thumbnail of a .meta file was generated
+// for a different size than new thumbnails are
+func (t *ThumbnailGenerator) hasOtherSize(meta metaFile) bool {
+	return meta.Size != t.thumbnailSize()
+}
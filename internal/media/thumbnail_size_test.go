@@ -0,0 +1,107 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestClampThumbnailSize(t *testing.T) {
+	tests := map[int]int{
+		0:     MinThumbnailSize,
+		64:    MinThumbnailSize,
+		200:   200,
+		1600:  1600,
+		10000: MaxThumbnailSize,
+	}
+	for size, want := range tests {
+		if got := ClampThumbnailSize(size); got != want {
+			t.Errorf("ClampThumbnailSize(%d) = %d, want %d", size, got, want)
+		}
+	}
+}
+
+func TestThumbnailSizeCacheKey(t *testing.T) {
+	gen := NewThumbnailGenerator(t.TempDir(), t.TempDir(), false, nil, time.Hour, nil)
+
+	defaultKey := gen.getCacheKey("/media/photo.jpg", database.FileTypeImage)
+	if strings.Contains(defaultKey, "-s") {
+		t.Errorf("Expected the default size to keep the plain cache key, got %q", defaultKey)
+	}
+
+	gen.SetThumbnailSize(320)
+	sizedKey := gen.getCacheKey("/media/photo.jpg", database.FileTypeImage)
+	if sizedKey != strings.TrimSuffix(defaultKey, ".jpg")+"-s320.jpg" {
+		t.Errorf("Expected the size in the cache key, got %q", sizedKey)
+	}
+	if folderKey := gen.getCacheKey("/media/album", database.FileTypeFolder); !strings.HasSuffix(folderKey, "-s320.png") {
+		t.Errorf("Expected the size in the folder cache key, got %q", folderKey)
+	}
+	if isVariantFile(sizedKey) {
+		t.Errorf("Expected %q not to be taken for a variant", sizedKey)
+	}
+}
+
+func TestThumbnailSizeIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+
+	db, _, err := database.New(context.Background(), filepath.Join(t.TempDir(), "size_test.db"), &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	filename := filepath.Join(mediaDir, "photo.jpg")
+	createTestImageFile(t, filename, 1200, 800, "jpeg", 85)
+	upsertTestFile(ctx, t, db, database.MediaFile{Path: "photo.jpg", Name: "photo.jpg", ParentPath: ".", Type: database.FileTypeImage})
+
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, db, time.Hour, nil)
+	gen.SetThumbnailSize(320)
+
+	data, err := gen.GetThumbnail(ctx, filename, database.FileTypeImage)
+	if err != nil {
+		t.Fatalf("GetThumbnail failed: %v", err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to decode thumbnail: %v", err)
+	}
+	if cfg.Width != 320 {
+		t.Errorf("Expected a 320px thumbnail, got %d", cfg.Width)
+	}
+
+	sizedKey := gen.getCacheKey(filename, database.FileTypeImage)
+	if meta, err := gen.readMetaFile(sizedKey); err != nil || gen.hasOtherSize(meta) {
+		t.Errorf("Expected the thumbnail to record the size it was generated for, got %+v (error: %v)", meta, err)
+	}
+
+	// Going back to the default size leaves the 320px thumbnail unused;
+	// cleanup removes it although its source is still indexed
+	gen.SetThumbnailSize(DefaultThumbnailSize)
+	if _, err := os.Stat(filepath.Join(cacheDir, gen.getCacheKey(filename, database.FileTypeImage))); !os.IsNotExist(err) {
+		t.Fatal("Expected no thumbnail cached for the default size yet")
+	}
+
+	if orphansRemoved, _ := gen.cleanupOrphanedThumbnails(ctx); orphansRemoved != 1 {
+		t.Errorf("Expected the thumbnail of the other size to be removed, got %d", orphansRemoved)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, sizedKey)); !os.IsNotExist(err) {
+		t.Error("Expected the 320px thumbnail to be removed")
+	}
+	if _, err := os.Stat(gen.getMetaPath(sizedKey)); !os.IsNotExist(err) {
+		t.Error("Expected the 320px thumbnail's .meta file to be removed")
+	}
+}
@@ -0,0 +1,162 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"media-viewer/internal/database"
+)
+
+// ParseThumbnailSizes parses a comma-separated list of thumbnail sizes such
+// as "256,512,1024". Each must be within MinThumbnailSize and
+// MaxThumbnailSize. The result is sorted and deduplicated; an empty value
+// returns nil.
+func ParseThumbnailSizes(value string) ([]int, error) {
+	var sizes []int
+	for part := range strings.SplitSeq(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		size, err := strconv.Atoi(part)
+		if err != nil || size < MinThumbnailSize || size > MaxThumbnailSize {
+			return nil, fmt.Errorf("invalid size %q (use %d-%d)", part, MinThumbnailSize, MaxThumbnailSize)
+		}
+		sizes = append(sizes, size)
+	}
+	slices.Sort(sizes)
+	return slices.Compact(sizes), nil
+}
+
+// ParseRequestedSize parses a ?size= thumbnail request parameter, the
+// longest edge the client wants in pixels. An empty value is 0, the regular
+// thumbnail.
+func ParseRequestedSize(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	size, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid thumbnail size %q", value)
+	}
+	return size, nil
+}
+
+// SetVariantSizes sets the sizes, besides the regular one, that thumbnails
+// are rendered at on request with GetSizedThumbnail. Requested sizes are
+// rounded up to one of them, which bounds how many variants each file can
+// have. Variants are never generated in the background. An empty list serves
+// every request the regular thumbnail.
+func (t *ThumbnailGenerator) SetVariantSizes(sizes []int) {
+	if len(sizes) == 0 {
+		t.variantSizes.Store(nil)
+		return
+	}
+	sorted := slices.Clone(sizes)
+	slices.Sort(sorted)
+	t.variantSizes.Store(&sorted)
+}
+
+// VariantSizes returns every size thumbnails can be served at, smallest
+// first: the regular size and those set with SetVariantSizes that are larger.
+func (t *ThumbnailGenerator) VariantSizes() []int {
+	regular := t.thumbnailSize()
+	sizes := []int{regular}
+	if variants := t.variantSizes.Load(); variants != nil {
+		for _, size := range *variants {
+			if size > regular {
+				sizes = append(sizes, size)
+			}
+		}
+	}
+	return sizes
+}
+
+// variantSizeFor returns the variant size a requested size is served at: the
+// smallest that is at least as large, or the largest. Returns 0 if the
+// regular thumbnail serves the request.
+func (t *ThumbnailGenerator) variantSizeFor(requested int) int {
+	sizes := t.VariantSizes()
+	size := sizes[len(sizes)-1]
+	for _, candidate := range sizes {
+		if candidate >= requested {
+			size = candidate
+			break
+		}
+	}
+	if size == sizes[0] {
+		return 0
+	}
+	return size
+}
+
+// getSizedKey returns the cache filename of a thumbnail rendered at a size,
+// in a format. Sized variants are tracked like scaled ones.
+func getSizedKey(cacheKey string, size int, format ThumbnailFormat) string {
+	return getRenderedKey(cacheKey, fmt.Sprintf("%dpx", size), format)
+}
+
+// GetSizedThumbnail returns the thumbnail of a file at the variant size the
+// requested size rounds up to (see SetVariantSizes), in the requested format
+// if possible. Like scaled thumbnails, sized ones are rendered from the
+// source on first request and cached until the regular thumbnail is
+// regenerated; folders and other files get the regular thumbnail. The
+// returned format is the one actually used.
+func (t *ThumbnailGenerator) GetSizedThumbnail(ctx context.Context, filePath string, fileType database.FileType, format ThumbnailFormat, size int) ([]byte, ThumbnailFormat, error) {
+	return t.getSizedThumbnail(ctx, filePath, fileType, format, size, t.staleWhileRevalidate.Load())
+}
+
+// getSizedThumbnail implements GetSizedThumbnail; allowStale is passed to
+// getThumbnail.
+func (t *ThumbnailGenerator) getSizedThumbnail(ctx context.Context, filePath string, fileType database.FileType, format ThumbnailFormat, size int, allowStale bool) ([]byte, ThumbnailFormat, error) {
+	size = t.variantSizeFor(size)
+	if size == 0 || (fileType != database.FileTypeImage && fileType != database.FileTypeVideo) {
+		return t.getThumbnailInFormat(ctx, filePath, fileType, format, allowStale)
+	}
+
+	// Corners and borders grow with the size, by whole multiples like scales
+	regular := t.thumbnailSize()
+	styleFactor := max((size+regular/2)/regular, 1)
+	return t.getRenderedVariant(ctx, filePath, fileType, format, renderedVariant{
+		suffix: fmt.Sprintf("%dpx", size),
+		size:   size,
+		style:  t.currentStyle().scaled(styleFactor),
+	}, allowStale)
+}
+
+// GetThumbnailSizes returns the sizes of the thumbnails of a file that are
+// currently cached in the default format, smallest first: the regular size,
+// scaled variants and sized variants. Variants older than the regular
+// thumbnail are left out, as they are rendered again when requested.
+func (t *ThumbnailGenerator) GetThumbnailSizes(filePath string, fileType database.FileType) []int {
+	cacheKey := t.getCacheKey(filePath, fileType)
+	baseTime, err := t.cachedThumbnailModTime(cacheKey)
+	if err != nil {
+		return []int{}
+	}
+
+	regular := t.thumbnailSize()
+	sizes := []int{regular}
+	cached := func(key string) bool {
+		info, err := os.Stat(filepath.Join(t.cacheDir, key))
+		return err == nil && !info.ModTime().Before(baseTime)
+	}
+	for scale := 2; scale <= maxThumbnailScale; scale++ {
+		if cached(getScaledKey(cacheKey, scale, ThumbnailFormatDefault)) {
+			sizes = append(sizes, regular*scale)
+		}
+	}
+	for _, size := range t.VariantSizes()[1:] {
+		if cached(getSizedKey(cacheKey, size, ThumbnailFormatDefault)) {
+			sizes = append(sizes, size)
+		}
+	}
+
+	slices.Sort(sizes)
+	return slices.Compact(sizes)
+}
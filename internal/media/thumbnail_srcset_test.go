@@ -0,0 +1,113 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestParseThumbnailSizes(t *testing.T) {
+	sizes, err := ParseThumbnailSizes(" 1024, 256,512,256 ")
+	if err != nil || fmt.Sprint(sizes) != "[256 512 1024]" {
+		t.Errorf("ParseThumbnailSizes = %v, %v; want [256 512 1024]", sizes, err)
+	}
+	if sizes, err := ParseThumbnailSizes(""); err != nil || sizes != nil {
+		t.Errorf("ParseThumbnailSizes of an empty value = %v, %v; want nil", sizes, err)
+	}
+	for _, value := range []string{"64", "8192", "big", "256,-1"} {
+		if _, err := ParseThumbnailSizes(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+
+	if size, err := ParseRequestedSize(""); err != nil || size != 0 {
+		t.Errorf("ParseRequestedSize of an empty value = %d, %v; want 0", size, err)
+	}
+	if size, err := ParseRequestedSize(" 300 "); err != nil || size != 300 {
+		t.Errorf("ParseRequestedSize = %d, %v; want 300", size, err)
+	}
+	for _, value := range []string{"0", "-5", "large"} {
+		if _, err := ParseRequestedSize(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+func TestVariantSizeFor(t *testing.T) {
+	gen := NewThumbnailGenerator(t.TempDir(), t.TempDir(), true, nil, time.Hour, nil)
+
+	if got := gen.variantSizeFor(1000); got != 0 {
+		t.Errorf("Expected the regular thumbnail without variant sizes, got %d", got)
+	}
+
+	// Sizes up to the regular size are dropped
+	gen.SetVariantSizes([]int{1024, 150, 512})
+	if got := fmt.Sprint(gen.VariantSizes()); got != "[200 512 1024]" {
+		t.Errorf("VariantSizes = %s, want [200 512 1024]", got)
+	}
+
+	tests := map[int]int{100: 0, 200: 0, 201: 512, 512: 512, 600: 1024, 5000: 1024}
+	for requested, want := range tests {
+		if got := gen.variantSizeFor(requested); got != want {
+			t.Errorf("variantSizeFor(%d) = %d, want %d", requested, got, want)
+		}
+	}
+}
+
+func TestGetSizedThumbnail(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, nil, time.Hour, nil)
+	gen.SetVariantSizes([]int{512, 1024})
+	ctx := context.Background()
+
+	filename := filepath.Join(mediaDir, "photo.jpg")
+	createTestImageFile(t, filename, 1200, 800, "jpeg", 85)
+
+	if sizes := gen.GetThumbnailSizes(filename, database.FileTypeImage); len(sizes) != 0 {
+		t.Errorf("Expected no cached sizes before generation, got %v", sizes)
+	}
+
+	sized, format, err := gen.GetSizedThumbnail(ctx, filename, database.FileTypeImage, ThumbnailFormatDefault, 300)
+	if err != nil || format != ThumbnailFormatDefault {
+		t.Fatalf("GetSizedThumbnail = %q, %v", format, err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(sized))
+	if err != nil {
+		t.Fatalf("Failed to decode thumbnail: %v", err)
+	}
+	if cfg.Width != 512 {
+		t.Errorf("Expected 300px to round up to 512, got %d", cfg.Width)
+	}
+
+	if _, _, err := gen.GetScaledThumbnail(ctx, filename, database.FileTypeImage, ThumbnailFormatDefault, 2); err != nil {
+		t.Fatalf("GetScaledThumbnail failed: %v", err)
+	}
+	if got := fmt.Sprint(gen.GetThumbnailSizes(filename, database.FileTypeImage)); got != "[200 400 512]" {
+		t.Errorf("GetThumbnailSizes = %s, want [200 400 512]", got)
+	}
+
+	// Sized variants are tracked by the regular thumbnail
+	cacheKey := gen.getCacheKey(filename, database.FileTypeImage)
+	sizedPath := filepath.Join(cacheDir, getSizedKey(cacheKey, 512, ThumbnailFormatDefault))
+	if removed := gen.cleanupOrphanedVariants(); removed != 0 {
+		t.Errorf("Expected tracked sized variants kept, %d removed", removed)
+	}
+	if err := gen.InvalidateThumbnail(filename); err != nil {
+		t.Fatalf("InvalidateThumbnail failed: %v", err)
+	}
+	if _, err := os.Stat(sizedPath); !os.IsNotExist(err) {
+		t.Error("Expected invalidation to remove the sized variant")
+	}
+}
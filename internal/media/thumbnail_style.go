@@ -0,0 +1,197 @@
+package media
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"media-viewer/internal/logging"
+)
+
+const (
+	// maxStyleCornerRadius and maxStyleBorderWidth bound the style for 200px thumbnails
+	maxStyleCornerRadius = 100
+	maxStyleBorderWidth  = 20
+)
+
+// ThumbnailStyle is decoration baked into cached image and video thumbnails:
+// rounded corners and a border. JPEG has no transparency, so the cut-off
+// corners are filled with a background color matching the gallery.
+type ThumbnailStyle struct {
+	CornerRadius int        // Corner radius in pixels; 0 keeps square corners
+	BorderWidth  int        // Border width in pixels; 0 draws no border
+	BorderColor  color.RGBA // Border color
+	Background   color.RGBA // Fill for the area outside the rounded corners
+}
+
+// DefaultThumbnailStyle returns the colors used for keys a THUMBNAIL_STYLE
+// value leaves out, taken from the gallery's stylesheet. It draws nothing
+// until a radius or border width is set.
+func DefaultThumbnailStyle() ThumbnailStyle {
+	return ThumbnailStyle{
+		BorderColor: color.RGBA{R: 0x0f, G: 0x34, B: 0x60, A: 255},
+		Background:  color.RGBA{R: 0x16, G: 0x21, B: 0x3e, A: 255},
+	}
+}
+
+// ParseThumbnailStyle parses a THUMBNAIL_STYLE value: comma-separated
+// key=value pairs from "radius", "border" (pixels), "border-color" and
+// "background" (hex colors), e.g. "radius=12,border=1". An empty value or
+// "none" returns the zero style, which leaves thumbnails undecorated.
+func ParseThumbnailStyle(value string) (ThumbnailStyle, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if value == "" || value == "none" {
+		return ThumbnailStyle{}, nil
+	}
+
+	style := DefaultThumbnailStyle()
+	for part := range strings.SplitSeq(value, ",") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return ThumbnailStyle{}, fmt.Errorf("invalid thumbnail style %q (expected key=value)", part)
+		}
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+
+		var err error
+		switch key {
+		case "radius":
+			style.CornerRadius, err = parseStylePixels(key, val, maxStyleCornerRadius)
+		case "border":
+			style.BorderWidth, err = parseStylePixels(key, val, maxStyleBorderWidth)
+		case "border-color":
+			style.BorderColor, err = ParseHexColor(val)
+		case "background":
+			style.Background, err = ParseHexColor(val)
+		default:
+			err = fmt.Errorf("unknown thumbnail style key %q (use radius, border, border-color or background)", key)
+		}
+		if err != nil {
+			return ThumbnailStyle{}, err
+		}
+	}
+	return style, nil
+}
+
+// parseStylePixels parses a pixel size between 0 and limit
+func parseStylePixels(key, value string, limit int) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSuffix(value, "px"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid thumbnail style %s %q: %w", key, value, err)
+	}
+	if n < 0 || n > limit {
+		return 0, fmt.Errorf("thumbnail style %s %q must be between 0 and %d", key, value, limit)
+	}
+	return n, nil
+}
+
+// IsZero reports whether the style leaves thumbnails undecorated
+func (s ThumbnailStyle) IsZero() bool {
+	return s.CornerRadius == 0 && s.BorderWidth == 0
+}
+
+// scaled returns the style for a thumbnail factor times the regular size,
+// so corners and borders keep their proportions on high-DPI displays
+func (s ThumbnailStyle) scaled(factor int) ThumbnailStyle {
+	s.CornerRadius *= factor
+	s.BorderWidth *= factor
+	return s
+}
+
+// String returns the style in the form ParseThumbnailStyle accepts, with every
+// key set, or an empty string for the zero style. It is also the form recorded
+// in .meta files.
+func (s ThumbnailStyle) String() string {
+	if s.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("radius=%d,border=%d,border-color=%s,background=%s",
+		s.CornerRadius, s.BorderWidth, FormatHexColor(s.BorderColor), FormatHexColor(s.Background))
+}
+
+// contentKeySuffix distinguishes shared thumbnails rendered with this style
+// from those of the same content rendered with another, so a style change
+// never reuses an outdated shared thumbnail
+func (s ThumbnailStyle) contentKeySuffix() string {
+	if s.IsZero() {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(s.String()))
+	return "-" + hex.EncodeToString(sum[:4])
+}
+
+// maskSVG renders a white rounded rectangle covering a w x h thumbnail, used
+// to cut the corners off
+func (s ThumbnailStyle) maskSVG(w, h int) []byte {
+	return fmt.Appendf(nil, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`+
+		`<rect width="%d" height="%d" rx="%d" fill="#fff"/></svg>`,
+		w, h, w, h, s.CornerRadius)
+}
+
+// borderSVG renders the border of a w x h thumbnail. The stroke is centered
+// on the rectangle's edge, so it is inset by half its width to stay inside
+// the thumbnail and follow the rounded corners.
+func (s ThumbnailStyle) borderSVG(w, h int) []byte {
+	inset := float64(s.BorderWidth) / 2
+	radius := max(float64(s.CornerRadius)-inset, 0)
+	return fmt.Appendf(nil, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`+
+		`<rect x="%g" y="%g" width="%g" height="%g" rx="%g" fill="none" stroke="%s" stroke-width="%d"/></svg>`,
+		w, h, inset, inset, float64(w)-2*inset, float64(h)-2*inset, radius, FormatHexColor(s.BorderColor), s.BorderWidth)
+}
+
+// SetThumbnailStyle sets the style applied to newly generated image and video
+// thumbnails. Cached thumbnails rendered with another style are regenerated
+// like stale ones. Styling needs libvips; without it thumbnails stay plain.
+func (t *ThumbnailGenerator) SetThumbnailStyle(style ThumbnailStyle) {
+	t.style.Store(&style)
+	t.styleFailed.Store(false)
+}
+
+// styler returns the function that applies a style, or nil if styling is
+// unavailable
+func (t *ThumbnailGenerator) styler() func(image.Image, ThumbnailStyle) (image.Image, error) {
+	if t.styleThumbnail != nil {
+		return t.styleThumbnail
+	}
+	if !IsVipsAvailable() {
+		return nil
+	}
+	return styleThumbnailWithVips
+}
+
+// currentStyle returns the style new image and video thumbnails are rendered
+// with: the configured one, or the zero style if none is set or styling is
+// unavailable or has failed
+func (t *ThumbnailGenerator) currentStyle() ThumbnailStyle {
+	style := t.style.Load()
+	if style == nil || style.IsZero() || t.styleFailed.Load() || t.styler() == nil {
+		return ThumbnailStyle{}
+	}
+	return *style
+}
+
+// applyStyle decorates a resized thumbnail. If styling fails the thumbnail is
+// returned plain with the zero style, and styling is turned off until the
+// style is set again, so it is not retried for every thumbnail.
+func (t *ThumbnailGenerator) applyStyle(thumb image.Image, style ThumbnailStyle) (image.Image, ThumbnailStyle) {
+	if style.IsZero() {
+		return thumb, style
+	}
+	styled, err := t.styler()(thumb, style)
+	if err != nil {
+		if !t.styleFailed.Swap(true) {
+			logging.Warn("Failed to apply thumbnail style, generating plain thumbnails instead: %v", err)
+		}
+		return thumb, ThumbnailStyle{}
+	}
+	return styled, style
+}
+
+// hasOutdatedStyle reports whether the thumbnail of a .meta file was
+// rendered with a different style than new thumbnails get
+func (t *ThumbnailGenerator) hasOutdatedStyle(meta metaFile) bool {
+	return meta.Style != t.currentStyle().String()
+}
@@ -0,0 +1,246 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestParseThumbnailStyle(t *testing.T) {
+	defaults := DefaultThumbnailStyle()
+
+	tests := []struct {
+		value    string
+		expected ThumbnailStyle
+		wantErr  bool
+	}{
+		{"", ThumbnailStyle{}, false},
+		{"none", ThumbnailStyle{}, false},
+		{"radius=12", ThumbnailStyle{CornerRadius: 12, BorderColor: defaults.BorderColor, Background: defaults.Background}, false},
+		{"radius=8, border=2px", ThumbnailStyle{CornerRadius: 8, BorderWidth: 2, BorderColor: defaults.BorderColor, Background: defaults.Background}, false},
+		{
+			"border=1,border-color=#FFF,background=000000",
+			ThumbnailStyle{BorderWidth: 1, BorderColor: color.RGBA{R: 255, G: 255, B: 255, A: 255}, Background: color.RGBA{A: 255}},
+			false,
+		},
+		{"radius", ThumbnailStyle{}, true},
+		{"radius=-1", ThumbnailStyle{}, true},
+		{"radius=101", ThumbnailStyle{}, true},
+		{"border=21", ThumbnailStyle{}, true},
+		{"border-color=red", ThumbnailStyle{}, true},
+		{"shadow=2", ThumbnailStyle{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseThumbnailStyle(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseThumbnailStyle(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseThumbnailStyle(%q) = %+v, want %+v", tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestThumbnailStyleString(t *testing.T) {
+	if s := (ThumbnailStyle{}).String(); s != "" {
+		t.Errorf("Expected empty string for the zero style, got %q", s)
+	}
+
+	style, err := ParseThumbnailStyle("radius=12,border=1")
+	if err != nil {
+		t.Fatalf("ParseThumbnailStyle failed: %v", err)
+	}
+	want := "radius=12,border=1,border-color=#0f3460,background=#16213e"
+	if got := style.String(); got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+
+	reparsed, err := ParseThumbnailStyle(style.String())
+	if err != nil || reparsed != style {
+		t.Errorf("Expected String() to round-trip, got %+v, %v", reparsed, err)
+	}
+
+	if suffix := style.contentKeySuffix(); suffix == "" || strings.ContainsAny(suffix, "/=,#") {
+		t.Errorf("Expected a filename-safe content key suffix, got %q", suffix)
+	}
+	if (ThumbnailStyle{}).contentKeySuffix() != "" {
+		t.Error("Expected no content key suffix for the zero style")
+	}
+}
+
+func TestThumbnailStyleSVG(t *testing.T) {
+	style := ThumbnailStyle{CornerRadius: 10, BorderWidth: 2, BorderColor: color.RGBA{R: 255, A: 255}}
+
+	mask := string(style.maskSVG(200, 150))
+	if !strings.Contains(mask, `width="200" height="150" rx="10"`) {
+		t.Errorf("Expected mask to cover the thumbnail with rounded corners, got %s", mask)
+	}
+
+	border := string(style.borderSVG(200, 150))
+	for _, want := range []string{`x="1" y="1" width="198" height="148" rx="9"`, `stroke="#ff0000"`, `stroke-width="2"`} {
+		if !strings.Contains(border, want) {
+			t.Errorf("Expected border SVG to contain %s, got %s", want, border)
+		}
+	}
+}
+
+func TestCurrentStyle(t *testing.T) {
+	gen := &ThumbnailGenerator{}
+	if !gen.currentStyle().IsZero() {
+		t.Error("Expected no style by default")
+	}
+
+	style := ThumbnailStyle{CornerRadius: 8}
+	gen.styleThumbnail = func(img image.Image, _ ThumbnailStyle) (image.Image, error) { return img, nil }
+	gen.SetThumbnailStyle(style)
+	if got := gen.currentStyle(); got != style {
+		t.Errorf("currentStyle() = %+v, want %+v", got, style)
+	}
+
+	gen.styleFailed.Store(true)
+	if !gen.currentStyle().IsZero() {
+		t.Error("Expected no style after styling failed")
+	}
+
+	// Setting the style again retries it
+	gen.SetThumbnailStyle(style)
+	if got := gen.currentStyle(); got != style {
+		t.Errorf("Expected style to be retried after being set again, got %+v", got)
+	}
+}
+
+func TestGetThumbnailStyled(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cacheDir := t.TempDir()
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(cacheDir, mediaDir, true, nil, time.Hour, nil)
+	ctx := context.Background()
+
+	var styled []ThumbnailStyle
+	gen.styleThumbnail = func(img image.Image, style ThumbnailStyle) (image.Image, error) {
+		styled = append(styled, style)
+		return img, nil
+	}
+
+	filename := filepath.Join(mediaDir, "photo.jpg")
+	createTestImageFile(t, filename, 300, 200, "jpeg", 85)
+	cacheKey := gen.getCacheKey(filename, database.FileTypeImage)
+
+	style := ThumbnailStyle{CornerRadius: 12, BorderWidth: 1}
+	gen.SetThumbnailStyle(style)
+
+	for range 2 {
+		if _, err := gen.GetThumbnail(ctx, filename, database.FileTypeImage); err != nil {
+			t.Fatalf("GetThumbnail failed: %v", err)
+		}
+	}
+	if len(styled) != 1 || styled[0] != style {
+		t.Fatalf("Expected the thumbnail to be styled once and then cached, got %+v", styled)
+	}
+
+	meta, err := gen.readMetaFile(cacheKey)
+	if err != nil {
+		t.Fatalf("Failed to read meta file: %v", err)
+	}
+	if meta.Style != style.String() {
+		t.Errorf("Expected style %q recorded in the meta file, got %q", style.String(), meta.Style)
+	}
+
+	// Changing the style regenerates the thumbnail
+	gen.SetThumbnailStyle(ThumbnailStyle{CornerRadius: 4})
+	if _, err := gen.GetThumbnail(ctx, filename, database.FileTypeImage); err != nil {
+		t.Fatalf("GetThumbnail failed: %v", err)
+	}
+	if len(styled) != 2 {
+		t.Fatalf("Expected a style change to regenerate the thumbnail, got %d stylings", len(styled))
+	}
+
+	// As does removing it, leaving a plain thumbnail
+	gen.SetThumbnailStyle(ThumbnailStyle{})
+	if _, err := gen.GetThumbnail(ctx, filename, database.FileTypeImage); err != nil {
+		t.Fatalf("GetThumbnail failed: %v", err)
+	}
+	if len(styled) != 2 {
+		t.Errorf("Expected no styling without a style, got %d stylings", len(styled))
+	}
+	if meta, err := os.ReadFile(gen.getMetaPath(cacheKey)); err != nil || strings.Contains(string(meta), metaStylePrefix) {
+		t.Errorf("Expected an unstyled meta file after removing the style, got %q, %v", meta, err)
+	}
+}
+
+func TestGetThumbnailStyleFailure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(t.TempDir(), mediaDir, true, nil, time.Hour, nil)
+	calls := 0
+	gen.styleThumbnail = func(image.Image, ThumbnailStyle) (image.Image, error) {
+		calls++
+		return nil, errors.New("no SVG loader")
+	}
+	gen.SetThumbnailStyle(ThumbnailStyle{CornerRadius: 12})
+
+	for _, name := range []string{"a.jpg", "b.jpg"} {
+		filename := filepath.Join(mediaDir, name)
+		createTestImageFile(t, filename, 300, 200, "jpeg", 85)
+
+		data, err := gen.GetThumbnail(context.Background(), filename, database.FileTypeImage)
+		if err != nil {
+			t.Fatalf("GetThumbnail failed: %v", err)
+		}
+		if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+			t.Errorf("Expected a plain JPEG thumbnail when styling fails")
+		}
+		if gen.isThumbnailStale(gen.getCacheKey(filename, database.FileTypeImage), time.Now().Add(-time.Hour)) {
+			t.Errorf("Expected the plain thumbnail not to be regenerated while styling is off")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected styling to stop after the first failure, got %d attempts", calls)
+	}
+}
+
+func TestStyleThumbnailWithVipsIfAvailable(t *testing.T) {
+	if !IsVipsAvailable() {
+		t.Skip("libvips not available")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 100, 80))
+	for y := range 80 {
+		for x := range 100 {
+			img.Set(x, y, color.RGBA{R: 200, G: 200, B: 200, A: 255})
+		}
+	}
+	style := ThumbnailStyle{CornerRadius: 20, Background: color.RGBA{B: 255, A: 255}}
+
+	styled, err := styleThumbnailWithVips(img, style)
+	if err != nil {
+		t.Fatalf("styleThumbnailWithVips failed: %v", err)
+	}
+	if b := styled.Bounds(); b.Dx() != 100 || b.Dy() != 80 {
+		t.Fatalf("Expected a 100x80 thumbnail, got %v", b)
+	}
+
+	if r, g, b, _ := styled.At(0, 0).RGBA(); r>>8 > 10 || g>>8 > 10 || b>>8 < 245 {
+		t.Errorf("Expected the corner filled with the background, got %v", styled.At(0, 0))
+	}
+	if r, _, _, _ := styled.At(50, 40).RGBA(); r>>8 < 190 {
+		t.Errorf("Expected the center unchanged, got %v", styled.At(50, 40))
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"image/jpeg"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
@@ -99,6 +100,26 @@ func TestNewThumbnailGenerator(t *testing.T) {
 	}
 }
 
+func TestSetGenerationInterval(t *testing.T) {
+	gen := NewThumbnailGenerator(t.TempDir(), t.TempDir(), false, nil, time.Hour, nil)
+
+	gen.SetGenerationInterval(-time.Minute)
+	if gen.getGenerationInterval() != time.Hour {
+		t.Errorf("Expected non-positive interval to be ignored, got %v", gen.getGenerationInterval())
+	}
+
+	gen.SetGenerationInterval(15 * time.Minute)
+	if gen.getGenerationInterval() != 15*time.Minute {
+		t.Errorf("generationInterval = %v, want 15m", gen.getGenerationInterval())
+	}
+
+	select {
+	case <-gen.intervalReset:
+	default:
+		t.Error("Expected interval reset to be signaled")
+	}
+}
+
 func TestIsEnabled(t *testing.T) {
 	tmpDir := t.TempDir()
 	mediaDir := t.TempDir()
@@ -250,7 +271,7 @@ func TestMetaFileOperations(t *testing.T) {
 	sourcePath := "/path/to/source/file.jpg"
 
 	// Write meta file
-	err := gen.writeMetaFile(cacheKey, sourcePath)
+	err := gen.writeMetaFile(cacheKey, sourcePath, "", ThumbnailStyle{}, ThumbnailDimensions{})
 	if err != nil {
 		t.Fatalf("writeMetaFile failed: %v", err)
 	}
@@ -262,13 +283,13 @@ func TestMetaFileOperations(t *testing.T) {
 	}
 
 	// Read meta file
-	readPath, err := gen.readMetaFile(cacheKey)
+	meta, err := gen.readMetaFile(cacheKey)
 	if err != nil {
 		t.Fatalf("readMetaFile failed: %v", err)
 	}
 
-	if readPath != sourcePath {
-		t.Errorf("readMetaFile returned %s, want %s", readPath, sourcePath)
+	if meta.SourcePath != sourcePath {
+		t.Errorf("readMetaFile returned %s, want %s", meta.SourcePath, sourcePath)
 	}
 
 	// Delete meta file
@@ -1472,6 +1493,7 @@ func TestDetectImageFormat(t *testing.T) {
 		{"TIF short", "/media/image.tif", "tiff"},
 		{"HEIC", "/media/image.heic", "heic"},
 		{"HEIF", "/media/image.heif", "heic"},
+		{"HEIC uppercase", "/media/IMG_0001.HEIC", "heic"},
 		{"AVIF", "/media/image.avif", "avif"},
 		{"SVG", "/media/image.svg", "svg"},
 		{"Unknown extension", "/media/file.xyz", "unknown"},
@@ -1493,6 +1515,37 @@ func TestDetectImageFormat(t *testing.T) {
 	}
 }
 
+func TestFFmpegImageArgs(t *testing.T) {
+	tests := []struct {
+		filePath  string
+		wantInput []string
+		pixFmt    string
+	}{
+		{"/media/photo.jpg", nil, "rgb24"},
+		{"/media/IMG_0001.HEIC", []string{"-f", "mov"}, "rgba"},
+		{"/media/image.heif", []string{"-f", "mov"}, "rgba"},
+		{"/media/image.avif", []string{"-f", "mov"}, "rgba"},
+		{"/media/anim.webp", nil, "rgba"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filePath, func(t *testing.T) {
+			args := ffmpegImageArgs(tt.filePath)
+
+			input := slices.Index(args, "-i")
+			if input < 0 || args[input+1] != tt.filePath {
+				t.Fatalf("expected -i %s in %v", tt.filePath, args)
+			}
+			if !slices.Equal(args[:input], tt.wantInput) {
+				t.Errorf("expected input options %v, got %v", tt.wantInput, args[:input])
+			}
+			if pixFmt := slices.Index(args, "-pix_fmt"); pixFmt < 0 || args[pixFmt+1] != tt.pixFmt {
+				t.Errorf("expected -pix_fmt %s in %v", tt.pixFmt, args)
+			}
+		})
+	}
+}
+
 // =============================================================================
 // formatBytes Tests
 // =============================================================================
@@ -1670,7 +1723,7 @@ func TestInvalidateThumbnail_RemovesCachedFileAndMeta(t *testing.T) {
 	if err := os.WriteFile(cachePath, []byte("thumb"), 0o644); err != nil {
 		t.Fatalf("Failed to create cache file: %v", err)
 	}
-	if err := gen.writeMetaFile(cacheKey, filePath); err != nil {
+	if err := gen.writeMetaFile(cacheKey, filePath, "", ThumbnailStyle{}, ThumbnailDimensions{}); err != nil {
 		t.Fatalf("Failed to write meta file: %v", err)
 	}
 
@@ -2074,6 +2127,99 @@ func TestStartStop_EnabledNilDB(t *testing.T) {
 	gen.Stop()
 }
 
+func TestStartStop_Cycle(t *testing.T) {
+	gen := NewThumbnailGenerator(t.TempDir(), t.TempDir(), true, nil, time.Hour, nil)
+
+	for i := range 3 {
+		gen.Start()
+		gen.Start() // No-op while running
+
+		stop := gen.stopSignal()
+		if isClosed(stop) {
+			t.Fatalf("Cycle %d: expected an open stop channel after Start", i)
+		}
+
+		gen.Stop()
+		gen.Stop() // No-op while stopped
+
+		if !isClosed(stop) {
+			t.Fatalf("Cycle %d: expected Stop to close the stop channel", i)
+		}
+	}
+}
+
+func TestStop_FinalizesGenerationStats(t *testing.T) {
+	gen := NewThumbnailGenerator(t.TempDir(), t.TempDir(), true, nil, time.Hour, nil)
+	gen.SetStopGracePeriod(5 * time.Second)
+
+	lastCompleted := time.Now().Add(-time.Hour)
+	gen.generationStats = GenerationStats{
+		InProgress:    true,
+		LastCompleted: lastCompleted,
+		TotalFiles:    10,
+		Processed:     4,
+		CurrentFile:   "photo.jpg",
+	}
+
+	// A run in progress that finishes its current file within the grace period
+	gen.isGenerating.Store(true)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		gen.isGenerating.Store(false)
+	}()
+
+	gen.Stop()
+
+	if gen.isGenerating.Load() {
+		t.Error("Expected Stop to wait for the run to finish")
+	}
+
+	stats := gen.GetStatus().Generation
+	if stats.InProgress || !stats.Interrupted || stats.CurrentFile != "" {
+		t.Errorf("Expected finalized, interrupted stats, got %+v", stats)
+	}
+	if stats.Processed != 4 || stats.TotalFiles != 10 {
+		t.Errorf("Expected the last-run summary to be kept, got %+v", stats)
+	}
+
+	gen.Start()
+	defer gen.Stop()
+
+	stats = gen.GetStatus().Generation
+	if stats.Processed != 0 || stats.TotalFiles != 0 || stats.Interrupted {
+		t.Errorf("Expected Start to reset the counters, got %+v", stats)
+	}
+	if !stats.LastCompleted.Equal(lastCompleted) {
+		t.Errorf("Expected LastCompleted %v to survive a restart, got %v", lastCompleted, stats.LastCompleted)
+	}
+}
+
+func TestStop_GracePeriodExpires(t *testing.T) {
+	gen := NewThumbnailGenerator(t.TempDir(), t.TempDir(), true, nil, time.Hour, nil)
+	gen.SetStopGracePeriod(20 * time.Millisecond)
+
+	gen.generationStats = GenerationStats{InProgress: true, Processed: 2, CurrentFile: "video.mp4"}
+	gen.isGenerating.Store(true)
+	defer gen.isGenerating.Store(false)
+
+	start := time.Now()
+	gen.Stop()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Stop to give up after the grace period, took %v", elapsed)
+	}
+
+	if stats := gen.GetStatus().Generation; stats.InProgress || !stats.Interrupted {
+		t.Errorf("Expected stats finalized despite the run still going, got %+v", stats)
+	}
+
+	// Counters of a run that is still going are left for it to finish
+	gen.Start()
+	defer gen.Stop()
+	if stats := gen.GetStatus().Generation; stats.Processed != 2 {
+		t.Errorf("Expected Start to leave a running run's stats alone, got %+v", stats)
+	}
+}
+
 // =============================================================================
 // Benchmarks for new functions
 // =============================================================================
@@ -0,0 +1,40 @@
+package media
+
+import (
+	"context"
+
+	"media-viewer/internal/database"
+)
+
+// WaitForThumbnail returns an up-to-date thumbnail like GetScaledThumbnail,
+// or like GetSizedThumbnail if size is positive, never serving a stale one,
+// and gives up with ctx's error if ctx ends first. Generation is not
+// canceled with ctx: it finishes in the background and is cached, so a later
+// request returns it immediately.
+func (t *ThumbnailGenerator) WaitForThumbnail(ctx context.Context, filePath string, fileType database.FileType, format ThumbnailFormat, scale, size int) ([]byte, ThumbnailFormat, error) {
+	type result struct {
+		data   []byte
+		format ThumbnailFormat
+		err    error
+	}
+
+	// Buffered so the generation goroutine doesn't block once the caller gave up
+	done := make(chan result, 1)
+	go func() {
+		genCtx := context.WithoutCancel(ctx)
+		var res result
+		if size > 0 {
+			res.data, res.format, res.err = t.getSizedThumbnail(genCtx, filePath, fileType, format, size, false)
+		} else {
+			res.data, res.format, res.err = t.getScaledThumbnail(genCtx, filePath, fileType, format, scale, false)
+		}
+		done <- res
+	}()
+
+	select {
+	case res := <-done:
+		return res.data, res.format, res.err
+	case <-ctx.Done():
+		return nil, format, ctx.Err()
+	}
+}
@@ -0,0 +1,91 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestWaitForThumbnailSkipsStale(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(t.TempDir(), mediaDir, true, nil, time.Hour, nil)
+	gen.SetStaleWhileRevalidate(true)
+	ctx := context.Background()
+
+	filename := filepath.Join(mediaDir, "photo.jpg")
+	createTestImageFile(t, filename, 300, 200, "jpeg", 85)
+
+	original, _, err := gen.WaitForThumbnail(ctx, filename, database.FileTypeImage, ThumbnailFormatDefault, 1, 0)
+	if err != nil {
+		t.Fatalf("WaitForThumbnail failed: %v", err)
+	}
+
+	// Edit the source after its thumbnail was cached
+	createTestImageFile(t, filename, 200, 300, "jpeg", 85)
+	edited := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filename, edited, edited); err != nil {
+		t.Fatalf("Failed to set source time: %v", err)
+	}
+
+	got, format, err := gen.WaitForThumbnail(ctx, filename, database.FileTypeImage, ThumbnailFormatDefault, 1, 0)
+	if err != nil {
+		t.Fatalf("WaitForThumbnail after edit failed: %v", err)
+	}
+	if format != ThumbnailFormatDefault {
+		t.Errorf("Expected the default format, got %q", format)
+	}
+	if bytes.Equal(got, original) {
+		t.Error("Expected the thumbnail to be regenerated rather than served stale")
+	}
+}
+
+func TestWaitForThumbnailTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	mediaDir := t.TempDir()
+	gen := NewThumbnailGenerator(t.TempDir(), mediaDir, true, nil, time.Hour, nil)
+
+	// Hold generation in the styling step until released
+	release := make(chan struct{})
+	gen.styleThumbnail = func(img image.Image, _ ThumbnailStyle) (image.Image, error) {
+		<-release
+		return img, nil
+	}
+	gen.SetThumbnailStyle(ThumbnailStyle{CornerRadius: 8})
+
+	filename := filepath.Join(mediaDir, "photo.jpg")
+	createTestImageFile(t, filename, 300, 200, "jpeg", 85)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := gen.WaitForThumbnail(ctx, filename, database.FileTypeImage, ThumbnailFormatDefault, 1, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the wait to time out, got %v", err)
+	}
+
+	// Generation carries on after the timeout and is cached
+	close(release)
+	cacheKey := gen.getCacheKey(filename, database.FileTypeImage)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if _, err := gen.readCachedThumbnail(cacheKey); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the thumbnail to be cached after the wait timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
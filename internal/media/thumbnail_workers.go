@@ -0,0 +1,28 @@
+package media
+
+import "media-viewer/internal/workers"
+
+// WorkerCounts describes the workers background thumbnail generation uses.
+// Memory pressure can halve them while a batch runs.
+type WorkerCounts struct {
+	workers.Choice
+
+	// Initial caps the initial full generation (THUMBNAIL_INITIAL_WORKERS)
+	Initial int `json:"initial"`
+
+	// LargeFiles caps deferred large files; 0 when deferral is disabled
+	LargeFiles int `json:"largeFiles"`
+}
+
+// WorkerCounts returns the worker counts generation runs currently use.
+func (t *ThumbnailGenerator) WorkerCounts() WorkerCounts {
+	choice := workers.Explain(workers.MultiplierMixed, maxThumbnailWorkers)
+	counts := WorkerCounts{
+		Choice:  choice,
+		Initial: workers.LimitInitial(choice.Count),
+	}
+	if t.largeFileThreshold.Load() > 0 {
+		counts.LargeFiles = min(choice.Count, int(max(t.largeFileWorkers.Load(), 1)))
+	}
+	return counts
+}
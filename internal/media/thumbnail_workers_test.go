@@ -0,0 +1,29 @@
+package media
+
+import "testing"
+
+func TestWorkerCounts(t *testing.T) {
+	t.Setenv("THUMBNAIL_WORKERS", "4")
+	t.Setenv("THUMBNAIL_INITIAL_WORKERS", "2")
+
+	gen := &ThumbnailGenerator{}
+	counts := gen.WorkerCounts()
+	if counts.Count != 4 || !counts.Override {
+		t.Errorf("Expected the THUMBNAIL_WORKERS override, got %+v", counts)
+	}
+	if counts.Initial != 2 {
+		t.Errorf("Initial = %d, want 2", counts.Initial)
+	}
+	if counts.LargeFiles != 0 {
+		t.Errorf("Expected no large-file workers without deferral, got %d", counts.LargeFiles)
+	}
+
+	gen.SetLargeFileDeferral(1<<20, 3)
+	if got := gen.WorkerCounts().LargeFiles; got != 3 {
+		t.Errorf("LargeFiles = %d, want 3", got)
+	}
+	gen.SetLargeFileDeferral(1<<20, 8)
+	if got := gen.WorkerCounts().LargeFiles; got != 4 {
+		t.Errorf("Expected large-file workers capped at the regular count, got %d", got)
+	}
+}
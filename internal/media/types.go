@@ -1,5 +1,7 @@
 package media
 
+import "strings"
+
 // FileType represents the type of a media file.
 type FileType string
 
@@ -31,6 +33,14 @@ const (
 	SortBySize SortField = "size"
 	// SortByType sorts results by file type.
 	SortByType SortField = "type"
+	// SortByManual sorts results by the folder's manual order; unpositioned items follow by name.
+	SortByManual SortField = "manual"
+	// SortByCreated sorts results by creation (birth) time, falling back to
+	// modification time for files indexed without one.
+	SortByCreated SortField = "created"
+	// SortByCaptured sorts results by the EXIF capture date, falling back to
+	// modification time for files indexed without one.
+	SortByCaptured SortField = "captured"
 
 	// SortAsc sorts in ascending order.
 	SortAsc SortOrder = "asc"
@@ -110,9 +120,10 @@ var MimeTypes = map[string]string{
 }
 
 // GetFileType returns the FileType for a given file extension.
-// The extension should be lowercase and include the leading dot (e.g., ".jpg").
+// The extension must include the leading dot (e.g., ".jpg"); case is ignored.
 // Returns FileTypeOther if the extension is not recognized.
 func GetFileType(ext string) FileType {
+	ext = strings.ToLower(ext)
 	if ImageExtensions[ext] {
 		return FileTypeImage
 	}
@@ -126,10 +137,10 @@ func GetFileType(ext string) FileType {
 }
 
 // GetMimeType returns the MIME type for a given file extension.
-// The extension should be lowercase and include the leading dot (e.g., ".jpg").
+// The extension must include the leading dot (e.g., ".jpg"); case is ignored.
 // Returns "application/octet-stream" if the extension is not recognized.
 func GetMimeType(ext string) string {
-	if mime, ok := MimeTypes[ext]; ok {
+	if mime, ok := MimeTypes[strings.ToLower(ext)]; ok {
 		return mime
 	}
 	return "application/octet-stream"
@@ -142,10 +153,10 @@ func IsMediaFile(ext string) bool {
 
 // IsSupportedImage returns true if the extension is a supported image format.
 func IsSupportedImage(ext string) bool {
-	return ImageExtensions[ext]
+	return ImageExtensions[strings.ToLower(ext)]
 }
 
 // IsSupportedVideo returns true if the extension is a supported video format.
 func IsSupportedVideo(ext string) bool {
-	return VideoExtensions[ext]
+	return VideoExtensions[strings.ToLower(ext)]
 }
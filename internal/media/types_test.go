@@ -273,7 +273,9 @@ func TestGetFileType(t *testing.T) {
 		{"Unknown extension", ".xyz", FileTypeOther},
 		{"Text file", ".txt", FileTypeOther},
 		{"Empty extension", "", FileTypeOther},
-		{"Case sensitive - uppercase", ".JPG", FileTypeOther}, // Should be lowercase
+		{"Uppercase image", ".JPG", FileTypeImage},
+		{"Mixed case video", ".Mp4", FileTypeVideo},
+		{"Uppercase HEIC", ".HEIC", FileTypeImage},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,40 @@
+package media
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"media-viewer/internal/logging"
+)
+
+// posterOffset returns the offset set for a video's thumbnail with
+// SetVideoPoster, if there is one
+func (t *ThumbnailGenerator) posterOffset(ctx context.Context, filePath string) (time.Duration, bool) {
+	if t.withoutDatabase("video posters") {
+		return 0, false
+	}
+
+	relPath, err := filepath.Rel(t.mediaDir, filePath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return 0, false
+	}
+
+	offset, ok, err := t.db.GetVideoPoster(ctx, relPath)
+	if err != nil {
+		logging.Debug("Failed to read poster offset for %s, using the seek strategy: %v", relPath, err)
+		return 0, false
+	}
+	return offset, ok
+}
+
+// videoSeekStrategyFor returns how a video's thumbnail frame is chosen: its
+// poster offset if one is set, otherwise the configured strategy. poster
+// reports which it is.
+func (t *ThumbnailGenerator) videoSeekStrategyFor(ctx context.Context, filePath string) (strategy VideoSeekStrategy, poster bool) {
+	if offset, ok := t.posterOffset(ctx, filePath); ok {
+		return VideoSeekStrategy{Mode: VideoSeekFixed, Offset: offset}, true
+	}
+	return t.getVideoSeekStrategy(), false
+}
@@ -0,0 +1,65 @@
+package media
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"media-viewer/internal/database"
+)
+
+func TestGenerateVideoThumbnailPosterIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	argsLog := installMockFFmpeg(t, "600.000000")
+	mediaDir := t.TempDir()
+	video := filepath.Join(mediaDir, "clip.mp4")
+	if err := os.WriteFile(video, []byte("video"), 0o644); err != nil {
+		t.Fatalf("Failed to write video: %v", err)
+	}
+
+	db, _, err := database.New(context.Background(), filepath.Join(t.TempDir(), "poster_test.db"), &database.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	gen := NewThumbnailGenerator(t.TempDir(), mediaDir, true, db, time.Hour, nil)
+	gen.SetVideoSeekStrategy(VideoSeekStrategy{Mode: VideoSeekPercent, Percent: 50})
+
+	lastSeek := func() string {
+		t.Helper()
+		if _, err := gen.generateVideoThumbnail(ctx, video); err != nil {
+			t.Fatalf("generateVideoThumbnail failed: %v", err)
+		}
+		data, err := os.ReadFile(argsLog)
+		if err != nil {
+			t.Fatalf("Failed to read ffmpeg arguments: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		return strings.Fields(lines[len(lines)-1])[1]
+	}
+
+	if seek := lastSeek(); seek != "00:05:00.000" {
+		t.Errorf("Expected the configured 50%% seek, got %s", seek)
+	}
+	if _, poster := gen.videoSeekStrategyFor(ctx, video); poster {
+		t.Error("Expected no poster before one is set")
+	}
+
+	if err := db.SetVideoPoster(ctx, "clip.mp4", 90*time.Second); err != nil {
+		t.Fatalf("SetVideoPoster failed: %v", err)
+	}
+	if seek := lastSeek(); seek != "00:01:30.000" {
+		t.Errorf("Expected the poster offset to override the strategy, got %s", seek)
+	}
+	if _, poster := gen.videoSeekStrategyFor(ctx, video); !poster {
+		t.Error("Expected the video to have a poster")
+	}
+}
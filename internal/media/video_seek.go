@@ -0,0 +1,135 @@
+package media
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"media-viewer/internal/transcoder"
+)
+
+// VideoSeekMode selects how the thumbnail frame of a video is chosen.
+type VideoSeekMode string
+
+const (
+	// VideoSeekFixed extracts the frame at a fixed offset from the start.
+	VideoSeekFixed VideoSeekMode = "fixed"
+	// VideoSeekPercent extracts the frame at a percentage of the duration.
+	VideoSeekPercent VideoSeekMode = "percent"
+	// VideoSeekSmart seeks into the video and lets FFmpeg's thumbnail filter
+	// pick the most representative frame from the following frames.
+	VideoSeekSmart VideoSeekMode = "smart"
+)
+
+const (
+	// smartSeekPercent is where smart mode starts looking, past typical intros
+	smartSeekPercent = 10.0
+
+	// smartSeekFrames is the number of frames the thumbnail filter compares
+	smartSeekFrames = 50
+
+	// smartSeekScaleWidth downscales frames before analysis so the filter's
+	// frame buffer stays small for 4K sources
+	smartSeekScaleWidth = 640
+)
+
+// VideoSeekStrategy describes which frame becomes a video's thumbnail.
+type VideoSeekStrategy struct {
+	Mode    VideoSeekMode
+	Offset  time.Duration // Used by VideoSeekFixed
+	Percent float64       // Used by VideoSeekPercent (0-100)
+}
+
+// DefaultVideoSeekStrategy returns the strategy used when none is configured.
+func DefaultVideoSeekStrategy() VideoSeekStrategy {
+	return VideoSeekStrategy{Mode: VideoSeekSmart}
+}
+
+// ParseVideoSeekStrategy parses a THUMBNAIL_VIDEO_SEEK value. Accepted forms
+// are "smart", a duration such as "1s" or "1m30s", or a percentage such as
+// "10%". An empty value returns the default strategy.
+func ParseVideoSeekStrategy(value string) (VideoSeekStrategy, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+
+	switch {
+	case value == "":
+		return DefaultVideoSeekStrategy(), nil
+
+	case value == string(VideoSeekSmart):
+		return VideoSeekStrategy{Mode: VideoSeekSmart}, nil
+
+	case strings.HasSuffix(value, "%"):
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil {
+			return VideoSeekStrategy{}, fmt.Errorf("invalid percentage %q: %w", value, err)
+		}
+		if percent < 0 || percent >= 100 {
+			return VideoSeekStrategy{}, fmt.Errorf("percentage %q must be between 0%% and 100%%", value)
+		}
+		return VideoSeekStrategy{Mode: VideoSeekPercent, Percent: percent}, nil
+
+	default:
+		offset, err := time.ParseDuration(value)
+		if err != nil {
+			return VideoSeekStrategy{}, fmt.Errorf("invalid video seek %q (use \"smart\", a duration like \"5s\", or a percentage like \"10%%\")", value)
+		}
+		if offset < 0 {
+			return VideoSeekStrategy{}, fmt.Errorf("video seek offset %q must not be negative", value)
+		}
+		return VideoSeekStrategy{Mode: VideoSeekFixed, Offset: offset}, nil
+	}
+}
+
+// String returns the strategy in the same form ParseVideoSeekStrategy accepts.
+func (s VideoSeekStrategy) String() string {
+	switch s.Mode {
+	case VideoSeekFixed:
+		return s.Offset.String()
+	case VideoSeekPercent:
+		return strconv.FormatFloat(s.Percent, 'f', -1, 64) + "%"
+	default:
+		return string(VideoSeekSmart)
+	}
+}
+
+// needsDuration reports whether the strategy needs the video duration.
+func (s VideoSeekStrategy) needsDuration() bool {
+	return s.Mode != VideoSeekFixed
+}
+
+// seekSeconds returns the offset to seek to for a video of the given duration.
+// Relative strategies use the first frame when the duration is unknown.
+func (s VideoSeekStrategy) seekSeconds(duration float64) float64 {
+	if s.Mode == VideoSeekFixed {
+		return s.Offset.Seconds()
+	}
+	if !transcoder.IsKnownDuration(duration) {
+		return 0
+	}
+	if s.Mode == VideoSeekPercent {
+		return duration * s.Percent / 100
+	}
+	return duration * smartSeekPercent / 100
+}
+
+// ffmpegArgs builds the FFmpeg arguments that extract the thumbnail frame as
+// PNG on stdout. The seek is placed before -i so FFmpeg jumps to the nearest
+// keyframe instead of decoding everything up to the offset.
+func (s VideoSeekStrategy) ffmpegArgs(filePath string, duration float64) []string {
+	args := []string{
+		"-ss", formatSeekTime(s.seekSeconds(duration)),
+		"-i", filePath,
+	}
+
+	if s.Mode == VideoSeekSmart {
+		args = append(args, "-vf", fmt.Sprintf("scale='min(%d,iw)':-2,thumbnail=%d", smartSeekScaleWidth, smartSeekFrames))
+	}
+
+	return append(args,
+		"-vframes", "1",
+		"-f", "image2pipe",
+		"-vcodec", "png",
+		"-",
+	)
+}
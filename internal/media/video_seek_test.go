@@ -0,0 +1,211 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseVideoSeekStrategy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected VideoSeekStrategy
+	}{
+		{"", VideoSeekStrategy{Mode: VideoSeekSmart}},
+		{"smart", VideoSeekStrategy{Mode: VideoSeekSmart}},
+		{" SMART ", VideoSeekStrategy{Mode: VideoSeekSmart}},
+		{"1s", VideoSeekStrategy{Mode: VideoSeekFixed, Offset: time.Second}},
+		{"1m30s", VideoSeekStrategy{Mode: VideoSeekFixed, Offset: 90 * time.Second}},
+		{"0s", VideoSeekStrategy{Mode: VideoSeekFixed}},
+		{"10%", VideoSeekStrategy{Mode: VideoSeekPercent, Percent: 10}},
+		{"2.5%", VideoSeekStrategy{Mode: VideoSeekPercent, Percent: 2.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseVideoSeekStrategy(tt.input)
+			if err != nil {
+				t.Fatalf("ParseVideoSeekStrategy(%q) returned error: %v", tt.input, err)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseVideoSeekStrategy(%q) = %+v, want %+v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseVideoSeekStrategyInvalid(t *testing.T) {
+	for _, input := range []string{"middle", "-5s", "abc%", "100%", "-1%", "5"} {
+		t.Run(input, func(t *testing.T) {
+			if _, err := ParseVideoSeekStrategy(input); err == nil {
+				t.Errorf("ParseVideoSeekStrategy(%q) expected error", input)
+			}
+		})
+	}
+}
+
+func TestVideoSeekStrategyString(t *testing.T) {
+	for _, input := range []string{"smart", "5s", "1m30s", "10%", "2.5%"} {
+		strategy, err := ParseVideoSeekStrategy(input)
+		if err != nil {
+			t.Fatalf("ParseVideoSeekStrategy(%q) returned error: %v", input, err)
+		}
+		if got := strategy.String(); got != input {
+			t.Errorf("String() = %q, want %q", got, input)
+		}
+	}
+}
+
+func TestVideoSeekStrategySeekSeconds(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy VideoSeekStrategy
+		duration float64
+		expected float64
+	}{
+		{"fixed ignores duration", VideoSeekStrategy{Mode: VideoSeekFixed, Offset: 5 * time.Second}, 100, 5},
+		{"percent of duration", VideoSeekStrategy{Mode: VideoSeekPercent, Percent: 25}, 200, 50},
+		{"percent unknown duration", VideoSeekStrategy{Mode: VideoSeekPercent, Percent: 25}, 0, 0},
+		{"percent negative duration", VideoSeekStrategy{Mode: VideoSeekPercent, Percent: 25}, -5, 0},
+		{"smart of duration", VideoSeekStrategy{Mode: VideoSeekSmart}, 600, 60},
+		{"smart unknown duration", VideoSeekStrategy{Mode: VideoSeekSmart}, 0, 0},
+		{"smart NaN duration", VideoSeekStrategy{Mode: VideoSeekSmart}, math.NaN(), 0},
+		{"smart infinite duration", VideoSeekStrategy{Mode: VideoSeekSmart}, math.Inf(1), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.strategy.seekSeconds(tt.duration); got != tt.expected {
+				t.Errorf("seekSeconds(%v) = %v, want %v", tt.duration, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestVideoSeekStrategyFFmpegArgs(t *testing.T) {
+	fixed := VideoSeekStrategy{Mode: VideoSeekFixed, Offset: time.Second}
+	args := fixed.ffmpegArgs("/media/video.mp4", 0)
+
+	// Input seeking: -ss must come before -i
+	if args[0] != "-ss" || args[1] != "00:00:01.000" || args[2] != "-i" || args[3] != "/media/video.mp4" {
+		t.Errorf("Expected input seek before -i, got %v", args)
+	}
+	if slices.Contains(args, "-vf") {
+		t.Errorf("Fixed seek should not use a filter, got %v", args)
+	}
+	if args[len(args)-1] != "-" {
+		t.Errorf("Expected output to stdout, got %v", args)
+	}
+
+	smart := VideoSeekStrategy{Mode: VideoSeekSmart}
+	args = smart.ffmpegArgs("/media/video.mp4", 600)
+	if args[1] != "00:01:00.000" {
+		t.Errorf("Expected smart seek to 10%% of duration, got %s", args[1])
+	}
+	idx := slices.Index(args, "-vf")
+	if idx < 0 || !strings.Contains(args[idx+1], "thumbnail=") {
+		t.Errorf("Expected thumbnail filter for smart seek, got %v", args)
+	}
+}
+
+func TestSetVideoSeekStrategy(t *testing.T) {
+	gen := &ThumbnailGenerator{}
+
+	if got := gen.getVideoSeekStrategy(); got != DefaultVideoSeekStrategy() {
+		t.Errorf("Expected default strategy, got %+v", got)
+	}
+
+	strategy := VideoSeekStrategy{Mode: VideoSeekPercent, Percent: 20}
+	gen.SetVideoSeekStrategy(strategy)
+	if got := gen.getVideoSeekStrategy(); got != strategy {
+		t.Errorf("getVideoSeekStrategy() = %+v, want %+v", got, strategy)
+	}
+}
+
+// installMockFFmpeg puts fake ffprobe and ffmpeg binaries first in PATH.
+// ffprobe prints the given duration; ffmpeg logs its arguments and writes a
+// small PNG to stdout. Returns the path of the argument log.
+func installMockFFmpeg(t *testing.T, duration string) string {
+	t.Helper()
+
+	binDir := t.TempDir()
+	frame := filepath.Join(binDir, "frame.png")
+	argsLog := filepath.Join(binDir, "ffmpeg-args.log")
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 32, 18))); err != nil {
+		t.Fatalf("Failed to encode frame: %v", err)
+	}
+	if err := os.WriteFile(frame, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("Failed to write frame: %v", err)
+	}
+
+	scripts := map[string]string{
+		"ffprobe": "#!/bin/bash\necho '" + duration + "'\n",
+		"ffmpeg":  "#!/bin/bash\necho \"$@\" >> '" + argsLog + "'\ncat '" + frame + "'\n",
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(binDir, name), []byte(script), 0o755); err != nil {
+			t.Fatalf("Failed to create mock %s: %v", name, err)
+		}
+	}
+
+	t.Setenv("PATH", binDir+":"+os.Getenv("PATH"))
+	return argsLog
+}
+
+func TestGetVideoDurationUnknown(t *testing.T) {
+	for _, duration := range []string{"N/A", "0.000000", "-1.000000", "nan", "inf"} {
+		t.Run(duration, func(t *testing.T) {
+			installMockFFmpeg(t, duration)
+			gen := &ThumbnailGenerator{}
+
+			if got, err := gen.getVideoDuration(context.Background(), "/media/live.ts"); err == nil {
+				t.Errorf("Expected error for duration %q, got %v", duration, got)
+			}
+		})
+	}
+}
+
+func TestGenerateVideoThumbnailZeroDuration(t *testing.T) {
+	for _, mode := range []string{"smart", "25%"} {
+		t.Run(mode, func(t *testing.T) {
+			argsLog := installMockFFmpeg(t, "0.000000")
+
+			strategy, err := ParseVideoSeekStrategy(mode)
+			if err != nil {
+				t.Fatalf("ParseVideoSeekStrategy failed: %v", err)
+			}
+			gen := &ThumbnailGenerator{}
+			gen.SetVideoSeekStrategy(strategy)
+
+			img, err := gen.generateVideoThumbnail(context.Background(), "/media/live.ts")
+			if err != nil {
+				t.Fatalf("generateVideoThumbnail failed: %v", err)
+			}
+			if img == nil || img.Bounds().Dx() != 32 {
+				t.Fatalf("Expected the extracted frame, got %v", img)
+			}
+
+			data, err := os.ReadFile(argsLog)
+			if err != nil {
+				t.Fatalf("Failed to read ffmpeg arguments: %v", err)
+			}
+			calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if len(calls) != 1 {
+				t.Fatalf("Expected a single ffmpeg call, got %d: %v", len(calls), calls)
+			}
+			if !strings.HasPrefix(calls[0], "-ss 00:00:00.000 -i /media/live.ts") {
+				t.Errorf("Expected the first frame to be extracted, got args: %s", calls[0])
+			}
+		})
+	}
+}
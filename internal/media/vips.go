@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"image"
+	"image/png"
 	"path/filepath"
 	"sync"
 
@@ -50,7 +51,7 @@ func InitVips() error {
 			case vips.LogLevelError, vips.LogLevelCritical:
 				logging.Error("[%s] %s", domain, msg)
 			case vips.LogLevelWarning:
-				logging.Warn("[%s] %s", domain, msg)
+				logging.WarnSampled("vips:"+domain, "[%s] %s", domain, msg)
 			case vips.LogLevelMessage, vips.LogLevelInfo, vips.LogLevelDebug:
 				logging.Debug("[%s] %s", domain, msg)
 			}
@@ -63,7 +64,7 @@ func InitVips() error {
 			case vips.LogLevelError, vips.LogLevelCritical:
 				logging.Error("[%s] %s", domain, msg)
 			case vips.LogLevelWarning:
-				logging.Warn("[%s] %s", domain, msg)
+				logging.WarnSampled("vips:"+domain, "[%s] %s", domain, msg)
 			case vips.LogLevelMessage, vips.LogLevelInfo, vips.LogLevelDebug:
 				// Suppressed at Info level
 			}
@@ -89,7 +90,7 @@ func InitVips() error {
 		vipsLogLevel = vips.LogLevelWarning
 		logHandler = func(domain string, level vips.LogLevel, msg string) {
 			if level >= vips.LogLevelError {
-				logging.Warn("[%s] %s", domain, msg)
+				logging.WarnSampled("vips:"+domain, "[%s] %s", domain, msg)
 			}
 		}
 	}
@@ -129,8 +130,15 @@ func ShutdownVips() {
 // LoadImageWithVips loads and resizes an image using libvips with decode-time shrinking
 // This is much more memory efficient than loading the full image then resizing
 func LoadImageWithVips(path string, targetWidth, targetHeight int) (image.Image, error) {
+	img, _, err := loadImageWithVips(path, targetWidth, targetHeight)
+	return img, err
+}
+
+// loadImageWithVips implements LoadImageWithVips, also returning the number
+// of pages (frames) libvips reports for the image
+func loadImageWithVips(path string, targetWidth, targetHeight int) (image.Image, int, error) {
 	if !vipsAvailable {
-		return nil, fmt.Errorf("libvips not available")
+		return nil, 0, fmt.Errorf("libvips not available")
 	}
 
 	// libvips can shrink during decode, which is MUCH more memory efficient
@@ -141,13 +149,14 @@ func LoadImageWithVips(path string, targetWidth, targetHeight int) (image.Image,
 	importParams := vips.NewImportParams()
 	ref, err := vips.LoadImageFromFile(path, importParams)
 	if err != nil {
-		return nil, fmt.Errorf("vips failed to load image: %w", err)
+		return nil, 0, fmt.Errorf("vips failed to load image: %w", err)
 	}
 	defer ref.Close()
 
-	// Get original dimensions
+	// Get original dimensions, and the page count before the resize
 	origWidth := ref.Width()
 	origHeight := ref.Height()
+	pages := ref.Pages()
 
 	logging.Debug("Vips loaded %s: %dx%d, shrinking to %dx%d",
 		filepath.Base(path), origWidth, origHeight, targetWidth, targetHeight)
@@ -156,7 +165,7 @@ func LoadImageWithVips(path string, targetWidth, targetHeight int) (image.Image,
 	// Use Lanczos3 for best quality
 	err = ref.Thumbnail(targetWidth, targetHeight, vips.InterestingNone)
 	if err != nil {
-		return nil, fmt.Errorf("vips resize failed: %w", err)
+		return nil, 0, fmt.Errorf("vips resize failed: %w", err)
 	}
 
 	// Export to JPEG bytes (we'll convert to image.Image)
@@ -166,20 +175,149 @@ func LoadImageWithVips(path string, targetWidth, targetHeight int) (image.Image,
 		OptimizeCoding: true,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("vips export failed: %w", err)
+		return nil, 0, fmt.Errorf("vips export failed: %w", err)
 	}
 
 	// Convert bytes back to image.Image for compatibility
 	// This adds a small overhead but keeps the API consistent
 	img, err := imaging.Decode(bytes.NewReader(imgBytes), imaging.AutoOrientation(true))
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode vips output: %w", err)
+		return nil, 0, fmt.Errorf("failed to decode vips output: %w", err)
 	}
 
 	logging.Debug("Vips processing complete for %s: final size %dx%d",
 		filepath.Base(path), img.Bounds().Dx(), img.Bounds().Dy())
 
-	return img, nil
+	return img, pages, nil
+}
+
+// encodeVariantWithVips re-encodes a cached thumbnail as WebP or AVIF
+func encodeVariantWithVips(data []byte, format ThumbnailFormat) ([]byte, error) {
+	if !IsVipsAvailable() {
+		return nil, fmt.Errorf("libvips not available")
+	}
+
+	ref, err := vips.NewImageFromBuffer(data)
+	if err != nil {
+		return nil, fmt.Errorf("vips failed to load thumbnail: %w", err)
+	}
+	defer ref.Close()
+
+	var out []byte
+	switch format {
+	case ThumbnailFormatWebP:
+		params := vips.NewWebpExportParams()
+		params.Quality = 80
+		params.StripMetadata = true
+		out, _, err = ref.ExportWebp(params)
+	case ThumbnailFormatAVIF:
+		params := vips.NewAvifExportParams()
+		params.Quality = 60
+		params.StripMetadata = true
+		out, _, err = ref.ExportAvif(params)
+	default:
+		return nil, fmt.Errorf("unsupported thumbnail format: %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("vips %s export failed: %w", format, err)
+	}
+	return out, nil
+}
+
+// obscureWithVips blurs or pixelates a cached thumbnail for a file flagged
+// as sensitive, re-encoding it as JPEG
+func obscureWithVips(data []byte, style SensitiveStyle) ([]byte, error) {
+	ref, err := vips.NewImageFromBuffer(data)
+	if err != nil {
+		return nil, fmt.Errorf("vips failed to load thumbnail: %w", err)
+	}
+	defer ref.Close()
+
+	width, height := ref.Width(), ref.Height()
+	longest := max(width, height)
+	if longest == 0 {
+		return nil, fmt.Errorf("empty thumbnail")
+	}
+
+	switch style {
+	case SensitivePixelate:
+		// Shrink to a few blocks, then scale back up without smoothing
+		if err := ref.Resize(float64(sensitivePixelBlocks)/float64(longest), vips.KernelLinear); err != nil {
+			return nil, fmt.Errorf("vips resize failed: %w", err)
+		}
+		if err := ref.ResizeWithVScale(float64(width)/float64(ref.Width()), float64(height)/float64(ref.Height()), vips.KernelNearest); err != nil {
+			return nil, fmt.Errorf("vips resize failed: %w", err)
+		}
+	default:
+		if err := ref.GaussianBlur(float64(longest) / sensitiveBlurDivisor); err != nil {
+			return nil, fmt.Errorf("vips blur failed: %w", err)
+		}
+	}
+
+	out, _, err := ref.ExportJpeg(&vips.JpegExportParams{
+		Quality:       75,
+		StripMetadata: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("vips jpeg export failed: %w", err)
+	}
+	return out, nil
+}
+
+// styleThumbnailWithVips bakes rounded corners and a border into a thumbnail.
+// The corners are cut with a rounded-rectangle mask and the border is drawn
+// over the result, both rendered from SVG, before flattening onto the style's
+// background color.
+func styleThumbnailWithVips(thumb image.Image, style ThumbnailStyle) (image.Image, error) {
+	var src bytes.Buffer
+	if err := png.Encode(&src, thumb); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail for styling: %w", err)
+	}
+
+	ref, err := vips.NewImageFromBuffer(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("vips failed to load thumbnail: %w", err)
+	}
+	defer ref.Close()
+
+	if !ref.HasAlpha() {
+		if err := ref.AddAlpha(); err != nil {
+			return nil, fmt.Errorf("vips failed to add alpha: %w", err)
+		}
+	}
+
+	width, height := ref.Width(), ref.Height()
+	if style.CornerRadius > 0 {
+		if err := compositeSVG(ref, style.maskSVG(width, height), vips.BlendModeDestIn); err != nil {
+			return nil, fmt.Errorf("failed to round corners: %w", err)
+		}
+	}
+	if style.BorderWidth > 0 {
+		if err := compositeSVG(ref, style.borderSVG(width, height), vips.BlendModeOver); err != nil {
+			return nil, fmt.Errorf("failed to draw border: %w", err)
+		}
+	}
+
+	bg := style.Background
+	if err := ref.Flatten(&vips.Color{R: bg.R, G: bg.G, B: bg.B}); err != nil {
+		return nil, fmt.Errorf("vips failed to flatten thumbnail: %w", err)
+	}
+
+	out, _, err := ref.ExportPng(vips.NewPngExportParams())
+	if err != nil {
+		return nil, fmt.Errorf("vips PNG export failed: %w", err)
+	}
+	return png.Decode(bytes.NewReader(out))
+}
+
+// compositeSVG renders an SVG the size of ref and composites it onto ref
+func compositeSVG(ref *vips.ImageRef, svg []byte, mode vips.BlendMode) error {
+	overlay, err := vips.NewImageFromBuffer(svg)
+	if err != nil {
+		return fmt.Errorf("vips failed to render SVG: %w", err)
+	}
+	defer overlay.Close()
+	return ref.Composite(overlay, mode, 0, 0)
 }
 
 // IsVipsAvailable returns whether libvips is initialized and available
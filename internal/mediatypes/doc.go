@@ -17,9 +17,11 @@
 //
 // # Extension Detection
 //
-// Use GetFileType to determine the type of a file based on its extension:
+// Use NormalizeExt to take the extension of a file name, and GetFileType to
+// determine the type of a file from it. Extensions are matched
+// case-insensitively, so "photo.JPG" and "photo.jpg" are the same type:
 //
-//	ext := strings.ToLower(filepath.Ext(filename))
+//	ext := mediatypes.NormalizeExt(filename)
 //	fileType := mediatypes.GetFileType(ext)
 //
 //	switch fileType {
@@ -33,7 +35,7 @@
 //
 // Use GetMimeType to get the appropriate MIME type for HTTP responses:
 //
-//	ext := strings.ToLower(filepath.Ext(filename))
+//	ext := mediatypes.NormalizeExt(filename)
 //	mimeType := mediatypes.GetMimeType(ext) // e.g., "image/jpeg"
 //
 // # Sorting
@@ -47,7 +49,8 @@
 // # Supported Formats
 //
 // The extension maps (ImageExtensions, VideoExtensions, PlaylistExtensions) can be
-// used directly for format validation or iteration:
+// used directly for format validation or iteration. Their keys are lowercase,
+// so index them with a normalized extension:
 //
 //	if mediatypes.ImageExtensions[ext] {
 //	    // File is a supported image
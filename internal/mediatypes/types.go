@@ -1,5 +1,10 @@
 package mediatypes
 
+import (
+	"path/filepath"
+	"strings"
+)
+
 // FileType represents the type of a media file.
 type FileType string
 
@@ -31,6 +36,14 @@ const (
 	SortBySize SortField = "size"
 	// SortByType sorts results by file type.
 	SortByType SortField = "type"
+	// SortByManual sorts results by the folder's manual order; unpositioned items follow by name.
+	SortByManual SortField = "manual"
+	// SortByCreated sorts results by creation (birth) time, falling back to
+	// modification time for files indexed without one.
+	SortByCreated SortField = "created"
+	// SortByCaptured sorts results by the EXIF capture date, falling back to
+	// modification time for files indexed without one.
+	SortByCaptured SortField = "captured"
 
 	// SortAsc sorts in ascending order.
 	SortAsc SortOrder = "asc"
@@ -117,10 +130,18 @@ var MimeTypes = map[string]string{
 	".wpl": "application/vnd.ms-wpl",
 }
 
+// NormalizeExt returns the extension of a file name or path in the form used
+// as a key by the extension maps: lowercase, with the leading dot (e.g.,
+// "IMG_0001.JPG" -> ".jpg"). Returns "" if the name has no extension.
+func NormalizeExt(name string) string {
+	return strings.ToLower(filepath.Ext(name))
+}
+
 // GetFileType returns the FileType for a given file extension.
-// The extension should be lowercase and include the leading dot (e.g., ".jpg").
+// The extension must include the leading dot (e.g., ".jpg"); case is ignored.
 // Returns FileTypeOther if the extension is not recognized.
 func GetFileType(ext string) FileType {
+	ext = strings.ToLower(ext)
 	if ImageExtensions[ext] {
 		return FileTypeImage
 	}
@@ -134,10 +155,10 @@ func GetFileType(ext string) FileType {
 }
 
 // GetMimeType returns the MIME type for a given file extension.
-// The extension should be lowercase and include the leading dot (e.g., ".jpg").
+// The extension must include the leading dot (e.g., ".jpg"); case is ignored.
 // Returns "application/octet-stream" if the extension is not recognized.
 func GetMimeType(ext string) string {
-	if mime, ok := MimeTypes[ext]; ok {
+	if mime, ok := MimeTypes[strings.ToLower(ext)]; ok {
 		return mime
 	}
 	return "application/octet-stream"
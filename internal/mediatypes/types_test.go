@@ -35,6 +35,21 @@ func TestGetFileType(t *testing.T) {
 			ext:  ".wpl",
 			want: FileTypePlaylist,
 		},
+		{
+			name: "Uppercase image",
+			ext:  ".JPG",
+			want: FileTypeImage,
+		},
+		{
+			name: "Mixed case video",
+			ext:  ".Mp4",
+			want: FileTypeVideo,
+		},
+		{
+			name: "Uppercase playlist",
+			ext:  ".WPL",
+			want: FileTypePlaylist,
+		},
 		{
 			name: "Unknown extension",
 			ext:  ".xyz",
@@ -83,6 +98,16 @@ func TestGetMimeType(t *testing.T) {
 			ext:  ".wpl",
 			want: "application/vnd.ms-wpl",
 		},
+		{
+			name: "Uppercase HEIC mime type",
+			ext:  ".HEIC",
+			want: "image/heic",
+		},
+		{
+			name: "Mixed case MP4 mime type",
+			ext:  ".Mp4",
+			want: "video/mp4",
+		},
 		{
 			name: "Unknown extension returns octet-stream",
 			ext:  ".unknown",
@@ -105,6 +130,27 @@ func TestGetMimeType(t *testing.T) {
 	}
 }
 
+func TestNormalizeExt(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"photo.jpg", ".jpg"},
+		{"IMG_0001.JPG", ".jpg"},
+		{"clip.Mp4", ".mp4"},
+		{"/media/Camera Roll/IMG_0002.HEIC", ".heic"},
+		{"archive.tar.GZ", ".gz"},
+		{"noext", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeExt(tt.name); got != tt.want {
+			t.Errorf("NormalizeExt(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
 func TestIsMediaFile(t *testing.T) {
 	tests := []struct {
 		name string
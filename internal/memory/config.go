@@ -3,8 +3,10 @@ package memory
 import (
 	"math"
 	"os"
+	"path/filepath"
 	"runtime/debug"
 	"strconv"
+	"strings"
 
 	"media-viewer/internal/logging"
 )
@@ -14,19 +16,37 @@ const (
 	// Reserve the rest for FFmpeg, image processing, goroutine stacks, etc.
 	DefaultMemoryRatio = 0.85
 
+	// DefaultTranscodeBytes is the memory reserved for each transcode counted
+	// by MEMORY_RESERVE_TRANSCODES when MEMORY_TRANSCODE_BYTES isn't set
+	DefaultTranscodeBytes = 256 << 20
+
+	// minReservedHeapRatio is the smallest share of the container limit left
+	// to the Go heap however much is reserved for transcodes
+	minReservedHeapRatio = 0.25
+
 	// Memory configuration source constants
 	sourceGOMEMLIMIT  = "GOMEMLIMIT"
 	sourceMEMORYLIMIT = "MEMORY_LIMIT"
+	sourceCgroupV2    = "cgroup-v2"
+	sourceCgroupV1    = "cgroup-v1"
 	sourceNone        = "none"
+
+	// cgroupUnbounded is the smallest cgroup limit treated as no limit. cgroup
+	// v1 reports an unset limit as the largest page-aligned int64.
+	cgroupUnbounded = 1 << 62
 )
 
+// cgroupRoot is where the cgroup filesystem is mounted. Tests point it at a
+// temporary directory.
+var cgroupRoot = "/sys/fs/cgroup"
+
 // ConfigResult holds the result of memory configuration
 type ConfigResult struct {
 	// Configured indicates whether GOMEMLIMIT was set
 	Configured bool
 
 	// Source indicates where the configuration came from
-	Source string // sourceGOMEMLIMIT, sourceMEMORYLIMIT, or sourceNone
+	Source string // sourceGOMEMLIMIT, sourceMEMORYLIMIT, sourceCgroupV2, sourceCgroupV1, or sourceNone
 
 	// ContainerLimit is the container memory limit in bytes (0 if not set)
 	ContainerLimit int64
@@ -36,6 +56,9 @@ type ConfigResult struct {
 
 	// Ratio is the memory ratio used (0 if not applicable)
 	Ratio float64
+	// TranscodeReserve is the memory reserved for transcodes in place of a
+	// ratio (0 if the ratio was used)
+	TranscodeReserve int64
 }
 
 // ConfigureFromEnv sets GOMEMLIMIT based on Kubernetes memory limit
@@ -45,6 +68,12 @@ type ConfigResult struct {
 //   - GOMEMLIMIT: If set, this takes precedence (standard Go env var)
 //   - MEMORY_LIMIT: Container memory limit in bytes (from Kubernetes Downward API)
 //   - MEMORY_RATIO: Optional ratio of memory to use for Go heap (default: 0.85)
+//   - MEMORY_RESERVE_TRANSCODES: Optional number of concurrent FFmpeg
+//     transcodes to reserve memory for instead of applying MEMORY_RATIO
+//   - MEMORY_TRANSCODE_BYTES: Memory to reserve per transcode (default: 256 MiB)
+//
+// When MEMORY_LIMIT is unset, the limit of the container's cgroup is used
+// instead, read from cgroup v2 and then cgroup v1.
 func ConfigureFromEnv() ConfigResult {
 	result := ConfigResult{}
 
@@ -60,23 +89,54 @@ func ConfigureFromEnv() ConfigResult {
 		return result
 	}
 
-	// Check for Kubernetes memory limit passed via Downward API
-	memLimitStr := os.Getenv("MEMORY_LIMIT")
-	if memLimitStr == "" {
-		logging.Debug("MEMORY_LIMIT not set, GOMEMLIMIT will not be configured automatically")
-		result.Source = sourceNone
-		return result
+	// Check for Kubernetes memory limit passed via Downward API, falling back
+	// to the cgroup limit
+	source := sourceMEMORYLIMIT
+	var memLimit int64
+	if memLimitStr := os.Getenv("MEMORY_LIMIT"); memLimitStr != "" {
+		var err error
+		memLimit, err = strconv.ParseInt(memLimitStr, 10, 64)
+		if err != nil {
+			logging.Warn("Failed to parse MEMORY_LIMIT %q: %v", memLimitStr, err)
+			result.Source = sourceNone
+			return result
+		}
+	} else {
+		memLimit, source = cgroupMemoryLimit()
+		if memLimit == 0 {
+			logging.Debug("MEMORY_LIMIT not set and no cgroup memory limit found, GOMEMLIMIT will not be configured automatically")
+			result.Source = sourceNone
+			return result
+		}
+		logging.Info("MEMORY_LIMIT not set, using %s memory limit of %s", source, formatBytes(memLimit))
 	}
 
-	memLimit, err := strconv.ParseInt(memLimitStr, 10, 64)
-	if err != nil {
-		logging.Warn("Failed to parse MEMORY_LIMIT %q: %v", memLimitStr, err)
-		result.Source = sourceNone
+	result.ContainerLimit = memLimit
+
+	// A transcode reservation replaces the ratio when it's configured
+	if reserve := transcodeReserve(); reserve > 0 {
+		goMemLimit := memLimit - reserve
+		if floor := int64(float64(memLimit) * minReservedHeapRatio); goMemLimit < floor {
+			logging.Warn("Transcode reservation of %s leaves too little of the %s limit, keeping %.0f%% for the Go heap",
+				formatBytes(reserve), formatBytes(memLimit), minReservedHeapRatio*100)
+			goMemLimit = floor
+		}
+
+		debug.SetMemoryLimit(goMemLimit)
+
+		result.Configured = true
+		result.Source = source
+		result.GoMemLimit = goMemLimit
+		result.TranscodeReserve = memLimit - goMemLimit
+
+		logging.Info("Configured GOMEMLIMIT: %s (%s container limit less %s reserved for transcodes)",
+			formatBytes(goMemLimit),
+			formatBytes(memLimit),
+			formatBytes(result.TranscodeReserve),
+		)
 		return result
 	}
 
-	result.ContainerLimit = memLimit
-
 	// Allow customizing the ratio via environment variable
 	ratio := DefaultMemoryRatio
 	if ratioStr := os.Getenv("MEMORY_RATIO"); ratioStr != "" {
@@ -100,7 +160,7 @@ func ConfigureFromEnv() ConfigResult {
 	debug.SetMemoryLimit(goMemLimit)
 
 	result.Configured = true
-	result.Source = sourceMEMORYLIMIT
+	result.Source = source
 	result.GoMemLimit = goMemLimit
 
 	logging.Info("Configured GOMEMLIMIT: %s (%.1f%% of %s container limit)",
@@ -112,6 +172,86 @@ func ConfigureFromEnv() ConfigResult {
 	return result
 }
 
+// transcodeReserve returns the memory to reserve for concurrent transcodes
+// from MEMORY_RESERVE_TRANSCODES and MEMORY_TRANSCODE_BYTES, or 0 when no
+// transcodes are to be reserved for and MEMORY_RATIO applies instead
+func transcodeReserve() int64 {
+	countStr := os.Getenv("MEMORY_RESERVE_TRANSCODES")
+	if countStr == "" {
+		return 0
+	}
+	count, err := strconv.ParseInt(countStr, 10, 64)
+	if err != nil || count < 0 {
+		logging.Warn("Invalid MEMORY_RESERVE_TRANSCODES %q, using MEMORY_RATIO", countStr)
+		return 0
+	}
+
+	perTranscode := int64(DefaultTranscodeBytes)
+	if bytesStr := os.Getenv("MEMORY_TRANSCODE_BYTES"); bytesStr != "" {
+		parsed, err := strconv.ParseInt(bytesStr, 10, 64)
+		if err != nil || parsed <= 0 {
+			logging.Warn("Invalid MEMORY_TRANSCODE_BYTES %q, using default %s", bytesStr, formatBytes(DefaultTranscodeBytes))
+		} else {
+			perTranscode = parsed
+		}
+	}
+
+	if count > math.MaxInt64/perTranscode {
+		return math.MaxInt64
+	}
+	return count * perTranscode
+}
+
+// cgroupMemoryLimit returns the memory limit of the cgroup this process runs
+// in and the cgroup version it was read from. It returns 0 when no cgroup limit
+// can be read or the limit is unbounded.
+func cgroupMemoryLimit() (int64, string) {
+	files := []struct {
+		path   string
+		source string
+	}{
+		{filepath.Join(cgroupRoot, "memory.max"), sourceCgroupV2},
+		{filepath.Join(cgroupRoot, "memory", "memory.limit_in_bytes"), sourceCgroupV1},
+	}
+
+	for _, f := range files {
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			continue
+		}
+
+		value := strings.TrimSpace(string(data))
+		if value == "max" {
+			logging.Debug("%s memory limit is unbounded", f.source)
+			return 0, sourceNone
+		}
+
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			logging.Warn("Failed to parse %s memory limit %q: %v", f.source, value, err)
+			return 0, sourceNone
+		}
+		if limit <= 0 || limit >= cgroupUnbounded {
+			logging.Debug("%s memory limit is unbounded", f.source)
+			return 0, sourceNone
+		}
+		return limit, f.source
+	}
+
+	return 0, sourceNone
+}
+
+// ReconfigureFromEnv re-applies MEMORY_LIMIT and MEMORY_RATIO at runtime, such as
+// after a configuration reload. Unlike ConfigureFromEnv, it removes a previously
+// applied Go memory limit when no limit is configured anymore.
+func ReconfigureFromEnv() ConfigResult {
+	result := ConfigureFromEnv()
+	if !result.Configured {
+		debug.SetMemoryLimit(math.MaxInt64)
+	}
+	return result
+}
+
 // formatBytes formats bytes into human-readable string
 func formatBytes(b int64) string {
 	const unit = 1024
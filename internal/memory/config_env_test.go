@@ -1,11 +1,22 @@
 package memory
 
 import (
+	"math"
 	"os"
+	"path/filepath"
 	"runtime/debug"
 	"testing"
 )
 
+// withCgroupRoot points cgroup limit lookups at dir for the duration of the
+// test, so the limits of the machine running the tests don't leak in.
+func withCgroupRoot(t *testing.T, dir string) {
+	t.Helper()
+	old := cgroupRoot
+	cgroupRoot = dir
+	t.Cleanup(func() { cgroupRoot = old })
+}
+
 func TestConfigureFromEnv_NoEnvironmentVariables(t *testing.T) {
 	// Clean environment
 	oldGoMemLimit := os.Getenv("GOMEMLIMIT")
@@ -20,6 +31,7 @@ func TestConfigureFromEnv_NoEnvironmentVariables(t *testing.T) {
 	os.Unsetenv("GOMEMLIMIT")
 	os.Unsetenv("MEMORY_LIMIT")
 	os.Unsetenv("MEMORY_RATIO")
+	withCgroupRoot(t, t.TempDir())
 
 	result := ConfigureFromEnv()
 
@@ -612,6 +624,7 @@ func TestConfigureFromEnv_EmptyStringVsUnset(t *testing.T) {
 			}()
 
 			os.Unsetenv("GOMEMLIMIT")
+			withCgroupRoot(t, t.TempDir())
 			if tt.setToEmpty {
 				os.Setenv("MEMORY_LIMIT", "")
 			} else {
@@ -673,3 +686,176 @@ func BenchmarkFormatBytes_LargeValue(b *testing.B) {
 		_ = formatBytes(testBytes)
 	}
 }
+
+func TestReconfigureFromEnv_AppliesNewRatio(t *testing.T) {
+	oldLimit := debug.SetMemoryLimit(-1)
+	defer debug.SetMemoryLimit(oldLimit)
+
+	t.Setenv("GOMEMLIMIT", "")
+	t.Setenv("MEMORY_LIMIT", "1073741824") // 1GB
+	t.Setenv("MEMORY_RATIO", "0.5")
+
+	result := ReconfigureFromEnv()
+	if !result.Configured {
+		t.Fatal("Expected Configured to be true")
+	}
+	if got := debug.SetMemoryLimit(-1); got != 536870912 {
+		t.Errorf("Expected memory limit 536870912, got %d", got)
+	}
+
+	os.Setenv("MEMORY_RATIO", "0.75")
+	ReconfigureFromEnv()
+	if got := debug.SetMemoryLimit(-1); got != 805306368 {
+		t.Errorf("Expected memory limit 805306368 after ratio change, got %d", got)
+	}
+}
+
+func TestReconfigureFromEnv_RemovesLimit(t *testing.T) {
+	oldLimit := debug.SetMemoryLimit(-1)
+	defer debug.SetMemoryLimit(oldLimit)
+
+	t.Setenv("GOMEMLIMIT", "")
+	t.Setenv("MEMORY_RATIO", "")
+	t.Setenv("MEMORY_LIMIT", "1073741824")
+	withCgroupRoot(t, t.TempDir())
+	ReconfigureFromEnv()
+
+	os.Unsetenv("MEMORY_LIMIT")
+	result := ReconfigureFromEnv()
+	if result.Configured {
+		t.Error("Expected Configured to be false after MEMORY_LIMIT removed")
+	}
+	if got := debug.SetMemoryLimit(-1); got != math.MaxInt64 {
+		t.Errorf("Expected memory limit to be removed, got %d", got)
+	}
+}
+
+func TestConfigureFromEnv_CgroupFallback(t *testing.T) {
+	oldLimit := debug.SetMemoryLimit(-1)
+	defer debug.SetMemoryLimit(oldLimit)
+
+	t.Setenv("GOMEMLIMIT", "")
+	t.Setenv("MEMORY_LIMIT", "")
+	t.Setenv("MEMORY_RATIO", "0.5")
+
+	tests := []struct {
+		name      string
+		files     map[string]string
+		source    string
+		container int64
+	}{
+		{"v2", map[string]string{"memory.max": "1073741824\n"}, "cgroup-v2", 1073741824},
+		{"v2 unbounded", map[string]string{"memory.max": "max\n"}, "none", 0},
+		{"v1", map[string]string{"memory/memory.limit_in_bytes": "2147483648\n"}, "cgroup-v1", 2147483648},
+		{"v1 unbounded", map[string]string{"memory/memory.limit_in_bytes": "9223372036854771712\n"}, "none", 0},
+		{"v2 preferred", map[string]string{
+			"memory.max":                   "1073741824",
+			"memory/memory.limit_in_bytes": "2147483648",
+		}, "cgroup-v2", 1073741824},
+		{"invalid", map[string]string{"memory.max": "lots"}, "none", 0},
+		{"missing", nil, "none", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			withCgroupRoot(t, dir)
+
+			result := ConfigureFromEnv()
+			if result.Source != tt.source {
+				t.Errorf("Expected Source %q, got %q", tt.source, result.Source)
+			}
+			if result.Configured != (tt.container > 0) {
+				t.Errorf("Expected Configured to be %v, got %v", tt.container > 0, result.Configured)
+			}
+			if result.ContainerLimit != tt.container {
+				t.Errorf("Expected ContainerLimit %d, got %d", tt.container, result.ContainerLimit)
+			}
+			if result.GoMemLimit != tt.container/2 {
+				t.Errorf("Expected GoMemLimit %d, got %d", tt.container/2, result.GoMemLimit)
+			}
+		})
+	}
+}
+
+func TestConfigureFromEnv_MEMORYLIMITOverridesCgroup(t *testing.T) {
+	oldLimit := debug.SetMemoryLimit(-1)
+	defer debug.SetMemoryLimit(oldLimit)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte("2147483648"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	withCgroupRoot(t, dir)
+
+	t.Setenv("GOMEMLIMIT", "")
+	t.Setenv("MEMORY_LIMIT", "1073741824")
+	t.Setenv("MEMORY_RATIO", "")
+
+	result := ConfigureFromEnv()
+	if result.Source != "MEMORY_LIMIT" || result.ContainerLimit != 1073741824 {
+		t.Errorf("Expected MEMORY_LIMIT to take precedence, got %+v", result)
+	}
+}
+
+func TestConfigureFromEnv_TranscodeReserve(t *testing.T) {
+	oldLimit := debug.SetMemoryLimit(-1)
+	defer debug.SetMemoryLimit(oldLimit)
+
+	const gib = 1 << 30
+	tests := []struct {
+		name         string
+		transcodes   string
+		perTranscode string
+		wantLimit    int64
+		wantRatio    float64
+	}{
+		{"unset uses the ratio", "", "", int64(float64(2*gib) * 0.5), 0.5},
+		{"zero uses the ratio", "0", "", int64(float64(2*gib) * 0.5), 0.5},
+		{"invalid uses the ratio", "two", "", int64(float64(2*gib) * 0.5), 0.5},
+		{"default per transcode", "2", "", 2*gib - 2*DefaultTranscodeBytes, 0},
+		{"custom per transcode", "3", "268435456", 2*gib - 3*268435456, 0},
+		{"invalid per transcode", "1", "lots", 2*gib - DefaultTranscodeBytes, 0},
+		{"floor", "10", "", 2 * gib / 4, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOMEMLIMIT", "")
+			t.Setenv("MEMORY_LIMIT", "2147483648")
+			t.Setenv("MEMORY_RATIO", "0.5")
+			t.Setenv("MEMORY_RESERVE_TRANSCODES", tt.transcodes)
+			t.Setenv("MEMORY_TRANSCODE_BYTES", tt.perTranscode)
+
+			result := ConfigureFromEnv()
+			if !result.Configured {
+				t.Fatal("Expected Configured to be true")
+			}
+			if result.GoMemLimit != tt.wantLimit {
+				t.Errorf("Expected GoMemLimit %d, got %d", tt.wantLimit, result.GoMemLimit)
+			}
+			if got := debug.SetMemoryLimit(-1); got != tt.wantLimit {
+				t.Errorf("Expected memory limit %d, got %d", tt.wantLimit, got)
+			}
+			if result.Ratio != tt.wantRatio {
+				t.Errorf("Expected Ratio %v, got %v", tt.wantRatio, result.Ratio)
+			}
+			wantReserve := int64(0)
+			if tt.wantRatio == 0 {
+				wantReserve = 2*gib - tt.wantLimit
+			}
+			if result.TranscodeReserve != wantReserve {
+				t.Errorf("Expected TranscodeReserve %d, got %d", wantReserve, result.TranscodeReserve)
+			}
+		})
+	}
+}
@@ -32,13 +32,25 @@
 //
 //   - MEMORY_LIMIT: Container memory limit in bytes. Typically set via
 //     Kubernetes Downward API (see example below). This is the raw value
-//     from which GOMEMLIMIT is calculated.
+//     from which GOMEMLIMIT is calculated. When unset, the limit of the
+//     container's cgroup is read from /sys/fs/cgroup/memory.max (cgroup v2)
+//     or /sys/fs/cgroup/memory/memory.limit_in_bytes (cgroup v1), and
+//     unbounded cgroups leave GOMEMLIMIT unconfigured.
 //
 //   - MEMORY_RATIO: Percentage of MEMORY_LIMIT to use for Go heap, expressed
 //     as a decimal between 0.0 and 1.0. Default is 0.85 (85%). Lower this
 //     value if your application spawns memory-intensive subprocesses or
 //     uses significant CGO/mmap memory.
 //
+//   - MEMORY_RESERVE_TRANSCODES: Number of concurrent FFmpeg transcodes to
+//     reserve memory for. When set, GOMEMLIMIT is MEMORY_LIMIT less that many
+//     times MEMORY_TRANSCODE_BYTES, and MEMORY_RATIO is ignored, so the heap
+//     limit follows how many transcodes are expected rather than a guessed
+//     percentage. At least a quarter of the limit is always left to the heap.
+//
+//   - MEMORY_TRANSCODE_BYTES: Memory to reserve for each transcode, in bytes.
+//     Default is 256 MiB.
+//
 // # Kubernetes Configuration
 //
 // To pass the container memory limit to your application, use the Kubernetes
@@ -115,8 +127,19 @@
 //
 //   - Automatic pausing when memory exceeds critical threshold
 //   - Throttling signals when memory is under pressure
+//   - Callbacks when the pressure level changes
 //   - Periodic memory usage tracking
 //
+// Instead of polling, a worker pool can resize itself as pressure changes:
+//
+//	monitor.OnPressureChange(func(level memory.PressureLevel, usage float64) {
+//	    if level == memory.PressureNormal {
+//	        pool.SetWorkers(maxWorkers)
+//	    } else {
+//	        pool.SetWorkers(1)
+//	    }
+//	})
+//
 // # Example Usage
 //
 // Basic configuration:
@@ -5,6 +5,7 @@ import (
 	"runtime"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"media-viewer/internal/logging"
@@ -36,6 +37,37 @@ func DefaultConfig() Config {
 	}
 }
 
+// PressureLevel is how close memory usage is to the limit, derived from the
+// water marks of the monitor's Config
+type PressureLevel int
+
+const (
+	// PressureNormal is usage below the high water mark
+	PressureNormal PressureLevel = iota
+	// PressureThrottle is usage at or above the high water mark
+	PressureThrottle
+	// PressureCritical is usage at or above the critical water mark. Like
+	// the pause it goes with, it lasts until usage drops below the high
+	// water mark.
+	PressureCritical
+)
+
+// String returns the name of the level
+func (l PressureLevel) String() string {
+	switch l {
+	case PressureThrottle:
+		return "throttle"
+	case PressureCritical:
+		return "critical"
+	default:
+		return "normal"
+	}
+}
+
+// PressureCallback is called with the new pressure level and the usage
+// ratio (0.0-1.0+) of the check that changed it
+type PressureCallback func(level PressureLevel, usage float64)
+
 // Monitor tracks memory usage and provides backpressure signals
 type Monitor struct {
 	config    Config
@@ -44,7 +76,14 @@ type Monitor struct {
 	mu        sync.RWMutex
 	current   uint64
 	isPaused  bool
+	level     PressureLevel
 	pauseChan chan struct{}
+	started   atomic.Bool
+	looping   atomic.Bool
+
+	// Registered by OnPressureChange
+	callbacksMu sync.Mutex
+	callbacks   []PressureCallback
 }
 
 // NewMonitor creates a new memory monitor
@@ -73,11 +112,67 @@ func NewMonitor(config Config) *Monitor {
 
 // Start begins monitoring memory usage
 func (m *Monitor) Start() {
-	if m.limit == 0 {
+	m.started.Store(true)
+
+	m.mu.RLock()
+	limit := m.limit
+	m.mu.RUnlock()
+
+	if limit == 0 {
 		return // No limit configured, nothing to monitor
 	}
 
-	go m.monitorLoop()
+	m.startLoop()
+}
+
+// startLoop launches the monitor loop unless it is already running
+func (m *Monitor) startLoop() {
+	if m.looping.CompareAndSwap(false, true) {
+		go m.monitorLoop()
+	}
+}
+
+// SetLimit changes the memory limit used for backpressure, e.g. after a
+// configuration reload. A limit of 0 disables backpressure. If the monitor was
+// started without a limit, monitoring begins once one is set.
+func (m *Monitor) SetLimit(limit int64) {
+	if limit < 0 {
+		limit = 0
+	}
+
+	m.mu.Lock()
+	m.limit = limit
+	if limit == 0 && m.isPaused {
+		// Nothing to measure against anymore, so release anyone waiting
+		m.isPaused = false
+		metrics.MemoryPaused.Set(0)
+		close(m.pauseChan)
+		m.pauseChan = make(chan struct{})
+	}
+	m.mu.Unlock()
+
+	if limit > 0 && m.started.Load() {
+		m.startLoop()
+	}
+}
+
+// OnPressureChange registers fn to be called whenever the pressure level
+// changes, e.g. to resize a worker pool instead of polling WaitIfPaused. It
+// is called from the monitor's goroutine, without the monitor's locks held,
+// so it may call the monitor's other methods but should return quickly.
+// Callbacks can be registered at any time, including after Start; they see
+// the transitions that follow, and PressureLevel returns the current level.
+func (m *Monitor) OnPressureChange(fn PressureCallback) {
+	m.callbacksMu.Lock()
+	m.callbacks = append(m.callbacks, fn)
+	m.callbacksMu.Unlock()
+}
+
+// PressureLevel returns the pressure level of the last check
+func (m *Monitor) PressureLevel() PressureLevel {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.level
 }
 
 // Stop stops the memory monitor
@@ -107,8 +202,9 @@ func (m *Monitor) checkMemory() {
 	m.current = stats.Alloc
 	wasPaused := m.isPaused
 
+	var usage float64
 	if m.limit > 0 {
-		usage := float64(stats.Alloc) / float64(m.limit)
+		usage = float64(stats.Alloc) / float64(m.limit)
 
 		// Update usage ratio metric
 		metrics.MemoryUsageRatio.Set(usage)
@@ -131,11 +227,38 @@ func (m *Monitor) checkMemory() {
 			}
 		}
 	}
+
+	level := PressureNormal
+	switch {
+	case m.limit == 0:
+	case m.isPaused:
+		level = PressureCritical
+	case usage >= m.config.HighWaterMark:
+		level = PressureThrottle
+	}
+	levelChanged := level != m.level
+	m.level = level
 	m.mu.Unlock()
 
 	if m.isPaused != wasPaused {
 		logging.Debug("Memory state changed: paused=%v, alloc=%.1f MB", m.isPaused, float64(stats.Alloc)/(1024*1024))
 	}
+
+	if levelChanged {
+		m.notifyPressureChange(level, usage)
+	}
+}
+
+// notifyPressureChange calls the callbacks registered by OnPressureChange
+func (m *Monitor) notifyPressureChange(level PressureLevel, usage float64) {
+	m.callbacksMu.Lock()
+	callbacks := make([]PressureCallback, len(m.callbacks))
+	copy(callbacks, m.callbacks)
+	m.callbacksMu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(level, usage)
+	}
 }
 
 // WaitIfPaused blocks if memory usage is critical, returns when it's safe to proceed
@@ -159,13 +282,13 @@ func (m *Monitor) WaitIfPaused() bool {
 
 // ShouldThrottle returns true if memory usage is above the high water mark
 func (m *Monitor) ShouldThrottle() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	if m.limit == 0 {
 		return false
 	}
 
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
 	return float64(m.current) >= float64(m.limit)*m.config.HighWaterMark
 }
 
@@ -179,13 +302,13 @@ func (m *Monitor) IsPaused() bool {
 // GetUsage returns current memory usage as a percentage of the limit (0.0-1.0)
 // Returns 0 if no limit is configured
 func (m *Monitor) GetUsage() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	if m.limit == 0 {
 		return 0
 	}
 
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
 	return float64(m.current) / float64(m.limit)
 }
 
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"math"
 	"runtime"
 	"testing"
 	"time"
@@ -355,3 +356,175 @@ func TestMonitorConcurrency(_ *testing.T) {
 
 	monitor.Stop()
 }
+
+func TestMonitorSetLimit(t *testing.T) {
+	config := Config{
+		MemoryLimitBytes:  1024 * 1024 * 100, // 100 MB
+		HighWaterMark:     0.7,
+		CriticalWaterMark: 0.85,
+		CheckInterval:     50 * time.Millisecond,
+	}
+
+	monitor := NewMonitor(config)
+
+	monitor.SetLimit(1024 * 1024 * 200)
+	if _, limit, _ := monitor.GetStats(); limit != 1024*1024*200 {
+		t.Errorf("Expected limit %d after SetLimit, got %d", 1024*1024*200, limit)
+	}
+
+	monitor.SetLimit(-1)
+	if _, limit, _ := monitor.GetStats(); limit != 0 {
+		t.Errorf("Expected negative limit to clamp to 0, got %d", limit)
+	}
+	if monitor.ShouldThrottle() {
+		t.Error("ShouldThrottle should be false with no limit")
+	}
+}
+
+func TestMonitorSetLimitReleasesPause(t *testing.T) {
+	monitor := NewMonitor(Config{
+		MemoryLimitBytes:  1024 * 1024 * 100,
+		HighWaterMark:     0.7,
+		CriticalWaterMark: 0.85,
+		CheckInterval:     5 * time.Second,
+	})
+
+	monitor.mu.Lock()
+	monitor.isPaused = true
+	monitor.mu.Unlock()
+
+	done := make(chan bool)
+	go func() {
+		done <- monitor.WaitIfPaused()
+	}()
+
+	monitor.SetLimit(0)
+
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Error("WaitIfPaused should return true when released")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitIfPaused did not return after limit was removed")
+	}
+}
+
+func TestMonitorSetLimitStartsLoop(t *testing.T) {
+	monitor := NewMonitor(Config{
+		MemoryLimitBytes:  0,
+		HighWaterMark:     0.7,
+		CriticalWaterMark: 0.85,
+		CheckInterval:     10 * time.Millisecond,
+	})
+	// Force no limit even if GOMEMLIMIT is set in the environment
+	monitor.SetLimit(0)
+	monitor.Start()
+	defer monitor.Stop()
+
+	monitor.SetLimit(1 << 40)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if current, _, _ := monitor.GetStats(); current > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected monitor loop to start sampling after a limit was set")
+}
+
+func TestMonitorOnPressureChange(t *testing.T) {
+	monitor := NewMonitor(Config{
+		MemoryLimitBytes:  1 << 40,
+		HighWaterMark:     0.7,
+		CriticalWaterMark: 0.85,
+		CheckInterval:     5 * time.Second,
+	})
+
+	type change struct {
+		level PressureLevel
+		usage float64
+	}
+	var changes []change
+	monitor.OnPressureChange(func(level PressureLevel, usage float64) {
+		changes = append(changes, change{level, usage})
+	})
+
+	monitor.checkMemory()
+	if len(changes) != 0 || monitor.PressureLevel() != PressureNormal {
+		t.Fatalf("Expected no change while below the high water mark, got %v", changes)
+	}
+
+	// Above the high water mark, but never critical
+	monitor.config.CriticalWaterMark = math.MaxFloat64
+	monitor.SetLimit(1)
+	monitor.checkMemory()
+	monitor.checkMemory()
+	if len(changes) != 1 || changes[0].level != PressureThrottle || changes[0].usage < 1 {
+		t.Fatalf("Expected a single change to throttle, got %v", changes)
+	}
+
+	// Registered late, it sees the next transition
+	var late []PressureLevel
+	monitor.OnPressureChange(func(level PressureLevel, _ float64) {
+		late = append(late, level)
+	})
+
+	monitor.config.CriticalWaterMark = 0.85
+	monitor.checkMemory()
+	if len(changes) != 2 || changes[1].level != PressureCritical || !monitor.IsPaused() {
+		t.Fatalf("Expected a change to critical, got %v", changes)
+	}
+	if len(late) != 1 || late[0] != PressureCritical {
+		t.Errorf("Expected the late callback to see the change to critical, got %v", late)
+	}
+
+	monitor.SetLimit(1 << 40)
+	monitor.checkMemory()
+	if len(changes) != 3 || changes[2].level != PressureNormal || monitor.PressureLevel() != PressureNormal {
+		t.Errorf("Expected a change back to normal, got %v", changes)
+	}
+}
+
+func TestMonitorOnPressureChangeWhileRunning(t *testing.T) {
+	monitor := NewMonitor(Config{
+		MemoryLimitBytes:  1,
+		HighWaterMark:     0.7,
+		CriticalWaterMark: 0.85,
+		CheckInterval:     5 * time.Millisecond,
+	})
+
+	levels := make(chan PressureLevel, 1)
+	monitor.OnPressureChange(func(level PressureLevel, _ float64) {
+		levels <- level
+	})
+	monitor.Start()
+	defer monitor.Stop()
+
+	// Registering while the loop runs is safe
+	for range 10 {
+		go monitor.OnPressureChange(func(PressureLevel, float64) {})
+	}
+
+	select {
+	case level := <-levels:
+		if level != PressureCritical {
+			t.Errorf("Expected critical pressure with a 1 byte limit, got %v", level)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a callback once the monitor reached critical pressure")
+	}
+}
+
+func TestPressureLevelString(t *testing.T) {
+	for level, want := range map[PressureLevel]string{
+		PressureNormal:   "normal",
+		PressureThrottle: "throttle",
+		PressureCritical: "critical",
+	} {
+		if got := level.String(); got != want {
+			t.Errorf("PressureLevel(%d).String() = %q, want %q", level, got, want)
+		}
+	}
+}
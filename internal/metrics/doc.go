@@ -22,6 +22,8 @@
 //   - DBQueryDuration: Histogram of query duration by operation
 //   - DBConnectionsOpen: Gauge of open database connections
 //   - DBSizeBytes: Gauge of database file sizes (main, WAL, SHM)
+//   - DBCheckpointDuration: Histogram of manual WAL checkpoint duration
+//   - DBLastCheckpointTimestamp: Gauge of last completed manual WAL checkpoint time
 //
 // ## Indexer Metrics
 //
@@ -44,6 +46,10 @@
 //   - ThumbnailGenerationDuration: Histogram of generation time by type
 //   - ThumbnailCacheHits: Counter of cache hits
 //   - ThumbnailCacheMisses: Counter of cache misses
+//   - ThumbnailDedupeHits: Counter of thumbnails shared with a duplicate source
+//   - ThumbnailStaleServed: Counter of stale thumbnails served during background regeneration
+//   - ThumbnailFreshnessRegenerations: Counter of stale thumbnails regenerated by the freshness pass
+//   - ThumbnailCacheEvictions: Counter of thumbnails evicted to enforce the cache size limit
 //   - ThumbnailCacheSize: Gauge of cache size in bytes
 //   - ThumbnailCacheCount: Gauge of cached thumbnail count
 //   - ThumbnailGeneratorRunning: Gauge indicating if background generation is active
@@ -71,6 +77,7 @@
 //
 // Track authentication activity:
 //   - AuthAttemptsTotal: Counter by status (success/failure)
+//   - AuthRateLimitedTotal: Counter of requests refused by AUTH_RATE_LIMIT
 //   - ActiveSessions: Gauge of active user sessions
 //
 // ## Memory Metrics
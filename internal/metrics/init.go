@@ -1,5 +1,7 @@
 package metrics
 
+import "strconv"
+
 // InitializeMetrics pre-populates all expected label combinations so that
 // every metric is exported from the first Prometheus scrape.
 // Call this once at startup after metric registration.
@@ -38,6 +40,13 @@ func InitializeMetrics() {
 		ThumbnailImageDecodeByFormat.WithLabelValues(format)
 	}
 
+	// --- Thumbnail orientation corrections ---
+	for orientation := 2; orientation <= 8; orientation++ {
+		for _, result := range []string{"corrected", "uncorrected"} {
+			ThumbnailOrientationCorrections.WithLabelValues(strconv.Itoa(orientation), result)
+		}
+	}
+
 	// --- Thumbnail generation detailed phases ---
 	thumbTypes := []string{"image", "video", "folder"}
 	phases := []string{"decode", "resize", "encode", "cache"}
@@ -53,6 +62,7 @@ func InitializeMetrics() {
 		ThumbnailGenerationsTotal.WithLabelValues(t, "error_unsupported")
 		ThumbnailGenerationsTotal.WithLabelValues(t, "error_nil")
 		ThumbnailGenerationsTotal.WithLabelValues(t, "error_encode")
+		ThumbnailGenerationsTotal.WithLabelValues(t, "source_changed")
 	}
 
 	// --- Thumbnail FFmpeg duration ---
@@ -65,6 +65,21 @@ var (
 		},
 		[]string{"file"}, // "main", "wal", "shm"
 	)
+
+	DBCheckpointDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "media_viewer_db_checkpoint_duration_seconds",
+			Help:    "Duration of manual WAL checkpoints in seconds",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+	)
+
+	DBLastCheckpointTimestamp = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "media_viewer_db_last_checkpoint_timestamp",
+			Help: "Timestamp of the last completed manual WAL checkpoint",
+		},
+	)
 )
 
 // Database mmap and storage health metrics
@@ -192,6 +207,34 @@ var (
 		},
 	)
 
+	ThumbnailDedupeHits = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "media_viewer_thumbnail_dedupe_hits_total",
+			Help: "Total number of thumbnails reused from an identical source file instead of being generated",
+		},
+	)
+
+	ThumbnailStaleServed = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "media_viewer_thumbnail_stale_served_total",
+			Help: "Total number of stale thumbnails served while being regenerated in the background",
+		},
+	)
+
+	ThumbnailFreshnessRegenerations = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "media_viewer_thumbnail_freshness_regenerated_total",
+			Help: "Total number of stale thumbnails regenerated by the background freshness pass",
+		},
+	)
+
+	ThumbnailCacheEvictions = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "media_viewer_thumbnail_cache_evictions_total",
+			Help: "Total number of thumbnails evicted to keep the cache under its size limit",
+		},
+	)
+
 	ThumbnailCacheSize = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "media_viewer_thumbnail_cache_size_bytes",
@@ -312,6 +355,13 @@ var (
 		[]string{"status"},
 	)
 
+	AuthRateLimitedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "media_viewer_auth_rate_limited_total",
+			Help: "Total number of login and setup requests refused by the rate limit",
+		},
+	)
+
 	ActiveSessions = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "media_viewer_active_sessions",
@@ -574,6 +624,14 @@ var (
 		[]string{"format"}, // jpeg/png/gif/webp
 	)
 
+	ThumbnailOrientationCorrections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "media_viewer_thumbnail_orientation_total",
+			Help: "Image thumbnails whose EXIF orientation required rotating or flipping, by orientation and whether the decoded image was corrected",
+		},
+		[]string{"orientation", "result"}, // 2-8, corrected/uncorrected
+	)
+
 	ThumbnailBatchProcessingRate = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "media_viewer_thumbnail_batch_processing_rate",
@@ -32,6 +32,8 @@ func TestDatabaseMetricsExist(t *testing.T) {
 		{"DBQueryDuration", DBQueryDuration},
 		{"DBConnectionsOpen", DBConnectionsOpen},
 		{"DBSizeBytes", DBSizeBytes},
+		{"DBCheckpointDuration", DBCheckpointDuration},
+		{"DBLastCheckpointTimestamp", DBLastCheckpointTimestamp},
 	}
 
 	for _, tt := range tests {
@@ -75,6 +77,10 @@ func TestThumbnailMetricsExist(t *testing.T) {
 		{"ThumbnailGenerationDuration", ThumbnailGenerationDuration},
 		{"ThumbnailCacheHits", ThumbnailCacheHits},
 		{"ThumbnailCacheMisses", ThumbnailCacheMisses},
+		{"ThumbnailDedupeHits", ThumbnailDedupeHits},
+		{"ThumbnailStaleServed", ThumbnailStaleServed},
+		{"ThumbnailFreshnessRegenerations", ThumbnailFreshnessRegenerations},
+		{"ThumbnailCacheEvictions", ThumbnailCacheEvictions},
 		{"ThumbnailCacheSize", ThumbnailCacheSize},
 		{"ThumbnailCacheCount", ThumbnailCacheCount},
 		{"ThumbnailGeneratorRunning", ThumbnailGeneratorRunning},
@@ -466,6 +472,7 @@ func TestAuthenticationMetrics(t *testing.T) {
 		metric interface{}
 	}{
 		{"AuthAttemptsTotal", AuthAttemptsTotal},
+		{"AuthRateLimitedTotal", AuthRateLimitedTotal},
 		{"ActiveSessions", ActiveSessions},
 	}
 
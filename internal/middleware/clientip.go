@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustedProxies are the reverse proxies whose X-Real-IP and X-Forwarded-For
+// headers are believed. Anyone else could send any address in them.
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses a comma-separated list of IP addresses and CIDR
+// ranges. Entries that don't parse are reported in the error and left out;
+// the rest are still returned.
+func ParseTrustedProxies(value string) (TrustedProxies, error) {
+	var proxies TrustedProxies
+	var invalid []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		cidr := entry
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			proxies = append(proxies, network)
+			continue
+		}
+		invalid = append(invalid, entry)
+	}
+	if len(invalid) > 0 {
+		return proxies, fmt.Errorf("invalid addresses %q", invalid)
+	}
+	return proxies, nil
+}
+
+// trusts reports whether an address is one of the proxies
+func (p TrustedProxies) trusts(ip net.IP) bool {
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the IP address of the client that made a request. The
+// forwarding headers are only used when the request comes from a trusted
+// proxy. X-Real-IP is taken as is; of X-Forwarded-For, the entries trusted
+// proxies added are skipped from the right, so addresses the client put
+// there itself are never used.
+func (p TrustedProxies) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !p.trusts(ip) {
+		return host
+	}
+
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+		return xri
+	}
+
+	xff := r.Header.Values("X-Forwarded-For")
+	entries := strings.Split(strings.Join(xff, ","), ",")
+	client := host
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := strings.TrimSpace(entries[i])
+		if entry == "" {
+			continue
+		}
+		client = entry
+		if ip := net.ParseIP(entry); ip == nil || !p.trusts(ip) {
+			break
+		}
+	}
+	return client
+}
@@ -2,19 +2,25 @@ package middleware
 
 import (
 	"compress/gzip"
+	"compress/zlib"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/andybalholm/brotli"
 )
 
 // CompressionConfig holds configuration for the compression middleware
 type CompressionConfig struct {
 	// MinSize is the minimum response size in bytes before compression is applied
 	MinSize int
-	// Level is the gzip compression level (gzip.BestSpeed to gzip.BestCompression)
+	// Level is the gzip and deflate compression level (gzip.BestSpeed to gzip.BestCompression)
 	Level int
+	// BrotliQuality is the Brotli compression quality (brotli.BestSpeed to brotli.BestCompression)
+	BrotliQuality int
 	// CompressibleTypes is a list of content types that should be compressed
 	CompressibleTypes []string
 }
@@ -22,8 +28,9 @@ type CompressionConfig struct {
 // DefaultCompressionConfig returns sensible defaults for compression
 func DefaultCompressionConfig() CompressionConfig {
 	return CompressionConfig{
-		MinSize: 1024, // 1KB minimum
-		Level:   gzip.DefaultCompression,
+		MinSize:       1024, // 1KB minimum
+		Level:         gzip.DefaultCompression,
+		BrotliQuality: brotli.DefaultCompression,
 		CompressibleTypes: []string{
 			"text/html",
 			"text/css",
@@ -41,18 +48,106 @@ func DefaultCompressionConfig() CompressionConfig {
 	}
 }
 
-// gzipWriterPool reduces allocations by reusing gzip writers
-var gzipWriterPool = sync.Pool{
-	New: func() interface{} {
-		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
-		return w
-	},
+// Content codings the middleware applies, in order of preference when a
+// client accepts several equally
+const (
+	encodingBrotli  = "br"
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+var supportedEncodings = []string{encodingBrotli, encodingGzip, encodingDeflate}
+
+// encoder is a streaming compressor for one content coding
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
 }
 
-// gzipResponseWriter wraps http.ResponseWriter to provide gzip compression
-type gzipResponseWriter struct {
+// newEncoderPools returns a pool of encoders for each supported content
+// coding, set up with the levels in config. Pooling reduces allocations by
+// reusing encoders across responses. An invalid level falls back to the
+// default.
+func newEncoderPools(config CompressionConfig) map[string]*sync.Pool {
+	level := config.Level
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		log.Printf("invalid compression level %d, using the default", level)
+		level = gzip.DefaultCompression
+	}
+	quality := config.BrotliQuality
+	if quality < brotli.BestSpeed || quality > brotli.BestCompression {
+		log.Printf("invalid Brotli quality %d, using the default", quality)
+		quality = brotli.DefaultCompression
+	}
+
+	return map[string]*sync.Pool{
+		encodingBrotli: {New: func() interface{} {
+			return brotli.NewWriterLevel(io.Discard, quality)
+		}},
+		encodingGzip: {New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(io.Discard, level)
+			return w
+		}},
+		// HTTP's deflate coding is the zlib format, not raw deflate
+		encodingDeflate: {New: func() interface{} {
+			w, _ := zlib.NewWriterLevel(io.Discard, level)
+			return w
+		}},
+	}
+}
+
+// negotiateEncoding picks the content coding for a response from a request's
+// Accept-Encoding header: the supported one with the highest q-value,
+// preferring br, then gzip, then deflate on ties. It returns "" if the client
+// accepts none of them.
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	wildcard := 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(param, "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		if name == "*" {
+			wildcard = q
+		} else {
+			qualities[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range supportedEncodings {
+		q, listed := qualities[encoding]
+		if !listed {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressResponseWriter wraps http.ResponseWriter to compress responses
+// with one content coding
+type compressResponseWriter struct {
 	http.ResponseWriter
-	gzipWriter     *gzip.Writer
+	encoding       string
+	pool           *sync.Pool
+	encoder        encoder
 	config         CompressionConfig
 	buffer         []byte
 	statusCode     int
@@ -61,10 +156,13 @@ type gzipResponseWriter struct {
 	wroteBody      bool
 }
 
-// newGzipResponseWriter creates a new gzip response writer
-func newGzipResponseWriter(w http.ResponseWriter, config CompressionConfig) *gzipResponseWriter {
-	return &gzipResponseWriter{
+// newCompressResponseWriter creates a response writer compressing with
+// encoding, using encoders from pool
+func newCompressResponseWriter(w http.ResponseWriter, config CompressionConfig, encoding string, pool *sync.Pool) *compressResponseWriter {
+	return &compressResponseWriter{
 		ResponseWriter: w,
+		encoding:       encoding,
+		pool:           pool,
 		config:         config,
 		statusCode:     http.StatusOK,
 		buffer:         make([]byte, 0, config.MinSize+1),
@@ -72,7 +170,7 @@ func newGzipResponseWriter(w http.ResponseWriter, config CompressionConfig) *gzi
 }
 
 // WriteHeader captures the status code
-func (g *gzipResponseWriter) WriteHeader(statusCode int) {
+func (g *compressResponseWriter) WriteHeader(statusCode int) {
 	if g.headerWritten {
 		return
 	}
@@ -80,11 +178,11 @@ func (g *gzipResponseWriter) WriteHeader(statusCode int) {
 }
 
 // Write buffers data until we know if we should compress
-func (g *gzipResponseWriter) Write(data []byte) (int, error) {
+func (g *compressResponseWriter) Write(data []byte) (int, error) {
 	if g.wroteBody && g.headerWritten {
 		// Already decided and writing
-		if g.shouldCompress && g.gzipWriter != nil {
-			return g.gzipWriter.Write(data)
+		if g.shouldCompress && g.encoder != nil {
+			return g.encoder.Write(data)
 		}
 		return g.ResponseWriter.Write(data)
 	}
@@ -101,7 +199,7 @@ func (g *gzipResponseWriter) Write(data []byte) (int, error) {
 }
 
 // shouldCompressContentType checks if the content type should be compressed
-func (g *gzipResponseWriter) shouldCompressContentType() bool {
+func (g *compressResponseWriter) shouldCompressContentType() bool {
 	contentType := g.Header().Get("Content-Type")
 	if contentType == "" {
 		return false
@@ -119,8 +217,20 @@ func (g *gzipResponseWriter) shouldCompressContentType() bool {
 	return false
 }
 
+// isEncoded reports whether the handler already encoded the response, such
+// as a file served precompressed
+func (g *compressResponseWriter) isEncoded() bool {
+	return g.Header().Get("Content-Encoding") != ""
+}
+
+// isPartialContent reports whether the handler is serving a byte range.
+// Compressing a range would make Content-Range describe bytes the client never receives.
+func (g *compressResponseWriter) isPartialContent() bool {
+	return g.statusCode == http.StatusPartialContent || g.Header().Get("Content-Range") != ""
+}
+
 // finalize decides whether to compress and writes the buffered data
-func (g *gzipResponseWriter) finalize() {
+func (g *compressResponseWriter) finalize() {
 	if g.headerWritten {
 		return
 	}
@@ -129,42 +239,39 @@ func (g *gzipResponseWriter) finalize() {
 	g.wroteBody = true
 
 	// Decide if we should compress
-	g.shouldCompress = len(g.buffer) >= g.config.MinSize && g.shouldCompressContentType()
+	g.shouldCompress = len(g.buffer) >= g.config.MinSize && !g.isPartialContent() && !g.isEncoded() && g.shouldCompressContentType()
 
 	if g.shouldCompress {
 		// Remove Content-Length as it will change
 		g.Header().Del("Content-Length")
+		// Byte ranges would refer to the uncompressed body, so stop advertising them
+		g.Header().Del("Accept-Ranges")
 		// Set compression headers
-		g.Header().Set("Content-Encoding", "gzip")
-		g.Header().Add("Vary", "Accept-Encoding")
+		g.Header().Set("Content-Encoding", g.encoding)
+		addVary(g.Header(), "Accept-Encoding")
 
-		// Get a gzip writer from the pool
-		writer, ok := gzipWriterPool.Get().(*gzip.Writer)
+		// Get an encoder from the pool
+		writer, ok := g.pool.Get().(encoder)
 		if !ok {
-			// Fallback: create new writer if type assertion fails
-			var err error
-			writer, err = gzip.NewWriterLevel(g.ResponseWriter, g.config.Level)
-			if err != nil {
-				// If we can't create a gzip writer, write uncompressed
-				log.Printf("failed to create gzip writer: %v", err)
-				g.shouldCompress = false
-				g.ResponseWriter.WriteHeader(g.statusCode)
-				if _, err := g.ResponseWriter.Write(g.buffer); err != nil {
-					log.Printf("failed to write uncompressed response: %v", err)
-				}
-				g.buffer = nil
-				return
+			// If there's no encoder, write uncompressed
+			log.Printf("failed to get %s encoder", g.encoding)
+			g.Header().Del("Content-Encoding")
+			g.shouldCompress = false
+			g.ResponseWriter.WriteHeader(g.statusCode)
+			if _, err := g.ResponseWriter.Write(g.buffer); err != nil {
+				log.Printf("failed to write uncompressed response: %v", err)
 			}
-		} else {
-			writer.Reset(g.ResponseWriter)
+			g.buffer = nil
+			return
 		}
-		g.gzipWriter = writer
+		writer.Reset(g.ResponseWriter)
+		g.encoder = writer
 
 		// Write the status code
 		g.ResponseWriter.WriteHeader(g.statusCode)
 
 		// Write buffered data
-		if _, err := g.gzipWriter.Write(g.buffer); err != nil {
+		if _, err := g.encoder.Write(g.buffer); err != nil {
 			log.Printf("failed to write compressed data: %v", err)
 		}
 	} else {
@@ -179,18 +286,30 @@ func (g *gzipResponseWriter) finalize() {
 	g.buffer = nil
 }
 
-// Close finalizes the response and returns the gzip writer to the pool
-func (g *gzipResponseWriter) Close() error {
+// addVary adds value to the Vary header unless it's already listed
+func addVary(header http.Header, value string) {
+	for _, existing := range header.Values("Vary") {
+		for _, field := range strings.Split(existing, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), value) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", value)
+}
+
+// Close finalizes the response and returns the encoder to the pool
+func (g *compressResponseWriter) Close() error {
 	// If we haven't written yet, finalize now
 	if !g.headerWritten {
 		g.finalize()
 	}
 
-	// Close the gzip writer and return it to the pool
-	if g.gzipWriter != nil {
-		err := g.gzipWriter.Close()
-		gzipWriterPool.Put(g.gzipWriter)
-		g.gzipWriter = nil
+	// Close the encoder and return it to the pool
+	if g.encoder != nil {
+		err := g.encoder.Close()
+		g.pool.Put(g.encoder)
+		g.encoder = nil
 		return err
 	}
 
@@ -198,16 +317,16 @@ func (g *gzipResponseWriter) Close() error {
 }
 
 // Flush implements http.Flusher
-func (g *gzipResponseWriter) Flush() {
+func (g *compressResponseWriter) Flush() {
 	// Finalize if we haven't yet
 	if !g.headerWritten {
 		g.finalize()
 	}
 
-	// Flush the gzip writer
-	if g.gzipWriter != nil {
-		if err := g.gzipWriter.Flush(); err != nil {
-			log.Printf("failed to flush gzip writer: %v", err)
+	// Flush the encoder
+	if g.encoder != nil {
+		if err := g.encoder.Flush(); err != nil {
+			log.Printf("failed to flush %s encoder: %v", g.encoding, err)
 		}
 	}
 
@@ -218,19 +337,23 @@ func (g *gzipResponseWriter) Flush() {
 }
 
 // Push implements http.Pusher for HTTP/2 support
-func (g *gzipResponseWriter) Push(target string, opts *http.PushOptions) error {
+func (g *compressResponseWriter) Push(target string, opts *http.PushOptions) error {
 	if pusher, ok := g.ResponseWriter.(http.Pusher); ok {
 		return pusher.Push(target, opts)
 	}
 	return http.ErrNotSupported
 }
 
-// Compression returns a middleware that compresses responses using gzip
+// Compression returns a middleware that compresses responses with Brotli,
+// gzip or deflate, whichever the client prefers
 func Compression(config CompressionConfig) func(http.Handler) http.Handler {
+	pools := newEncoderPools(config)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Check if client accepts gzip encoding
-			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			// Check which encoding the client accepts, if any
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -247,16 +370,22 @@ func Compression(config CompressionConfig) func(http.Handler) http.Handler {
 				return
 			}
 
-			// Create gzip response writer
-			gzw := newGzipResponseWriter(w, config)
+			// Skip compression for byte-range requests (video seeking, resumed downloads)
+			if r.Header.Get("Range") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Create compressing response writer
+			cw := newCompressResponseWriter(w, config, encoding, pools[encoding])
 			defer func() {
-				if err := gzw.Close(); err != nil {
-					log.Printf("failed to close gzip response writer: %v", err)
+				if err := cw.Close(); err != nil {
+					log.Printf("failed to close %s response writer: %v", encoding, err)
 				}
 			}()
 
 			// Call the next handler
-			next.ServeHTTP(gzw, r)
+			next.ServeHTTP(cw, r)
 		})
 	}
 }
@@ -1,7 +1,9 @@
 // Package middleware provides HTTP middleware for the media viewer application.
 //
 // It includes:
-//   - Request logging in W3C Extended Log Format
-//   - Response compression (gzip, deflate)
+//   - Request logging in W3C Extended Log Format, or as JSON lines
+//   - Response compression (Brotli, gzip, deflate)
+//   - Request timeouts for non-streaming routes
+//   - Per-client rate limiting of the login and setup endpoints
 //   - Configurable filtering for static files and health checks
 package middleware
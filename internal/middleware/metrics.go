@@ -140,6 +140,8 @@ func normalizePath(path string) string {
 		"/api/thumbnail/",
 		"/api/stream/",
 		"/api/stream-info/",
+		"/api/hls/",
+		"/api/thumbnail-sizes/",
 		"/api/playlist/",
 		"/js/",
 		"/css/",
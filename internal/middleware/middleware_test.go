@@ -3,12 +3,21 @@ package middleware
 import (
 	"bytes"
 	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
 	"io"
+	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/andybalholm/brotli"
 )
 
 func TestNewResponseWriter(t *testing.T) {
@@ -179,6 +188,10 @@ func TestDefaultCompressionConfig(t *testing.T) {
 		t.Errorf("Expected Level to be DefaultCompression (%d), got %d", gzip.DefaultCompression, config.Level)
 	}
 
+	if config.BrotliQuality != brotli.DefaultCompression {
+		t.Errorf("Expected BrotliQuality to be DefaultCompression (%d), got %d", brotli.DefaultCompression, config.BrotliQuality)
+	}
+
 	if len(config.CompressibleTypes) == 0 {
 		t.Error("Expected CompressibleTypes to have default values")
 	}
@@ -314,7 +327,7 @@ func TestCompressionMiddleware(t *testing.T) {
 func TestGzipResponseWriterBuffering(t *testing.T) {
 	w := httptest.NewRecorder()
 	config := DefaultCompressionConfig()
-	grw := newGzipResponseWriter(w, config)
+	grw := newCompressResponseWriter(w, config, encodingGzip, newEncoderPools(config)[encodingGzip])
 
 	// Write small amount of data (less than MinSize)
 	smallData := []byte("small")
@@ -369,6 +382,426 @@ func TestCompressionWithMultipleWrites(t *testing.T) {
 	}
 }
 
+func TestCompressionSkipsRangeRequests(t *testing.T) {
+	body := strings.Repeat("Hello, World! ", 200)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+	})
+
+	tests := []struct {
+		name              string
+		rangeHeader       string
+		expectCompression bool
+	}{
+		{name: "Range request passes through", rangeHeader: "bytes=0-99", expectCompression: false},
+		{name: "Full request compressed", rangeHeader: "", expectCompression: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrappedHandler := Compression(DefaultCompressionConfig())(handler)
+
+			req := httptest.NewRequest("GET", "/test", http.NoBody)
+			req.Header.Set("Accept-Encoding", "gzip")
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+			w := httptest.NewRecorder()
+
+			wrappedHandler.ServeHTTP(w, req)
+
+			isCompressed := w.Header().Get("Content-Encoding") == "gzip"
+			if isCompressed != tt.expectCompression {
+				t.Errorf("Expected compression=%v, got compression=%v", tt.expectCompression, isCompressed)
+			}
+			if isCompressed && w.Header().Get("Accept-Ranges") != "" {
+				t.Error("Expected Accept-Ranges to be removed from compressed response")
+			}
+			if !isCompressed && w.Body.String() != body {
+				t.Error("Expected uncompressed body to pass through unchanged")
+			}
+		})
+	}
+}
+
+func TestCompressionSkipsPartialContentResponses(t *testing.T) {
+	body := strings.Repeat("a", 2048)
+
+	tests := []struct {
+		name   string
+		status int
+		header string
+	}{
+		{name: "206 status", status: http.StatusPartialContent},
+		{name: "Content-Range header", status: http.StatusOK, header: "bytes 0-2047/4096"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				if tt.header != "" {
+					w.Header().Set("Content-Range", tt.header)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(body))
+			})
+
+			// Partial responses must not be gzipped even without a Range header
+			wrappedHandler := Compression(DefaultCompressionConfig())(handler)
+
+			req := httptest.NewRequest("GET", "/test", http.NoBody)
+			req.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+
+			wrappedHandler.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			if w.Header().Get("Content-Encoding") != "" {
+				t.Error("Expected partial content response to be uncompressed")
+			}
+			if w.Body.String() != body {
+				t.Error("Expected body to pass through unchanged")
+			}
+		})
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip, deflate, br", "br"},
+		{"deflate, gzip", "gzip"},
+		{"BR;q=1.0, gzip", "br"},
+		{"br;q=0.5, gzip;q=0.8", "gzip"},
+		{"br;q=0, gzip", "gzip"},
+		{"*", "br"},
+		{"*;q=0.5, br;q=0", "gzip"},
+		{"gzip;q=0, *;q=0", ""},
+		{"zstd", ""},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.acceptEncoding); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.acceptEncoding, got, tt.want)
+		}
+	}
+}
+
+func TestCompressionEncodings(t *testing.T) {
+	body := strings.Repeat(`{"name":"beach.jpg","type":"image"},`, 100)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Header().Set("Vary", "accept-encoding")
+		w.Write([]byte(body))
+	})
+	wrappedHandler := Compression(DefaultCompressionConfig())(handler)
+
+	tests := []struct {
+		encoding string
+		decode   func(io.Reader) (io.Reader, error)
+	}{
+		{"br", func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }},
+		{"gzip", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"deflate", func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/files", http.NoBody)
+			req.Header.Set("Accept-Encoding", tt.encoding)
+			w := httptest.NewRecorder()
+
+			wrappedHandler.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Fatalf("Expected Content-Encoding %q, got %q", tt.encoding, got)
+			}
+			if w.Header().Get("Content-Length") != "" {
+				t.Error("Expected Content-Length to be removed")
+			}
+			if vary := w.Header().Values("Vary"); len(vary) != 1 {
+				t.Errorf("Expected Accept-Encoding once in Vary, got %v", vary)
+			}
+			if w.Body.Len() >= len(body) {
+				t.Errorf("Expected a compressed body, got %d bytes from %d", w.Body.Len(), len(body))
+			}
+
+			reader, err := tt.decode(w.Body)
+			if err != nil {
+				t.Fatalf("Failed to create %s reader: %v", tt.encoding, err)
+			}
+			decompressed, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("Failed to decompress: %v", err)
+			}
+			if string(decompressed) != body {
+				t.Error("Decompressed content doesn't match original")
+			}
+		})
+	}
+}
+
+func TestCompressionSkipsEncodedResponses(t *testing.T) {
+	body := strings.Repeat("precompressed ", 200)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write([]byte(body))
+	})
+
+	req := httptest.NewRequest("GET", "/app.js", http.NoBody)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	w := httptest.NewRecorder()
+
+	Compression(DefaultCompressionConfig())(handler).ServeHTTP(w, req)
+
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("Expected the handler's Content-Encoding to be kept, got %q", got)
+	}
+	if w.Body.String() != body {
+		t.Error("Expected an already encoded body to pass through unchanged")
+	}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	t.Run("fast handler", func(t *testing.T) {
+		handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Deadline(); !ok {
+				t.Error("Expected the request context to have a deadline")
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"ok":true}`))
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/files", http.NoBody))
+
+		if w.Code != http.StatusCreated || w.Body.String() != `{"ok":true}` || w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Expected the response passed through, got %d %q", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("stuck handler", func(t *testing.T) {
+		done := make(chan error, 1)
+		handler := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			w.Write([]byte("too late"))
+			done <- r.Context().Err()
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/search", http.NoBody))
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503, got %d", w.Code)
+		}
+		if strings.Contains(w.Body.String(), "too late") {
+			t.Error("Expected writes after the timeout to be discarded")
+		}
+		if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the handler's context to pass its deadline, got %v", err)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		handler := Timeout(0)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Deadline(); ok {
+				t.Error("Expected no deadline with the timeout disabled")
+			}
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/files", http.NoBody))
+	})
+}
+
+func TestRateLimiter(t *testing.T) {
+	newLimiter := func(t *testing.T, perMinute int) (*RateLimiter, *time.Time) {
+		t.Helper()
+		l := NewRateLimiter(perMinute, nil)
+		t.Cleanup(l.Stop)
+		now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		l.now = func() time.Time { return now }
+		return l, &now
+	}
+	request := func(handler http.HandlerFunc, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/auth/login", http.NoBody)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	t.Run("refuses requests over the limit", func(t *testing.T) {
+		l, _ := newLimiter(t, 3)
+		handler := l.Limit(ok)
+
+		for i := 0; i < 3; i++ {
+			if w := request(handler, "203.0.113.5:4000"); w.Code != http.StatusOK {
+				t.Fatalf("Request %d: expected 200, got %d", i+1, w.Code)
+			}
+		}
+
+		w := request(handler, "203.0.113.5:4001")
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected 429 once the limit is used up, got %d", w.Code)
+		}
+		if got := w.Header().Get("Retry-After"); got != "20" {
+			t.Errorf("Retry-After = %q, want %q", got, "20")
+		}
+
+		if w := request(handler, "203.0.113.6:4000"); w.Code != http.StatusOK {
+			t.Errorf("Expected another client to be unaffected, got %d", w.Code)
+		}
+	})
+
+	t.Run("refills over time", func(t *testing.T) {
+		l, now := newLimiter(t, 2)
+		handler := l.Limit(ok)
+
+		request(handler, "203.0.113.5:4000")
+		request(handler, "203.0.113.5:4000")
+		if w := request(handler, "203.0.113.5:4000"); w.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected 429, got %d", w.Code)
+		}
+
+		*now = now.Add(30 * time.Second)
+		if w := request(handler, "203.0.113.5:4000"); w.Code != http.StatusOK {
+			t.Errorf("Expected a request to be allowed after half a minute, got %d", w.Code)
+		}
+		if w := request(handler, "203.0.113.5:4000"); w.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected only one token to have refilled, got %d", w.Code)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		l, _ := newLimiter(t, 0)
+		handler := l.Limit(ok)
+
+		for i := 0; i < 100; i++ {
+			if w := request(handler, "203.0.113.5:4000"); w.Code != http.StatusOK {
+				t.Fatalf("Request %d: expected 200 with the limit disabled, got %d", i+1, w.Code)
+			}
+		}
+	})
+
+	t.Run("set limit resets buckets", func(t *testing.T) {
+		l, _ := newLimiter(t, 1)
+		handler := l.Limit(ok)
+
+		request(handler, "203.0.113.5:4000")
+		if w := request(handler, "203.0.113.5:4000"); w.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected 429, got %d", w.Code)
+		}
+
+		l.SetLimit(5)
+		if w := request(handler, "203.0.113.5:4000"); w.Code != http.StatusOK {
+			t.Errorf("Expected the new limit to apply, got %d", w.Code)
+		}
+	})
+
+	t.Run("cleanup drops idle buckets", func(t *testing.T) {
+		l, now := newLimiter(t, 5)
+		handler := l.Limit(ok)
+
+		request(handler, "203.0.113.5:4000")
+		*now = now.Add(30 * time.Second)
+		request(handler, "203.0.113.6:4000")
+
+		*now = now.Add(45 * time.Second)
+		l.cleanup()
+
+		if _, ok := l.buckets["203.0.113.5"]; ok {
+			t.Error("Expected the idle bucket to be dropped")
+		}
+		if _, ok := l.buckets["203.0.113.6"]; !ok {
+			t.Error("Expected the recently used bucket to be kept")
+		}
+	})
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies(" 10.0.0.0/8, 192.0.2.7 ,::1,, fd00::/8")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+	for _, addr := range []string{"10.1.2.3", "192.0.2.7", "::1", "fd00::5"} {
+		if !proxies.trusts(net.ParseIP(addr)) {
+			t.Errorf("expected %s to be trusted", addr)
+		}
+	}
+	for _, addr := range []string{"11.0.0.1", "192.0.2.8", "::2"} {
+		if proxies.trusts(net.ParseIP(addr)) {
+			t.Errorf("expected %s not to be trusted", addr)
+		}
+	}
+
+	proxies, err = ParseTrustedProxies("10.0.0.0/8,proxy.local,300.0.0.1")
+	if err == nil {
+		t.Error("expected an error for invalid entries")
+	}
+	if len(proxies) != 1 {
+		t.Errorf("expected the valid entry to be kept, got %v", proxies)
+	}
+
+	if proxies, err := ParseTrustedProxies(""); err != nil || len(proxies) != 0 {
+		t.Errorf("ParseTrustedProxies(\"\") = %v, %v, want none", proxies, err)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("127.0.0.0/8,10.0.0.0/8,192.168.0.0/16,::1")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		proxies    TrustedProxies
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"direct client", proxies, "203.0.113.5:4000", nil, "203.0.113.5"},
+		{"headers from an untrusted client ignored", proxies, "203.0.113.5:4000", map[string]string{"X-Real-IP": "198.51.100.1", "X-Forwarded-For": "198.51.100.2"}, "203.0.113.5"},
+		{"X-Real-IP from a proxy", proxies, "10.0.0.2:4000", map[string]string{"X-Real-IP": "198.51.100.1"}, "198.51.100.1"},
+		{"last X-Forwarded-For entry from a proxy", proxies, "127.0.0.1:4000", map[string]string{"X-Forwarded-For": "192.0.2.9, 198.51.100.2"}, "198.51.100.2"},
+		{"trusted X-Forwarded-For hops skipped", proxies, "127.0.0.1:4000", map[string]string{"X-Forwarded-For": "192.0.2.9, 198.51.100.2, 10.0.0.3"}, "198.51.100.2"},
+		{"all X-Forwarded-For hops trusted", proxies, "127.0.0.1:4000", map[string]string{"X-Forwarded-For": "10.0.0.4, 10.0.0.3"}, "10.0.0.4"},
+		{"proxy without headers", proxies, "192.168.1.10:4000", nil, "192.168.1.10"},
+		{"IPv6 proxy", proxies, "[::1]:4000", map[string]string{"X-Forwarded-For": "2001:db8::1"}, "2001:db8::1"},
+		{"IPv6 client", proxies, "[2001:db8::1]:4000", nil, "2001:db8::1"},
+		{"no trusted proxies", nil, "127.0.0.1:4000", map[string]string{"X-Real-IP": "198.51.100.1"}, "127.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/auth/login", http.NoBody)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := tt.proxies.clientIP(req); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func BenchmarkLoggingMiddleware(b *testing.B) {
 	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -775,6 +1208,11 @@ func TestNormalizePath(t *testing.T) {
 			path:     "/api/stream-info/videos/clip.webm",
 			expected: "/api/stream-info/{path}",
 		},
+		{
+			name:     "API HLS segment path",
+			path:     "/api/hls/videos/clip.mkv/segment_00003.m4s",
+			expected: "/api/hls/{path}",
+		},
 		{
 			name:     "API playlist path",
 			path:     "/api/playlist/12345",
@@ -1283,6 +1721,104 @@ func TestSanitizeLogField_Idempotent(t *testing.T) {
 	}
 }
 
+func TestParseLogFormat(t *testing.T) {
+	tests := map[string]LogFormat{"": LogFormatW3C, "w3c": LogFormatW3C, " JSON ": LogFormatJSON}
+	for value, want := range tests {
+		got, err := ParseLogFormat(value)
+		if err != nil || got != want {
+			t.Errorf("ParseLogFormat(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if format, err := ParseLogFormat("xml"); err == nil || format != LogFormatW3C {
+		t.Errorf("Expected an error and the W3C format for an unknown format, got %q, %v", format, err)
+	}
+}
+
+// captureLog redirects the standard logger to a buffer for the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(previous) })
+	return &buf
+}
+
+func TestLoggerMiddleware_JSON(t *testing.T) {
+	buf := captureLog(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})
+	config := DefaultLoggingConfig()
+	config.Format = LogFormatJSON
+	wrappedHandler := Logger(config)(handler)
+
+	req := httptest.NewRequest("POST", "/api/tags/file?path=a.jpg", http.NoBody)
+	req.RemoteAddr = "192.0.2.7:51234"
+	req.Header.Set("User-Agent", "curl/8.5.0\nforged line")
+	wrappedHandler.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected one log line, got %d: %q", len(lines), buf.String())
+	}
+
+	var entry struct {
+		Time       string  `json:"time"`
+		Method     string  `json:"method"`
+		Path       string  `json:"path"`
+		Query      string  `json:"query"`
+		Status     int     `json:"status"`
+		DurationMS float64 `json:"duration_ms"`
+		Bytes      int64   `json:"bytes"`
+		RemoteAddr string  `json:"remote_addr"`
+		UserAgent  string  `json:"user_agent"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("Log line is not JSON: %v: %q", err, lines[0])
+	}
+
+	if _, err := time.Parse(time.RFC3339Nano, entry.Time); err != nil {
+		t.Errorf("Expected an RFC 3339 time, got %q", entry.Time)
+	}
+	if entry.Method != "POST" || entry.Path != "/api/tags/file" || entry.Query != "path=a.jpg" {
+		t.Errorf("Unexpected request fields: %+v", entry)
+	}
+	if entry.Status != http.StatusCreated || entry.Bytes != int64(len("created")) {
+		t.Errorf("Unexpected response fields: %+v", entry)
+	}
+	if entry.DurationMS < 0 {
+		t.Errorf("Expected a non-negative duration, got %v", entry.DurationMS)
+	}
+	if entry.RemoteAddr != "192.0.2.7" {
+		t.Errorf("Expected remote_addr 192.0.2.7, got %q", entry.RemoteAddr)
+	}
+	if entry.UserAgent != "curl/8.5.0\nforged line" {
+		t.Errorf("Expected the user agent to survive encoding, got %q", entry.UserAgent)
+	}
+}
+
+func TestLoggerMiddleware_JSONSkipsFiltered(t *testing.T) {
+	buf := captureLog(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	config := DefaultLoggingConfig()
+	config.Format = LogFormatJSON
+	config.LogHealthChecks = false
+	wrappedHandler := Logger(config)(handler)
+
+	for _, path := range []string{"/healthz", "/app.js"} {
+		wrappedHandler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, http.NoBody))
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected health checks and static files to be skipped, got %q", buf.String())
+	}
+}
+
 // =============================================================================
 // End-to-End Logging Sanitization Tests
 // =============================================================================
@@ -1375,12 +1911,18 @@ func TestLoggerMiddleware_SanitizesRequestPath(t *testing.T) {
 }
 
 // =============================================================================
-// getClientIP Sanitization Tests
+// clientIP Sanitization Tests
 // =============================================================================
 
-func TestGetClientIP_MaliciousHeaders(t *testing.T) {
+func TestClientIP_MaliciousHeaders(t *testing.T) {
 	t.Parallel()
 
+	// httptest requests come from 192.0.2.1
+	proxies, err := ParseTrustedProxies("192.0.2.1,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+
 	tests := []struct {
 		name           string
 		xForwardedFor  string
@@ -1424,9 +1966,9 @@ func TestGetClientIP_MaliciousHeaders(t *testing.T) {
 				req.RemoteAddr = tt.remoteAddr
 			}
 
-			ip := getClientIP(req)
+			ip := proxies.clientIP(req)
 			if !strings.Contains(ip, tt.expectedSubstr) {
-				t.Errorf("getClientIP() = %q, expected to contain %q", ip, tt.expectedSubstr)
+				t.Errorf("clientIP() = %q, expected to contain %q", ip, tt.expectedSubstr)
 			}
 		})
 	}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"media-viewer/internal/metrics"
+)
+
+// rateLimitCleanupInterval is how often buckets of idle clients are dropped
+const rateLimitCleanupInterval = time.Minute
+
+// rateLimitBucket is the token bucket of one client. It holds up to a
+// minute's worth of requests and refills continuously.
+type rateLimitBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter limits how many requests each client IP may make per minute,
+// with a token bucket per client so short bursts up to the limit are allowed.
+// Requests over the limit get 429 Too Many Requests with a Retry-After header.
+//
+// Buckets are kept in memory. Once a client has been idle long enough for its
+// bucket to refill, the bucket is dropped, so addresses seen once don't
+// accumulate.
+type RateLimiter struct {
+	mu        sync.Mutex
+	perMinute int
+	buckets   map[string]*rateLimitBucket
+	proxies   TrustedProxies
+	now       func() time.Time // Replaced in tests
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewRateLimiter creates a limiter allowing perMinute requests per client
+// IP, and starts dropping idle buckets in the background until Stop is
+// called. A perMinute of 0 or less lets every request through. Clients
+// behind one of proxies are told apart by its forwarding headers.
+func NewRateLimiter(perMinute int, proxies TrustedProxies) *RateLimiter {
+	l := &RateLimiter{
+		perMinute: perMinute,
+		buckets:   make(map[string]*rateLimitBucket),
+		proxies:   proxies,
+		now:       time.Now,
+		stop:      make(chan struct{}),
+	}
+	go l.cleanupLoop()
+	return l
+}
+
+// SetLimit changes the requests allowed per client IP per minute. Buckets
+// are reset, so clients start over with the new limit.
+func (l *RateLimiter) SetLimit(perMinute int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.perMinute = perMinute
+	l.buckets = make(map[string]*rateLimitBucket)
+}
+
+// Stop ends the background cleanup.
+func (l *RateLimiter) Stop() {
+	l.stopOnce.Do(func() { close(l.stop) })
+}
+
+// Limit wraps a handler so requests over the limit are refused.
+func (l *RateLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := l.proxies.clientIP(r)
+		wait, ok := l.allow(key)
+		if !ok {
+			metrics.AuthRateLimitedTotal.Inc()
+			log.Printf("Rate limit exceeded: %s %s from %s", r.Method, sanitizeLogField(r.URL.Path), sanitizeLogField(key))
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// allow takes a token from the client's bucket. If there is none, it
+// reports how long until there will be.
+func (l *RateLimiter) allow(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.perMinute <= 0 {
+		return 0, true
+	}
+
+	now := l.now()
+	limit := float64(l.perMinute)
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &rateLimitBucket{tokens: limit, last: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = min(limit, bucket.tokens+now.Sub(bucket.last).Minutes()*limit)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return time.Duration((1 - bucket.tokens) / limit * float64(time.Minute)), false
+	}
+	bucket.tokens--
+	return 0, true
+}
+
+// cleanupLoop drops idle buckets until Stop is called
+func (l *RateLimiter) cleanupLoop() {
+	ticker := time.NewTicker(rateLimitCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.cleanup()
+		}
+	}
+}
+
+// cleanup drops the buckets that have refilled since their last request.
+// A client coming back gets a full bucket either way.
+func (l *RateLimiter) cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.last) >= time.Minute {
+			delete(l.buckets, key)
+		}
+	}
+}
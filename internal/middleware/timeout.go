@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// timeoutMessage is the body of the 503 response sent when a request times out
+const timeoutMessage = "Request timed out"
+
+// Timeout returns a middleware that bounds how long a request may take. The
+// request context gets a deadline of d, and if the handler hasn't finished by
+// then the client receives 503 Service Unavailable; anything the handler
+// writes afterwards is discarded.
+//
+// Responses are buffered until the handler returns and can't be flushed early,
+// so the middleware must not wrap streaming or file-download routes. A d of 0
+// or less disables it.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+
+		logged := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The context is canceled when the request ends either way; only a
+			// missed deadline is worth reporting, and it is reported as soon as
+			// it passes rather than when a stuck handler eventually returns
+			stop := context.AfterFunc(r.Context(), func() {
+				if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+					log.Printf("Request timed out after %v: %s %s", d, r.Method, sanitizeLogField(r.URL.Path))
+				}
+			})
+			defer stop()
+
+			next.ServeHTTP(w, r)
+		})
+
+		return http.TimeoutHandler(logged, d, timeoutMessage)
+	}
+}
@@ -40,6 +40,9 @@ func TestGetMediaType(t *testing.T) {
 	}{
 		{"video.mp4", "video"},
 		{"video.mkv", "video"},
+		{"VIDEO.MP4", "video"},
+		{"clip.Mov", "video"},
+		{"AUDIO.FLAC", "audio"},
 		{"video.avi", "video"},
 		{"video.mov", "video"},
 		{"audio.mp3", "audio"},
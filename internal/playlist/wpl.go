@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"media-viewer/internal/logging"
+	"media-viewer/internal/mediatypes"
 )
 
 // WPL represents a Windows Playlist file
@@ -275,7 +276,7 @@ func getRelativePath(fullPath, mediaDir string) string {
 
 // getMediaType returns the media type based on file extension
 func getMediaType(filename string) string {
-	ext := strings.ToLower(filepath.Ext(filename))
+	ext := mediatypes.NormalizeExt(filename)
 
 	videoExts := map[string]bool{
 		".mp4": true, ".mkv": true, ".avi": true, ".mov": true,
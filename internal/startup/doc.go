@@ -21,8 +21,12 @@
 //   - LOG_LEVEL: Logging level - debug, info, warn, error (default: info)
 //   - LOG_STATIC_FILES: Log static file requests (default: false)
 //   - LOG_HEALTH_CHECKS: Log health check requests (default: true)
+//   - ACCESS_LOG_FORMAT: Request log format, w3c or json (default: w3c)
+//   - TRUSTED_PROXIES: Proxies whose X-Real-IP and X-Forwarded-For are believed (default: loopback and private ranges)
 //   - MEMORY_LIMIT: Container memory limit for automatic GOMEMLIMIT configuration
 //   - MEMORY_RATIO: Percentage of MEMORY_LIMIT for Go heap (default: 0.85)
+//   - MEMORY_RESERVE_TRANSCODES: Transcodes to reserve memory for instead of MEMORY_RATIO
+//   - MEMORY_TRANSCODE_BYTES: Memory reserved per transcode (default: 256 MiB)
 //   - GOMEMLIMIT: Direct override for Go's memory limit
 //
 // # Directory Setup
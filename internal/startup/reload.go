@@ -0,0 +1,355 @@
+package startup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"media-viewer/internal/logging"
+)
+
+// reloadableSettings are read again by ReloadConfig and applied to the
+// running components without a restart.
+var reloadableSettings = []string{
+	"INDEX_INTERVAL",
+	"THUMBNAIL_INTERVAL",
+	"POLL_INTERVAL",
+	"INDEX_QUIET_PERIOD",
+	"LOG_LEVEL",
+	"LOG_SAMPLE_INTERVAL",
+	"DEBUG",
+	"MEMORY_LIMIT",
+	"MEMORY_RATIO",
+	"MEMORY_RESERVE_TRANSCODES",
+	"MEMORY_TRANSCODE_BYTES",
+	"INDEX_WORKERS",
+	"INDEX_BIRTHTIME",
+	"INDEX_CAMERA",
+	"INDEX_EXIF",
+	"INDEX_XMP",
+	"INDEX_STRICT_PERMISSIONS",
+	"INDEX_DUPLICATES",
+	"INDEX_HASH_MODE",
+	"INDEX_HASH_ALGORITHM",
+	"INDEX_PROGRESS_INTERVAL",
+	"THUMBNAIL_WORKERS",
+	"THUMBNAIL_INITIAL_WORKERS",
+	"THUMBNAIL_VIDEO_SEEK",
+	"THUMBNAIL_SERVE_STALE",
+	"THUMBNAIL_FOLDER_FRAMES",
+	"THUMBNAIL_STYLE",
+	"THUMBNAIL_FORMAT",
+	"THUMBNAIL_SENSITIVE",
+	"THUMBNAIL_OTHER_FILES",
+	"THUMBNAIL_CHANGED_FILES",
+	"THUMBNAIL_LARGE_FILE_MB",
+	"THUMBNAIL_LARGE_WORKERS",
+	"THUMBNAIL_CACHE_MAX_BYTES",
+	"THUMBNAIL_FRESHNESS_INTERVAL",
+	"AUTH_RATE_LIMIT",
+}
+
+// restartSettings are only read at startup. ReloadConfig reports changes to
+// them so the operator knows a restart is still needed.
+var restartSettings = []string{
+	"MEDIA_DIR",
+	"CACHE_DIR",
+	"DATABASE_DIR",
+	"TRANSCODER_LOG_DIR",
+	"TRANSCODER_LOG_MAX_AGE",
+	"TRANSCODER_LOG_MAX_SIZE_MB",
+	"TRANSCODER_LOG_ERRORS_ONLY",
+	"GPU_ACCEL",
+	"TRANSCODE_MAX_WAIT",
+	"TRANSCODE_WIDTH_LADDER",
+	"TRANSCODE_HDR_TONEMAP",
+	"TRANSCODE_PRESET",
+	"TRANSCODE_CRF",
+	"TRANSCODE_HLS_SEGMENT",
+	"TRANSCODE_HLS_PREFETCH",
+	"THUMBNAIL_STOP_GRACE",
+	"THUMBNAIL_WAIT_TIMEOUT",
+	"THUMBNAIL_DIMENSION_HEADERS",
+	"REQUEST_TIMEOUT",
+	"STREAM_MAX_BYTES_PER_SEC",
+	"PORT",
+	"METRICS_PORT",
+	"METRICS_ENABLED",
+	"DB_MMAP_DISABLED",
+	"DB_WAL_AUTOCHECKPOINT",
+	"DB_CHECKPOINT_AFTER_INDEX",
+	"DB_RECOVER_CORRUPT",
+	"PUBLIC_MODE",
+	"SVG_SAFETY",
+	"SPA_FALLBACK",
+	"DISABLED_ROUTES",
+	"MEDIA_WRITE_ENABLED",
+	"TRASH_DIR",
+	"TRASH_TTL",
+	"PALETTE_EXTRACTION",
+	"ANIMATED_DETECTION",
+	"SEARCH_DID_YOU_MEAN",
+	"THUMBNAIL_DEDUPE",
+	"THUMBNAIL_SIZE",
+	"THUMBNAIL_VARIANT_SIZES",
+	"SESSION_DURATION",
+	"SESSION_CLEANUP_INTERVAL",
+	"LOG_STATIC_FILES",
+	"LOG_HEALTH_CHECKS",
+	"ACCESS_LOG_FORMAT",
+	"TRUSTED_PROXIES",
+	"GOMEMLIMIT",
+	"CPU_LIMIT",
+	"CPU_LIMIT_CGROUP",
+	"CPU_LIMIT_ROUNDING",
+	"WEBAUTHN_RP_ID",
+	"WEBAUTHN_RP_DISPLAY_NAME",
+	"WEBAUTHN_RP_ORIGINS",
+}
+
+var (
+	settingsMu sync.Mutex
+	// loadedSettings holds the raw value of every known setting as last applied
+	loadedSettings map[string]string
+	// processEnvKeys are variables set in the real process environment, which
+	// always take precedence over CONFIG_FILE
+	processEnvKeys map[string]bool
+	// fileEnvKeys are variables currently set from CONFIG_FILE
+	fileEnvKeys map[string]bool
+)
+
+// ReloadResult describes the outcome of a configuration reload.
+type ReloadResult struct {
+	// Changed lists reloadable settings whose value changed and was applied
+	Changed []string `json:"changed"`
+	// RestartRequired lists startup-only settings whose value changed
+	RestartRequired []string `json:"restartRequired"`
+
+	IndexInterval     time.Duration `json:"-"`
+	ThumbnailInterval time.Duration `json:"-"`
+	PollInterval      time.Duration `json:"-"`
+	IndexQuietPeriod  time.Duration `json:"-"`
+
+	IndexBirthTime  bool `json:"-"`
+	IndexCamera     bool `json:"-"`
+	IndexExif       bool `json:"-"`
+	IndexXMP        bool `json:"-"`
+	IndexStrictPerm bool `json:"-"`
+	IndexDuplicates bool `json:"-"`
+
+	IndexHashMode      string `json:"-"`
+	IndexHashAlgorithm string `json:"-"`
+
+	IndexProgressInterval int `json:"-"`
+
+	VideoThumbnailSeek   string `json:"-"`
+	ServeStaleThumbnails bool   `json:"-"`
+	FolderVideoFrames    bool   `json:"-"`
+	ThumbnailStyle       string `json:"-"`
+	ThumbnailFormat      string `json:"-"`
+	ThumbnailSensitive   string `json:"-"`
+	OtherThumbnails      string `json:"-"`
+	ChangedFiles         string `json:"-"`
+	LargeFileThreshold   int64  `json:"-"`
+	LargeFileWorkers     int    `json:"-"`
+	ThumbnailCacheMax    int64  `json:"-"`
+
+	ThumbnailFreshness time.Duration `json:"-"`
+
+	AuthRateLimit int `json:"-"`
+}
+
+// HasChanged reports whether the named setting changed in this reload.
+func (r *ReloadResult) HasChanged(name string) bool {
+	return slices.Contains(r.Changed, name)
+}
+
+// ApplyConfigFile loads KEY=VALUE settings from the file named by CONFIG_FILE
+// into the environment. Variables already set in the process environment are
+// never overridden. Call it before anything else reads the environment.
+// Does nothing if CONFIG_FILE is not set.
+func ApplyConfigFile() error {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return nil
+	}
+
+	values, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	if processEnvKeys == nil {
+		processEnvKeys = make(map[string]bool)
+		for _, entry := range os.Environ() {
+			if key, _, ok := strings.Cut(entry, "="); ok {
+				processEnvKeys[key] = true
+			}
+		}
+	}
+
+	// Settings removed from the file fall back to their defaults
+	for key := range fileEnvKeys {
+		if _, ok := values[key]; !ok {
+			if err := os.Unsetenv(key); err != nil {
+				return fmt.Errorf("failed to unset %s: %w", key, err)
+			}
+		}
+	}
+
+	fileEnvKeys = make(map[string]bool, len(values))
+	for key, value := range values {
+		if processEnvKeys[key] {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+		fileEnvKeys[key] = true
+	}
+
+	return nil
+}
+
+// readConfigFile parses a file of KEY=VALUE lines. Blank lines and lines
+// starting with # are ignored; an optional "export " prefix, surrounding
+// quotes and trailing " # comments" on unquoted values are stripped.
+func readConfigFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("config file %s line %d: expected KEY=VALUE", path, lineNum)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if before, _, found := strings.Cut(value, " #"); found {
+			// Inline comment on an unquoted value
+			value = strings.TrimSpace(before)
+		}
+		values[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	return values, nil
+}
+
+// snapshotSettings captures the current raw value of every known setting.
+func snapshotSettings() map[string]string {
+	snapshot := make(map[string]string, len(reloadableSettings)+len(restartSettings))
+	for _, name := range reloadableSettings {
+		snapshot[name] = os.Getenv(name)
+	}
+	for _, name := range restartSettings {
+		snapshot[name] = os.Getenv(name)
+	}
+	return snapshot
+}
+
+// recordLoadedSettings stores the settings in effect after LoadConfig so
+// later reloads can tell what changed.
+func recordLoadedSettings() {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	loadedSettings = snapshotSettings()
+}
+
+// ReloadConfig re-reads CONFIG_FILE and the environment, and reports which
+// reloadable settings changed and which changes need a restart. It does not
+// touch the running components; the caller applies the result.
+func ReloadConfig() (*ReloadResult, error) {
+	if err := ApplyConfigFile(); err != nil {
+		return nil, err
+	}
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	if loadedSettings == nil {
+		return nil, errors.New("configuration has not been loaded")
+	}
+
+	current := snapshotSettings()
+	result := &ReloadResult{
+		Changed:         []string{},
+		RestartRequired: []string{},
+	}
+
+	for _, name := range reloadableSettings {
+		if current[name] != loadedSettings[name] {
+			result.Changed = append(result.Changed, name)
+			loadedSettings[name] = current[name]
+		}
+	}
+
+	// Startup-only values keep their original baseline so the restart
+	// requirement is reported until the process actually restarts
+	for _, name := range restartSettings {
+		if current[name] != loadedSettings[name] {
+			result.RestartRequired = append(result.RestartRequired, name)
+		}
+	}
+
+	rc := loadRawConfig()
+	durations := parseDurations(rc)
+	result.IndexInterval = durations.indexInterval
+	result.ThumbnailInterval = durations.thumbnailInterval
+	result.PollInterval = durations.pollInterval
+	result.IndexQuietPeriod = durations.indexQuietPeriod
+	result.IndexBirthTime = rc.indexBirthTime
+	result.IndexCamera = rc.indexCamera
+	result.IndexExif = rc.indexExif
+	result.IndexXMP = rc.indexXMP
+	result.IndexStrictPerm = rc.indexStrictPerms
+	result.IndexDuplicates = rc.indexDuplicates
+	result.IndexHashMode = rc.indexHashMode
+	result.IndexHashAlgorithm = rc.indexHashAlgorithm
+	result.IndexProgressInterval = rc.indexProgress
+	result.VideoThumbnailSeek = rc.videoThumbnailSeek
+	result.ServeStaleThumbnails = rc.serveStaleThumbnails
+	result.FolderVideoFrames = rc.folderVideoFrames
+	result.ThumbnailStyle = rc.thumbnailStyle
+	result.ThumbnailFormat = rc.thumbnailFormat
+	result.ThumbnailSensitive = rc.thumbnailSensitive
+	result.OtherThumbnails = rc.otherThumbnails
+	result.ChangedFiles = rc.changedFiles
+	result.LargeFileThreshold = largeFileThreshold(rc.largeFileMB)
+	result.LargeFileWorkers = rc.largeFileWorkers
+	result.ThumbnailCacheMax = int64(max(rc.thumbnailCacheMax, 0))
+	result.ThumbnailFreshness = max(durations.thumbFreshness, 0)
+	result.AuthRateLimit = max(rc.authRateLimit, 0)
+
+	logging.Info("Configuration reloaded: changed=%v restartRequired=%v", result.Changed, result.RestartRequired)
+
+	return result, nil
+}
@@ -0,0 +1,197 @@
+package startup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// resetReloadState clears the package-level reload state and unsets the given
+// variables for the duration of the test.
+func resetReloadState(t *testing.T, keys ...string) {
+	t.Helper()
+
+	for _, key := range keys {
+		if value, ok := os.LookupEnv(key); ok {
+			t.Cleanup(func() { os.Setenv(key, value) })
+			os.Unsetenv(key)
+		}
+	}
+
+	settingsMu.Lock()
+	loadedSettings = nil
+	processEnvKeys = nil
+	fileEnvKeys = nil
+	settingsMu.Unlock()
+
+	t.Cleanup(func() {
+		settingsMu.Lock()
+		defer settingsMu.Unlock()
+		for key := range fileEnvKeys {
+			os.Unsetenv(key)
+		}
+		loadedSettings = nil
+		processEnvKeys = nil
+		fileEnvKeys = nil
+	})
+}
+
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "media-viewer.env")
+	writeConfigFile(t, path, `# comment line
+
+POLL_INTERVAL=1m
+export LOG_LEVEL=debug
+  INDEX_WORKERS = 4  # for NFS
+GPU_ACCEL="none"
+WEBAUTHN_RP_ORIGINS="https://a.example #1"
+WEBAUTHN_RP_DISPLAY_NAME='My Media'
+EMPTY=
+`)
+
+	values, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("readConfigFile failed: %v", err)
+	}
+
+	want := map[string]string{
+		"POLL_INTERVAL":            "1m",
+		"LOG_LEVEL":                "debug",
+		"INDEX_WORKERS":            "4",
+		"GPU_ACCEL":                "none",
+		"WEBAUTHN_RP_ORIGINS":      "https://a.example #1",
+		"WEBAUTHN_RP_DISPLAY_NAME": "My Media",
+		"EMPTY":                    "",
+	}
+	if len(values) != len(want) {
+		t.Errorf("Expected %d values, got %d: %v", len(want), len(values), values)
+	}
+	for key, expected := range want {
+		if values[key] != expected {
+			t.Errorf("%s = %q, want %q", key, values[key], expected)
+		}
+	}
+}
+
+func TestReadConfigFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := readConfigFile(filepath.Join(dir, "missing.env")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+
+	path := filepath.Join(dir, "bad.env")
+	writeConfigFile(t, path, "POLL_INTERVAL=1m\nnot a setting\n")
+	if _, err := readConfigFile(path); err == nil {
+		t.Error("Expected error for line without '='")
+	}
+}
+
+func TestApplyConfigFile_ProcessEnvTakesPrecedence(t *testing.T) {
+	resetReloadState(t, "POLL_INTERVAL", "INDEX_INTERVAL")
+	t.Setenv("INDEX_INTERVAL", "2h")
+
+	path := filepath.Join(t.TempDir(), "media-viewer.env")
+	writeConfigFile(t, path, "POLL_INTERVAL=1m\nINDEX_INTERVAL=5m\n")
+	t.Setenv("CONFIG_FILE", path)
+
+	if err := ApplyConfigFile(); err != nil {
+		t.Fatalf("ApplyConfigFile failed: %v", err)
+	}
+
+	if got := os.Getenv("POLL_INTERVAL"); got != "1m" {
+		t.Errorf("POLL_INTERVAL = %q, want value from file", got)
+	}
+	if got := os.Getenv("INDEX_INTERVAL"); got != "2h" {
+		t.Errorf("INDEX_INTERVAL = %q, want process environment value", got)
+	}
+
+	// Removing a setting from the file falls back to the default
+	writeConfigFile(t, path, "INDEX_INTERVAL=5m\n")
+	if err := ApplyConfigFile(); err != nil {
+		t.Fatalf("ApplyConfigFile failed: %v", err)
+	}
+	if _, ok := os.LookupEnv("POLL_INTERVAL"); ok {
+		t.Error("Expected POLL_INTERVAL to be unset after removal from file")
+	}
+}
+
+func TestApplyConfigFile_NotConfigured(t *testing.T) {
+	resetReloadState(t, "CONFIG_FILE")
+
+	if err := ApplyConfigFile(); err != nil {
+		t.Errorf("Expected no error without CONFIG_FILE, got %v", err)
+	}
+}
+
+func TestReloadConfig_BeforeLoad(t *testing.T) {
+	resetReloadState(t, "CONFIG_FILE")
+
+	if _, err := ReloadConfig(); err == nil {
+		t.Error("Expected error when configuration has not been loaded")
+	}
+}
+
+func TestReloadConfig_ReportsChanges(t *testing.T) {
+	resetReloadState(t, "POLL_INTERVAL", "LOG_LEVEL", "PORT")
+
+	path := filepath.Join(t.TempDir(), "media-viewer.env")
+	writeConfigFile(t, path, "POLL_INTERVAL=30s\nPORT=8080\n")
+	t.Setenv("CONFIG_FILE", path)
+
+	if err := ApplyConfigFile(); err != nil {
+		t.Fatalf("ApplyConfigFile failed: %v", err)
+	}
+	recordLoadedSettings()
+
+	writeConfigFile(t, path, "POLL_INTERVAL=2m\nPORT=9000\nLOG_LEVEL=warn\n")
+
+	result, err := ReloadConfig()
+	if err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+
+	if !result.HasChanged("POLL_INTERVAL") || !result.HasChanged("LOG_LEVEL") {
+		t.Errorf("Expected POLL_INTERVAL and LOG_LEVEL in changed, got %v", result.Changed)
+	}
+	if len(result.Changed) != 2 {
+		t.Errorf("Expected 2 changed settings, got %v", result.Changed)
+	}
+	if len(result.RestartRequired) != 1 || result.RestartRequired[0] != "PORT" {
+		t.Errorf("Expected PORT to require restart, got %v", result.RestartRequired)
+	}
+	if result.PollInterval != 2*time.Minute {
+		t.Errorf("PollInterval = %v, want 2m", result.PollInterval)
+	}
+
+	// Reloading again applies nothing new but still reports the pending restart
+	result, err = ReloadConfig()
+	if err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+	if len(result.Changed) != 0 {
+		t.Errorf("Expected no changes on second reload, got %v", result.Changed)
+	}
+	if len(result.RestartRequired) != 1 {
+		t.Errorf("Expected restart still required, got %v", result.RestartRequired)
+	}
+}
+
+func TestReloadConfig_InvalidFile(t *testing.T) {
+	resetReloadState(t)
+	recordLoadedSettings()
+
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.env"))
+
+	if _, err := ReloadConfig(); err == nil {
+		t.Error("Expected error for unreadable config file")
+	}
+}
@@ -65,10 +65,14 @@ type Config struct {
 	IndexInterval     time.Duration
 	ThumbnailInterval time.Duration
 	PollInterval      time.Duration
+	IndexQuietPeriod  time.Duration // Minimum gap between an index run and a triggered one
 	SessionDuration   time.Duration
 	SessionCleanup    time.Duration
+	AuthRateLimit     int    // Login and setup requests allowed per client IP per minute (0 = unlimited)
+	TrustedProxies    string // Proxies whose forwarding headers give the client IP (comma-separated IPs/CIDRs)
 	LogStaticFiles    bool
 	LogHealthChecks   bool
+	AccessLogFormat   string // Access log line format (w3c/json)
 	MetricsEnabled    bool
 
 	// Derived paths
@@ -78,12 +82,161 @@ type Config struct {
 	TranscoderLogDir string
 	GPUAccel         string // GPU acceleration mode (auto/nvidia/vaapi/videotoolbox/none)
 
+	// TranscodeMaxWait caps how long a transcode may run, and is the full
+	// budget for videos whose duration is unknown (live recordings, fragmented files)
+	TranscodeMaxWait time.Duration
+
+	// TranscodeWidthLadder lists the widths transcode requests are rounded up to
+	// (e.g. "480,720,1080"); empty keeps requested widths as-is
+	TranscodeWidthLadder string
+
+	// HDRToneMapping converts HDR videos to SDR when transcoding so they don't
+	// look washed out in browsers; CPU-intensive
+	HDRToneMapping bool
+
+	// TranscodePreset and TranscodeCRF are the libx264 preset and CRF used
+	// when transcoding on the CPU; GPU encoders ignore them
+	TranscodePreset string
+	TranscodeCRF    int
+
+	// HLSSegmentDuration is the target length of the segments of videos
+	// served as HLS, and HLSPrefetch how many segments past the one served
+	// are checked ahead of the player (0 = none)
+	HLSSegmentDuration time.Duration
+	HLSPrefetch        int
+
+	// TranscoderLogMaxAge and TranscoderLogMaxMB limit the FFmpeg logs kept in
+	// TranscoderLogDir (0 = no limit); TranscoderLogErrors keeps only the logs
+	// of failed transcodes
+	TranscoderLogMaxAge time.Duration
+	TranscoderLogMaxMB  int
+	TranscoderLogErrors bool
+
+	// ThumbnailStopGrace is how long stopping the thumbnail generator waits for
+	// a run in progress to finish its current files
+	ThumbnailStopGrace time.Duration
+
+	// ThumbnailWaitTimeout bounds how long GET /api/thumbnail/{path}?wait=true
+	// waits for a thumbnail to be generated
+	ThumbnailWaitTimeout time.Duration
+
+	// ThumbnailDimensions adds the thumbnail's dimensions and its
+	// source's aspect ratio to GET /api/thumbnail/{path} responses
+	ThumbnailDimensions bool
+
+	// RequestTimeout bounds non-streaming API requests, which get 503 once it
+	// passes; 0 disables it
+	RequestTimeout time.Duration
+
+	// StreamMaxBytesPerSec caps the bandwidth of each file or video stream (0 = unlimited)
+	StreamMaxBytesPerSec int64
+
 	// Feature flags based on directory availability
 	ThumbnailsEnabled  bool
 	TranscodingEnabled bool
 
+	// PaletteEnabled stores dominant colors for generated thumbnails (enables color search)
+	PaletteEnabled bool
+
+	// AnimatedDetection records which GIF, PNG and WebP images are animated as their thumbnails are generated
+	AnimatedDetection bool
+
+	// SearchDidYouMean is the number of "did you mean" suggestions returned
+	// for searches that find nothing (0 = none)
+	SearchDidYouMean int
+
+	// ThumbnailDedupe shares one cached thumbnail between source files with identical content
+	ThumbnailDedupe bool
+
+	// ServeStaleThumbnails returns outdated thumbnails while regenerating them in the background
+	ServeStaleThumbnails bool
+
+	// FolderVideoFrames samples frames from across each video for folder composites
+	FolderVideoFrames bool
+
+	// ThumbnailStyle bakes rounded corners and a border into thumbnails ("" for none)
+	ThumbnailStyle string
+
+	// ThumbnailFormat is the format encoded with every thumbnail and preferred for clients accepting it: "jpeg", "webp" or "avif"
+	ThumbnailFormat string
+
+	// ThumbnailSensitive is how thumbnails of files flagged as sensitive are obscured: "blur" or "pixelate"
+	ThumbnailSensitive string
+
+	// ThumbnailSize is the longest edge of image and video thumbnails in pixels
+	ThumbnailSize int
+
+	// ThumbnailVariantSizes lists the sizes thumbnails are rendered at on request with ?size=
+	ThumbnailVariantSizes string
+
+	// OtherThumbnails draws thumbnails for files of no media type: "off", "badge" or "preview"
+	OtherThumbnails string
+
+	// ChangedFiles handles files that change while their thumbnail is generated: "retry", "skip" or "off"
+	ChangedFiles string
+
+	// LargeFileThreshold defers thumbnails of images above this size (bytes) to the end of a run (0 = off)
+	LargeFileThreshold int64
+	// LargeFileWorkers caps the workers generating deferred large-file thumbnails
+	LargeFileWorkers int
+	// ThumbnailCacheMax caps the thumbnail cache in bytes, evicting the least recently read thumbnails (0 = unlimited)
+	ThumbnailCacheMax int64
+	// ThumbnailFreshness is how often cached thumbnails are checked against their sources' content (0 = never)
+	ThumbnailFreshness time.Duration
+
+	// IndexBirthTime records file creation times where available, for sorting by creation
+	IndexBirthTime bool
+	// IndexCamera records the camera and lens of images from their EXIF data, for browsing by them
+	IndexCamera bool
+	// IndexExif records when images were taken from their EXIF data, for sorting by capture date
+	IndexExif bool
+	// IndexXMP imports star ratings, rejects and color labels from XMP sidecars as tags
+	IndexXMP bool
+	// IndexStrictPerms fails an index run on a directory it isn't permitted to read, instead of skipping it
+	IndexStrictPerms bool
+
+	// IndexDuplicates hashes the content of files sharing a size with another file, for finding duplicates
+	IndexDuplicates bool
+	// IndexHashMode is how much of each file is read to hash it (sampled/full/partial)
+	IndexHashMode string
+	// IndexHashAlgorithm is the hash function for content hashes (sha256/xxhash)
+	IndexHashAlgorithm string
+
+	// IndexProgressInterval is how many files and folders pass between indexer progress log lines (0 = off)
+	IndexProgressInterval int
+
+	// VideoThumbnailSeek selects the video thumbnail frame ("smart", a duration, or a percentage)
+	VideoThumbnailSeek string
+
 	// Database options
-	DBMmapDisabled bool // Disable SQLite mmap for unreliable storage (Longhorn, NFS)
+	DBMmapDisabled       bool // Disable SQLite mmap for unreliable storage (Longhorn, NFS)
+	DBWALAutoCheckpoint  int  // WAL pages that trigger an automatic checkpoint; negative disables them
+	DBWALIndexCheckpoint bool // Checkpoint and truncate the WAL after each index run
+	DBRecoverCorrupt     bool // Move a corrupt database aside and start with an empty one
+
+	// PublicMode serves read-only routes without authentication; mutating routes still require login
+	PublicMode bool
+
+	// SVGSafety selects how original SVG files are served (off/sandbox/sanitize/attachment)
+	SVGSafety string
+
+	// SPAFallback serves index.html for unknown non-API paths that don't look
+	// like files, so the app's own routes survive a reload
+	SPAFallback bool
+
+	// DisabledRoutes lists API route groups that answer 404 instead of being
+	// served, such as "reindex,rebuild" (comma-separated)
+	DisabledRoutes string
+
+	// MediaWriteEnabled allows the API routes that change the media
+	// directory itself: moving, deleting to the trash and restoring files
+	MediaWriteEnabled bool
+
+	// TrashDir holds files and folders deleted through the API until they
+	// are restored or purged; TrashTTL is how long they are kept there
+	// (0 = until restored)
+	TrashDir string
+	TrashTTL time.Duration
 
 	// WebAuthn configuration
 	WebAuthnEnabled       bool
@@ -94,6 +247,10 @@ type Config struct {
 
 // startup.go — LoadConfig refactored to reduce cognitive complexity
 
+// defaultTrustedProxies is the default of TRUSTED_PROXIES: loopback and
+// private addresses, where a reverse proxy usually is
+const defaultTrustedProxies = "127.0.0.0/8,::1/128,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,fc00::/7"
+
 // rawConfig holds the raw string values from environment variables
 // before parsing and validation.
 type rawConfig struct {
@@ -101,18 +258,74 @@ type rawConfig struct {
 	cacheDir              string
 	databaseDir           string
 	transcoderLogDir      string
+	transcoderLogMaxAge   string
+	transcoderLogMaxMB    int
+	transcoderLogErrors   bool
 	gpuAccel              string
+	transcodeMaxWait      string
+	transcodeLadder       string
+	hdrToneMapping        bool
+	transcodePreset       string
+	transcodeCRF          int
+	hlsSegment            string
+	hlsPrefetch           int
 	port                  string
 	metricsPort           string
 	indexInterval         string
 	thumbnailInterval     string
+	thumbnailStopGrace    string
+	thumbnailWaitTimeout  string
+	thumbnailDimensions   bool
+	requestTimeout        string
+	streamMaxBytesPerSec  int
 	pollInterval          string
+	indexQuietPeriod      string
+	indexBirthTime        bool
+	indexCamera           bool
+	indexExif             bool
+	indexXMP              bool
+	indexStrictPerms      bool
+	indexDuplicates       bool
+	indexHashMode         string
+	indexHashAlgorithm    string
+	indexProgress         int
 	sessionDuration       string
 	sessionCleanup        string
+	authRateLimit         int
+	trustedProxies        string
 	logStaticFiles        bool
 	logHealthChecks       bool
+	accessLogFormat       string
 	metricsEnabled        bool
 	dbMmapDisabled        bool
+	walAutoCheckpoint     int
+	walIndexCheckpoint    bool
+	dbRecoverCorrupt      bool
+	publicMode            bool
+	svgSafety             string
+	spaFallback           bool
+	disabledRoutes        string
+	mediaWriteEnabled     bool
+	trashDir              string
+	trashTTL              string
+	paletteExtraction     bool
+	animatedDetection     bool
+	searchDidYouMean      int
+	videoThumbnailSeek    string
+	thumbnailDedupe       bool
+	serveStaleThumbnails  bool
+	folderVideoFrames     bool
+	thumbnailStyle        string
+	thumbnailFormat       string
+	thumbnailSensitive    string
+	thumbnailSize         int
+	thumbnailVariants     string
+	otherThumbnails       string
+	changedFiles          string
+	largeFileMB           int
+	largeFileWorkers      int
+	thumbnailCacheMax     int
+	thumbnailFreshness    string
 	webAuthnRPID          string
 	webAuthnRPDisplayName string
 	webAuthnRPOrigins     string
@@ -125,18 +338,74 @@ func loadRawConfig() *rawConfig {
 		cacheDir:              getEnv("CACHE_DIR", "/cache"),
 		databaseDir:           getEnv("DATABASE_DIR", "/database"),
 		transcoderLogDir:      getEnv("TRANSCODER_LOG_DIR", ""),
+		transcoderLogMaxAge:   getEnv("TRANSCODER_LOG_MAX_AGE", "0"),
+		transcoderLogMaxMB:    getEnvInt("TRANSCODER_LOG_MAX_SIZE_MB", 0),
+		transcoderLogErrors:   getEnvBool("TRANSCODER_LOG_ERRORS_ONLY", false),
 		gpuAccel:              getEnv("GPU_ACCEL", "auto"),
+		transcodeMaxWait:      getEnv("TRANSCODE_MAX_WAIT", "30m"),
+		transcodeLadder:       getEnv("TRANSCODE_WIDTH_LADDER", ""),
+		hdrToneMapping:        getEnvBool("TRANSCODE_HDR_TONEMAP", false),
+		transcodePreset:       getEnv("TRANSCODE_PRESET", "fast"),
+		transcodeCRF:          getEnvInt("TRANSCODE_CRF", 23),
+		hlsSegment:            getEnv("TRANSCODE_HLS_SEGMENT", "6s"),
+		hlsPrefetch:           getEnvInt("TRANSCODE_HLS_PREFETCH", 0),
 		port:                  getEnv("PORT", "8080"),
 		metricsPort:           getEnv("METRICS_PORT", "9090"),
 		indexInterval:         getEnv("INDEX_INTERVAL", "30m"),
 		thumbnailInterval:     getEnv("THUMBNAIL_INTERVAL", "6h"),
+		thumbnailStopGrace:    getEnv("THUMBNAIL_STOP_GRACE", "10s"),
+		thumbnailWaitTimeout:  getEnv("THUMBNAIL_WAIT_TIMEOUT", "2m"),
+		thumbnailDimensions:   getEnvBool("THUMBNAIL_DIMENSION_HEADERS", false),
+		requestTimeout:        getEnv("REQUEST_TIMEOUT", "3m"),
+		streamMaxBytesPerSec:  getEnvInt("STREAM_MAX_BYTES_PER_SEC", 0),
 		pollInterval:          getEnv("POLL_INTERVAL", "30s"),
+		indexQuietPeriod:      getEnv("INDEX_QUIET_PERIOD", "10s"),
+		indexBirthTime:        getEnvBool("INDEX_BIRTHTIME", false),
+		indexCamera:           getEnvBool("INDEX_CAMERA", false),
+		indexExif:             getEnvBool("INDEX_EXIF", false),
+		indexXMP:              getEnvBool("INDEX_XMP", false),
+		indexStrictPerms:      getEnvBool("INDEX_STRICT_PERMISSIONS", false),
+		indexDuplicates:       getEnvBool("INDEX_DUPLICATES", false),
+		indexHashMode:         getEnv("INDEX_HASH_MODE", "sampled"),
+		indexHashAlgorithm:    getEnv("INDEX_HASH_ALGORITHM", "sha256"),
+		indexProgress:         getEnvInt("INDEX_PROGRESS_INTERVAL", 10000),
 		sessionDuration:       getEnv("SESSION_DURATION", "5m"),
 		sessionCleanup:        getEnv("SESSION_CLEANUP_INTERVAL", "1m"),
+		authRateLimit:         getEnvInt("AUTH_RATE_LIMIT", 10),
+		trustedProxies:        getEnv("TRUSTED_PROXIES", defaultTrustedProxies),
 		logStaticFiles:        getEnvBool("LOG_STATIC_FILES", false),
 		logHealthChecks:       getEnvBool("LOG_HEALTH_CHECKS", true),
+		accessLogFormat:       getEnv("ACCESS_LOG_FORMAT", "w3c"),
 		metricsEnabled:        getEnvBool("METRICS_ENABLED", true),
 		dbMmapDisabled:        getEnvBool("DB_MMAP_DISABLED", false),
+		walAutoCheckpoint:     getEnvInt("DB_WAL_AUTOCHECKPOINT", 1000),
+		walIndexCheckpoint:    getEnvBool("DB_CHECKPOINT_AFTER_INDEX", false),
+		dbRecoverCorrupt:      getEnvBool("DB_RECOVER_CORRUPT", false),
+		publicMode:            getEnvBool("PUBLIC_MODE", false),
+		svgSafety:             getEnv("SVG_SAFETY", "sandbox"),
+		spaFallback:           getEnvBool("SPA_FALLBACK", false),
+		disabledRoutes:        getEnv("DISABLED_ROUTES", ""),
+		mediaWriteEnabled:     getEnvBool("MEDIA_WRITE_ENABLED", false),
+		trashDir:              getEnv("TRASH_DIR", ""),
+		trashTTL:              getEnv("TRASH_TTL", "720h"),
+		paletteExtraction:     getEnvBool("PALETTE_EXTRACTION", false),
+		animatedDetection:     getEnvBool("ANIMATED_DETECTION", false),
+		searchDidYouMean:      getEnvInt("SEARCH_DID_YOU_MEAN", 5),
+		videoThumbnailSeek:    getEnv("THUMBNAIL_VIDEO_SEEK", "smart"),
+		thumbnailDedupe:       getEnvBool("THUMBNAIL_DEDUPE", false),
+		serveStaleThumbnails:  getEnvBool("THUMBNAIL_SERVE_STALE", false),
+		folderVideoFrames:     getEnvBool("THUMBNAIL_FOLDER_FRAMES", false),
+		thumbnailStyle:        getEnv("THUMBNAIL_STYLE", ""),
+		thumbnailFormat:       getEnv("THUMBNAIL_FORMAT", "jpeg"),
+		thumbnailSensitive:    getEnv("THUMBNAIL_SENSITIVE", "blur"),
+		thumbnailSize:         getEnvInt("THUMBNAIL_SIZE", 200),
+		thumbnailVariants:     getEnv("THUMBNAIL_VARIANT_SIZES", "256,512,1024"),
+		otherThumbnails:       getEnv("THUMBNAIL_OTHER_FILES", "off"),
+		changedFiles:          getEnv("THUMBNAIL_CHANGED_FILES", "retry"),
+		largeFileMB:           getEnvInt("THUMBNAIL_LARGE_FILE_MB", 0),
+		largeFileWorkers:      getEnvInt("THUMBNAIL_LARGE_WORKERS", 1),
+		thumbnailCacheMax:     getEnvInt("THUMBNAIL_CACHE_MAX_BYTES", 0),
+		thumbnailFreshness:    getEnv("THUMBNAIL_FRESHNESS_INTERVAL", "0"),
 		webAuthnRPID:          getEnv("WEBAUTHN_RP_ID", ""),
 		webAuthnRPDisplayName: getEnv("WEBAUTHN_RP_DISPLAY_NAME", "Media Viewer"),
 		webAuthnRPOrigins:     getEnv("WEBAUTHN_RP_ORIGINS", ""),
@@ -145,32 +414,125 @@ func loadRawConfig() *rawConfig {
 
 // logRawConfig logs all configuration values.
 func logRawConfig(rc *rawConfig) {
+	if configFile := os.Getenv("CONFIG_FILE"); configFile != "" {
+		logging.Info("  CONFIG_FILE:             %s", configFile)
+	}
 	logging.Info("  MEDIA_DIR:               %s", rc.mediaDir)
 	logging.Info("  CACHE_DIR:               %s", rc.cacheDir)
 	logging.Info("  DATABASE_DIR:            %s", rc.databaseDir)
 	if rc.transcoderLogDir != "" {
 		logging.Info("  TRANSCODER_LOG_DIR:      %s", rc.transcoderLogDir)
+		logging.Info("  TRANSCODER_LOG_MAX_AGE:  %s", rc.transcoderLogMaxAge)
+		logging.Info("  TRANSCODER_LOG_MAX_SIZE_MB: %d", rc.transcoderLogMaxMB)
+		logging.Info("  TRANSCODER_LOG_ERRORS_ONLY: %v", rc.transcoderLogErrors)
 	} else {
 		logging.Info("  TRANSCODER_LOG_DIR:      (not configured)")
 	}
 	logging.Info("  GPU_ACCEL:               %s (auto-detect: nvidia/vaapi/videotoolbox)", rc.gpuAccel)
+	logging.Info("  TRANSCODE_MAX_WAIT:      %s", rc.transcodeMaxWait)
+	if rc.transcodeLadder != "" {
+		logging.Info("  TRANSCODE_WIDTH_LADDER:  %s", rc.transcodeLadder)
+	} else {
+		logging.Info("  TRANSCODE_WIDTH_LADDER:  (disabled)")
+	}
+	logging.Info("  TRANSCODE_HDR_TONEMAP:   %v", rc.hdrToneMapping)
+	logging.Info("  TRANSCODE_PRESET:        %s", rc.transcodePreset)
+	logging.Info("  TRANSCODE_CRF:           %d", rc.transcodeCRF)
+	logging.Info("  TRANSCODE_HLS_SEGMENT:   %s", rc.hlsSegment)
+	logging.Info("  TRANSCODE_HLS_PREFETCH:  %d", rc.hlsPrefetch)
 	logging.Info("  PORT:                    %s", rc.port)
+	logging.Info("  REQUEST_TIMEOUT:         %s", rc.requestTimeout)
+	if rc.streamMaxBytesPerSec > 0 {
+		logging.Info("  STREAM_MAX_BYTES_PER_SEC: %d per stream", rc.streamMaxBytesPerSec)
+	} else {
+		logging.Info("  STREAM_MAX_BYTES_PER_SEC: (unlimited)")
+	}
 	logging.Info("  METRICS_PORT:            %s", rc.metricsPort)
 	logging.Info("  METRICS_ENABLED:         %v", rc.metricsEnabled)
 	logging.Info("  DB_MMAP_DISABLED:        %v", rc.dbMmapDisabled)
 	if rc.dbMmapDisabled {
 		logging.Info("    (SIGBUS protection enabled — recommended for Longhorn/NFS/network storage)")
 	}
+	if rc.walAutoCheckpoint > 0 {
+		logging.Info("  DB_WAL_AUTOCHECKPOINT:   %d pages", rc.walAutoCheckpoint)
+	} else {
+		logging.Info("  DB_WAL_AUTOCHECKPOINT:   (disabled)")
+	}
+	logging.Info("  DB_CHECKPOINT_AFTER_INDEX: %v", rc.walIndexCheckpoint)
+	logging.Info("  DB_RECOVER_CORRUPT:      %v", rc.dbRecoverCorrupt)
 	logging.Info("  INDEX_INTERVAL:          %s", rc.indexInterval)
 	logging.Info("  THUMBNAIL_INTERVAL:      %s", rc.thumbnailInterval)
+	logging.Info("  THUMBNAIL_STOP_GRACE:    %s", rc.thumbnailStopGrace)
+	logging.Info("  THUMBNAIL_WAIT_TIMEOUT:  %s", rc.thumbnailWaitTimeout)
+	logging.Info("  THUMBNAIL_DIMENSION_HEADERS: %v", rc.thumbnailDimensions)
 	logging.Info("  POLL_INTERVAL:           %s", rc.pollInterval)
+	logging.Info("  INDEX_QUIET_PERIOD:      %s", rc.indexQuietPeriod)
+	logging.Info("  INDEX_BIRTHTIME:         %v", rc.indexBirthTime)
+	logging.Info("  INDEX_CAMERA:            %v", rc.indexCamera)
+	logging.Info("  INDEX_EXIF:              %v", rc.indexExif)
+	logging.Info("  INDEX_XMP:               %v", rc.indexXMP)
+	logging.Info("  INDEX_STRICT_PERMISSIONS: %v", rc.indexStrictPerms)
+	logging.Info("  INDEX_DUPLICATES:        %v", rc.indexDuplicates)
+	logging.Info("  INDEX_HASH_MODE:         %s", rc.indexHashMode)
+	logging.Info("  INDEX_HASH_ALGORITHM:    %s", rc.indexHashAlgorithm)
+	logging.Info("  INDEX_PROGRESS_INTERVAL: %d", rc.indexProgress)
 	logWorkerConfig("INDEX_WORKERS", getEnv("INDEX_WORKERS", ""), "3 (default for NFS safety)")
 	logWorkerConfig("THUMBNAIL_WORKERS", getEnv("THUMBNAIL_WORKERS", ""), "(auto - CPU-based, max 6)")
+	logWorkerConfig("THUMBNAIL_INITIAL_WORKERS", getEnv("THUMBNAIL_INITIAL_WORKERS", ""), "(same as THUMBNAIL_WORKERS)")
+	logging.Info("  PALETTE_EXTRACTION:      %v", rc.paletteExtraction)
+	logging.Info("  ANIMATED_DETECTION:      %v", rc.animatedDetection)
+	logging.Info("  SEARCH_DID_YOU_MEAN:     %d", rc.searchDidYouMean)
+	logging.Info("  THUMBNAIL_VIDEO_SEEK:    %s", rc.videoThumbnailSeek)
+	logging.Info("  THUMBNAIL_DEDUPE:        %v", rc.thumbnailDedupe)
+	logging.Info("  THUMBNAIL_SERVE_STALE:   %v", rc.serveStaleThumbnails)
+	logging.Info("  THUMBNAIL_FOLDER_FRAMES: %v", rc.folderVideoFrames)
+	logging.Info("  THUMBNAIL_STYLE:         %s", rc.thumbnailStyle)
+	logging.Info("  THUMBNAIL_FORMAT:        %s", rc.thumbnailFormat)
+	logging.Info("  THUMBNAIL_SENSITIVE:     %s", rc.thumbnailSensitive)
+	logging.Info("  THUMBNAIL_SIZE:          %d", rc.thumbnailSize)
+	if rc.thumbnailVariants != "" {
+		logging.Info("  THUMBNAIL_VARIANT_SIZES: %s", rc.thumbnailVariants)
+	} else {
+		logging.Info("  THUMBNAIL_VARIANT_SIZES: (disabled)")
+	}
+	logging.Info("  THUMBNAIL_OTHER_FILES:   %s", rc.otherThumbnails)
+	logging.Info("  THUMBNAIL_CHANGED_FILES: %s", rc.changedFiles)
+	if rc.largeFileMB > 0 {
+		logging.Info("  THUMBNAIL_LARGE_FILE_MB: %d (up to %d workers)", rc.largeFileMB, rc.largeFileWorkers)
+	} else {
+		logging.Info("  THUMBNAIL_LARGE_FILE_MB: (disabled)")
+	}
+	if rc.thumbnailCacheMax > 0 {
+		logging.Info("  THUMBNAIL_CACHE_MAX_BYTES: %d", rc.thumbnailCacheMax)
+	} else {
+		logging.Info("  THUMBNAIL_CACHE_MAX_BYTES: (unlimited)")
+	}
+	logging.Info("  THUMBNAIL_FRESHNESS_INTERVAL: %s", rc.thumbnailFreshness)
 	logging.Info("  SESSION_DURATION:        %s", rc.sessionDuration)
 	logging.Info("  SESSION_CLEANUP_INTERVAL:%s", rc.sessionCleanup)
+	logging.Info("  AUTH_RATE_LIMIT:         %d", rc.authRateLimit)
+	logging.Info("  TRUSTED_PROXIES:         %s", rc.trustedProxies)
 	logging.Info("  LOG_STATIC_FILES:        %v", rc.logStaticFiles)
 	logging.Info("  LOG_HEALTH_CHECKS:       %v", rc.logHealthChecks)
+	logging.Info("  ACCESS_LOG_FORMAT:       %s", rc.accessLogFormat)
 	logging.Info("  LOG_LEVEL:               %s", logging.GetLevel())
+	logging.Info("  LOG_SAMPLE_INTERVAL:     %v", logging.GetSampleInterval())
+	logging.Info("  PUBLIC_MODE:             %v", rc.publicMode)
+	if rc.publicMode {
+		logging.Info("    (read-only routes are accessible without login)")
+	}
+	logging.Info("  SVG_SAFETY:              %s", rc.svgSafety)
+	logging.Info("  SPA_FALLBACK:            %v", rc.spaFallback)
+	if rc.disabledRoutes != "" {
+		logging.Info("  DISABLED_ROUTES:         %s", rc.disabledRoutes)
+	} else {
+		logging.Info("  DISABLED_ROUTES:         (none)")
+	}
+	logging.Info("  MEDIA_WRITE_ENABLED:     %v", rc.mediaWriteEnabled)
+	if rc.trashDir != "" {
+		logging.Info("  TRASH_DIR:               %s", rc.trashDir)
+	}
+	logging.Info("  TRASH_TTL:               %s", rc.trashTTL)
 	logWebAuthnConfig(rc)
 }
 
@@ -203,8 +565,17 @@ type parsedDurations struct {
 	indexInterval     time.Duration
 	thumbnailInterval time.Duration
 	pollInterval      time.Duration
+	indexQuietPeriod  time.Duration
 	sessionDuration   time.Duration
 	sessionCleanup    time.Duration
+	transcodeMaxWait  time.Duration
+	hlsSegment        time.Duration
+	stopGrace         time.Duration
+	waitTimeout       time.Duration
+	requestTimeout    time.Duration
+	transcoderLogAge  time.Duration
+	thumbFreshness    time.Duration
+	trashTTL          time.Duration
 }
 
 // parseDurations parses all duration strings from the raw config.
@@ -213,11 +584,26 @@ func parseDurations(rc *rawConfig) parsedDurations {
 		indexInterval:     parseDurationWithDefault(rc.indexInterval, "INDEX_INTERVAL", 30*time.Minute),
 		thumbnailInterval: parseDurationWithDefault(rc.thumbnailInterval, "THUMBNAIL_INTERVAL", 6*time.Hour),
 		pollInterval:      parseDurationWithDefault(rc.pollInterval, "POLL_INTERVAL", 30*time.Second),
+		indexQuietPeriod:  parseDurationWithDefault(rc.indexQuietPeriod, "INDEX_QUIET_PERIOD", 10*time.Second),
 		sessionDuration:   parseDurationWithDefault(rc.sessionDuration, "SESSION_DURATION", 5*time.Minute),
 		sessionCleanup:    parseDurationWithDefault(rc.sessionCleanup, "SESSION_CLEANUP_INTERVAL", 1*time.Minute),
+		transcodeMaxWait:  parseDurationWithDefault(rc.transcodeMaxWait, "TRANSCODE_MAX_WAIT", 30*time.Minute),
+		hlsSegment:        parseDurationWithDefault(rc.hlsSegment, "TRANSCODE_HLS_SEGMENT", 6*time.Second),
+		stopGrace:         parseDurationWithDefault(rc.thumbnailStopGrace, "THUMBNAIL_STOP_GRACE", 10*time.Second),
+		waitTimeout:       parseDurationWithDefault(rc.thumbnailWaitTimeout, "THUMBNAIL_WAIT_TIMEOUT", 2*time.Minute),
+		requestTimeout:    parseDurationWithDefault(rc.requestTimeout, "REQUEST_TIMEOUT", 3*time.Minute),
+		transcoderLogAge:  parseDurationWithDefault(rc.transcoderLogMaxAge, "TRANSCODER_LOG_MAX_AGE", 0),
+		thumbFreshness:    parseDurationWithDefault(rc.thumbnailFreshness, "THUMBNAIL_FRESHNESS_INTERVAL", 0),
+		trashTTL:          parseDurationWithDefault(rc.trashTTL, "TRASH_TTL", 30*24*time.Hour),
 	}
 }
 
+// largeFileThreshold converts THUMBNAIL_LARGE_FILE_MB to bytes; zero or a
+// negative value disables large-file deferral
+func largeFileThreshold(mb int) int64 {
+	return int64(max(mb, 0)) << 20
+}
+
 // parseDurationWithDefault parses a duration string, logging a warning and
 // returning the default if parsing fails.
 func parseDurationWithDefault(value, name string, defaultVal time.Duration) time.Duration {
@@ -311,6 +697,11 @@ func LoadConfig() (*Config, error) {
 	logRawConfig(rc)
 
 	durations := parseDurations(rc)
+	walAutoCheckpoint := rc.walAutoCheckpoint
+	if walAutoCheckpoint <= 0 {
+		// database.Options treats zero as SQLite's default
+		walAutoCheckpoint = -1
+	}
 	webAuthnEnabled, webAuthnOrigins := parseWebAuthnConfig(rc)
 
 	mediaDir, cacheDir, databaseDir, err := resolveDirectories(rc)
@@ -318,6 +709,13 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	trashDir := filepath.Join(cacheDir, ".trash")
+	if rc.trashDir != "" {
+		if trashDir, err = filepath.Abs(rc.trashDir); err != nil {
+			return nil, fmt.Errorf("failed to resolve trash directory path: %w", err)
+		}
+	}
+
 	config := &Config{
 		MediaDir:              mediaDir,
 		CacheDir:              cacheDir,
@@ -327,17 +725,73 @@ func LoadConfig() (*Config, error) {
 		IndexInterval:         durations.indexInterval,
 		ThumbnailInterval:     durations.thumbnailInterval,
 		PollInterval:          durations.pollInterval,
+		IndexQuietPeriod:      durations.indexQuietPeriod,
 		SessionDuration:       durations.sessionDuration,
 		SessionCleanup:        durations.sessionCleanup,
+		AuthRateLimit:         max(rc.authRateLimit, 0),
+		TrustedProxies:        rc.trustedProxies,
 		LogStaticFiles:        rc.logStaticFiles,
 		LogHealthChecks:       rc.logHealthChecks,
+		AccessLogFormat:       rc.accessLogFormat,
 		MetricsEnabled:        rc.metricsEnabled,
 		DatabasePath:          filepath.Join(databaseDir, "media.db"),
 		ThumbnailDir:          filepath.Join(cacheDir, "thumbnails"),
 		TranscodeDir:          filepath.Join(cacheDir, "transcoded"),
 		TranscoderLogDir:      rc.transcoderLogDir,
 		GPUAccel:              rc.gpuAccel,
+		TranscodeMaxWait:      durations.transcodeMaxWait,
+		TranscodeWidthLadder:  rc.transcodeLadder,
+		HDRToneMapping:        rc.hdrToneMapping,
+		TranscodePreset:       rc.transcodePreset,
+		TranscodeCRF:          rc.transcodeCRF,
+		HLSSegmentDuration:    durations.hlsSegment,
+		HLSPrefetch:           max(rc.hlsPrefetch, 0),
+		TranscoderLogMaxAge:   durations.transcoderLogAge,
+		TranscoderLogMaxMB:    max(rc.transcoderLogMaxMB, 0),
+		TranscoderLogErrors:   rc.transcoderLogErrors,
+		ThumbnailStopGrace:    durations.stopGrace,
+		ThumbnailWaitTimeout:  durations.waitTimeout,
+		ThumbnailDimensions:   rc.thumbnailDimensions,
+		RequestTimeout:        durations.requestTimeout,
+		StreamMaxBytesPerSec:  int64(max(rc.streamMaxBytesPerSec, 0)),
 		DBMmapDisabled:        rc.dbMmapDisabled,
+		DBWALAutoCheckpoint:   walAutoCheckpoint,
+		DBWALIndexCheckpoint:  rc.walIndexCheckpoint,
+		DBRecoverCorrupt:      rc.dbRecoverCorrupt,
+		PublicMode:            rc.publicMode,
+		SVGSafety:             rc.svgSafety,
+		SPAFallback:           rc.spaFallback,
+		DisabledRoutes:        rc.disabledRoutes,
+		MediaWriteEnabled:     rc.mediaWriteEnabled,
+		TrashDir:              trashDir,
+		TrashTTL:              max(durations.trashTTL, 0),
+		PaletteEnabled:        rc.paletteExtraction,
+		AnimatedDetection:     rc.animatedDetection,
+		SearchDidYouMean:      max(rc.searchDidYouMean, 0),
+		VideoThumbnailSeek:    rc.videoThumbnailSeek,
+		ThumbnailDedupe:       rc.thumbnailDedupe,
+		ServeStaleThumbnails:  rc.serveStaleThumbnails,
+		FolderVideoFrames:     rc.folderVideoFrames,
+		ThumbnailStyle:        rc.thumbnailStyle,
+		ThumbnailFormat:       rc.thumbnailFormat,
+		ThumbnailSensitive:    rc.thumbnailSensitive,
+		ThumbnailSize:         rc.thumbnailSize,
+		ThumbnailVariantSizes: rc.thumbnailVariants,
+		OtherThumbnails:       rc.otherThumbnails,
+		ChangedFiles:          rc.changedFiles,
+		LargeFileThreshold:    largeFileThreshold(rc.largeFileMB),
+		LargeFileWorkers:      rc.largeFileWorkers,
+		ThumbnailCacheMax:     int64(max(rc.thumbnailCacheMax, 0)),
+		ThumbnailFreshness:    max(durations.thumbFreshness, 0),
+		IndexBirthTime:        rc.indexBirthTime,
+		IndexCamera:           rc.indexCamera,
+		IndexExif:             rc.indexExif,
+		IndexXMP:              rc.indexXMP,
+		IndexStrictPerms:      rc.indexStrictPerms,
+		IndexDuplicates:       rc.indexDuplicates,
+		IndexHashMode:         rc.indexHashMode,
+		IndexHashAlgorithm:    rc.indexHashAlgorithm,
+		IndexProgressInterval: rc.indexProgress,
 		WebAuthnEnabled:       webAuthnEnabled,
 		WebAuthnRPID:          rc.webAuthnRPID,
 		WebAuthnRPDisplayName: rc.webAuthnRPDisplayName,
@@ -356,6 +810,9 @@ func LoadConfig() (*Config, error) {
 	logging.Info("    Transcoding: %s", enabledString(config.TranscodingEnabled))
 	logging.Info("    Metrics:     %s", enabledString(config.MetricsEnabled))
 	logging.Info("    WebAuthn:    %s", enabledString(config.WebAuthnEnabled))
+	logging.Info("    Public mode: %s", enabledString(config.PublicMode))
+
+	recordLoadedSettings()
 
 	return config, nil
 }
@@ -400,6 +857,11 @@ func LogDatabaseInit(duration time.Duration, dbInfo *database.Info) {
 	logging.Info("  [OK] Database initialized in %v", duration)
 	if dbInfo != nil {
 		logging.Info("    Database path: %s", dbInfo.Path)
+		if dbInfo.RecoveredFrom != "" {
+			logging.Warn("    Database was corrupt and has been recreated empty (DB_RECOVER_CORRUPT)")
+			logging.Warn("    The corrupt file was kept at %s", dbInfo.RecoveredFrom)
+			logging.Warn("    The library is re-indexed from the media directory; users, favorites, tags and collections must be set up again")
+		}
 		if dbInfo.PermissionWarning != "" {
 			logging.Warn("    Database permission diagnostics: %s", dbInfo.PermissionWarning)
 		}
@@ -788,6 +1250,19 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return parsed
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		logging.Warn("Invalid integer value for %s: %q, using default: %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
 // LogMemoryConfig logs the memory configuration
 func LogMemoryConfig(memConfig MemoryConfig) {
 	logging.Info("")
@@ -805,10 +1280,18 @@ func LogMemoryConfig(memConfig MemoryConfig) {
 	case "GOMEMLIMIT":
 		logging.Info("  Source:              GOMEMLIMIT environment variable")
 		logging.Info("  GOMEMLIMIT:          %s", formatBytesStartup(memConfig.GoMemLimit))
-	case "MEMORY_LIMIT":
-		logging.Info("  Source:              MEMORY_LIMIT (Kubernetes Downward API)")
+	case "MEMORY_LIMIT", "cgroup-v2", "cgroup-v1":
+		if memConfig.Source == "MEMORY_LIMIT" {
+			logging.Info("  Source:              MEMORY_LIMIT (Kubernetes Downward API)")
+		} else {
+			logging.Info("  Source:              %s memory limit (MEMORY_LIMIT not set)", memConfig.Source)
+		}
 		logging.Info("  Container Limit:     %s", formatBytesStartup(memConfig.ContainerLimit))
-		logging.Info("  Memory Ratio:        %.1f%%", memConfig.Ratio*100)
+		if memConfig.TranscodeReserve > 0 {
+			logging.Info("  Transcode Reserve:   %s", formatBytesStartup(memConfig.TranscodeReserve))
+		} else {
+			logging.Info("  Memory Ratio:        %.1f%%", memConfig.Ratio*100)
+		}
 		logging.Info("  GOMEMLIMIT:          %s", formatBytesStartup(memConfig.GoMemLimit))
 		logging.Info("  Reserved for OS/FFmpeg: %s", formatBytesStartup(memConfig.ContainerLimit-memConfig.GoMemLimit))
 	}
@@ -821,6 +1304,10 @@ type MemoryConfig struct {
 	ContainerLimit int64
 	GoMemLimit     int64
 	Ratio          float64
+
+	// TranscodeReserve is set instead of Ratio when memory was reserved
+	// for a number of transcodes
+	TranscodeReserve int64
 }
 
 // formatBytesStartup formats bytes into human-readable string
@@ -109,11 +109,14 @@ func TestRouteInfo(t *testing.T) {
 func TestRawConfigDefaults(t *testing.T) {
 	// Unset all env vars to ensure defaults
 	envVars := []string{
-		"MEDIA_DIR", "CACHE_DIR", "DATABASE_DIR", "TRANSCODER_LOG_DIR",
-		"GPU_ACCEL", "PORT", "METRICS_PORT", "INDEX_INTERVAL",
-		"THUMBNAIL_INTERVAL", "POLL_INTERVAL", "SESSION_DURATION",
-		"SESSION_CLEANUP_INTERVAL", "LOG_STATIC_FILES", "LOG_HEALTH_CHECKS",
-		"METRICS_ENABLED", "DB_MMAP_DISABLED", "WEBAUTHN_RP_ID",
+		"MEDIA_DIR", "CACHE_DIR", "DATABASE_DIR", "TRANSCODER_LOG_DIR", "TRANSCODER_LOG_MAX_AGE",
+		"TRANSCODER_LOG_MAX_SIZE_MB", "TRANSCODER_LOG_ERRORS_ONLY",
+		"GPU_ACCEL", "TRANSCODE_PRESET", "TRANSCODE_CRF", "TRANSCODE_HLS_SEGMENT", "TRANSCODE_HLS_PREFETCH", "PORT", "METRICS_PORT", "INDEX_INTERVAL",
+		"THUMBNAIL_INTERVAL", "POLL_INTERVAL", "INDEX_QUIET_PERIOD", "INDEX_BIRTHTIME", "INDEX_CAMERA", "INDEX_EXIF", "INDEX_XMP", "INDEX_STRICT_PERMISSIONS", "INDEX_DUPLICATES", "INDEX_HASH_MODE", "INDEX_HASH_ALGORITHM", "INDEX_PROGRESS_INTERVAL", "SESSION_DURATION",
+		"SESSION_CLEANUP_INTERVAL", "AUTH_RATE_LIMIT", "TRUSTED_PROXIES", "LOG_STATIC_FILES", "LOG_HEALTH_CHECKS", "ACCESS_LOG_FORMAT",
+		"METRICS_ENABLED", "DB_MMAP_DISABLED", "DB_WAL_AUTOCHECKPOINT",
+		"DB_CHECKPOINT_AFTER_INDEX", "DB_RECOVER_CORRUPT", "THUMBNAIL_STYLE", "THUMBNAIL_FORMAT", "THUMBNAIL_SENSITIVE", "THUMBNAIL_SIZE", "THUMBNAIL_VARIANT_SIZES", "SEARCH_DID_YOU_MEAN", "ANIMATED_DETECTION", "THUMBNAIL_OTHER_FILES", "THUMBNAIL_LARGE_FILE_MB",
+		"THUMBNAIL_LARGE_WORKERS", "THUMBNAIL_CACHE_MAX_BYTES", "THUMBNAIL_FRESHNESS_INTERVAL", "THUMBNAIL_WAIT_TIMEOUT", "THUMBNAIL_DIMENSION_HEADERS", "REQUEST_TIMEOUT", "SVG_SAFETY", "SPA_FALLBACK", "DISABLED_ROUTES", "MEDIA_WRITE_ENABLED", "TRASH_DIR", "TRASH_TTL", "WEBAUTHN_RP_ID",
 		"WEBAUTHN_RP_DISPLAY_NAME", "WEBAUTHN_RP_ORIGINS",
 	}
 	for _, key := range envVars {
@@ -138,6 +141,22 @@ func TestRawConfigDefaults(t *testing.T) {
 	if rc.gpuAccel != "auto" {
 		t.Errorf("gpuAccel = %q, want %q", rc.gpuAccel, "auto")
 	}
+	if rc.transcodePreset != "fast" {
+		t.Errorf("transcodePreset = %q, want %q", rc.transcodePreset, "fast")
+	}
+	if rc.transcodeCRF != 23 {
+		t.Errorf("transcodeCRF = %d, want 23", rc.transcodeCRF)
+	}
+	if rc.hlsSegment != "6s" {
+		t.Errorf("hlsSegment = %q, want %q", rc.hlsSegment, "6s")
+	}
+	if rc.hlsPrefetch != 0 {
+		t.Errorf("hlsPrefetch = %d, want 0", rc.hlsPrefetch)
+	}
+	if rc.transcoderLogMaxAge != "0" || rc.transcoderLogMaxMB != 0 || rc.transcoderLogErrors {
+		t.Errorf("transcoder log retention = %q, %d MB, errors only %v; want no limits",
+			rc.transcoderLogMaxAge, rc.transcoderLogMaxMB, rc.transcoderLogErrors)
+	}
 	if rc.port != "8080" {
 		t.Errorf("port = %q, want %q", rc.port, "8080")
 	}
@@ -153,6 +172,9 @@ func TestRawConfigDefaults(t *testing.T) {
 	if rc.pollInterval != "30s" {
 		t.Errorf("pollInterval = %q, want %q", rc.pollInterval, "30s")
 	}
+	if rc.indexQuietPeriod != "10s" {
+		t.Errorf("indexQuietPeriod = %q, want %q", rc.indexQuietPeriod, "10s")
+	}
 	if rc.sessionDuration != "5m" {
 		t.Errorf("sessionDuration = %q, want %q", rc.sessionDuration, "5m")
 	}
@@ -165,12 +187,120 @@ func TestRawConfigDefaults(t *testing.T) {
 	if !rc.logHealthChecks {
 		t.Error("logHealthChecks should default to true")
 	}
+	if rc.authRateLimit != 10 {
+		t.Errorf("authRateLimit = %d, want 10", rc.authRateLimit)
+	}
+	if rc.trustedProxies != defaultTrustedProxies {
+		t.Errorf("trustedProxies = %q, want %q", rc.trustedProxies, defaultTrustedProxies)
+	}
+	if rc.accessLogFormat != "w3c" {
+		t.Errorf("accessLogFormat should default to w3c, got %q", rc.accessLogFormat)
+	}
 	if !rc.metricsEnabled {
 		t.Error("metricsEnabled should default to true")
 	}
 	if rc.dbMmapDisabled {
 		t.Error("dbMmapDisabled should default to false")
 	}
+	if rc.dbRecoverCorrupt {
+		t.Error("dbRecoverCorrupt should default to false")
+	}
+	if rc.walAutoCheckpoint != 1000 {
+		t.Errorf("walAutoCheckpoint = %d, want 1000", rc.walAutoCheckpoint)
+	}
+	if rc.walIndexCheckpoint {
+		t.Error("walIndexCheckpoint should default to false")
+	}
+	if rc.indexBirthTime {
+		t.Error("indexBirthTime should default to false")
+	}
+	if rc.indexCamera {
+		t.Error("indexCamera should default to false")
+	}
+	if rc.indexExif {
+		t.Error("indexExif should default to false")
+	}
+	if rc.indexXMP {
+		t.Error("indexXMP should default to false")
+	}
+	if rc.indexStrictPerms {
+		t.Error("indexStrictPerms should default to false")
+	}
+	if rc.indexDuplicates {
+		t.Error("indexDuplicates should default to false")
+	}
+	if rc.indexHashMode != "sampled" || rc.indexHashAlgorithm != "sha256" {
+		t.Errorf("Expected hash defaults sampled/sha256, got %s/%s", rc.indexHashMode, rc.indexHashAlgorithm)
+	}
+	if rc.animatedDetection {
+		t.Error("animatedDetection should default to false")
+	}
+	if rc.spaFallback {
+		t.Error("spaFallback should default to false")
+	}
+	if rc.thumbnailDimensions {
+		t.Error("thumbnailDimensions should default to false")
+	}
+	if rc.disabledRoutes != "" {
+		t.Errorf("disabledRoutes should default to empty, got %q", rc.disabledRoutes)
+	}
+	if rc.mediaWriteEnabled {
+		t.Error("mediaWriteEnabled should default to false")
+	}
+	if rc.trashDir != "" || rc.trashTTL != "720h" {
+		t.Errorf("Expected trash defaults empty/720h, got %q/%q", rc.trashDir, rc.trashTTL)
+	}
+	if rc.indexProgress != 10000 {
+		t.Errorf("indexProgress = %d, want 10000", rc.indexProgress)
+	}
+	if rc.thumbnailStyle != "" {
+		t.Errorf("thumbnailStyle = %q, want empty", rc.thumbnailStyle)
+	}
+	if rc.thumbnailFormat != "jpeg" {
+		t.Errorf("thumbnailFormat = %q, want jpeg", rc.thumbnailFormat)
+	}
+	if rc.thumbnailSensitive != "blur" {
+		t.Errorf("thumbnailSensitive = %q, want blur", rc.thumbnailSensitive)
+	}
+	if rc.searchDidYouMean != 5 {
+		t.Errorf("searchDidYouMean = %d, want 5", rc.searchDidYouMean)
+	}
+	if rc.thumbnailSize != 200 {
+		t.Errorf("thumbnailSize = %d, want 200", rc.thumbnailSize)
+	}
+	if rc.thumbnailVariants != "256,512,1024" {
+		t.Errorf("thumbnailVariants = %q, want 256,512,1024", rc.thumbnailVariants)
+	}
+	if rc.otherThumbnails != "off" {
+		t.Errorf("otherThumbnails = %q, want off", rc.otherThumbnails)
+	}
+	if rc.changedFiles != "retry" {
+		t.Errorf("changedFiles = %q, want retry", rc.changedFiles)
+	}
+	if rc.largeFileMB != 0 {
+		t.Errorf("largeFileMB = %d, want 0", rc.largeFileMB)
+	}
+	if rc.largeFileWorkers != 1 {
+		t.Errorf("largeFileWorkers = %d, want 1", rc.largeFileWorkers)
+	}
+	if rc.thumbnailCacheMax != 0 {
+		t.Errorf("thumbnailCacheMax = %d, want 0", rc.thumbnailCacheMax)
+	}
+	if rc.thumbnailFreshness != "0" {
+		t.Errorf("thumbnailFreshness = %q, want %q", rc.thumbnailFreshness, "0")
+	}
+	if rc.thumbnailWaitTimeout != "2m" {
+		t.Errorf("thumbnailWaitTimeout = %q, want %q", rc.thumbnailWaitTimeout, "2m")
+	}
+	if rc.requestTimeout != "3m" {
+		t.Errorf("requestTimeout = %q, want %q", rc.requestTimeout, "3m")
+	}
+	if rc.streamMaxBytesPerSec != 0 {
+		t.Errorf("streamMaxBytesPerSec = %d, want 0", rc.streamMaxBytesPerSec)
+	}
+	if rc.svgSafety != "sandbox" {
+		t.Errorf("svgSafety = %q, want %q", rc.svgSafety, "sandbox")
+	}
 	if rc.webAuthnRPID != "" {
 		t.Errorf("webAuthnRPID = %q, want empty", rc.webAuthnRPID)
 	}
@@ -352,6 +482,7 @@ func TestParseDurations_ValidValues(t *testing.T) {
 		pollInterval:      "1m",
 		sessionDuration:   "15m",
 		sessionCleanup:    "5m",
+		transcodeMaxWait:  "2h",
 	}
 
 	d := parseDurations(rc)
@@ -371,6 +502,9 @@ func TestParseDurations_ValidValues(t *testing.T) {
 	if d.sessionCleanup != 5*time.Minute {
 		t.Errorf("sessionCleanup = %v, want 5m", d.sessionCleanup)
 	}
+	if d.transcodeMaxWait != 2*time.Hour {
+		t.Errorf("transcodeMaxWait = %v, want 2h", d.transcodeMaxWait)
+	}
 }
 
 func TestParseDurations_InvalidValues(t *testing.T) {
@@ -378,8 +512,10 @@ func TestParseDurations_InvalidValues(t *testing.T) {
 		indexInterval:     "bad",
 		thumbnailInterval: "nope",
 		pollInterval:      "invalid",
+		indexQuietPeriod:  "soon",
 		sessionDuration:   "wrong",
 		sessionCleanup:    "broken",
+		transcodeMaxWait:  "forever",
 	}
 
 	d := parseDurations(rc)
@@ -393,12 +529,18 @@ func TestParseDurations_InvalidValues(t *testing.T) {
 	if d.pollInterval != 30*time.Second {
 		t.Errorf("pollInterval = %v, want default 30s", d.pollInterval)
 	}
+	if d.indexQuietPeriod != 10*time.Second {
+		t.Errorf("indexQuietPeriod = %v, want default 10s", d.indexQuietPeriod)
+	}
 	if d.sessionDuration != 5*time.Minute {
 		t.Errorf("sessionDuration = %v, want default 5m", d.sessionDuration)
 	}
 	if d.sessionCleanup != 1*time.Minute {
 		t.Errorf("sessionCleanup = %v, want default 1m", d.sessionCleanup)
 	}
+	if d.transcodeMaxWait != 30*time.Minute {
+		t.Errorf("transcodeMaxWait = %v, want default 30m", d.transcodeMaxWait)
+	}
 }
 
 func TestParseDurations_MixedValues(t *testing.T) {
@@ -163,6 +163,35 @@ func TestGetEnvBool(t *testing.T) {
 	}
 }
 
+func TestGetEnvInt(t *testing.T) {
+	tests := []struct {
+		name         string
+		envValue     string
+		defaultValue int
+		want         int
+		setEnv       bool
+	}{
+		{"Returns default when unset", "", 1000, 1000, false},
+		{"Returns default when empty", "", 1000, 1000, true},
+		{"Parses positive value", "4000", 1000, 4000, true},
+		{"Parses zero", "0", 1000, 0, true},
+		{"Parses negative value", "-1", 1000, -1, true},
+		{"Returns default when invalid", "lots", 1000, 1000, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setEnv {
+				t.Setenv("TEST_INT", tt.envValue)
+			}
+
+			if got := getEnvInt("TEST_INT", tt.defaultValue); got != tt.want {
+				t.Errorf("getEnvInt(%q, %d) = %d, want %d (env: %q)", "TEST_INT", tt.defaultValue, got, tt.want, tt.envValue)
+			}
+		})
+	}
+}
+
 func TestFormatBytesStartup(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -362,6 +391,19 @@ func TestLogMemoryConfig_MEMORY_LIMIT(_ *testing.T) {
 	LogMemoryConfig(mc)
 }
 
+func TestLogMemoryConfig_Cgroup(_ *testing.T) {
+	mc := MemoryConfig{
+		Configured:     true,
+		Source:         "cgroup-v2",
+		ContainerLimit: 1073741824,
+		GoMemLimit:     912680550,
+		Ratio:          0.85,
+	}
+
+	// Should not panic
+	LogMemoryConfig(mc)
+}
+
 func TestLogWebAuthnInit_Disabled(_ *testing.T) {
 	// Should not panic
 	LogWebAuthnInit(false, "")
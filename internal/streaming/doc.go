@@ -55,10 +55,29 @@ For more control, create a TimeoutWriter directly:
 			// Handle error
 		}
 
-		bytesWritten, duration := tw.Stats()
-		log.Printf("Complete: %d bytes in %v", bytesWritten, duration)
+		bytesWritten, duration, rate := tw.Stats()
+		log.Printf("Complete: %d bytes in %v at %.0f bytes/s", bytesWritten, duration, rate)
 	}
 
+# Profiles
+
+Rather than tuning a config by hand, features pick a named profile so that
+streams of the same kind behave consistently:
+
+	config := streaming.ProfileConfig(streaming.ProfileVideo)
+
+The profiles are:
+
+  - ProfileVideo ("video"): 256KB chunks, 30s write and 60s idle timeouts,
+    for media streams
+  - ProfileBulkDownload ("bulk-download"): 1MB chunks, 60s write and 2m idle
+    timeouts, for large generated downloads such as archives
+  - ProfileAPI ("api"): 32KB chunks, 10s write and 30s idle timeouts, and a
+    2m limit on the whole response
+
+Unknown names get DefaultTimeoutWriterConfig. The returned config is a copy
+and can be adjusted before use.
+
 # Configuration
 
 TimeoutWriterConfig controls the behavior of timeout-protected streaming:
@@ -85,6 +104,12 @@ TimeoutWriterConfig controls the behavior of timeout-protected streaming:
 		// Default: 64KB
 		ChunkSize int
 
+		// MaxBytesPerSecond limits the stream's bandwidth with a token
+		// bucket, pacing chunks while still honoring WriteTimeout and
+		// cancellation. Set to 0 for unlimited bandwidth.
+		// Default: 0 (unlimited)
+		MaxBytesPerSecond int64
+
 		// OnProgress is called periodically with streaming statistics.
 		// Useful for logging or metrics. May be nil.
 		OnProgress func(bytesWritten int64, duration time.Duration)
@@ -118,6 +143,24 @@ These errors can be checked using errors.Is:
 		return
 	}
 
+# JSON Arrays
+
+JSONArrayWriter encodes a JSON array one element at a time, for responses
+built from a database row stream. Buffer the underlying writer, since each
+element is written separately:
+
+	buf := bufio.NewWriter(tw)
+	items := streaming.NewJSONArrayWriter(buf)
+	err := db.StreamFiles(ctx, opts, func(f database.MediaFile) error {
+		return items.Encode(f)
+	})
+	if err == nil {
+		err = items.Close()
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+
 # Thread Safety
 
 TimeoutWriter is safe for concurrent use from multiple goroutines, though typical
@@ -134,6 +177,33 @@ It implements io.Writer and properly handles http.Flusher for streaming response
 	w.Header().Set("Transfer-Encoding", "chunked")
 	streaming.StreamWithTimeout(ctx, w, videoFile, config)
 
+It is an http.Flusher itself, so a response can be sent early without
+waiting for a chunk to fill, such as the first element of a long listing.
+
+# Shutdown
+
+Every TimeoutWriter is tracked from creation until Close. DrainAll asks them
+all to stop once the chunk in progress is written, then waits for their
+handlers to close them:
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if remaining := streaming.DrainAll(ctx); remaining > 0 {
+		log.Printf("%d streams still open", remaining)
+	}
+	srv.Shutdown(ctx)
+
+A drained writer's Write returns ErrStreamCanceled, so the handler returns
+and the response ends cleanly instead of being cut off by the server.
+
+Handlers that write their responses themselves, such as with http.ServeFile,
+are tracked by wrapping them with the Track middleware, and DrainAll waits
+for them to return too. Their ThrottledWriters stop like drained
+TimeoutWriters; unthrottled responses are left to finish:
+
+	streams := router.PathPrefix("/api").Subrouter()
+	streams.Use(streaming.Track)
+
 # Performance Considerations
 
   - ChunkSize affects memory usage and responsiveness. Larger chunks are more
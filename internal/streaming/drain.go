@@ -0,0 +1,124 @@
+package streaming
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// stream is a response DrainAll can stop and wait for: a TimeoutWriter, or a
+// request served by a handler wrapped with Track
+type stream interface {
+	// drain asks the stream to stop at the end of the chunk it is writing
+	drain()
+	// finished is closed once the stream has ended
+	finished() <-chan struct{}
+}
+
+// active tracks the open streams so DrainAll can stop them at shutdown. Once
+// draining, streams opened later start out draining too.
+var active = struct {
+	mu       sync.Mutex
+	streams  map[stream]struct{}
+	draining bool
+}{streams: make(map[stream]struct{})}
+
+// register adds a new stream to the active set
+func register(s stream) {
+	active.mu.Lock()
+	defer active.mu.Unlock()
+	active.streams[s] = struct{}{}
+	if active.draining {
+		s.drain()
+	}
+}
+
+// unregister removes an ended stream from the active set
+func unregister(s stream) {
+	active.mu.Lock()
+	defer active.mu.Unlock()
+	delete(active.streams, s)
+}
+
+// draining reports whether DrainAll has been called
+func draining() bool {
+	active.mu.Lock()
+	defer active.mu.Unlock()
+	return active.draining
+}
+
+// ActiveStreams returns the number of TimeoutWriters that haven't been closed
+// and tracked requests that haven't finished
+func ActiveStreams() int {
+	active.mu.Lock()
+	defer active.mu.Unlock()
+	return len(active.streams)
+}
+
+// trackedRequest is a request served by a handler wrapped with Track
+type trackedRequest struct {
+	done chan struct{}
+}
+
+// drain does nothing: the handler's ThrottledWriters stop by themselves once
+// draining, and unthrottled responses are left to finish
+func (tr *trackedRequest) drain() {}
+
+func (tr *trackedRequest) finished() <-chan struct{} {
+	return tr.done
+}
+
+// Track is middleware that tracks the requests of handlers writing their
+// responses themselves, such as with http.ServeFile behind a ThrottledWriter,
+// so DrainAll waits for them as it does for TimeoutWriters.
+func Track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tr := &trackedRequest{done: make(chan struct{})}
+		register(tr)
+		defer func() {
+			unregister(tr)
+			close(tr.done)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// DrainAll asks every open stream to stop at the end of the chunk it is
+// writing, and waits until they have all ended or ctx is done. A drained
+// TimeoutWriter's or ThrottledWriter's next Write returns ErrStreamCanceled,
+// so handlers finish their responses normally and clients see the stream end
+// rather than a reset connection when the server shuts down. Requests
+// tracked with Track are waited for until their handlers return.
+//
+// Streams opened while it waits are drained and waited for too. It returns
+// the number of streams still open when ctx ended the wait, or 0 once
+// they've all ended.
+func DrainAll(ctx context.Context) int {
+	for {
+		streams := startDraining()
+		if len(streams) == 0 {
+			return 0
+		}
+		for _, s := range streams {
+			select {
+			case <-s.finished():
+			case <-ctx.Done():
+				return ActiveStreams()
+			}
+		}
+	}
+}
+
+// startDraining marks the open streams and any opened later as draining, and
+// returns the open ones
+func startDraining() []stream {
+	active.mu.Lock()
+	defer active.mu.Unlock()
+	active.draining = true
+	streams := make([]stream, 0, len(active.streams))
+	for s := range active.streams {
+		s.drain()
+		streams = append(streams, s)
+	}
+	return streams
+}
@@ -0,0 +1,73 @@
+package streaming
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ErrJSONArrayClosed is returned when encoding into a closed JSONArrayWriter
+var ErrJSONArrayClosed = errors.New("json array writer closed")
+
+// JSONArrayWriter encodes a JSON array one element at a time, so responses
+// built from a row stream never hold the whole result in memory. Each element
+// is a separate small write, so wrap slow writers such as a TimeoutWriter in a
+// bufio.Writer.
+type JSONArrayWriter struct {
+	w      io.Writer
+	count  int
+	closed bool
+}
+
+// NewJSONArrayWriter creates a JSONArrayWriter that writes to w. Nothing is
+// written until the first Encode or Close.
+func NewJSONArrayWriter(w io.Writer) *JSONArrayWriter {
+	return &JSONArrayWriter{w: w}
+}
+
+// Encode marshals v and appends it to the array
+func (a *JSONArrayWriter) Encode(v any) error {
+	if a.closed {
+		return ErrJSONArrayClosed
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	sep := ","
+	if a.count == 0 {
+		sep = "["
+	}
+	if _, err := io.WriteString(a.w, sep); err != nil {
+		return err
+	}
+	if _, err := a.w.Write(data); err != nil {
+		return err
+	}
+
+	a.count++
+	return nil
+}
+
+// Count returns the number of elements encoded so far
+func (a *JSONArrayWriter) Count() int {
+	return a.count
+}
+
+// Close ends the array, writing an empty one if nothing was encoded. It does
+// not close the underlying writer.
+func (a *JSONArrayWriter) Close() error {
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+
+	end := "]"
+	if a.count == 0 {
+		end = "[]"
+	}
+	_, err := io.WriteString(a.w, end)
+	return err
+}
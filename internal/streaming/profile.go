@@ -0,0 +1,47 @@
+package streaming
+
+import "time"
+
+// Named streaming profiles. Each feature that streams picks the profile that
+// matches its traffic rather than tuning a TimeoutWriterConfig by hand.
+const (
+	// ProfileVideo suits long-running media streams, where larger chunks
+	// keep throughput up and seeking clients may pause briefly
+	ProfileVideo = "video"
+	// ProfileBulkDownload suits large generated downloads such as archives,
+	// which may stall between entries while the next one is read
+	ProfileBulkDownload = "bulk-download"
+	// ProfileAPI suits small responses that should finish quickly
+	ProfileAPI = "api"
+)
+
+// profiles holds the settings for each named profile
+var profiles = map[string]TimeoutWriterConfig{
+	ProfileVideo: {
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  60 * time.Second,
+		ChunkSize:    256 * 1024,
+	},
+	ProfileBulkDownload: {
+		WriteTimeout: 60 * time.Second,
+		IdleTimeout:  2 * time.Minute,
+		ChunkSize:    1024 * 1024,
+	},
+	ProfileAPI: {
+		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  30 * time.Second,
+		MaxDuration:  2 * time.Minute,
+		ChunkSize:    32 * 1024,
+	},
+}
+
+// ProfileConfig returns the TimeoutWriterConfig for a named profile. Unknown
+// names get DefaultTimeoutWriterConfig. The result is a copy, so callers may
+// adjust it, e.g. to set OnProgress.
+func ProfileConfig(name string) TimeoutWriterConfig {
+	profile, ok := profiles[name]
+	if !ok {
+		return DefaultTimeoutWriterConfig()
+	}
+	return profile
+}
@@ -3,7 +3,11 @@ package streaming
 import (
 	"context"
 	"errors"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -62,6 +66,39 @@ func TestDefaultTimeoutWriterConfig(t *testing.T) {
 	}
 }
 
+func TestProfileConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		writeTimeout time.Duration
+		maxDuration  time.Duration
+		chunkSize    int
+	}{
+		{ProfileVideo, 30 * time.Second, 0, 256 * 1024},
+		{ProfileBulkDownload, 60 * time.Second, 0, 1024 * 1024},
+		{ProfileAPI, 10 * time.Second, 2 * time.Minute, 32 * 1024},
+		{"unknown", 30 * time.Second, 0, 64 * 1024},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ProfileConfig(tt.name)
+			if config.WriteTimeout != tt.writeTimeout || config.MaxDuration != tt.maxDuration || config.ChunkSize != tt.chunkSize {
+				t.Errorf("Unexpected config %+v", config)
+			}
+			if config.IdleTimeout <= config.WriteTimeout {
+				t.Errorf("Expected IdleTimeout above WriteTimeout, got %v", config.IdleTimeout)
+			}
+		})
+	}
+
+	// Callers get a copy they can change
+	config := ProfileConfig(ProfileVideo)
+	config.ChunkSize = 1
+	if ProfileConfig(ProfileVideo).ChunkSize == 1 {
+		t.Error("Changing a returned config should not change the profile")
+	}
+}
+
 func TestNewTimeoutWriter(t *testing.T) {
 	ctx := context.Background()
 	w := httptest.NewRecorder()
@@ -105,7 +142,7 @@ func TestTimeoutWriterWrite(t *testing.T) {
 	}
 
 	// Stats should track bytes written correctly
-	bytesWritten, _ := tw.Stats()
+	bytesWritten, _, _ := tw.Stats()
 	if bytesWritten != int64(len(data)) {
 		t.Errorf("Expected bytes written=%d, got %d", len(data), bytesWritten)
 	}
@@ -182,7 +219,7 @@ func TestTimeoutWriterBytesWritten(t *testing.T) {
 		totalBytes += int64(n)
 
 		// Verify bytes were written
-		bytesWritten, _ := tw.Stats()
+		bytesWritten, _, _ := tw.Stats()
 		if bytesWritten != totalBytes {
 			t.Errorf("Expected bytes written=%d, got %d", totalBytes, bytesWritten)
 		}
@@ -198,13 +235,16 @@ func TestTimeoutWriterStats(t *testing.T) {
 	defer tw.Close()
 
 	// Stats should show zero bytes and minimal duration initially
-	bytesWritten, duration := tw.Stats()
+	bytesWritten, duration, rate := tw.Stats()
 	if bytesWritten != 0 {
 		t.Errorf("Initial bytes written should be 0, got %d", bytesWritten)
 	}
 	if duration > 100*time.Millisecond {
 		t.Errorf("Initial duration too high: %v", duration)
 	}
+	if rate != 0 {
+		t.Errorf("Initial rate should be 0, got %v", rate)
+	}
 
 	// Write some data
 	data := []byte("test data")
@@ -217,7 +257,7 @@ func TestTimeoutWriterStats(t *testing.T) {
 	time.Sleep(50 * time.Millisecond)
 
 	// Stats should show written bytes and increased duration
-	bytesWritten, duration = tw.Stats()
+	bytesWritten, duration, rate = tw.Stats()
 	if bytesWritten != int64(len(data)) {
 		t.Errorf("Expected bytes written=%d, got %d", len(data), bytesWritten)
 	}
@@ -227,6 +267,9 @@ func TestTimeoutWriterStats(t *testing.T) {
 	if duration > 200*time.Millisecond {
 		t.Errorf("Duration unexpectedly high: %v", duration)
 	}
+	if want := float64(bytesWritten) / duration.Seconds(); rate < want*0.9 || rate > want*1.1 {
+		t.Errorf("Expected a rate of about %.0f bytes/s, got %.0f", want, rate)
+	}
 }
 
 func TestTimeoutWriterOnProgress(t *testing.T) {
@@ -475,7 +518,7 @@ func TestTimeoutWriterChunkedWrites(t *testing.T) {
 		t.Errorf("Expected to write %d bytes, wrote %d", len(data), n)
 	}
 
-	bytesWritten, _ := tw.Stats()
+	bytesWritten, _, _ := tw.Stats()
 	if bytesWritten != int64(len(data)) {
 		t.Errorf("Expected %d bytes written total, got %d", len(data), bytesWritten)
 	}
@@ -521,3 +564,261 @@ func BenchmarkTimeoutWriterCreation(b *testing.B) {
 		tw.Close()
 	}
 }
+
+func TestThrottledWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := NewThrottledWriter(context.Background(), rec, 4000)
+
+	// The first 1000 bytes (a quarter second) go out at once, the rest at 4000 bytes/s
+	start := time.Now()
+	n, err := w.Write(make([]byte, 3000))
+	elapsed := time.Since(start)
+
+	if err != nil || n != 3000 {
+		t.Fatalf("Write = %d, %v; want 3000, nil", n, err)
+	}
+	if rec.Body.Len() != 3000 {
+		t.Errorf("Expected 3000 bytes written through, got %d", rec.Body.Len())
+	}
+	if elapsed < 400*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Expected about 500ms of throttling, took %v", elapsed)
+	}
+}
+
+func TestNewThrottledWriterUnlimited(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if w := NewThrottledWriter(context.Background(), rec, 0); w != rec {
+		t.Error("Expected the writer itself without a limit")
+	}
+}
+
+func TestThrottledWriterClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := NewThrottledWriter(ctx, httptest.NewRecorder(), 100)
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	n, err := w.Write(make([]byte, 1000))
+	if !errors.Is(err, ErrClientGone) {
+		t.Errorf("Expected ErrClientGone, got %v", err)
+	}
+	if n >= 1000 {
+		t.Errorf("Expected the write to stop early, wrote %d bytes", n)
+	}
+}
+
+func TestTimeoutWriterMaxBytesPerSecond(t *testing.T) {
+	rec := httptest.NewRecorder()
+	config := DefaultTimeoutWriterConfig()
+	config.MaxBytesPerSecond = 4000
+
+	tw := NewTimeoutWriter(context.Background(), rec, config)
+	defer tw.Close()
+
+	// Chunks shrink to the limiter's burst so each wait stays short
+	if got := tw.chunkSize(); got != 1000 {
+		t.Errorf("Expected chunks capped at 1000 bytes, got %d", got)
+	}
+
+	// The first 1000 bytes go out at once, the other 5000 at 4000 bytes/s
+	start := time.Now()
+	n, err := tw.Write(make([]byte, 6000))
+	elapsed := time.Since(start)
+
+	if err != nil || n != 6000 {
+		t.Fatalf("Write = %d, %v; want 6000, nil", n, err)
+	}
+	if elapsed < 1150*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Expected about 1.25s of throttling, took %v", elapsed)
+	}
+
+	// Averaged from when the writer was created, the burst lifts the rate
+	// just above the limit
+	if _, _, rate := tw.Stats(); rate < 3000 || rate > 5200 {
+		t.Errorf("Expected an average rate near 4000 bytes/s, got %.0f", rate)
+	}
+}
+
+func TestJSONArrayWriter(t *testing.T) {
+	var buf strings.Builder
+	a := NewJSONArrayWriter(&buf)
+
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing written before the first element, got %q", buf.String())
+	}
+	for _, v := range []any{map[string]int{"n": 1}, "two", 3} {
+		if err := a.Encode(v); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if got := buf.String(); got != `[{"n":1},"two",3]` {
+		t.Errorf("Unexpected output %s", got)
+	}
+	if a.Count() != 3 {
+		t.Errorf("Expected count 3, got %d", a.Count())
+	}
+	if err := a.Encode(4); !errors.Is(err, ErrJSONArrayClosed) {
+		t.Errorf("Expected ErrJSONArrayClosed after Close, got %v", err)
+	}
+}
+
+func TestJSONArrayWriterEmpty(t *testing.T) {
+	var buf strings.Builder
+	a := NewJSONArrayWriter(&buf)
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got := buf.String(); got != "[]" {
+		t.Errorf("Expected an empty array, got %s", got)
+	}
+}
+
+// isolateDrain gives the test its own set of active streams, so streams other
+// tests left open don't hold up DrainAll, and undoes draining afterwards
+func isolateDrain(t *testing.T) {
+	t.Helper()
+	active.mu.Lock()
+	saved := active.streams
+	active.streams = make(map[stream]struct{})
+	active.mu.Unlock()
+	t.Cleanup(func() {
+		active.mu.Lock()
+		active.streams = saved
+		active.draining = false
+		active.mu.Unlock()
+	})
+}
+
+// slowRecorder is a response recorder for a client that takes a while to
+// receive each write
+type slowRecorder struct {
+	*httptest.ResponseRecorder
+	delay time.Duration
+}
+
+func (r slowRecorder) Write(p []byte) (int, error) {
+	time.Sleep(r.delay)
+	return r.ResponseRecorder.Write(p)
+}
+
+func TestDrainAllStopsStreams(t *testing.T) {
+	isolateDrain(t)
+
+	config := DefaultTimeoutWriterConfig()
+	config.ChunkSize = 1000
+	tw := NewTimeoutWriter(context.Background(), slowRecorder{httptest.NewRecorder(), 50 * time.Millisecond}, config)
+	if got := ActiveStreams(); got != 1 {
+		t.Fatalf("Expected 1 active stream, got %d", got)
+	}
+
+	// A handler streaming 6000 bytes to a slow client, closing when done
+	type result struct {
+		n   int
+		err error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		defer tw.Close()
+		n, err := tw.Write(make([]byte, 6000))
+		resultCh <- result{n, err}
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if remaining := DrainAll(ctx); remaining != 0 {
+		t.Errorf("Expected every stream to be drained, %d remain", remaining)
+	}
+
+	res := <-resultCh
+	if !errors.Is(res.err, ErrStreamCanceled) {
+		t.Errorf("Expected ErrStreamCanceled, got %v", res.err)
+	}
+	if res.n == 0 || res.n%1000 != 0 || res.n >= 6000 {
+		t.Errorf("Expected the write to stop after a whole chunk, wrote %d bytes", res.n)
+	}
+	if got := ActiveStreams(); got != 0 {
+		t.Errorf("Expected no active streams, got %d", got)
+	}
+
+	// Streams opened after draining starts are drained straight away
+	late := NewTimeoutWriter(context.Background(), httptest.NewRecorder(), DefaultTimeoutWriterConfig())
+	defer late.Close()
+	if _, err := late.Write([]byte("late")); !errors.Is(err, ErrStreamCanceled) {
+		t.Errorf("Expected ErrStreamCanceled for a stream opened while draining, got %v", err)
+	}
+}
+
+func TestDrainAllTimeout(t *testing.T) {
+	isolateDrain(t)
+
+	if remaining := DrainAll(context.Background()); remaining != 0 {
+		t.Errorf("Expected nothing to drain, %d remain", remaining)
+	}
+
+	// Never written to again or closed by its handler
+	tw := NewTimeoutWriter(context.Background(), httptest.NewRecorder(), DefaultTimeoutWriterConfig())
+	defer tw.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if remaining := DrainAll(ctx); remaining != 1 {
+		t.Errorf("Expected 1 stream left open, got %d", remaining)
+	}
+}
+
+func TestDrainAllWaitsForTrackedRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	if err := os.WriteFile(path, make([]byte, 100000), 0o644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	// Served unthrottled to a slow client, and throttled to 4000 bytes per
+	// second, which would take 25 seconds
+	tests := []struct {
+		name     string
+		rate     int64
+		complete bool
+	}{
+		{"unthrottled", 0, true},
+		{"throttled", 4000, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateDrain(t)
+
+			handler := Track(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.ServeFile(NewThrottledWriter(r.Context(), w, tt.rate), r, path)
+			}))
+			rec := httptest.NewRecorder()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				handler.ServeHTTP(slowRecorder{rec, 50 * time.Millisecond}, httptest.NewRequest(http.MethodGet, "/video.mp4", http.NoBody))
+			}()
+
+			time.Sleep(100 * time.Millisecond)
+			if got := ActiveStreams(); got != 1 {
+				t.Fatalf("Expected 1 active stream, got %d", got)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if remaining := DrainAll(ctx); remaining != 0 {
+				t.Errorf("Expected every stream to be drained, %d remain", remaining)
+			}
+			select {
+			case <-done:
+			default:
+				t.Fatal("Expected DrainAll to wait for the handler to return")
+			}
+
+			if complete := rec.Body.Len() == 100000; complete != tt.complete {
+				t.Errorf("Expected complete=%v, got %d bytes", tt.complete, rec.Body.Len())
+			}
+		})
+	}
+}
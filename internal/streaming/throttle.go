@@ -0,0 +1,127 @@
+package streaming
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket limiting a single stream to a number of bytes
+// per second. The bucket holds a quarter second of data, so streams start
+// promptly and are then paced evenly.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter for bytesPerSec, or nil if it is not positive
+func newRateLimiter(bytesPerSec int64) *rateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	burst := max(bytesPerSec/4, 1)
+	if burst > math.MaxInt32 {
+		burst = math.MaxInt32
+	}
+	return &rateLimiter{
+		rate:   float64(bytesPerSec),
+		burst:  int(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until n bytes may be sent. n must not exceed the burst size.
+// Returns the context's error if it is canceled while waiting.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(float64(l.burst), l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	// Take the tokens now, so concurrent writers queue up behind each other
+	l.tokens -= float64(n)
+	deficit := -l.tokens
+	l.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(deficit / l.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ThrottledWriter wraps an http.ResponseWriter and limits how fast the
+// response body is written. Use it for responses served by http.ServeFile
+// and similar, which write to the response directly; TimeoutWriter limits
+// its own streams through TimeoutWriterConfig.MaxBytesPerSecond.
+type ThrottledWriter struct {
+	http.ResponseWriter
+	ctx     context.Context
+	limiter *rateLimiter
+}
+
+// NewThrottledWriter returns w limited to bytesPerSec, or w itself if
+// bytesPerSec is not positive.
+func NewThrottledWriter(ctx context.Context, w http.ResponseWriter, bytesPerSec int64) http.ResponseWriter {
+	limiter := newRateLimiter(bytesPerSec)
+	if limiter == nil {
+		return w
+	}
+	return &ThrottledWriter{ResponseWriter: w, ctx: ctx, limiter: limiter}
+}
+
+// Write writes p in pieces no larger than the limiter's burst, waiting
+// before each one as needed. Once DrainAll is called it stops before the
+// next piece with ErrStreamCanceled.
+func (tw *ThrottledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if draining() {
+			return written, ErrStreamCanceled
+		}
+		size := min(len(p), tw.limiter.burst)
+		if err := tw.limiter.wait(tw.ctx, size); err != nil {
+			return written, throttleError(err)
+		}
+
+		n, err := tw.ResponseWriter.Write(p[:size])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[size:]
+	}
+	return written, nil
+}
+
+// Flush sends buffered data to the client if the underlying writer supports it
+func (tw *ThrottledWriter) Flush() {
+	if flusher, ok := tw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (tw *ThrottledWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// throttleError maps a context error from waiting to the package's errors
+func throttleError(err error) error {
+	if err == context.Canceled {
+		return ErrClientGone
+	}
+	return ErrStreamCanceled
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"media-viewer/internal/logging"
@@ -22,7 +23,8 @@ var (
 	ErrClientGone = errors.New("client disconnected")
 
 	// ErrStreamCancelled indicates that the stream was canceled programmatically,
-	// either by calling Close() on the TimeoutWriter or via context cancellation.
+	// either by calling Close() on the TimeoutWriter, by DrainAll, or via
+	// context cancellation.
 	ErrStreamCanceled = errors.New("stream canceled")
 )
 
@@ -36,6 +38,9 @@ type TimeoutWriterConfig struct {
 	MaxDuration time.Duration
 	// ChunkSize is the size of chunks to write (0 = write as received)
 	ChunkSize int
+	// MaxBytesPerSecond limits the stream's bandwidth (0 = unlimited). Chunks
+	// are capped at a quarter second of data so they stay within WriteTimeout.
+	MaxBytesPerSecond int64
 	// OnProgress is called periodically with bytes written
 	OnProgress func(bytesWritten int64, duration time.Duration)
 }
@@ -64,6 +69,9 @@ type TimeoutWriter struct {
 	writeMu      sync.Mutex // Serializes actual writes to underlying writer
 	closed       bool
 	flusher      http.Flusher
+	limiter      *rateLimiter  // nil unless MaxBytesPerSecond is set
+	draining     atomic.Bool   // Set by DrainAll; checked between chunks
+	done         chan struct{} // Closed by Close
 }
 
 // NewTimeoutWriter creates a new timeout-protected writer
@@ -77,7 +85,10 @@ func NewTimeoutWriter(ctx context.Context, w http.ResponseWriter, config Timeout
 		config:    config,
 		startTime: time.Now(),
 		lastWrite: time.Now(),
+		limiter:   newRateLimiter(config.MaxBytesPerSecond),
+		done:      make(chan struct{}),
 	}
+	register(tw)
 
 	// Check if the underlying writer supports flushing
 	if flusher, ok := w.(http.Flusher); ok {
@@ -99,6 +110,10 @@ func (tw *TimeoutWriter) Write(p []byte) (n int, err error) {
 	}
 	tw.mu.Unlock()
 
+	if tw.draining.Load() {
+		return 0, ErrStreamCanceled
+	}
+
 	// Check context before writing
 	select {
 	case <-tw.ctx.Done():
@@ -112,30 +127,63 @@ func (tw *TimeoutWriter) Write(p []byte) (n int, err error) {
 	}
 
 	// Write in chunks if configured
-	if tw.config.ChunkSize > 0 && len(p) > tw.config.ChunkSize {
+	if chunkSize := tw.chunkSize(); chunkSize > 0 && len(p) > chunkSize {
 		return tw.writeChunked(p)
 	}
 
+	if err := tw.throttle(len(p)); err != nil {
+		return 0, err
+	}
 	return tw.writeWithTimeout(p)
 }
 
+// chunkSize returns the configured chunk size, capped at the rate limiter's
+// burst when bandwidth is limited
+func (tw *TimeoutWriter) chunkSize() int {
+	if tw.limiter == nil {
+		return tw.config.ChunkSize
+	}
+	if tw.config.ChunkSize > 0 {
+		return min(tw.config.ChunkSize, tw.limiter.burst)
+	}
+	return tw.limiter.burst
+}
+
+// throttle waits until n bytes may be written under MaxBytesPerSecond
+func (tw *TimeoutWriter) throttle(n int) error {
+	if tw.limiter == nil {
+		return nil
+	}
+	if err := tw.limiter.wait(tw.ctx, n); err != nil {
+		return tw.contextError()
+	}
+	return nil
+}
+
 // writeChunked writes data in smaller chunks
 func (tw *TimeoutWriter) writeChunked(p []byte) (int, error) {
 	totalWritten := 0
 
 	for len(p) > 0 {
-		// Check context between chunks
+		// Check context and draining between chunks
 		select {
 		case <-tw.ctx.Done():
 			return totalWritten, tw.contextError()
 		default:
 		}
+		if tw.draining.Load() {
+			return totalWritten, ErrStreamCanceled
+		}
 
-		chunkSize := tw.config.ChunkSize
+		chunkSize := tw.chunkSize()
 		if len(p) < chunkSize {
 			chunkSize = len(p)
 		}
 
+		if err := tw.throttle(chunkSize); err != nil {
+			return totalWritten, err
+		}
+
 		n, err := tw.writeWithTimeout(p[:chunkSize])
 		totalWritten += n
 
@@ -198,6 +246,17 @@ func (tw *TimeoutWriter) writeWithTimeout(p []byte) (int, error) {
 	}
 }
 
+// Flush sends anything buffered by the underlying writer to the client, if
+// it supports flushing
+func (tw *TimeoutWriter) Flush() {
+	if tw.flusher == nil {
+		return
+	}
+	tw.writeMu.Lock()
+	defer tw.writeMu.Unlock()
+	tw.flusher.Flush()
+}
+
 // idleChecker monitors for idle connections
 func (tw *TimeoutWriter) idleChecker() {
 	if tw.config.IdleTimeout <= 0 {
@@ -239,6 +298,16 @@ func (tw *TimeoutWriter) contextError() error {
 	return ErrStreamCanceled
 }
 
+// drain makes the next Write return ErrStreamCanceled
+func (tw *TimeoutWriter) drain() {
+	tw.draining.Store(true)
+}
+
+// finished is closed by Close
+func (tw *TimeoutWriter) finished() <-chan struct{} {
+	return tw.done
+}
+
 // Close marks the writer as closed
 func (tw *TimeoutWriter) Close() error {
 	tw.mu.Lock()
@@ -250,15 +319,23 @@ func (tw *TimeoutWriter) Close() error {
 
 	tw.closed = true
 	tw.cancel()
+	close(tw.done)
+	unregister(tw)
 
 	return nil
 }
 
-// Stats returns streaming statistics
-func (tw *TimeoutWriter) Stats() (bytesWritten int64, duration time.Duration) {
+// Stats returns streaming statistics: the bytes written so far, how long the
+// stream has been open, and the average rate it achieved in bytes per second.
+// With MaxBytesPerSecond set, the rate settles at or just under the limit.
+func (tw *TimeoutWriter) Stats() (bytesWritten int64, duration time.Duration, bytesPerSec float64) {
 	tw.mu.Lock()
 	defer tw.mu.Unlock()
-	return tw.bytesWritten, time.Since(tw.startTime)
+	duration = time.Since(tw.startTime)
+	if duration > 0 {
+		bytesPerSec = float64(tw.bytesWritten) / duration.Seconds()
+	}
+	return tw.bytesWritten, duration, bytesPerSec
 }
 
 // StreamWithTimeout streams from a reader to an HTTP response with timeout protection
@@ -277,8 +354,8 @@ func StreamWithTimeout(ctx context.Context, w http.ResponseWriter, r io.Reader,
 	// Copy with our timeout writer
 	_, err := io.Copy(tw, r)
 
-	bytesWritten, duration := tw.Stats()
-	logging.Debug("Stream completed: %d bytes in %v", bytesWritten, duration)
+	bytesWritten, duration, rate := tw.Stats()
+	logging.Debug("Stream completed: %d bytes in %v (%.0f bytes/s)", bytesWritten, duration, rate)
 
 	return err
 }
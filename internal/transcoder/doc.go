@@ -7,6 +7,7 @@
 // # Features
 //
 //   - Automatic codec detection to determine if transcoding is needed
+//   - Chapter marker extraction for seekable chapter lists
 //   - On-the-fly transcoding with FFmpeg for incompatible formats
 //   - Direct streaming for browser-compatible videos (H.264, VP8, VP9, AV1)
 //   - Resolution scaling support for adaptive quality
@@ -50,6 +51,20 @@
 //
 //	freedBytes, err := trans.ClearCache()
 //
+// Each cache entry has a JSON manifest next to it recording its source, target
+// width, codec and whether the transcode finished. Call ReconcileCache at
+// startup to remove files left behind by transcodes a restart interrupted:
+//
+//	valid, removed := trans.ReconcileCache()
+//
+// Videos can also be served as HLS: GetOrStartHLS starts transcoding a video
+// into fragmented MP4 segments behind a playlist, kept in the cache's hls
+// directory, and returns once the first segment is written. HLSFilePath
+// looks up the segments as they appear, and PrefetchHLS reads the ones after
+// a served segment ahead of the player.
+//
+//	playlist, err := trans.GetOrStartHLS(ctx, "/path/to/video.mkv", 1280, info)
+//
 // # Configuration
 //
 // The transcoder can be disabled by passing false as the enabled parameter to New().
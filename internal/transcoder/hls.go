@@ -0,0 +1,628 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"media-viewer/internal/logging"
+	"media-viewer/internal/memory"
+)
+
+// HLSPlaylistName is the name of the playlist of an HLS rendition. The init
+// and media segments it lists sit next to it, named relative to it.
+const HLSPlaylistName = "playlist.m3u8"
+
+// DefaultHLSSegmentDuration is the target length of an HLS segment
+const DefaultHLSSegmentDuration = 6 * time.Second
+
+const (
+	// hlsDirName is the directory of the transcode cache holding one
+	// directory per HLS rendition
+	hlsDirName = "hls"
+
+	// hlsMaxBaseName is how much of a video's file name starts its rendition
+	// directory name, which must stay within the 255 bytes file systems allow.
+	// The hash after it keeps truncated names unique.
+	hlsMaxBaseName = 128
+
+	hlsInitName       = "init.mp4"
+	hlsSegmentPattern = "segment_%05d.m4s"
+
+	// hlsTranscodeWorkers bounds how many HLS transcodes run at once,
+	// whether started for a playlist or by PrefetchHLS
+	hlsTranscodeWorkers = 2
+
+	// maxHLSPrefetch caps the look-ahead of SetHLSPrefetch
+	maxHLSPrefetch = 10
+
+	// hlsPrefetchRetryAfter is how long PrefetchHLS leaves a rendition whose
+	// transcode failed before starting it again
+	hlsPrefetchRetryAfter = time.Minute
+)
+
+// hlsFileName matches the names of the files FFmpeg writes for a rendition,
+// so only those can be requested
+var hlsFileName = regexp.MustCompile(`^(playlist\.m3u8|init\.mp4|segment_[0-9]{5,}\.m4s)$`)
+
+// hlsSegmentName matches a media segment, capturing its number
+var hlsSegmentName = regexp.MustCompile(`^segment_([0-9]{5,})\.m4s$`)
+
+// hlsResumePoint is where the transcode of a rendition that stopped before
+// the end picks up again: the first segment it didn't write, and the time in
+// the video that segment starts at
+type hlsResumePoint struct {
+	segment int
+	offset  time.Duration
+}
+
+// hlsJob is a running HLS transcode. done is closed when it ends, after err
+// is set if it failed.
+type hlsJob struct {
+	done chan struct{}
+	err  error
+}
+
+// SetHLSSegmentDuration sets the target length of the segments of HLS
+// renditions transcoded from now on. Segments are cut at keyframes, so a
+// stream-copied video's segments follow its own keyframe interval.
+func (t *Transcoder) SetHLSSegmentDuration(d time.Duration) {
+	if d < time.Second {
+		return
+	}
+	t.hlsSegment.Store(int64(d))
+}
+
+// hlsRenditionDir returns the cache directory of a video's HLS rendition at
+// targetWidth. The name is derived from the source path, its modification
+// time and the width, so a changed source gets a new rendition and the
+// stale one is left for ReconcileCache.
+func (t *Transcoder) hlsRenditionDir(filePath string, targetWidth int) (string, error) {
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%d\x00%d", filePath, stat.ModTime().UnixNano(), targetWidth))
+	// Sanitized names are ASCII, so cutting them never splits a character
+	base := sanitizeLogFileName(filepath.Base(filePath))
+	base = base[:min(len(base), hlsMaxBaseName)]
+	name := fmt.Sprintf("%s_%x_w%d", base, sum[:8], targetWidth)
+	return filepath.Join(t.cacheDir, hlsDirName, name), nil
+}
+
+// GetOrStartHLS returns the path of the HLS playlist of a video at
+// targetWidth, starting its transcode in the background if it isn't cached.
+// It returns as soon as the playlist lists a first segment: while the
+// transcode runs the playlist is an event playlist that grows a segment at a
+// time, ending with #EXT-X-ENDLIST once complete.
+func (t *Transcoder) GetOrStartHLS(ctx context.Context, filePath string, targetWidth int, info *VideoInfo) (string, error) {
+	if !t.enabled {
+		return "", fmt.Errorf("transcoding required but disabled (cache directory not writable)")
+	}
+
+	targetWidth = t.snapWidth(targetWidth)
+	dir, err := t.hlsRenditionDir(filePath, targetWidth)
+	if err != nil {
+		return "", fmt.Errorf("source file stat: %w", err)
+	}
+	playlist := filepath.Join(dir, HLSPlaylistName)
+
+	job := t.ensureHLSJob(filePath, dir, targetWidth, info)
+	if job == nil {
+		logging.Debug("HLS rendition already cached: %s", dir)
+		return playlist, nil
+	}
+
+	timeout := time.NewTimer(t.transcodeWaitTimeout(info.Duration))
+	defer timeout.Stop()
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		// FFmpeg renames the playlist into place once the first segment is written
+		if _, err := os.Stat(playlist); err == nil {
+			return playlist, nil
+		}
+
+		select {
+		case <-job.done:
+			if job.err != nil {
+				return "", fmt.Errorf("transcode failed: %w", job.err)
+			}
+			if _, err := os.Stat(playlist); err != nil {
+				return "", fmt.Errorf("transcode produced no playlist: %w", err)
+			}
+			return playlist, nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-timeout.C:
+			return "", fmt.Errorf("timed out waiting for the first HLS segment of %s", filePath)
+		case <-ticker.C:
+		}
+	}
+}
+
+// ensureHLSJob returns the running transcode of the rendition in dir,
+// starting it unless the rendition is complete, in which case it returns nil.
+// targetWidth is already snapped.
+func (t *Transcoder) ensureHLSJob(filePath, dir string, targetWidth int, info *VideoInfo) *hlsJob {
+	t.hlsMu.Lock()
+	defer t.hlsMu.Unlock()
+
+	if job := t.hlsJobs[dir]; job != nil {
+		return job
+	}
+	if m, err := readManifest(filepath.Join(dir, HLSPlaylistName)); err == nil && m.Complete {
+		return nil
+	}
+	job := &hlsJob{done: make(chan struct{})}
+	t.hlsJobs[dir] = job
+	t.startHLSJob(job, filePath, dir, targetWidth, info)
+	return job
+}
+
+// startHLSJob runs job's transcode in the background, once one of the
+// hlsTranscodeWorkers slots is free. The caller holds t.hlsMu and has
+// registered job for dir.
+func (t *Transcoder) startHLSJob(job *hlsJob, filePath, dir string, targetWidth int, info *VideoInfo) {
+	needsScaling := targetWidth > 0 && targetWidth < info.Width
+	// Segments are fMP4, which browsers only play H.264 from reliably
+	needsReencode := info.Codec != "h264" || needsScaling
+
+	logging.Info("Starting background HLS transcode: %s -> %s", filePath, dir)
+
+	go func() {
+		// Use a background context so transcoding continues even if request is canceled
+		bgCtx, cancel := t.backgroundTranscodeContext(info)
+		defer cancel()
+
+		var err error
+		select {
+		case t.hlsSlots <- struct{}{}:
+			if t.shuttingDown.Load() {
+				err = errors.New("transcoder is shutting down")
+			} else {
+				err = t.transcodeHLS(bgCtx, filePath, dir, targetWidth, info, needsReencode)
+			}
+			<-t.hlsSlots
+		case <-bgCtx.Done():
+			err = bgCtx.Err()
+		}
+		if err != nil {
+			logging.Error("Background HLS transcode failed for %s: %v", filePath, err)
+			// Segments already written are kept for the transcode to resume
+			// from; anything less is of no use
+			if _, ok := readHLSResumePoint(dir); !ok {
+				if removeErr := os.RemoveAll(dir); removeErr != nil {
+					logging.Warn("Failed to remove partial HLS rendition %s: %v", dir, removeErr)
+				}
+			}
+		} else {
+			logging.Info("Background HLS transcode completed: %s", dir)
+		}
+		t.lastCacheUpdate.Store(0)
+
+		t.hlsMu.Lock()
+		job.err = err
+		delete(t.hlsJobs, dir)
+		for failedDir, failedAt := range t.hlsFailed {
+			if time.Since(failedAt) >= hlsPrefetchRetryAfter {
+				delete(t.hlsFailed, failedDir)
+			}
+		}
+		if err != nil {
+			t.hlsFailed[dir] = time.Now()
+		} else {
+			delete(t.hlsFailed, dir)
+		}
+		t.hlsMu.Unlock()
+		close(job.done)
+	}()
+}
+
+// transcodeHLS transcodes a video to an HLS rendition in dir, retrying on the
+// CPU if the GPU encoder fails
+func (t *Transcoder) transcodeHLS(ctx context.Context, filePath, dir string, targetWidth int, info *VideoInfo, needsReencode bool) error {
+	err := t.transcodeHLSWithOptions(ctx, filePath, dir, targetWidth, info, needsReencode, false)
+	var ffErr *ffmpegError
+	if errors.As(err, &ffErr) && t.gpuAvailable && t.isGPUError(ffErr.stderr) {
+		if t.shuttingDown.Load() || ctx.Err() != nil {
+			return err
+		}
+
+		logging.Warn("GPU encoding failed for HLS transcode of %s, retrying with CPU...", filePath)
+
+		t.gpuMu.Lock()
+		t.gpuAvailable = false
+		t.gpuMu.Unlock()
+
+		return t.transcodeHLSWithOptions(ctx, filePath, dir, targetWidth, info, needsReencode, true)
+	}
+	return err
+}
+
+// transcodeHLSWithOptions runs FFmpeg's HLS muxer into dir. A rendition
+// whose transcode stopped before the end, after a failure or a restart, is
+// resumed from the first segment it didn't write; otherwise dir is emptied
+// first. Its manifest is written next to the playlist and marked complete
+// once FFmpeg finishes, for ReconcileCache.
+func (t *Transcoder) transcodeHLSWithOptions(ctx context.Context, filePath, dir string, targetWidth int, info *VideoInfo, needsReencode, forceCPU bool) error {
+	cleanInput, err := sanitizeFilePath(filePath)
+	if err != nil {
+		return fmt.Errorf("invalid input file: %w", err)
+	}
+
+	playlist := filepath.Join(dir, HLSPlaylistName)
+	resume, ok := readHLSResumePoint(dir)
+	if ok {
+		logging.Info("Resuming HLS transcode of %s at segment %d (%v)", filePath, resume.segment, resume.offset)
+		if err := clearUnlistedHLSFiles(dir, resume.segment); err != nil {
+			return fmt.Errorf("failed to clear HLS directory: %w", err)
+		}
+	} else {
+		// Left over from an attempt that wrote no segments
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to clear HLS directory: %w", err)
+		}
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return fmt.Errorf("failed to create HLS directory: %w", err)
+		}
+		writeManifest(playlist, newCacheManifest(filePath, targetWidth, info, needsReencode))
+	}
+
+	args := t.buildHLSArgs(cleanInput, targetWidth, info, needsReencode, forceCPU, resume)
+	// Sanitize args to prevent command injection
+	for _, arg := range args {
+		if strings.ContainsAny(arg, ";&|$><") {
+			return fmt.Errorf("invalid ffmpeg argument: %s", arg)
+		}
+	}
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...) // #nosec G204 -- args are constructed internally, paths are validated above
+	// Output names are relative, so the playlist refers to its segments by name
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	logFile := t.createTranscoderLog(filePath, targetWidth)
+	if logFile != nil {
+		defer t.closeTranscoderLog(ctx, logFile, cmd)
+		cmd.Stderr = io.MultiWriter(&stderr, logFile)
+	} else {
+		cmd.Stderr = &stderr
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	// Keyed by directory, since an MP4 transcode of the same file may be running
+	t.processMu.Lock()
+	t.processes[dir] = cmd
+	t.processMu.Unlock()
+	defer func() {
+		t.processMu.Lock()
+		delete(t.processes, dir)
+		t.processMu.Unlock()
+	}()
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &ffmpegError{err: err, stderr: stderr.String()}
+	}
+
+	markManifestComplete(playlist)
+	return nil
+}
+
+// buildHLSArgs builds ffmpeg arguments for an HLS rendition written to the
+// working directory: fMP4 segments behind an event playlist, each written to
+// a temporary file and renamed into place, so nothing half-written is listed
+// or served. With a resume point past the start, the video is read from
+// there and its segments are appended to the playlist, numbered and timed
+// on from the ones already written.
+func (t *Transcoder) buildHLSArgs(inputPath string, targetWidth int, info *VideoInfo, needsReencode, forceCPU bool, resume hlsResumePoint) []string {
+	segment := time.Duration(t.hlsSegment.Load())
+	seconds := strconv.FormatFloat(segment.Seconds(), 'f', -1, 64)
+
+	args := t.buildEncodeArgs(inputPath, targetWidth, info, needsReencode, forceCPU)
+
+	flags := "temp_file+independent_segments"
+	if resume.segment > 0 {
+		offset := strconv.FormatFloat(resume.offset.Seconds(), 'f', -1, 64)
+		// Seeking the input lands on the keyframe the segment starts at
+		input := slices.Index(args, "-i")
+		args = slices.Insert(args, input, "-ss", offset)
+		args = append(args,
+			"-output_ts_offset", offset,
+			"-start_number", strconv.Itoa(resume.segment),
+		)
+		flags += "+append_list"
+	}
+
+	needsScaling := targetWidth > 0 && targetWidth < info.Width
+	if needsReencode || needsScaling || t.shouldToneMap(info) {
+		// Keyframes where segments should start, so they come out evenly sized
+		args = append(args, "-force_key_frames", "expr:gte(t,n_forced*"+seconds+")")
+	}
+
+	return append(args,
+		"-f", "hls",
+		"-hls_time", seconds,
+		"-hls_playlist_type", "event",
+		"-hls_segment_type", "fmp4",
+		"-hls_fmp4_init_filename", hlsInitName,
+		"-hls_segment_filename", hlsSegmentPattern,
+		"-hls_flags", flags,
+		HLSPlaylistName,
+	)
+}
+
+// readHLSResumePoint returns where the transcode of the rendition in dir
+// resumes: after the segments its playlist lists, which are all written.
+// Reports false unless it lists at least one, along with the init segment,
+// and doesn't end the stream.
+func readHLSResumePoint(dir string) (hlsResumePoint, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, HLSPlaylistName))
+	if err != nil {
+		return hlsResumePoint{}, false
+	}
+	if _, err := os.Stat(filepath.Join(dir, hlsInitName)); err != nil {
+		return hlsResumePoint{}, false
+	}
+
+	var resume hlsResumePoint
+	var duration float64
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "#EXT-X-ENDLIST":
+			return hlsResumePoint{}, false
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			if duration, err = strconv.ParseFloat(value, 64); err != nil {
+				return hlsResumePoint{}, false
+			}
+		case line != "" && !strings.HasPrefix(line, "#"):
+			if line != fmt.Sprintf(hlsSegmentPattern, resume.segment) {
+				return hlsResumePoint{}, false
+			}
+			if _, err := os.Stat(filepath.Join(dir, line)); err != nil {
+				return hlsResumePoint{}, false
+			}
+			resume.segment++
+			resume.offset += time.Duration(duration * float64(time.Second))
+		}
+	}
+	return resume, resume.segment > 0
+}
+
+// clearUnlistedHLSFiles removes what a transcode that stopped early left in
+// dir past the segments its playlist lists: temporary files, and segments
+// from first on that were renamed into place but never listed
+func clearUnlistedHLSFiles(dir string, first int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		unlisted := strings.HasSuffix(name, ".tmp")
+		if m := hlsSegmentName.FindStringSubmatch(name); m != nil {
+			n, err := strconv.Atoi(m[1])
+			unlisted = err == nil && n >= first
+		}
+		if unlisted {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// HLSFilePath returns the path of a file of a video's HLS rendition at
+// targetWidth: its playlist, init segment or a media segment. The error
+// wraps fs.ErrNotExist for a name that isn't one of these or a file that
+// hasn't been written (yet). Unlike GetOrStartHLS, it never starts a
+// transcode.
+func (t *Transcoder) HLSFilePath(filePath string, targetWidth int, name string) (string, error) {
+	if !hlsFileName.MatchString(name) {
+		return "", fmt.Errorf("not an HLS file: %q: %w", name, fs.ErrNotExist)
+	}
+
+	dir, err := t.hlsRenditionDir(filePath, t.snapWidth(targetWidth))
+	if err != nil {
+		return "", fmt.Errorf("source file stat: %w", err)
+	}
+
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// reconcileHLS removes the HLS renditions that can't be served or resumed:
+// those whose transcode stopped before writing a segment, whose source
+// changed or is gone, and any without a manifest. Returns the number kept
+// and removed.
+func (t *Transcoder) reconcileHLS() (valid, removed int) {
+	root := filepath.Join(t.cacheDir, hlsDirName)
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warn("Failed to read HLS cache directory: %v", err)
+		}
+		return 0, 0
+	}
+
+	for _, entry := range entries {
+		dir := filepath.Join(root, entry.Name())
+		if !entry.IsDir() {
+			continue
+		}
+		if reason := staleHLSReason(dir); reason != "" {
+			logging.Debug("Removing HLS rendition %s: %s", dir, reason)
+			if err := os.RemoveAll(dir); err != nil {
+				logging.Warn("Failed to remove %s: %v", dir, err)
+				continue
+			}
+			removed++
+			continue
+		}
+		valid++
+	}
+	return valid, removed
+}
+
+// staleHLSReason returns why the HLS rendition in dir can't be served or
+// resumed, or "" if it can. One whose transcode stopped before the end is
+// kept if it has segments to resume from.
+func staleHLSReason(dir string) string {
+	playlist := filepath.Join(dir, HLSPlaylistName)
+	if m, err := readManifest(playlist); err == nil && !m.Complete {
+		if _, ok := readHLSResumePoint(dir); !ok {
+			return "transcode did not finish"
+		}
+		return sourceChangedReason(m)
+	}
+	return staleEntryReason(playlist)
+}
+
+// SetHLSPrefetch sets how many segments past the one just served PrefetchHLS
+// looks ahead, up to 10; 0 turns prefetching off.
+func (t *Transcoder) SetHLSPrefetch(depth int) {
+	t.hlsPrefetch.Store(int32(min(max(depth, 0), maxHLSPrefetch)))
+}
+
+// SetMemoryMonitor makes PrefetchHLS start no transcodes while m reports
+// memory pressure.
+func (t *Transcoder) SetMemoryMonitor(m *memory.Monitor) {
+	t.memMonitor.Store(m)
+}
+
+// PrefetchHLS makes sure the segments a player will request after name, a
+// file of a video's HLS rendition at targetWidth that was just requested,
+// are on their way. FFmpeg writes a rendition's segments in order as fast as
+// it can, so this only matters for one whose transcode stopped before the
+// end, after a failure or a restart, and kept the segments it wrote: once a
+// segment within the look-ahead is missing, the transcode resumes from the
+// first one it didn't write. One that just failed is left alone for a
+// minute.
+//
+// Only renditions with a manifest, which a playlist request started, are
+// resumed, so segment requests can't start transcodes of their own.
+// It returns at once and works in the background. It does nothing while
+// prefetching is off, and starts nothing under memory pressure or when every
+// HLS transcode slot is busy.
+func (t *Transcoder) PrefetchHLS(filePath string, targetWidth int, name string) {
+	depth := int(t.hlsPrefetch.Load())
+	if depth == 0 || !t.enabled {
+		return
+	}
+
+	next, ok := nextHLSSegment(name)
+	if !ok {
+		return
+	}
+
+	targetWidth = t.snapWidth(targetWidth)
+	dir, err := t.hlsRenditionDir(filePath, targetWidth)
+	if err != nil {
+		return
+	}
+
+	t.hlsMu.Lock()
+	if t.hlsPrefetching[dir] || t.hlsJobs[dir] != nil {
+		t.hlsMu.Unlock()
+		return
+	}
+	t.hlsPrefetching[dir] = true
+	t.hlsMu.Unlock()
+
+	go func() {
+		defer func() {
+			t.hlsMu.Lock()
+			delete(t.hlsPrefetching, dir)
+			t.hlsMu.Unlock()
+		}()
+		t.prefetchHLS(filePath, dir, targetWidth, next, depth)
+	}()
+}
+
+// nextHLSSegment returns the number of the media segment a player requests
+// after name: the one after a media segment, or the first after the init
+// segment. Reports false for any other name.
+func nextHLSSegment(name string) (int, bool) {
+	if name == hlsInitName {
+		return 0, true
+	}
+	m := hlsSegmentName.FindStringSubmatch(name)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+	return n + 1, true
+}
+
+// prefetchHLS does the work of PrefetchHLS for the depth segments from next
+// on, in the rendition in dir
+func (t *Transcoder) prefetchHLS(filePath, dir string, targetWidth, next, depth int) {
+	m, err := readManifest(filepath.Join(dir, HLSPlaylistName))
+	if err != nil || m.Complete {
+		return
+	}
+
+	// Resumed only once the player nears the last segment written, so a
+	// rendition nobody watches to the end isn't finished in the background
+	written := true
+	for n := next; n < next+depth && written; n++ {
+		_, err := os.Stat(filepath.Join(dir, fmt.Sprintf(hlsSegmentPattern, n)))
+		written = err == nil
+	}
+	if written {
+		return
+	}
+
+	t.hlsMu.Lock()
+	_, running := t.hlsJobs[dir]
+	failed, ok := t.hlsFailed[dir]
+	t.hlsMu.Unlock()
+	if running || (ok && time.Since(failed) < hlsPrefetchRetryAfter) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	info, err := t.GetVideoInfo(ctx, filePath)
+	if err != nil {
+		logging.Warn("HLS prefetch: failed to get video info for %s: %v", filePath, err)
+		return
+	}
+
+	// Checked right before starting, since probing the video takes a while
+	if m := t.memMonitor.Load(); m != nil && m.ShouldThrottle() {
+		return
+	}
+	if len(t.hlsSlots) == cap(t.hlsSlots) {
+		return
+	}
+	logging.Info("HLS prefetch: transcode of %s stopped before the end, resuming it", filePath)
+	t.ensureHLSJob(filePath, dir, targetWidth, info)
+}
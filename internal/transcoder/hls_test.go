@@ -0,0 +1,493 @@
+package transcoder
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildHLSArgs(t *testing.T) {
+	trans := New("/tmp/cache", "", true, "none")
+	trans.SetHLSSegmentDuration(4 * time.Second)
+
+	info := &VideoInfo{Codec: "hevc", Width: 1920, Height: 1080}
+	args := trans.buildHLSArgs("/test/input.mkv", 1280, info, true, false, hlsResumePoint{})
+	joined := strings.Join(args, " ")
+
+	for _, want := range []string{
+		"-c:v libx264",
+		"-force_key_frames expr:gte(t,n_forced*4)",
+		"-f hls",
+		"-hls_time 4",
+		"-hls_segment_type fmp4",
+		"-hls_fmp4_init_filename init.mp4",
+		"-hls_segment_filename segment_%05d.m4s",
+		"-hls_flags temp_file+independent_segments",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected %q in args: %s", want, joined)
+		}
+	}
+	if args[len(args)-1] != HLSPlaylistName {
+		t.Errorf("Expected the playlist as the output, got %q", args[len(args)-1])
+	}
+	if slices.Contains(args, "-movflags") {
+		t.Errorf("Expected no MP4 movflags for HLS output: %s", joined)
+	}
+
+	// Stream-copied video keeps its own keyframes
+	copied := trans.buildHLSArgs("/test/input.mp4", 0, &VideoInfo{Codec: "h264", Width: 1920}, false, false, hlsResumePoint{})
+	if slices.Contains(copied, "-force_key_frames") {
+		t.Errorf("Expected no forced keyframes when copying: %v", copied)
+	}
+	if !strings.Contains(strings.Join(copied, " "), "-c:v copy") {
+		t.Errorf("Expected the video stream to be copied: %v", copied)
+	}
+	if slices.Contains(args, "-ss") || slices.Contains(args, "-start_number") {
+		t.Errorf("Expected a new rendition to start at the beginning: %s", joined)
+	}
+
+	// A resumed rendition seeks to its first missing segment and appends
+	resumed := trans.buildHLSArgs("/test/input.mkv", 1280, info, true, false, hlsResumePoint{segment: 3, offset: 12500 * time.Millisecond})
+	joined = strings.Join(resumed, " ")
+	for _, want := range []string{
+		"-ss 12.5 -i /test/input.mkv",
+		"-output_ts_offset 12.5",
+		"-start_number 3",
+		"-hls_flags temp_file+independent_segments+append_list",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected %q in resumed args: %s", want, joined)
+		}
+	}
+}
+
+func TestSetHLSSegmentDuration(t *testing.T) {
+	trans := New("/tmp/cache", "", true, "none")
+	if got := time.Duration(trans.hlsSegment.Load()); got != DefaultHLSSegmentDuration {
+		t.Errorf("Expected the default segment duration, got %v", got)
+	}
+
+	trans.SetHLSSegmentDuration(10 * time.Second)
+	trans.SetHLSSegmentDuration(0)
+	trans.SetHLSSegmentDuration(500 * time.Millisecond)
+	if got := time.Duration(trans.hlsSegment.Load()); got != 10*time.Second {
+		t.Errorf("Expected durations under a second to be ignored, got %v", got)
+	}
+}
+
+func TestHLSRenditionDir(t *testing.T) {
+	cacheDir := t.TempDir()
+	source := filepath.Join(t.TempDir(), "clip.mkv")
+	if err := os.WriteFile(source, []byte("source"), 0o644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	trans := New(cacheDir, "", true, "none")
+
+	dir, err := trans.hlsRenditionDir(source, 720)
+	if err != nil {
+		t.Fatalf("hlsRenditionDir() error: %v", err)
+	}
+	if filepath.Dir(dir) != filepath.Join(cacheDir, hlsDirName) {
+		t.Errorf("Expected the rendition under the hls directory, got %s", dir)
+	}
+	if again, _ := trans.hlsRenditionDir(source, 720); again != dir {
+		t.Errorf("Expected the same directory for the same source, got %s and %s", dir, again)
+	}
+	if other, _ := trans.hlsRenditionDir(source, 1080); other == dir {
+		t.Error("Expected another width to get another directory")
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(source, later, later); err != nil {
+		t.Fatalf("Failed to touch source: %v", err)
+	}
+	if changed, _ := trans.hlsRenditionDir(source, 720); changed == dir {
+		t.Error("Expected a changed source to get another directory")
+	}
+
+	if _, err := trans.hlsRenditionDir(filepath.Join(t.TempDir(), "missing.mkv"), 720); err == nil {
+		t.Error("Expected an error for a missing source")
+	}
+
+	// A name near the file system's limit still leaves room for the hash
+	long := filepath.Join(t.TempDir(), strings.Repeat("a", 250)+".mkv")
+	if err := os.WriteFile(long, []byte("source"), 0o644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	longDir, err := trans.hlsRenditionDir(long, 720)
+	if err != nil {
+		t.Fatalf("hlsRenditionDir() error: %v", err)
+	}
+	if err := os.MkdirAll(longDir, 0o755); err != nil {
+		t.Errorf("Expected the rendition directory of a long name to be creatable: %v", err)
+	}
+}
+
+func TestHLSFilePath(t *testing.T) {
+	cacheDir := t.TempDir()
+	source := filepath.Join(t.TempDir(), "clip.mkv")
+	if err := os.WriteFile(source, []byte("source"), 0o644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	trans := New(cacheDir, "", true, "none")
+
+	dir, err := trans.hlsRenditionDir(source, 640)
+	if err != nil {
+		t.Fatalf("hlsRenditionDir() error: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("Failed to create rendition: %v", err)
+	}
+	for _, name := range []string{HLSPlaylistName, hlsInitName, "segment_00000.m4s"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("data"), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{HLSPlaylistName, hlsInitName, "segment_00000.m4s"} {
+		path, err := trans.HLSFilePath(source, 640, name)
+		if err != nil || path != filepath.Join(dir, name) {
+			t.Errorf("HLSFilePath(%q) = %q, %v; want the file in %s", name, path, err, dir)
+		}
+	}
+
+	for _, name := range []string{"segment_00001.m4s", "playlist.m3u8.json", "../clip.mkv", "segment_1.m4s", ""} {
+		if _, err := trans.HLSFilePath(source, 640, name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("HLSFilePath(%q) error = %v, want fs.ErrNotExist", name, err)
+		}
+	}
+}
+
+func TestReconcileCacheHLS(t *testing.T) {
+	cacheDir := t.TempDir()
+	sourceDir := t.TempDir()
+	trans := New(cacheDir, "", true, "none")
+
+	rendition := func(name string, complete bool) string {
+		t.Helper()
+		source := filepath.Join(sourceDir, name)
+		if err := os.WriteFile(source, []byte("source"), 0o644); err != nil {
+			t.Fatalf("Failed to write source: %v", err)
+		}
+		dir, err := trans.hlsRenditionDir(source, 0)
+		if err != nil {
+			t.Fatalf("hlsRenditionDir() error: %v", err)
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("Failed to create rendition: %v", err)
+		}
+		for _, file := range []string{HLSPlaylistName, hlsInitName, "segment_00000.m4s", "segment_00001.m4s"} {
+			if err := os.WriteFile(filepath.Join(dir, file), []byte("data"), 0o644); err != nil {
+				t.Fatalf("Failed to write %s: %v", file, err)
+			}
+		}
+		playlist := filepath.Join(dir, HLSPlaylistName)
+		m := newCacheManifest(source, 0, &VideoInfo{Codec: "hevc"}, true)
+		m.Complete = complete
+		writeManifest(playlist, m)
+		return dir
+	}
+
+	kept := rendition("kept.mkv", true)
+	interrupted := rendition("interrupted.mkv", false)
+	deleted := rendition("deleted.mkv", true)
+	if err := os.Remove(filepath.Join(sourceDir, "deleted.mkv")); err != nil {
+		t.Fatalf("Failed to remove source: %v", err)
+	}
+
+	valid, removed := trans.ReconcileCache()
+	if valid != 1 || removed != 2 {
+		t.Errorf("ReconcileCache() = %d valid, %d removed; want 1 and 2", valid, removed)
+	}
+	if _, err := os.Stat(filepath.Join(kept, "segment_00001.m4s")); err != nil {
+		t.Errorf("Expected the complete rendition to be kept: %v", err)
+	}
+	for _, dir := range []string{interrupted, deleted} {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", filepath.Base(dir))
+		}
+	}
+
+	// The kept rendition counts once, however many segments it has
+	if _, count, err := trans.GetCacheSize(); err != nil || count != 1 {
+		t.Errorf("GetCacheSize() count = %d, %v; want 1", count, err)
+	}
+}
+
+func TestNextHLSSegment(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected int
+		ok       bool
+	}{
+		{"init.mp4", 0, true},
+		{"segment_00000.m4s", 1, true},
+		{"segment_00041.m4s", 42, true},
+		{"segment_123456.m4s", 123457, true},
+		{"playlist.m3u8", 0, false},
+		{"segment_1.m4s", 0, false},
+		{"../segment_00000.m4s", 0, false},
+	}
+
+	for _, tt := range tests {
+		if got, ok := nextHLSSegment(tt.name); got != tt.expected || ok != tt.ok {
+			t.Errorf("nextHLSSegment(%q) = %d, %v; want %d, %v", tt.name, got, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestSetHLSPrefetch(t *testing.T) {
+	trans := New("/tmp/cache", "", true, "none")
+	for _, tt := range []struct{ depth, expected int }{{2, 2}, {-1, 0}, {50, maxHLSPrefetch}} {
+		trans.SetHLSPrefetch(tt.depth)
+		if got := int(trans.hlsPrefetch.Load()); got != tt.expected {
+			t.Errorf("SetHLSPrefetch(%d) stored %d, want %d", tt.depth, got, tt.expected)
+		}
+	}
+}
+
+func TestPrefetchHLS(t *testing.T) {
+	cacheDir := t.TempDir()
+	source := filepath.Join(t.TempDir(), "clip.mkv")
+	if err := os.WriteFile(source, []byte("source"), 0o644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	stat, err := os.Stat(source)
+	if err != nil {
+		t.Fatalf("Failed to stat source: %v", err)
+	}
+	trans := New(cacheDir, "", true, "none")
+	// Probed already, as by the playlist request that started the rendition
+	trans.storeVideoInfo(source, stat, &VideoInfo{Codec: "h264", Width: 1280, Height: 720, Duration: 60})
+	dir, err := trans.hlsRenditionDir(source, 0)
+	if err != nil {
+		t.Fatalf("hlsRenditionDir() error: %v", err)
+	}
+	playlist := filepath.Join(dir, HLSPlaylistName)
+
+	started := func() bool {
+		trans.hlsMu.Lock()
+		defer trans.hlsMu.Unlock()
+		_, failed := trans.hlsFailed[dir]
+		return trans.hlsJobs[dir] != nil || failed
+	}
+
+	// Off by default
+	trans.PrefetchHLS(source, 0, "segment_00000.m4s")
+	if len(trans.hlsPrefetching) != 0 {
+		t.Error("Expected no prefetch with prefetching off")
+	}
+
+	// Segment requests never start a rendition that has no manifest
+	trans.prefetchHLS(source, dir, 0, 1, 2)
+	if started() {
+		t.Fatal("Expected no transcode for a rendition without a manifest")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("Failed to create rendition: %v", err)
+	}
+	for _, name := range []string{HLSPlaylistName, hlsInitName, "segment_00000.m4s", "segment_00001.m4s", "segment_00002.m4s"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("data"), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	writeManifest(playlist, newCacheManifest(source, 0, &VideoInfo{Codec: "h264"}, false))
+
+	// The look-ahead is written, so the rendition isn't cleared yet
+	trans.prefetchHLS(source, dir, 0, 1, 2)
+	if started() {
+		t.Fatal("Expected no transcode while the next segments are written")
+	}
+
+	// A rendition whose transcode just failed is left alone
+	trans.hlsFailed[dir] = time.Now()
+	trans.prefetchHLS(source, dir, 0, 2, 2)
+	if len(trans.hlsJobs) != 0 {
+		t.Fatal("Expected a recently failed rendition to be left alone")
+	}
+	delete(trans.hlsFailed, dir)
+
+	// Nothing starts while every transcode slot is busy
+	for range cap(trans.hlsSlots) {
+		trans.hlsSlots <- struct{}{}
+	}
+	trans.prefetchHLS(source, dir, 0, 2, 2)
+	if started() {
+		t.Fatal("Expected no transcode with every slot busy")
+	}
+	for range cap(trans.hlsSlots) {
+		<-trans.hlsSlots
+	}
+
+	// A finished rendition is never started again
+	markManifestComplete(playlist)
+	trans.prefetchHLS(source, dir, 0, 5, 2)
+	if started() {
+		t.Fatal("Expected no transcode for a finished rendition")
+	}
+
+	writeManifest(playlist, newCacheManifest(source, 0, &VideoInfo{Codec: "h264"}, false))
+	trans.prefetchHLS(source, dir, 0, 2, 2)
+	trans.hlsMu.Lock()
+	job := trans.hlsJobs[dir]
+	trans.hlsMu.Unlock()
+	if job == nil && !started() {
+		t.Fatal("Expected an unfinished rendition missing the next segment to be started again")
+	}
+	if job != nil {
+		<-job.done
+	}
+}
+
+func TestHLSResumesAfterEarlyStop(t *testing.T) {
+	// An FFmpeg that stops after two segments, and finishes the rendition
+	// when resumed from the third
+	binDir := t.TempDir()
+	argsFile := filepath.Join(binDir, "args")
+	script := `#!/bin/sh
+echo "$@" >> ` + argsFile + `
+case " $* " in
+*" -start_number 2 "*)
+	printf seg2 > segment_00002.m4s
+	printf '#EXTINF:6.000000,\nsegment_00002.m4s\n#EXT-X-ENDLIST\n' >> playlist.m3u8
+	exit 0
+	;;
+esac
+printf init > init.mp4
+printf seg0 > segment_00000.m4s
+printf seg1 > segment_00001.m4s
+printf '#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-TARGETDURATION:6\n#EXT-X-PLAYLIST-TYPE:EVENT\n#EXT-X-MAP:URI="init.mp4"\n#EXTINF:6.000000,\nsegment_00000.m4s\n#EXTINF:6.000000,\nsegment_00001.m4s\n' > playlist.m3u8
+printf half > segment_00002.m4s.tmp
+echo "Conversion failed" >&2
+exit 1
+`
+	if err := os.WriteFile(filepath.Join(binDir, "ffmpeg"), []byte(script), 0o755); err != nil {
+		t.Fatalf("Failed to write mock ffmpeg: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cacheDir := t.TempDir()
+	source := filepath.Join(t.TempDir(), "clip.mkv")
+	if err := os.WriteFile(source, []byte("source"), 0o644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	info := &VideoInfo{Codec: "h264", Width: 1280, Height: 720, Duration: 18}
+	trans := New(cacheDir, "", true, "none")
+	dir, err := trans.hlsRenditionDir(source, 0)
+	if err != nil {
+		t.Fatalf("hlsRenditionDir() error: %v", err)
+	}
+	playlist := filepath.Join(dir, HLSPlaylistName)
+
+	job := trans.ensureHLSJob(source, dir, 0, info)
+	select {
+	case <-job.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the transcode to stop")
+	}
+	if job.err == nil {
+		t.Fatal("Expected the transcode to fail")
+	}
+	for _, name := range []string{hlsInitName, "segment_00000.m4s", "segment_00001.m4s"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s kept after the transcode stopped: %v", name, err)
+		}
+	}
+
+	// Kept through a restart too
+	trans = New(cacheDir, "", true, "none")
+	if valid, removed := trans.ReconcileCache(); valid != 1 || removed != 0 {
+		t.Errorf("ReconcileCache() = %d valid, %d removed; want the partial rendition kept", valid, removed)
+	}
+	stat, err := os.Stat(source)
+	if err != nil {
+		t.Fatalf("Failed to stat source: %v", err)
+	}
+	trans.storeVideoInfo(source, stat, info)
+	trans.SetHLSPrefetch(2)
+
+	// The segments after the init segment are written, so nothing resumes yet
+	trans.prefetchHLS(source, dir, 0, 0, 2)
+	if len(trans.hlsJobs) != 0 {
+		t.Fatal("Expected no transcode while the next segments are written")
+	}
+
+	// Playing the first segment, the third is within the look-ahead
+	trans.PrefetchHLS(source, 0, "segment_00000.m4s")
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if m, err := readManifest(playlist); err == nil && m.Complete {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the transcode to resume and finish")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("Failed to read ffmpeg arguments: %v", err)
+	}
+	runs := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(runs) != 2 {
+		t.Fatalf("Expected ffmpeg to run twice, got %d runs", len(runs))
+	}
+	for _, want := range []string{"-ss 12 -i", "-output_ts_offset 12", "-start_number 2", "+append_list"} {
+		if !strings.Contains(runs[1], want) {
+			t.Errorf("Expected %q in the resumed run: %s", want, runs[1])
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "segment_00000.m4s")); string(data) != "seg0" {
+		t.Errorf("Expected the segments written before to be kept, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "segment_00002.m4s.tmp")); !os.IsNotExist(err) {
+		t.Errorf("Expected the half-written segment to be cleared: %v", err)
+	}
+	if _, ok := readHLSResumePoint(dir); ok {
+		t.Error("Expected a finished rendition to have no resume point")
+	}
+}
+
+func TestHLSJobWaitsForSlot(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "clip.mkv")
+	if err := os.WriteFile(source, []byte("source"), 0o644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	trans := New(t.TempDir(), "", true, "none")
+	dir, err := trans.hlsRenditionDir(source, 0)
+	if err != nil {
+		t.Fatalf("hlsRenditionDir() error: %v", err)
+	}
+
+	for range cap(trans.hlsSlots) {
+		trans.hlsSlots <- struct{}{}
+	}
+	job := trans.ensureHLSJob(source, dir, 0, &VideoInfo{Codec: "h264", Width: 1280, Height: 720, Duration: 60})
+
+	select {
+	case <-job.done:
+		t.Fatal("Expected the transcode to wait for a free slot")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected nothing written before a slot is free, got %v", err)
+	}
+
+	<-trans.hlsSlots
+	select {
+	case <-job.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the transcode to run once a slot was free")
+	}
+	if len(trans.hlsSlots) != cap(trans.hlsSlots)-1 {
+		t.Errorf("Expected the transcode to release its slot, %d of %d held", len(trans.hlsSlots), cap(trans.hlsSlots))
+	}
+}
@@ -0,0 +1,124 @@
+package transcoder
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"media-viewer/internal/logging"
+)
+
+// logRetention limits the FFmpeg logs kept in the log directory. Zero
+// values don't limit.
+type logRetention struct {
+	maxAge   time.Duration
+	maxBytes int64
+}
+
+// SetLogRetention limits the FFmpeg logs PruneLogs keeps: logs older than
+// maxAge are deleted, and then the oldest logs until the rest take up no
+// more than maxBytes. Zero disables either limit.
+func (t *Transcoder) SetLogRetention(maxAge time.Duration, maxBytes int64) {
+	t.logRetention.Store(&logRetention{maxAge: max(maxAge, 0), maxBytes: max(maxBytes, 0)})
+	if t.logDir == "" {
+		return
+	}
+	switch {
+	case maxAge > 0 && maxBytes > 0:
+		logging.Info("Transcoder logs kept for %v, up to %d MB", maxAge, maxBytes>>20)
+	case maxAge > 0:
+		logging.Info("Transcoder logs kept for %v", maxAge)
+	case maxBytes > 0:
+		logging.Info("Transcoder logs kept up to %d MB", maxBytes>>20)
+	}
+}
+
+// SetLogErrorsOnly keeps the FFmpeg logs of failed transcodes only. Logs
+// are still written while FFmpeg runs, and deleted when it succeeds.
+func (t *Transcoder) SetLogErrorsOnly(enabled bool) {
+	t.logErrorsOnly.Store(enabled)
+}
+
+// closeTranscoderLog closes a transcode's log once FFmpeg has exited. When
+// only errors are logged, it is deleted unless FFmpeg failed or timed out:
+// a run cancelled because its request ended, which happens on every seek,
+// isn't a failure.
+func (t *Transcoder) closeTranscoderLog(ctx context.Context, logFile *os.File, cmd *exec.Cmd) {
+	if err := logFile.Close(); err != nil {
+		logging.Warn("Failed to close transcode log file: %v", err)
+	}
+
+	if !t.logErrorsOnly.Load() {
+		return
+	}
+	failed := cmd.ProcessState != nil && !cmd.ProcessState.Success() && !errors.Is(ctx.Err(), context.Canceled)
+	if failed {
+		return
+	}
+	if err := os.Remove(logFile.Name()); err != nil && !os.IsNotExist(err) {
+		logging.Warn("Failed to remove transcode log file: %v", err)
+	}
+}
+
+// PruneLogs deletes the FFmpeg logs beyond the limits set by
+// SetLogRetention, oldest first, and returns how many it deleted.
+func (t *Transcoder) PruneLogs() int {
+	retention := t.logRetention.Load()
+	if t.logDir == "" || retention == nil || (retention.maxAge == 0 && retention.maxBytes == 0) {
+		return 0
+	}
+
+	entries, err := os.ReadDir(t.logDir)
+	if err != nil {
+		logging.Warn("Failed to read transcoder log directory: %v", err)
+		return 0
+	}
+
+	type logEntry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var logs []logEntry
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasSuffix(entry.Name(), ".log") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		logs = append(logs, logEntry{filepath.Join(t.logDir, entry.Name()), info.Size(), info.ModTime()})
+	}
+
+	// Newest first, so everything past the size limit is older
+	sort.Slice(logs, func(i, j int) bool { return logs[i].modTime.After(logs[j].modTime) })
+
+	removed := 0
+	var kept int64
+	for _, log := range logs {
+		expired := retention.maxAge > 0 && time.Since(log.modTime) > retention.maxAge
+		oversize := retention.maxBytes > 0 && kept+log.size > retention.maxBytes
+		if !expired && !oversize {
+			kept += log.size
+			continue
+		}
+		if err := os.Remove(log.path); err != nil {
+			if !os.IsNotExist(err) {
+				logging.Warn("Failed to remove transcoder log %s: %v", log.path, err)
+			}
+			continue
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		logging.Info("Pruned %d transcoder logs (%d MB kept)", removed, kept>>20)
+	}
+	return removed
+}
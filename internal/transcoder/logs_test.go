@@ -0,0 +1,93 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestLog writes a transcoder log of the given size, last modified age ago
+func writeTestLog(t *testing.T, dir, name string, size int, age time.Duration) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, make([]byte, size), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	modTime := time.Now().Add(-age)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set mod time of %s: %v", name, err)
+	}
+	return path
+}
+
+func TestPruneLogs(t *testing.T) {
+	logDir := t.TempDir()
+	trans := New(t.TempDir(), logDir, true, "none")
+
+	newest := writeTestLog(t, logDir, "newest.log", 400, time.Minute)
+	recent := writeTestLog(t, logDir, "recent.log", 400, time.Hour)
+	older := writeTestLog(t, logDir, "older.log", 400, 2*time.Hour)
+	expired := writeTestLog(t, logDir, "expired.log", 10, 48*time.Hour)
+	other := writeTestLog(t, logDir, "notes.txt", 10, 48*time.Hour)
+
+	// No limits by default
+	if removed := trans.PruneLogs(); removed != 0 {
+		t.Errorf("PruneLogs without limits removed %d logs", removed)
+	}
+
+	trans.SetLogRetention(24*time.Hour, 1000)
+	if removed := trans.PruneLogs(); removed != 2 {
+		t.Errorf("PruneLogs removed %d logs, want 2", removed)
+	}
+
+	for path, want := range map[string]bool{newest: true, recent: true, older: false, expired: false, other: true} {
+		_, err := os.Stat(path)
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %v, want %v", filepath.Base(path), exists, want)
+		}
+	}
+}
+
+func TestCloseTranscoderLogErrorsOnly(t *testing.T) {
+	if _, err := exec.LookPath("false"); err != nil {
+		t.Skip("true and false commands not available")
+	}
+
+	logDir := t.TempDir()
+	trans := New(t.TempDir(), logDir, true, "none")
+	trans.SetLogErrorsOnly(true)
+
+	run := func(ctx context.Context, command string) bool {
+		t.Helper()
+		logFile := trans.createTranscoderLog("/media/"+command+".mp4", 720)
+		if logFile == nil {
+			t.Fatal("Expected log file to be created")
+		}
+		cmd := exec.CommandContext(ctx, command)
+		_ = cmd.Run()
+		trans.closeTranscoderLog(ctx, logFile, cmd)
+		_, err := os.Stat(logFile.Name())
+		return err == nil
+	}
+
+	if run(context.Background(), "true") {
+		t.Error("Expected the log of a successful run to be deleted")
+	}
+	if !run(context.Background(), "false") {
+		t.Error("Expected the log of a failed run to be kept")
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if run(canceled, "false") {
+		t.Error("Expected the log of a canceled run to be deleted")
+	}
+
+	trans.SetLogErrorsOnly(false)
+	if !run(context.Background(), "true") {
+		t.Error("Expected logs to be kept when not limited to errors")
+	}
+}
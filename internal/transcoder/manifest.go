@@ -0,0 +1,190 @@
+package transcoder
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"media-viewer/internal/logging"
+)
+
+// manifestExt is appended to a cache entry's path for its manifest
+const manifestExt = ".json"
+
+// cacheManifest records what a transcode cache entry was made from and
+// whether it finished, so ReconcileCache can tell entries that survived a
+// restart intact from ones whose transcode was cut short.
+type cacheManifest struct {
+	Source        string    `json:"source"`
+	SourceModTime time.Time `json:"sourceModTime"`
+	TargetWidth   int       `json:"targetWidth"`
+	Codec         string    `json:"codec"`
+	Complete      bool      `json:"complete"`
+}
+
+// manifestPath returns the path of a cache entry's manifest
+func manifestPath(cachePath string) string {
+	return cachePath + manifestExt
+}
+
+// newCacheManifest describes a transcode that is about to start. The codec is
+// the output video codec: H.264 when re-encoding, otherwise the source's.
+func newCacheManifest(filePath string, targetWidth int, info *VideoInfo, needsReencode bool) cacheManifest {
+	m := cacheManifest{
+		Source:      filePath,
+		TargetWidth: targetWidth,
+		Codec:       "h264",
+	}
+	if !needsReencode {
+		m.Codec = info.Codec
+	}
+	if stat, err := os.Stat(filePath); err == nil {
+		m.SourceModTime = stat.ModTime()
+	}
+	return m
+}
+
+// writeManifest writes a cache entry's manifest. A missing manifest only
+// means the entry is left alone by ReconcileCache, so failures are logged.
+func writeManifest(cachePath string, m cacheManifest) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		logging.Warn("Failed to encode transcode manifest for %s: %v", cachePath, err)
+		return
+	}
+	if err := os.WriteFile(manifestPath(cachePath), data, 0o600); err != nil {
+		logging.Warn("Failed to write transcode manifest for %s: %v", cachePath, err)
+	}
+}
+
+// readManifest reads a cache entry's manifest
+func readManifest(cachePath string) (cacheManifest, error) {
+	var m cacheManifest
+	data, err := os.ReadFile(manifestPath(cachePath))
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
+
+// markManifestComplete records that a cache entry's transcode finished and
+// the file has been renamed into place
+func markManifestComplete(cachePath string) {
+	m, err := readManifest(cachePath)
+	if err != nil {
+		// Already warned about when it couldn't be written
+		logging.Debug("Failed to read transcode manifest for %s: %v", cachePath, err)
+		return
+	}
+	m.Complete = true
+	writeManifest(cachePath, m)
+}
+
+// discardPendingManifest removes a cache entry's manifest unless it records
+// a finished transcode. Called when a transcode attempt ends, so a failed
+// attempt doesn't leave its manifest behind.
+func discardPendingManifest(cachePath string) {
+	if m, err := readManifest(cachePath); err == nil && m.Complete {
+		return
+	}
+	_ = os.Remove(manifestPath(cachePath))
+}
+
+// ReconcileCache cleans up the cache directory after a restart. Leftover .tmp
+// and .err files from interrupted transcodes are deleted, as are entries
+// whose manifest shows they never finished or whose source has changed or
+// gone since, and HLS renditions likewise, except that one with segments
+// written is kept for its transcode to resume. Complete entries are kept, so
+// they're served without being transcoded again. Entries from before
+// manifests were written are left for the usual staleness check when they're
+// requested.
+//
+// It must run before any transcode starts, since it can't tell a .tmp file
+// being written from an orphaned one.
+func (t *Transcoder) ReconcileCache() (valid, removed int) {
+	if !t.enabled || t.cacheDir == "" {
+		return 0, 0
+	}
+
+	entries, err := os.ReadDir(t.cacheDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warn("Failed to read transcode cache directory: %v", err)
+		}
+		return 0, 0
+	}
+
+	remove := func(path string) {
+		if err := os.Remove(path); err == nil {
+			removed++
+		} else if !os.IsNotExist(err) {
+			logging.Warn("Failed to remove %s: %v", path, err)
+		}
+	}
+
+	var manifests []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		path := filepath.Join(t.cacheDir, entry.Name())
+		switch {
+		case strings.HasSuffix(path, ".tmp"), strings.HasSuffix(path, ".err"):
+			remove(path)
+		case strings.HasSuffix(path, manifestExt):
+			manifests = append(manifests, strings.TrimSuffix(path, manifestExt))
+		}
+	}
+
+	for _, cachePath := range manifests {
+		if reason := staleEntryReason(cachePath); reason != "" {
+			logging.Debug("Removing transcode cache entry %s: %s", cachePath, reason)
+			remove(cachePath)
+			remove(manifestPath(cachePath))
+			continue
+		}
+		valid++
+	}
+
+	hlsValid, hlsRemoved := t.reconcileHLS()
+	valid += hlsValid
+	removed += hlsRemoved
+
+	if removed > 0 {
+		t.lastCacheUpdate.Store(0)
+	}
+	logging.Info("Transcode cache reconciled: %d complete entries, %d files removed", valid, removed)
+	return valid, removed
+}
+
+// staleEntryReason returns why a cache entry with a manifest can't be
+// served, or "" if it can
+func staleEntryReason(cachePath string) string {
+	m, err := readManifest(cachePath)
+	if err != nil {
+		return "unreadable manifest"
+	}
+	if !m.Complete {
+		return "transcode did not finish"
+	}
+	if stat, err := os.Stat(cachePath); err != nil || stat.Size() == 0 {
+		return "cache file missing or empty"
+	}
+	return sourceChangedReason(m)
+}
+
+// sourceChangedReason returns why the source a cache entry was transcoded
+// from no longer matches it, or "" if it does
+func sourceChangedReason(m cacheManifest) string {
+	source, err := os.Stat(m.Source)
+	if err != nil {
+		return "source missing"
+	}
+	if !source.ModTime().Equal(m.SourceModTime) {
+		return "source changed"
+	}
+	return ""
+}
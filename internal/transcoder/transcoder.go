@@ -3,22 +3,29 @@ package transcoder
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
+	"math"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unsafe"
 
 	"media-viewer/internal/logging"
+	"media-viewer/internal/mediatypes"
+	"media-viewer/internal/memory"
 	"media-viewer/internal/streaming"
 )
 
@@ -65,15 +72,112 @@ type Transcoder struct {
 	cachedCount     atomic.Int64
 	lastCacheUpdate atomic.Int64 // Unix timestamp
 	cacheMu         sync.Mutex   // Protects recalculation
+
+	// Probe results keyed by source path, invalidated when size or mtime changes
+	infoCache       map[string]cachedVideoInfo
+	infoCacheMu     sync.RWMutex
+	infoCacheHits   atomic.Int64
+	infoCacheMisses atomic.Int64
+
+	// Hit/miss counts for the cache size values above
+	sizeCacheHits   atomic.Int64
+	sizeCacheMisses atomic.Int64
+
+	// Upper bound on waiting for a cached transcode; also the budget for
+	// videos whose duration is unknown (stored as nanoseconds)
+	maxTranscodeWait atomic.Int64
+
+	// Standard output widths that requested widths are rounded up to, so
+	// nearby sizes share one transcode; nil disables snapping
+	widthLadder atomic.Pointer[[]int]
+
+	// Tone-map HDR sources to SDR when transcoding (CPU only)
+	toneMapHDR atomic.Bool
+
+	// libx264 preset and CRF for CPU encoding, see SetCPUEncoder
+	cpuEncoder atomic.Pointer[cpuEncoderSettings]
+
+	// Limits on the FFmpeg logs kept in logDir, see SetLogRetention, and
+	// whether the logs of successful transcodes are deleted
+	logRetention  atomic.Pointer[logRetention]
+	logErrorsOnly atomic.Bool
+
+	// Target HLS segment length (stored as nanoseconds), the running HLS
+	// transcodes keyed by their cache directory, and the slots every HLS
+	// transcode holds while FFmpeg runs, bounding how many run at once
+	hlsSegment atomic.Int64
+	hlsJobs    map[string]*hlsJob
+	hlsSlots   chan struct{}
+	hlsMu      sync.Mutex
+
+	// How many segments past the one served PrefetchHLS looks ahead
+	// (0 = none), and the renditions it is checking
+	hlsPrefetch    atomic.Int32
+	hlsPrefetching map[string]bool
+
+	// When the last HLS transcode of a rendition failed, keyed like hlsJobs
+	hlsFailed map[string]time.Time
+
+	// Keeps PrefetchHLS from starting transcodes under memory pressure; nil
+	// never does
+	memMonitor atomic.Pointer[memory.Monitor]
+}
+
+// cpuEncoderSettings are the libx264 rate control settings for CPU encoding
+type cpuEncoderSettings struct {
+	preset string
+	crf    int
+}
+
+// cachedVideoInfo is a probe result along with the file state it was taken from.
+type cachedVideoInfo struct {
+	info    VideoInfo
+	size    int64
+	modTime time.Time
+}
+
+// CacheStats describes the usage of an in-memory cache.
+type CacheStats struct {
+	Entries     int
+	Hits        int64
+	Misses      int64
+	MemoryBytes int64 // Estimate
 }
 
+// maxVideoInfoCacheEntries bounds the probe cache so large libraries can't grow it without limit.
+const maxVideoInfoCacheEntries = 2000
+
+const (
+	// minTranscodeWait is how long a request waits for a short video to transcode
+	minTranscodeWait = 5 * time.Minute
+
+	// transcodeWaitFactor allows for encoding at half real-time speed on slow hardware
+	transcodeWaitFactor = 2
+
+	// DefaultMaxTranscodeWait caps how long a request waits for a transcode.
+	// Videos with unknown duration (live-stream recordings, fragmented files)
+	// get the full budget.
+	DefaultMaxTranscodeWait = 30 * time.Minute
+)
+
 // VideoInfo contains information about a video file.
 type VideoInfo struct {
-	Duration       float64 `json:"duration"`
-	Width          int     `json:"width"`
-	Height         int     `json:"height"`
-	Codec          string  `json:"codec"`
-	NeedsTranscode bool    `json:"needsTranscode"`
+	Duration       float64   `json:"duration"` // Seconds; 0 if unknown
+	Width          int       `json:"width"`
+	Height         int       `json:"height"`
+	Codec          string    `json:"codec"`
+	NeedsTranscode bool      `json:"needsTranscode"`
+	Chapters       []Chapter `json:"chapters"`
+	ColorTransfer  string    `json:"colorTransfer,omitempty"`
+	ColorPrimaries string    `json:"colorPrimaries,omitempty"`
+	HDR            bool      `json:"hdr"` // PQ or HLG transfer
+}
+
+// Chapter is a chapter marker embedded in a video container.
+type Chapter struct {
+	Title     string  `json:"title"`
+	StartTime float64 `json:"startTime"`
+	EndTime   float64 `json:"endTime"`
 }
 
 var compatibleCodecs = map[string]bool{
@@ -83,19 +187,45 @@ var compatibleCodecs = map[string]bool{
 	"av1":  true,
 }
 
+// hdrTransfers are the ffprobe color_transfer values of HDR video:
+// PQ (HDR10, HDR10+, Dolby Vision) and HLG
+var hdrTransfers = map[string]bool{
+	"smpte2084":    true,
+	"arib-std-b67": true,
+}
+
+// toneMapFilter converts HDR video to SDR BT.709: linearize the signal, map
+// highlights into SDR range with the Hable curve, then convert to 8-bit
+// BT.709 4:2:0 for H.264. Requires an ffmpeg built with zimg (zscale).
+const toneMapFilter = "zscale=t=linear:npl=100,format=gbrpf32le,zscale=p=bt709," +
+	"tonemap=tonemap=hable:desat=0,zscale=t=bt709:m=bt709:r=tv,format=yuv420p"
+
 var compatibleContainers = map[string]bool{
 	"mp4":  true,
 	"webm": true,
 	"ogg":  true,
 }
 
+// CompatibleCodecs returns the video codecs browsers play without
+// transcoding, sorted
+func CompatibleCodecs() []string {
+	return slices.Sorted(maps.Keys(compatibleCodecs))
+}
+
+// CompatibleContainers returns the container formats, as file extensions
+// without the dot, browsers play without remuxing, sorted
+func CompatibleContainers() []string {
+	return slices.Sorted(maps.Keys(compatibleContainers))
+}
+
+// hasCompatibleContainer reports whether a file's extension, in any case,
+// names a container browsers play directly
+func hasCompatibleContainer(filePath string) bool {
+	return compatibleContainers[strings.TrimPrefix(mediatypes.NormalizeExt(filePath), ".")]
+}
+
 // New creates a new Transcoder instance with the specified GPU acceleration mode.
 func New(cacheDir, logDir string, enabled bool, gpuAccel string) *Transcoder {
-	config := streaming.DefaultTimeoutWriterConfig()
-	config.WriteTimeout = 30 * time.Second
-	config.IdleTimeout = 60 * time.Second
-	config.ChunkSize = 256 * 1024 // 256KB chunks for video
-
 	logging.Info("Transcoder initialized: cacheDir=%q, logDir=%q, enabled=%v, gpuAccel=%q", cacheDir, logDir, enabled, gpuAccel)
 
 	// Create log directory if specified
@@ -106,14 +236,22 @@ func New(cacheDir, logDir string, enabled bool, gpuAccel string) *Transcoder {
 	}
 
 	t := &Transcoder{
-		cacheDir:     cacheDir,
-		logDir:       logDir,
-		enabled:      enabled,
-		processes:    make(map[string]*exec.Cmd),
-		cacheLocks:   make(map[string]*sync.Mutex),
-		streamConfig: config,
-		gpuAccel:     GPUAccel(gpuAccel),
-	}
+		cacheDir:       cacheDir,
+		logDir:         logDir,
+		enabled:        enabled,
+		processes:      make(map[string]*exec.Cmd),
+		cacheLocks:     make(map[string]*sync.Mutex),
+		hlsJobs:        make(map[string]*hlsJob),
+		hlsPrefetching: make(map[string]bool),
+		hlsFailed:      make(map[string]time.Time),
+		hlsSlots:       make(chan struct{}, hlsTranscodeWorkers),
+		infoCache:      make(map[string]cachedVideoInfo),
+		streamConfig:   streaming.ProfileConfig(streaming.ProfileVideo),
+		gpuAccel:       GPUAccel(gpuAccel),
+	}
+	t.maxTranscodeWait.Store(int64(DefaultMaxTranscodeWait))
+	t.hlsSegment.Store(int64(DefaultHLSSegmentDuration))
+	t.cpuEncoder.Store(&cpuEncoderSettings{preset: DefaultX264Preset, crf: DefaultX264CRF})
 
 	// Detect GPU capabilities if auto or specific GPU requested
 	if t.gpuAccel != GPUAccelNone {
@@ -125,6 +263,151 @@ func New(cacheDir, logDir string, enabled bool, gpuAccel string) *Transcoder {
 	return t
 }
 
+// SetMaxTranscodeWait sets the longest a request waits for a cached transcode.
+// Videos with unknown duration wait up to this limit, and their background
+// transcode is stopped once it is reached.
+func (t *Transcoder) SetMaxTranscodeWait(maxWait time.Duration) {
+	if maxWait <= 0 {
+		return
+	}
+	t.maxTranscodeWait.Store(int64(maxWait))
+}
+
+// SetHDRToneMapping enables tone-mapping of HDR video to SDR. Browsers play
+// HDR transcoded without it washed out; with it, HDR videos are always
+// transcoded, on the CPU, which is considerably slower. Set it before the
+// first video is probed: it decides whether HDR videos need a transcode.
+func (t *Transcoder) SetHDRToneMapping(enabled bool) {
+	t.toneMapHDR.Store(enabled)
+}
+
+// shouldToneMap reports whether a video is transcoded with tone-mapping
+func (t *Transcoder) shouldToneMap(info *VideoInfo) bool {
+	return info.HDR && t.toneMapHDR.Load()
+}
+
+// ParseWidthLadder parses a comma-separated list of output widths such as
+// "480,720,1080". The result is sorted and deduplicated; an empty value
+// returns nil.
+func ParseWidthLadder(value string) ([]int, error) {
+	var ladder []int
+	for part := range strings.SplitSeq(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		width, err := strconv.Atoi(part)
+		if err != nil || width <= 0 {
+			return nil, fmt.Errorf("invalid width %q", part)
+		}
+		ladder = append(ladder, width)
+	}
+	slices.Sort(ladder)
+	return slices.Compact(ladder), nil
+}
+
+// x264Presets are the libx264 presets, fastest first
+var x264Presets = []string{
+	"ultrafast", "superfast", "veryfast", "faster", "fast",
+	"medium", "slow", "slower", "veryslow", "placebo",
+}
+
+// Default and allowed libx264 settings for CPU encoding
+const (
+	DefaultX264Preset = "fast"
+	DefaultX264CRF    = 23
+	MinX264CRF        = 0
+	MaxX264CRF        = 51
+)
+
+// ParseX264Preset validates a libx264 preset name, in any case. An empty
+// value returns DefaultX264Preset.
+func ParseX264Preset(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return DefaultX264Preset, nil
+	}
+	if !slices.Contains(x264Presets, value) {
+		return "", fmt.Errorf("unknown preset %q (valid: %s)", value, strings.Join(x264Presets, ", "))
+	}
+	return value, nil
+}
+
+// ClampX264CRF limits a CRF to the range libx264 accepts for 8-bit output
+func ClampX264CRF(crf int) int {
+	return min(max(crf, MinX264CRF), MaxX264CRF)
+}
+
+// SetCPUEncoder sets the libx264 preset and CRF used when transcoding on the
+// CPU. Faster presets and higher CRFs trade quality for speed and size; GPU
+// encoders keep their own rate control. The preset should come from
+// ParseX264Preset, and the CRF is clamped to the valid range.
+func (t *Transcoder) SetCPUEncoder(preset string, crf int) {
+	crf = ClampX264CRF(crf)
+	t.cpuEncoder.Store(&cpuEncoderSettings{preset: preset, crf: crf})
+	logging.Info("CPU encoder: libx264 preset=%s, crf=%d", preset, crf)
+}
+
+// SetWidthLadder sets the widths requested transcode widths are snapped to.
+// A request is served at the smallest rung that is at least as wide, so
+// concurrent requests for 700px and 720px share one transcode and cache
+// entry. An empty ladder disables snapping.
+func (t *Transcoder) SetWidthLadder(ladder []int) {
+	if len(ladder) == 0 {
+		t.widthLadder.Store(nil)
+		return
+	}
+	sorted := slices.Clone(ladder)
+	slices.Sort(sorted)
+	t.widthLadder.Store(&sorted)
+}
+
+// snapWidth returns the ladder rung for a requested width. Zero (original
+// size) and widths above the largest rung are returned unchanged.
+func (t *Transcoder) snapWidth(targetWidth int) int {
+	ladder := t.widthLadder.Load()
+	if ladder == nil || targetWidth <= 0 {
+		return targetWidth
+	}
+	for _, rung := range *ladder {
+		if rung >= targetWidth {
+			return rung
+		}
+	}
+	return targetWidth
+}
+
+// transcodeWaitTimeout returns how long to wait for a video of the given
+// duration to transcode: twice its length, at least minTranscodeWait, and
+// never more than the configured maximum.
+func (t *Transcoder) transcodeWaitTimeout(duration float64) time.Duration {
+	maxWait := time.Duration(t.maxTranscodeWait.Load())
+	if !IsKnownDuration(duration) {
+		return maxWait
+	}
+
+	wait := time.Duration(duration * transcodeWaitFactor * float64(time.Second))
+	wait = max(wait, minTranscodeWait)
+	return min(wait, maxWait)
+}
+
+// backgroundTranscodeContext returns the context for a transcode that outlives
+// the request. Without a duration there is no way to tell a slow transcode
+// from one that never ends, so those are capped at the maximum wait.
+func (t *Transcoder) backgroundTranscodeContext(info *VideoInfo) (context.Context, context.CancelFunc) {
+	if IsKnownDuration(info.Duration) {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), t.transcodeWaitTimeout(info.Duration))
+}
+
+// IsKnownDuration reports whether a probed duration is usable for seek and
+// progress calculations. ffprobe reports zero, N/A or nan for some live-stream
+// recordings and fragmented files.
+func IsKnownDuration(duration float64) bool {
+	return duration > 0 && !math.IsInf(duration, 0) && !math.IsNaN(duration)
+}
+
 // IsEnabled returns whether transcoding is enabled.
 func (t *Transcoder) IsEnabled() bool {
 	return t.enabled
@@ -135,13 +418,107 @@ func (t *Transcoder) GetCacheDir() string {
 	return t.cacheDir
 }
 
-// GetVideoInfo retrieves codec and dimension information about a video file.
+// GetVideoInfo retrieves codec, dimension, and chapter information about a video file.
+// Results are cached per file and reused until the file's size or modification time changes.
 func (t *Transcoder) GetVideoInfo(ctx context.Context, filePath string) (*VideoInfo, error) {
+	stat, statErr := os.Stat(filePath)
+	if statErr == nil {
+		if info, ok := t.getCachedVideoInfo(filePath, stat); ok {
+			return info, nil
+		}
+	}
+
+	info, err := t.probeVideoInfo(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if statErr == nil {
+		t.storeVideoInfo(filePath, stat, info)
+	}
+
+	return info, nil
+}
+
+// getCachedVideoInfo returns a copy of the cached probe result if the file is unchanged.
+func (t *Transcoder) getCachedVideoInfo(filePath string, stat os.FileInfo) (*VideoInfo, bool) {
+	t.infoCacheMu.RLock()
+	cached, ok := t.infoCache[filePath]
+	t.infoCacheMu.RUnlock()
+
+	if !ok || cached.size != stat.Size() || !cached.modTime.Equal(stat.ModTime()) {
+		t.infoCacheMisses.Add(1)
+		return nil, false
+	}
+	t.infoCacheHits.Add(1)
+
+	info := cached.info
+	info.Chapters = slices.Clone(info.Chapters)
+	return &info, true
+}
+
+// storeVideoInfo caches a probe result for the given file state.
+func (t *Transcoder) storeVideoInfo(filePath string, stat os.FileInfo, info *VideoInfo) {
+	t.infoCacheMu.Lock()
+	defer t.infoCacheMu.Unlock()
+
+	if _, exists := t.infoCache[filePath]; !exists && len(t.infoCache) >= maxVideoInfoCacheEntries {
+		// Evict an arbitrary entry; re-probing an evicted file is cheap
+		for key := range t.infoCache {
+			delete(t.infoCache, key)
+			break
+		}
+	}
+
+	cached := cachedVideoInfo{
+		info:    *info,
+		size:    stat.Size(),
+		modTime: stat.ModTime(),
+	}
+	cached.info.Chapters = slices.Clone(info.Chapters)
+	t.infoCache[filePath] = cached
+}
+
+// InfoCacheStats reports the usage of the in-memory probe cache.
+func (t *Transcoder) InfoCacheStats() CacheStats {
+	t.infoCacheMu.RLock()
+	defer t.infoCacheMu.RUnlock()
+
+	var memory int64
+	for path, cached := range t.infoCache {
+		memory += int64(len(path)) + int64(unsafe.Sizeof(cached)) + int64(len(cached.info.Codec))
+		for _, chapter := range cached.info.Chapters {
+			memory += int64(unsafe.Sizeof(chapter)) + int64(len(chapter.Title))
+		}
+	}
+
+	return CacheStats{
+		Entries:     len(t.infoCache),
+		Hits:        t.infoCacheHits.Load(),
+		Misses:      t.infoCacheMisses.Load(),
+		MemoryBytes: memory,
+	}
+}
+
+// FlushInfoCache drops all cached probe results so the next request for each
+// video runs ffprobe again. Returns the number of entries removed.
+func (t *Transcoder) FlushInfoCache() int {
+	t.infoCacheMu.Lock()
+	defer t.infoCacheMu.Unlock()
+
+	count := len(t.infoCache)
+	clear(t.infoCache)
+	return count
+}
+
+// probeVideoInfo runs ffprobe against a file and parses the result.
+func (t *Transcoder) probeVideoInfo(ctx context.Context, filePath string) (*VideoInfo, error) {
 	cmd := exec.CommandContext(ctx, "ffprobe",
 		"-v", "quiet",
 		"-print_format", "json",
 		"-show_format",
 		"-show_streams",
+		"-show_chapters",
 		filePath,
 	)
 
@@ -166,6 +543,12 @@ func (t *Transcoder) GetVideoInfo(ctx context.Context, filePath string) (*VideoI
 		durStr := strings.Trim(output[start:start+end], ` "`)
 		info.Duration, _ = strconv.ParseFloat(durStr, 64)
 	}
+	if !IsKnownDuration(info.Duration) {
+		// Normalize N/A, nan, negative and infinite values; NaN would also
+		// break JSON encoding of the stream info
+		logging.Debug("Duration unknown for %s", filePath)
+		info.Duration = 0
+	}
 
 	// Extract codec
 	if idx := strings.Index(output, `"codec_name"`); idx != -1 {
@@ -210,12 +593,80 @@ func (t *Transcoder) GetVideoInfo(ctx context.Context, filePath string) (*VideoI
 	}
 
 	// Check if transcoding is needed
-	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filePath), "."))
-	info.NeedsTranscode = !compatibleCodecs[info.Codec] || !compatibleContainers[ext]
+	info.NeedsTranscode = !compatibleCodecs[info.Codec] || !hasCompatibleContainer(filePath)
+
+	info.Chapters = parseChapters(stdout.Bytes())
+
+	info.ColorTransfer, info.ColorPrimaries = parseColorInfo(stdout.Bytes())
+	info.HDR = hdrTransfers[info.ColorTransfer]
+	if t.shouldToneMap(info) {
+		// Even browser-compatible HDR (VP9, AV1) is transcoded to tone-map it
+		info.NeedsTranscode = true
+	}
 
 	return info, nil
 }
 
+// parseColorInfo extracts the color transfer and primaries of the first video
+// stream from ffprobe JSON output. Both are empty if ffprobe didn't report them.
+func parseColorInfo(output []byte) (transfer, primaries string) {
+	var probe struct {
+		Streams []struct {
+			CodecType      string `json:"codec_type"`
+			ColorTransfer  string `json:"color_transfer"`
+			ColorPrimaries string `json:"color_primaries"`
+		} `json:"streams"`
+	}
+
+	if err := json.Unmarshal(output, &probe); err != nil {
+		logging.Debug("Failed to parse ffprobe streams: %v", err)
+		return "", ""
+	}
+
+	for _, stream := range probe.Streams {
+		if stream.CodecType == "video" {
+			return stream.ColorTransfer, stream.ColorPrimaries
+		}
+	}
+	return "", ""
+}
+
+// parseChapters extracts chapter markers from ffprobe JSON output.
+// Returns an empty (non-nil) slice when the video has no chapters or the output can't be parsed.
+func parseChapters(output []byte) []Chapter {
+	var probe struct {
+		Chapters []struct {
+			StartTime string            `json:"start_time"`
+			EndTime   string            `json:"end_time"`
+			Tags      map[string]string `json:"tags"`
+		} `json:"chapters"`
+	}
+
+	chapters := []Chapter{}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		logging.Debug("Failed to parse ffprobe chapters: %v", err)
+		return chapters
+	}
+
+	for i, c := range probe.Chapters {
+		start, _ := strconv.ParseFloat(c.StartTime, 64)
+		end, _ := strconv.ParseFloat(c.EndTime, 64)
+
+		title := c.Tags["title"]
+		if title == "" {
+			title = fmt.Sprintf("Chapter %d", i+1)
+		}
+
+		chapters = append(chapters, Chapter{
+			Title:     title,
+			StartTime: start,
+			EndTime:   end,
+		})
+	}
+
+	return chapters
+}
+
 // GetOrStartTranscode checks if video is cached, or starts transcoding in background
 // Returns: cachePath, isCached, error
 func (t *Transcoder) GetOrStartTranscode(_ context.Context, filePath string, targetWidth int, info *VideoInfo) (cachePath string, isCached bool, err error) {
@@ -223,7 +674,8 @@ func (t *Transcoder) GetOrStartTranscode(_ context.Context, filePath string, tar
 		return "", false, fmt.Errorf("transcoding required but disabled (cache directory not writable)")
 	}
 
-	// Generate cache key and path
+	// Generate cache key and path from the snapped width
+	targetWidth = t.snapWidth(targetWidth)
 	cacheKey := fmt.Sprintf("%s_w%d.mp4", filepath.Base(filePath), targetWidth)
 	cachePath = filepath.Join(t.cacheDir, cacheKey)
 
@@ -265,7 +717,8 @@ func (t *Transcoder) GetOrStartTranscode(_ context.Context, filePath string, tar
 		defer cacheLock.Unlock()
 
 		// Use a background context so transcoding continues even if request is canceled
-		bgCtx := context.Background()
+		bgCtx, cancel := t.backgroundTranscodeContext(info)
+		defer cancel()
 
 		if err := t.transcodeDirectToCache(bgCtx, filePath, cachePath, targetWidth, info, needsReencode); err != nil {
 			logging.Error("Background transcode failed for %s: %v", filePath, err)
@@ -296,7 +749,8 @@ func (t *Transcoder) GetOrStartTranscodeAndWait(ctx context.Context, filePath st
 		return "", fmt.Errorf("transcoding required but disabled (cache directory not writable)")
 	}
 
-	// Generate cache key and path
+	// Generate cache key and path from the snapped width
+	targetWidth = t.snapWidth(targetWidth)
 	cacheKey := fmt.Sprintf("%s_w%d.mp4", filepath.Base(filePath), targetWidth)
 	cachePath := filepath.Join(t.cacheDir, cacheKey)
 
@@ -335,7 +789,8 @@ func (t *Transcoder) GetOrStartTranscodeAndWait(ctx context.Context, filePath st
 			defer cacheLock.Unlock()
 
 			// Use a background context so transcoding continues even if request is canceled
-			bgCtx := context.Background()
+			bgCtx, cancel := t.backgroundTranscodeContext(info)
+			defer cancel()
 
 			if err := t.transcodeDirectToCache(bgCtx, filePath, cachePath, targetWidth, info, needsReencode); err != nil {
 				logging.Error("Background transcode failed for %s: %v", filePath, err)
@@ -359,7 +814,7 @@ func (t *Transcoder) GetOrStartTranscodeAndWait(ctx context.Context, filePath st
 
 	// Wait for transcode to complete
 	// We need the complete file for proper HTTP Range support
-	maxWaitTime := 5 * time.Minute // Reasonable timeout for most videos
+	maxWaitTime := t.transcodeWaitTimeout(info.Duration)
 	startWait := time.Now()
 	lastLogTime := time.Now()
 	lastSize := int64(0)
@@ -436,7 +891,8 @@ func (t *Transcoder) TranscodeToCache(ctx context.Context, filePath string, targ
 		return "", fmt.Errorf("transcoding required but disabled (cache directory not writable)")
 	}
 
-	// Generate cache key and path
+	// Generate cache key and path from the snapped width
+	targetWidth = t.snapWidth(targetWidth)
 	cacheKey := fmt.Sprintf("%s_w%d.mp4", filepath.Base(filePath), targetWidth)
 	cachePath := filepath.Join(t.cacheDir, cacheKey)
 
@@ -472,11 +928,26 @@ func (t *Transcoder) TranscodeToCache(ctx context.Context, filePath string, targ
 	return cachePath, nil
 }
 
+// ffmpegError is a failed ffmpeg run, with the stderr output that explains it
+type ffmpegError struct {
+	err    error
+	stderr string
+}
+
+func (e *ffmpegError) Error() string {
+	return fmt.Sprintf("ffmpeg error: %v - %s", e.err, e.stderr)
+}
+
+func (e *ffmpegError) Unwrap() error {
+	return e.err
+}
+
 // transcodeDirectToCache transcodes a video directly to a cache file without streaming
 func (t *Transcoder) transcodeDirectToCache(ctx context.Context, filePath, cachePath string, targetWidth int, info *VideoInfo, needsReencode bool) error {
 	// Try with GPU first, then retry with CPU if GPU fails
 	err := t.transcodeDirectToCacheWithOptions(ctx, filePath, cachePath, targetWidth, info, needsReencode, false)
-	if err != nil && t.gpuAvailable && t.isGPUError(err.Error()) {
+	var ffErr *ffmpegError
+	if errors.As(err, &ffErr) && t.gpuAvailable && t.isGPUError(ffErr.stderr) {
 		// Don't retry if shutting down or context canceled
 		if t.shuttingDown.Load() {
 			logging.Info("Shutdown in progress, skipping CPU retry for: %s", filePath)
@@ -523,8 +994,10 @@ func (t *Transcoder) transcodeDirectToCacheWithOptions(ctx context.Context, file
 	defer func() {
 		if !success {
 			_ = os.Remove(tmpPath)
+			discardPendingManifest(cachePath)
 		}
 	}()
+	writeManifest(cachePath, newCacheManifest(filePath, targetWidth, info, needsReencode))
 
 	// Run FFmpeg to transcode directly to file (not stdout)
 	// This allows +faststart to work since it needs a seekable output
@@ -547,11 +1020,7 @@ func (t *Transcoder) transcodeDirectToCacheWithOptions(ctx context.Context, file
 	var stderr bytes.Buffer
 	logFile := t.createTranscoderLog(filePath, targetWidth)
 	if logFile != nil {
-		defer func() {
-			if err := logFile.Close(); err != nil {
-				logging.Warn("Failed to close transcode log file: %v", err)
-			}
-		}()
+		defer t.closeTranscoderLog(ctx, logFile, cmd)
 		cmd.Stderr = io.MultiWriter(&stderr, logFile)
 	} else {
 		cmd.Stderr = &stderr
@@ -581,7 +1050,7 @@ func (t *Transcoder) transcodeDirectToCacheWithOptions(ctx context.Context, file
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
-		return fmt.Errorf("ffmpeg error: %w - %s", cmdErr, stderr.String())
+		return &ffmpegError{err: cmdErr, stderr: stderr.String()}
 	}
 
 	logging.Info("FFmpeg completed, renaming %s to %s", tmpPath, cachePath)
@@ -601,6 +1070,7 @@ func (t *Transcoder) transcodeDirectToCacheWithOptions(ctx context.Context, file
 	}
 
 	success = true
+	markManifestComplete(cachePath)
 	logging.Info("Successfully cached transcoded video: %s (%.2f MB)", cachePath, float64(stat.Size())/(1024*1024))
 	return nil
 }
@@ -613,6 +1083,7 @@ func (t *Transcoder) StreamVideo(ctx context.Context, filePath string, w io.Writ
 		logging.Error("Failed to get video info for %s: %v", filePath, err)
 		return err
 	}
+	targetWidth = t.snapWidth(targetWidth)
 
 	logging.Debug("StreamVideo: file=%s, codec=%s, needsTranscode=%v, width=%d->%d",
 		filePath, info.Codec, info.NeedsTranscode, info.Width, targetWidth)
@@ -746,6 +1217,7 @@ func (t *Transcoder) getCachedFile(sourcePath, cachePath string) (*os.File, erro
 			sourceInfo.ModTime(), cacheInfo.ModTime())
 		// Delete stale cache
 		_ = os.Remove(cachePath)
+		_ = os.Remove(manifestPath(cachePath))
 		return nil, errors.New("cache is stale")
 	}
 
@@ -787,7 +1259,9 @@ func (t *Transcoder) transcodeAndCache(ctx context.Context, filePath string, w i
 		}
 		// Clean up temp file if we didn't rename it
 		_ = os.Remove(tempPath)
+		discardPendingManifest(cachePath)
 	}()
+	writeManifest(cachePath, newCacheManifest(filePath, targetWidth, info, needsReencode))
 
 	// Build ffmpeg command - output to stdout for streaming
 	args := t.buildFFmpegArgs(filePath, "-", targetWidth, info, needsReencode)
@@ -810,11 +1284,7 @@ func (t *Transcoder) transcodeAndCache(ctx context.Context, filePath string, w i
 	var stderr bytes.Buffer
 	logFile := t.createTranscoderLog(filePath, targetWidth)
 	if logFile != nil {
-		defer func() {
-			if err := logFile.Close(); err != nil {
-				logging.Warn("Failed to close transcoder log file: %v", err)
-			}
-		}()
+		defer t.closeTranscoderLog(ctx, logFile, cmd)
 		cmd.Stderr = io.MultiWriter(&stderr, logFile)
 	} else {
 		cmd.Stderr = &stderr
@@ -866,6 +1336,7 @@ func (t *Transcoder) transcodeAndCache(ctx context.Context, filePath string, w i
 // getEncoderInfo returns a string describing the encoder being used
 func (t *Transcoder) getEncoderInfo(targetWidth int, info *VideoInfo, needsReencode bool) string {
 	needsScaling := targetWidth > 0 && targetWidth < info.Width
+	toneMap := t.shouldToneMap(info)
 
 	type encoderMode int
 	const (
@@ -876,9 +1347,9 @@ func (t *Transcoder) getEncoderInfo(targetWidth int, info *VideoInfo, needsReenc
 
 	var mode encoderMode
 	switch {
-	case !needsReencode && !needsScaling:
+	case !needsReencode && !needsScaling && !toneMap:
 		mode = modeStreamCopy
-	case t.gpuAvailable && t.gpuEncoder != "":
+	case !toneMap && t.gpuAvailable && t.gpuEncoder != "":
 		mode = modeGPU
 	default:
 		mode = modeCPU
@@ -890,6 +1361,9 @@ func (t *Transcoder) getEncoderInfo(targetWidth int, info *VideoInfo, needsReenc
 	case modeGPU:
 		return fmt.Sprintf(" [GPU: %s/%s]", t.gpuAccel, t.gpuEncoder)
 	case modeCPU:
+		if toneMap {
+			return " [CPU: libx264, HDR tone-mapped]"
+		}
 		return " [CPU: libx264]"
 	default:
 		return ""
@@ -931,7 +1405,7 @@ func (t *Transcoder) handleTranscodeFailure(ctx context.Context, filePath string
 	stderrStr := stderr.String()
 
 	// Check if this is a GPU-related error and retry with CPU if we haven't already
-	if t.gpuAvailable && t.isGPUError(stderrStr) {
+	if t.gpuAvailable && isGPUFailure(streamErr, cmdErr) && t.isGPUError(stderrStr) {
 		// Don't retry if shutting down or context canceled
 		if t.shuttingDown.Load() {
 			logging.Info("Shutdown in progress, skipping CPU retry for: %s", filePath)
@@ -989,6 +1463,10 @@ func (t *Transcoder) finalizeCache(tempPath, cachePath string) {
 		return
 	}
 	logging.Debug("Cache temp file written: %d bytes", fileInfo.Size())
+	if fileInfo.Size() == 0 {
+		logging.Warn("FFmpeg produced no output, not caching %s", cachePath)
+		return
+	}
 
 	// Atomic rename to final cache path
 	if err := os.Rename(tempPath, cachePath); err != nil {
@@ -1000,6 +1478,7 @@ func (t *Transcoder) finalizeCache(tempPath, cachePath string) {
 	if fileInfo, err := os.Stat(cachePath); err != nil {
 		logging.Warn("Cache file missing after rename: %v", err)
 	} else {
+		markManifestComplete(cachePath)
 		logging.Info("Transcode completed and cached to %s (%d bytes)", cachePath, fileInfo.Size())
 	}
 }
@@ -1028,11 +1507,7 @@ func (t *Transcoder) transcodeStream(ctx context.Context, filePath string, w io.
 	var stderr bytes.Buffer
 	logFile := t.createTranscoderLog(filePath, targetWidth)
 	if logFile != nil {
-		defer func() {
-			if err := logFile.Close(); err != nil {
-				logging.Warn("Failed to close transcoder log file: %v", err)
-			}
-		}()
+		defer t.closeTranscoderLog(ctx, logFile, cmd)
 		// Write to both buffer and log file
 		cmd.Stderr = io.MultiWriter(&stderr, logFile)
 	} else {
@@ -1076,6 +1551,27 @@ func (t *Transcoder) buildFFmpegArgs(inputPath, outputPath string, targetWidth i
 
 // buildFFmpegArgsWithOptions builds ffmpeg arguments with option to force CPU encoding
 func (t *Transcoder) buildFFmpegArgsWithOptions(inputPath, outputPath string, targetWidth int, info *VideoInfo, needsReencode, forceCPU bool) []string {
+	args := t.buildEncodeArgs(inputPath, targetWidth, info, needsReencode, forceCPU)
+
+	// MP4 muxer configuration depends on output type
+	if outputPath != "-" {
+		// For file output: use +faststart to put moov atom at beginning for better seeking
+		logging.Debug("Using +faststart for file output: %s", outputPath)
+		args = append(args, "-movflags", "+faststart")
+	} else {
+		// For stdout/pipe: use fragmented MP4 which supports non-seekable output
+		logging.Debug("Using fragmented MP4 for stdout streaming")
+		args = append(args, "-movflags", "frag_keyframe+empty_moov")
+	}
+
+	args = append(args, "-f", "mp4", outputPath)
+	return args
+}
+
+// buildEncodeArgs builds the input and codec arguments shared by every
+// output format: the video is copied when it can be, and otherwise encoded
+// to H.264 on the GPU or CPU; audio is always encoded to AAC.
+func (t *Transcoder) buildEncodeArgs(inputPath string, targetWidth int, info *VideoInfo, needsReencode, forceCPU bool) []string {
 	var args []string
 
 	// Initialize hardware device for VA-API if using GPU
@@ -1088,14 +1584,17 @@ func (t *Transcoder) buildFFmpegArgsWithOptions(inputPath, outputPath string, ta
 	// Check if we need to scale the video
 	needsScaling := targetWidth > 0 && targetWidth < info.Width
 
+	// Tone-mapping needs a re-encode, and runs on the CPU
+	toneMap := t.shouldToneMap(info)
+
 	// If codec is compatible AND no scaling needed, just copy the video stream (much faster)
 	// Otherwise, we must re-encode
-	if !needsReencode && !needsScaling {
+	if !needsReencode && !needsScaling && !toneMap {
 		logging.Info("Using stream copy (no re-encoding needed)")
 		args = append(args, "-c:v", "copy")
 	} else {
 		// Re-encode with h264 - use GPU if available and not forced to CPU, otherwise CPU
-		if !forceCPU && t.gpuAvailable && t.gpuEncoder != "" {
+		if !forceCPU && !toneMap && t.gpuAvailable && t.gpuEncoder != "" {
 			// Build a description of the encoding configuration
 			var filterDesc string
 			if t.gpuInitFilter != "" {
@@ -1116,6 +1615,9 @@ func (t *Transcoder) buildFFmpegArgsWithOptions(inputPath, outputPath string, ta
 			if forceCPU && t.gpuAvailable {
 				logging.Info("Falling back to CPU encoder after GPU failure")
 			}
+			if toneMap {
+				logging.Info("Tone-mapping HDR video (%s) to SDR", info.ColorTransfer)
+			}
 			var scaleDesc string
 			if needsScaling {
 				scaleDesc = fmt.Sprintf(" with scale=%dx-2", targetWidth)
@@ -1123,36 +1625,28 @@ func (t *Transcoder) buildFFmpegArgsWithOptions(inputPath, outputPath string, ta
 				scaleDesc = " (maintaining dimensions)"
 			}
 			logging.Info("Using CPU encoder: libx264%s", scaleDesc)
-			logging.Debug("CPU encoder details: preset=fast, crf=23, scaling=%v", needsScaling)
+			encoder := t.cpuEncoder.Load()
+			logging.Debug("CPU encoder details: preset=%s, crf=%d, scaling=%v", encoder.preset, encoder.crf, needsScaling)
 			args = t.addCPUEncoderArgs(args, targetWidth, info, needsScaling)
 		}
 	}
 
 	// Always re-encode audio to AAC for web compatibility
 	args = append(args, "-c:a", "aac", "-b:a", "128k")
-
-	// MP4 muxer configuration depends on output type
-	if outputPath != "-" {
-		// For file output: use +faststart to put moov atom at beginning for better seeking
-		logging.Debug("Using +faststart for file output: %s", outputPath)
-		args = append(args, "-movflags", "+faststart")
-	} else {
-		// For stdout/pipe: use fragmented MP4 which supports non-seekable output
-		logging.Debug("Using fragmented MP4 for stdout streaming")
-		args = append(args, "-movflags", "frag_keyframe+empty_moov")
-	}
-
-	args = append(args, "-f", "mp4", outputPath)
 	return args
 }
 
-// handleTranscodeError handles errors from transcoding
+// handleTranscodeError handles errors from transcoding. Failure is decided by
+// the stream and the ffmpeg exit status alone: ffmpeg also writes warnings
+// (deprecated pixel formats, non-monotonous DTS) to stderr on runs that
+// produce valid output, so stderr is diagnostic only.
 func (t *Transcoder) handleTranscodeError(ctx context.Context, filePath string, streamErr, cmdErr error, stderrOutput string) error {
 	// Determine the actual error
 	if streamErr != nil {
-		if errors.Is(streamErr, streaming.ErrClientGone) || errors.Is(streamErr, streaming.ErrWriteTimeout) {
+		if errors.Is(streamErr, streaming.ErrClientGone) || errors.Is(streamErr, streaming.ErrWriteTimeout) ||
+			errors.Is(streamErr, streaming.ErrStreamCanceled) {
 			logging.Debug("Stream ended: %v for %s", streamErr, filePath)
-			return nil // Not really an error, client just left
+			return nil // Not really an error, client left or the server is shutting down
 		}
 		return streamErr
 	}
@@ -1170,6 +1664,19 @@ func (t *Transcoder) handleTranscodeError(ctx context.Context, filePath string,
 	return nil
 }
 
+// isGPUFailure reports whether a failed transcode may be retried on the CPU
+// when its stderr names a GPU problem. FFmpeg mentions the hardware encoder
+// in its normal output, so stderr only counts when the process itself failed,
+// and not when it was killed because the client went away or the stream was
+// drained for shutdown.
+func isGPUFailure(streamErr, cmdErr error) bool {
+	if cmdErr == nil {
+		return false
+	}
+	return !errors.Is(streamErr, streaming.ErrClientGone) && !errors.Is(streamErr, streaming.ErrWriteTimeout) &&
+		!errors.Is(streamErr, streaming.ErrStreamCanceled)
+}
+
 // isGPUError checks if an ffmpeg error is related to GPU hardware access
 func (t *Transcoder) isGPUError(stderrOutput string) bool {
 	lowerOutput := strings.ToLower(stderrOutput)
@@ -1283,11 +1790,7 @@ func (t *Transcoder) retryTranscodeWithCPU(ctx context.Context, filePath string,
 	var stderr bytes.Buffer
 	logFile := t.createTranscoderLog(filePath, targetWidth)
 	if logFile != nil {
-		defer func() {
-			if err := logFile.Close(); err != nil {
-				logging.Warn("Failed to close transcoder log file: %v", err)
-			}
-		}()
+		defer t.closeTranscoderLog(ctx, logFile, cmd)
 		cmd.Stderr = io.MultiWriter(&stderr, logFile)
 	} else {
 		cmd.Stderr = &stderr
@@ -1359,6 +1862,10 @@ func (t *Transcoder) retryTranscodeWithCPU(ctx context.Context, filePath string,
 		return nil
 	}
 	logging.Debug("Cache temp file written: %d bytes", fileInfo.Size())
+	if fileInfo.Size() == 0 {
+		logging.Warn("FFmpeg produced no output, not caching %s", cachePath)
+		return nil
+	}
 
 	// Atomic rename to final cache path
 	logging.Info("FFmpeg completed, renaming %s to %s", tempPath, cachePath)
@@ -1367,6 +1874,7 @@ func (t *Transcoder) retryTranscodeWithCPU(ctx context.Context, filePath string,
 		return nil // Transcode succeeded, cache is bonus
 	}
 
+	markManifestComplete(cachePath)
 	fileSize := float64(fileInfo.Size()) / (1024 * 1024)
 	logging.Info("Successfully cached transcoded video: %s (%.2f MB)", cachePath, fileSize)
 
@@ -1441,7 +1949,7 @@ func (t *Transcoder) ClearCache() (int64, error) {
 	return freedBytes, nil
 }
 
-// GetCacheSize returns the total size of the transcoder cache in bytes and the number of files (excluding .err files).
+// GetCacheSize returns the total size of the transcoder cache in bytes and the number of files (excluding .err files and manifests).
 func (t *Transcoder) GetCacheSize() (size int64, count int, err error) {
 	if t.cacheDir == "" || !t.enabled {
 		return 0, 0, nil
@@ -1454,6 +1962,7 @@ func (t *Transcoder) GetCacheSize() (size int64, count int, err error) {
 
 	if now-lastUpdate < cacheDuration {
 		// Return cached values
+		t.sizeCacheHits.Add(1)
 		return t.cachedSize.Load(), int(t.cachedCount.Load()), nil
 	}
 
@@ -1464,8 +1973,10 @@ func (t *Transcoder) GetCacheSize() (size int64, count int, err error) {
 	// Double-check after acquiring lock (another goroutine might have updated it)
 	lastUpdate = t.lastCacheUpdate.Load()
 	if now-lastUpdate < cacheDuration {
+		t.sizeCacheHits.Add(1)
 		return t.cachedSize.Load(), int(t.cachedCount.Load()), nil
 	}
+	t.sizeCacheMisses.Add(1)
 
 	// Recalculate cache size
 	size, count, err = t.getDirSizeAndCount(t.cacheDir)
@@ -1487,6 +1998,28 @@ func (t *Transcoder) GetCacheSize() (size int64, count int, err error) {
 	return size, count, nil
 }
 
+// SizeCacheStats reports the usage of the cached cache size values.
+func (t *Transcoder) SizeCacheStats() CacheStats {
+	stats := CacheStats{
+		Hits:   t.sizeCacheHits.Load(),
+		Misses: t.sizeCacheMisses.Load(),
+	}
+	if t.lastCacheUpdate.Load() > 0 {
+		stats.Entries = 1
+		stats.MemoryBytes = 24 // size, count and timestamp
+	}
+	return stats
+}
+
+// FlushSizeCache discards the cached cache size so the next GetCacheSize call
+// walks the cache directory. Returns the number of entries removed.
+func (t *Transcoder) FlushSizeCache() int {
+	if t.lastCacheUpdate.Swap(0) > 0 {
+		return 1
+	}
+	return 0
+}
+
 // progressTrackingReader wraps an io.Reader to log streaming progress
 type progressTrackingReader struct {
 	reader     io.Reader
@@ -1566,14 +2099,19 @@ func (t *Transcoder) getDirSize(path string) (int64, error) {
 }
 
 func (t *Transcoder) getDirSizeAndCount(path string) (size int64, count int, err error) {
+	hlsRoot := filepath.Join(t.cacheDir, hlsDirName) + string(filepath.Separator)
 	err = filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if !info.IsDir() {
 			size += info.Size()
-			// Exclude .err files from count
-			if !strings.HasSuffix(filePath, ".err") {
+			// Exclude .err files and manifests from count, and count an HLS
+			// rendition once, by its playlist
+			switch {
+			case strings.HasSuffix(filePath, ".err"), strings.HasSuffix(filePath, manifestExt):
+			case strings.HasPrefix(filePath, hlsRoot) && info.Name() != HLSPlaylistName:
+			default:
 				count++
 			}
 		}
@@ -1915,21 +2453,28 @@ func (t *Transcoder) addGPUEncoderArgs(args []string, targetWidth int, info *Vid
 
 // addCPUEncoderArgs adds CPU encoder arguments to ffmpeg command
 func (t *Transcoder) addCPUEncoderArgs(args []string, targetWidth int, info *VideoInfo, needsScaling bool) []string {
-	args = append(args, "-c:v", "libx264", "-preset", "fast", "-crf", "23")
+	encoder := t.cpuEncoder.Load()
+	args = append(args, "-c:v", "libx264", "-preset", encoder.preset, "-crf", strconv.Itoa(encoder.crf))
+
+	var filters []string
+	if t.shouldToneMap(info) {
+		filters = append(filters, toneMapFilter)
+	}
 
 	// Tier 2: Always add scale filter when re-encoding to ensure output dimensions
 	// match the (possibly adjusted) dimensions from GetVideoInfo
 	if needsScaling {
 		// Scale to requested width, maintaining aspect ratio with even height
 		logging.Debug("Adding scale filter: %dx-2", targetWidth)
-		args = append(args, "-vf", fmt.Sprintf("scale=%d:-2", targetWidth))
+		filters = append(filters, fmt.Sprintf("scale=%d:-2", targetWidth))
 	} else {
 		// No size reduction, but force exact dimensions to handle odd dimensions
 		// This ensures output matches Tier 1 adjusted dimensions (always even)
 		logging.Debug("Adding scale filter for exact dimensions: %dx%d", info.Width, info.Height)
-		args = append(args, "-vf", fmt.Sprintf("scale=%d:%d", info.Width, info.Height))
+		filters = append(filters, fmt.Sprintf("scale=%d:%d", info.Width, info.Height))
 	}
 
+	args = append(args, "-vf", strings.Join(filters, ","))
 	return args
 }
 
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 )
 
 // TestGetCacheSize_Caching tests that cache size results are cached for 2 minutes
@@ -247,3 +248,176 @@ func BenchmarkGetCacheSize_LargeCache(b *testing.B) {
 		}
 	})
 }
+
+func TestInfoCacheStatsAndFlush(t *testing.T) {
+	trans := New(t.TempDir(), "", true, "none")
+
+	sourcePath := filepath.Join(t.TempDir(), "video.mkv")
+	if err := os.WriteFile(sourcePath, []byte("source"), 0o644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	stat, err := os.Stat(sourcePath)
+	if err != nil {
+		t.Fatalf("Failed to stat source: %v", err)
+	}
+
+	if _, ok := trans.getCachedVideoInfo(sourcePath, stat); ok {
+		t.Fatal("Expected empty cache to miss")
+	}
+	probed := &VideoInfo{
+		Codec:    "hevc",
+		Chapters: []Chapter{{Title: "Intro"}},
+	}
+	trans.storeVideoInfo(sourcePath, stat, probed)
+	probed.Chapters[0].Title = "Changed after storing"
+
+	info, ok := trans.getCachedVideoInfo(sourcePath, stat)
+	if !ok {
+		t.Fatal("Expected stored probe result to hit")
+	}
+	if info.Chapters[0].Title != "Intro" {
+		t.Errorf("Expected the cache to keep its own chapters, got %q", info.Chapters[0].Title)
+	}
+	info.Chapters[0].Title = "Changed after reading"
+
+	if info, _ := trans.getCachedVideoInfo(sourcePath, stat); info.Chapters[0].Title != "Intro" {
+		t.Errorf("Expected callers to get their own chapters, got %q", info.Chapters[0].Title)
+	}
+
+	stats := trans.InfoCacheStats()
+	if stats.Entries != 1 || stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Unexpected cache usage: %+v", stats)
+	}
+	if stats.MemoryBytes <= int64(len(sourcePath)) {
+		t.Errorf("Expected memory estimate to cover the entry, got %d", stats.MemoryBytes)
+	}
+
+	if flushed := trans.FlushInfoCache(); flushed != 1 {
+		t.Errorf("Expected 1 entry flushed, got %d", flushed)
+	}
+	if _, ok := trans.getCachedVideoInfo(sourcePath, stat); ok {
+		t.Error("Expected flushed probe result to miss")
+	}
+	if stats := trans.InfoCacheStats(); stats.Entries != 0 || stats.MemoryBytes != 0 {
+		t.Errorf("Expected empty cache after flush, got %+v", stats)
+	}
+}
+
+func TestCacheManifestLifecycle(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "video.mkv")
+	if err := os.WriteFile(sourcePath, []byte("source"), 0o644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	cachePath := filepath.Join(t.TempDir(), "video.mkv_w720.mp4")
+
+	writeManifest(cachePath, newCacheManifest(sourcePath, 720, &VideoInfo{Codec: "hevc"}, true))
+	m, err := readManifest(cachePath)
+	if err != nil {
+		t.Fatalf("readManifest() error: %v", err)
+	}
+	if m.Source != sourcePath || m.TargetWidth != 720 || m.Codec != "h264" || m.Complete || m.SourceModTime.IsZero() {
+		t.Errorf("Unexpected pending manifest: %+v", m)
+	}
+
+	// A failed attempt's manifest is discarded
+	discardPendingManifest(cachePath)
+	if _, err := os.Stat(manifestPath(cachePath)); !os.IsNotExist(err) {
+		t.Error("Expected pending manifest to be discarded")
+	}
+
+	// A finished one is kept
+	writeManifest(cachePath, newCacheManifest(sourcePath, 0, &VideoInfo{Codec: "vp9"}, false))
+	markManifestComplete(cachePath)
+	discardPendingManifest(cachePath)
+	m, err = readManifest(cachePath)
+	if err != nil {
+		t.Fatalf("readManifest() error: %v", err)
+	}
+	if !m.Complete || m.Codec != "vp9" {
+		t.Errorf("Unexpected complete manifest: %+v", m)
+	}
+}
+
+func TestReconcileCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	sourceDir := t.TempDir()
+	trans := New(cacheDir, "", true, "none")
+
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	entry := func(name string, complete bool) string {
+		t.Helper()
+		source := filepath.Join(sourceDir, name)
+		writeFile(source, "source")
+		cachePath := filepath.Join(cacheDir, name+"_w0.mp4")
+		writeFile(cachePath, "transcoded")
+		m := newCacheManifest(source, 0, &VideoInfo{Codec: "hevc"}, true)
+		m.Complete = complete
+		writeManifest(cachePath, m)
+		return cachePath
+	}
+
+	kept := entry("kept.mkv", true)
+	interrupted := entry("interrupted.mkv", false)
+	changed := entry("changed.mkv", true)
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(sourceDir, "changed.mkv"), later, later); err != nil {
+		t.Fatalf("Failed to touch source: %v", err)
+	}
+	deleted := entry("deleted.mkv", true)
+	if err := os.Remove(filepath.Join(sourceDir, "deleted.mkv")); err != nil {
+		t.Fatalf("Failed to remove source: %v", err)
+	}
+	legacy := filepath.Join(cacheDir, "legacy.mkv_w0.mp4")
+	writeFile(legacy, "transcoded")
+	writeFile(filepath.Join(cacheDir, "crashed.mkv_w0.mp4.tmp"), "partial")
+	writeFile(filepath.Join(cacheDir, "failed.mkv_w0.mp4.err"), "ffmpeg error")
+
+	valid, removed := trans.ReconcileCache()
+	if valid != 1 {
+		t.Errorf("Expected 1 valid entry, got %d", valid)
+	}
+	// Two leftovers, then a cache file and manifest for each of three stale entries
+	if removed != 8 {
+		t.Errorf("Expected 8 files removed, got %d", removed)
+	}
+
+	for _, path := range []string{kept, manifestPath(kept), legacy} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be kept: %v", filepath.Base(path), err)
+		}
+	}
+	for _, path := range []string{interrupted, changed, deleted, manifestPath(interrupted)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", filepath.Base(path))
+		}
+	}
+
+	_, count, err := trans.GetCacheSize()
+	if err != nil {
+		t.Fatalf("GetCacheSize() error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 cached videos after reconcile, got %d", count)
+	}
+}
+
+func TestReconcileCacheDisabled(t *testing.T) {
+	cacheDir := t.TempDir()
+	tmpPath := filepath.Join(cacheDir, "video.mkv_w0.mp4.tmp")
+	if err := os.WriteFile(tmpPath, []byte("partial"), 0o644); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+
+	trans := New(cacheDir, "", false, "none")
+	if valid, removed := trans.ReconcileCache(); valid != 0 || removed != 0 {
+		t.Errorf("Expected disabled transcoder to skip reconcile, got %d valid, %d removed", valid, removed)
+	}
+	if _, err := os.Stat(tmpPath); err != nil {
+		t.Errorf("Expected temp file to be left alone: %v", err)
+	}
+}
@@ -3,10 +3,13 @@ package transcoder
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"io"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
@@ -312,6 +315,291 @@ echo '{"streams":[{"codec_name":"h264","width":1279,"height":719}],"format":{"du
 	}
 }
 
+func TestGetVideoInfo_ParsesChapters(t *testing.T) {
+	tmpDir := t.TempDir()
+	mockFFProbe := filepath.Join(tmpDir, "ffprobe")
+
+	// Second chapter has no title tag and should get a generated one
+	ffprobeScript := `#!/bin/bash
+echo '{"streams":[{"codec_name":"h264","width":1920,"height":1080}],"chapters":[{"id":0,"start_time":"0.000000","end_time":"300.500000","tags":{"title":"Opening"}},{"id":1,"start_time":"300.500000","end_time":"900.000000"}],"format":{"duration":"900.0"}}'
+`
+
+	if err := os.WriteFile(mockFFProbe, []byte(ffprobeScript), 0o755); err != nil {
+		t.Fatalf("Failed to create mock ffprobe: %v", err)
+	}
+
+	oldPath := os.Getenv("PATH")
+	defer func() {
+		_ = os.Setenv("PATH", oldPath)
+	}()
+	_ = os.Setenv("PATH", tmpDir+":"+oldPath)
+
+	trans := New("/tmp/cache", "", true, "none")
+	ctx := context.Background()
+
+	info, err := trans.GetVideoInfo(ctx, "/fake/movie.mkv")
+	if err != nil {
+		t.Fatalf("GetVideoInfo() error: %v", err)
+	}
+
+	if len(info.Chapters) != 2 {
+		t.Fatalf("Expected 2 chapters, got %d", len(info.Chapters))
+	}
+
+	if info.Chapters[0].Title != "Opening" {
+		t.Errorf("Expected first chapter title=Opening, got %q", info.Chapters[0].Title)
+	}
+	if info.Chapters[0].StartTime != 0 || info.Chapters[0].EndTime != 300.5 {
+		t.Errorf("Unexpected first chapter bounds: %+v", info.Chapters[0])
+	}
+	if info.Chapters[1].Title != "Chapter 2" {
+		t.Errorf("Expected generated title 'Chapter 2', got %q", info.Chapters[1].Title)
+	}
+	if info.Chapters[1].StartTime != 300.5 || info.Chapters[1].EndTime != 900 {
+		t.Errorf("Unexpected second chapter bounds: %+v", info.Chapters[1])
+	}
+
+	// Chapters must not confuse the existing stream/format parsing
+	if info.Duration != 900 {
+		t.Errorf("Expected duration=900, got %f", info.Duration)
+	}
+}
+
+func TestGetVideoInfo_NoChaptersReturnsEmptyList(t *testing.T) {
+	tmpDir := t.TempDir()
+	mockFFProbe := filepath.Join(tmpDir, "ffprobe")
+
+	ffprobeScript := `#!/bin/bash
+echo '{"streams":[{"codec_name":"h264","width":1920,"height":1080}],"format":{"duration":"100.0"}}'
+`
+
+	if err := os.WriteFile(mockFFProbe, []byte(ffprobeScript), 0o755); err != nil {
+		t.Fatalf("Failed to create mock ffprobe: %v", err)
+	}
+
+	oldPath := os.Getenv("PATH")
+	defer func() {
+		_ = os.Setenv("PATH", oldPath)
+	}()
+	_ = os.Setenv("PATH", tmpDir+":"+oldPath)
+
+	trans := New("/tmp/cache", "", true, "none")
+
+	info, err := trans.GetVideoInfo(context.Background(), "/fake/video.mp4")
+	if err != nil {
+		t.Fatalf("GetVideoInfo() error: %v", err)
+	}
+
+	if info.Chapters == nil {
+		t.Error("Expected non-nil empty chapter list so JSON encodes as []")
+	}
+	if len(info.Chapters) != 0 {
+		t.Errorf("Expected no chapters, got %d", len(info.Chapters))
+	}
+}
+
+func TestGetVideoInfo_DetectsHDR(t *testing.T) {
+	tests := []struct {
+		name          string
+		stream        string
+		toneMap       bool
+		wantHDR       bool
+		wantTranscode bool
+		wantTransfer  string
+		wantPrimaries string
+	}{
+		{"PQ", `"codec_name":"vp9","color_transfer":"smpte2084","color_primaries":"bt2020"`, false, true, false, "smpte2084", "bt2020"},
+		{"HLG", `"codec_name":"vp9","color_transfer":"arib-std-b67","color_primaries":"bt2020"`, false, true, false, "arib-std-b67", "bt2020"},
+		{"HDR with tone-mapping", `"codec_name":"vp9","color_transfer":"smpte2084","color_primaries":"bt2020"`, true, true, true, "smpte2084", "bt2020"},
+		{"SDR with tone-mapping", `"codec_name":"vp9","color_transfer":"bt709","color_primaries":"bt709"`, true, false, false, "bt709", "bt709"},
+		{"no color info", `"codec_name":"vp9"`, true, false, false, "", ""},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			ffprobeScript := `#!/bin/bash
+echo '{"streams":[{"codec_type":"video",` + tt.stream + `,"width":3840,"height":2160},{"codec_type":"audio","codec_name":"opus"}],"format":{"duration":"60.0"}}'
+`
+			if err := os.WriteFile(filepath.Join(tmpDir, "ffprobe"), []byte(ffprobeScript), 0o755); err != nil {
+				t.Fatalf("Failed to create mock ffprobe: %v", err)
+			}
+			t.Setenv("PATH", tmpDir+":"+os.Getenv("PATH"))
+
+			trans := New("/tmp/cache", "", true, "none")
+			trans.SetHDRToneMapping(tt.toneMap)
+
+			info, err := trans.GetVideoInfo(context.Background(), "/fake/hdr"+strconv.Itoa(i)+".webm")
+			if err != nil {
+				t.Fatalf("GetVideoInfo() error: %v", err)
+			}
+
+			if info.HDR != tt.wantHDR {
+				t.Errorf("HDR = %v, want %v", info.HDR, tt.wantHDR)
+			}
+			if info.NeedsTranscode != tt.wantTranscode {
+				t.Errorf("NeedsTranscode = %v, want %v", info.NeedsTranscode, tt.wantTranscode)
+			}
+			if info.ColorTransfer != tt.wantTransfer || info.ColorPrimaries != tt.wantPrimaries {
+				t.Errorf("Color = %q/%q, want %q/%q", info.ColorTransfer, info.ColorPrimaries, tt.wantTransfer, tt.wantPrimaries)
+			}
+		})
+	}
+}
+
+func TestGetVideoInfo_UnknownDuration(t *testing.T) {
+	for _, duration := range []string{`"N/A"`, `"0.000000"`, `"nan"`, `"-1.5"`, `"inf"`} {
+		t.Run(duration, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			mockFFProbe := filepath.Join(tmpDir, "ffprobe")
+
+			// Live-stream recordings and fragmented files report no usable duration
+			ffprobeScript := `#!/bin/bash
+echo '{"streams":[{"codec_name":"h264","width":1920,"height":1080}],"format":{"duration":` + duration + `}}'
+`
+
+			if err := os.WriteFile(mockFFProbe, []byte(ffprobeScript), 0o755); err != nil {
+				t.Fatalf("Failed to create mock ffprobe: %v", err)
+			}
+			t.Setenv("PATH", tmpDir+":"+os.Getenv("PATH"))
+
+			trans := New("/tmp/cache", "", true, "none")
+
+			info, err := trans.GetVideoInfo(context.Background(), "/fake/video.mp4")
+			if err != nil {
+				t.Fatalf("GetVideoInfo() error: %v", err)
+			}
+
+			if info.Duration != 0 {
+				t.Errorf("Expected unknown duration to be reported as 0, got %v", info.Duration)
+			}
+			if _, err := json.Marshal(info); err != nil {
+				t.Errorf("Expected video info to encode as JSON, got %v", err)
+			}
+			if info.Codec != "h264" || info.Width != 1920 {
+				t.Errorf("Expected other fields to be parsed, got codec=%s width=%d", info.Codec, info.Width)
+			}
+		})
+	}
+}
+
+func TestTranscodeWaitTimeout(t *testing.T) {
+	trans := New("/tmp/cache", "", true, "none")
+
+	tests := []struct {
+		name     string
+		duration float64
+		expected time.Duration
+	}{
+		{"short video uses minimum", 30, minTranscodeWait},
+		{"scales with duration", 600, 20 * time.Minute},
+		{"long video is capped", 3 * 3600, DefaultMaxTranscodeWait},
+		{"unknown duration gets full budget", 0, DefaultMaxTranscodeWait},
+		{"NaN duration gets full budget", math.NaN(), DefaultMaxTranscodeWait},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trans.transcodeWaitTimeout(tt.duration); got != tt.expected {
+				t.Errorf("transcodeWaitTimeout(%v) = %v, want %v", tt.duration, got, tt.expected)
+			}
+		})
+	}
+
+	trans.SetMaxTranscodeWait(time.Hour)
+	if got := trans.transcodeWaitTimeout(0); got != time.Hour {
+		t.Errorf("Expected configured maximum for unknown duration, got %v", got)
+	}
+
+	// Invalid values keep the current setting
+	trans.SetMaxTranscodeWait(0)
+	if got := trans.transcodeWaitTimeout(0); got != time.Hour {
+		t.Errorf("Expected zero to be ignored, got %v", got)
+	}
+}
+
+func TestBackgroundTranscodeContext(t *testing.T) {
+	trans := New("/tmp/cache", "", true, "none")
+
+	ctx, cancel := trans.backgroundTranscodeContext(&VideoInfo{Duration: 120})
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("Expected no deadline for a video with known duration")
+	}
+
+	ctx, cancel = trans.backgroundTranscodeContext(&VideoInfo{})
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatal("Expected a deadline for a video with unknown duration")
+	}
+	if remaining := time.Until(deadline); remaining > DefaultMaxTranscodeWait || remaining < DefaultMaxTranscodeWait-time.Minute {
+		t.Errorf("Expected deadline near %v, got %v", DefaultMaxTranscodeWait, remaining)
+	}
+}
+
+func TestGetVideoInfo_CachesUntilFileChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+	mockFFProbe := filepath.Join(tmpDir, "ffprobe")
+	countFile := filepath.Join(tmpDir, "probe-count")
+
+	// Record each invocation so we can tell when a cached result was used
+	ffprobeScript := `#!/bin/bash
+echo x >> ` + countFile + `
+echo '{"streams":[{"codec_name":"h264","width":1920,"height":1080}],"format":{"duration":"100.0"}}'
+`
+
+	if err := os.WriteFile(mockFFProbe, []byte(ffprobeScript), 0o755); err != nil {
+		t.Fatalf("Failed to create mock ffprobe: %v", err)
+	}
+
+	oldPath := os.Getenv("PATH")
+	defer func() {
+		_ = os.Setenv("PATH", oldPath)
+	}()
+	_ = os.Setenv("PATH", tmpDir+":"+oldPath)
+
+	videoFile := filepath.Join(tmpDir, "video.mp4")
+	if err := os.WriteFile(videoFile, []byte("fake video"), 0o644); err != nil {
+		t.Fatalf("Failed to create test video: %v", err)
+	}
+
+	probeCount := func() int {
+		data, err := os.ReadFile(countFile)
+		if err != nil {
+			return 0
+		}
+		return strings.Count(string(data), "x")
+	}
+
+	trans := New("/tmp/cache", "", true, "none")
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := trans.GetVideoInfo(ctx, videoFile); err != nil {
+			t.Fatalf("GetVideoInfo() error: %v", err)
+		}
+	}
+
+	if got := probeCount(); got != 1 {
+		t.Errorf("Expected 1 ffprobe call for unchanged file, got %d", got)
+	}
+
+	// Changing the file should invalidate the cached entry
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(videoFile, future, future); err != nil {
+		t.Fatalf("Failed to update mtime: %v", err)
+	}
+
+	if _, err := trans.GetVideoInfo(ctx, videoFile); err != nil {
+		t.Fatalf("GetVideoInfo() error: %v", err)
+	}
+
+	if got := probeCount(); got != 2 {
+		t.Errorf("Expected re-probe after file change, got %d calls", got)
+	}
+}
+
 // =============================================================================
 // StreamVideo Tests
 // =============================================================================
@@ -1799,3 +2087,127 @@ func TestGetOrStartTranscodeAndWait_SuccessfulTranscodeCleansErrorFile(t *testin
 		t.Error("Error file should be removed after successful transcode")
 	}
 }
+
+func TestParseWidthLadder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []int
+	}{
+		{"", nil},
+		{"720", []int{720}},
+		{"480,720,1080", []int{480, 720, 1080}},
+		{" 1080, 480 ,720,720, ", []int{480, 720, 1080}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseWidthLadder(tt.input)
+			if err != nil {
+				t.Fatalf("ParseWidthLadder(%q) returned error: %v", tt.input, err)
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("ParseWidthLadder(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+
+	for _, input := range []string{"abc", "720,-480", "0", "720p"} {
+		if _, err := ParseWidthLadder(input); err == nil {
+			t.Errorf("ParseWidthLadder(%q) expected error", input)
+		}
+	}
+}
+
+func TestParseX264Preset(t *testing.T) {
+	tests := map[string]string{
+		"":           DefaultX264Preset,
+		"ultrafast":  "ultrafast",
+		" VeryFast ": "veryfast",
+		"medium":     "medium",
+	}
+	for input, want := range tests {
+		got, err := ParseX264Preset(input)
+		if err != nil {
+			t.Errorf("ParseX264Preset(%q) returned error: %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseX264Preset(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"quick", "p4", "fast,slow"} {
+		if _, err := ParseX264Preset(input); err == nil {
+			t.Errorf("ParseX264Preset(%q) expected error", input)
+		}
+	}
+}
+
+func TestClampX264CRF(t *testing.T) {
+	tests := map[int]int{-5: 0, 0: 0, 23: 23, 51: 51, 70: 51}
+	for crf, want := range tests {
+		if got := ClampX264CRF(crf); got != want {
+			t.Errorf("ClampX264CRF(%d) = %d, want %d", crf, got, want)
+		}
+	}
+}
+
+func TestSnapWidth(t *testing.T) {
+	trans := New("/tmp/cache", "", true, "none")
+
+	if got := trans.snapWidth(700); got != 700 {
+		t.Errorf("Expected width unchanged without a ladder, got %d", got)
+	}
+
+	trans.SetWidthLadder([]int{1080, 480, 720})
+
+	tests := []struct {
+		width    int
+		expected int
+	}{
+		{0, 0},
+		{320, 480},
+		{480, 480},
+		{700, 720},
+		{720, 720},
+		{900, 1080},
+		{1920, 1920},
+	}
+	for _, tt := range tests {
+		if got := trans.snapWidth(tt.width); got != tt.expected {
+			t.Errorf("snapWidth(%d) = %d, want %d", tt.width, got, tt.expected)
+		}
+	}
+
+	trans.SetWidthLadder(nil)
+	if got := trans.snapWidth(700); got != 700 {
+		t.Errorf("Expected clearing the ladder to disable snapping, got %d", got)
+	}
+}
+
+func TestGetOrStartTranscodeSharesSnappedWidth(t *testing.T) {
+	cacheDir := t.TempDir()
+	sourcePath := filepath.Join(t.TempDir(), "video.mkv")
+	if err := os.WriteFile(sourcePath, []byte("source"), 0o644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	cachePath := filepath.Join(cacheDir, "video.mkv_w720.mp4")
+	if err := os.WriteFile(cachePath, []byte("transcoded"), 0o644); err != nil {
+		t.Fatalf("Failed to write cached transcode: %v", err)
+	}
+
+	trans := New(cacheDir, "", true, "none")
+	trans.SetWidthLadder([]int{480, 720, 1080})
+	info := &VideoInfo{Width: 1920, Height: 1080, Codec: "hevc", NeedsTranscode: true}
+
+	for _, width := range []int{700, 720} {
+		got, isCached, err := trans.GetOrStartTranscode(context.Background(), sourcePath, width, info)
+		if err != nil {
+			t.Fatalf("GetOrStartTranscode(%d) failed: %v", width, err)
+		}
+		if !isCached || got != cachePath {
+			t.Errorf("GetOrStartTranscode(%d) = %s (cached=%v), want shared %s", width, got, isCached, cachePath)
+		}
+	}
+}
@@ -214,6 +214,26 @@ func TestAddCPUEncoderArgs(t *testing.T) {
 	}
 }
 
+func TestAddCPUEncoderArgs_CustomSettings(t *testing.T) {
+	trans := New("/tmp/cache", "", true, "none")
+	trans.SetCPUEncoder("veryfast", 60)
+
+	info := &VideoInfo{Codec: "hevc", Width: 1920, Height: 1080}
+	args := strings.Join(trans.addCPUEncoderArgs(nil, 0, info, false), " ")
+	if !strings.Contains(args, "-preset veryfast -crf 51") {
+		t.Errorf("Expected configured preset and clamped CRF in args, got: %s", args)
+	}
+
+	// GPU encoders keep their own rate control
+	trans.gpuAvailable = true
+	trans.gpuEncoder = "h264_nvenc"
+	trans.gpuAccel = GPUAccelNVIDIA
+	args = strings.Join(trans.addGPUEncoderArgs(nil, 0, info, false), " ")
+	if strings.Contains(args, "veryfast") || strings.Contains(args, "-crf") {
+		t.Errorf("Expected GPU args to ignore CPU encoder settings, got: %s", args)
+	}
+}
+
 func TestAddGPUEncoderArgs_NVIDIA(t *testing.T) {
 	trans := New("/tmp/cache", "", true, "none")
 	// Simulate NVIDIA GPU available
@@ -452,6 +472,38 @@ func TestBuildFFmpegArgs_WithGPU(t *testing.T) {
 	}
 }
 
+func TestBuildFFmpegArgs_HDRToneMapping(t *testing.T) {
+	trans := New("/tmp/cache", "", true, "none")
+	trans.gpuAvailable = true
+	trans.gpuEncoder = "h264_nvenc"
+	trans.gpuAccel = GPUAccelNVIDIA
+	trans.SetHDRToneMapping(true)
+
+	// Browser-compatible codec that would otherwise be stream copied
+	info := &VideoInfo{Codec: "vp9", Width: 3840, Height: 2160, HDR: true, ColorTransfer: "smpte2084"}
+
+	args := trans.buildFFmpegArgs("/test/input.webm", "/test/output.mp4", 1280, info, false)
+	joined := strings.Join(args, " ")
+
+	if !strings.Contains(joined, "-c:v libx264") {
+		t.Errorf("Expected tone-mapping to use the CPU encoder, got: %s", joined)
+	}
+	if !strings.Contains(joined, "-vf "+toneMapFilter+",scale=1280:-2") {
+		t.Errorf("Expected tone-mapping before scaling, got: %s", joined)
+	}
+	if got := trans.getEncoderInfo(1280, info, false); got != " [CPU: libx264, HDR tone-mapped]" {
+		t.Errorf("getEncoderInfo() = %q", got)
+	}
+
+	// Disabled, HDR is handled like any other video
+	trans.SetHDRToneMapping(false)
+	args = trans.buildFFmpegArgs("/test/input.webm", "/test/output.mp4", 0, info, false)
+	joined = strings.Join(args, " ")
+	if !strings.Contains(joined, "-c:v copy") || strings.Contains(joined, "zscale") {
+		t.Errorf("Expected stream copy without tone-mapping, got: %s", joined)
+	}
+}
+
 func TestBuildFFmpegArgs_GPUNotAvailable(t *testing.T) {
 	trans := New("/tmp/cache", "", true, "none")
 	// GPU explicitly not available
@@ -3,12 +3,14 @@ package transcoder
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -137,6 +139,35 @@ func TestCompatibleContainers(t *testing.T) {
 	}
 }
 
+func TestHasCompatibleContainer(t *testing.T) {
+	tests := []struct {
+		filePath   string
+		compatible bool
+	}{
+		{"/media/clip.mp4", true},
+		{"/media/CLIP.MP4", true},
+		{"/media/clip.Mp4", true},
+		{"/media/clip.WebM", true},
+		{"/media/clip.MKV", false},
+		{"/media/clip", false},
+	}
+
+	for _, tt := range tests {
+		if got := hasCompatibleContainer(tt.filePath); got != tt.compatible {
+			t.Errorf("hasCompatibleContainer(%q) = %v, want %v", tt.filePath, got, tt.compatible)
+		}
+	}
+}
+
+func TestCompatibleFormats(t *testing.T) {
+	if got := strings.Join(CompatibleCodecs(), ","); got != "av1,h264,vp8,vp9" {
+		t.Errorf("CompatibleCodecs() = %s, want av1,h264,vp8,vp9", got)
+	}
+	if got := strings.Join(CompatibleContainers(), ","); got != "mp4,ogg,webm" {
+		t.Errorf("CompatibleContainers() = %s, want mp4,ogg,webm", got)
+	}
+}
+
 func TestTranscoderStreamConfig(t *testing.T) {
 	trans := New("/tmp/cache", "", true, "none")
 
@@ -1188,6 +1219,12 @@ func TestFinalizeCache(t *testing.T) {
 			setupTempFile: false,
 			expectSuccess: false,
 		},
+		{
+			name:          "Empty output",
+			setupTempFile: true,
+			tempSize:      0,
+			expectSuccess: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1366,6 +1403,90 @@ func TestHandleTranscodeFailure(t *testing.T) {
 	}
 }
 
+func TestHandleTranscodeFailureIgnoresGPUOutputWithoutExitError(t *testing.T) {
+	// A GPU encode mentions its encoder on stderr even when it succeeds
+	stderrText := "Stream #0:0 -> #0:0 (hevc (native) -> h264 (h264_nvenc))\n[mp4 @ 0x1] Non-monotonous DTS in output stream 0:0"
+
+	tests := []struct {
+		name      string
+		streamErr error
+		cmdErr    error
+		wantErr   bool
+	}{
+		{"stream failed, ffmpeg succeeded", errors.New("stream error"), nil, true},
+		{"client left", streaming.ErrClientGone, errors.New("signal: broken pipe"), false},
+		{"write timeout", fmt.Errorf("write: %w", streaming.ErrWriteTimeout), errors.New("exit status 255"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trans := New("/tmp/cache", "", true, "none")
+			trans.gpuAvailable = true
+
+			dir := t.TempDir()
+			tempPath := filepath.Join(dir, "test.mp4.tmp")
+			cacheFile, err := os.Create(tempPath)
+			if err != nil {
+				t.Fatalf("Failed to create temp file: %v", err)
+			}
+
+			stderr := bytes.NewBufferString(stderrText)
+			err = trans.handleTranscodeFailure(context.Background(), "/test/video.mp4", &bytes.Buffer{}, filepath.Join(dir, "test.mp4"),
+				1280, &VideoInfo{Width: 1920, Height: 1080}, true,
+				tt.streamErr, tt.cmdErr, stderr, cacheFile, tempPath)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handleTranscodeFailure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !trans.gpuAvailable {
+				t.Error("Expected GPU to remain available")
+			}
+		})
+	}
+}
+
+func TestHandleTranscodeErrorIgnoresStderr(t *testing.T) {
+	trans := New("/tmp/cache", "", true, "none")
+	stderr := "[swscaler @ 0x1] deprecated pixel format used, make sure you did set range correctly\nError while decoding stream #0:1"
+
+	if err := trans.handleTranscodeError(context.Background(), "/test/video.mp4", nil, nil, stderr); err != nil {
+		t.Errorf("Expected a successful exit to succeed regardless of stderr, got %v", err)
+	}
+	if err := trans.handleTranscodeError(context.Background(), "/test/video.mp4", nil, errors.New("exit status 1"), ""); err == nil {
+		t.Error("Expected a failed exit to fail without stderr")
+	}
+}
+
+func TestHandleTranscodeErrorDrainedStream(t *testing.T) {
+	trans := New("/tmp/cache", "", true, "none")
+	cmdErr := errors.New("signal: killed")
+
+	if err := trans.handleTranscodeError(context.Background(), "/test/video.mp4", streaming.ErrStreamCanceled, cmdErr, ""); err != nil {
+		t.Errorf("Expected a stream drained for shutdown not to be an error, got %v", err)
+	}
+	if isGPUFailure(streaming.ErrStreamCanceled, cmdErr) {
+		t.Error("Expected a drained stream not to count as a GPU failure")
+	}
+}
+
+func TestFFmpegError(t *testing.T) {
+	cmdErr := errors.New("exit status 1")
+	var err error = &ffmpegError{err: cmdErr, stderr: "No NVENC capable devices found"}
+
+	if !errors.Is(err, cmdErr) {
+		t.Error("Expected ffmpegError to wrap the exit error")
+	}
+	if !strings.Contains(err.Error(), "No NVENC capable devices found") {
+		t.Errorf("Expected stderr in the error message, got %q", err.Error())
+	}
+
+	// Only ffmpeg failures carry stderr to check for GPU errors
+	var ffErr *ffmpegError
+	if errors.As(fmt.Errorf("failed to rename cache file: %w", errors.New("/cache/cuda/x.mp4")), &ffErr) {
+		t.Error("Expected other errors not to be treated as ffmpeg failures")
+	}
+}
+
 // =============================================================================
 // sanitizeFilePath Tests
 // =============================================================================
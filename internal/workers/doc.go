@@ -72,6 +72,9 @@ This is useful for:
   - Debugging resource issues
   - Temporarily limiting concurrency
 
+LimitInitial applies the lower THUMBNAIL_INITIAL_WORKERS cap to the initial full
+thumbnail generation, when memory use peaks, without slowing later incremental runs.
+
 # Workload Types
 
 Different workloads benefit from different worker-to-CPU ratios:
@@ -180,6 +183,10 @@ With this configuration:
 	count := workers.ForMixed(8)
 	log.Printf("Starting %d workers (GOMAXPROCS=%d)", count, runtime.GOMAXPROCS(0))
 
+	// Explain also reports whether THUMBNAIL_WORKERS overrode the count
+	choice := workers.Explain(workers.MultiplierMixed, 8)
+	log.Printf("Starting %d workers (override=%v)", choice.Count, choice.Override)
+
 # Thread Safety
 
 All functions in this package are safe for concurrent use. They read from
@@ -193,5 +200,18 @@ to runtime.NumCPU(), and the container-awareness benefits are lost.
 
 For Go < 1.19, consider using the go.uber.org/automaxprocs package to achieve
 similar behavior.
+
+# Setting GOMAXPROCS From a CPU Limit
+
+The runtime rounds a fractional CPU limit up, so a 1.5 core limit runs two
+threads and gets throttled. ConfigureGOMAXPROCS, called once at startup, sets
+GOMAXPROCS from CPU_LIMIT, or from the cgroup CPU quota when
+CPU_LIMIT_CGROUP is true, rounded as CPU_LIMIT_ROUNDING says:
+
+	result := workers.ConfigureGOMAXPROCS()
+	log.Printf("GOMAXPROCS=%d from %s", result.GOMAXPROCS, result.Source)
+
+A GOMAXPROCS environment variable takes precedence, and without a limit the
+runtime's choice is kept.
 */
 package workers
@@ -0,0 +1,211 @@
+package workers
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"media-viewer/internal/logging"
+)
+
+// Rounding modes for fractional CPU limits
+const (
+	RoundNearest = "nearest"
+	RoundDown    = "down"
+	RoundUp      = "up"
+)
+
+// GOMAXPROCS configuration source constants
+const (
+	sourceGOMAXPROCS = "GOMAXPROCS"
+	sourceCPULimit   = "CPU_LIMIT"
+	sourceCgroupV2   = "cgroup-v2"
+	sourceCgroupV1   = "cgroup-v1"
+	sourceNone       = "none"
+)
+
+// cgroupRoot is where the cgroup filesystem is mounted. Tests point it at a
+// temporary directory.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// ProcsResult holds the result of GOMAXPROCS configuration
+type ProcsResult struct {
+	// Configured indicates whether GOMAXPROCS was set from a CPU limit
+	Configured bool
+
+	// Source indicates where the CPU limit came from
+	Source string // sourceGOMAXPROCS, sourceCPULimit, sourceCgroupV2, sourceCgroupV1, or sourceNone
+
+	// CPULimit is the CPU limit in cores (0 if not known)
+	CPULimit float64
+
+	// Rounding is the rounding mode applied to a fractional CPULimit
+	Rounding string
+
+	// GOMAXPROCS is the resulting value
+	GOMAXPROCS int
+}
+
+// ConfigureGOMAXPROCS sets GOMAXPROCS from a CPU limit, rounding fractional
+// limits the same way whichever Go version or cgroup setup is in use. Worker
+// counts follow, since they are derived from GOMAXPROCS.
+// Call this early in main(), before worker pools are sized.
+//
+// Environment variables:
+//   - GOMAXPROCS: If set, this takes precedence (standard Go env var)
+//   - CPU_LIMIT: CPU limit in cores ("1.5") or millicores ("1500m")
+//   - CPU_LIMIT_CGROUP: When true and CPU_LIMIT is unset, read the limit from
+//     the cgroup CPU quota
+//   - CPU_LIMIT_ROUNDING: nearest (default), down, or up
+//
+// Without either limit, GOMAXPROCS is left at the Go runtime's default.
+func ConfigureGOMAXPROCS() ProcsResult {
+	result := ProcsResult{Source: sourceNone}
+
+	if env := os.Getenv("GOMAXPROCS"); env != "" {
+		result.Source = sourceGOMAXPROCS
+		result.GOMAXPROCS = runtime.GOMAXPROCS(0)
+		logging.Info("GOMAXPROCS set via environment: %s", env)
+		return result
+	}
+
+	var limit float64
+	if limitStr := os.Getenv("CPU_LIMIT"); limitStr != "" {
+		parsed, err := parseCPULimit(limitStr)
+		if err != nil {
+			logging.Warn("Failed to parse CPU_LIMIT %q: %v", limitStr, err)
+			result.GOMAXPROCS = runtime.GOMAXPROCS(0)
+			return result
+		}
+		limit = parsed
+		result.Source = sourceCPULimit
+	} else if enabled, _ := strconv.ParseBool(os.Getenv("CPU_LIMIT_CGROUP")); enabled {
+		limit, result.Source = cgroupCPULimit()
+	}
+
+	if limit == 0 {
+		logging.Debug("No CPU limit configured, leaving GOMAXPROCS at %d", runtime.GOMAXPROCS(0))
+		result.GOMAXPROCS = runtime.GOMAXPROCS(0)
+		return result
+	}
+
+	rounding := strings.ToLower(strings.TrimSpace(os.Getenv("CPU_LIMIT_ROUNDING")))
+	switch rounding {
+	case "":
+		rounding = RoundNearest
+	case RoundNearest, RoundDown, RoundUp:
+	default:
+		logging.Warn("Invalid CPU_LIMIT_ROUNDING %q, using %s", rounding, RoundNearest)
+		rounding = RoundNearest
+	}
+
+	procs := procsForLimit(limit, rounding, runtime.NumCPU())
+	runtime.GOMAXPROCS(procs)
+
+	result.Configured = true
+	result.CPULimit = limit
+	result.Rounding = rounding
+	result.GOMAXPROCS = procs
+
+	logging.Info("Configured GOMAXPROCS: %d (%s CPU limit of %g, rounded %s)", procs, result.Source, limit, rounding)
+	return result
+}
+
+// procsForLimit rounds a CPU limit to a GOMAXPROCS value between 1 and
+// numCPU.
+func procsForLimit(limit float64, rounding string, numCPU int) int {
+	var procs float64
+	switch rounding {
+	case RoundDown:
+		procs = math.Floor(limit)
+	case RoundUp:
+		procs = math.Ceil(limit)
+	default:
+		procs = math.Round(limit)
+	}
+
+	if procs < 1 {
+		return 1
+	}
+	if procs > float64(numCPU) {
+		return numCPU
+	}
+	return int(procs)
+}
+
+// parseCPULimit parses a CPU limit in cores, or in millicores with an "m"
+// suffix as Kubernetes writes them.
+func parseCPULimit(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+
+	var limit float64
+	if millis, ok := strings.CutSuffix(value, "m"); ok {
+		n, err := strconv.ParseFloat(millis, 64)
+		if err != nil {
+			return 0, err
+		}
+		limit = n / 1000
+	} else {
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, err
+		}
+		limit = n
+	}
+
+	if limit <= 0 || math.IsInf(limit, 0) || math.IsNaN(limit) {
+		return 0, fmt.Errorf("must be a positive number of cores")
+	}
+	return limit, nil
+}
+
+// cgroupCPULimit returns the CPU quota of the cgroup this process runs in, in
+// cores, and the cgroup version it was read from. It returns 0 when no quota
+// can be read or the cgroup is unbounded.
+func cgroupCPULimit() (float64, string) {
+	// cgroup v2: "<quota> <period>", or "max <period>" when unbounded
+	if data, err := os.ReadFile(filepath.Join(cgroupRoot, "cpu.max")); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) != 2 || fields[0] == "max" {
+			logging.Debug("%s CPU limit is unbounded", sourceCgroupV2)
+			return 0, sourceNone
+		}
+		return cpuQuota(sourceCgroupV2, fields[0], fields[1])
+	}
+
+	// cgroup v1: quota and period in separate files, quota -1 when unbounded
+	quota, err := os.ReadFile(filepath.Join(cgroupRoot, "cpu", "cpu.cfs_quota_us"))
+	if err != nil {
+		logging.Debug("No cgroup CPU limit found")
+		return 0, sourceNone
+	}
+	period, err := os.ReadFile(filepath.Join(cgroupRoot, "cpu", "cpu.cfs_period_us"))
+	if err != nil {
+		logging.Debug("No cgroup CPU period found")
+		return 0, sourceNone
+	}
+	return cpuQuota(sourceCgroupV1, strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+// cpuQuota converts a cgroup quota and period, in microseconds, to cores.
+func cpuQuota(source, quotaStr, periodStr string) (float64, string) {
+	quota, err := strconv.ParseInt(quotaStr, 10, 64)
+	if err != nil {
+		logging.Warn("Failed to parse %s CPU quota %q: %v", source, quotaStr, err)
+		return 0, sourceNone
+	}
+	period, err := strconv.ParseInt(periodStr, 10, 64)
+	if err != nil || period <= 0 {
+		logging.Warn("Invalid %s CPU period %q", source, periodStr)
+		return 0, sourceNone
+	}
+	if quota <= 0 {
+		logging.Debug("%s CPU limit is unbounded", source)
+		return 0, sourceNone
+	}
+	return float64(quota) / float64(period), source
+}
@@ -6,6 +6,21 @@ import (
 	"strconv"
 )
 
+// Worker-to-CPU multipliers for each type of task
+const (
+	MultiplierCPU   = 1.0
+	MultiplierIO    = 2.0
+	MultiplierMixed = 1.5
+)
+
+// Choice describes a worker count and what it was computed from.
+type Choice struct {
+	Count      int     `json:"count"`
+	Multiplier float64 `json:"multiplier"`
+	Limit      int     `json:"limit"`    // 0 = no limit
+	Override   bool    `json:"override"` // Count was set by THUMBNAIL_WORKERS
+}
+
 // Count returns the optimal number of workers for a given task type.
 // It respects container CPU limits via GOMAXPROCS (Go 1.19+).
 //
@@ -19,13 +34,23 @@ import (
 //
 // Can be overridden with THUMBNAIL_WORKERS environment variable.
 func Count(multiplier float64, limit int) int {
+	return Explain(multiplier, limit).Count
+}
+
+// Explain returns the worker count Count would, along with the inputs it was
+// chosen from, for logging and diagnostics.
+func Explain(multiplier float64, limit int) Choice {
+	choice := Choice{Multiplier: multiplier, Limit: limit}
+
 	// Check for manual override first
 	if override := os.Getenv("THUMBNAIL_WORKERS"); override != "" {
 		if count, err := strconv.Atoi(override); err == nil && count > 0 {
+			choice.Override = true
+			choice.Count = count
 			if limit > 0 && count > limit {
-				return limit
+				choice.Count = limit
 			}
-			return count
+			return choice
 		}
 	}
 
@@ -41,23 +66,37 @@ func Count(multiplier float64, limit int) int {
 		workers = limit
 	}
 
-	return workers
+	choice.Count = workers
+	return choice
 }
 
 // ForCPU returns worker count for CPU-bound tasks (1 per CPU).
 // The limit parameter caps the maximum number of workers.
 func ForCPU(limit int) int {
-	return Count(1.0, limit)
+	return Count(MultiplierCPU, limit)
 }
 
 // ForIO returns worker count for I/O-bound tasks (2 per CPU).
 // The limit parameter caps the maximum number of workers.
 func ForIO(limit int) int {
-	return Count(2.0, limit)
+	return Count(MultiplierIO, limit)
 }
 
 // ForMixed returns worker count for mixed tasks (1.5 per CPU).
 // The limit parameter caps the maximum number of workers.
 func ForMixed(limit int) int {
-	return Count(1.5, limit)
+	return Count(MultiplierMixed, limit)
+}
+
+// LimitInitial caps a worker count for the initial full thumbnail generation,
+// the run that builds the cache from scratch and uses the most memory.
+// The cap is read from THUMBNAIL_INITIAL_WORKERS; if unset or invalid, count
+// is returned unchanged.
+func LimitInitial(count int) int {
+	if override := os.Getenv("THUMBNAIL_INITIAL_WORKERS"); override != "" {
+		if limit, err := strconv.Atoi(override); err == nil && limit > 0 && count > limit {
+			return limit
+		}
+	}
+	return count
 }
@@ -2,6 +2,7 @@ package workers
 
 import (
 	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 )
@@ -345,3 +346,212 @@ func maxInt(a, b int) int {
 	}
 	return b
 }
+
+func TestLimitInitial(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		count    int
+		expected int
+	}{
+		{"unset", "", 6, 6},
+		{"caps higher count", "2", 6, 2},
+		{"keeps lower count", "8", 6, 6},
+		{"ignores invalid", "abc", 6, 6},
+		{"ignores zero", "0", 6, 6},
+		{"ignores negative", "-1", 6, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("THUMBNAIL_INITIAL_WORKERS", tt.env)
+			if got := LimitInitial(tt.count); got != tt.expected {
+				t.Errorf("LimitInitial(%d) with %q = %d, want %d", tt.count, tt.env, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	t.Setenv("THUMBNAIL_WORKERS", "")
+	choice := Explain(MultiplierMixed, 6)
+	if choice.Override || choice.Multiplier != MultiplierMixed || choice.Limit != 6 {
+		t.Errorf("Unexpected inputs in %+v", choice)
+	}
+	if choice.Count != ForMixed(6) {
+		t.Errorf("Explain count = %d, want ForMixed's %d", choice.Count, ForMixed(6))
+	}
+
+	t.Setenv("THUMBNAIL_WORKERS", "10")
+	choice = Explain(MultiplierMixed, 6)
+	if !choice.Override || choice.Count != 6 {
+		t.Errorf("Expected the override capped at 6, got %+v", choice)
+	}
+}
+
+func TestProcsForLimit(t *testing.T) {
+	tests := []struct {
+		limit    float64
+		rounding string
+		numCPU   int
+		expected int
+	}{
+		{1.5, RoundNearest, 8, 2},
+		{1.4, RoundNearest, 8, 1},
+		{1.5, RoundDown, 8, 1},
+		{1.1, RoundUp, 8, 2},
+		{0.25, RoundNearest, 8, 1},
+		{0.25, RoundDown, 8, 1},
+		{16, RoundNearest, 8, 8},
+		{3, RoundUp, 8, 3},
+	}
+
+	for _, tt := range tests {
+		if got := procsForLimit(tt.limit, tt.rounding, tt.numCPU); got != tt.expected {
+			t.Errorf("procsForLimit(%g, %s, %d) = %d, want %d", tt.limit, tt.rounding, tt.numCPU, got, tt.expected)
+		}
+	}
+}
+
+func TestParseCPULimit(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected float64
+		wantErr  bool
+	}{
+		{"2", 2, false},
+		{"1.5", 1.5, false},
+		{"1500m", 1.5, false},
+		{" 250m ", 0.25, false},
+		{"0", 0, true},
+		{"-1", 0, true},
+		{"lots", 0, true},
+		{"m", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseCPULimit(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.expected {
+			t.Errorf("parseCPULimit(%q) = %g, %v; want %g (error: %v)", tt.value, got, err, tt.expected, tt.wantErr)
+		}
+	}
+}
+
+func TestConfigureGOMAXPROCS(t *testing.T) {
+	original := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(original)
+
+	oldRoot := cgroupRoot
+	defer func() { cgroupRoot = oldRoot }()
+
+	writeFiles := func(t *testing.T, files map[string]string) string {
+		t.Helper()
+		dir := t.TempDir()
+		for name, content := range files {
+			path := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+
+	// Limits above one CPU can't be checked on a single-CPU machine
+	minCPU := func(t *testing.T, n int) {
+		if runtime.NumCPU() < n {
+			t.Skipf("needs at least %d CPUs", n)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		env        map[string]string
+		files      map[string]string
+		needCPUs   int
+		configured bool
+		source     string
+		procs      int
+	}{
+		{
+			name:       "CPU_LIMIT",
+			env:        map[string]string{"CPU_LIMIT": "1500m"},
+			needCPUs:   2,
+			configured: true,
+			source:     "CPU_LIMIT",
+			procs:      2,
+		},
+		{
+			name:       "CPU_LIMIT rounded down",
+			env:        map[string]string{"CPU_LIMIT": "2.5", "CPU_LIMIT_ROUNDING": "down"},
+			needCPUs:   2,
+			configured: true,
+			source:     "CPU_LIMIT",
+			procs:      2,
+		},
+		{
+			name:   "invalid CPU_LIMIT",
+			env:    map[string]string{"CPU_LIMIT": "lots", "CPU_LIMIT_CGROUP": "true"},
+			files:  map[string]string{"cpu.max": "100000 100000"},
+			source: "none",
+		},
+		{
+			name:   "cgroup not enabled",
+			files:  map[string]string{"cpu.max": "100000 100000"},
+			source: "none",
+		},
+		{
+			name:       "cgroup v2",
+			env:        map[string]string{"CPU_LIMIT_CGROUP": "true"},
+			files:      map[string]string{"cpu.max": "150000 100000\n"},
+			needCPUs:   2,
+			configured: true,
+			source:     "cgroup-v2",
+			procs:      2,
+		},
+		{
+			name:   "cgroup v2 unbounded",
+			env:    map[string]string{"CPU_LIMIT_CGROUP": "true"},
+			files:  map[string]string{"cpu.max": "max 100000\n"},
+			source: "none",
+		},
+		{
+			name:       "cgroup v1",
+			env:        map[string]string{"CPU_LIMIT_CGROUP": "true", "CPU_LIMIT_ROUNDING": "up"},
+			files:      map[string]string{"cpu/cpu.cfs_quota_us": "50000\n", "cpu/cpu.cfs_period_us": "100000\n"},
+			configured: true,
+			source:     "cgroup-v1",
+			procs:      1,
+		},
+		{
+			name:   "cgroup v1 unbounded",
+			env:    map[string]string{"CPU_LIMIT_CGROUP": "true"},
+			files:  map[string]string{"cpu/cpu.cfs_quota_us": "-1\n", "cpu/cpu.cfs_period_us": "100000\n"},
+			source: "none",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minCPU(t, tt.needCPUs)
+			for _, key := range []string{"GOMAXPROCS", "CPU_LIMIT", "CPU_LIMIT_CGROUP", "CPU_LIMIT_ROUNDING"} {
+				t.Setenv(key, tt.env[key])
+			}
+			cgroupRoot = writeFiles(t, tt.files)
+			runtime.GOMAXPROCS(original)
+
+			result := ConfigureGOMAXPROCS()
+			if result.Configured != tt.configured || result.Source != tt.source {
+				t.Errorf("Expected configured=%v source=%q, got %+v", tt.configured, tt.source, result)
+			}
+			if tt.configured && (result.GOMAXPROCS != tt.procs || runtime.GOMAXPROCS(0) != tt.procs) {
+				t.Errorf("Expected GOMAXPROCS %d, got %d (runtime %d)", tt.procs, result.GOMAXPROCS, runtime.GOMAXPROCS(0))
+			}
+			if !tt.configured && runtime.GOMAXPROCS(0) != original {
+				t.Errorf("Expected GOMAXPROCS to stay %d, got %d", original, runtime.GOMAXPROCS(0))
+			}
+		})
+	}
+}
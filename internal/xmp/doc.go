@@ -0,0 +1,28 @@
+// Package xmp reads the culling metadata photo workflow applications such as
+// Lightroom and darktable write to XMP sidecar files: star ratings, rejects
+// and color labels.
+//
+// Like package exif it reads only what the indexer uses, with the standard
+// library's XML decoder, so the indexer doesn't need a general XMP library.
+//
+// # Sidecar Names
+//
+// A sidecar sits next to its image. darktable appends .xmp to the whole file
+// name (IMG_0001.CR2.xmp), while Lightroom replaces the extension
+// (IMG_0001.xmp); [Find] looks for both.
+//
+// # Properties
+//
+//   - xmp:Rating: 1 to 5 stars, 0 for unrated, and -1 for a reject
+//   - xmp:Label: Lightroom's color label, by name
+//   - darktable:colorlabels: darktable's color labels, numbered 0 (red) to
+//     4 (purple)
+//
+// # Usage
+//
+//	sidecar, err := xmp.Read(imagePath)
+//	if errors.Is(err, xmp.ErrNoSidecar) {
+//	    // The image has no sidecar
+//	}
+//	stars := sidecar.Rating // 0 if unrated
+package xmp
@@ -0,0 +1,176 @@
+package xmp
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// XMP namespaces of the properties read
+const (
+	nsXMP       = "http://ns.adobe.com/xap/1.0/"
+	nsDarktable = "http://darktable.sf.net/"
+	nsRDF       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+)
+
+// maxSidecarSize bounds how much of a sidecar is read. Sidecars are a few
+// KB; darktable's, which include the edit history, rarely reach 100 KB.
+const maxSidecarSize = 4 << 20
+
+// darktableColors names darktable's color labels, which it records by number
+var darktableColors = []string{"Red", "Yellow", "Green", "Blue", "Purple"}
+
+// ErrNoSidecar is returned by Read for images without a sidecar
+var ErrNoSidecar = errors.New("no XMP sidecar")
+
+// Sidecar is the culling metadata read from an XMP sidecar
+type Sidecar struct {
+	// Rating is the star rating, 1 to 5, or 0 if the image isn't rated
+	Rating int
+
+	// Rejected is set for images rated -1, which Lightroom and darktable
+	// use to mark rejects
+	Rejected bool
+
+	// Labels are the color labels, such as "Red". Lightroom records at
+	// most one, by name; darktable may record several.
+	Labels []string
+}
+
+// Find returns the path of the sidecar of the image at path, if it has one.
+// darktable names sidecars after the whole file name (IMG_0001.CR2.xmp) and
+// Lightroom after the name without its extension (IMG_0001.xmp); the first
+// found is used.
+func Find(path string) (string, bool) {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, candidate := range []string{path + ".xmp", path + ".XMP", base + ".xmp", base + ".XMP"} {
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// Read reads the sidecar of the image at path. Returns ErrNoSidecar if it
+// doesn't have one.
+func Read(path string) (*Sidecar, error) {
+	sidecarPath, ok := Find(path)
+	if !ok {
+		return nil, ErrNoSidecar
+	}
+
+	f, err := os.Open(sidecarPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Decode(io.LimitReader(f, maxSidecarSize))
+}
+
+// Decode reads the rating and labels from an XMP packet. Properties may be
+// written as attributes of an rdf:Description or as elements inside it, as
+// different applications do; ones it doesn't record are left empty.
+func Decode(r io.Reader) (*Sidecar, error) {
+	s := &Sidecar{}
+	dec := xml.NewDecoder(r)
+
+	// The property whose element is open, and the text read inside it
+	var open xml.Name
+	var text strings.Builder
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return s, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed XMP: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			for _, attr := range t.Attr {
+				s.set(attr.Name, attr.Value)
+			}
+			switch {
+			case t.Name.Space == nsRDF && t.Name.Local == "li":
+				text.Reset()
+			case isProperty(t.Name):
+				open = t.Name
+				text.Reset()
+			}
+		case xml.CharData:
+			if open.Local != "" {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch {
+			case open.Local == "":
+			case t.Name.Space == nsRDF && t.Name.Local == "li":
+				// An item of a list-valued property, such as darktable's labels
+				s.set(open, text.String())
+				text.Reset()
+			case t.Name == open:
+				s.set(open, text.String())
+				open = xml.Name{}
+			}
+		}
+	}
+}
+
+// isProperty reports whether name is one of the properties Decode reads
+func isProperty(name xml.Name) bool {
+	switch name.Space {
+	case nsXMP:
+		return name.Local == "Rating" || name.Local == "Label"
+	case nsDarktable:
+		return name.Local == "colorlabels"
+	default:
+		return false
+	}
+}
+
+// set records a property's value. Values of other properties, and ones that
+// can't be read, are ignored.
+func (s *Sidecar) set(name xml.Name, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" || !isProperty(name) {
+		return
+	}
+
+	switch name.Local {
+	case "Rating":
+		rating, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return
+		}
+		switch {
+		case rating < 0:
+			s.Rejected = true
+		case rating >= 1 && rating <= 5:
+			s.Rating = int(rating)
+		}
+	case "Label":
+		s.addLabel(value)
+	case "colorlabels":
+		if i, err := strconv.Atoi(value); err == nil && i >= 0 && i < len(darktableColors) {
+			s.addLabel(darktableColors[i])
+		}
+	}
+}
+
+// addLabel adds a label, unless the sidecar already has it
+func (s *Sidecar) addLabel(label string) {
+	for _, existing := range s.Labels {
+		if strings.EqualFold(existing, label) {
+			return
+		}
+	}
+	s.Labels = append(s.Labels, label)
+}
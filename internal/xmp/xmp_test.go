@@ -0,0 +1,127 @@
+package xmp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lightroomSidecar is how Lightroom Classic writes a rating and label
+const lightroomSidecar = `<x:xmpmeta xmlns:x="adobe:ns:meta/" x:xmptk="Adobe XMP Core 7.0-c000">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:crs="http://ns.adobe.com/camera-raw-settings/1.0/"
+   xmp:Rating="4"
+   xmp:Label="Red"
+   crs:Exposure2012="+0.35"/>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+// darktableSidecar is how darktable writes a reject and two color labels,
+// with its edit history left out
+const darktableSidecar = `<?xml version="1.0" encoding="UTF-8"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/" x:xmptk="XMP Core 4.4.0-Exiv2">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:darktable="http://darktable.sf.net/"
+   xmp:Rating="-1"
+   darktable:xmp_version="5">
+   <darktable:colorlabels>
+    <rdf:Seq>
+     <rdf:li>0</rdf:li>
+     <rdf:li>3</rdf:li>
+    </rdf:Seq>
+   </darktable:colorlabels>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		xmp  string
+		want Sidecar
+	}{
+		{"lightroom", lightroomSidecar, Sidecar{Rating: 4, Labels: []string{"Red"}}},
+		{"darktable", darktableSidecar, Sidecar{Rejected: true, Labels: []string{"Red", "Blue"}}},
+		{
+			"elements",
+			`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:xmp="http://ns.adobe.com/xap/1.0/">
+			  <rdf:Description><xmp:Rating> 2 </xmp:Rating><xmp:Label>To Print</xmp:Label></rdf:Description>
+			</rdf:RDF>`,
+			Sidecar{Rating: 2, Labels: []string{"To Print"}},
+		},
+		{
+			"unrated",
+			`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:xmp="http://ns.adobe.com/xap/1.0/">
+			  <rdf:Description xmp:Rating="0" xmp:CreatorTool="darktable"/>
+			</rdf:RDF>`,
+			Sidecar{},
+		},
+		{
+			"other namespaces ignored",
+			`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:other="urn:other">
+			  <rdf:Description other:Rating="5" other:Label="Green"/>
+			</rdf:RDF>`,
+			Sidecar{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(strings.NewReader(tt.xmp))
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Decode = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	if _, err := Decode(strings.NewReader(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF`)); err == nil {
+		t.Error("Expected an error for truncated XML")
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	write("darktable.CR2.xmp", darktableSidecar)
+	write("lightroom.xmp", lightroomSidecar)
+	// The darktable name wins when both exist
+	write("both.NEF.xmp", darktableSidecar)
+	write("both.xmp", lightroomSidecar)
+
+	tests := map[string]bool{
+		"darktable.CR2": true,
+		"lightroom.NEF": false,
+		"both.NEF":      true,
+	}
+	for image, rejected := range tests {
+		sidecar, err := Read(filepath.Join(dir, image))
+		if err != nil {
+			t.Errorf("Read(%s) failed: %v", image, err)
+			continue
+		}
+		if sidecar.Rejected != rejected {
+			t.Errorf("Read(%s) read the wrong sidecar: %+v", image, sidecar)
+		}
+	}
+
+	if _, err := Read(filepath.Join(dir, "plain.jpg")); !errors.Is(err, ErrNoSidecar) {
+		t.Errorf("Expected ErrNoSidecar, got %v", err)
+	}
+}
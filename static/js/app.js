@@ -239,7 +239,7 @@ const MediaApp = {
 
             const data = await response.json();
 
-            if (!data.authenticated) {
+            if (!data.authenticated && !data.publicMode) {
                 console.debug('MediaApp: auth invalid on resume, redirecting');
                 window.location.replace('/login.html');
             }
@@ -262,7 +262,8 @@ const MediaApp = {
 
             const data = await response.json();
 
-            if (!data.authenticated) {
+            // Public mode allows browsing without a session
+            if (!data.authenticated && !data.publicMode) {
                 window.location.replace('/login.html');
                 return;
             }
@@ -197,7 +197,7 @@ const Gallery = {
             };
 
             // Load thumbnail with fetch for proper timeout control
-            const thumbnailUrl = item.thumbnailUrl || `/api/thumbnail/${item.path}`;
+            const thumbnailUrl = this.thumbnailSrc(item);
             const timeoutId = setTimeout(() => {
                 controller.abort();
                 handleFailure();
@@ -652,11 +652,21 @@ const Gallery = {
         return this.icons[type] || this.icons.other;
     },
 
+    // Thumbnail URL for an item; high-DPI displays ask for a sharper variant
+    thumbnailSrc(item) {
+        const url = item.thumbnailUrl || `/api/thumbnail/${item.path}`;
+        const dpr = Math.min(Math.ceil(window.devicePixelRatio || 1), 3);
+        if (dpr <= 1 || item.type === 'folder') {
+            return url;
+        }
+        return url + (url.includes('?') ? '&' : '?') + `dpr=${dpr}`;
+    },
+
     downloadItem(item) {
         if (!item || item.type === 'folder' || item.type === 'playlist') return;
 
         const link = document.createElement('a');
-        link.href = `/api/file/${item.path}?download=true`;
+        link.href = `/api/file/${item.path}?download=true${item.sensitive === true ? '&reveal=true' : ''}`;
         link.download = item.name;
         document.body.appendChild(link);
         link.click();
@@ -1019,7 +1029,7 @@ const Gallery = {
             };
 
             // Load thumbnail with fetch for proper timeout control
-            const originalSrc = item.thumbnailUrl || `/api/thumbnail/${item.path}`;
+            const originalSrc = this.thumbnailSrc(item);
             const cacheBuster = `t=${Date.now()}`;
             const retryUrl = originalSrc + (originalSrc.includes('?') ? '&' : '?') + cacheBuster;
 
@@ -57,6 +57,7 @@ const Lightbox = {
         this.cacheElements();
         this.createHotZones();
         this.createLoadingIndicator();
+        this.createRevealButton();
         this.createAutoplayToggle();
         this.createLoopToggle();
         this.createTagsOverlay();
@@ -474,6 +475,25 @@ const Lightbox = {
         this.elements.loader = loader;
     },
 
+    /**
+     * Create the button that reveals a file flagged as sensitive, which is
+     * shown obscured until then
+     */
+    createRevealButton() {
+        const button = document.createElement('button');
+        button.className = 'lightbox-reveal hidden';
+        button.textContent = 'Show sensitive content';
+        button.addEventListener('click', (e) => {
+            e.stopPropagation();
+            const file = this.items[this.currentIndex];
+            if (!file) return;
+            file.revealed = true;
+            this.showMedia();
+        });
+        this.elements.content.appendChild(button);
+        this.elements.revealBtn = button;
+    },
+
     createTagsOverlay() {
         const overlay = document.createElement('div');
         overlay.className = 'lightbox-tags-overlay hidden';
@@ -1247,8 +1267,9 @@ const Lightbox = {
 
         this.elements.image.classList.add('hidden');
         this.elements.video.classList.add('hidden');
+        this.elements.revealBtn.classList.add('hidden');
 
-        const isVideo = file.type === 'video';
+        const isVideo = file.type === 'video' && !this.isObscured(file);
         const showLoopButton = this.shouldShowLoopButton(file);
 
         // Clean up video player when switching to image
@@ -1277,7 +1298,9 @@ const Lightbox = {
             this.updateLoopButton();
         }
 
-        if (file.type === 'image') {
+        if (this.isObscured(file)) {
+            this.showObscured(file);
+        } else if (file.type === 'image') {
             this.loadImage(file, loadId);
         } else if (file.type === 'video') {
             this.loadVideo(file, loadId);
@@ -1291,6 +1314,37 @@ const Lightbox = {
         }
     },
 
+    /**
+     * URL of a file itself, or of its video stream with endpoint /api/stream.
+     * Files flagged as sensitive are only served with ?reveal=true, so it's
+     * added for them.
+     */
+    fileUrl(file, query = '', endpoint = '/api/file') {
+        const params = new URLSearchParams(query);
+        if (file.sensitive === true) {
+            params.set('reveal', 'true');
+        }
+        const search = params.toString();
+        return search ? `${endpoint}/${file.path}?${search}` : `${endpoint}/${file.path}`;
+    },
+
+    /**
+     * Whether a file is flagged as sensitive and not yet revealed
+     */
+    isObscured(file) {
+        return file.sensitive === true && !file.revealed;
+    },
+
+    /**
+     * Show the obscured thumbnail of a sensitive file with a button to reveal it
+     */
+    showObscured(file) {
+        this.hideLoading();
+        this.elements.image.src = `/api/thumbnail/${file.path}`;
+        this.elements.image.classList.remove('hidden');
+        this.elements.revealBtn.classList.remove('hidden');
+    },
+
     /**
      * Get tags from gallery item if available
      */
@@ -1464,7 +1518,7 @@ const Lightbox = {
     },
 
     loadImage(file, loadId) {
-        const imageUrl = `/api/file/${file.path}`;
+        const imageUrl = this.fileUrl(file);
 
         if (this.preloadCache.has(imageUrl)) {
             const cachedImg = this.preloadCache.get(imageUrl);
@@ -1618,7 +1672,7 @@ const Lightbox = {
             }
 
             // Clear the preload cache for this image to force reload
-            const imageUrl = `/api/file/${file.path}`;
+            const imageUrl = this.fileUrl(file);
             this.preloadCache.delete(imageUrl);
 
             // Clear the failed image tracking (will be set again if retry fails)
@@ -1683,7 +1737,7 @@ const Lightbox = {
         this.showLoading();
 
         const video = this.elements.video;
-        const videoUrl = `/api/stream/${file.path}`;
+        const videoUrl = this.fileUrl(file, '', '/api/stream');
 
         // Apply loop setting BEFORE loading
         video.loop = Preferences.isMediaLoopEnabled();
@@ -1850,8 +1904,8 @@ const Lightbox = {
             const item = this.items[entry.index];
             if (!item) return;
 
-            // Preload image
-            if (item.type === 'image') {
+            // Preload image, unless it's shown obscured
+            if (item.type === 'image' && !this.isObscured(item)) {
                 const priority = index < 2 ? 'high' : 'low';
                 this.preloadImage(item, priority);
             }
@@ -1906,7 +1960,7 @@ const Lightbox = {
     },
 
     preloadImage(file, _ = 'low') {
-        const imageUrl = `/api/file/${file.path}`;
+        const imageUrl = this.fileUrl(file);
 
         if (this.preloadCache.has(imageUrl)) {
             return;
@@ -1966,7 +2020,7 @@ const Lightbox = {
 
         const currentItem = this.items[this.currentIndex];
         if (currentItem) {
-            keepUrls.add(`/api/file/${currentItem.path}`);
+            keepUrls.add(this.fileUrl(currentItem));
         }
 
         for (let i = 1; i <= this.maxPreload; i++) {
@@ -1976,8 +2030,8 @@ const Lightbox = {
             const nextItem = this.items[nextIndex];
             const prevItem = this.items[prevIndex];
 
-            if (nextItem) keepUrls.add(`/api/file/${nextItem.path}`);
-            if (prevItem) keepUrls.add(`/api/file/${prevItem.path}`);
+            if (nextItem) keepUrls.add(this.fileUrl(nextItem));
+            if (prevItem) keepUrls.add(this.fileUrl(prevItem));
         }
 
         for (const url of this.preloadCache.keys()) {
@@ -2064,7 +2118,7 @@ const Lightbox = {
         if (!file || file.type === 'folder') return;
 
         const link = document.createElement('a');
-        link.href = `/api/file/${file.path}?download=true`;
+        link.href = this.fileUrl(file, 'download=true');
         link.download = file.name;
         document.body.appendChild(link);
         link.click();
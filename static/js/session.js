@@ -25,6 +25,7 @@ const SessionManager = {
         initialized: false,
         consecutiveKeepaliveFailures: 0,
         serverOfflineWarningShown: false,
+        publicBrowsing: null, // true when browsing in public mode without a session
     },
 
     /**
@@ -210,6 +211,12 @@ const SessionManager = {
             if (!response.ok) {
                 this.log('Keepalive failed - response not ok:', response.status);
                 if (response.status === 401) {
+                    // Anonymous visitors in public mode have no session to keep alive
+                    if (await this.isPublicBrowsing()) {
+                        this.log('Public mode without session - stopping keepalive');
+                        this.stop();
+                        return;
+                    }
                     this.handleSessionExpired();
                 }
                 return;
@@ -287,6 +294,7 @@ const SessionManager = {
 
             if (response.ok) {
                 const data = await response.json();
+                this.state.publicBrowsing = !!data.publicMode && !data.authenticated;
                 if (data.authenticated && data.expiresIn) {
                     this.state.sessionExpiresAt = Date.now() + data.expiresIn * 1000;
                     this.log(`Session info: expires in ${data.expiresIn}s`);
@@ -297,6 +305,30 @@ const SessionManager = {
         }
     },
 
+    /**
+     * Check whether the user is browsing anonymously in public mode
+     */
+    async isPublicBrowsing() {
+        if (typeof this.state.publicBrowsing === 'boolean') {
+            return this.state.publicBrowsing;
+        }
+
+        try {
+            const response = await fetch('/api/auth/check', {
+                credentials: 'same-origin',
+                cache: 'no-store',
+            });
+            if (!response.ok) {
+                return false;
+            }
+            const data = await response.json();
+            this.state.publicBrowsing = !!data.publicMode && !data.authenticated;
+            return this.state.publicBrowsing;
+        } catch {
+            return false;
+        }
+    },
+
     /**
      * Show a warning that the session is about to expire
      * Only called when user is inactive
@@ -1036,9 +1036,12 @@ class SettingsManager {
             });
 
             if (response.ok) {
+                const result = await response.json();
                 this.showSuccess(
                     'cache-success',
-                    'Media reindex started. New files will appear shortly.'
+                    result.status === 'coalesced'
+                        ? 'A reindex ran recently or is in progress. Another will follow it shortly.'
+                        : 'Media reindex started. New files will appear shortly.'
                 );
             } else {
                 const error = await response.text();
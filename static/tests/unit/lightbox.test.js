@@ -984,6 +984,30 @@ describe('Lightbox Module', () => {
         });
     });
 
+    describe('fileUrl()', () => {
+        test('returns the plain file URL', () => {
+            expect(Lightbox.fileUrl({ path: 'a/photo.jpg' })).toBe('/api/file/a/photo.jpg');
+        });
+
+        test('adds reveal for sensitive files', () => {
+            expect(Lightbox.fileUrl({ path: 'photo.jpg', sensitive: true })).toBe(
+                '/api/file/photo.jpg?reveal=true'
+            );
+        });
+
+        test('keeps other query parameters', () => {
+            expect(Lightbox.fileUrl({ path: 'photo.jpg', sensitive: true }, 'download=true')).toBe(
+                '/api/file/photo.jpg?download=true&reveal=true'
+            );
+        });
+
+        test('builds stream URLs', () => {
+            expect(Lightbox.fileUrl({ path: 'clip.mp4', sensitive: true }, '', '/api/stream')).toBe(
+                '/api/stream/clip.mp4?reveal=true'
+            );
+        });
+    });
+
     describe('Animation loop detection', () => {
         beforeEach(() => {
             globalThis.Preferences = {
@@ -47,6 +47,7 @@ describe('SessionManager', () => {
             initialized: false,
             consecutiveKeepaliveFailures: 0,
             serverOfflineWarningShown: false,
+            publicBrowsing: null,
         };
 
         // Mock fetch
@@ -256,6 +257,28 @@ describe('SessionManager', () => {
             expect(handleExpiredSpy).toHaveBeenCalled();
         });
 
+        it('should stop keepalive instead of redirecting in public mode', async () => {
+            global.fetch = vi.fn().mockImplementation((url) => {
+                if (url === '/api/auth/check') {
+                    return Promise.resolve({
+                        ok: true,
+                        status: 200,
+                        json: async () => ({ authenticated: false, publicMode: true }),
+                    });
+                }
+                return Promise.resolve({ ok: false, status: 401 });
+            });
+
+            const handleExpiredSpy = vi.spyOn(SessionManager, 'handleSessionExpired');
+            const stopSpy = vi.spyOn(SessionManager, 'stop');
+
+            await SessionManager.sendKeepalive();
+
+            expect(handleExpiredSpy).not.toHaveBeenCalled();
+            expect(stopSpy).toHaveBeenCalled();
+            expect(SessionManager.state.publicBrowsing).toBe(true);
+        });
+
         it('should handle unsuccessful response', async () => {
             global.fetch = vi.fn().mockResolvedValue({
                 ok: true,